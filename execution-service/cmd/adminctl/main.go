@@ -0,0 +1,368 @@
+// Command adminctl runs common execution service admin actions against the
+// admin API, printing results as JSON so they can be piped into other tools.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"execution_service/pkg/client"
+)
+
+const usage = `Usage: adminctl [-url URL] [-token TOKEN] <command> [args]
+
+Commands:
+  rejudge problem <problem-id> [-verdict WA,TLE] [-after TIME] [-before TIME] [-wait]
+  rejudge submission <submission-id>...
+  rejudge status <job-id>
+  dlq list [-limit N]
+  dlq requeue [-limit N] [submission-id...]
+  drain on|off
+  languages refresh
+  debug on|off <submission-id>
+  cache invalidate [-submission IDS] [-problem IDS] [-language CODES] [-judge-status]
+  plagiarism list [-limit N] [-offset N]
+
+The URL and token default to EXECUTION_SERVICE_URL and EXECUTION_SERVICE_TOKEN.
+Drain and languages refresh apply to the node at URL, so point them at the
+node rather than a load balancer. Times are RFC 3339.
+`
+
+func main() {
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	baseURL := flag.String("url", envOr("EXECUTION_SERVICE_URL", "http://localhost:3003"), "execution service URL")
+	token := flag.String("token", os.Getenv("EXECUTION_SERVICE_TOKEN"), "admin bearer token")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout for each request")
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c := client.NewClient(*baseURL)
+	if *token != "" {
+		c.SetToken(*token)
+	}
+	cli := &adminctl{client: c, timeout: *timeout}
+
+	command, sub, args := flag.Arg(0), flag.Arg(1), flag.Args()[2:]
+	var err error
+	switch command + " " + sub {
+	case "rejudge problem":
+		err = cli.rejudgeProblem(args)
+	case "rejudge submission":
+		err = cli.rejudgeSubmissions(args)
+	case "rejudge status":
+		err = cli.rejudgeStatus(args)
+	case "dlq list":
+		err = cli.listDeadLetters(args)
+	case "dlq requeue":
+		err = cli.requeueDeadLetters(args)
+	case "drain on", "drain off":
+		err = cli.drain(sub == "on")
+	case "languages refresh":
+		err = cli.refreshLanguages()
+	case "debug on", "debug off":
+		err = cli.debug(sub == "on", args)
+	case "cache invalidate":
+		err = cli.invalidateCache(args)
+	case "plagiarism list":
+		err = cli.listPlagiarismReports(args)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "adminctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+type adminctl struct {
+	client  *client.Client
+	timeout time.Duration
+}
+
+func (a *adminctl) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), a.timeout)
+}
+
+func (a *adminctl) rejudgeProblem(args []string) error {
+	flags := flag.NewFlagSet("rejudge problem", flag.ExitOnError)
+	verdicts := flags.String("verdict", "", "comma-separated verdicts to rejudge")
+	after := flags.String("after", "", "only submissions made at or after this time")
+	before := flags.String("before", "", "only submissions made before this time")
+	wait := flags.Bool("wait", false, "wait for the job to finish")
+	problemID, err := parseTarget(flags, args)
+	if err != nil {
+		return err
+	}
+
+	request := &client.RejudgeProblemRequest{}
+	for _, verdict := range splitList(*verdicts) {
+		request.Verdicts = append(request.Verdicts, client.Verdict(strings.ToUpper(verdict)))
+	}
+	if request.SubmittedAfter, err = parseTime(*after); err != nil {
+		return err
+	}
+	if request.SubmittedBefore, err = parseTime(*before); err != nil {
+		return err
+	}
+
+	ctx, cancel := a.context()
+	job, err := a.client.RejudgeProblem(ctx, problemID, request)
+	cancel()
+	if err != nil {
+		return err
+	}
+	if *wait {
+		return a.waitForJob(job)
+	}
+	return printJSON(job)
+}
+
+// waitForJob polls the job until it finishes, reporting progress on stderr.
+func (a *adminctl) waitForJob(job *client.RejudgeJob) error {
+	for job.Status == client.RejudgeJobPending || job.Status == client.RejudgeJobRunning {
+		fmt.Fprintf(os.Stderr, "job %d: %s, %d/%d submissions\n", job.ID, job.Status, job.Processed, job.Total)
+		time.Sleep(2 * time.Second)
+
+		ctx, cancel := a.context()
+		next, err := a.client.GetRejudgeJob(ctx, job.ID)
+		cancel()
+		if err != nil {
+			return err
+		}
+		job = next
+	}
+
+	if err := printJSON(job); err != nil {
+		return err
+	}
+	if job.Status == client.RejudgeJobFailed {
+		return fmt.Errorf("rejudge job %d failed", job.ID)
+	}
+	return nil
+}
+
+func (a *adminctl) rejudgeSubmissions(args []string) error {
+	ids, err := parseIDs(args)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("at least one submission ID is required")
+	}
+
+	queued := []int64{}
+	for _, id := range ids {
+		ctx, cancel := a.context()
+		err := a.client.RejudgeSubmission(ctx, id)
+		cancel()
+		if err != nil {
+			printJSON(map[string]any{"queued": queued})
+			return fmt.Errorf("submission %d: %w", id, err)
+		}
+		queued = append(queued, id)
+	}
+	return printJSON(map[string]any{"queued": queued})
+}
+
+func (a *adminctl) rejudgeStatus(args []string) error {
+	jobID, err := parseTarget(flag.NewFlagSet("rejudge status", flag.ExitOnError), args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := a.context()
+	defer cancel()
+	job, err := a.client.GetRejudgeJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	return printJSON(job)
+}
+
+func (a *adminctl) listDeadLetters(args []string) error {
+	flags := flag.NewFlagSet("dlq list", flag.ExitOnError)
+	limit := flags.Int("limit", 100, "maximum messages to list")
+	flags.Parse(args)
+
+	ctx, cancel := a.context()
+	defer cancel()
+	dlq, err := a.client.InspectDeadLetters(ctx, *limit)
+	if err != nil {
+		return err
+	}
+	return printJSON(dlq)
+}
+
+func (a *adminctl) requeueDeadLetters(args []string) error {
+	flags := flag.NewFlagSet("dlq requeue", flag.ExitOnError)
+	limit := flags.Int("limit", 100, "maximum messages to examine")
+	flags.Parse(args)
+
+	ids, err := parseIDs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := a.context()
+	defer cancel()
+	requeued, err := a.client.RequeueDeadLetters(ctx, &client.RequeueDeadLettersRequest{SubmissionIDs: ids, Limit: *limit})
+	if err != nil {
+		return err
+	}
+	return printJSON(map[string]any{"requeued": requeued})
+}
+
+func (a *adminctl) drain(drained bool) error {
+	ctx, cancel := a.context()
+	defer cancel()
+	status, err := a.client.SetNodeDrained(ctx, drained)
+	if err != nil {
+		return err
+	}
+	return printJSON(status)
+}
+
+func (a *adminctl) refreshLanguages() error {
+	ctx, cancel := a.context()
+	defer cancel()
+	languages, err := a.client.RefreshLanguages(ctx)
+	if err != nil {
+		return err
+	}
+	return printJSON(languages)
+}
+
+func (a *adminctl) debug(enabled bool, args []string) error {
+	submissionID, err := parseTarget(flag.NewFlagSet("debug", flag.ExitOnError), args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := a.context()
+	defer cancel()
+	if err := a.client.SetSubmissionDebug(ctx, submissionID, enabled); err != nil {
+		return err
+	}
+	return printJSON(map[string]any{"submission_id": submissionID, "debug": enabled})
+}
+
+func (a *adminctl) invalidateCache(args []string) error {
+	flags := flag.NewFlagSet("cache invalidate", flag.ExitOnError)
+	submissions := flags.String("submission", "", "comma-separated submission IDs")
+	problems := flags.String("problem", "", "comma-separated problem IDs")
+	languages := flags.String("language", "", "comma-separated language codes")
+	judgeStatus := flags.Bool("judge-status", false, "drop the cached judge status")
+	flags.Parse(args)
+
+	request := &client.InvalidateCacheRequest{
+		Languages:   splitList(*languages),
+		JudgeStatus: *judgeStatus,
+	}
+	var err error
+	if request.SubmissionIDs, err = parseIDs(splitList(*submissions)); err != nil {
+		return err
+	}
+	if request.ProblemIDs, err = parseIDs(splitList(*problems)); err != nil {
+		return err
+	}
+	if len(request.SubmissionIDs) == 0 && len(request.ProblemIDs) == 0 && len(request.Languages) == 0 && !request.JudgeStatus {
+		return fmt.Errorf("nothing to invalidate")
+	}
+
+	ctx, cancel := a.context()
+	defer cancel()
+	if err := a.client.InvalidateCache(ctx, request); err != nil {
+		return err
+	}
+	return printJSON(request)
+}
+
+func (a *adminctl) listPlagiarismReports(args []string) error {
+	flags := flag.NewFlagSet("plagiarism list", flag.ExitOnError)
+	limit := flags.Int("limit", 20, "maximum reports to list")
+	offset := flags.Int("offset", 0, "reports to skip")
+	flags.Parse(args)
+
+	ctx, cancel := a.context()
+	defer cancel()
+	page, err := a.client.ListPlagiarismReports(ctx, *limit, *offset)
+	if err != nil {
+		return err
+	}
+	return printJSON(page)
+}
+
+// parseTarget parses flags that may follow the single ID argument.
+func parseTarget(flags *flag.FlagSet, args []string) (int64, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return 0, fmt.Errorf("%s needs an ID", flags.Name())
+	}
+	flags.Parse(args[1:])
+	return parseID(args[0])
+}
+
+func parseIDs(args []string) ([]int64, error) {
+	var ids []int64
+	for _, arg := range args {
+		id, err := parseID(arg)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func parseID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid ID %q", arg)
+	}
+	return id, nil
+}
+
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time %q: %w", value, err)
+	}
+	return &t, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
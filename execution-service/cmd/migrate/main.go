@@ -2,19 +2,16 @@ package main
 
 import (
 	"database/sql"
-	"embed"
 	"log"
 	"os"
 
+	"execution_service/cmd/migrate/migrations"
 	"execution_service/internal/config"
 
 	_ "github.com/lib/pq"
 	"github.com/pressly/goose/v3"
 )
 
-//go:embed migrations/*.sql
-var embedFS embed.FS
-
 func main() {
 	if len(os.Args) < 2 {
 		log.Fatal("Usage: migrate <command> [args]")
@@ -37,21 +34,21 @@ func main() {
 		log.Fatalf("Failed to set dialect: %v", err)
 	}
 
-	goose.SetBaseFS(embedFS)
+	goose.SetBaseFS(migrations.FS)
 
 	switch command {
 	case "up":
-		if err := goose.Up(db, "migrations"); err != nil {
+		if err := goose.Up(db, "."); err != nil {
 			log.Fatalf("Failed to run migrations: %v", err)
 		}
 		log.Println("Migrations completed successfully")
 	case "down":
-		if err := goose.Down(db, "migrations"); err != nil {
+		if err := goose.Down(db, "."); err != nil {
 			log.Fatalf("Failed to rollback migration: %v", err)
 		}
 		log.Println("Rollback completed successfully")
 	case "status":
-		if err := goose.Status(db, "migrations"); err != nil {
+		if err := goose.Status(db, "."); err != nil {
 			log.Fatalf("Failed to get migration status: %v", err)
 		}
 	case "create":
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Published domain events, kept for consumer replay
+CREATE TABLE execution.event_log (
+    seq BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    routing_key VARCHAR(100) NOT NULL,
+    data JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_event_log_created ON execution.event_log(created_at);
+CREATE INDEX idx_event_log_type_seq ON execution.event_log(event_type, seq);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_event_log_type_seq;
+DROP INDEX IF EXISTS idx_event_log_created;
+DROP TABLE IF EXISTS execution.event_log;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE execution.submission_test_results
+    ADD COLUMN checker_time_ms INTEGER,
+    ADD COLUMN checker_memory_kb INTEGER;
+
+-- +goose Down
+ALTER TABLE execution.submission_test_results
+    DROP COLUMN IF EXISTS checker_memory_kb,
+    DROP COLUMN IF EXISTS checker_time_ms;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE execution.submissions ADD COLUMN team_id BIGINT;
+
+CREATE INDEX idx_submissions_team ON execution.submissions(team_id, submitted_at DESC) WHERE team_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_submissions_team;
+ALTER TABLE execution.submissions DROP COLUMN IF EXISTS team_id;
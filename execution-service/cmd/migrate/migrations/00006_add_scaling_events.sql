@@ -0,0 +1,21 @@
+-- +goose Up
+-- Auto-scaler decisions, including dry-run recommendations
+CREATE TABLE execution.scaling_events (
+    id BIGSERIAL PRIMARY KEY,
+    trigger VARCHAR(20) NOT NULL,
+    queue_size INTEGER NOT NULL,
+    active_workers INTEGER NOT NULL,
+    p95_wait_ms INTEGER NOT NULL,
+    previous_count INTEGER NOT NULL,
+    new_count INTEGER NOT NULL,
+    dry_run BOOLEAN DEFAULT FALSE,
+    applied BOOLEAN DEFAULT FALSE,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_scaling_events_created ON execution.scaling_events(created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_scaling_events_created;
+DROP TABLE IF EXISTS execution.scaling_events;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE execution.submissions ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_submissions_metadata_tags ON execution.submissions USING GIN ((metadata->'tags'));
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_submissions_metadata_tags;
+ALTER TABLE execution.submissions DROP COLUMN IF EXISTS metadata;
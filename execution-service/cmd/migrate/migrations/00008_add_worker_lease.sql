@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE execution.judge_workers ADD COLUMN lease_expires_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+CREATE INDEX idx_judge_workers_lease ON execution.judge_workers(status, lease_expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_judge_workers_lease;
+ALTER TABLE execution.judge_workers DROP COLUMN IF EXISTS lease_expires_at;
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE execution.verdict_signatures (
+    submission_id BIGINT PRIMARY KEY REFERENCES execution.submissions(id) ON DELETE CASCADE,
+    key_id VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    signature TEXT NOT NULL,
+    signed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.verdict_signatures;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE execution.submissions ADD COLUMN testset_version VARCHAR(64);
+ALTER TABLE execution.submissions ADD COLUMN outdated_tests BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_submissions_outdated_tests ON execution.submissions(problem_id) WHERE outdated_tests;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_submissions_outdated_tests;
+ALTER TABLE execution.submissions DROP COLUMN IF EXISTS outdated_tests;
+ALTER TABLE execution.submissions DROP COLUMN IF EXISTS testset_version;
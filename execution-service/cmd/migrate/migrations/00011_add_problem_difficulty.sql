@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE execution.problem_difficulty (
+    problem_id BIGINT PRIMARY KEY,
+    total_submissions INTEGER NOT NULL DEFAULT 0,
+    accepted_submissions INTEGER NOT NULL DEFAULT 0,
+    attempting_users INTEGER NOT NULL DEFAULT 0,
+    solvers INTEGER NOT NULL DEFAULT 0,
+    acceptance_rate DOUBLE PRECISION NOT NULL DEFAULT 0,
+    avg_attempts_before_ac DOUBLE PRECISION NOT NULL DEFAULT 0,
+    median_runtime_ratio DOUBLE PRECISION,
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    level VARCHAR(20) NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.problem_difficulty;
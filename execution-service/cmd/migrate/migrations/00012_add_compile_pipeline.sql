@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE execution.supported_languages ADD COLUMN compile_pipeline JSONB;
+
+UPDATE execution.supported_languages
+SET compile_pipeline = jsonb_build_array(jsonb_build_object('name', 'compile', 'command', compile_command))
+WHERE compile_command IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE execution.supported_languages DROP COLUMN IF EXISTS compile_pipeline;
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE execution.contest_environments (
+    contest_id BIGINT PRIMARY KEY,
+    toolchain_id VARCHAR(64) NOT NULL,
+    environment JSONB NOT NULL,
+    pinned_by BIGINT NOT NULL,
+    pinned_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.contest_environments;
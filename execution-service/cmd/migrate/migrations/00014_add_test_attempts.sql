@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE execution.submission_test_results
+    ADD COLUMN attempts JSONB;
+
+-- +goose Down
+ALTER TABLE execution.submission_test_results
+    DROP COLUMN IF EXISTS attempts;
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE execution.shadow_configs (
+    id BIGSERIAL PRIMARY KEY,
+    problem_id BIGINT NOT NULL,
+    checker_url TEXT,
+    time_limit_ms INTEGER,
+    memory_limit_kb INTEGER,
+    note TEXT NOT NULL DEFAULT '',
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    stopped_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_shadow_configs_active ON execution.shadow_configs(problem_id) WHERE stopped_at IS NULL;
+
+CREATE TABLE execution.shadow_results (
+    id BIGSERIAL PRIMARY KEY,
+    config_id BIGINT NOT NULL REFERENCES execution.shadow_configs(id) ON DELETE CASCADE,
+    submission_id BIGINT NOT NULL,
+    live_verdict VARCHAR(20) NOT NULL,
+    shadow_verdict VARCHAR(20) NOT NULL,
+    live_passed INTEGER NOT NULL,
+    shadow_passed INTEGER NOT NULL,
+    live_time_ms INTEGER NOT NULL,
+    shadow_time_ms INTEGER NOT NULL,
+    differences JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_shadow_results_config ON execution.shadow_results(config_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.shadow_results;
+DROP TABLE IF EXISTS execution.shadow_configs;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE execution.submission_test_results
+    ADD COLUMN stderr TEXT,
+    ADD COLUMN stderr_visible BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE execution.submission_test_results
+    DROP COLUMN IF EXISTS stderr_visible,
+    DROP COLUMN IF EXISTS stderr;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE execution.api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    key_prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes JSONB NOT NULL,
+    rate_limit INTEGER NOT NULL,
+    usage_count BIGINT NOT NULL DEFAULT 0,
+    last_used_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_api_keys_user ON execution.api_keys(user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.api_keys;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE execution.canaries (
+    id BIGSERIAL PRIMARY KEY,
+    submission_id BIGINT NOT NULL UNIQUE REFERENCES execution.submissions(id) ON DELETE CASCADE,
+    expected_verdict VARCHAR(20) NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE execution.canary_runs (
+    id BIGSERIAL PRIMARY KEY,
+    canary_id BIGINT NOT NULL REFERENCES execution.canaries(id) ON DELETE CASCADE,
+    node VARCHAR(255) NOT NULL,
+    verdict VARCHAR(20) NOT NULL,
+    max_time_ms INTEGER NOT NULL,
+    tests_passed INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_canary_runs_canary ON execution.canary_runs(canary_id, node, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.canary_runs;
+DROP TABLE IF EXISTS execution.canaries;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE execution.rejudge_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    problem_id BIGINT NOT NULL,
+    verdicts JSONB NOT NULL DEFAULT '[]',
+    submitted_after TIMESTAMP,
+    submitted_before TIMESTAMP,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    total INTEGER NOT NULL,
+    processed INTEGER NOT NULL DEFAULT 0,
+    last_submission_id BIGINT NOT NULL DEFAULT 0,
+    max_submission_id BIGINT NOT NULL,
+    node VARCHAR(255),
+    lease_expires_at TIMESTAMP,
+    error TEXT,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_rejudge_jobs_active ON execution.rejudge_jobs(id) WHERE status IN ('pending', 'running');
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.rejudge_jobs;
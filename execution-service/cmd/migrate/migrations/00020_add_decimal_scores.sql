@@ -0,0 +1,37 @@
+-- +goose Up
+ALTER TABLE execution.submissions ALTER COLUMN score TYPE NUMERIC(10,2) USING score::NUMERIC;
+
+-- Scores were never written before; derive them from the tests passed
+UPDATE execution.submissions
+SET score = ROUND(test_cases_passed * 100.0 / test_cases_total, 2)
+WHERE test_cases_total > 0 AND verdict NOT IN ('pending', 'CE');
+
+CREATE TABLE execution.problem_scoring (
+    problem_id BIGINT PRIMARY KEY,
+    aggregation VARCHAR(10) NOT NULL,
+    updated_by BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE execution.user_problem_scores (
+    user_id BIGINT NOT NULL,
+    problem_id BIGINT NOT NULL,
+    score NUMERIC(10,2) NOT NULL,
+    attempts INTEGER NOT NULL,
+    aggregation VARCHAR(10) NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, problem_id)
+);
+
+CREATE INDEX idx_user_problem_scores_problem ON execution.user_problem_scores(problem_id, score DESC);
+
+INSERT INTO execution.user_problem_scores (user_id, problem_id, score, attempts, aggregation)
+SELECT user_id, problem_id, COALESCE(MAX(score), 0), COUNT(*), 'max'
+FROM execution.submissions
+WHERE verdict <> 'pending'
+GROUP BY user_id, problem_id;
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.user_problem_scores;
+DROP TABLE IF EXISTS execution.problem_scoring;
+ALTER TABLE execution.submissions ALTER COLUMN score TYPE INTEGER USING ROUND(score)::INTEGER;
@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE execution.submission_debug (
+    submission_id BIGINT PRIMARY KEY REFERENCES execution.submissions(id) ON DELETE CASCADE,
+    enabled_by BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.submission_debug;
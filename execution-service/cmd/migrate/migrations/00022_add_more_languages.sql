@@ -0,0 +1,13 @@
+-- +goose Up
+INSERT INTO execution.supported_languages (language_code, language_name, version, compile_command, compile_pipeline, execute_command) VALUES
+('rust', 'Rust 2021', '2021', 'rustc -O --edition 2021 -o {output} {input}',
+ '[{"name": "compile", "command": "rustc -O --edition 2021 -o {output} {input}", "time_limit_ms": 30000, "processes": 8}]', './{executable}'),
+('kotlin', 'Kotlin 1.9', '1.9', 'kotlinc {input} -include-runtime -d {output}.jar',
+ '[{"name": "compile", "command": "kotlinc {input} -include-runtime -d {output}.jar", "time_limit_ms": 60000, "memory_limit_kb": 1048576, "processes": 64}]', 'java -jar {executable}.jar'),
+('csharp', 'C# (Mono)', '6.12', 'mcs -optimize+ -out:{output}.exe {input}',
+ '[{"name": "compile", "command": "mcs -optimize+ -out:{output}.exe {input}", "time_limit_ms": 30000, "processes": 16}]', 'mono {executable}.exe'),
+('ruby', 'Ruby 3', '3.2', NULL, NULL, 'ruby {input}')
+ON CONFLICT (language_code) DO NOTHING;
+
+-- +goose Down
+DELETE FROM execution.supported_languages WHERE language_code IN ('rust', 'kotlin', 'csharp', 'ruby');
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE execution.submissions ADD COLUMN trusted BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE execution.submissions DROP COLUMN IF EXISTS trusted;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE execution.supported_languages ADD COLUMN file_extension VARCHAR(10);
+
+UPDATE execution.supported_languages l
+SET file_extension = e.extension
+FROM (VALUES
+    ('cpp', '.cpp'), ('c', '.c'), ('java', '.java'), ('python', '.py'), ('go', '.go'),
+    ('rust', '.rs'), ('kotlin', '.kt'), ('csharp', '.cs'), ('ruby', '.rb')
+) AS e(code, extension)
+WHERE l.language_code = e.code;
+
+-- +goose Down
+ALTER TABLE execution.supported_languages DROP COLUMN IF EXISTS file_extension;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE execution.supported_languages ADD COLUMN execution_profile JSONB;
+
+-- +goose Down
+ALTER TABLE execution.supported_languages DROP COLUMN IF EXISTS execution_profile;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE execution.supported_languages
+    ADD COLUMN time_multiplier DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (time_multiplier > 0),
+    ADD COLUMN memory_multiplier DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (memory_multiplier > 0);
+
+-- +goose Down
+ALTER TABLE execution.supported_languages
+    DROP COLUMN IF EXISTS memory_multiplier,
+    DROP COLUMN IF EXISTS time_multiplier;
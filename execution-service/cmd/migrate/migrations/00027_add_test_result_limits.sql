@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE execution.submission_test_results
+    ADD COLUMN time_limit_ms INTEGER,
+    ADD COLUMN memory_limit_kb INTEGER,
+    ADD COLUMN effective_time_limit_ms INTEGER,
+    ADD COLUMN effective_memory_limit_kb INTEGER;
+
+-- +goose Down
+ALTER TABLE execution.submission_test_results
+    DROP COLUMN IF EXISTS effective_memory_limit_kb,
+    DROP COLUMN IF EXISTS effective_time_limit_ms,
+    DROP COLUMN IF EXISTS memory_limit_kb,
+    DROP COLUMN IF EXISTS time_limit_ms;
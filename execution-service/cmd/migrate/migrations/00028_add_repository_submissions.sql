@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE execution.submissions
+    ADD COLUMN repository_url TEXT,
+    ADD COLUMN commit_sha VARCHAR(40),
+    ADD COLUMN entry_file VARCHAR(255);
+
+-- +goose Down
+ALTER TABLE execution.submissions
+    DROP COLUMN IF EXISTS entry_file,
+    DROP COLUMN IF EXISTS commit_sha,
+    DROP COLUMN IF EXISTS repository_url;
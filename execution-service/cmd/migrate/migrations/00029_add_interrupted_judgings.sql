@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE execution.interrupted_judgings (
+    submission_id BIGINT PRIMARY KEY REFERENCES execution.submissions(id) ON DELETE CASCADE,
+    testset_version VARCHAR(64) NOT NULL,
+    tests JSONB NOT NULL,
+    interrupted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.interrupted_judgings;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Periodic views of what each node had queued, for postmortems
+CREATE TABLE execution.queue_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    node VARCHAR(255) NOT NULL,
+    queue_depth INTEGER NOT NULL DEFAULT 0,
+    entries JSONB,
+    taken_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_queue_snapshots_taken ON execution.queue_snapshots(taken_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_queue_snapshots_taken;
+DROP TABLE IF EXISTS execution.queue_snapshots;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Client-supplied keys that make retried submission requests return the
+-- original submission
+CREATE TABLE execution.submission_idempotency_keys (
+    user_id BIGINT NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    submission_id BIGINT NOT NULL REFERENCES execution.submissions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.submission_idempotency_keys;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Platform-wide default and maximum limits, editable at runtime. Without a
+-- row the judge config applies.
+CREATE TABLE execution.platform_limits (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    default_time_limit_ms INTEGER NOT NULL,
+    default_memory_limit_kb INTEGER NOT NULL,
+    max_time_limit_ms INTEGER NOT NULL,
+    max_memory_limit_kb INTEGER NOT NULL,
+    max_stack_size_kb INTEGER NOT NULL,
+    max_output_size_kb INTEGER NOT NULL,
+    updated_by BIGINT,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.platform_limits;
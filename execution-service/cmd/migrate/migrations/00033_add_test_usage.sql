@@ -0,0 +1,10 @@
+-- +goose Up
+-- Opt-in sandbox usage report per test.
+ALTER TABLE execution.submission_test_results
+    ADD COLUMN usage JSONB,
+    ADD COLUMN usage_visible BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE execution.submission_test_results
+    DROP COLUMN IF EXISTS usage_visible,
+    DROP COLUMN IF EXISTS usage;
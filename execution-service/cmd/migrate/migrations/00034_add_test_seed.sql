@@ -0,0 +1,8 @@
+-- +goose Up
+-- Seed injected into a test's run for randomized solutions.
+ALTER TABLE execution.submission_test_results
+    ADD COLUMN seed BIGINT;
+
+-- +goose Down
+ALTER TABLE execution.submission_test_results
+    DROP COLUMN IF EXISTS seed;
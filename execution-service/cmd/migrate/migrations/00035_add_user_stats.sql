@@ -0,0 +1,32 @@
+-- +goose Up
+-- Per-user counters kept in step with submissions' final verdicts, so
+-- profile pages do not scan the submissions table.
+CREATE TABLE execution.user_verdict_counts (
+    user_id BIGINT NOT NULL,
+    verdict VARCHAR(20) NOT NULL,
+    count BIGINT NOT NULL,
+    PRIMARY KEY (user_id, verdict)
+);
+
+CREATE TABLE execution.user_problem_accepts (
+    user_id BIGINT NOT NULL,
+    problem_id BIGINT NOT NULL,
+    accepted BIGINT NOT NULL,
+    PRIMARY KEY (user_id, problem_id)
+);
+
+INSERT INTO execution.user_verdict_counts (user_id, verdict, count)
+SELECT user_id, verdict, COUNT(*)
+FROM execution.submissions
+WHERE verdict <> 'pending'
+GROUP BY user_id, verdict;
+
+INSERT INTO execution.user_problem_accepts (user_id, problem_id, accepted)
+SELECT user_id, problem_id, COUNT(*)
+FROM execution.submissions
+WHERE verdict = 'AC'
+GROUP BY user_id, problem_id;
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.user_problem_accepts;
+DROP TABLE IF EXISTS execution.user_verdict_counts;
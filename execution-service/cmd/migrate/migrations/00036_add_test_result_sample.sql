@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE execution.submission_test_results
+    ADD COLUMN is_sample BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE execution.submission_test_results
+    DROP COLUMN IF EXISTS is_sample;
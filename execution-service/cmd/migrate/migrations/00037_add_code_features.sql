@@ -0,0 +1,13 @@
+-- +goose Up
+-- Plagiarism features extracted from each submission's code, so checks
+-- compare stored features instead of downloading and parsing every
+-- candidate again. Rows from an older feature version are re-extracted.
+CREATE TABLE execution.code_features (
+    submission_id BIGINT PRIMARY KEY REFERENCES execution.submissions(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    features JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.code_features;
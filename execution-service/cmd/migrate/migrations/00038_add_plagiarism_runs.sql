@@ -0,0 +1,25 @@
+-- +goose Up
+-- Contest-wide plagiarism runs. Pairs holds the ranked suspicious pairs once
+-- the run completes; a run whose node stops renewing its lease is restarted
+-- by another node.
+CREATE TABLE execution.plagiarism_runs (
+    id BIGSERIAL PRIMARY KEY,
+    contest_id BIGINT NOT NULL,
+    threshold DOUBLE PRECISION NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    pairs JSONB NOT NULL DEFAULT '[]',
+    node VARCHAR(255),
+    lease_expires_at TIMESTAMP,
+    error TEXT,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX idx_plagiarism_runs_active ON execution.plagiarism_runs(id) WHERE status IN ('pending', 'running');
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.plagiarism_runs;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Starter code handed out with a problem, stripped from submissions before
+-- plagiarism features are extracted. A NULL language applies to every
+-- language.
+CREATE TABLE execution.plagiarism_boilerplate (
+    id BIGSERIAL PRIMARY KEY,
+    problem_id BIGINT NOT NULL,
+    language VARCHAR(50),
+    code TEXT NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_plagiarism_boilerplate_problem ON execution.plagiarism_boilerplate(problem_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.plagiarism_boilerplate;
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE execution.submissions
+    ADD COLUMN plagiarism_checked_at TIMESTAMP;
+
+-- Submissions already in a report were the ones the scheduler skipped
+UPDATE execution.submissions
+SET plagiarism_checked_at = NOW()
+WHERE id IN (
+    SELECT submission1_id FROM execution.plagiarism_reports
+    UNION
+    SELECT submission2_id FROM execution.plagiarism_reports
+);
+
+CREATE INDEX idx_submissions_plagiarism_unchecked ON execution.submissions(submitted_at DESC)
+    WHERE verdict = 'AC' AND plagiarism_checked_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS execution.idx_submissions_plagiarism_unchecked;
+ALTER TABLE execution.submissions
+    DROP COLUMN IF EXISTS plagiarism_checked_at;
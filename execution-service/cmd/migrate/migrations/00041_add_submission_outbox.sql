@@ -0,0 +1,20 @@
+-- +goose Up
+-- Submissions recorded but not yet confirmed by the broker. A row is written
+-- in the transaction that creates the submission and deleted once the judge
+-- request is published, so a failed publish is retried instead of lost.
+CREATE TABLE execution.submission_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    submission_id BIGINT NOT NULL REFERENCES execution.submissions(id) ON DELETE CASCADE,
+    time_limit_ms INTEGER NOT NULL,
+    memory_limit_kb INTEGER NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_submission_outbox_next_attempt ON execution.submission_outbox(next_attempt_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.submission_outbox;
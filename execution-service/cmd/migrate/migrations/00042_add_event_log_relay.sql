@@ -0,0 +1,20 @@
+-- +goose Up
+-- The event log is the outbox of the events exchange. Events are recorded
+-- unpublished and the relay publishes them in seq order.
+ALTER TABLE execution.event_log ADD COLUMN published_at TIMESTAMP;
+UPDATE execution.event_log SET published_at = created_at;
+
+CREATE INDEX idx_event_log_unpublished ON execution.event_log(seq) WHERE published_at IS NULL;
+
+-- A single row leasing the relay to one node, so events leave in order
+CREATE TABLE execution.event_relay (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    node VARCHAR(255) NOT NULL DEFAULT '',
+    lease_expires_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO execution.event_relay DEFAULT VALUES;
+
+-- +goose Down
+DROP TABLE IF EXISTS execution.event_relay;
+ALTER TABLE execution.event_log DROP COLUMN IF EXISTS published_at;
@@ -0,0 +1,8 @@
+// Package migrations embeds the goose SQL migrations so that both the
+// migrate tool and the server can read them.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
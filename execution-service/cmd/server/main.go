@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -10,12 +11,15 @@ import (
 	"syscall"
 	"time"
 
+	"execution_service/cmd/migrate/migrations"
 	"execution_service/internal/api"
 	"execution_service/internal/cache"
 	"execution_service/internal/config"
 	"execution_service/internal/database"
+	grpcserver "execution_service/internal/grpc"
 	"execution_service/internal/httpclient"
 	"execution_service/internal/middleware"
+	"execution_service/internal/models"
 	"execution_service/internal/plagiarism"
 	"execution_service/internal/queue"
 	"execution_service/internal/rbac"
@@ -28,6 +32,9 @@ import (
 )
 
 func main() {
+	logBuffer := services.NewLogBuffer(500)
+	log.SetOutput(io.MultiWriter(os.Stderr, logBuffer))
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
@@ -44,6 +51,14 @@ func main() {
 	}
 	defer db.Close()
 
+	schemaService, err := services.NewSchemaService(db, migrations.FS, cfg.Database.AutoMigrate)
+	if err != nil {
+		log.Fatalf("Failed to initialize schema check: %v", err)
+	}
+	if status := schemaService.Check(context.Background()); !status.Ready {
+		log.Printf("Warning: database schema is not ready: %s", status.Error)
+	}
+
 	minioClient, err := storage.NewMinIOClient(&cfg.MinIO)
 	if err != nil {
 		log.Fatalf("Failed to create MinIO client: %v", err)
@@ -61,11 +76,26 @@ func main() {
 	}
 	defer valkeyClient.Close()
 
+	eventLog := services.NewEventLogService(db, &cfg.Events)
+	rabbitmqClient.SetEventRecorder(eventLog.Record)
+	eventLog.SetPublisher(rabbitmqClient.PublishLoggedEvent)
+
 	isolateSandbox := sandbox.NewIsolateSandbox(&cfg.Isolate)
+	languageService := services.NewLanguageService(db, valkeyClient)
+	languageService.SetSandbox(isolateSandbox)
+	if _, err := languageService.Refresh(context.Background()); err != nil {
+		log.Printf("Warning: using built-in language configs: %v", err)
+		isolateSandbox.SnapshotEnvironment(context.Background())
+	}
 
 	// Initialize resource validation service
 	contentClient := httpclient.NewContentServiceClient("http://localhost:3002")
 	resourceValidator := services.NewResourceValidationService(&cfg.Judge, contentClient)
+	resourceValidator.SetCache(valkeyClient)
+	resourceValidator.SetDatabase(db)
+	if err := resourceValidator.RefreshPlatformLimits(context.Background()); err != nil {
+		log.Printf("Warning: using judge config limits: %v", err)
+	}
 
 	judgePool := worker.NewJudgePool(
 		cfg.Judge.WorkerCount,
@@ -75,9 +105,100 @@ func main() {
 		isolateSandbox,
 		resourceValidator,
 	)
+	languageService.SetRefreshListener(judgePool.SyncLanguages)
+
+	metricsService := services.NewMetricsService()
+	judgePool.SetMetricsService(metricsService)
+
+	boxPool := sandbox.NewBoxPool(isolateSandbox, cfg.Isolate.BoxPoolSize)
+	boxPool.SetListener(func(operation, result string, stats sandbox.BoxPoolStats) {
+		metricsService.RecordSandboxOperation(operation, result)
+		metricsService.RecordBoxPool(stats.Available, stats.Warm, stats.InUse)
+	})
+	boxPool.SetWarmListener(func(event string, stats sandbox.WarmPoolStats) {
+		metricsService.RecordWarmPool(stats.Language, event, stats.Ready, stats.OldestAgeSeconds)
+	})
+	if err := boxPool.Start(); err != nil {
+		log.Printf("Warning: running without a sandbox box pool: %v", err)
+	} else {
+		isolateSandbox.SetBoxPool(boxPool)
+		defer boxPool.Close()
+		for language, size := range cfg.Isolate.WarmPools {
+			if err := boxPool.ResizeWarm(language, size); err != nil {
+				log.Printf("Warning: not warming %s boxes: %v", language, err)
+			}
+		}
+	}
+
+	judgePool.SetCheckerTimeBudget(cfg.Judge.CheckerTimeBudget)
+	judgePool.SetTLERetry(cfg.Judge.TLERetry)
+	judgePool.SetStderrVisibility(models.StderrVisibility(cfg.Judge.StderrVisibility))
+	judgePool.SetUsageReport(models.UsageReport(cfg.Judge.UsageReport))
+	judgePool.SetTestParallelism(cfg.Judge.TestParallelism)
+	if cfg.Judge.Seed.Enabled {
+		judgePool.SetSeedInjection(cfg.Judge.Seed.EnvVar, cfg.Judge.Seed.Secret)
+	}
+	judgePool.SetSchedulerLimits(cfg.Judge.SchedulerBuffer, cfg.Judge.ReservedWorkers, cfg.Judge.ReservedPriority)
+	judgePool.SetShutdownGracePeriod(cfg.Judge.ShutdownGracePeriod)
+	judgePool.SetContestWorkers(cfg.Judge.ContestWorkers)
+	judgePool.SetAutoScaleDryRun(cfg.Judge.AutoScaleDryRun)
+	judgePool.SetContentClient(contentClient)
+	contentClient.SetObserver(metricsService.RecordContentServiceRequest)
+
+	diskWatcher := services.NewDiskWatcherService(&cfg.Isolate, metricsService)
+	judgePool.SetDiskWatcher(diskWatcher)
+	judgePool.SetSupplementaryData(services.NewSupplementaryDataService(minioClient, &cfg.Isolate))
+	judgePool.SetArtifactCache(services.NewArtifactCacheService(minioClient, metricsService))
+
+	testsetService := services.NewTestsetService(db, minioClient, contentClient, rabbitmqClient)
+	difficultyService := services.NewDifficultyService(db, rabbitmqClient, contentClient)
+	diagnosticsService := services.NewDiagnosticsService(db, rabbitmqClient, minioClient, isolateSandbox, cfg, logBuffer)
+	diagnosticsService.SetWorkerStateSource(func() any { return judgePool.WorkerStates() })
+	contestEnvironments := services.NewContestEnvironmentService(db, isolateSandbox)
+	judgePool.SetContestEnvironments(contestEnvironments)
+	shadowJudging := services.NewShadowJudgingService(db)
+	queueSnapshots := services.NewQueueSnapshotService(db, judgePool.QueueSnapshot, &cfg.QueueSnapshots)
+	judgePool.SetShadowJudging(shadowJudging)
+	attemptTimelines := services.NewAttemptTimelineService(db, valkeyClient)
+	judgePool.SetAttemptTimelines(attemptTimelines)
+	scoring := services.NewScoringService(db, &cfg.Scoring)
+	judgePool.SetScoring(scoring)
+	userStats := services.NewUserStatsService(db, &cfg.UserStats)
+	submissionService := services.NewSubmissionService(db, minioClient, rabbitmqClient)
+	submissionService.SetResourceValidator(resourceValidator)
+	var codeSpool *services.CodeSpoolService
+	if cfg.MinIO.Spool.Enabled {
+		codeSpool, err = services.NewCodeSpoolService(minioClient, metricsService, &cfg.MinIO.Spool)
+		if err != nil {
+			log.Fatalf("Failed to initialize submission spool: %v", err)
+		}
+		submissionService.SetSpool(codeSpool)
+	}
+	rejudgeJobs := services.NewRejudgeJobService(db, submissionService, &cfg.Rejudge, judgePool.NodeName())
+	consistency := services.NewConsistencyService(db, rabbitmqClient, &cfg.Consistency, judgePool.NodeName())
+	consistency.SetCanaryJudge(judgePool.JudgeCanary)
+	consistency.SetDrainer(judgePool.SetDrained)
+	limitSimulations := services.NewLimitSimulationService(db)
+	limitSimulations.SetRerunJudge(judgePool.JudgeWithLimits)
+
+	var verdictSigner *services.VerdictSigningService
+	if cfg.Signing.Enabled {
+		verdictSigner, err = services.NewVerdictSigningService(&cfg.Signing, db)
+		if err != nil {
+			log.Fatalf("Failed to initialize verdict signing: %v", err)
+		}
+		judgePool.SetVerdictSigner(verdictSigner)
+	}
+
+	var storagePolicies *services.StoragePolicyService
+	if cfg.MinIO.Policies.Enabled {
+		storagePolicies = services.NewStoragePolicyService(minioClient, rabbitmqClient, &cfg.MinIO.Policies)
+	}
 
 	// Initialize plagiarism detector
 	plagiarismDetector := plagiarism.NewPlagiarismDetector(db, minioClient, &cfg.Plagiarism)
+	plagiarismDetector.SetEventPublisher(rabbitmqClient.PublishEvent)
+	plagiarismDetector.SetLoadSource(judgePool.Load)
 
 	// Set plagiarism enqueuer for judge pool
 	judgePool.SetPlagiarismEnqueuer(plagiarismDetector.EnqueueSubmission)
@@ -87,26 +208,82 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize RBAC service: %v", err)
 	}
-
-	// Initialize circuit breaker service
-	circuitBreakerService := services.NewCircuitBreakerService()
+	rbacService.SetCache(valkeyClient, cfg.RBAC.DecisionCacheTTL)
+	rbacService.SetObserver(metricsService.RecordRBACDecision)
 
 	// Initialize security middleware
 	securityMiddleware := middleware.NewSecurityMiddleware(cfg.JWT.Secret)
 	securityMiddleware.SetRBACService(rbacService)
 
-	// Pass circuit breaker service to judge pool (can be used for future external service calls)
-	// For now, we'll initialize it and make it available for monitoring
-
-	handler := api.NewHandler(db, rabbitmqClient, judgePool, minioClient, cfg.JWT.Secret)
+	apiKeyService := services.NewAPIKeyService(db, &cfg.APIKeys)
+	apiKeyService.SetObserver(metricsService.RecordAPIKeyRequest)
+	securityMiddleware.SetAPIKeyService(apiKeyService)
+
+	handler := api.NewHandler(db, rabbitmqClient, judgePool, minioClient, submissionService, cfg.JWT.Secret)
+	handler.SetMetricsService(metricsService)
+	handler.SetCache(valkeyClient)
+	handler.SetResourceValidator(resourceValidator)
+	handler.SetEventLog(eventLog)
+	handler.SetDiskWatcher(diskWatcher)
+	handler.SetSchemaService(schemaService)
+	handler.SetHealthCheckService(services.NewHealthCheckService(db, rabbitmqClient, minioClient, valkeyClient, isolateSandbox))
+	handler.SetVerdictSigner(verdictSigner)
+	handler.SetTestsetService(testsetService)
+	handler.SetDifficultyService(difficultyService)
+	handler.SetDiagnosticsService(diagnosticsService)
+	handler.SetContestEnvironmentService(contestEnvironments)
+	handler.SetShadowJudgingService(shadowJudging)
+	handler.SetPlagiarismDetector(plagiarismDetector)
+	handler.SetQueueSnapshotService(queueSnapshots)
+	handler.SetStoragePolicyService(storagePolicies)
+	handler.SetCodeSpool(codeSpool)
+	handler.SetAttemptTimelineService(attemptTimelines)
+	handler.SetAPIKeyService(apiKeyService)
+	handler.SetConsistencyService(consistency)
+	handler.SetLimitSimulationService(limitSimulations)
+	handler.SetRejudgeJobService(rejudgeJobs)
+	handler.SetScoringService(scoring)
+	handler.SetUserStatsService(userStats)
+	cacheInvalidation := services.NewCacheInvalidationService(valkeyClient, rabbitmqClient, judgePool.NodeName())
+	cacheInvalidation.SetShadowJudging(shadowJudging)
+	cacheInvalidation.SetLanguageService(languageService)
+	cacheInvalidation.SetRBAC(rbacService)
+	handler.SetCacheInvalidationService(cacheInvalidation)
+	handler.SetRBACService(rbacService)
+	handler.SetLanguageService(languageService)
+	if cfg.GitIntake.Enabled {
+		handler.SetRepositoryIntakeService(services.NewRepositoryIntakeService(&cfg.GitIntake, isolateSandbox))
+	}
+	deadLetters := services.NewDeadLetterQueueService(rabbitmqClient)
+	judgePool.SetDeadLetterQueue(deadLetters, cfg.Judge.MaxDeliveryAttempts)
+	handler.SetDeadLetterQueueService(deadLetters)
+	executionLogs := services.NewExecutionLogService(db, &cfg.Logs)
+	judgePool.SetExecutionLogs(executionLogs)
+	handler.SetExecutionLogService(executionLogs)
+	executionLogs.SetTailPublisher(valkeyClient.PublishContestLog, judgePool.NodeName())
+	contestLogHub := api.NewContestLogHub(cfg.Logs.TailMaxStreams)
+	handler.SetContestLogHub(contestLogHub, cfg.Logs.TailEventsPerSecond)
+	progressHub := api.NewProgressHub()
+	handler.SetProgressHub(progressHub)
+	judgePool.SetProgressPublisher(valkeyClient.PublishSubmissionProgress)
+	securityMiddleware.SetServiceScopeAuditor(handler.AuditServiceScope)
+
+	// Drop the cached judge status whenever the pool is resized
+	judgePool.SetScaleListener(func(workerCount int) {
+		if err := valkeyClient.InvalidateJudgeStatus(context.Background()); err != nil {
+			log.Printf("Failed to invalidate judge status cache: %v", err)
+		}
+	})
 
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 	router.Use(gin.Logger())
+	router.Use(middleware.Metrics(metricsService))
 	router.Use(gin.Recovery())
 
 	// Apply security middleware
 	router.Use(securityMiddleware.SecurityHeaders())
+	router.Use(securityMiddleware.APIKeyAuth())
 	router.Use(securityMiddleware.JWTRateLimit(60))             // 60 requests per minute
 	router.Use(securityMiddleware.ValidateRequestSize(1 << 20)) // 1MB max request size
 	router.Use(securityMiddleware.ValidateContentType("application/json", "text/plain"))
@@ -125,6 +302,8 @@ func main() {
 
 	errChan := make(chan error, 1)
 
+	go isolateSandbox.PreparePCH(ctx)
+
 	go func() {
 		log.Printf("Starting execution service on port %s", cfg.Server.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -132,6 +311,18 @@ func main() {
 		}
 	}()
 
+	var grpcServer *grpcserver.Server
+	if cfg.GRPC.Enabled {
+		grpcServer = grpcserver.NewServer(db, submissionService, securityMiddleware, services.NewAuditLogService(db))
+		grpcServer.SetLanguageService(languageService)
+		go func() {
+			log.Printf("Starting gRPC server on port %s", cfg.GRPC.Port)
+			if err := grpcServer.Serve(cfg.GRPC.Port); err != nil {
+				errChan <- fmt.Errorf("failed to start gRPC server: %w", err)
+			}
+		}()
+	}
+
 	go func() {
 		log.Printf("Starting judge worker pool with %d workers", cfg.Judge.WorkerCount)
 		if err := judgePool.Start(ctx); err != nil {
@@ -147,6 +338,36 @@ func main() {
 		}
 	}()
 
+	go eventLog.Start(ctx)
+	go eventLog.StartRelay(ctx, judgePool.NodeName())
+	go progressHub.Run(ctx, valkeyClient.SubscribeSubmissionProgress(ctx))
+	go executionLogs.Start(ctx)
+	go contestLogHub.Run(ctx, valkeyClient.SubscribeContestLogs(ctx))
+	go diskWatcher.Start(ctx)
+	go schemaService.Start(ctx)
+	go testsetService.Start(ctx)
+	go difficultyService.Start(ctx)
+	go rejudgeJobs.Start(ctx)
+	go submissionService.StartOutboxRelay(ctx)
+	go plagiarismDetector.StartContestRuns(ctx, judgePool.NodeName())
+	go languageService.Start(ctx)
+	go userStats.Start(ctx)
+	go cacheInvalidation.Start(ctx)
+	go resourceValidator.Start(ctx)
+	if cfg.Consistency.Enabled {
+		go consistency.Start(ctx)
+	}
+	if cfg.QueueSnapshots.Enabled {
+		go queueSnapshots.Start(ctx)
+	}
+	if storagePolicies != nil {
+		go storagePolicies.Start(ctx)
+	}
+	if codeSpool != nil {
+		go codeSpool.Start(ctx)
+	}
+
+	rabbitmqClient.OnReconnect(judgePool.HandleReconnect)
 	rabbitmqClient.StartHeartbeat()
 
 	quit := make(chan os.Signal, 1)
@@ -167,6 +388,9 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
 
 	judgePool.Stop()
 	plagiarismDetector.Stop()
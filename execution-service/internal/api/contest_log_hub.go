@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"sync"
+
+	"execution_service/internal/models"
+)
+
+// ContestLogHub fans contest log entries out to the proctor streams watching
+// each contest. A stream that falls behind misses entries rather than
+// stalling the others.
+type ContestLogHub struct {
+	mu          sync.Mutex
+	maxStreams  int
+	streams     int
+	subscribers map[int64]map[chan *models.ContestLogEntry]struct{}
+}
+
+func NewContestLogHub(maxStreams int) *ContestLogHub {
+	return &ContestLogHub{
+		maxStreams:  maxStreams,
+		subscribers: make(map[int64]map[chan *models.ContestLogEntry]struct{}),
+	}
+}
+
+// Run delivers entries from source until it closes or ctx is done.
+func (lh *ContestLogHub) Run(ctx context.Context, source <-chan *models.ContestLogEntry) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok := <-source:
+			if !ok {
+				return
+			}
+			lh.publish(entry)
+		}
+	}
+}
+
+// Subscribe returns the log entries of one contest and a function that stops
+// delivery. It reports false when the node already serves maxStreams streams.
+func (lh *ContestLogHub) Subscribe(contestID int64) (<-chan *models.ContestLogEntry, func(), bool) {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+
+	if lh.streams >= lh.maxStreams {
+		return nil, nil, false
+	}
+	lh.streams++
+
+	ch := make(chan *models.ContestLogEntry, 64)
+	if lh.subscribers[contestID] == nil {
+		lh.subscribers[contestID] = make(map[chan *models.ContestLogEntry]struct{})
+	}
+	lh.subscribers[contestID][ch] = struct{}{}
+
+	return ch, func() {
+		lh.mu.Lock()
+		defer lh.mu.Unlock()
+		lh.streams--
+		delete(lh.subscribers[contestID], ch)
+		if len(lh.subscribers[contestID]) == 0 {
+			delete(lh.subscribers, contestID)
+		}
+	}, true
+}
+
+func (lh *ContestLogHub) publish(entry *models.ContestLogEntry) {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+
+	for ch := range lh.subscribers[entry.ContestID] {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
@@ -1,46 +1,228 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"execution_service/internal/cache"
 	"execution_service/internal/database"
 	"execution_service/internal/middleware"
 	"execution_service/internal/models"
+	"execution_service/internal/plagiarism"
 	"execution_service/internal/queue"
+	"execution_service/internal/rbac"
 	"execution_service/internal/services"
 	"execution_service/internal/storage"
 	"execution_service/internal/validation"
 	"execution_service/internal/worker"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
 )
 
 type Handler struct {
-	db       *database.DB
-	queue    *queue.RabbitMQClient
-	pool     *worker.JudgePool
-	storage  *storage.MinIOClient
-	security *middleware.SecurityMiddleware
-	audit    *services.AuditLogService
-	metrics  *services.MetricsService
+	db           *database.DB
+	queue        *queue.RabbitMQClient
+	pool         *worker.JudgePool
+	storage      *storage.MinIOClient
+	security     *middleware.SecurityMiddleware
+	audit        *services.AuditLogService
+	metrics      *services.MetricsService
+	submissions  *services.SubmissionService
+	cache        *cache.ValkeyClient
+	limits       *services.ResourceValidationService
+	events       *services.EventLogService
+	disk         *services.DiskWatcherService
+	spool        *services.CodeSpoolService
+	schema       *services.SchemaService
+	health       *services.HealthCheckService
+	signer       *services.VerdictSigningService
+	testsets     *services.TestsetService
+	difficulty   *services.DifficultyService
+	diagnostics  *services.DiagnosticsService
+	environment  *services.ContestEnvironmentService
+	shadow       *services.ShadowJudgingService
+	simulations  *services.LimitSimulationService
+	plagiarism   *plagiarism.PlagiarismDetector
+	timelines    *services.AttemptTimelineService
+	progress     *ProgressHub
+	contestLogs  *ContestLogHub
+	logTailRate  int
+	apiKeys      *services.APIKeyService
+	consistency  *services.ConsistencyService
+	rejudges     *services.RejudgeJobService
+	scoring      *services.ScoringService
+	userStats    *services.UserStatsService
+	invalidation *services.CacheInvalidationService
+	rbac         *rbac.RBACService
+	languages    *services.LanguageService
+	repositories *services.RepositoryIntakeService
+	deadLetters  *services.DeadLetterQueueService
+	logs         *services.ExecutionLogService
+	snapshots    *services.QueueSnapshotService
+	policies     *services.StoragePolicyService
 }
 
-func NewHandler(db *database.DB, q *queue.RabbitMQClient, p *worker.JudgePool, s *storage.MinIOClient, jwtSecret string) *Handler {
+func NewHandler(db *database.DB, q *queue.RabbitMQClient, p *worker.JudgePool, s *storage.MinIOClient, submissions *services.SubmissionService, jwtSecret string) *Handler {
 	securityMiddleware := middleware.NewSecurityMiddleware(jwtSecret)
 	auditService := services.NewAuditLogService(db)
 	metricsService := services.NewMetricsService()
-	return &Handler{
-		db:       db,
-		queue:    q,
-		pool:     p,
-		storage:  s,
-		security: securityMiddleware,
-		audit:    auditService,
-		metrics:  metricsService,
+	h := &Handler{
+		db:          db,
+		queue:       q,
+		pool:        p,
+		storage:     s,
+		security:    securityMiddleware,
+		audit:       auditService,
+		metrics:     metricsService,
+		submissions: submissions,
 	}
+	securityMiddleware.SetServiceScopeAuditor(h.AuditServiceScope)
+	return h
+}
+
+func (h *Handler) SetMetricsService(ms *services.MetricsService) {
+	h.metrics = ms
+}
+
+func (h *Handler) SetCache(c *cache.ValkeyClient) {
+	h.cache = c
+}
+
+func (h *Handler) SetResourceValidator(rv *services.ResourceValidationService) {
+	h.limits = rv
+}
+
+func (h *Handler) SetEventLog(es *services.EventLogService) {
+	h.events = es
+}
+
+func (h *Handler) SetDiskWatcher(dw *services.DiskWatcherService) {
+	h.disk = dw
+}
+
+func (h *Handler) SetSchemaService(ss *services.SchemaService) {
+	h.schema = ss
+}
+
+// SetHealthCheckService makes the health and readiness endpoints weigh each
+// dependency by its criticality.
+func (h *Handler) SetHealthCheckService(hs *services.HealthCheckService) {
+	h.health = hs
+}
+
+func (h *Handler) SetVerdictSigner(vs *services.VerdictSigningService) {
+	h.signer = vs
+}
+
+func (h *Handler) SetTestsetService(ts *services.TestsetService) {
+	h.testsets = ts
+}
+
+func (h *Handler) SetDifficultyService(ds *services.DifficultyService) {
+	h.difficulty = ds
+}
+
+func (h *Handler) SetDiagnosticsService(ds *services.DiagnosticsService) {
+	h.diagnostics = ds
+}
+
+func (h *Handler) SetContestEnvironmentService(es *services.ContestEnvironmentService) {
+	h.environment = es
+}
+
+func (h *Handler) SetShadowJudgingService(ss *services.ShadowJudgingService) {
+	h.shadow = ss
+}
+
+func (h *Handler) SetLimitSimulationService(ls *services.LimitSimulationService) {
+	h.simulations = ls
+}
+
+func (h *Handler) SetConsistencyService(cs *services.ConsistencyService) {
+	h.consistency = cs
+}
+
+func (h *Handler) SetRejudgeJobService(rs *services.RejudgeJobService) {
+	h.rejudges = rs
+}
+
+func (h *Handler) SetScoringService(ss *services.ScoringService) {
+	h.scoring = ss
+}
+
+func (h *Handler) SetUserStatsService(us *services.UserStatsService) {
+	h.userStats = us
+}
+
+func (h *Handler) SetCacheInvalidationService(cs *services.CacheInvalidationService) {
+	h.invalidation = cs
+}
+
+// SetRBACService checks permissions in handlers as well as in the route
+// middleware.
+func (h *Handler) SetRBACService(rs *rbac.RBACService) {
+	h.rbac = rs
+	h.security.SetRBACService(rs)
+}
+
+func (h *Handler) SetLanguageService(ls *services.LanguageService) {
+	h.languages = ls
+}
+
+func (h *Handler) SetRepositoryIntakeService(rs *services.RepositoryIntakeService) {
+	h.repositories = rs
+}
+
+func (h *Handler) SetDeadLetterQueueService(dlqs *services.DeadLetterQueueService) {
+	h.deadLetters = dlqs
+}
+
+func (h *Handler) SetExecutionLogService(ls *services.ExecutionLogService) {
+	h.logs = ls
+}
+
+func (h *Handler) SetQueueSnapshotService(qs *services.QueueSnapshotService) {
+	h.snapshots = qs
+}
+
+func (h *Handler) SetCodeSpool(cs *services.CodeSpoolService) {
+	h.spool = cs
+}
+
+func (h *Handler) SetStoragePolicyService(ps *services.StoragePolicyService) {
+	h.policies = ps
+}
+
+func (h *Handler) SetPlagiarismDetector(pd *plagiarism.PlagiarismDetector) {
+	h.plagiarism = pd
+}
+
+func (h *Handler) SetAttemptTimelineService(ts *services.AttemptTimelineService) {
+	h.timelines = ts
+}
+
+func (h *Handler) SetProgressHub(ph *ProgressHub) {
+	h.progress = ph
+}
+
+// SetContestLogHub enables proctor log streams, each capped at
+// eventsPerSecond entries.
+func (h *Handler) SetContestLogHub(lh *ContestLogHub, eventsPerSecond int) {
+	h.contestLogs = lh
+	h.logTailRate = eventsPerSecond
+}
+
+func (h *Handler) SetAPIKeyService(ks *services.APIKeyService) {
+	h.apiKeys = ks
 }
 
 func (h *Handler) RequireAuth() gin.HandlerFunc {
@@ -54,13 +236,52 @@ func (h *Handler) RequireAdmin() gin.HandlerFunc {
 func (h *Handler) RegisterRoutes(r *gin.Engine) {
 	api := r.Group("/api")
 	{
+		api.GET("/version", h.GetVersion)
+		api.GET("/events/schema", h.GetEventSchema)
+		api.GET("/events/stream", h.RequireAuth(), h.security.RequireAnyRole("admin", "super_admin", "judge"), h.StreamEvents)
+		api.GET("/problems/difficulty", h.GetProblemDifficulties)
+
 		submissions := api.Group("/submissions")
+		submissions.Use(h.security.OptionalAuth())
 		{
-			submissions.POST("", h.CreateSubmission)
-			submissions.GET("/:id", h.GetSubmission)
-			submissions.GET("/user/:userId", h.GetUserSubmissions)
+			submissions.POST("", h.security.AcceptAPIKey(models.APIKeyScopeSubmit), h.CreateSubmission)
+			submissions.POST("/repository", h.security.AcceptAPIKey(models.APIKeyScopeSubmit), h.CreateRepositorySubmission)
+			submissions.POST("/trusted", h.RequireAuth(), h.security.RequirePermission("submission", "trusted"), h.CreateTrustedSubmission)
+			submissions.GET("/:id", h.security.AcceptAPIKey(models.APIKeyScopeReadOwn), h.GetSubmission)
+			submissions.GET("/user/:userId", h.security.AcceptAPIKey(models.APIKeyScopeReadOwn), h.GetUserSubmissions)
 			submissions.GET("/problem/:problemId", h.GetProblemSubmissions)
+			submissions.GET("/team/:teamId", h.GetTeamSubmissions)
 			submissions.POST("/:id/rejudge", h.RejudgeSubmission)
+			submissions.POST("/problem/:problemId/rejudge", h.RequireAuth(), h.RequireAdmin(), h.RejudgeProblem)
+			submissions.GET("/:id/certificate", h.GetSubmissionCertificate)
+			submissions.GET("/:id/tests", h.security.AcceptAPIKey(models.APIKeyScopeReadOwn), h.GetSubmissionTests)
+			submissions.GET("/:id/results", h.security.AcceptAPIKey(models.APIKeyScopeReadOwn), h.GetSubmissionResults)
+			submissions.GET("/:id/verdict", h.security.AcceptAPIKey(models.APIKeyScopeReadOwn), h.GetStructuredVerdict)
+			submissions.GET("/:id/code", h.security.AcceptAPIKey(models.APIKeyScopeReadOwn), h.GetSubmissionCode)
+			submissions.GET("/:id/stream", h.security.AcceptAPIKey(models.APIKeyScopeReadOwn), h.StreamSubmission)
+			submissions.GET("/:id/events", h.security.AcceptAPIKey(models.APIKeyScopeReadOwn), h.StreamSubmissionEvents)
+		}
+
+		apiKeys := api.Group("/api-keys")
+		apiKeys.Use(h.RequireAuth())
+		{
+			apiKeys.POST("", h.CreateAPIKey)
+			apiKeys.GET("", h.ListAPIKeys)
+			apiKeys.DELETE("/:id", h.RevokeAPIKey)
+		}
+
+		users := api.Group("/users")
+		users.Use(h.security.OptionalAuth())
+		{
+			users.GET("/:id/problems/:pid/attempts", h.GetAttemptTimeline)
+			users.GET("/:id/problems/:pid/score", h.GetUserProblemScore)
+			users.GET("/:id/stats", h.GetUserStats)
+		}
+
+		certificates := api.Group("/certificates")
+		{
+			certificates.GET("/public-key", h.GetSigningPublicKey)
+			certificates.POST("/verify", h.VerifyCertificate)
 		}
 
 		judge := api.Group("/judge")
@@ -77,15 +298,85 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 			languages.GET("/:code", h.GetLanguage)
 		}
 
+		problems := api.Group("/problems")
+		problems.Use(h.RequireAuth())
+		{
+			problems.POST("/:problemId/sample-run", h.security.JWTRateLimit(10), h.SampleRun)
+		}
+
 		admin := api.Group("/admin")
 		admin.Use(h.RequireAuth())
 		admin.Use(h.RequireAdmin())
 		{
 			admin.POST("/clear-box/:id", h.ClearBox)
+			admin.GET("/box-pool", h.GetBoxPoolStats)
+			admin.GET("/box-pool/warm", h.GetWarmPools)
+			admin.PUT("/box-pool/warm/:language", h.ResizeWarmPool)
+			admin.POST("/box-pool/warm/:language/flush", h.FlushWarmPool)
+			admin.GET("/sandbox/capabilities", h.GetSandboxCapabilities)
+			admin.GET("/limits", h.GetPlatformLimits)
+			admin.PUT("/limits", h.UpdatePlatformLimits)
+			admin.GET("/problems/:problemId/limits", h.GetProblemLimits)
+			admin.PUT("/problems/:problemId/limits/override", h.SetProblemLimitOverride)
+			admin.DELETE("/problems/:problemId/limits/override", h.ClearProblemLimitOverride)
+			admin.GET("/problems/:problemId/outdated-submissions", h.GetOutdatedSubmissions)
+			admin.POST("/problems/:problemId/testset/refresh", h.RefreshTestset)
+			admin.POST("/problems/:problemId/validate-inputs", h.ValidateProblemInputs)
+			admin.POST("/problems/:problemId/simulate-limits", h.SimulateLimits)
+			admin.POST("/problems/:problemId/difficulty/recalculate", h.RecalculateDifficulty)
+			admin.GET("/events", h.ReplayEvents)
+			admin.POST("/submissions/:id/transfer", h.TransferSubmission)
+			admin.POST("/users/stats/repair", h.RepairUserStats)
+			admin.PUT("/submissions/:id/debug", h.SetSubmissionDebug)
+			admin.GET("/submissions/:id/similar", h.FindSimilarSubmissions)
+			admin.GET("/scaling-events", h.GetScalingEvents)
+			admin.PUT("/autoscaler/dry-run", h.SetAutoScaleDryRun)
+			admin.GET("/queue-snapshots", h.GetQueueSnapshots)
+			admin.GET("/storage/policies", h.GetStoragePolicies)
+			admin.GET("/storage/spool", h.GetSpoolStatus)
+			admin.POST("/diagnostics", h.CreateDiagnosticsBundle)
+			admin.GET("/environment", h.GetSandboxEnvironment)
+			admin.GET("/contests/:contestId/environment", h.GetContestEnvironment)
+			admin.PUT("/contests/:contestId/environment", h.PinContestEnvironment)
+			admin.DELETE("/contests/:contestId/environment", h.UnpinContestEnvironment)
+			admin.GET("/contests/:contestId/logs/stream", h.StreamContestLogs)
+			admin.GET("/problems/:problemId/shadow", h.GetShadowReport)
+			admin.PUT("/problems/:problemId/shadow", h.StartShadowJudging)
+			admin.DELETE("/problems/:problemId/shadow", h.StopShadowJudging)
+			admin.GET("/problems/:problemId/scoring", h.GetProblemScoring)
+			admin.PUT("/problems/:problemId/scoring", h.SetProblemScoring)
+			admin.GET("/problems/:problemId/scores", h.GetProblemScores)
+			admin.GET("/plagiarism/throttle", h.GetPlagiarismThrottle)
+			admin.GET("/plagiarism/reports", h.GetPlagiarismReports)
+			admin.GET("/plagiarism/:reportId", h.GetPlagiarismReport)
+			admin.GET("/plagiarism/:reportId/diff", h.GetPlagiarismDiff)
+			admin.PUT("/plagiarism/:reportId/review", h.ReviewPlagiarismReport)
+			admin.POST("/plagiarism/contest/:contestId/run", h.StartPlagiarismRun)
+			admin.GET("/plagiarism/runs/:id", h.GetPlagiarismRun)
+			admin.GET("/problems/:problemId/plagiarism/boilerplate", h.ListPlagiarismBoilerplate)
+			admin.POST("/problems/:problemId/plagiarism/boilerplate", h.AddPlagiarismBoilerplate)
+			admin.DELETE("/problems/:problemId/plagiarism/boilerplate/:id", h.RemovePlagiarismBoilerplate)
+			admin.GET("/dlq", h.InspectDeadLetters)
+			admin.POST("/dlq/requeue", h.RequeueDeadLetters)
+			admin.POST("/dlq/purge", h.PurgeDeadLetters)
+			admin.POST("/dlq/:id/replay", h.ReplayDeadLetter)
+			admin.PUT("/drain", h.SetNodeDrained)
+			admin.POST("/languages", h.CreateLanguage)
+			admin.PUT("/languages/:code", h.UpdateLanguage)
+			admin.DELETE("/languages/:code", h.DeleteLanguage)
+			admin.POST("/languages/refresh", h.RefreshLanguages)
+			admin.POST("/cache/invalidate", h.InvalidateCache)
+			admin.GET("/rejudge-jobs/:id", h.GetRejudgeJob)
+			admin.GET("/consistency", h.GetConsistencyReport)
+			admin.POST("/consistency/run", h.RunConsistencyCheck)
+			admin.GET("/canaries", h.ListCanaries)
+			admin.POST("/canaries", h.AddCanary)
+			admin.DELETE("/canaries/:id", h.RemoveCanary)
 		}
 	}
 
 	r.GET("/health", h.HealthCheck)
+	r.GET("/ready", h.ReadinessCheck)
 	r.GET("/metrics", h.Metrics)
 	r.GET("/circuit-breakers", h.CircuitBreakerStatus)
 	r.GET("/prometheus", h.PrometheusMetrics)
@@ -93,14 +384,28 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 }
 
 func (h *Handler) CreateSubmission(c *gin.Context) {
+	h.createSubmission(c, false)
+}
+
+// CreateTrustedSubmission lets setters judge their own solutions without the
+// static pattern checks, which reject constructs checkers and generators
+// legitimately use. Sandbox limits still apply and every use is audited.
+func (h *Handler) CreateTrustedSubmission(c *gin.Context) {
+	h.createSubmission(c, true)
+}
+
+func (h *Handler) createSubmission(c *gin.Context, trusted bool) {
 	var request struct {
 		UserID        int64  `json:"user_id" binding:"required,min=1"`
+		TeamID        *int64 `json:"team_id,omitempty"`
 		ProblemID     int64  `json:"problem_id" binding:"required,min=1"`
 		ContestID     *int64 `json:"contest_id,omitempty"`
 		Language      string `json:"language" binding:"required"`
 		Code          string `json:"code" binding:"required"`
 		TimeLimitMs   int    `json:"time_limit_ms,omitempty"`
 		MemoryLimitKb int    `json:"memory_limit_kb,omitempty"`
+		SchemaVersion int    `json:"schema_version,omitempty"`
+		models.SubmissionMetadata
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -108,187 +413,322 @@ func (h *Handler) CreateSubmission(c *gin.Context) {
 		return
 	}
 
-	// Validate language
-	if err := validation.ValidateLanguage(request.Language); err != nil {
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if idempotencyKey != "" {
+		if err := validation.ValidateIdempotencyKey(idempotencyKey); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	schemaVersion, err := validation.ValidateSubmissionSchema(request.SchemaVersion, &request.SubmissionMetadata)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	// Validate code
-	codeBytes := []byte(request.Code)
-	if err := validation.ValidateCode(codeBytes, request.Language); err != nil {
+	if err := validation.ValidateSubmissionMetadata(&request.SubmissionMetadata); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Set default limits if not provided
-	timeLimit := request.TimeLimitMs
-	if timeLimit <= 0 {
-		timeLimit = 2000 // Default 2 seconds
-	}
-	memoryLimit := request.MemoryLimitKb
-	if memoryLimit <= 0 {
-		memoryLimit = 262144 // Default 256MB
+	// Validate language
+	if err := h.validateLanguage(request.Language); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	// Validate limits
-	if timeLimit > 30000 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "time limit must be <= 30000ms"})
-		return
+	// Validate code
+	codeBytes, codeErr := validation.DecodeSubmissionCode(request.Code, request.Language)
+	if codeErr == nil {
+		if trusted && request.Language != models.LanguageOutputOnly {
+			codeErr = validation.ValidateCodeSize(codeBytes)
+		} else {
+			codeErr = validation.ValidateCode(codeBytes, request.Language)
+		}
 	}
-	if memoryLimit > 524288 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "memory limit must be <= 524288KB"})
+	if codeErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": codeErr.Error()})
 		return
 	}
 
-	// Create submission record
-	submission := &models.Submission{
-		UserID:          request.UserID,
-		ProblemID:       request.ProblemID,
-		ContestID:       request.ContestID,
-		Language:        request.Language,
-		Verdict:         models.VerdictPending,
-		Score:           0,
-		TestCasesPassed: 0,
-		IsPublic:        false,
+	serviceName, onBehalf, ok := h.authorizeSubmitter(c, request.UserID, request.TeamID)
+	if !ok {
+		return
 	}
 
-	// Upload code to storage
-	codeURL, err := h.storage.UploadCode(c.Request.Context(), submission.ID, request.Language, codeBytes)
+	timeLimit, memoryLimit, err := validation.ValidateSubmissionLimits(request.TimeLimitMs, request.MemoryLimitKb, h.platformLimits())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload code"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	submission.CodeURL = codeURL
 
-	// Save submission to database
-	err = h.db.CreateSubmission(c.Request.Context(), submission)
-	if err != nil {
+	submission := &models.Submission{
+		UserID:    request.UserID,
+		TeamID:    request.TeamID,
+		ProblemID: request.ProblemID,
+		ContestID: request.ContestID,
+		Language:  request.Language,
+		Metadata:  request.SubmissionMetadata,
+		Trusted:   trusted,
+	}
+	if idempotencyKey != "" {
+		replayed, err := h.submissions.CreateIdempotent(c.Request.Context(), submission, codeBytes, timeLimit, memoryLimit, idempotencyKey)
+		if errors.Is(err, services.ErrIdempotencyKeyReused) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to create submission: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create submission"})
+			return
+		}
+		if replayed {
+			c.Header("Idempotent-Replayed", "true")
+			c.JSON(http.StatusOK, withSchema(gin.H{
+				"submission_id": submission.ID,
+				"status":        "queued",
+				"message":       "Submission already created for this Idempotency-Key",
+			}, schemaVersion, submission))
+			return
+		}
+	} else if err := h.submissions.Create(c.Request.Context(), submission, codeBytes, timeLimit, memoryLimit); err != nil {
+		log.Printf("Failed to create submission: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create submission"})
 		return
 	}
 
-	// Determine priority based on contest
-	priority := 0 // Default practice priority
-	if request.ContestID != nil {
-		priority = 5 // Contest priority
+	if onBehalf {
+		h.logServiceScope(c, serviceName, middleware.ScopeSubmitOnBehalfOf, map[string]interface{}{
+			"submission_id": submission.ID,
+			"user_id":       request.UserID,
+			"team_id":       request.TeamID,
+			"problem_id":    request.ProblemID,
+		})
 	}
 
-	// Create judge request
-	judgeRequest := &models.JudgeRequest{
-		SubmissionID:  submission.ID,
-		UserID:        request.UserID,
-		ProblemID:     request.ProblemID,
-		Language:      request.Language,
-		CodeURL:       codeURL,
-		TimeLimitMs:   timeLimit,
-		MemoryLimitKb: memoryLimit,
-		Priority:      priority,
+	if trusted {
+		callerID, _ := callerUserID(c)
+		auditEvent := &services.AuditEvent{
+			UserID:     callerID,
+			Action:     services.AdminActionTrustedSubmission,
+			Resource:   "submission",
+			ResourceID: &submission.ID,
+			IPAddress:  c.ClientIP(),
+			UserAgent:  c.GetHeader("User-Agent"),
+			Details: map[string]interface{}{
+				"problem_id": request.ProblemID,
+				"user_id":    request.UserID,
+				"language":   request.Language,
+			},
+			Timestamp: time.Now(),
+			Severity:  services.SeverityWarning,
+		}
+		if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+			log.Printf("Failed to log trusted submission: %v", err)
+		}
 	}
 
-	// Validate judge request
-	if err := validation.ValidateJudgeRequest(judgeRequest); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	c.JSON(http.StatusCreated, withSchema(gin.H{
+		"submission_id": submission.ID,
+		"status":        "queued",
+		"message":       "Submission queued for judging",
+	}, schemaVersion, submission))
+}
+
+// withSchema adds the version 2 fields to a submission response.
+func withSchema(response gin.H, schemaVersion int, submission *models.Submission) gin.H {
+	if schemaVersion >= models.SubmissionSchemaV2 {
+		response["schema_version"] = schemaVersion
+		response["verdict"] = structuredVerdict(submission, nil)
 	}
+	return response
+}
 
-	// Publish to RabbitMQ
-	err = h.queue.PublishSubmission(c.Request.Context(), judgeRequest)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue submission"})
-		return
+func (h *Handler) platformLimits() *models.PlatformLimits {
+	if h.limits == nil {
+		return validation.DefaultPlatformLimits()
 	}
+	return h.limits.PlatformLimits()
+}
 
-	// Log submission creation
-	h.db.CreateExecutionLog(c.Request.Context(), &models.ExecutionLog{
-		SubmissionID: submission.ID,
-		Level:        "INFO",
-		Message:      fmt.Sprintf("Submission created for user %d, problem %d, language %s", request.UserID, request.ProblemID, request.Language),
-	})
+// authorizeSubmitter writes the error response and reports false when the
+// caller may not submit as userID and teamID. onBehalf is set for service
+// accounts with submit_on_behalf_of, which may submit for any user or team.
+func (h *Handler) authorizeSubmitter(c *gin.Context, userID int64, teamID *int64) (string, bool, bool) {
+	serviceName, onBehalf := middleware.ServiceScope(c, middleware.ScopeSubmitOnBehalfOf)
 
-	c.JSON(http.StatusCreated, gin.H{
-		"submission_id": submission.ID,
-		"status":        "queued",
-		"message":       "Submission queued for judging",
-	})
+	// Authenticated callers may only submit as themselves
+	if callerID, ok := callerUserID(c); ok && !onBehalf && callerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "user_id does not match token"})
+		return "", false, false
+	}
+
+	// Team submissions require a token proving membership
+	if teamID != nil && !onBehalf {
+		if _, ok := callerUserID(c); !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required for team submissions"})
+			return "", false, false
+		}
+		if !isTeamMember(c, *teamID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this team"})
+			return "", false, false
+		}
+	}
+
+	return serviceName, onBehalf, true
 }
 
-func (h *Handler) GetSubmission(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := validation.ValidateSubmissionID(idStr)
-	if err != nil {
+// CreateRepositorySubmission judges a whole project: the commit is fetched
+// from an allowlisted git host, checked against the intake limits, stored
+// as an archive and judged through the multi-file pipeline.
+func (h *Handler) CreateRepositorySubmission(c *gin.Context) {
+	var request struct {
+		UserID        int64  `json:"user_id" binding:"required,min=1"`
+		TeamID        *int64 `json:"team_id,omitempty"`
+		ProblemID     int64  `json:"problem_id" binding:"required,min=1"`
+		ContestID     *int64 `json:"contest_id,omitempty"`
+		Language      string `json:"language" binding:"required"`
+		RepositoryURL string `json:"repository_url" binding:"required"`
+		CommitSHA     string `json:"commit_sha" binding:"required"`
+		EntryFile     string `json:"entry_file,omitempty"`
+		TimeLimitMs   int    `json:"time_limit_ms,omitempty"`
+		MemoryLimitKb int    `json:"memory_limit_kb,omitempty"`
+		SchemaVersion int    `json:"schema_version,omitempty"`
+		models.SubmissionMetadata
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	submission, err := h.db.GetSubmission(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+	if h.repositories == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Repository submissions not available"})
 		return
 	}
 
-	c.JSON(http.StatusOK, submission)
-}
-
-func (h *Handler) GetUserSubmissions(c *gin.Context) {
-	userIDStr := c.Param("userId")
-	userID, err := validation.ValidateUserID(userIDStr)
+	schemaVersion, err := validation.ValidateSubmissionSchema(request.SchemaVersion, &request.SubmissionMetadata)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validation.ValidateSubmissionMetadata(&request.SubmissionMetadata); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.validateLanguage(request.Language); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entryFile, err := h.repositories.EntryFile(request.Language, request.EntryFile)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := h.repositories.Validate(request.RepositoryURL, request.CommitSHA); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	limitStr := c.Query("limit")
-	offsetStr := c.Query("offset")
-	limit, offset, err := validation.ValidatePagination(limitStr, offsetStr)
+	serviceName, onBehalf, ok := h.authorizeSubmitter(c, request.UserID, request.TeamID)
+	if !ok {
+		return
+	}
+
+	timeLimit, memoryLimit, err := validation.ValidateSubmissionLimits(request.TimeLimitMs, request.MemoryLimitKb, h.platformLimits())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	submissions, err := h.db.GetUserSubmissions(c.Request.Context(), userID, limit, offset)
+	project, err := h.repositories.Fetch(c.Request.Context(), request.RepositoryURL, request.CommitSHA, entryFile)
+	if errors.Is(err, services.ErrInvalidRepository) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get submissions"})
+		log.Printf("Failed to fetch %s at %s: %v", request.RepositoryURL, request.CommitSHA, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch repository"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"submissions": submissions,
-		"limit":       limit,
-		"offset":      offset,
-	})
+	submission := &models.Submission{
+		UserID:        request.UserID,
+		TeamID:        request.TeamID,
+		ProblemID:     request.ProblemID,
+		ContestID:     request.ContestID,
+		Language:      request.Language,
+		Metadata:      request.SubmissionMetadata,
+		RepositoryURL: &request.RepositoryURL,
+		CommitSHA:     &request.CommitSHA,
+		EntryFile:     &project.EntryFile,
+	}
+	if err := h.submissions.CreateProject(c.Request.Context(), submission, project.Archive, timeLimit, memoryLimit); err != nil {
+		log.Printf("Failed to create submission: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create submission"})
+		return
+	}
+
+	if onBehalf {
+		h.logServiceScope(c, serviceName, middleware.ScopeSubmitOnBehalfOf, map[string]interface{}{
+			"submission_id": submission.ID,
+			"user_id":       request.UserID,
+			"team_id":       request.TeamID,
+			"problem_id":    request.ProblemID,
+		})
+	}
+
+	c.JSON(http.StatusCreated, withSchema(gin.H{
+		"submission_id": submission.ID,
+		"status":        "queued",
+		"message":       "Submission queued for judging",
+		"file_count":    project.FileCount,
+		"size_bytes":    project.SizeBytes,
+	}, schemaVersion, submission))
 }
 
-func (h *Handler) GetProblemSubmissions(c *gin.Context) {
-	problemIDStr := c.Param("problemId")
-	problemID, err := validation.ValidateProblemID(problemIDStr)
+func (h *Handler) SampleRun(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	limitStr := c.Query("limit")
-	offsetStr := c.Query("offset")
-	limit, offset, err := validation.ValidatePagination(limitStr, offsetStr)
-	if err != nil {
+	var request struct {
+		Language string `json:"language" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.validateLanguage(request.Language); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Language == models.LanguageOutputOnly {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sample runs are not available for output-only submissions"})
+		return
+	}
+
+	codeBytes := []byte(request.Code)
+	if err := validation.ValidateCode(codeBytes, request.Language); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	submissions, err := h.db.GetProblemSubmissions(c.Request.Context(), problemID, limit, offset)
+	result, err := h.pool.RunSamples(c.Request.Context(), problemID, request.Language, codeBytes)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get submissions"})
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"submissions": submissions,
-		"limit":       limit,
-		"offset":      offset,
-	})
+	c.JSON(http.StatusOK, result)
 }
 
-func (h *Handler) RejudgeSubmission(c *gin.Context) {
+func (h *Handler) GetSubmission(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := validation.ValidateSubmissionID(idStr)
 	if err != nil {
@@ -302,222 +742,3566 @@ func (h *Handler) RejudgeSubmission(c *gin.Context) {
 		return
 	}
 
-	// Get user info for audit logging
-	userIDValue, _ := c.Get("user_id")
-	var userID int64
-	if v, ok := userIDValue.(float64); ok {
-		userID = int64(v)
+	// Team submissions are only visible to team members and admins
+	if submission.TeamID != nil && !isTeamMember(c, *submission.TeamID) && !isAdminRole(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this team"})
+		return
+	}
+	if apiKeyReadsOther(c, submission.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys may only read their owner's submissions"})
+		return
 	}
 
-	request := &models.JudgeRequest{
-		SubmissionID:  id,
-		UserID:        submission.UserID,
-		ProblemID:     submission.ProblemID,
-		Language:      submission.Language,
-		CodeURL:       submission.CodeURL,
-		TimeLimitMs:   2000,
-		MemoryLimitKb: 262144,
-		Priority:      5,
+	c.JSON(http.StatusOK, submission)
+}
+
+// StreamSubmission pushes judging progress over a WebSocket; see
+// followSubmission for what is sent.
+func (h *Handler) StreamSubmission(c *gin.Context) {
+	id, ok := h.streamableSubmission(c)
+	if !ok {
+		return
 	}
 
-	// Log admin action before execution
-	auditEvent := &services.AuditEvent{
-		UserID:     userID,
-		Action:     services.AdminActionSubmissionRejudge,
-		Resource:   "submission",
-		ResourceID: &id,
-		IPAddress:  c.ClientIP(),
-		UserAgent:  c.GetHeader("User-Agent"),
-		Details: map[string]interface{}{
-			"submission_id": id,
-			"problem_id":    submission.ProblemID,
-			"user_id":       submission.UserID,
-			"language":      submission.Language,
+	// Clients authenticate with a bearer header, which browsers cannot send
+	// cross-site, so the default origin check is not needed
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		// The stream outlives the server's read and write timeouts
+		ws.SetDeadline(time.Time{})
+
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		go func() {
+			// Clients send nothing; a failed read means they went away
+			var discard string
+			for websocket.Message.Receive(ws, &discard) == nil {
+			}
+			cancel()
+		}()
+
+		h.followSubmission(ctx, id, func(progress *models.SubmissionProgress) bool {
+			return websocket.JSON.Send(ws, progress) == nil
+		}, nil)
+	}}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// StreamSubmissionEvents is the Server-Sent Events fallback for clients that
+// cannot use WebSockets. Each step is an event named after its stage.
+// Clients should close the stream on the completed event, since EventSource
+// otherwise reconnects and receives it again.
+func (h *Handler) StreamSubmissionEvents(c *gin.Context) {
+	id, ok := h.streamableSubmission(c)
+	if !ok {
+		return
+	}
+
+	// The stream outlives the server write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	c.Writer.Flush()
+
+	seq := 0
+	h.followSubmission(c.Request.Context(), id, func(progress *models.SubmissionProgress) bool {
+		data, err := json.Marshal(progress)
+		if err != nil {
+			return false
+		}
+		seq++
+		if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", seq, progress.Stage, data); err != nil {
+			return false
+		}
+		c.Writer.Flush()
+		return true
+	}, func() bool {
+		if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+			return false
+		}
+		c.Writer.Flush()
+		return true
+	})
+}
+
+// StreamContestLogs tails the WARN and ERROR execution logs of a contest's
+// submissions as server-sent events. Entries over the stream's rate cap are
+// dropped and counted in a "dropped" event.
+func (h *Handler) StreamContestLogs(c *gin.Context) {
+	if h.contestLogs == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Contest log streaming not available"})
+		return
+	}
+
+	contestID, err := validation.ValidateContestID(c.Param("contestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, unsubscribe, ok := h.contestLogs.Subscribe(contestID)
+	if !ok {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many contest log streams on this node"})
+		return
+	}
+	defer unsubscribe()
+
+	// The stream outlives the server write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(5 * time.Second)
+	defer heartbeat.Stop()
+
+	seq, sent, dropped := 0, 0, 0
+	window := time.Now()
+	for {
+		var err error
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if dropped > 0 {
+				seq++
+				_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: dropped\ndata: {\"dropped\":%d}\n\n", seq, dropped)
+				dropped = 0
+			} else {
+				_, err = fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			}
+		case entry := <-entries:
+			if time.Since(window) >= time.Second {
+				window = time.Now()
+				sent = 0
+			}
+			if sent >= h.logTailRate {
+				dropped++
+				continue
+			}
+			sent++
+
+			data, marshalErr := json.Marshal(entry)
+			if marshalErr != nil {
+				continue
+			}
+			seq++
+			_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: log\ndata: %s\n\n", seq, data)
+		}
+		if err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// streamableSubmission checks the caller may watch the submission, writing
+// the error response when not.
+func (h *Handler) streamableSubmission(c *gin.Context) (int64, bool) {
+	if h.progress == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Submission streaming not available"})
+		return 0, false
+	}
+
+	id, err := validation.ValidateSubmissionID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, false
+	}
+
+	submission, err := h.db.GetSubmission(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return 0, false
+	}
+
+	if submission.TeamID != nil && !isTeamMember(c, *submission.TeamID) && !isAdminRole(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this team"})
+		return 0, false
+	}
+	if apiKeyReadsOther(c, submission.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys may only read their owner's submissions"})
+		return 0, false
+	}
+
+	return id, true
+}
+
+// followSubmission sends a submission's progress until it is judged, send
+// fails or ctx is done. The first step is the current state and the last is
+// the completed step carrying the stored submission. Steps lost in transit
+// are covered by re-reading the submission every few seconds, when idle is
+// also called if set.
+func (h *Handler) followSubmission(ctx context.Context, id int64, send func(*models.SubmissionProgress) bool, idle func() bool) {
+	// Subscribing first means a verdict stored meanwhile is seen below
+	updates, unsubscribe := h.progress.Subscribe(id)
+	defer unsubscribe()
+
+	complete := func() bool {
+		current, err := h.db.GetSubmission(ctx, id)
+		if err != nil || current.Verdict == models.VerdictPending {
+			return false
+		}
+		send(&models.SubmissionProgress{
+			SubmissionID: id,
+			Stage:        models.ProgressCompleted,
+			TestsDone:    current.TestCasesPassed,
+			Submission:   current,
+			Timestamp:    time.Now(),
+		})
+		return true
+	}
+
+	if complete() {
+		return
+	}
+	if !send(&models.SubmissionProgress{SubmissionID: id, Stage: models.ProgressQueued, Timestamp: time.Now()}) {
+		return
+	}
+
+	poll := time.NewTicker(5 * time.Second)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			if complete() {
+				return
+			}
+			if idle != nil && !idle() {
+				return
+			}
+		case progress := <-updates:
+			if progress.Stage == models.ProgressCompleted {
+				if complete() {
+					return
+				}
+				continue
+			}
+			if !send(progress) {
+				return
+			}
+		}
+	}
+}
+
+// GetSubmissionTests returns per-test results. Program stderr is shown to
+// the submitter where the problem's policy allows it, sandbox usage where the
+// usage report is public, and checker output only to admins; all are always
+// shown to admins.
+func (h *Handler) GetSubmissionTests(c *gin.Context) {
+	submission, results, ok := h.submissionTestResults(c)
+	if !ok {
+		return
+	}
+
+	admin := isAdminRole(c)
+	owner := ownsSubmission(c, submission)
+	if !admin {
+		for i := range results {
+			results[i].CheckerOutput = nil
+			if !owner || !results[i].StderrVisible {
+				results[i].Stderr = nil
+			}
+			if !owner || !results[i].UsageVisible {
+				results[i].Usage = nil
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"submission_id": submission.ID,
+		"tests":         results,
+	})
+}
+
+// GetSubmissionResults returns the per-test verdict breakdown. Admins see
+// every field; others get a redacted view where checker output is shown only
+// on sample tests, and only to the submitter.
+func (h *Handler) GetSubmissionResults(c *gin.Context) {
+	submission, results, ok := h.submissionTestResults(c)
+	if !ok {
+		return
+	}
+
+	admin := isAdminRole(c)
+	owner := ownsSubmission(c, submission)
+	if !admin {
+		for i := range results {
+			if !owner || !results[i].IsSample {
+				results[i].CheckerOutput = nil
+			}
+			if !owner || !results[i].StderrVisible {
+				results[i].Stderr = nil
+			}
+			if !owner || !results[i].UsageVisible {
+				results[i].Usage = nil
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"submission_id": submission.ID,
+		"verdict":       submission.Verdict,
+		"redacted":      !admin,
+		"results":       results,
+	})
+}
+
+// GetSubmissionCode streams a submission's code from storage. Submitters
+// read their own code with submission read:own; anyone else needs read:any
+// or an admin role, and each such read is audited.
+func (h *Handler) GetSubmissionCode(c *gin.Context) {
+	id, err := validation.ValidateSubmissionID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	callerID, ok := callerUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	submission, err := h.db.GetSubmission(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+	if apiKeyReadsOther(c, submission.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys may only read their owner's submissions"})
+		return
+	}
+
+	owner := ownsSubmission(c, submission)
+	action := "read:own"
+	if !owner {
+		action = "read:any"
+	}
+	admin := isAdminRole(c)
+	allowed := admin
+	switch {
+	case admin:
+	case h.rbac != nil:
+		allowed, err = h.rbac.CheckPermission(callerID, "submission", action)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+	default:
+		allowed = owner
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to read this submission's code"})
+		return
+	}
+
+	if services.IsSpooled(submission.CodeURL) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Submission code is not in storage yet"})
+		return
+	}
+	code, size, err := h.storage.OpenCode(c.Request.Context(), submission.CodeURL)
+	if err != nil {
+		log.Printf("Failed to open code of submission %d: %v", id, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read submission code"})
+		return
+	}
+	defer code.Close()
+
+	if admin || !owner {
+		auditEvent := &services.AuditEvent{
+			UserID:     callerID,
+			Action:     services.AdminActionSubmissionCodeRead,
+			Resource:   "submission",
+			ResourceID: &submission.ID,
+			IPAddress:  c.ClientIP(),
+			UserAgent:  c.GetHeader("User-Agent"),
+			Details: map[string]interface{}{
+				"owner_id":   submission.UserID,
+				"problem_id": submission.ProblemID,
+			},
+			Timestamp: time.Now(),
+			Severity:  services.SeverityInfo,
+		}
+		if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+			log.Printf("Failed to log admin action: %v", err)
+		}
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	switch {
+	case submission.EntryFile != nil:
+		contentType = "application/gzip"
+	case submission.Language == models.LanguageOutputOnly:
+		contentType = "application/zip"
+	}
+	c.Header("Cache-Control", "no-store")
+	c.DataFromReader(http.StatusOK, size, contentType, code, nil)
+}
+
+// GetStructuredVerdict returns the version 2 verdict of a submission.
+func (h *Handler) GetStructuredVerdict(c *gin.Context) {
+	submission, results, ok := h.submissionTestResults(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, structuredVerdict(submission, results))
+}
+
+// structuredVerdict groups results, ordered by test number, into ranges of
+// consecutive tests with the same verdict.
+func structuredVerdict(submission *models.Submission, results []models.SubmissionTestResult) *models.StructuredVerdict {
+	verdict := &models.StructuredVerdict{
+		SchemaVersion: models.SubmissionSchemaV2,
+		SubmissionID:  submission.ID,
+		Verdict:       submission.Verdict,
+		Final:         submission.Verdict != models.VerdictPending,
+		Score:         submission.Score,
+		TestsPassed:   submission.TestCasesPassed,
+		TestsTotal:    submission.TestCasesTotal,
+		CompileOutput: submission.CompileOutput,
+		Ranges:        []models.VerdictRange{},
+	}
+
+	for _, result := range results {
+		timeMs, memoryKb := 0, 0
+		if result.ExecutionTimeMs != nil {
+			timeMs = *result.ExecutionTimeMs
+		}
+		if result.MemoryUsedKb != nil {
+			memoryKb = *result.MemoryUsedKb
+		}
+
+		last := len(verdict.Ranges) - 1
+		if last >= 0 && verdict.Ranges[last].Verdict == result.Verdict && verdict.Ranges[last].LastTest+1 == result.TestNumber {
+			current := &verdict.Ranges[last]
+			current.LastTest = result.TestNumber
+			current.MaxTimeMs = max(current.MaxTimeMs, timeMs)
+			current.MaxMemoryKb = max(current.MaxMemoryKb, memoryKb)
+			continue
+		}
+		verdict.Ranges = append(verdict.Ranges, models.VerdictRange{
+			FirstTest:   result.TestNumber,
+			LastTest:    result.TestNumber,
+			Verdict:     result.Verdict,
+			MaxTimeMs:   timeMs,
+			MaxMemoryKb: memoryKb,
+		})
+	}
+	return verdict
+}
+
+// submissionTestResults loads a submission's test results for a caller
+// allowed to read them, writing the error response when not.
+func (h *Handler) submissionTestResults(c *gin.Context) (*models.Submission, []models.SubmissionTestResult, bool) {
+	id, err := validation.ValidateSubmissionID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+
+	submission, err := h.db.GetSubmission(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return nil, nil, false
+	}
+
+	if submission.TeamID != nil && !isTeamMember(c, *submission.TeamID) && !isAdminRole(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this team"})
+		return nil, nil, false
+	}
+	if apiKeyReadsOther(c, submission.UserID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys may only read their owner's submissions"})
+		return nil, nil, false
+	}
+
+	results, err := h.db.GetSubmissionTestResults(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get test results"})
+		return nil, nil, false
+	}
+	return submission, results, true
+}
+
+// ownsSubmission reports whether the caller submitted it, alone or as a
+// member of its team.
+func ownsSubmission(c *gin.Context, submission *models.Submission) bool {
+	callerID, _ := callerUserID(c)
+	return callerID == submission.UserID || (submission.TeamID != nil && isTeamMember(c, *submission.TeamID))
+}
+
+func (h *Handler) GetSubmissionCertificate(c *gin.Context) {
+	if h.signer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Verdict signing not enabled"})
+		return
+	}
+
+	id, err := validation.ValidateSubmissionID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	submission, err := h.db.GetSubmission(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	if submission.TeamID != nil && !isTeamMember(c, *submission.TeamID) && !isAdminRole(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this team"})
+		return
+	}
+
+	signature, err := h.signer.GetSignature(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Certificate not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, signature)
+}
+
+func (h *Handler) GetSigningPublicKey(c *gin.Context) {
+	if h.signer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Verdict signing not enabled"})
+		return
+	}
+
+	key, err := h.signer.PublicKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+func (h *Handler) VerifyCertificate(c *gin.Context) {
+	if h.signer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Verdict signing not enabled"})
+		return
+	}
+
+	var request struct {
+		Payload   string `json:"payload" binding:"required"`
+		Signature string `json:"signature" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	valid, err := h.signer.Verify(request.Payload, request.Signature)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"valid": valid}
+	if valid {
+		var certificate models.VerdictCertificate
+		if err := json.Unmarshal([]byte(request.Payload), &certificate); err == nil {
+			response["certificate"] = certificate
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) GetUserSubmissions(c *gin.Context) {
+	userIDStr := c.Param("userId")
+	userID, err := validation.ValidateUserID(userIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if apiKeyReadsOther(c, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys may only read their owner's submissions"})
+		return
+	}
+
+	limitStr := c.Query("limit")
+	offsetStr := c.Query("offset")
+	limit, offset, err := validation.ValidatePagination(limitStr, offsetStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags := c.QueryArray("tag")
+	for _, tag := range tags {
+		if err := validation.ValidateTag(tag); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	submissions, err := h.db.GetUserSubmissions(c.Request.Context(), userID, tags, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get submissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"submissions": submissions,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+// GetAttemptTimeline returns the user's attempts at a problem compacted into
+// runs of identical outcomes.
+func (h *Handler) GetAttemptTimeline(c *gin.Context) {
+	userID, err := validation.ValidateUserID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	problemID, err := validation.ValidateProblemID(c.Param("pid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.timelines == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Attempt timelines not available"})
+		return
+	}
+
+	timeline, err := h.timelines.Get(c.Request.Context(), userID, problemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get attempt timeline"})
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+func (h *Handler) GetUserProblemScore(c *gin.Context) {
+	userID, err := validation.ValidateUserID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	problemID, err := validation.ValidateProblemID(c.Param("pid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.scoring == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Scoring not available"})
+		return
+	}
+
+	score, err := h.scoring.UserScore(c.Request.Context(), userID, problemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get score"})
+		return
+	}
+	if score == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No judged submissions for this problem"})
+		return
+	}
+
+	c.JSON(http.StatusOK, score)
+}
+
+func (h *Handler) GetUserStats(c *gin.Context) {
+	userID, err := validation.ValidateUserID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.userStats == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "User stats not available"})
+		return
+	}
+
+	stats, err := h.userStats.Get(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// RepairUserStats recounts the per-user verdict counters now instead of
+// waiting for the periodic repair.
+func (h *Handler) RepairUserStats(c *gin.Context) {
+	if h.userStats == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "User stats not available"})
+		return
+	}
+
+	repaired, err := h.userStats.Repair(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to repair user stats"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    services.AdminActionUserStatsRepair,
+		Resource:  "user_stats",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"repaired": repaired,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"repaired": repaired})
+}
+
+func (h *Handler) GetTeamSubmissions(c *gin.Context) {
+	teamID, err := validation.ValidateTeamID(c.Param("teamId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isTeamMember(c, teamID) && !isAdminRole(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this team"})
+		return
+	}
+
+	limit, offset, err := validation.ValidatePagination(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags := c.QueryArray("tag")
+	for _, tag := range tags {
+		if err := validation.ValidateTag(tag); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	submissions, err := h.db.GetTeamSubmissions(c.Request.Context(), teamID, tags, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get submissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"submissions": submissions,
+		"team_id":     teamID,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+func (h *Handler) GetProblemSubmissions(c *gin.Context) {
+	problemIDStr := c.Param("problemId")
+	problemID, err := validation.ValidateProblemID(problemIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limitStr := c.Query("limit")
+	offsetStr := c.Query("offset")
+	limit, offset, err := validation.ValidatePagination(limitStr, offsetStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags := c.QueryArray("tag")
+	for _, tag := range tags {
+		if err := validation.ValidateTag(tag); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	submissions, err := h.db.GetProblemSubmissions(c.Request.Context(), problemID, tags, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get submissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"submissions": submissions,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+func (h *Handler) RejudgeSubmission(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := validation.ValidateSubmissionID(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	submission, err := h.db.GetSubmission(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	// Get user info for audit logging
+	userIDValue, _ := c.Get("user_id")
+	var userID int64
+	if v, ok := userIDValue.(float64); ok {
+		userID = int64(v)
+	}
+
+	// Log admin action before execution
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionSubmissionRejudge,
+		Resource:   "submission",
+		ResourceID: &id,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"submission_id": id,
+			"problem_id":    submission.ProblemID,
+			"user_id":       submission.UserID,
+			"language":      submission.Language,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		// Log error but don't fail the request
+		fmt.Printf("Failed to log admin action: %v\n", err)
+	}
+
+	err = h.submissions.Rejudge(c.Request.Context(), submission, 5)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue rejudge"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rejudge queued"})
+}
+
+// RejudgeProblem starts a background job that requeues the problem's
+// submissions, optionally filtered by verdict and submission time. An empty
+// body rejudges every submission.
+func (h *Handler) RejudgeProblem(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request struct {
+		Verdicts        []models.Verdict `json:"verdicts" binding:"omitempty,dive,oneof=pending AC WA TLE ILE MLE RE CE IE"`
+		SubmittedAfter  *time.Time       `json:"submitted_after"`
+		SubmittedBefore *time.Time       `json:"submitted_before"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.SubmittedAfter != nil && request.SubmittedBefore != nil && !request.SubmittedAfter.Before(*request.SubmittedBefore) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "submitted_after must be before submitted_before"})
+		return
+	}
+
+	if h.rejudges == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Bulk rejudging not available"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	job := &models.RejudgeJob{
+		ProblemID:       problemID,
+		Verdicts:        request.Verdicts,
+		SubmittedAfter:  request.SubmittedAfter,
+		SubmittedBefore: request.SubmittedBefore,
+		CreatedBy:       userID,
+	}
+	if err := h.rejudges.Create(c.Request.Context(), job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionProblemRejudge,
+		Resource:   "problem",
+		ResourceID: &problemID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"job_id":           job.ID,
+			"total":            job.Total,
+			"verdicts":         job.Verdicts,
+			"submitted_after":  job.SubmittedAfter,
+			"submitted_before": job.SubmittedBefore,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *Handler) GetRejudgeJob(c *gin.Context) {
+	jobID, err := validation.ValidateRejudgeJobID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.rejudges == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Bulk rejudging not available"})
+		return
+	}
+
+	job, err := h.rejudges.Get(c.Request.Context(), jobID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rejudge job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *Handler) GetJudgeStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	if h.cache != nil {
+		if status, err := h.cache.GetCachedJudgeStatus(ctx); err == nil {
+			h.metrics.RecordCacheHit("judge_status")
+			c.JSON(http.StatusOK, status)
+			return
+		}
+		h.metrics.RecordCacheMiss("judge_status")
+	}
+
+	status := h.pool.GetStatus()
+	if h.cache != nil {
+		if err := h.cache.CacheJudgeStatus(ctx, status); err != nil {
+			log.Printf("Failed to cache judge status: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *Handler) GetWorkers(c *gin.Context) {
+	status := h.pool.GetStatus()
+	c.JSON(http.StatusOK, gin.H{
+		"workers": status,
+	})
+}
+
+func (h *Handler) ScaleWorkers(c *gin.Context) {
+	var request struct {
+		WorkerCount int `json:"worker_count" binding:"required,min=1,max=50"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get user info for audit logging
+	userIDValue, _ := c.Get("user_id")
+	var userID int64
+	if v, ok := userIDValue.(float64); ok {
+		userID = int64(v)
+	}
+
+	// Get current status
+	currentStatus := h.pool.GetStatus()
+	currentWorkers := currentStatus["total_workers"].(int)
+
+	if request.WorkerCount == currentWorkers {
+		c.JSON(http.StatusOK, gin.H{
+			"message":           "No scaling needed",
+			"current_workers":   currentWorkers,
+			"requested_workers": request.WorkerCount,
+		})
+		return
+	}
+
+	// Log admin action before execution
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    services.AdminActionWorkerScale,
+		Resource:  "judge_workers",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"previous_count": currentWorkers,
+			"new_count":      request.WorkerCount,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		// Log error but don't fail the request
+		fmt.Printf("Failed to log admin action: %v\n", err)
+	}
+
+	// Perform scaling operation
+	err := h.pool.ScaleWorkers(request.WorkerCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":             fmt.Sprintf("Failed to scale workers: %v", err),
+			"current_workers":   currentWorkers,
+			"requested_workers": request.WorkerCount,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Worker scaling completed",
+		"previous_workers": currentWorkers,
+		"current_workers":  request.WorkerCount,
+	})
+}
+
+func (h *Handler) GetScalingEvents(c *gin.Context) {
+	limit, offset, err := validation.ValidatePagination(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	events, err := h.db.GetScalingEvents(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get scaling events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":  events,
+		"dry_run": h.pool.IsAutoScaleDryRun(),
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// GetQueueSnapshots returns the queue snapshots taken within ?window=
+// (default 10m) either side of ?at= (RFC 3339, default now), optionally for
+// one ?node=.
+func (h *Handler) GetQueueSnapshots(c *gin.Context) {
+	at := time.Now()
+	if value := c.Query("at"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid at timestamp (must be RFC 3339)"})
+			return
+		}
+		at = parsed
+	}
+
+	window, err := time.ParseDuration(c.DefaultQuery("window", "10m"))
+	if err != nil || window <= 0 || window > 24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid window (must be a duration up to 24h)"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit (must be 1-1000)"})
+		return
+	}
+
+	if h.snapshots == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Queue snapshots not available"})
+		return
+	}
+
+	snapshots, err := h.snapshots.Around(c.Request.Context(), at, window, c.Query("node"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get queue snapshots"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"snapshots": snapshots,
+		"at":        at,
+		"window":    window.String(),
+		"has_more":  len(snapshots) == limit,
+	})
+}
+
+func (h *Handler) SetAutoScaleDryRun(c *gin.Context) {
+	var request struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    services.AdminActionSystemConfig,
+		Resource:  "autoscaler",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"dry_run": *request.Enabled,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	h.pool.SetAutoScaleDryRun(*request.Enabled)
+
+	c.JSON(http.StatusOK, gin.H{"dry_run": *request.Enabled})
+}
+
+func (h *Handler) GetQueueStatus(c *gin.Context) {
+	queueSize, err := h.queue.GetQueueInfo()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get queue info"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"queue_size": queueSize,
+		"is_healthy": h.queue.IsHealthy(),
+	})
+}
+
+func (h *Handler) GetLanguages(c *gin.Context) {
+	ctx := c.Request.Context()
+	if h.cache != nil {
+		if languages, err := h.cache.GetCachedLanguageList(ctx); err == nil {
+			h.metrics.RecordCacheHit("language_list")
+			c.JSON(http.StatusOK, gin.H{"languages": languages})
+			return
+		}
+		h.metrics.RecordCacheMiss("language_list")
+	}
+
+	languages, err := h.db.GetSupportedLanguages(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get languages"})
+		return
+	}
+
+	if h.cache != nil {
+		if err := h.cache.CacheLanguageList(ctx, languages); err != nil {
+			log.Printf("Failed to cache language list: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"languages": languages})
+}
+
+func (h *Handler) GetLanguage(c *gin.Context) {
+	code := c.Param("code")
+	if err := h.validateLanguage(code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	language, err := h.db.GetLanguage(c.Request.Context(), code)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Language not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, language)
+}
+
+func (h *Handler) ClearBox(c *gin.Context) {
+	idStr := c.Param("id")
+	boxID, err := strconv.Atoi(idStr)
+	if err != nil || boxID < 0 || boxID > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid box ID (must be 0-1000)"})
+		return
+	}
+
+	// Get user info for audit logging
+	userIDValue, _ := c.Get("user_id")
+	var userID int64
+	if v, ok := userIDValue.(float64); ok {
+		userID = int64(v)
+	}
+
+	isolateSandbox := h.pool.GetSandbox()
+	if isolateSandbox == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sandbox not available"})
+		return
+	}
+
+	// Log admin action before execution
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionBoxCleanup,
+		Resource:   "sandbox_box",
+		ResourceID: &[]int64{int64(boxID)}[0],
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"box_id": boxID,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		// Log error but don't fail the request
+		fmt.Printf("Failed to log admin action: %v\n", err)
+	}
+
+	isolateSandbox.CleanupBox(boxID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Box %d cleaned up successfully", boxID),
+		"box_id":  boxID,
+	})
+}
+
+func (h *Handler) GetPlatformLimits(c *gin.Context) {
+	c.JSON(http.StatusOK, h.platformLimits())
+}
+
+// UpdatePlatformLimits replaces the platform-wide default and maximum
+// limits. Other nodes pick them up within a minute.
+func (h *Handler) UpdatePlatformLimits(c *gin.Context) {
+	var request struct {
+		DefaultTimeLimitMs   int `json:"default_time_limit_ms" binding:"required,min=1"`
+		DefaultMemoryLimitKb int `json:"default_memory_limit_kb" binding:"required,min=1"`
+		MaxTimeLimitMs       int `json:"max_time_limit_ms" binding:"required,min=1"`
+		MaxMemoryLimitKb     int `json:"max_memory_limit_kb" binding:"required,min=1"`
+		MaxStackSizeKb       int `json:"max_stack_size_kb" binding:"required,min=1"`
+		MaxOutputSizeKb      int `json:"max_output_size_kb" binding:"required,min=1"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.limits == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Resource validation not available"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	previous := h.limits.PlatformLimits()
+	limits := &models.PlatformLimits{
+		DefaultTimeLimitMs:   request.DefaultTimeLimitMs,
+		DefaultMemoryLimitKb: request.DefaultMemoryLimitKb,
+		MaxTimeLimitMs:       request.MaxTimeLimitMs,
+		MaxMemoryLimitKb:     request.MaxMemoryLimitKb,
+		MaxStackSizeKb:       request.MaxStackSizeKb,
+		MaxOutputSizeKb:      request.MaxOutputSizeKb,
+		UpdatedBy:            &userID,
+	}
+
+	if err := h.limits.UpdatePlatformLimits(c.Request.Context(), limits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    services.AdminActionPlatformLimits,
+		Resource:  "platform_limits",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"previous": previous,
+			"limits":   limits,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityWarning,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, limits)
+}
+
+func (h *Handler) GetProblemLimits(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.limits == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Resource validation not available"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.limits.GetEffectiveLimits(c.Request.Context(), problemID))
+}
+
+func (h *Handler) SetProblemLimitOverride(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request struct {
+		TimeLimitMs   int    `json:"time_limit_ms" binding:"required,min=1"`
+		MemoryLimitKb int    `json:"memory_limit_kb" binding:"required,min=1"`
+		TTLMinutes    int    `json:"ttl_minutes" binding:"required,min=1,max=10080"`
+		Reason        string `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.limits == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Resource validation not available"})
+		return
+	}
+
+	userIDValue, _ := c.Get("user_id")
+	var userID int64
+	if v, ok := userIDValue.(float64); ok {
+		userID = int64(v)
+	}
+
+	override := &models.LimitOverride{
+		ProblemID:     problemID,
+		TimeLimitMs:   request.TimeLimitMs,
+		MemoryLimitKb: request.MemoryLimitKb,
+		Reason:        request.Reason,
+		SetBy:         userID,
+		ExpiresAt:     time.Now().Add(time.Duration(request.TTLMinutes) * time.Minute),
+	}
+
+	if err := h.limits.SetLimitOverride(c.Request.Context(), override); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionLimitOverride,
+		Resource:   "problem_limits",
+		ResourceID: &problemID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"time_limit_ms":   override.TimeLimitMs,
+			"memory_limit_kb": override.MemoryLimitKb,
+			"expires_at":      override.ExpiresAt,
+			"reason":          override.Reason,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityWarning,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, override)
+}
+
+func (h *Handler) GetOutdatedSubmissions(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "500"))
+	if err != nil || limit < 1 || limit > 5000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit (must be 1-5000)"})
+		return
+	}
+
+	if h.testsets == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Testset tracking not available"})
+		return
+	}
+
+	ids, err := h.testsets.GetOutdatedSubmissions(c.Request.Context(), problemID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get outdated submissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"problem_id":     problemID,
+		"submission_ids": ids,
+		"has_more":       len(ids) == limit,
+	})
+}
+
+func (h *Handler) RefreshTestset(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.testsets == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Testset tracking not available"})
+		return
+	}
+
+	version, flagged, err := h.testsets.Refresh(c.Request.Context(), problemID)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"problem_id":      problemID,
+		"testset_version": version,
+		"flagged":         flagged,
+	})
+}
+
+// ValidateProblemInputs runs the problem's input validator, or the one given
+// as validator_url, over every test input and reports the inputs it rejects.
+func (h *Handler) ValidateProblemInputs(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request struct {
+		ValidatorURL string `json:"validator_url"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.pool.ValidateInputs(c.Request.Context(), problemID, request.ValidatorURL)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionInputValidation,
+		Resource:   "problem",
+		ResourceID: &problemID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"validator_url": request.ValidatorURL,
+			"valid":         report.Valid,
+			"tests":         len(report.Tests),
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// CreateDiagnosticsBundle gathers a support bundle into MinIO and returns a
+// short-lived download link. ?failed_limit= caps the failed submissions whose
+// execution logs are included (default 20, max 100).
+func (h *Handler) CreateDiagnosticsBundle(c *gin.Context) {
+	if h.diagnostics == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Diagnostics not available"})
+		return
+	}
+
+	failedLimit := 20
+	if raw := c.Query("failed_limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 || parsed > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed_limit must be between 0 and 100"})
+			return
+		}
+		failedLimit = parsed
+	}
+
+	userID, _ := callerUserID(c)
+
+	bundle, err := h.diagnostics.Generate(c.Request.Context(), userID, failedLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    services.AdminActionDiagnostics,
+		Resource:  "diagnostics",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"object_url":   bundle.ObjectURL,
+			"failed_limit": failedLimit,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusCreated, bundle)
+}
+
+// GetSandboxEnvironment returns this node's toolchain snapshot.
+func (h *Handler) GetSandboxCapabilities(c *gin.Context) {
+	isolateSandbox := h.pool.GetSandbox()
+	if isolateSandbox == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sandbox not available"})
+		return
+	}
+
+	capabilities := isolateSandbox.Capabilities()
+	if c.Query("probe") == "devices" {
+		capabilities.Devices = isolateSandbox.ProbeDevices(c.Request.Context())
+	}
+	c.JSON(http.StatusOK, capabilities)
+}
+
+func (h *Handler) GetBoxPoolStats(c *gin.Context) {
+	isolateSandbox := h.pool.GetSandbox()
+	if isolateSandbox == nil || isolateSandbox.BoxPool() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Box pool not available"})
+		return
+	}
+
+	c.JSON(http.StatusOK, isolateSandbox.BoxPool().Stats())
+}
+
+// GetStoragePolicies reports each bucket's encryption, retention and
+// lifecycle status as of the last policy check.
+func (h *Handler) GetStoragePolicies(c *gin.Context) {
+	if h.policies == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Storage policies not enabled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"buckets": h.policies.Status()})
+}
+
+func (h *Handler) GetSpoolStatus(c *gin.Context) {
+	if h.spool == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Submission spooling not enabled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.spool.Status())
+}
+
+func (h *Handler) GetWarmPools(c *gin.Context) {
+	isolateSandbox := h.pool.GetSandbox()
+	if isolateSandbox == nil || isolateSandbox.BoxPool() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Box pool not available"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pools": isolateSandbox.BoxPool().WarmStats()})
+}
+
+// ResizeWarmPool sets how many pooled boxes are kept warm for a language,
+// such as to pre-warm Java boxes before a contest starts.
+func (h *Handler) ResizeWarmPool(c *gin.Context) {
+	isolateSandbox := h.pool.GetSandbox()
+	if isolateSandbox == nil || isolateSandbox.BoxPool() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Box pool not available"})
+		return
+	}
+
+	var request struct {
+		Size *int `json:"size" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	language := c.Param("language")
+	if err := isolateSandbox.BoxPool().ResizeWarm(language, *request.Size); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    services.AdminActionWarmPoolResize,
+		Resource:  "warm_pool",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"language": language,
+			"size":     *request.Size,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"language": language, "size": *request.Size})
+}
+
+// FlushWarmPool re-initializes a language's warm boxes and warms them again.
+func (h *Handler) FlushWarmPool(c *gin.Context) {
+	isolateSandbox := h.pool.GetSandbox()
+	if isolateSandbox == nil || isolateSandbox.BoxPool() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Box pool not available"})
+		return
+	}
+
+	language := c.Param("language")
+	if err := isolateSandbox.BoxPool().FlushWarm(language); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    services.AdminActionWarmPoolFlush,
+		Resource:  "warm_pool",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"language": language,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"language": language, "flushed": true})
+}
+
+func (h *Handler) GetSandboxEnvironment(c *gin.Context) {
+	if h.environment == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Contest environments not available"})
+		return
+	}
+
+	env := h.environment.Current()
+	if env == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sandbox environment snapshot not captured"})
+		return
+	}
+
+	c.JSON(http.StatusOK, env)
+}
+
+func (h *Handler) GetContestEnvironment(c *gin.Context) {
+	contestID, err := validation.ValidateContestID(c.Param("contestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.environment == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Contest environments not available"})
+		return
+	}
+
+	pin, err := h.environment.Get(c.Request.Context(), contestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if pin == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contest environment not pinned"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pin":               pin,
+		"available_on_node": h.environment.Available(pin),
+	})
+}
+
+// PinContestEnvironment pins the contest to this node's current toolchain
+// snapshot, replacing any earlier pin. Call it at contest start.
+func (h *Handler) PinContestEnvironment(c *gin.Context) {
+	contestID, err := validation.ValidateContestID(c.Param("contestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.environment == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Contest environments not available"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+
+	pin, err := h.environment.Pin(c.Request.Context(), contestID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionEnvironmentPin,
+		Resource:   "contest_environment",
+		ResourceID: &contestID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"toolchain_id": pin.Environment.ToolchainID,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, pin)
+}
+
+func (h *Handler) UnpinContestEnvironment(c *gin.Context) {
+	contestID, err := validation.ValidateContestID(c.Param("contestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.environment == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Contest environments not available"})
+		return
+	}
+
+	removed, err := h.environment.Unpin(c.Request.Context(), contestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contest environment not pinned"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionEnvironmentUnpin,
+		Resource:   "contest_environment",
+		ResourceID: &contestID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Timestamp:  time.Now(),
+		Severity:   services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Contest environment unpinned",
+		"contest_id": contestID,
+	})
+}
+
+// GetShadowReport compares live and shadow verdicts for the problem's most
+// recent shadow config.
+func (h *Handler) GetShadowReport(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, _, err := validation.ValidatePagination(c.Query("limit"), "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.shadow == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Shadow judging not available"})
+		return
+	}
+
+	report, err := h.shadow.Report(c.Request.Context(), problemID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Problem has no shadow config"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// StartShadowJudging judges new submissions to the problem a second time
+// with the given checker or limits. It replaces any active shadow config.
+func (h *Handler) StartShadowJudging(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request struct {
+		CheckerURL    *string `json:"checker_url"`
+		TimeLimitMs   *int    `json:"time_limit_ms" binding:"omitempty,min=1"`
+		MemoryLimitKb *int    `json:"memory_limit_kb" binding:"omitempty,min=1"`
+		Note          string  `json:"note"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.CheckerURL == nil && request.TimeLimitMs == nil && request.MemoryLimitKb == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one of checker_url, time_limit_ms or memory_limit_kb is required"})
+		return
+	}
+
+	if h.shadow == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Shadow judging not available"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+
+	config := &models.ShadowConfig{
+		ProblemID:     problemID,
+		CheckerURL:    request.CheckerURL,
+		TimeLimitMs:   request.TimeLimitMs,
+		MemoryLimitKb: request.MemoryLimitKb,
+		Note:          request.Note,
+		CreatedBy:     userID,
+	}
+	if err := h.shadow.Start(c.Request.Context(), config); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionShadowStart,
+		Resource:   "shadow_config",
+		ResourceID: &problemID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"config_id": config.ID,
+			"note":      config.Note,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// SimulateLimits reports how the problem's recent accepted submissions would
+// fare under candidate limits, re-judging the slowest few when asked to.
+func (h *Handler) SimulateLimits(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request struct {
+		TimeLimitMs   int `json:"time_limit_ms" binding:"required,min=1"`
+		MemoryLimitKb int `json:"memory_limit_kb" binding:"required,min=1"`
+		Limit         int `json:"limit" binding:"omitempty,min=1,max=1000"`
+		RerunSample   int `json:"rerun_sample" binding:"omitempty,min=0"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.RerunSample > services.MaxLimitRerunSample {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("rerun_sample must be at most %d", services.MaxLimitRerunSample)})
+		return
+	}
+	if request.Limit == 0 {
+		request.Limit = 200
+	}
+
+	if h.simulations == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Limit simulation not available"})
+		return
+	}
+
+	simulation, err := h.simulations.Simulate(c.Request.Context(), problemID, request.TimeLimitMs, request.MemoryLimitKb, request.Limit, request.RerunSample)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, simulation)
+}
+
+func (h *Handler) GetProblemScoring(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.scoring == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Scoring not available"})
+		return
+	}
+
+	scoring, err := h.scoring.Aggregation(c.Request.Context(), problemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, scoring)
+}
+
+func (h *Handler) SetProblemScoring(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request struct {
+		Aggregation string `json:"aggregation" binding:"required,oneof=sum max last"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.scoring == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Scoring not available"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+
+	scoring := &models.ProblemScoring{
+		ProblemID:   problemID,
+		Aggregation: request.Aggregation,
+		UpdatedBy:   &userID,
+	}
+	recomputed, err := h.scoring.SetAggregation(c.Request.Context(), scoring)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionScoringUpdate,
+		Resource:   "problem_scoring",
+		ResourceID: &problemID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"aggregation": scoring.Aggregation,
+			"recomputed":  recomputed,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"scoring":    scoring,
+		"recomputed": recomputed,
+	})
+}
+
+func (h *Handler) GetProblemScores(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, offset, err := validation.ValidatePagination(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.scoring == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Scoring not available"})
+		return
+	}
+
+	scores, err := h.scoring.ProblemScores(c.Request.Context(), problemID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get scores"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"problem_id": problemID,
+		"scores":     scores,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
+
+func (h *Handler) StopShadowJudging(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.shadow == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Shadow judging not available"})
+		return
+	}
+
+	stopped, err := h.shadow.Stop(c.Request.Context(), problemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !stopped {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Problem has no active shadow config"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionShadowStop,
+		Resource:   "shadow_config",
+		ResourceID: &problemID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Timestamp:  time.Now(),
+		Severity:   services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Shadow judging stopped",
+		"problem_id": problemID,
+	})
+}
+
+// GetPlagiarismThrottle reports whether plagiarism detection is backing off
+// for judge load.
+func (h *Handler) GetPlagiarismThrottle(c *gin.Context) {
+	if h.plagiarism == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Plagiarism detection not available"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.plagiarism.ThrottleStatus())
+}
+
+// GetPlagiarismReports lists reports, only those with the given status when
+// one is set, such as status=pending for the review queue.
+func (h *Handler) GetPlagiarismReports(c *gin.Context) {
+	limit, offset, err := validation.ValidatePagination(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reports, err := h.db.GetPlagiarismReports(c.Request.Context(), c.Query("status"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get plagiarism reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports": reports,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// StartPlagiarismRun queues a run that cross-compares the contest's accepted
+// submissions problem by problem. The optional body sets the similarity
+// threshold for reported pairs, defaulting to the detector's threshold.
+func (h *Handler) StartPlagiarismRun(c *gin.Context) {
+	contestID, err := validation.ValidateContestID(c.Param("contestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request struct {
+		Threshold float64 `json:"threshold" binding:"omitempty,gt=0,lte=1"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.plagiarism == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Plagiarism detection not available"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	run := &models.PlagiarismRun{
+		ContestID: contestID,
+		Threshold: request.Threshold,
+		CreatedBy: userID,
+	}
+	if err := h.plagiarism.CreateContestRun(c.Request.Context(), run); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create plagiarism run"})
+		return
+	}
+
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionPlagiarismRun,
+		Resource:   "contest",
+		ResourceID: &contestID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"run_id":    run.ID,
+			"threshold": run.Threshold,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusAccepted, run)
+}
+
+// GetPlagiarismRun reports a run's progress and, once it completes, its
+// suspicious pairs, most similar first.
+func (h *Handler) GetPlagiarismRun(c *gin.Context) {
+	runID, err := validation.ValidatePlagiarismRunID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.plagiarism == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Plagiarism detection not available"})
+		return
+	}
+
+	run, err := h.plagiarism.GetContestRun(c.Request.Context(), runID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get plagiarism run"})
+		return
+	}
+	if run == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plagiarism run not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+func (h *Handler) ListPlagiarismBoilerplate(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.plagiarism == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Plagiarism detection not available"})
+		return
+	}
+
+	snippets, err := h.plagiarism.ListBoilerplate(c.Request.Context(), problemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get plagiarism boilerplate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"problem_id":  problemID,
+		"boilerplate": snippets,
+	})
+}
+
+// AddPlagiarismBoilerplate registers starter code to strip from the
+// problem's submissions before comparing them. Without a language the
+// snippet applies to every language.
+func (h *Handler) AddPlagiarismBoilerplate(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request struct {
+		Language *string `json:"language"`
+		Code     string  `json:"code" binding:"required,max=65536"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Language != nil {
+		if err := validation.ValidateLanguageFormat(*request.Language); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if h.plagiarism == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Plagiarism detection not available"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	snippet := &models.PlagiarismBoilerplate{
+		ProblemID: problemID,
+		Language:  request.Language,
+		Code:      request.Code,
+		CreatedBy: userID,
+	}
+	if err := h.plagiarism.AddBoilerplate(c.Request.Context(), snippet); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionBoilerplateAdd,
+		Resource:   "plagiarism_boilerplate",
+		ResourceID: &snippet.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"problem_id": problemID,
+			"language":   request.Language,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusCreated, snippet)
+}
+
+func (h *Handler) RemovePlagiarismBoilerplate(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	snippetID, err := validation.ValidateBoilerplateID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.plagiarism == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Plagiarism detection not available"})
+		return
+	}
+
+	removed, err := h.plagiarism.RemoveBoilerplate(c.Request.Context(), problemID, snippetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Boilerplate not found"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionBoilerplateRemove,
+		Resource:   "plagiarism_boilerplate",
+		ResourceID: &snippetID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"problem_id": problemID,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Boilerplate removed",
+		"boilerplate_id": snippetID,
+	})
+}
+
+// GetPlagiarismReport returns a report with both of its submissions.
+func (h *Handler) GetPlagiarismReport(c *gin.Context) {
+	reportID, err := validation.ValidateReportID(c.Param("reportId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.db.GetPlagiarismReport(c.Request.Context(), reportID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plagiarism report not found"})
+		return
+	}
+
+	submission1, err := h.db.GetSubmission(c.Request.Context(), report.Submission1ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get submission"})
+		return
+	}
+	submission2, err := h.db.GetSubmission(c.Request.Context(), report.Submission2ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get submission"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report":      report,
+		"submission1": submission1,
+		"submission2": submission2,
+	})
+}
+
+// ReviewPlagiarismReport confirms or dismisses a pending report. A
+// confirmation is published as a PlagiarismConfirmed event.
+func (h *Handler) ReviewPlagiarismReport(c *gin.Context) {
+	if h.plagiarism == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Plagiarism detection not available"})
+		return
+	}
+
+	reportID, err := validation.ValidateReportID(c.Param("reportId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request struct {
+		Status string `json:"status" binding:"required,oneof=confirmed dismissed"`
+		Notes  string `json:"notes" binding:"max=2000"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := h.db.GetPlagiarismReport(c.Request.Context(), reportID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plagiarism report not found"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+
+	report, err := h.plagiarism.ReviewReport(c.Request.Context(), reportID, request.Status, userID, request.Notes)
+	if errors.Is(err, plagiarism.ErrReportReviewed) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to review plagiarism report %d: %v", reportID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review plagiarism report"})
+		return
+	}
+
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionPlagiarismReview,
+		Resource:   "plagiarism_report",
+		ResourceID: &reportID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"status": request.Status,
+			"notes":  request.Notes,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetPlagiarismDiff shows the two submissions of a plagiarism report side by
+// side, with the lines the flagging algorithm matched highlighted.
+func (h *Handler) GetPlagiarismDiff(c *gin.Context) {
+	if h.plagiarism == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Plagiarism detection not available"})
+		return
+	}
+
+	reportID, err := validation.ValidateReportID(c.Param("reportId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.db.GetPlagiarismReport(c.Request.Context(), reportID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plagiarism report not found"})
+		return
+	}
+
+	diff, err := h.plagiarism.DiffReport(c.Request.Context(), report)
+	if errors.Is(err, plagiarism.ErrUnsupportedSimilarity) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to diff plagiarism report %d: %v", reportID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to diff plagiarism report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// FindSimilarSubmissions ranks other submissions to the same problem by
+// similarity, within the problem or the submission's contest.
+func (h *Handler) FindSimilarSubmissions(c *gin.Context) {
+	if h.plagiarism == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Plagiarism detection not available"})
+		return
+	}
+
+	submissionID, err := validation.ValidateSubmissionID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit, _, err := validation.ValidatePagination(c.DefaultQuery("limit", "10"), "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	scope := plagiarism.SimilarityScope(c.DefaultQuery("scope", string(plagiarism.ScopeProblem)))
+
+	submission, err := h.db.GetSubmission(c.Request.Context(), submissionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	similar, err := h.plagiarism.FindSimilar(c.Request.Context(), submission, scope, limit)
+	if errors.Is(err, plagiarism.ErrUnsupportedSimilarity) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to find submissions similar to %d: %v", submissionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find similar submissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"submission_id": submissionID,
+		"scope":         scope,
+		"similar":       similar,
+	})
+}
+
+// SetSubmissionDebug keeps every execution log of the submission, bypassing
+// sampling, for its next judging or rejudge.
+func (h *Handler) SetSubmissionDebug(c *gin.Context) {
+	submissionID, err := validation.ValidateSubmissionID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.logs == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Execution log sampling not available"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	found, err := h.logs.SetDebug(c.Request.Context(), submissionID, *request.Enabled, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionSubmissionDebug,
+		Resource:   "submission",
+		ResourceID: &submissionID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"enabled": *request.Enabled,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"submission_id": submissionID,
+		"debug":         *request.Enabled,
+	})
+}
+
+// InspectDeadLetters lists messages at the head of the dead letter queue
+// without removing them.
+func (h *Handler) InspectDeadLetters(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit (must be 1-1000)"})
+		return
+	}
+
+	if h.deadLetters == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Dead letter queue not available"})
+		return
+	}
+
+	entries, err := h.deadLetters.Inspect(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": entries,
+		"has_more": len(entries) == limit,
+	})
+}
+
+func (h *Handler) RequeueDeadLetters(c *gin.Context) {
+	var request struct {
+		SubmissionIDs []int64 `json:"submission_ids" binding:"omitempty,dive,min=1"`
+		Limit         int     `json:"limit" binding:"omitempty,min=1,max=1000"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Limit == 0 {
+		request.Limit = 100
+	}
+
+	if h.deadLetters == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Dead letter queue not available"})
+		return
+	}
+
+	requeued, err := h.deadLetters.Requeue(c.Request.Context(), request.SubmissionIDs, request.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    services.AdminActionDLQRequeue,
+		Resource:  "dead_letter_queue",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"requested":      request.SubmissionIDs,
+			"submission_ids": requeued,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityWarning,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submission_ids": requeued})
+}
+
+// ReplayDeadLetter sends one parked submission back to the judge queue with
+// a fresh attempt count.
+func (h *Handler) ReplayDeadLetter(c *gin.Context) {
+	id, err := validation.ValidateSubmissionID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.deadLetters == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Dead letter queue not available"})
+		return
+	}
+
+	// Look as deep into the queue as the listing endpoint can
+	requeued, err := h.deadLetters.Requeue(c.Request.Context(), []int64{id}, 1000)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(requeued) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not in dead letter queue"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionDLQReplay,
+		Resource:   "dead_letter_queue",
+		ResourceID: &id,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Timestamp:  time.Now(),
+		Severity:   services.SeverityWarning,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submission_id": id, "replayed": len(requeued)})
+}
+
+// PurgeDeadLetters drops the parked submissions matching the request, unlike
+// PurgeDLQ which empties the queue.
+func (h *Handler) PurgeDeadLetters(c *gin.Context) {
+	var request struct {
+		SubmissionIDs []int64 `json:"submission_ids" binding:"omitempty,dive,min=1"`
+		ProblemID     int64   `json:"problem_id" binding:"omitempty,min=1"`
+		Limit         int     `json:"limit" binding:"omitempty,min=1,max=1000"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(request.SubmissionIDs) == 0 && request.ProblemID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "submission_ids or problem_id is required"})
+		return
+	}
+	if request.Limit == 0 {
+		request.Limit = 100
+	}
+
+	if h.deadLetters == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Dead letter queue not available"})
+		return
+	}
+
+	discarded, err := h.deadLetters.Discard(c.Request.Context(), request.SubmissionIDs, request.ProblemID, request.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    services.AdminActionDLQPurge,
+		Resource:  "dead_letter_queue",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"requested":      request.SubmissionIDs,
+			"problem_id":     request.ProblemID,
+			"submission_ids": discarded,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityWarning,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submission_ids": discarded})
+}
+
+// SetNodeDrained stops or resumes judging on the node serving the request.
+func (h *Handler) SetNodeDrained(c *gin.Context) {
+	var request struct {
+		Drained *bool `json:"drained" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    services.AdminActionNodeDrain,
+		Resource:  "judge_node",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"node":    h.pool.NodeName(),
+			"drained": *request.Drained,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityWarning,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	h.pool.SetDrained(*request.Drained)
+
+	c.JSON(http.StatusOK, gin.H{
+		"node":    h.pool.NodeName(),
+		"drained": h.pool.IsDrained(),
+	})
+}
+
+// RefreshLanguages reloads supported_languages on this node; other nodes pick
+// the change up on their next periodic refresh.
+func (h *Handler) RefreshLanguages(c *gin.Context) {
+	if h.languages == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Language service not available"})
+		return
+	}
+
+	languages, err := h.languages.Refresh(c.Request.Context())
+	if err != nil {
+		log.Printf("Failed to refresh languages: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh languages"})
+		return
+	}
+
+	codes := make([]string, 0, len(languages))
+	for _, language := range languages {
+		codes = append(codes, language.LanguageCode)
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    services.AdminActionLanguageRefresh,
+		Resource:  "languages",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"node":      h.pool.NodeName(),
+			"languages": codes,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"languages": languages})
+}
+
+// CreateLanguage adds a language. Multipliers default to 1 and the language
+// starts enabled unless the request says otherwise.
+func (h *Handler) CreateLanguage(c *gin.Context) {
+	var request struct {
+		LanguageCode string `json:"language_code" binding:"required"`
+		models.LanguageSettings
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.languages == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Language service not available"})
+		return
+	}
+
+	language := &models.SupportedLanguage{
+		LanguageCode:     request.LanguageCode,
+		TimeMultiplier:   1,
+		MemoryMultiplier: 1,
+		IsEnabled:        true,
+	}
+	request.Apply(language)
+
+	err := h.languages.CreateLanguage(c.Request.Context(), language)
+	if errors.Is(err, services.ErrInvalidLanguage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrLanguageExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Language already exists"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to create language %s: %v", language.LanguageCode, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create language"})
+		return
+	}
+
+	h.auditLanguageChange(c, services.AdminActionLanguageCreate, language)
+	c.JSON(http.StatusCreated, language)
+}
+
+// UpdateLanguage changes the fields present in the request; setting
+// is_enabled to false disables the language without deleting it.
+func (h *Handler) UpdateLanguage(c *gin.Context) {
+	code := c.Param("code")
+	if err := validation.ValidateLanguageFormat(code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request models.LanguageSettings
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.languages == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Language service not available"})
+		return
+	}
+
+	language, err := h.db.GetLanguageByCode(c.Request.Context(), code)
+	if err != nil {
+		log.Printf("Failed to get language %s: %v", code, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get language"})
+		return
+	}
+	if language == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Language not found"})
+		return
+	}
+	request.Apply(language)
+
+	err = h.languages.UpdateLanguage(c.Request.Context(), language)
+	if errors.Is(err, services.ErrInvalidLanguage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrLanguageNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Language not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to update language %s: %v", code, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update language"})
+		return
+	}
+
+	h.auditLanguageChange(c, services.AdminActionLanguageUpdate, language)
+	c.JSON(http.StatusOK, language)
+}
+
+func (h *Handler) DeleteLanguage(c *gin.Context) {
+	code := c.Param("code")
+	if err := validation.ValidateLanguageFormat(code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.languages == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Language service not available"})
+		return
+	}
+
+	err := h.languages.DeleteLanguage(c.Request.Context(), code)
+	if errors.Is(err, services.ErrLanguageNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Language not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete language %s: %v", code, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete language"})
+		return
+	}
+
+	h.auditLanguageChange(c, services.AdminActionLanguageDelete, &models.SupportedLanguage{LanguageCode: code})
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Language deleted",
+		"language_code": code,
+	})
+}
+
+func (h *Handler) auditLanguageChange(c *gin.Context, action string, language *models.SupportedLanguage) {
+	userID, _ := callerUserID(c)
+	details := map[string]interface{}{"language_code": language.LanguageCode}
+	if action != services.AdminActionLanguageDelete {
+		details["is_enabled"] = language.IsEnabled
+		details["time_multiplier"] = language.TimeMultiplier
+		details["memory_multiplier"] = language.MemoryMultiplier
+	}
+
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    action,
+		Resource:  "language",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details:   details,
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+}
+
+// InvalidateCache drops cached entries ahead of their TTL. Typed targets,
+// such as problem:123 or user-perms:42, are also dropped from every judge
+// node's memory.
+func (h *Handler) InvalidateCache(c *gin.Context) {
+	var request struct {
+		SubmissionIDs []int64  `json:"submission_ids" binding:"omitempty,dive,min=1"`
+		ProblemIDs    []int64  `json:"problem_ids" binding:"omitempty,dive,min=1"`
+		Languages     []string `json:"languages"`
+		JudgeStatus   bool     `json:"judge_status"`
+		Targets       []string `json:"targets"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, language := range request.Languages {
+		if err := validation.ValidateLanguageFormat(language); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	targets := make([]models.CacheTarget, 0, len(request.Targets))
+	for _, raw := range request.Targets {
+		target, err := validation.ValidateCacheTarget(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		targets = append(targets, target)
+	}
+
+	if h.cache == nil || (len(targets) > 0 && h.invalidation == nil) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cache not available"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var errs []string
+	record := func(err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	for _, id := range request.SubmissionIDs {
+		record(h.cache.InvalidateSubmission(ctx, id))
+	}
+	for _, id := range request.ProblemIDs {
+		record(h.cache.InvalidateProblem(ctx, id))
+	}
+	for _, language := range request.Languages {
+		record(h.cache.InvalidateLanguage(ctx, language))
+	}
+	if len(request.Languages) > 0 {
+		record(h.cache.InvalidateLanguageList(ctx))
+	}
+	if request.JudgeStatus {
+		record(h.cache.InvalidateJudgeStatus(ctx))
+	}
+	if len(targets) > 0 {
+		record(h.invalidation.Invalidate(ctx, targets))
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    services.AdminActionCacheInvalidate,
+		Resource:  "cache",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"submission_ids": request.SubmissionIDs,
+			"problem_ids":    request.ProblemIDs,
+			"languages":      request.Languages,
+			"judge_status":   request.JudgeStatus,
+			"targets":        request.Targets,
 		},
 		Timestamp: time.Now(),
 		Severity:  services.SeverityInfo,
 	}
 
+	if err := h.audit.LogAdminAction(ctx, auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	if len(errs) > 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invalidate some entries", "details": errs})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invalidated": true})
+}
+
+// GetConsistencyReport compares the latest canary runs of every judge node.
+func (h *Handler) GetConsistencyReport(c *gin.Context) {
+	if h.consistency == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Consistency checking not available"})
+		return
+	}
+
+	report, err := h.consistency.Report(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RunConsistencyCheck judges the canaries on the node serving the request.
+// Other nodes are covered by their own schedules.
+func (h *Handler) RunConsistencyCheck(c *gin.Context) {
+	if h.consistency == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Consistency checking not available"})
+		return
+	}
+
+	report, err := h.consistency.RunOnce(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:    userID,
+		Action:    services.AdminActionConsistencyRun,
+		Resource:  "canary",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
 	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
-		// Log error but don't fail the request
-		fmt.Printf("Failed to log admin action: %v\n", err)
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *Handler) ListCanaries(c *gin.Context) {
+	if h.consistency == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Consistency checking not available"})
+		return
 	}
 
-	err = h.queue.PublishSubmission(c.Request.Context(), request)
+	canaries, err := h.consistency.ListCanaries(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue rejudge"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Rejudge queued"})
+	c.JSON(http.StatusOK, gin.H{"canaries": canaries})
 }
 
-func (h *Handler) GetJudgeStatus(c *gin.Context) {
-	status := h.pool.GetStatus()
-	c.JSON(http.StatusOK, status)
+// AddCanary adds a judged submission to the canary set. The expected
+// verdict defaults to the submission's own.
+func (h *Handler) AddCanary(c *gin.Context) {
+	var request struct {
+		SubmissionID    int64          `json:"submission_id" binding:"required,min=1"`
+		ExpectedVerdict models.Verdict `json:"expected_verdict" binding:"omitempty,oneof=AC WA TLE ILE MLE RE CE IE"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.consistency == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Consistency checking not available"})
+		return
+	}
+
+	submission, err := h.db.GetSubmission(c.Request.Context(), request.SubmissionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
+
+	expected := request.ExpectedVerdict
+	if expected == "" {
+		expected = submission.Verdict
+	}
+	if expected == models.VerdictPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Submission has not been judged yet"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	canary := &models.Canary{
+		SubmissionID:    submission.ID,
+		ExpectedVerdict: expected,
+		CreatedBy:       userID,
+	}
+	if err := h.consistency.AddCanary(c.Request.Context(), canary); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionCanaryAdd,
+		Resource:   "canary",
+		ResourceID: &canary.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"submission_id":    canary.SubmissionID,
+			"expected_verdict": canary.ExpectedVerdict,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusCreated, canary)
 }
 
-func (h *Handler) GetWorkers(c *gin.Context) {
-	status := h.pool.GetStatus()
+func (h *Handler) RemoveCanary(c *gin.Context) {
+	canaryID, err := validation.ValidateCanaryID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.consistency == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Consistency checking not available"})
+		return
+	}
+
+	removed, err := h.consistency.RemoveCanary(c.Request.Context(), canaryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Canary not found"})
+		return
+	}
+
+	userID, _ := callerUserID(c)
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionCanaryRemove,
+		Resource:   "canary",
+		ResourceID: &canaryID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Timestamp:  time.Now(),
+		Severity:   services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"workers": status,
+		"message":   "Canary removed",
+		"canary_id": canaryID,
 	})
 }
 
-func (h *Handler) ScaleWorkers(c *gin.Context) {
-	var request struct {
-		WorkerCount int `json:"worker_count" binding:"required,min=1,max=50"`
+// GetProblemDifficulties returns stored estimates for ?ids=1,2,3 (up to 200).
+func (h *Handler) GetProblemDifficulties(c *gin.Context) {
+	if h.difficulty == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Difficulty estimation not available"})
+		return
 	}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
+	var problemIDs []int64
+	for _, raw := range strings.Split(c.Query("ids"), ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		id, err := validation.ValidateProblemID(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		problemIDs = append(problemIDs, id)
+	}
+	if len(problemIDs) == 0 || len(problemIDs) > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must list 1-200 problem IDs"})
+		return
+	}
+
+	difficulties, err := h.difficulty.GetDifficulties(c.Request.Context(), problemIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get difficulties"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"difficulties": difficulties})
+}
+
+func (h *Handler) RecalculateDifficulty(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get user info for audit logging
+	if h.difficulty == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Difficulty estimation not available"})
+		return
+	}
+
+	difficulty, err := h.difficulty.Recalculate(c.Request.Context(), problemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, difficulty)
+}
+
+func (h *Handler) ClearProblemLimitOverride(c *gin.Context) {
+	problemID, err := validation.ValidateProblemID(c.Param("problemId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.limits == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Resource validation not available"})
+		return
+	}
+
+	if err := h.limits.ClearLimitOverride(c.Request.Context(), problemID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	userIDValue, _ := c.Get("user_id")
 	var userID int64
 	if v, ok := userIDValue.(float64); ok {
 		userID = int64(v)
 	}
 
-	// Get current status
-	currentStatus := h.pool.GetStatus()
-	currentWorkers := currentStatus["total_workers"].(int)
+	auditEvent := &services.AuditEvent{
+		UserID:     userID,
+		Action:     services.AdminActionLimitOverrideClear,
+		Resource:   "problem_limits",
+		ResourceID: &problemID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Timestamp:  time.Now(),
+		Severity:   services.SeverityInfo,
+	}
+
+	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Limit override cleared",
+		"problem_id": problemID,
+	})
+}
+
+func (h *Handler) TransferSubmission(c *gin.Context) {
+	id, err := validation.ValidateSubmissionID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var request struct {
+		UserID int64  `json:"user_id" binding:"required,min=1"`
+		TeamID *int64 `json:"team_id,omitempty"`
+		Reason string `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	submission, err := h.db.GetSubmission(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Submission not found"})
+		return
+	}
 
-	if request.WorkerCount == currentWorkers {
-		c.JSON(http.StatusOK, gin.H{
-			"message":           "No scaling needed",
-			"current_workers":   currentWorkers,
-			"requested_workers": request.WorkerCount,
-		})
+	if err := h.db.TransferSubmissionOwnership(c.Request.Context(), id, request.UserID, request.TeamID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer submission"})
 		return
 	}
 
-	// Log admin action before execution
+	if h.timelines != nil {
+		h.timelines.Invalidate(c.Request.Context(), submission.UserID, submission.ProblemID)
+		h.timelines.Invalidate(c.Request.Context(), request.UserID, submission.ProblemID)
+	}
+
+	adminID, _ := callerUserID(c)
 	auditEvent := &services.AuditEvent{
-		UserID:    userID,
-		Action:    services.AdminActionWorkerScale,
-		Resource:  "judge_workers",
-		IPAddress: c.ClientIP(),
-		UserAgent: c.GetHeader("User-Agent"),
+		UserID:     adminID,
+		Action:     services.AdminActionSubmissionTransfer,
+		Resource:   "submission",
+		ResourceID: &id,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
 		Details: map[string]interface{}{
-			"previous_count": currentWorkers,
-			"new_count":      request.WorkerCount,
+			"previous_user_id": submission.UserID,
+			"previous_team_id": submission.TeamID,
+			"new_user_id":      request.UserID,
+			"new_team_id":      request.TeamID,
+			"reason":           request.Reason,
 		},
 		Timestamp: time.Now(),
-		Severity:  services.SeverityInfo,
+		Severity:  services.SeverityWarning,
 	}
 
 	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
-		// Log error but don't fail the request
-		fmt.Printf("Failed to log admin action: %v\n", err)
-	}
-
-	// Perform scaling operation
-	err := h.pool.ScaleWorkers(request.WorkerCount)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":             fmt.Sprintf("Failed to scale workers: %v", err),
-			"current_workers":   currentWorkers,
-			"requested_workers": request.WorkerCount,
-		})
-		return
+		log.Printf("Failed to log admin action: %v", err)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":          "Worker scaling completed",
-		"previous_workers": currentWorkers,
-		"current_workers":  request.WorkerCount,
+		"submission_id": id,
+		"user_id":       request.UserID,
+		"team_id":       request.TeamID,
 	})
 }
 
-func (h *Handler) GetQueueStatus(c *gin.Context) {
-	queueSize, err := h.queue.GetQueueInfo()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get queue info"})
+func (h *Handler) ReplayEvents(c *gin.Context) {
+	since, err := strconv.ParseInt(c.DefaultQuery("since", "0"), 10, 64)
+	if err != nil || since < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since sequence"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"queue_size": queueSize,
-		"is_healthy": h.queue.IsHealthy(),
-	})
-}
-
-func (h *Handler) GetLanguages(c *gin.Context) {
-	languages, err := h.db.GetSupportedLanguages(c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get languages"})
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit (must be 1-1000)"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"languages": languages})
-}
-
-func (h *Handler) GetLanguage(c *gin.Context) {
-	code := c.Param("code")
-	if err := validation.ValidateLanguage(code); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if h.events == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Event log not available"})
 		return
 	}
 
-	language, err := h.db.GetLanguage(c.Request.Context(), code)
+	events, err := h.events.Replay(c.Request.Context(), since, c.Query("type"), limit)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Language not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get events"})
 		return
 	}
 
-	c.JSON(http.StatusOK, language)
+	next := since
+	if len(events) > 0 {
+		next = events[len(events)-1].Seq
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":     events,
+		"next_since": next,
+		"has_more":   len(events) == limit,
+	})
 }
 
-func (h *Handler) ClearBox(c *gin.Context) {
-	idStr := c.Param("id")
-	boxID, err := strconv.Atoi(idStr)
-	if err != nil || boxID < 0 || boxID > 1000 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid box ID (must be 0-1000)"})
+// StreamEvents relays dashboard events as Server-Sent Events. Clients resume
+// with the Last-Event-ID header or ?since=, and may filter by ?contest_id=
+// and repeated ?type=.
+func (h *Handler) StreamEvents(c *gin.Context) {
+	if h.events == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Event log not available"})
 		return
 	}
 
-	// Get user info for audit logging
-	userIDValue, _ := c.Get("user_id")
-	var userID int64
-	if v, ok := userIDValue.(float64); ok {
-		userID = int64(v)
+	var seq int64
+	since := c.Query("since")
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		since = lastEventID
 	}
-
-	isolateSandbox := h.pool.GetSandbox()
-	if isolateSandbox == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sandbox not available"})
-		return
+	if since != "" {
+		parsed, err := strconv.ParseInt(since, 10, 64)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since sequence"})
+			return
+		}
+		seq = parsed
 	}
 
-	// Log admin action before execution
-	auditEvent := &services.AuditEvent{
-		UserID:     userID,
-		Action:     services.AdminActionBoxCleanup,
-		Resource:   "sandbox_box",
-		ResourceID: &[]int64{int64(boxID)}[0],
-		IPAddress:  c.ClientIP(),
-		UserAgent:  c.GetHeader("User-Agent"),
-		Details: map[string]interface{}{
-			"box_id": boxID,
-		},
-		Timestamp: time.Now(),
-		Severity:  services.SeverityInfo,
+	var contestID *int64
+	if contest := c.Query("contest_id"); contest != "" {
+		id, err := strconv.ParseInt(contest, 10, 64)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contest_id"})
+			return
+		}
+		contestID = &id
 	}
 
-	if err := h.audit.LogAdminAction(c.Request.Context(), auditEvent); err != nil {
-		// Log error but don't fail the request
-		fmt.Printf("Failed to log admin action: %v\n", err)
+	eventTypes := services.StreamEventTypes
+	if requested := c.QueryArray("type"); len(requested) > 0 {
+		eventTypes = nil
+		for _, eventType := range requested {
+			for _, allowed := range services.StreamEventTypes {
+				if eventType == allowed {
+					eventTypes = append(eventTypes, eventType)
+				}
+			}
+		}
+		if len(eventTypes) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No streamable event types requested"})
+			return
+		}
 	}
 
-	isolateSandbox.CleanupBox(boxID)
+	// The stream outlives the server write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": fmt.Sprintf("Box %d cleaned up successfully", boxID),
-		"box_id":  boxID,
-	})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	poll := time.NewTicker(time.Second)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		case <-poll.C:
+			events, err := h.events.Stream(ctx, seq, eventTypes, contestID, 100)
+			if err != nil {
+				log.Printf("Failed to read event stream: %v", err)
+				continue
+			}
+			for _, event := range events {
+				fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.EventType, event.Data)
+				seq = event.Seq
+			}
+			if len(events) > 0 {
+				c.Writer.Flush()
+			}
+		}
+	}
 }
 
 func (h *Handler) HealthCheck(c *gin.Context) {
@@ -525,18 +4309,24 @@ func (h *Handler) HealthCheck(c *gin.Context) {
 		"status": "healthy",
 	}
 
-	if err := h.db.Ping(c.Request.Context()); err != nil {
-		health["status"] = "unhealthy"
-		health["database"] = "disconnected"
+	if h.health != nil {
+		result := h.health.CheckHealth(c.Request.Context())
+		health["status"] = string(result.Status)
+		health["dependencies"] = result.Checks
 	} else {
-		health["database"] = "connected"
-	}
+		if err := h.db.Ping(c.Request.Context()); err != nil {
+			health["status"] = "unhealthy"
+			health["database"] = "disconnected"
+		} else {
+			health["database"] = "connected"
+		}
 
-	if !h.queue.IsHealthy() {
-		health["status"] = "unhealthy"
-		health["rabbitmq"] = "disconnected"
-	} else {
-		health["rabbitmq"] = "connected"
+		if !h.queue.IsHealthy() {
+			health["status"] = "unhealthy"
+			health["rabbitmq"] = "disconnected"
+		} else {
+			health["rabbitmq"] = "connected"
+		}
 	}
 
 	status := h.pool.GetStatus()
@@ -544,13 +4334,91 @@ func (h *Handler) HealthCheck(c *gin.Context) {
 	health["active_workers"] = status["active_workers"]
 	health["queue_size"] = status["queue_size"]
 
-	if health["status"] == "healthy" {
+	if h.disk != nil {
+		usage := h.disk.Usage()
+		health["disk"] = usage
+		if usage.Pressure == "critical" {
+			health["status"] = "unhealthy"
+		}
+	}
+
+	if h.schema != nil {
+		schema := h.schema.Status()
+		health["schema"] = schema
+		if !schema.Ready {
+			health["status"] = "unhealthy"
+		}
+	}
+
+	if h.spool != nil {
+		spool := h.spool.Status()
+		health["spool"] = spool
+		// Intake still works while spooling, but judging waits on MinIO
+		if spool.Degraded && health["status"] != "unhealthy" {
+			health["status"] = string(services.StatusDegraded)
+		}
+	}
+
+	if health["status"] != "unhealthy" {
 		c.JSON(http.StatusOK, health)
 	} else {
 		c.JSON(http.StatusServiceUnavailable, health)
 	}
 }
 
+func (h *Handler) ReadinessCheck(c *gin.Context) {
+	ready := gin.H{"ready": true}
+
+	if h.health != nil {
+		readiness := h.health.CheckReadiness(c.Request.Context())
+		ready["dependencies"] = readiness.Details
+		if readiness.Status == services.StatusUnhealthy {
+			ready["ready"] = false
+		}
+	} else {
+		if err := h.db.Ping(c.Request.Context()); err != nil {
+			ready["ready"] = false
+			ready["database"] = "disconnected"
+		}
+
+		if !h.queue.IsHealthy() {
+			ready["ready"] = false
+			ready["rabbitmq"] = "disconnected"
+		}
+	}
+
+	if h.schema != nil {
+		schema := h.schema.Status()
+		ready["schema"] = schema
+		if !schema.Ready {
+			ready["ready"] = false
+		}
+	}
+
+	if ready["ready"] == true {
+		c.JSON(http.StatusOK, ready)
+	} else {
+		c.JSON(http.StatusServiceUnavailable, ready)
+	}
+}
+
+func (h *Handler) GetEventSchema(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"events": h.queue.EventContracts()})
+}
+
+func (h *Handler) GetVersion(c *gin.Context) {
+	version := gin.H{"service": "execution-service"}
+
+	if h.schema != nil {
+		schema := h.schema.Status()
+		version["schema_version"] = schema.CurrentVersion
+		version["target_schema_version"] = schema.TargetVersion
+		version["schema_ready"] = schema.Ready
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
 func (h *Handler) Metrics(c *gin.Context) {
 	queueSize, _ := h.queue.GetQueueInfo()
 	status := h.pool.GetStatus()
@@ -601,3 +4469,221 @@ func (h *Handler) CleanupStats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stats)
 }
+
+// AuditServiceScope records a service account exercising a privileged scope.
+// CreateAPIKey issues an API key to the caller. The secret is only in this
+// response.
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	if h.apiKeys == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "API keys not available"})
+		return
+	}
+
+	userID, ok := callerUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	var request struct {
+		Name          string   `json:"name" binding:"required,max=100"`
+		Scopes        []string `json:"scopes" binding:"required"`
+		RateLimit     int      `json:"rate_limit,omitempty"`
+		ExpiresInDays int      `json:"expires_in_days,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validation.ValidateAPIKeyScopes(request.Scopes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.RateLimit < 0 || request.RateLimit > h.apiKeys.MaxRateLimit() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("rate_limit must be between 1 and %d", h.apiKeys.MaxRateLimit())})
+		return
+	}
+	if request.ExpiresInDays < 0 || request.ExpiresInDays > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be between 1 and 365"})
+		return
+	}
+
+	var expiresAt *time.Time
+	if request.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, request.ExpiresInDays)
+		expiresAt = &t
+	}
+
+	key, secret, err := h.apiKeys.Issue(c.Request.Context(), userID, request.Name, request.Scopes, request.RateLimit, expiresAt)
+	if errors.Is(err, services.ErrAPIKeyLimitReached) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	h.logAPIKeyEvent(c, services.SecurityEventAPIKeyIssue, userID, key)
+	c.JSON(http.StatusCreated, gin.H{"api_key": key, "key": secret})
+}
+
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	if h.apiKeys == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "API keys not available"})
+		return
+	}
+
+	userID, ok := callerUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	keys, err := h.apiKeys.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// RevokeAPIKey revokes one of the caller's keys; admins may revoke any key.
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	if h.apiKeys == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "API keys not available"})
+		return
+	}
+
+	keyID, err := validation.ValidateAPIKeyID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := callerUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found"})
+		return
+	}
+
+	owner := userID
+	if isAdminRole(c) {
+		owner = 0
+	}
+
+	key, err := h.apiKeys.Revoke(c.Request.Context(), keyID, owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	if key == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	h.logAPIKeyEvent(c, services.SecurityEventAPIKeyRevoke, userID, key)
+	c.JSON(http.StatusOK, key)
+}
+
+func (h *Handler) logAPIKeyEvent(c *gin.Context, action string, userID int64, key *models.APIKey) {
+	event := &services.AuditEvent{
+		UserID:     userID,
+		Action:     action,
+		Resource:   "api_key",
+		ResourceID: &key.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Details: map[string]interface{}{
+			"owner_id":   key.UserID,
+			"key_prefix": key.KeyPrefix,
+			"scopes":     key.Scopes,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogSecurityEvent(c.Request.Context(), event); err != nil {
+		log.Printf("Failed to log API key event: %v", err)
+	}
+}
+
+func (h *Handler) AuditServiceScope(c *gin.Context, serviceName, scope string) {
+	h.logServiceScope(c, serviceName, scope, nil)
+}
+
+func (h *Handler) logServiceScope(c *gin.Context, serviceName, scope string, details map[string]interface{}) {
+	if details == nil {
+		details = make(map[string]interface{})
+	}
+	details["service_account"] = serviceName
+	details["scope"] = scope
+	details["path"] = c.Request.URL.Path
+
+	event := &services.AuditEvent{
+		Action:    services.SecurityEventServiceScope,
+		Resource:  "service_account",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details:   details,
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := h.audit.LogSecurityEvent(c.Request.Context(), event); err != nil {
+		log.Printf("Failed to log service scope use: %v", err)
+	}
+}
+
+func callerUserID(c *gin.Context) (int64, bool) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+
+	switch v := userIDValue.(type) {
+	case float64:
+		return int64(v), true
+	case int64:
+		return v, true
+	case string:
+		id, err := strconv.ParseInt(v, 10, 64)
+		return id, err == nil
+	}
+	return 0, false
+}
+
+// apiKeyReadsOther reports whether an API key caller is reading another
+// user's data; keys only ever read their owner's.
+func apiKeyReadsOther(c *gin.Context, ownerID int64) bool {
+	if !middleware.APIKeyCaller(c) {
+		return false
+	}
+	callerID, _ := callerUserID(c)
+	return callerID != ownerID
+}
+
+func isTeamMember(c *gin.Context, teamID int64) bool {
+	teamIDs, _ := c.Get("team_ids")
+	ids, _ := teamIDs.([]int64)
+	for _, id := range ids {
+		if id == teamID {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) validateLanguage(code string) error {
+	if h.languages == nil {
+		return validation.ValidateLanguage(code)
+	}
+	return h.languages.Validate(code)
+}
+
+func isAdminRole(c *gin.Context) bool {
+	role, _ := c.Get("role")
+	return role == "admin" || role == "super_admin"
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"sync"
+
+	"execution_service/internal/models"
+)
+
+// ProgressHub fans judging progress out to the streams watching each
+// submission. A subscriber that falls behind misses steps rather than
+// stalling the others.
+type ProgressHub struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[chan *models.SubmissionProgress]struct{}
+}
+
+func NewProgressHub() *ProgressHub {
+	return &ProgressHub{
+		subscribers: make(map[int64]map[chan *models.SubmissionProgress]struct{}),
+	}
+}
+
+// Run delivers progress from source until it closes or ctx is done.
+func (ph *ProgressHub) Run(ctx context.Context, source <-chan *models.SubmissionProgress) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case progress, ok := <-source:
+			if !ok {
+				return
+			}
+			ph.publish(progress)
+		}
+	}
+}
+
+// Subscribe returns the progress of one submission and a function that
+// stops delivery.
+func (ph *ProgressHub) Subscribe(submissionID int64) (<-chan *models.SubmissionProgress, func()) {
+	ch := make(chan *models.SubmissionProgress, 16)
+
+	ph.mu.Lock()
+	if ph.subscribers[submissionID] == nil {
+		ph.subscribers[submissionID] = make(map[chan *models.SubmissionProgress]struct{})
+	}
+	ph.subscribers[submissionID][ch] = struct{}{}
+	ph.mu.Unlock()
+
+	return ch, func() {
+		ph.mu.Lock()
+		defer ph.mu.Unlock()
+		delete(ph.subscribers[submissionID], ch)
+		if len(ph.subscribers[submissionID]) == 0 {
+			delete(ph.subscribers, submissionID)
+		}
+	}
+}
+
+func (ph *ProgressHub) publish(progress *models.SubmissionProgress) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+
+	for ch := range ph.subscribers[progress.SubmissionID] {
+		select {
+		case ch <- progress:
+		default:
+		}
+	}
+}
@@ -133,6 +133,165 @@ func (v *ValkeyClient) GetCachedLanguage(ctx context.Context, code string) (*mod
 	return &language, nil
 }
 
+func (v *ValkeyClient) CacheLanguageList(ctx context.Context, languages []models.SupportedLanguage) error {
+	data, err := json.Marshal(languages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal language list: %w", err)
+	}
+
+	return v.client.Set(ctx, "language:list", data, 5*time.Minute).Err()
+}
+
+func (v *ValkeyClient) GetCachedLanguageList(ctx context.Context) ([]models.SupportedLanguage, error) {
+	data, err := v.client.Get(ctx, "language:list").Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("not found")
+		}
+		return nil, fmt.Errorf("failed to get cached language list: %w", err)
+	}
+
+	var languages []models.SupportedLanguage
+	err = json.Unmarshal([]byte(data), &languages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal language list: %w", err)
+	}
+
+	return languages, nil
+}
+
+func (v *ValkeyClient) CacheJudgeStatus(ctx context.Context, status map[string]any) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to marshal judge status: %w", err)
+	}
+
+	return v.client.Set(ctx, "judge:status", data, 5*time.Second).Err()
+}
+
+func (v *ValkeyClient) GetCachedJudgeStatus(ctx context.Context) (map[string]any, error) {
+	data, err := v.client.Get(ctx, "judge:status").Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("not found")
+		}
+		return nil, fmt.Errorf("failed to get cached judge status: %w", err)
+	}
+
+	var status map[string]any
+	err = json.Unmarshal([]byte(data), &status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal judge status: %w", err)
+	}
+
+	return status, nil
+}
+
+func (v *ValkeyClient) CacheAttemptTimeline(ctx context.Context, timeline *models.AttemptTimeline) error {
+	key := fmt.Sprintf("attempts:timeline:%d:%d", timeline.UserID, timeline.ProblemID)
+
+	data, err := json.Marshal(timeline)
+	if err != nil {
+		return fmt.Errorf("failed to marshal attempt timeline: %w", err)
+	}
+
+	return v.client.Set(ctx, key, data, 10*time.Minute).Err()
+}
+
+func (v *ValkeyClient) GetCachedAttemptTimeline(ctx context.Context, userID, problemID int64) (*models.AttemptTimeline, error) {
+	key := fmt.Sprintf("attempts:timeline:%d:%d", userID, problemID)
+
+	data, err := v.client.Get(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("not found")
+		}
+		return nil, fmt.Errorf("failed to get cached attempt timeline: %w", err)
+	}
+
+	var timeline models.AttemptTimeline
+	err = json.Unmarshal([]byte(data), &timeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal attempt timeline: %w", err)
+	}
+
+	return &timeline, nil
+}
+
+func (v *ValkeyClient) SetLimitOverride(ctx context.Context, override *models.LimitOverride) error {
+	key := fmt.Sprintf("problem:limits:override:%d", override.ProblemID)
+
+	ttl := time.Until(override.ExpiresAt)
+	if ttl <= 0 {
+		return fmt.Errorf("override already expired")
+	}
+
+	data, err := json.Marshal(override)
+	if err != nil {
+		return fmt.Errorf("failed to marshal limit override: %w", err)
+	}
+
+	return v.client.Set(ctx, key, data, ttl).Err()
+}
+
+func (v *ValkeyClient) GetLimitOverride(ctx context.Context, problemID int64) (*models.LimitOverride, error) {
+	key := fmt.Sprintf("problem:limits:override:%d", problemID)
+
+	data, err := v.client.Get(ctx, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("not found")
+		}
+		return nil, fmt.Errorf("failed to get limit override: %w", err)
+	}
+
+	var override models.LimitOverride
+	err = json.Unmarshal([]byte(data), &override)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal limit override: %w", err)
+	}
+
+	return &override, nil
+}
+
+func (v *ValkeyClient) DeleteLimitOverride(ctx context.Context, problemID int64) error {
+	key := fmt.Sprintf("problem:limits:override:%d", problemID)
+	return v.client.Del(ctx, key).Err()
+}
+
+func (v *ValkeyClient) GetRBACGeneration(ctx context.Context) (int64, error) {
+	gen, err := v.client.Get(ctx, "rbac:generation").Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get rbac generation: %w", err)
+	}
+	return gen, nil
+}
+
+func (v *ValkeyClient) BumpRBACGeneration(ctx context.Context) error {
+	return v.client.Incr(ctx, "rbac:generation").Err()
+}
+
+func (v *ValkeyClient) CacheRBACDecision(ctx context.Context, generation, userID int64, check string, allowed bool, ttl time.Duration) error {
+	key := fmt.Sprintf("rbac:decision:%d:%d:%s", generation, userID, check)
+	return v.client.Set(ctx, key, allowed, ttl).Err()
+}
+
+func (v *ValkeyClient) GetCachedRBACDecision(ctx context.Context, generation, userID int64, check string) (bool, error) {
+	key := fmt.Sprintf("rbac:decision:%d:%d:%s", generation, userID, check)
+
+	allowed, err := v.client.Get(ctx, key).Bool()
+	if err != nil {
+		if err == redis.Nil {
+			return false, fmt.Errorf("not found")
+		}
+		return false, fmt.Errorf("failed to get rbac decision: %w", err)
+	}
+	return allowed, nil
+}
+
 func (v *ValkeyClient) SetQueueSize(ctx context.Context, size int) error {
 	return v.client.Set(ctx, "judge:queue:size", size, 10*time.Second).Err()
 }
@@ -158,9 +317,152 @@ func (v *ValkeyClient) InvalidateProblem(ctx context.Context, problemID int64) e
 	return v.client.Del(ctx, key).Err()
 }
 
+func (v *ValkeyClient) InvalidateAttemptTimeline(ctx context.Context, userID, problemID int64) error {
+	key := fmt.Sprintf("attempts:timeline:%d:%d", userID, problemID)
+	return v.client.Del(ctx, key).Err()
+}
+
 func (v *ValkeyClient) InvalidateLanguage(ctx context.Context, code string) error {
 	key := fmt.Sprintf("language:config:%s", code)
-	return v.client.Del(ctx, key).Err()
+	return v.client.Del(ctx, key, "language:list").Err()
+}
+
+func (v *ValkeyClient) InvalidateLanguageList(ctx context.Context) error {
+	return v.client.Del(ctx, "language:list").Err()
+}
+
+func (v *ValkeyClient) InvalidateJudgeStatus(ctx context.Context) error {
+	return v.client.Del(ctx, "judge:status").Err()
+}
+
+// InvalidateAllTestCases drops every problem's cached test cases.
+func (v *ValkeyClient) InvalidateAllTestCases(ctx context.Context) error {
+	return v.deleteMatching(ctx, "problem:test_cases:*")
+}
+
+// InvalidateRBACDecisions drops the user's decisions cached under the policy
+// generation.
+func (v *ValkeyClient) InvalidateRBACDecisions(ctx context.Context, generation, userID int64) error {
+	return v.deleteMatching(ctx, fmt.Sprintf("rbac:decision:%d:%d:*", generation, userID))
+}
+
+// deleteMatching deletes the keys matching pattern in batches, scanning
+// rather than using KEYS so a large keyspace does not block the server.
+func (v *ValkeyClient) deleteMatching(ctx context.Context, pattern string) error {
+	const batch = 500
+
+	iter := v.client.Scan(ctx, 0, pattern, batch).Iterator()
+	keys := make([]string, 0, batch)
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == batch {
+			if err := v.client.Del(ctx, keys...).Err(); err != nil {
+				return err
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		return v.client.Del(ctx, keys...).Err()
+	}
+	return nil
+}
+
+const submissionProgressChannel = "submission:progress"
+
+// PublishSubmissionProgress broadcasts a judging step to every node, so a
+// stream sees progress whichever node judges the submission.
+func (v *ValkeyClient) PublishSubmissionProgress(ctx context.Context, progress *models.SubmissionProgress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to marshal submission progress: %w", err)
+	}
+	return v.client.Publish(ctx, submissionProgressChannel, data).Err()
+}
+
+// SubscribeSubmissionProgress delivers progress published by any node until
+// ctx is done.
+func (v *ValkeyClient) SubscribeSubmissionProgress(ctx context.Context) <-chan *models.SubmissionProgress {
+	pubsub := v.client.Subscribe(ctx, submissionProgressChannel)
+	progress := make(chan *models.SubmissionProgress, 64)
+
+	go func() {
+		defer close(progress)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var step models.SubmissionProgress
+				if err := json.Unmarshal([]byte(msg.Payload), &step); err != nil {
+					continue
+				}
+				select {
+				case progress <- &step:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return progress
+}
+
+const contestLogChannel = "contest:logs"
+
+// PublishContestLog broadcasts a contest log entry to every node, so a
+// proctor's stream sees it whichever node judged the submission.
+func (v *ValkeyClient) PublishContestLog(ctx context.Context, entry *models.ContestLogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal contest log: %w", err)
+	}
+	return v.client.Publish(ctx, contestLogChannel, data).Err()
+}
+
+// SubscribeContestLogs delivers contest log entries published by any node
+// until ctx is done.
+func (v *ValkeyClient) SubscribeContestLogs(ctx context.Context) <-chan *models.ContestLogEntry {
+	pubsub := v.client.Subscribe(ctx, contestLogChannel)
+	entries := make(chan *models.ContestLogEntry, 64)
+
+	go func() {
+		defer close(entries)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var entry models.ContestLogEntry
+				if err := json.Unmarshal([]byte(msg.Payload), &entry); err != nil {
+					continue
+				}
+				select {
+				case entries <- &entry:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return entries
 }
 
 func (v *ValkeyClient) IsHealthy() bool {
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"execution_service/internal/config"
+	"execution_service/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestClient(t *testing.T) (*ValkeyClient, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client, err := NewValkeyClient(&config.ValkeyConfig{URL: server.Addr()})
+	if err != nil {
+		t.Fatalf("NewValkeyClient() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, server
+}
+
+func TestLanguageListCache(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	if _, err := client.GetCachedLanguageList(ctx); err == nil {
+		t.Fatal("GetCachedLanguageList() before caching: want a miss")
+	}
+
+	languages := []models.SupportedLanguage{
+		{ID: 1, LanguageCode: "cpp", LanguageName: "C++", IsEnabled: true},
+		{ID: 2, LanguageCode: "python", LanguageName: "Python", IsEnabled: true},
+	}
+	if err := client.CacheLanguageList(ctx, languages); err != nil {
+		t.Fatalf("CacheLanguageList() error = %v", err)
+	}
+	if ttl := server.TTL("language:list"); ttl != 5*time.Minute {
+		t.Errorf("language list TTL = %v, want 5m", ttl)
+	}
+
+	cached, err := client.GetCachedLanguageList(ctx)
+	if err != nil {
+		t.Fatalf("GetCachedLanguageList() error = %v", err)
+	}
+	if len(cached) != len(languages) {
+		t.Fatalf("cached %d languages, want %d", len(cached), len(languages))
+	}
+	for i := range languages {
+		if cached[i].LanguageCode != languages[i].LanguageCode || cached[i].LanguageName != languages[i].LanguageName {
+			t.Errorf("cached language %d = %+v, want %+v", i, cached[i], languages[i])
+		}
+	}
+
+	// Changing one language drops the list as well
+	if err := client.InvalidateLanguage(ctx, "cpp"); err != nil {
+		t.Fatalf("InvalidateLanguage() error = %v", err)
+	}
+	if _, err := client.GetCachedLanguageList(ctx); err == nil {
+		t.Error("GetCachedLanguageList() after InvalidateLanguage: want a miss")
+	}
+}
+
+func TestJudgeStatusCache(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := context.Background()
+
+	status := map[string]any{"total_workers": 4, "is_healthy": true}
+	if err := client.CacheJudgeStatus(ctx, status); err != nil {
+		t.Fatalf("CacheJudgeStatus() error = %v", err)
+	}
+
+	cached, err := client.GetCachedJudgeStatus(ctx)
+	if err != nil {
+		t.Fatalf("GetCachedJudgeStatus() error = %v", err)
+	}
+	if cached["total_workers"] != float64(4) || cached["is_healthy"] != true {
+		t.Errorf("cached status = %v, want %v", cached, status)
+	}
+
+	server.FastForward(5 * time.Second)
+	if _, err := client.GetCachedJudgeStatus(ctx); err == nil {
+		t.Error("GetCachedJudgeStatus() after 5s: want a miss")
+	}
+
+	if err := client.CacheJudgeStatus(ctx, status); err != nil {
+		t.Fatalf("CacheJudgeStatus() error = %v", err)
+	}
+	if err := client.InvalidateJudgeStatus(ctx); err != nil {
+		t.Fatalf("InvalidateJudgeStatus() error = %v", err)
+	}
+	if _, err := client.GetCachedJudgeStatus(ctx); err == nil {
+		t.Error("GetCachedJudgeStatus() after InvalidateJudgeStatus: want a miss")
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -28,6 +27,9 @@ type CheckerConfig struct {
 	MaxCheckerMemory   int           `yaml:"max_checker_memory"`
 	SupportedLanguages []string      `yaml:"supported_languages"`
 	TempDir            string        `yaml:"temp_dir"`
+	// TestlibHeader is copied next to C and C++ checkers as testlib.h when
+	// present on the host.
+	TestlibHeader string `yaml:"testlib_header"`
 }
 
 type CheckerResult struct {
@@ -36,6 +38,10 @@ type CheckerResult struct {
 	Message       string  `json:"message"`
 	ExecutionTime int     `json:"execution_time_ms"`
 	MemoryUsed    int     `json:"memory_used_kb"`
+	TimedOut      bool    `json:"timed_out"`
+	// Failed means the checker reported its own failure, testlib's FAIL,
+	// so the test cannot be judged.
+	Failed bool `json:"failed"`
 }
 
 type CheckerCompilationResult struct {
@@ -52,10 +58,32 @@ func NewCustomChecker(sandbox *sandbox.IsolateSandbox, storage *storage.MinIOCli
 	}
 }
 
-func (cc *CustomChecker) ValidateOutput(ctx context.Context, testCase *models.TestCase, programOutput, expectedOutput string) (*CheckerResult, error) {
+// CheckInput is what a checker judges. Following testlib, the test input,
+// the program's output and the expected answer are written to input.txt,
+// output.txt and expected.txt and passed as arguments in that order.
+// DataDir, when set, is mounted read-only at /data with judge data files.
+type CheckInput struct {
+	Input    []byte
+	Output   string
+	Expected string
+	DataDir  string
+}
+
+// testlib exit codes
+const (
+	exitAccepted      = 0
+	exitWrongAnswer   = 1
+	exitPresentation  = 2
+	exitFail          = 3
+	exitDirt          = 4
+	exitPoints        = 7
+	exitUnexpectedEOF = 8
+)
+
+func (cc *CustomChecker) ValidateOutput(ctx context.Context, testCase *models.TestCase, in *CheckInput) (*CheckerResult, error) {
 	// If no custom checker URL, fall back to exact matching
 	if testCase.CheckerURL == "" {
-		return cc.exactMatch(programOutput, expectedOutput), nil
+		return cc.exactMatch(in.Output, in.Expected), nil
 	}
 
 	// Download custom checker code
@@ -83,8 +111,14 @@ func (cc *CustomChecker) ValidateOutput(ctx context.Context, testCase *models.Te
 		}, nil
 	}
 
-	// Compile checker
-	compileResult, err := cc.compileChecker(ctx, checkerCode, checkerLanguage)
+	// The checker is built and run in the same box, so the build is there
+	boxID, err := cc.sandbox.AcquireBox()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create isolate box: %w", err)
+	}
+	defer cc.sandbox.ReleaseBox(boxID)
+
+	compileResult, err := cc.compileChecker(ctx, boxID, checkerCode, checkerLanguage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile checker: %w", err)
 	}
@@ -97,8 +131,7 @@ func (cc *CustomChecker) ValidateOutput(ctx context.Context, testCase *models.Te
 		}, nil
 	}
 
-	// Execute checker
-	result, err := cc.executeChecker(ctx, programOutput, expectedOutput, checkerLanguage)
+	result, err := cc.executeChecker(ctx, boxID, checkerLanguage, in)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute checker: %w", err)
 	}
@@ -106,59 +139,38 @@ func (cc *CustomChecker) ValidateOutput(ctx context.Context, testCase *models.Te
 	return result, nil
 }
 
-func (cc *CustomChecker) compileChecker(ctx context.Context, checkerCode []byte, language string) (*CheckerCompilationResult, error) {
-	boxID, err := cc.sandbox.CreateBox()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create isolate box: %w", err)
-	}
-	defer cc.sandbox.CleanupBox(boxID)
-
+func (cc *CustomChecker) compileChecker(ctx context.Context, boxID int, checkerCode []byte, language string) (*CheckerCompilationResult, error) {
 	boxDir := cc.sandbox.GetBoxDir(boxID)
 	checkerFile := filepath.Join(boxDir, "checker"+cc.getFileExtension(language))
 
-	err = os.WriteFile(checkerFile, checkerCode, 0644)
+	err := os.WriteFile(checkerFile, checkerCode, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("failed to write checker file: %w", err)
 	}
+	if err := cc.installTestlib(boxDir, language); err != nil {
+		return nil, err
+	}
 
 	// Get language-specific compile command
-	compileCmd := cc.getCompileCommand(language, "checker", "checker")
+	compileCmd := cc.getCompileCommand(language, "checker"+cc.getFileExtension(language), "checker")
 	if compileCmd == "" {
 		// No compilation needed for interpreted languages
 		return &CheckerCompilationResult{Success: true}, nil
 	}
 
 	// Execute compilation in sandbox
-	args := []string{
-		"--box-id=" + strconv.Itoa(boxID),
-		"--cg",
-		"--cg-timing",
-		"--processes=5",
-		"--mem=262144", // 256MB for compilation
-		"--time=" + strconv.Itoa(int(cc.config.MaxCheckerTime.Seconds())),
-		"--wall-time=" + strconv.Itoa(int(cc.config.MaxCheckerTime.Seconds()*2)),
-		"--fsize=16384", // 16MB max file size
-		"--env=PATH=/usr/bin:/bin",
-		"--dir=/etc:noexec",
-		"--dir=/usr:noexec",
-		"--dir=/lib:noexec",
-		"--dir=/lib64:noexec",
-		"--dir=/tmp:rw",
-		"--run",
-		"--",
-		"/bin/bash",
-		"-c",
-		compileCmd,
-	}
-
-	cmd := exec.CommandContext(ctx, cc.sandbox.GetPath(), args...)
-	cmd.Dir = boxDir
-
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	spec := sandbox.RunSpec{
+		Command:    compileCmd,
+		TimeLimit:  cc.config.MaxCheckerTime,
+		MemoryKb:   262144, // 256MB for compilation
+		Processes:  5,
+		Mounts:     sandbox.WorkMounts(),
+		StdoutPipe: &stdout,
+		StderrPipe: &stderr,
+	}
 
-	err = cmd.Run()
+	err = cc.sandbox.Run(ctx, boxID, spec)
 	if err != nil {
 		return &CheckerCompilationResult{
 			Success: false,
@@ -174,34 +186,41 @@ func (cc *CustomChecker) compileChecker(ctx context.Context, checkerCode []byte,
 	}, nil
 }
 
-func (cc *CustomChecker) executeChecker(ctx context.Context, programOutput, expectedOutput, language string) (*CheckerResult, error) {
-	boxID, err := cc.sandbox.CreateBox()
+// installTestlib puts testlib.h beside C and C++ checkers so they can
+// include it. Without a header on the host, checkers must bring their own.
+func (cc *CustomChecker) installTestlib(boxDir, language string) error {
+	if (language != "cpp" && language != "c") || cc.config.TestlibHeader == "" {
+		return nil
+	}
+	header, err := os.ReadFile(cc.config.TestlibHeader)
+	if os.IsNotExist(err) {
+		return nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to create isolate box: %w", err)
+		return fmt.Errorf("failed to read testlib header: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(boxDir, "testlib.h"), header, 0644); err != nil {
+		return fmt.Errorf("failed to write testlib header: %w", err)
 	}
-	defer cc.sandbox.CleanupBox(boxID)
+	return nil
+}
 
+func (cc *CustomChecker) executeChecker(ctx context.Context, boxID int, language string, in *CheckInput) (*CheckerResult, error) {
 	boxDir := cc.sandbox.GetBoxDir(boxID)
 
-	// Write input files for checker
-	inputFile := filepath.Join(boxDir, "input.txt")
-	outputFile := filepath.Join(boxDir, "output.txt")
-	expectedFile := filepath.Join(boxDir, "expected.txt")
-
-	if err := os.WriteFile(inputFile, []byte(programOutput), 0644); err != nil {
-		return nil, fmt.Errorf("failed to write input file: %w", err)
+	files := map[string][]byte{
+		"input.txt":    in.Input,
+		"output.txt":   []byte(in.Output),
+		"expected.txt": []byte(in.Expected),
 	}
-
-	if err := os.WriteFile(outputFile, []byte(programOutput), 0644); err != nil {
-		return nil, fmt.Errorf("failed to write output file: %w", err)
-	}
-
-	if err := os.WriteFile(expectedFile, []byte(expectedOutput), 0644); err != nil {
-		return nil, fmt.Errorf("failed to write expected file: %w", err)
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(boxDir, name), data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", name, err)
+		}
 	}
 
 	// Get language-specific execute command
-	executeCmd := cc.getExecuteCommand(language, "checker", "input.txt", "output.txt", "expected.txt")
+	executeCmd := cc.getExecuteCommand(language, cc.checkerProgram(language), "input.txt", "output.txt", "expected.txt")
 	if executeCmd == "" {
 		return &CheckerResult{
 			IsCorrect: false,
@@ -210,96 +229,121 @@ func (cc *CustomChecker) executeChecker(ctx context.Context, programOutput, expe
 		}, nil
 	}
 
+	mounts := sandbox.SystemMounts()
+	if in.DataDir != "" {
+		mounts = append(mounts, sandbox.Mount{Path: "/data", Source: in.DataDir})
+	}
+
 	// Execute checker in sandbox
-	args := []string{
-		"--box-id=" + strconv.Itoa(boxID),
-		"--cg",
-		"--cg-timing",
-		"--processes=1",
-		"--mem=" + strconv.Itoa(cc.config.MaxCheckerMemory),
-		"--time=" + strconv.Itoa(int(cc.config.MaxCheckerTime.Seconds())),
-		"--wall-time=" + strconv.Itoa(int(cc.config.MaxCheckerTime.Seconds()*2)),
-		"--extra-time=0.5",
-		"--stack=65536",
-		"--fsize=16384",
-		"--chdir=/box",
-		"--env=HOME=/tmp",
-		"--env=PATH=/usr/bin:/bin",
-		"--dir=/etc:noexec",
-		"--dir=/usr:noexec",
-		"--dir=/lib:noexec",
-		"--dir=/lib64:noexec",
-		"--stdin=input.txt",
-		"--stdout=checker_output.txt",
-		"--stderr=error.txt",
-		"--meta=meta.txt",
-		"--run",
-		"--",
-		"/bin/bash",
-		"-c",
-		executeCmd,
-	}
-
-	cmd := exec.CommandContext(ctx, cc.sandbox.GetPath(), args...)
-	cmd.Dir = boxDir
+	spec := sandbox.RunSpec{
+		Command:   executeCmd,
+		TimeLimit: cc.config.MaxCheckerTime,
+		MemoryKb:  cc.config.MaxCheckerMemory,
+		Processes: 1,
+		Mounts:    mounts,
+		Stdin:     "input.txt",
+		Stdout:    "checker_output.txt",
+		Stderr:    "error.txt",
+		Meta:      "meta.txt",
+	}
 
 	startTime := time.Now()
-	err = cmd.Run()
+	runErr := cc.sandbox.Run(ctx, boxID, spec)
 	executionTime := time.Since(startTime)
 
-	if err != nil {
-		// Try to read any output even if execution failed
-		outputFile = filepath.Join(boxDir, "checker_output.txt")
-		errorFile := filepath.Join(boxDir, "error.txt")
-
-		var output, errorStr []byte
-		if output, _ = os.ReadFile(outputFile); len(output) > 0 {
-			return cc.parseCheckerOutput(string(output), executionTime, 0), nil
-		}
-
-		if errorStr, _ = os.ReadFile(errorFile); len(errorStr) > 0 {
-			return &CheckerResult{
-				IsCorrect: false,
-				Score:     0.0,
-				Message:   fmt.Sprintf("Checker execution failed: %s", string(errorStr)),
-			}, nil
-		}
-
+	meta, _ := os.ReadFile(filepath.Join(boxDir, "meta.txt"))
+	_, memoryKb := cc.parseMetaFile(string(meta))
+	if runErr != nil && strings.Contains(string(meta), "status:TO") {
 		return &CheckerResult{
-			IsCorrect: false,
-			Score:     0.0,
-			Message:   fmt.Sprintf("Checker execution failed: %v", err),
+			IsCorrect:     false,
+			Score:         0.0,
+			Message:       "Checker exceeded time limit",
+			ExecutionTime: int(executionTime.Milliseconds()),
+			MemoryUsed:    memoryKb,
+			TimedOut:      true,
 		}, nil
 	}
 
-	// Read checker output
-	outputFile = filepath.Join(boxDir, "checker_output.txt")
-	output, err := os.ReadFile(outputFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read checker output: %w", err)
+	output, _ := os.ReadFile(filepath.Join(boxDir, "checker_output.txt"))
+	if result := cc.parseCheckerOutput(string(output), executionTime, memoryKb); result != nil {
+		return result, nil
 	}
 
-	// Parse execution metrics from meta.txt
-	metaFile := filepath.Join(boxDir, "meta.txt")
-	meta, _ := os.ReadFile(metaFile)
-	_, memoryKb := cc.parseMetaFile(string(meta))
+	// Otherwise the exit code decides, as with testlib checkers, which
+	// report on stderr
+	exitCode, exited := cc.parseExitCode(string(meta))
+	if runErr == nil {
+		exitCode, exited = exitAccepted, true
+	}
+	errorOutput, _ := os.ReadFile(filepath.Join(boxDir, "error.txt"))
+	message := strings.TrimSpace(string(errorOutput))
+	if !exited {
+		if message == "" {
+			message = runErr.Error()
+		}
+		return nil, fmt.Errorf("checker did not finish: %s", message)
+	}
 
-	return cc.parseCheckerOutput(string(output), executionTime, memoryKb), nil
+	result := &CheckerResult{
+		Message:       message,
+		ExecutionTime: int(executionTime.Milliseconds()),
+		MemoryUsed:    memoryKb,
+	}
+	switch exitCode {
+	case exitAccepted:
+		// Older checkers print a rejection to stdout and exit cleanly
+		if legacy := strings.TrimSpace(string(output)); legacy != "" {
+			result.Message = strings.SplitN(legacy, "\n", 2)[0]
+			break
+		}
+		result.IsCorrect = true
+		result.Score = 1.0
+	case exitWrongAnswer, exitPresentation, exitDirt, exitUnexpectedEOF:
+		// There is no presentation verdict; format errors are wrong answers
+	case exitPoints:
+		// quitp reports "points <value> <message>"; older testlib omits the word
+		fields := strings.Fields(message)
+		if len(fields) > 0 && fields[0] == "points" {
+			fields = fields[1:]
+		}
+		if len(fields) > 0 {
+			if score, err := strconv.ParseFloat(fields[0], 64); err == nil && score > 0 {
+				result.Score = min(normalizeScore(score), 1.0)
+				result.IsCorrect = result.Score > 0.5
+			}
+		}
+	case exitFail:
+		result.Failed = true
+		if result.Message == "" {
+			result.Message = "Checker failed"
+		}
+	default:
+		return nil, fmt.Errorf("checker failed with exit code %d: %s", exitCode, message)
+	}
+	if result.Message == "" && !result.IsCorrect {
+		result.Message = "Wrong answer"
+	}
+	return result, nil
 }
 
-func (cc *CustomChecker) parseCheckerOutput(output string, executionTime time.Duration, memoryKb int) *CheckerResult {
-	// Parse checker output format
-	// Expected format: "score message" or "CORRECT/INCORRECT message"
-	lines := strings.Split(strings.TrimSpace(output), "\n")
-	if len(lines) == 0 {
-		return &CheckerResult{
-			IsCorrect: false,
-			Score:     0.0,
-			Message:   "No output from checker",
-		}
+// checkerProgram is what the execute command runs: the built binary, or the
+// source for interpreted languages.
+func (cc *CustomChecker) checkerProgram(language string) string {
+	if cc.getCompileCommand(language, "", "") == "" {
+		return "checker" + cc.getFileExtension(language)
 	}
+	return "checker"
+}
 
+// parseCheckerOutput reads a verdict a checker printed to stdout, either
+// "CORRECT"/"INCORRECT" or a score, each optionally followed by a message.
+// It returns nil when stdout holds no verdict.
+func (cc *CustomChecker) parseCheckerOutput(output string, executionTime time.Duration, memoryKb int) *CheckerResult {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
 	firstLine := strings.TrimSpace(lines[0])
+	if firstLine == "" {
+		return nil
+	}
 
 	// Check for simple CORRECT/INCORRECT format
 	if strings.ToUpper(firstLine) == "CORRECT" {
@@ -332,37 +376,33 @@ func (cc *CustomChecker) parseCheckerOutput(output string, executionTime time.Du
 
 	// Try to parse as "score message"
 	parts := strings.Fields(firstLine)
-	if len(parts) >= 1 {
-		if score, err := strconv.ParseFloat(parts[0], 64); err == nil {
-			message := strings.Join(parts[1:], " ")
-			if message == "" {
-				message = "Checker completed"
-			}
+	if score, err := strconv.ParseFloat(parts[0], 64); err == nil {
+		message := strings.Join(parts[1:], " ")
+		if message == "" {
+			message = "Checker completed"
+		}
 
-			// Normalize score to 0-1 range
-			normalizedScore := score
-			if normalizedScore > 1.0 {
-				normalizedScore = normalizedScore / 100.0
-			}
+		normalizedScore := normalizeScore(score)
 
-			return &CheckerResult{
-				IsCorrect:     normalizedScore > 0.5,
-				Score:         normalizedScore,
-				Message:       message,
-				ExecutionTime: int(executionTime.Milliseconds()),
-				MemoryUsed:    memoryKb,
-			}
+		return &CheckerResult{
+			IsCorrect:     normalizedScore > 0.5,
+			Score:         normalizedScore,
+			Message:       message,
+			ExecutionTime: int(executionTime.Milliseconds()),
+			MemoryUsed:    memoryKb,
 		}
 	}
 
-	// Default case - treat as incorrect
-	return &CheckerResult{
-		IsCorrect:     false,
-		Score:         0.0,
-		Message:       firstLine,
-		ExecutionTime: int(executionTime.Milliseconds()),
-		MemoryUsed:    memoryKb,
+	return nil
+}
+
+// normalizeScore brings a checker's score into the 0-1 range, reading scores
+// above 1 as percentages.
+func normalizeScore(score float64) float64 {
+	if score > 1.0 {
+		return score / 100.0
 	}
+	return score
 }
 
 func (cc *CustomChecker) exactMatch(programOutput, expectedOutput string) *CheckerResult {
@@ -478,6 +518,22 @@ func (cc *CustomChecker) parseMetaFile(meta string) (timeMs, memoryKb int) {
 	return
 }
 
+// parseExitCode reports the exit code from isolate's meta file and whether
+// the checker exited on its own rather than being killed.
+func (cc *CustomChecker) parseExitCode(meta string) (int, bool) {
+	for _, line := range strings.Split(meta, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "exitcode:"); ok {
+			code, err := strconv.Atoi(strings.TrimSpace(value))
+			return code, err == nil
+		}
+	}
+	return 0, false
+}
+
+func (cc *CustomChecker) SetMaxCheckerTime(d time.Duration) {
+	cc.config.MaxCheckerTime = d
+}
+
 func (cc *CustomChecker) GetDefaultConfig() *CheckerConfig {
 	return &CheckerConfig{
 		MaxCheckerSize:     65536, // 64KB
@@ -485,5 +541,6 @@ func (cc *CustomChecker) GetDefaultConfig() *CheckerConfig {
 		MaxCheckerMemory:   131072, // 128MB
 		SupportedLanguages: []string{"cpp", "c", "java", "python", "go", "javascript", "bash"},
 		TempDir:            "/tmp/checker",
+		TestlibHeader:      "/usr/local/include/testlib.h",
 	}
 }
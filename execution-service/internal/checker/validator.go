@@ -0,0 +1,124 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"execution_service/internal/sandbox"
+)
+
+// ValidatorInput is one test input to validate.
+type ValidatorInput struct {
+	TestCaseID int64
+	TestNumber int
+	Input      []byte
+}
+
+// ValidationReport is the outcome of a validator run. CompileError is set,
+// and Tests left empty, when the validator does not build.
+type ValidationReport struct {
+	Valid        bool                    `json:"valid"`
+	CompileError string                  `json:"compile_error,omitempty"`
+	Tests        []InputValidationResult `json:"tests"`
+}
+
+type InputValidationResult struct {
+	TestCaseID    int64  `json:"test_case_id"`
+	TestNumber    int    `json:"test_number"`
+	Valid         bool   `json:"valid"`
+	Message       string `json:"message,omitempty"`
+	ExecutionTime int    `json:"execution_time_ms"`
+}
+
+// ValidateInputs builds a validator once and runs it over each input. Like
+// testlib validators, it reads the input on stdin and exits 0 when the input
+// is valid, explaining a rejection on stderr.
+func (cc *CustomChecker) ValidateInputs(ctx context.Context, validatorURL string, inputs []ValidatorInput) (*ValidationReport, error) {
+	validatorCode, err := cc.storage.DownloadCode(ctx, validatorURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download validator code: %w", err)
+	}
+	if int64(len(validatorCode)) > cc.config.MaxCheckerSize {
+		return nil, fmt.Errorf("validator code too large: %d bytes", len(validatorCode))
+	}
+	language := cc.detectCheckerLanguage(validatorURL)
+	if language == "" {
+		return nil, fmt.Errorf("unable to determine validator language")
+	}
+
+	boxID, err := cc.sandbox.AcquireBox()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create isolate box: %w", err)
+	}
+	defer cc.sandbox.ReleaseBox(boxID)
+
+	compileResult, err := cc.compileChecker(ctx, boxID, validatorCode, language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile validator: %w", err)
+	}
+	if !compileResult.Success {
+		return &ValidationReport{CompileError: compileResult.Error, Tests: []InputValidationResult{}}, nil
+	}
+
+	report := &ValidationReport{Valid: true, Tests: make([]InputValidationResult, 0, len(inputs))}
+	for _, input := range inputs {
+		result, err := cc.executeValidator(ctx, boxID, language, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate test %d: %w", input.TestNumber, err)
+		}
+		report.Valid = report.Valid && result.Valid
+		report.Tests = append(report.Tests, *result)
+	}
+	return report, nil
+}
+
+func (cc *CustomChecker) executeValidator(ctx context.Context, boxID int, language string, input ValidatorInput) (*InputValidationResult, error) {
+	boxDir := cc.sandbox.GetBoxDir(boxID)
+	if err := os.WriteFile(filepath.Join(boxDir, "input.txt"), input.Input, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write input.txt: %w", err)
+	}
+
+	executeCmd := strings.TrimSpace(cc.getExecuteCommand(language, cc.checkerProgram(language), "", "", ""))
+	if executeCmd == "" {
+		return nil, fmt.Errorf("unsupported validator language: %s", language)
+	}
+
+	spec := sandbox.RunSpec{
+		Command:   executeCmd,
+		TimeLimit: cc.config.MaxCheckerTime,
+		MemoryKb:  cc.config.MaxCheckerMemory,
+		Processes: 1,
+		Mounts:    sandbox.SystemMounts(),
+		Stdin:     "input.txt",
+		Stderr:    "error.txt",
+		Meta:      "meta.txt",
+	}
+	runErr := cc.sandbox.Run(ctx, boxID, spec)
+
+	meta, _ := os.ReadFile(filepath.Join(boxDir, "meta.txt"))
+	timeMs, _ := cc.parseMetaFile(string(meta))
+	result := &InputValidationResult{
+		TestCaseID:    input.TestCaseID,
+		TestNumber:    input.TestNumber,
+		Valid:         runErr == nil,
+		ExecutionTime: timeMs,
+	}
+	if result.Valid {
+		return result, nil
+	}
+
+	errorOutput, _ := os.ReadFile(filepath.Join(boxDir, "error.txt"))
+	result.Message = strings.TrimSpace(string(errorOutput))
+	if strings.Contains(string(meta), "status:TO") {
+		result.Message = "Validator exceeded time limit"
+	} else if _, exited := cc.parseExitCode(string(meta)); !exited && result.Message == "" {
+		result.Message = runErr.Error()
+	}
+	if result.Message == "" {
+		result.Message = "Invalid input"
+	}
+	return result, nil
+}
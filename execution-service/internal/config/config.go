@@ -4,21 +4,34 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Server     ServerConfig     `yaml:"server"`
-	Database   DatabaseConfig   `yaml:"database"`
-	RabbitMQ   RabbitMQConfig   `yaml:"rabbitmq"`
-	MinIO      MinIOConfig      `yaml:"minio"`
-	Valkey     ValkeyConfig     `yaml:"valkey"`
-	Judge      JudgeConfig      `yaml:"judge"`
-	Isolate    IsolateConfig    `yaml:"isolate"`
-	JWT        JWTConfig        `yaml:"jwt"`
-	Plagiarism PlagiarismConfig `yaml:"plagiarism"`
+	Server         ServerConfig        `yaml:"server"`
+	Database       DatabaseConfig      `yaml:"database"`
+	RabbitMQ       RabbitMQConfig      `yaml:"rabbitmq"`
+	MinIO          MinIOConfig         `yaml:"minio"`
+	Valkey         ValkeyConfig        `yaml:"valkey"`
+	Judge          JudgeConfig         `yaml:"judge"`
+	Isolate        IsolateConfig       `yaml:"isolate"`
+	JWT            JWTConfig           `yaml:"jwt"`
+	Plagiarism     PlagiarismConfig    `yaml:"plagiarism"`
+	Events         EventsConfig        `yaml:"events"`
+	RBAC           RBACConfig          `yaml:"rbac"`
+	Signing        SigningConfig       `yaml:"signing"`
+	APIKeys        APIKeysConfig       `yaml:"api_keys"`
+	Consistency    ConsistencyConfig   `yaml:"consistency"`
+	GRPC           GRPCConfig          `yaml:"grpc"`
+	Rejudge        RejudgeConfig       `yaml:"rejudge"`
+	Scoring        ScoringConfig       `yaml:"scoring"`
+	Logs           ExecutionLogConfig  `yaml:"execution_logs"`
+	GitIntake      GitIntakeConfig     `yaml:"git_intake"`
+	QueueSnapshots QueueSnapshotConfig `yaml:"queue_snapshots"`
+	UserStats      UserStatsConfig     `yaml:"user_stats"`
 }
 
 type ServerConfig struct {
@@ -32,12 +45,30 @@ type DatabaseConfig struct {
 	MaxOpenConns    int           `yaml:"max_open_conns"`
 	MaxIdleConns    int           `yaml:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	AutoMigrate     bool          `yaml:"auto_migrate"`
 }
 
 type RabbitMQConfig struct {
-	URL           string `yaml:"url"`
-	QueueName     string `yaml:"queue_name"`
-	PrefetchCount int    `yaml:"prefetch_count"`
+	URL                string                `yaml:"url"`
+	QueueName          string                `yaml:"queue_name"`
+	ContestQueueName   string                `yaml:"contest_queue_name"`
+	PrefetchCount      int                   `yaml:"prefetch_count"`
+	EventsExchange     string                `yaml:"events_exchange"`
+	DeadLetterExchange string                `yaml:"dead_letter_exchange"`
+	CacheExchange      string                `yaml:"cache_exchange"`
+	RoutingKeys        map[string]string     `yaml:"routing_keys"`
+	Encryption         QueueEncryptionConfig `yaml:"encryption"`
+}
+
+// QueueEncryptionConfig encrypts judge requests and internal queue messages
+// with AES-256-GCM. Keys maps key IDs to base64 32-byte keys, typically
+// injected from a KMS-backed secret. Events are only encrypted when listed,
+// since other services consume them.
+type QueueEncryptionConfig struct {
+	Enabled     bool              `yaml:"enabled"`
+	ActiveKeyID string            `yaml:"active_key_id"`
+	Keys        map[string]string `yaml:"keys"`
+	Events      []string          `yaml:"events"`
 }
 
 type MinIOConfig struct {
@@ -46,6 +77,38 @@ type MinIOConfig struct {
 	SecretKey  string `yaml:"secret_key"`
 	BucketName string `yaml:"bucket_name"`
 	UseSSL     bool   `yaml:"use_ssl"`
+	// ScopedCredentials makes contest-scoped reads use temporary credentials
+	// from MinIO STS whose policy excludes other contests' prefixes.
+	ScopedCredentials bool                `yaml:"scoped_credentials"`
+	Policies          StoragePolicyConfig `yaml:"policies"`
+	Spool             SpoolConfig         `yaml:"spool"`
+}
+
+// SpoolConfig keeps submission intake alive through short MinIO outages.
+// Code that cannot be uploaded is sealed with Key (base64, 32 bytes) under
+// Dir, up to MaxBytes in total, and uploaded and queued once MinIO answers.
+type SpoolConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Dir            string        `yaml:"dir"`
+	MaxBytes       int64         `yaml:"max_bytes"`
+	Key            string        `yaml:"key"`
+	UploadInterval time.Duration `yaml:"upload_interval"`
+}
+
+// StoragePolicyConfig is kept applied to the bucket by the service.
+// Encryption is "sse-s3" or "sse-kms" with KMSKeyID, or empty to leave it
+// alone. ContestRetention locks objects under contests/ for that long after
+// upload and needs a bucket with object locking. Code objects older than
+// TransitionDays move to TransitionStorageClass.
+type StoragePolicyConfig struct {
+	Enabled                bool          `yaml:"enabled"`
+	Encryption             string        `yaml:"encryption"`
+	KMSKeyID               string        `yaml:"kms_key_id"`
+	ContestRetention       time.Duration `yaml:"contest_retention"`
+	RetentionMode          string        `yaml:"retention_mode"`
+	TransitionDays         int           `yaml:"transition_days"`
+	TransitionStorageClass string        `yaml:"transition_storage_class"`
+	CheckInterval          time.Duration `yaml:"check_interval"`
 }
 
 type ValkeyConfig struct {
@@ -63,12 +126,101 @@ type JudgeConfig struct {
 	MaxMemoryLimit     int           `yaml:"max_memory_limit"`
 	MaxStackSize       int           `yaml:"max_stack_size"`
 	MaxOutputSize      int           `yaml:"max_output_size"`
+	CheckerTimeBudget  time.Duration `yaml:"checker_time_budget"`
+	AutoScaleDryRun    bool          `yaml:"auto_scale_dry_run"`
+	JudgingBudget      time.Duration `yaml:"judging_budget"`
+	TestOverhead       time.Duration `yaml:"test_overhead"`
+	SchedulerBuffer    int           `yaml:"scheduler_buffer"`
+	// ShutdownGracePeriod is how long Stop waits for running judgings before
+	// saving their finished tests and giving up on them.
+	ShutdownGracePeriod time.Duration `yaml:"shutdown_grace_period"`
+	// ReservedWorkers are kept free for submissions at or above ReservedPriority.
+	ReservedWorkers  int            `yaml:"reserved_workers"`
+	ReservedPriority int            `yaml:"reserved_priority"`
+	TLERetry         TLERetryConfig `yaml:"tle_retry"`
+	// StderrVisibility applies to problems without their own stderr policy.
+	StderrVisibility string `yaml:"stderr_visibility"`
+	// UsageReport keeps each test's sandbox usage: "off", "staff" or "users".
+	UsageReport string `yaml:"usage_report"`
+	// TestParallelism is how many tests of one submission a worker runs at
+	// once. Parallel runs share the host's CPUs, so timings get noisier.
+	TestParallelism int `yaml:"test_parallelism"`
+	// ContestWorkers are kept free for submissions from the contest queue.
+	ContestWorkers int        `yaml:"contest_workers"`
+	Seed           SeedConfig `yaml:"seed"`
+	// MaxDeliveryAttempts is how many times a submission is tried before it
+	// is parked in the dead letter queue as an internal error.
+	MaxDeliveryAttempts int `yaml:"max_delivery_attempts"`
+}
+
+// SeedConfig gives randomized solutions a reproducible seed. When enabled,
+// every run of a test sees the same integer in EnvVar, derived from Secret,
+// the submission and the test number, so rejudges and appeal reruns repeat
+// the original run exactly. The seed is recorded with the test result.
+type SeedConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	EnvVar  string `yaml:"env_var"`
+	Secret  string `yaml:"secret"`
+}
+
+// TLERetryPolicy re-runs a test that exceeded the time limit by at most
+// MarginPercent, up to MaxRetries times; the first run within the limit counts.
+type TLERetryPolicy struct {
+	Enabled       bool    `yaml:"enabled"`
+	MarginPercent float64 `yaml:"margin_percent"`
+	MaxRetries    int     `yaml:"max_retries"`
+}
+
+// TLERetryConfig is the default policy plus per-contest overrides. Zero
+// margin or retries in an override inherit the default.
+type TLERetryConfig struct {
+	TLERetryPolicy `yaml:",inline"`
+	Contests       map[int64]TLERetryPolicy `yaml:"contests"`
+}
+
+func (c TLERetryConfig) For(contestID *int64) TLERetryPolicy {
+	if contestID == nil {
+		return c.TLERetryPolicy
+	}
+	policy, ok := c.Contests[*contestID]
+	if !ok {
+		return c.TLERetryPolicy
+	}
+	if policy.MarginPercent <= 0 {
+		policy.MarginPercent = c.MarginPercent
+	}
+	if policy.MaxRetries <= 0 {
+		policy.MaxRetries = c.MaxRetries
+	}
+	return policy
 }
 
 type IsolateConfig struct {
-	Path     string `yaml:"path"`
-	BoxRoot  string `yaml:"box_root"`
-	MaxBoxes int    `yaml:"max_boxes"`
+	Path              string        `yaml:"path"`
+	BoxRoot           string        `yaml:"box_root"`
+	MaxBoxes          int           `yaml:"max_boxes"`
+	CacheDirs         []string      `yaml:"cache_dirs"`
+	DiskWarnRatio     float64       `yaml:"disk_warn_ratio"`
+	DiskCriticalRatio float64       `yaml:"disk_critical_ratio"`
+	DiskCheckInterval time.Duration `yaml:"disk_check_interval"`
+	JavaClassMode     string        `yaml:"java_class_mode"`
+	PCHEnabled        bool          `yaml:"pch_enabled"`
+	PCHDir            string        `yaml:"pch_dir"`
+	// DataDir caches problem supplementary files; DataMaxBytes caps one problem's set.
+	DataDir      string `yaml:"data_dir"`
+	DataMaxBytes int64  `yaml:"data_max_bytes"`
+	// WorkDir holds each submission's compiled program while its tests run.
+	WorkDir string `yaml:"work_dir"`
+	// StderrMaxBytes caps the program stderr kept per test result.
+	StderrMaxBytes int `yaml:"stderr_max_bytes"`
+	// BoxPoolSize boxes are kept initialized and reused between runs.
+	BoxPoolSize int `yaml:"box_pool_size"`
+	// WarmPools sets how many pooled boxes start warm for each language;
+	// admins can resize them at runtime.
+	WarmPools map[string]int `yaml:"warm_pools"`
+	// Backend is "isolate" or "runsc" for hosts that cannot run isolate.
+	Backend   string `yaml:"backend"`
+	RunscPath string `yaml:"runsc_path"`
 }
 
 type JWTConfig struct {
@@ -83,6 +235,120 @@ type PlagiarismConfig struct {
 	CheckInterval          time.Duration `yaml:"check_interval"`
 	MaxSubmissionsPerCheck int           `yaml:"max_submissions_per_check"`
 	Algorithms             []string      `yaml:"algorithms"`
+	// The winnowing algorithm hashes k-grams of WinnowingK tokens and keeps
+	// the minimum hash of every WinnowingWindow consecutive k-grams.
+	WinnowingK      int `yaml:"winnowing_k"`
+	WinnowingWindow int `yaml:"winnowing_window"`
+	// CrossLanguage also compares submissions in different languages on a
+	// language-agnostic token stream, to catch translated solutions.
+	CrossLanguage bool `yaml:"cross_language"`
+	// Backend selects "internal", "moss" or "jplag"; ContestBackends overrides it per contest ID.
+	Backend         string           `yaml:"backend"`
+	ContestBackends map[int64]string `yaml:"contest_backends"`
+	ExternalWeight  float64          `yaml:"external_weight"`
+	ExternalTimeout time.Duration    `yaml:"external_timeout"`
+	MOSSServer      string           `yaml:"moss_server"`
+	MOSSUserID      string           `yaml:"moss_user_id"`
+	JPlagCommand    []string         `yaml:"jplag_command"`
+	// Detection slows down once judge utilization reaches ThrottleUtilization
+	// and pauses while the judge queue also holds PauseQueueDepth submissions.
+	ThrottleUtilization float64       `yaml:"throttle_utilization"`
+	PauseQueueDepth     int           `yaml:"pause_queue_depth"`
+	ThrottleDelay       time.Duration `yaml:"throttle_delay"`
+	LoadCheckInterval   time.Duration `yaml:"load_check_interval"`
+}
+
+type EventsConfig struct {
+	RetentionPeriod time.Duration `yaml:"retention_period"`
+}
+
+type RBACConfig struct {
+	DecisionCacheTTL time.Duration `yaml:"decision_cache_ttl"`
+}
+
+// SigningConfig holds the Ed25519 key used to sign final verdicts. PrivateKey
+// is the base64-encoded 32-byte seed or 64-byte private key.
+type SigningConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	PrivateKey string `yaml:"private_key"`
+	KeyID      string `yaml:"key_id"`
+}
+
+// APIKeysConfig bounds self-service API keys. RateLimit is both the default
+// and the highest per-key limit, in requests per minute.
+type APIKeysConfig struct {
+	RateLimit  int `yaml:"rate_limit"`
+	MaxPerUser int `yaml:"max_per_user"`
+}
+
+// GRPCConfig serves the internal submission API to other services, which
+// authenticate with service-account tokens.
+type GRPCConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Port    string `yaml:"port"`
+}
+
+// RejudgeConfig throttles bulk rejudge jobs. Rate is the number of
+// submissions requeued per second by each job.
+type RejudgeConfig struct {
+	Rate      int `yaml:"rate"`
+	BatchSize int `yaml:"batch_size"`
+}
+
+// ScoringConfig sets how a user's submission scores combine into their score
+// on a problem that has no aggregation of its own: sum, max or last.
+type ScoringConfig struct {
+	DefaultAggregation string `yaml:"default_aggregation"`
+}
+
+// UserStatsConfig sets how often the per-user verdict counters are checked
+// against the submissions table.
+type UserStatsConfig struct {
+	RepairInterval time.Duration `yaml:"repair_interval"`
+}
+
+// ExecutionLogConfig decides which execution logs are written to the
+// database. SampleRates maps a level to the fraction of submissions whose
+// logs at that level are kept; levels not listed are always kept. Every log
+// of a submission flagged for debugging is kept. WARN and ERROR logs of
+// contest submissions are also streamed to proctors, each stream capped at
+// TailEventsPerSecond, with at most TailMaxStreams streams per node.
+type ExecutionLogConfig struct {
+	SampleRates         map[string]float64 `yaml:"sample_rates"`
+	TailEventsPerSecond int                `yaml:"tail_events_per_second"`
+	TailMaxStreams      int                `yaml:"tail_max_streams"`
+}
+
+// GitIntakeConfig allows submissions that reference a commit of a git
+// repository. Only https URLs on AllowedHosts are fetched, and the checkout
+// may hold at most MaxFiles files and MaxBytes bytes.
+type GitIntakeConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	AllowedHosts []string      `yaml:"allowed_hosts"`
+	MaxFiles     int           `yaml:"max_files"`
+	MaxBytes     int64         `yaml:"max_bytes"`
+	FetchTimeout time.Duration `yaml:"fetch_timeout"`
+}
+
+// QueueSnapshotConfig records what each node has queued every Interval and
+// keeps the snapshots for Retention, so postmortems can see the queue as it
+// was.
+type QueueSnapshotConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Interval  time.Duration `yaml:"interval"`
+	Retention time.Duration `yaml:"retention"`
+}
+
+// ConsistencyConfig drives the canary check that compares judging across
+// nodes. A node deviates when a canary verdict differs from the expected one
+// or its time exceeds the median across nodes by more than TimeTolerance, a
+// fraction. With Drain set, a deviating node stops taking submissions until
+// a later check passes.
+type ConsistencyConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Interval      time.Duration `yaml:"interval"`
+	TimeTolerance float64       `yaml:"time_tolerance"`
+	Drain         bool          `yaml:"drain"`
 }
 
 func Load() (*Config, error) {
@@ -128,6 +394,11 @@ func loadFromEnv(cfg *Config) error {
 	if dbURL := os.Getenv("DATABASE_URL"); dbURL != "" {
 		cfg.Database.URL = dbURL
 	}
+	if autoMigrate := os.Getenv("DB_AUTO_MIGRATE"); autoMigrate != "" {
+		if a, err := strconv.ParseBool(autoMigrate); err == nil {
+			cfg.Database.AutoMigrate = a
+		}
+	}
 
 	if rabbitURL := os.Getenv("RABBITMQ_URL"); rabbitURL != "" {
 		cfg.RabbitMQ.URL = rabbitURL
@@ -139,6 +410,12 @@ func loadFromEnv(cfg *Config) error {
 	if cfg.RabbitMQ.QueueName == "" {
 		cfg.RabbitMQ.QueueName = "judge.submissions"
 	}
+	if contestQueueName := os.Getenv("RABBITMQ_CONTEST_QUEUE_NAME"); contestQueueName != "" {
+		cfg.RabbitMQ.ContestQueueName = contestQueueName
+	}
+	if cfg.RabbitMQ.ContestQueueName == "" {
+		cfg.RabbitMQ.ContestQueueName = "judge.contest"
+	}
 
 	if prefetchCount := os.Getenv("RABBITMQ_PREFETCH_COUNT"); prefetchCount != "" {
 		if count, err := strconv.Atoi(prefetchCount); err == nil {
@@ -149,6 +426,62 @@ func loadFromEnv(cfg *Config) error {
 		cfg.RabbitMQ.PrefetchCount = 1
 	}
 
+	if exchange := os.Getenv("RABBITMQ_EVENTS_EXCHANGE"); exchange != "" {
+		cfg.RabbitMQ.EventsExchange = exchange
+	}
+	if cfg.RabbitMQ.EventsExchange == "" {
+		cfg.RabbitMQ.EventsExchange = "codehakam.events"
+	}
+
+	if dlx := os.Getenv("RABBITMQ_DEAD_LETTER_EXCHANGE"); dlx != "" {
+		cfg.RabbitMQ.DeadLetterExchange = dlx
+	}
+	if cfg.RabbitMQ.DeadLetterExchange == "" {
+		cfg.RabbitMQ.DeadLetterExchange = "judge.failed"
+	}
+
+	if exchange := os.Getenv("RABBITMQ_CACHE_EXCHANGE"); exchange != "" {
+		cfg.RabbitMQ.CacheExchange = exchange
+	}
+	if cfg.RabbitMQ.CacheExchange == "" {
+		cfg.RabbitMQ.CacheExchange = "judge.cache"
+	}
+
+	// RABBITMQ_ROUTING_KEYS takes EventType=template pairs separated by commas
+	if routingKeys := os.Getenv("RABBITMQ_ROUTING_KEYS"); routingKeys != "" {
+		if cfg.RabbitMQ.RoutingKeys == nil {
+			cfg.RabbitMQ.RoutingKeys = make(map[string]string)
+		}
+		for _, pair := range strings.Split(routingKeys, ",") {
+			if eventType, template, ok := strings.Cut(strings.TrimSpace(pair), "="); ok {
+				cfg.RabbitMQ.RoutingKeys[eventType] = template
+			}
+		}
+	}
+
+	if enabled := os.Getenv("QUEUE_ENCRYPTION_ENABLED"); enabled != "" {
+		if e, err := strconv.ParseBool(enabled); err == nil {
+			cfg.RabbitMQ.Encryption.Enabled = e
+		}
+	}
+	if keyID := os.Getenv("QUEUE_ENCRYPTION_KEY_ID"); keyID != "" {
+		cfg.RabbitMQ.Encryption.ActiveKeyID = keyID
+	}
+	// QUEUE_ENCRYPTION_KEYS takes keyID=base64key pairs separated by commas
+	if keys := os.Getenv("QUEUE_ENCRYPTION_KEYS"); keys != "" {
+		if cfg.RabbitMQ.Encryption.Keys == nil {
+			cfg.RabbitMQ.Encryption.Keys = make(map[string]string)
+		}
+		for _, pair := range strings.Split(keys, ",") {
+			if keyID, key, ok := strings.Cut(strings.TrimSpace(pair), "="); ok {
+				cfg.RabbitMQ.Encryption.Keys[keyID] = key
+			}
+		}
+	}
+	if events := os.Getenv("QUEUE_ENCRYPTION_EVENTS"); events != "" {
+		cfg.RabbitMQ.Encryption.Events = strings.Split(events, ",")
+	}
+
 	if endpoint := os.Getenv("MINIO_ENDPOINT"); endpoint != "" {
 		cfg.MinIO.Endpoint = endpoint
 	}
@@ -170,6 +503,102 @@ func loadFromEnv(cfg *Config) error {
 			cfg.MinIO.UseSSL = ssl
 		}
 	}
+	if scoped := os.Getenv("MINIO_SCOPED_CREDENTIALS"); scoped != "" {
+		if sc, err := strconv.ParseBool(scoped); err == nil {
+			cfg.MinIO.ScopedCredentials = sc
+		}
+	}
+
+	policies := &cfg.MinIO.Policies
+	if enabled := os.Getenv("MINIO_POLICIES_ENABLED"); enabled != "" {
+		if e, err := strconv.ParseBool(enabled); err == nil {
+			policies.Enabled = e
+		}
+	}
+	if encryption := os.Getenv("MINIO_ENCRYPTION"); encryption != "" {
+		policies.Encryption = encryption
+	}
+	if keyID := os.Getenv("MINIO_KMS_KEY_ID"); keyID != "" {
+		policies.KMSKeyID = keyID
+	}
+	switch policies.Encryption {
+	case "", "sse-s3":
+	case "sse-kms":
+		if policies.KMSKeyID == "" {
+			return fmt.Errorf("sse-kms encryption needs a KMS key ID")
+		}
+	default:
+		return fmt.Errorf("unknown bucket encryption %q", policies.Encryption)
+	}
+	if retention := os.Getenv("MINIO_CONTEST_RETENTION"); retention != "" {
+		if r, err := time.ParseDuration(retention); err == nil {
+			policies.ContestRetention = r
+		}
+	}
+	if mode := os.Getenv("MINIO_RETENTION_MODE"); mode != "" {
+		policies.RetentionMode = mode
+	}
+	switch policies.RetentionMode {
+	case "":
+		policies.RetentionMode = "GOVERNANCE"
+	case "GOVERNANCE", "COMPLIANCE":
+	default:
+		return fmt.Errorf("unknown retention mode %q", policies.RetentionMode)
+	}
+	if days := os.Getenv("MINIO_TRANSITION_DAYS"); days != "" {
+		if d, err := strconv.Atoi(days); err == nil {
+			policies.TransitionDays = d
+		}
+	}
+	if class := os.Getenv("MINIO_TRANSITION_STORAGE_CLASS"); class != "" {
+		policies.TransitionStorageClass = class
+	}
+	if policies.TransitionDays > 0 && policies.TransitionStorageClass == "" {
+		return fmt.Errorf("code transition needs a storage class")
+	}
+	if interval := os.Getenv("MINIO_POLICY_CHECK_INTERVAL"); interval != "" {
+		if i, err := time.ParseDuration(interval); err == nil {
+			policies.CheckInterval = i
+		}
+	}
+	if policies.CheckInterval == 0 {
+		policies.CheckInterval = time.Hour
+	}
+
+	spool := &cfg.MinIO.Spool
+	if enabled := os.Getenv("MINIO_SPOOL_ENABLED"); enabled != "" {
+		if e, err := strconv.ParseBool(enabled); err == nil {
+			spool.Enabled = e
+		}
+	}
+	if dir := os.Getenv("MINIO_SPOOL_DIR"); dir != "" {
+		spool.Dir = dir
+	}
+	if maxBytes := os.Getenv("MINIO_SPOOL_MAX_BYTES"); maxBytes != "" {
+		if m, err := strconv.ParseInt(maxBytes, 10, 64); err == nil {
+			spool.MaxBytes = m
+		}
+	}
+	if key := os.Getenv("MINIO_SPOOL_KEY"); key != "" {
+		spool.Key = key
+	}
+	if interval := os.Getenv("MINIO_SPOOL_UPLOAD_INTERVAL"); interval != "" {
+		if i, err := time.ParseDuration(interval); err == nil {
+			spool.UploadInterval = i
+		}
+	}
+	if spool.Dir == "" {
+		spool.Dir = "/var/cache/codehakam/spool"
+	}
+	if spool.MaxBytes <= 0 {
+		spool.MaxBytes = 256 << 20
+	}
+	if spool.UploadInterval <= 0 {
+		spool.UploadInterval = 15 * time.Second
+	}
+	if spool.Enabled && spool.Key == "" {
+		return fmt.Errorf("MINIO_SPOOL_KEY is required when spooling is enabled")
+	}
 
 	if valkeyURL := os.Getenv("VALKEY_URL"); valkeyURL != "" {
 		cfg.Valkey.URL = valkeyURL
@@ -205,6 +634,176 @@ func loadFromEnv(cfg *Config) error {
 	if cfg.Judge.MaxQueueSize == 0 {
 		cfg.Judge.MaxQueueSize = 1000
 	}
+	if cfg.Judge.DefaultTimeLimit == 0 {
+		cfg.Judge.DefaultTimeLimit = 2 * time.Second
+	}
+	if cfg.Judge.DefaultMemoryLimit == 0 {
+		cfg.Judge.DefaultMemoryLimit = 262144
+	}
+	if cfg.Judge.MaxTimeLimit == 0 {
+		cfg.Judge.MaxTimeLimit = 30 * time.Second
+	}
+	if cfg.Judge.MaxMemoryLimit == 0 {
+		cfg.Judge.MaxMemoryLimit = 524288
+	}
+	if cfg.Judge.MaxStackSize == 0 {
+		cfg.Judge.MaxStackSize = 65536
+	}
+	if cfg.Judge.MaxOutputSize == 0 {
+		cfg.Judge.MaxOutputSize = 16384
+	}
+
+	if budget := os.Getenv("CHECKER_TIME_BUDGET"); budget != "" {
+		if b, err := time.ParseDuration(budget); err == nil {
+			cfg.Judge.CheckerTimeBudget = b
+		}
+	}
+	if cfg.Judge.CheckerTimeBudget == 0 {
+		cfg.Judge.CheckerTimeBudget = 5 * time.Second
+	}
+
+	if budget := os.Getenv("JUDGING_BUDGET"); budget != "" {
+		if b, err := time.ParseDuration(budget); err == nil {
+			cfg.Judge.JudgingBudget = b
+		}
+	}
+	if cfg.Judge.JudgingBudget == 0 {
+		cfg.Judge.JudgingBudget = 15 * time.Minute
+	}
+
+	if overhead := os.Getenv("JUDGING_TEST_OVERHEAD"); overhead != "" {
+		if o, err := time.ParseDuration(overhead); err == nil {
+			cfg.Judge.TestOverhead = o
+		}
+	}
+	if cfg.Judge.TestOverhead == 0 {
+		cfg.Judge.TestOverhead = 500 * time.Millisecond
+	}
+
+	if buffer := os.Getenv("JUDGE_SCHEDULER_BUFFER"); buffer != "" {
+		if b, err := strconv.Atoi(buffer); err == nil {
+			cfg.Judge.SchedulerBuffer = b
+		}
+	}
+	if cfg.Judge.SchedulerBuffer <= 0 {
+		cfg.Judge.SchedulerBuffer = 32
+	}
+
+	if grace := os.Getenv("JUDGE_SHUTDOWN_GRACE_PERIOD"); grace != "" {
+		if g, err := time.ParseDuration(grace); err == nil {
+			cfg.Judge.ShutdownGracePeriod = g
+		}
+	}
+	if cfg.Judge.ShutdownGracePeriod <= 0 {
+		cfg.Judge.ShutdownGracePeriod = 30 * time.Second
+	}
+
+	if reserved := os.Getenv("JUDGE_RESERVED_WORKERS"); reserved != "" {
+		if r, err := strconv.Atoi(reserved); err == nil {
+			cfg.Judge.ReservedWorkers = r
+		}
+	}
+
+	if contestWorkers := os.Getenv("JUDGE_CONTEST_WORKERS"); contestWorkers != "" {
+		if w, err := strconv.Atoi(contestWorkers); err == nil {
+			cfg.Judge.ContestWorkers = w
+		}
+	}
+
+	if priority := os.Getenv("JUDGE_RESERVED_PRIORITY"); priority != "" {
+		if p, err := strconv.Atoi(priority); err == nil {
+			cfg.Judge.ReservedPriority = p
+		}
+	}
+	if cfg.Judge.ReservedPriority == 0 {
+		cfg.Judge.ReservedPriority = 5
+	}
+
+	if visibility := os.Getenv("JUDGE_STDERR_VISIBILITY"); visibility != "" {
+		cfg.Judge.StderrVisibility = visibility
+	}
+	if cfg.Judge.StderrVisibility == "" {
+		cfg.Judge.StderrVisibility = "samples"
+	}
+
+	if report := os.Getenv("JUDGE_USAGE_REPORT"); report != "" {
+		cfg.Judge.UsageReport = report
+	}
+	switch cfg.Judge.UsageReport {
+	case "":
+		cfg.Judge.UsageReport = "off"
+	case "off", "staff", "users":
+	default:
+		return fmt.Errorf("unknown usage report setting %q", cfg.Judge.UsageReport)
+	}
+
+	seed := &cfg.Judge.Seed
+	if enabled := os.Getenv("JUDGE_SEED_ENABLED"); enabled != "" {
+		if e, err := strconv.ParseBool(enabled); err == nil {
+			seed.Enabled = e
+		}
+	}
+	if envVar := os.Getenv("JUDGE_SEED_ENV_VAR"); envVar != "" {
+		seed.EnvVar = envVar
+	}
+	if secret := os.Getenv("JUDGE_SEED_SECRET"); secret != "" {
+		seed.Secret = secret
+	}
+	if seed.EnvVar == "" {
+		seed.EnvVar = "JUDGE_SEED"
+	}
+	if !validEnvName(seed.EnvVar) || seed.EnvVar == "HOME" || seed.EnvVar == "PATH" {
+		return fmt.Errorf("invalid seed environment variable %q", seed.EnvVar)
+	}
+	if seed.Enabled && seed.Secret == "" {
+		return fmt.Errorf("JUDGE_SEED_SECRET is required when seed injection is enabled")
+	}
+
+	if parallelism := os.Getenv("JUDGE_TEST_PARALLELISM"); parallelism != "" {
+		if p, err := strconv.Atoi(parallelism); err == nil {
+			cfg.Judge.TestParallelism = p
+		}
+	}
+	if cfg.Judge.TestParallelism <= 0 {
+		cfg.Judge.TestParallelism = 1
+	}
+
+	if attempts := os.Getenv("JUDGE_MAX_DELIVERY_ATTEMPTS"); attempts != "" {
+		if a, err := strconv.Atoi(attempts); err == nil {
+			cfg.Judge.MaxDeliveryAttempts = a
+		}
+	}
+	if cfg.Judge.MaxDeliveryAttempts <= 0 {
+		cfg.Judge.MaxDeliveryAttempts = 3
+	}
+
+	if enabled := os.Getenv("TLE_RETRY_ENABLED"); enabled != "" {
+		if e, err := strconv.ParseBool(enabled); err == nil {
+			cfg.Judge.TLERetry.Enabled = e
+		}
+	}
+	if margin := os.Getenv("TLE_RETRY_MARGIN_PERCENT"); margin != "" {
+		if m, err := strconv.ParseFloat(margin, 64); err == nil {
+			cfg.Judge.TLERetry.MarginPercent = m
+		}
+	}
+	if cfg.Judge.TLERetry.MarginPercent <= 0 {
+		cfg.Judge.TLERetry.MarginPercent = 10
+	}
+	if retries := os.Getenv("TLE_RETRY_MAX_RETRIES"); retries != "" {
+		if r, err := strconv.Atoi(retries); err == nil {
+			cfg.Judge.TLERetry.MaxRetries = r
+		}
+	}
+	if cfg.Judge.TLERetry.MaxRetries <= 0 {
+		cfg.Judge.TLERetry.MaxRetries = 2
+	}
+
+	if dryRun := os.Getenv("AUTOSCALE_DRY_RUN"); dryRun != "" {
+		if d, err := strconv.ParseBool(dryRun); err == nil {
+			cfg.Judge.AutoScaleDryRun = d
+		}
+	}
 
 	if isolatePath := os.Getenv("ISOLATE_PATH"); isolatePath != "" {
 		cfg.Isolate.Path = isolatePath
@@ -220,6 +819,112 @@ func loadFromEnv(cfg *Config) error {
 		cfg.Isolate.BoxRoot = "/var/local/lib/isolate"
 	}
 
+	if warn := os.Getenv("DISK_WARN_RATIO"); warn != "" {
+		if w, err := strconv.ParseFloat(warn, 64); err == nil {
+			cfg.Isolate.DiskWarnRatio = w
+		}
+	}
+	if cfg.Isolate.DiskWarnRatio == 0 {
+		cfg.Isolate.DiskWarnRatio = 0.80
+	}
+
+	if critical := os.Getenv("DISK_CRITICAL_RATIO"); critical != "" {
+		if cr, err := strconv.ParseFloat(critical, 64); err == nil {
+			cfg.Isolate.DiskCriticalRatio = cr
+		}
+	}
+	if cfg.Isolate.DiskCriticalRatio == 0 {
+		cfg.Isolate.DiskCriticalRatio = 0.95
+	}
+
+	if interval := os.Getenv("DISK_CHECK_INTERVAL"); interval != "" {
+		if i, err := time.ParseDuration(interval); err == nil {
+			cfg.Isolate.DiskCheckInterval = i
+		}
+	}
+	if cfg.Isolate.DiskCheckInterval == 0 {
+		cfg.Isolate.DiskCheckInterval = 30 * time.Second
+	}
+
+	if dataDir := os.Getenv("ISOLATE_DATA_DIR"); dataDir != "" {
+		cfg.Isolate.DataDir = dataDir
+	}
+	if cfg.Isolate.DataDir == "" {
+		cfg.Isolate.DataDir = "/var/cache/codehakam/data"
+	}
+	if workDir := os.Getenv("ISOLATE_WORK_DIR"); workDir != "" {
+		cfg.Isolate.WorkDir = workDir
+	}
+	if cfg.Isolate.WorkDir == "" {
+		cfg.Isolate.WorkDir = "/var/cache/codehakam/work"
+	}
+	if maxBytes := os.Getenv("ISOLATE_DATA_MAX_BYTES"); maxBytes != "" {
+		if m, err := strconv.ParseInt(maxBytes, 10, 64); err == nil {
+			cfg.Isolate.DataMaxBytes = m
+		}
+	}
+	if cfg.Isolate.DataMaxBytes == 0 {
+		cfg.Isolate.DataMaxBytes = 512 << 20
+	}
+
+	if maxBytes := os.Getenv("ISOLATE_STDERR_MAX_BYTES"); maxBytes != "" {
+		if m, err := strconv.Atoi(maxBytes); err == nil {
+			cfg.Isolate.StderrMaxBytes = m
+		}
+	}
+	if cfg.Isolate.StderrMaxBytes == 0 {
+		cfg.Isolate.StderrMaxBytes = 8192
+	}
+
+	if size := os.Getenv("ISOLATE_BOX_POOL_SIZE"); size != "" {
+		if s, err := strconv.Atoi(size); err == nil {
+			cfg.Isolate.BoxPoolSize = s
+		}
+	}
+	if cfg.Isolate.BoxPoolSize == 0 {
+		cfg.Isolate.BoxPoolSize = 8
+	}
+
+	if backend := os.Getenv("SANDBOX_BACKEND"); backend != "" {
+		cfg.Isolate.Backend = backend
+	}
+	switch cfg.Isolate.Backend {
+	case "":
+		cfg.Isolate.Backend = "isolate"
+	case "isolate", "runsc":
+	default:
+		return fmt.Errorf("unknown sandbox backend %q", cfg.Isolate.Backend)
+	}
+	if runscPath := os.Getenv("RUNSC_PATH"); runscPath != "" {
+		cfg.Isolate.RunscPath = runscPath
+	}
+	if cfg.Isolate.RunscPath == "" {
+		cfg.Isolate.RunscPath = "/usr/local/bin/runsc"
+	}
+
+	if cfg.Isolate.CacheDirs == nil {
+		cfg.Isolate.CacheDirs = []string{"/tmp/checker", cfg.Isolate.DataDir}
+	}
+
+	if mode := os.Getenv("JAVA_CLASS_MODE"); mode != "" {
+		cfg.Isolate.JavaClassMode = mode
+	}
+	if cfg.Isolate.JavaClassMode == "" {
+		cfg.Isolate.JavaClassMode = "rename"
+	}
+
+	if enabled := os.Getenv("ISOLATE_PCH_ENABLED"); enabled != "" {
+		if e, err := strconv.ParseBool(enabled); err == nil {
+			cfg.Isolate.PCHEnabled = e
+		}
+	}
+	if pchDir := os.Getenv("ISOLATE_PCH_DIR"); pchDir != "" {
+		cfg.Isolate.PCHDir = pchDir
+	}
+	if cfg.Isolate.PCHDir == "" {
+		cfg.Isolate.PCHDir = "/var/cache/codehakam/pch"
+	}
+
 	if jwtSecret := os.Getenv("JWT_SECRET"); jwtSecret != "" {
 		cfg.JWT.Secret = jwtSecret
 	}
@@ -286,5 +991,339 @@ func loadFromEnv(cfg *Config) error {
 		cfg.Plagiarism.Algorithms = []string{"tokens", "lines", "structure", "variables", "functions"}
 	}
 
+	if k := os.Getenv("PLAGIARISM_WINNOWING_K"); k != "" {
+		if n, err := strconv.Atoi(k); err == nil {
+			cfg.Plagiarism.WinnowingK = n
+		}
+	}
+	if cfg.Plagiarism.WinnowingK == 0 {
+		cfg.Plagiarism.WinnowingK = 5
+	}
+
+	if window := os.Getenv("PLAGIARISM_WINNOWING_WINDOW"); window != "" {
+		if w, err := strconv.Atoi(window); err == nil {
+			cfg.Plagiarism.WinnowingWindow = w
+		}
+	}
+	if cfg.Plagiarism.WinnowingWindow == 0 {
+		cfg.Plagiarism.WinnowingWindow = 4
+	}
+
+	if crossLanguage := os.Getenv("PLAGIARISM_CROSS_LANGUAGE"); crossLanguage != "" {
+		if c, err := strconv.ParseBool(crossLanguage); err == nil {
+			cfg.Plagiarism.CrossLanguage = c
+		}
+	}
+
+	if backend := os.Getenv("PLAGIARISM_BACKEND"); backend != "" {
+		cfg.Plagiarism.Backend = backend
+	}
+	if cfg.Plagiarism.Backend == "" {
+		cfg.Plagiarism.Backend = "internal"
+	}
+
+	// PLAGIARISM_CONTEST_BACKENDS takes ContestID=backend pairs separated by commas
+	if contestBackends := os.Getenv("PLAGIARISM_CONTEST_BACKENDS"); contestBackends != "" {
+		if cfg.Plagiarism.ContestBackends == nil {
+			cfg.Plagiarism.ContestBackends = make(map[int64]string)
+		}
+		for _, pair := range strings.Split(contestBackends, ",") {
+			contest, backend, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				continue
+			}
+			if contestID, err := strconv.ParseInt(contest, 10, 64); err == nil {
+				cfg.Plagiarism.ContestBackends[contestID] = backend
+			}
+		}
+	}
+
+	if weight := os.Getenv("PLAGIARISM_EXTERNAL_WEIGHT"); weight != "" {
+		if w, err := strconv.ParseFloat(weight, 64); err == nil {
+			cfg.Plagiarism.ExternalWeight = w
+		}
+	}
+	if cfg.Plagiarism.ExternalWeight == 0 {
+		cfg.Plagiarism.ExternalWeight = 0.5
+	}
+
+	if timeout := os.Getenv("PLAGIARISM_EXTERNAL_TIMEOUT"); timeout != "" {
+		if t, err := time.ParseDuration(timeout); err == nil {
+			cfg.Plagiarism.ExternalTimeout = t
+		}
+	}
+	if cfg.Plagiarism.ExternalTimeout == 0 {
+		cfg.Plagiarism.ExternalTimeout = 5 * time.Minute
+	}
+
+	if utilization := os.Getenv("PLAGIARISM_THROTTLE_UTILIZATION"); utilization != "" {
+		if u, err := strconv.ParseFloat(utilization, 64); err == nil {
+			cfg.Plagiarism.ThrottleUtilization = u
+		}
+	}
+	if cfg.Plagiarism.ThrottleUtilization == 0 {
+		cfg.Plagiarism.ThrottleUtilization = 0.8
+	}
+
+	if depth := os.Getenv("PLAGIARISM_PAUSE_QUEUE_DEPTH"); depth != "" {
+		if d, err := strconv.Atoi(depth); err == nil {
+			cfg.Plagiarism.PauseQueueDepth = d
+		}
+	}
+	if cfg.Plagiarism.PauseQueueDepth == 0 {
+		cfg.Plagiarism.PauseQueueDepth = 10
+	}
+
+	if delay := os.Getenv("PLAGIARISM_THROTTLE_DELAY"); delay != "" {
+		if d, err := time.ParseDuration(delay); err == nil {
+			cfg.Plagiarism.ThrottleDelay = d
+		}
+	}
+	if cfg.Plagiarism.ThrottleDelay == 0 {
+		cfg.Plagiarism.ThrottleDelay = 10 * time.Second
+	}
+
+	if interval := os.Getenv("PLAGIARISM_LOAD_CHECK_INTERVAL"); interval != "" {
+		if i, err := time.ParseDuration(interval); err == nil {
+			cfg.Plagiarism.LoadCheckInterval = i
+		}
+	}
+	if cfg.Plagiarism.LoadCheckInterval == 0 {
+		cfg.Plagiarism.LoadCheckInterval = 5 * time.Second
+	}
+
+	if server := os.Getenv("MOSS_SERVER"); server != "" {
+		cfg.Plagiarism.MOSSServer = server
+	}
+	if cfg.Plagiarism.MOSSServer == "" {
+		cfg.Plagiarism.MOSSServer = "moss.stanford.edu:7690"
+	}
+	if userID := os.Getenv("MOSS_USER_ID"); userID != "" {
+		cfg.Plagiarism.MOSSUserID = userID
+	}
+
+	if command := os.Getenv("JPLAG_COMMAND"); command != "" {
+		cfg.Plagiarism.JPlagCommand = strings.Fields(command)
+	}
+	if len(cfg.Plagiarism.JPlagCommand) == 0 {
+		cfg.Plagiarism.JPlagCommand = []string{"java", "-jar", "/opt/jplag/jplag.jar"}
+	}
+
+	if retention := os.Getenv("EVENT_RETENTION_PERIOD"); retention != "" {
+		if r, err := time.ParseDuration(retention); err == nil {
+			cfg.Events.RetentionPeriod = r
+		}
+	}
+	if cfg.Events.RetentionPeriod == 0 {
+		cfg.Events.RetentionPeriod = 7 * 24 * time.Hour
+	}
+
+	if ttl := os.Getenv("RBAC_CACHE_TTL"); ttl != "" {
+		if t, err := time.ParseDuration(ttl); err == nil {
+			cfg.RBAC.DecisionCacheTTL = t
+		}
+	}
+	if cfg.RBAC.DecisionCacheTTL == 0 {
+		cfg.RBAC.DecisionCacheTTL = 30 * time.Second
+	}
+
+	if enabled := os.Getenv("VERDICT_SIGNING_ENABLED"); enabled != "" {
+		if e, err := strconv.ParseBool(enabled); err == nil {
+			cfg.Signing.Enabled = e
+		}
+	}
+	if key := os.Getenv("VERDICT_SIGNING_KEY"); key != "" {
+		cfg.Signing.PrivateKey = key
+	}
+	if keyID := os.Getenv("VERDICT_SIGNING_KEY_ID"); keyID != "" {
+		cfg.Signing.KeyID = keyID
+	}
+	if cfg.Signing.KeyID == "" {
+		cfg.Signing.KeyID = "default"
+	}
+
+	if limit := os.Getenv("API_KEY_RATE_LIMIT"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil {
+			cfg.APIKeys.RateLimit = l
+		}
+	}
+	if cfg.APIKeys.RateLimit <= 0 {
+		cfg.APIKeys.RateLimit = 60
+	}
+	if max := os.Getenv("API_KEY_MAX_PER_USER"); max != "" {
+		if m, err := strconv.Atoi(max); err == nil {
+			cfg.APIKeys.MaxPerUser = m
+		}
+	}
+	if cfg.APIKeys.MaxPerUser <= 0 {
+		cfg.APIKeys.MaxPerUser = 10
+	}
+
+	if enabled := os.Getenv("CONSISTENCY_CHECK_ENABLED"); enabled != "" {
+		if e, err := strconv.ParseBool(enabled); err == nil {
+			cfg.Consistency.Enabled = e
+		}
+	}
+	if interval := os.Getenv("CONSISTENCY_CHECK_INTERVAL"); interval != "" {
+		if i, err := time.ParseDuration(interval); err == nil {
+			cfg.Consistency.Interval = i
+		}
+	}
+	if cfg.Consistency.Interval == 0 {
+		cfg.Consistency.Interval = time.Hour
+	}
+	if tolerance := os.Getenv("CONSISTENCY_TIME_TOLERANCE"); tolerance != "" {
+		if t, err := strconv.ParseFloat(tolerance, 64); err == nil {
+			cfg.Consistency.TimeTolerance = t
+		}
+	}
+	if cfg.Consistency.TimeTolerance <= 0 {
+		cfg.Consistency.TimeTolerance = 0.25
+	}
+	if drain := os.Getenv("CONSISTENCY_DRAIN"); drain != "" {
+		if d, err := strconv.ParseBool(drain); err == nil {
+			cfg.Consistency.Drain = d
+		}
+	}
+
+	if enabled := os.Getenv("GRPC_ENABLED"); enabled != "" {
+		if e, err := strconv.ParseBool(enabled); err == nil {
+			cfg.GRPC.Enabled = e
+		}
+	}
+	if port := os.Getenv("GRPC_PORT"); port != "" {
+		cfg.GRPC.Port = port
+	}
+	if cfg.GRPC.Port == "" {
+		cfg.GRPC.Port = "3103"
+	}
+
+	if rate := os.Getenv("REJUDGE_RATE"); rate != "" {
+		if r, err := strconv.Atoi(rate); err == nil {
+			cfg.Rejudge.Rate = r
+		}
+	}
+	if cfg.Rejudge.Rate <= 0 {
+		cfg.Rejudge.Rate = 20
+	}
+	if batchSize := os.Getenv("REJUDGE_BATCH_SIZE"); batchSize != "" {
+		if b, err := strconv.Atoi(batchSize); err == nil {
+			cfg.Rejudge.BatchSize = b
+		}
+	}
+	if cfg.Rejudge.BatchSize <= 0 {
+		cfg.Rejudge.BatchSize = 100
+	}
+
+	if aggregation := os.Getenv("SCORING_DEFAULT_AGGREGATION"); aggregation != "" {
+		cfg.Scoring.DefaultAggregation = aggregation
+	}
+
+	if interval := os.Getenv("USER_STATS_REPAIR_INTERVAL"); interval != "" {
+		if i, err := time.ParseDuration(interval); err == nil {
+			cfg.UserStats.RepairInterval = i
+		}
+	}
+	if cfg.UserStats.RepairInterval <= 0 {
+		cfg.UserStats.RepairInterval = 6 * time.Hour
+	}
+	if cfg.Logs.SampleRates == nil {
+		cfg.Logs.SampleRates = map[string]float64{"INFO": 0.1, "DEBUG": 0}
+	}
+	if rate := os.Getenv("EXECUTION_LOG_TAIL_EVENTS_PER_SECOND"); rate != "" {
+		if n, err := strconv.Atoi(rate); err == nil {
+			cfg.Logs.TailEventsPerSecond = n
+		}
+	}
+	if cfg.Logs.TailEventsPerSecond <= 0 {
+		cfg.Logs.TailEventsPerSecond = 20
+	}
+	if streams := os.Getenv("EXECUTION_LOG_TAIL_MAX_STREAMS"); streams != "" {
+		if n, err := strconv.Atoi(streams); err == nil {
+			cfg.Logs.TailMaxStreams = n
+		}
+	}
+	if cfg.Logs.TailMaxStreams <= 0 {
+		cfg.Logs.TailMaxStreams = 50
+	}
+	sampleRates := make(map[string]float64, len(cfg.Logs.SampleRates))
+	for level, rate := range cfg.Logs.SampleRates {
+		sampleRates[strings.ToUpper(level)] = rate
+	}
+	cfg.Logs.SampleRates = sampleRates
+	for _, level := range []string{"DEBUG", "INFO"} {
+		if rate := os.Getenv("EXECUTION_LOG_" + level + "_SAMPLE_RATE"); rate != "" {
+			if r, err := strconv.ParseFloat(rate, 64); err == nil {
+				cfg.Logs.SampleRates[level] = r
+			}
+		}
+	}
+	for level, rate := range cfg.Logs.SampleRates {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("execution log sample rate for %s must be between 0 and 1", level)
+		}
+	}
+
+	if enabled := os.Getenv("GIT_INTAKE_ENABLED"); enabled != "" {
+		if e, err := strconv.ParseBool(enabled); err == nil {
+			cfg.GitIntake.Enabled = e
+		}
+	}
+	if hosts := os.Getenv("GIT_INTAKE_ALLOWED_HOSTS"); hosts != "" {
+		cfg.GitIntake.AllowedHosts = strings.Split(hosts, ",")
+	}
+	for i, host := range cfg.GitIntake.AllowedHosts {
+		cfg.GitIntake.AllowedHosts[i] = strings.ToLower(strings.TrimSpace(host))
+	}
+	if cfg.GitIntake.MaxFiles <= 0 {
+		cfg.GitIntake.MaxFiles = 200
+	}
+	if cfg.GitIntake.MaxBytes <= 0 {
+		cfg.GitIntake.MaxBytes = 5 << 20
+	}
+	if cfg.GitIntake.FetchTimeout <= 0 {
+		cfg.GitIntake.FetchTimeout = 30 * time.Second
+	}
+
+	if enabled := os.Getenv("QUEUE_SNAPSHOTS_ENABLED"); enabled != "" {
+		if e, err := strconv.ParseBool(enabled); err == nil {
+			cfg.QueueSnapshots.Enabled = e
+		}
+	}
+	if interval := os.Getenv("QUEUE_SNAPSHOT_INTERVAL"); interval != "" {
+		if i, err := time.ParseDuration(interval); err == nil {
+			cfg.QueueSnapshots.Interval = i
+		}
+	}
+	if cfg.QueueSnapshots.Interval <= 0 {
+		cfg.QueueSnapshots.Interval = time.Minute
+	}
+	if retention := os.Getenv("QUEUE_SNAPSHOT_RETENTION"); retention != "" {
+		if r, err := time.ParseDuration(retention); err == nil {
+			cfg.QueueSnapshots.Retention = r
+		}
+	}
+	if cfg.QueueSnapshots.Retention <= 0 {
+		cfg.QueueSnapshots.Retention = 7 * 24 * time.Hour
+	}
+
+	switch cfg.Scoring.DefaultAggregation {
+	case "":
+		cfg.Scoring.DefaultAggregation = "max"
+	case "sum", "max", "last":
+	default:
+		return fmt.Errorf("unknown scoring aggregation %q", cfg.Scoring.DefaultAggregation)
+	}
+
 	return nil
 }
+
+// validEnvName reports whether name is usable as an environment variable.
+func validEnvName(name string) bool {
+	for i, r := range name {
+		if r != '_' && (r < 'A' || r > 'Z') && (r < '0' || r > '9' || i == 0) {
+			return false
+		}
+	}
+	return name != ""
+}
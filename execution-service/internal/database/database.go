@@ -3,15 +3,22 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"execution_service/internal/models"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+var ErrWorkerLeased = errors.New("worker name is leased by another process")
+
+// ErrIdempotencyKeyExists means another request already used the key.
+var ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+
 type DB struct {
 	conn *sqlx.DB
 }
@@ -37,15 +44,189 @@ func (db *DB) Ping(ctx context.Context) error {
 	return db.conn.PingContext(ctx)
 }
 
+func (db *DB) SQL() *sql.DB {
+	return db.conn.DB
+}
+
 func (db *DB) CreateSubmission(ctx context.Context, submission *models.Submission) error {
+	return insertSubmission(ctx, db.conn, submission)
+}
+
+// CreateSubmissionWithOutbox records the submission and its outbox entry
+// together, so a recorded submission is always queued eventually.
+func (db *DB) CreateSubmissionWithOutbox(ctx context.Context, submission *models.Submission, entry *models.OutboxEntry) error {
+	tx, err := db.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertSubmission(ctx, tx, submission); err != nil {
+		return err
+	}
+	entry.SubmissionID = submission.ID
+	if err := insertOutboxEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CreateSubmissionWithIdempotencyKey records the submission, its key and,
+// when entry is not nil, its outbox entry together. If the key is already
+// taken, nothing is recorded and ErrIdempotencyKeyExists is returned.
+func (db *DB) CreateSubmissionWithIdempotencyKey(ctx context.Context, submission *models.Submission, key *models.IdempotencyKey, entry *models.OutboxEntry) error {
+	tx, err := db.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertSubmission(ctx, tx, submission); err != nil {
+		return err
+	}
+	if entry != nil {
+		entry.SubmissionID = submission.ID
+		if err := insertOutboxEntry(ctx, tx, entry); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO execution.submission_idempotency_keys (user_id, idempotency_key, request_hash, submission_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, idempotency_key) DO NOTHING`,
+		key.UserID, key.Key, key.RequestHash, submission.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create idempotency key: %w", err)
+	}
+	if inserted, _ := result.RowsAffected(); inserted == 0 {
+		return ErrIdempotencyKeyExists
+	}
+	key.SubmissionID = submission.ID
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) GetIdempotencyKey(ctx context.Context, userID int64, key string) (*models.IdempotencyKey, error) {
+	query := `
+		SELECT user_id, idempotency_key, request_hash, submission_id, created_at
+		FROM execution.submission_idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2`
+
+	var idempotencyKey models.IdempotencyKey
+	err := db.conn.GetContext(ctx, &idempotencyKey, query, userID, key)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return &idempotencyKey, nil
+}
+
+func (db *DB) DeleteIdempotencyKey(ctx context.Context, userID int64, key string) error {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM execution.submission_idempotency_keys WHERE user_id = $1 AND idempotency_key = $2`, userID, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// CreateOutboxEntry records a judge request for a submission that already
+// exists.
+func (db *DB) CreateOutboxEntry(ctx context.Context, entry *models.OutboxEntry) error {
+	return insertOutboxEntry(ctx, db.conn, entry)
+}
+
+func insertOutboxEntry(ctx context.Context, q sqlx.QueryerContext, entry *models.OutboxEntry) error {
+	query := `
+		INSERT INTO execution.submission_outbox (submission_id, time_limit_ms, memory_limit_kb, priority, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	err := q.QueryRowxContext(ctx, query,
+		entry.SubmissionID,
+		entry.TimeLimitMs,
+		entry.MemoryLimitKb,
+		entry.Priority,
+		entry.NextAttemptAt,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create outbox entry: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimOutboxEntries returns up to limit entries that are due, pushing their
+// next attempt back by lease so no other node publishes them meanwhile.
+func (db *DB) ClaimOutboxEntries(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEntry, error) {
+	query := `
+		UPDATE execution.submission_outbox
+		SET attempts = attempts + 1, next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE id IN (
+			SELECT id FROM execution.submission_outbox
+			WHERE next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, submission_id, time_limit_ms, memory_limit_kb, priority, attempts, last_error,
+			next_attempt_at, created_at`
+
+	entries := []models.OutboxEntry{}
+	if err := db.conn.SelectContext(ctx, &entries, query, limit, lease.Seconds()); err != nil {
+		return nil, fmt.Errorf("failed to claim outbox entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// RetryOutboxEntry records why publishing failed and when to try again.
+func (db *DB) RetryOutboxEntry(ctx context.Context, id int64, message string, delay time.Duration) error {
+	_, err := db.conn.ExecContext(ctx, `
+		UPDATE execution.submission_outbox
+		SET last_error = $2, next_attempt_at = NOW() + $3 * INTERVAL '1 second'
+		WHERE id = $1`,
+		id, message, delay.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to update outbox entry: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) DeleteOutboxEntry(ctx context.Context, id int64) error {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM execution.submission_outbox WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete outbox entry: %w", err)
+	}
+
+	return nil
+}
+
+func insertSubmission(ctx context.Context, q sqlx.QueryerContext, submission *models.Submission) error {
 	query := `
 		INSERT INTO execution.submissions 
-		(user_id, problem_id, contest_id, language, code_url, verdict, score, test_cases_passed, test_cases_total, is_public)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		(user_id, team_id, problem_id, contest_id, language, code_url, verdict, score, test_cases_passed, test_cases_total, is_public, metadata, trusted,
+		 repository_url, commit_sha, entry_file)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		RETURNING id, submitted_at`
 
-	err := db.conn.QueryRowContext(ctx, query,
+	err := q.QueryRowxContext(ctx, query,
 		submission.UserID,
+		submission.TeamID,
 		submission.ProblemID,
 		submission.ContestID,
 		submission.Language,
@@ -55,6 +236,11 @@ func (db *DB) CreateSubmission(ctx context.Context, submission *models.Submissio
 		submission.TestCasesPassed,
 		submission.TestCasesTotal,
 		submission.IsPublic,
+		submission.Metadata,
+		submission.Trusted,
+		submission.RepositoryURL,
+		submission.CommitSHA,
+		submission.EntryFile,
 	).Scan(&submission.ID, &submission.SubmittedAt)
 
 	if err != nil {
@@ -66,9 +252,10 @@ func (db *DB) CreateSubmission(ctx context.Context, submission *models.Submissio
 
 func (db *DB) GetSubmission(ctx context.Context, id int64) (*models.Submission, error) {
 	query := `
-		SELECT id, user_id, problem_id, contest_id, language, code_url, verdict, 
+		SELECT id, user_id, team_id, problem_id, contest_id, language, code_url, verdict, 
 			   score, execution_time_ms, memory_used_kb, test_cases_passed, test_cases_total,
-			   compile_output, is_public, submitted_at, judged_at
+			   compile_output, is_public, metadata, testset_version, outdated_tests,
+			   trusted, repository_url, commit_sha, entry_file, submitted_at, judged_at
 		FROM execution.submissions 
 		WHERE id = $1`
 
@@ -88,16 +275,19 @@ func (db *DB) UpdateSubmissionResult(ctx context.Context, id int64, result *mode
 	query := `
 		UPDATE execution.submissions 
 		SET verdict = $2, execution_time_ms = $3, memory_used_kb = $4, 
-			test_cases_passed = $5, test_cases_total = $6, judged_at = NOW()
+			test_cases_passed = $5, test_cases_total = $6, judged_at = NOW(),
+			testset_version = NULLIF($7, ''), outdated_tests = FALSE, score = $8
 		WHERE id = $1`
 
-	_, err := db.conn.ExecContext(ctx, query,
+	err := db.updateSubmissionVerdict(ctx, id, result.Verdict, query,
 		id,
 		result.Verdict,
 		result.ExecutionTimeMs,
 		result.MemoryUsedKb,
 		result.TestCasesPassed,
 		result.TestCasesTotal,
+		result.TestsetVersion,
+		result.Score,
 	)
 
 	if err != nil {
@@ -107,13 +297,117 @@ func (db *DB) UpdateSubmissionResult(ctx context.Context, id int64, result *mode
 	return nil
 }
 
+// updateSubmissionVerdict runs an update that sets the submission's verdict
+// and moves the user's counters from the old verdict to the new one in the
+// same transaction.
+func (db *DB) updateSubmissionVerdict(ctx context.Context, id int64, verdict models.Verdict, query string, args ...interface{}) error {
+	tx, err := db.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current struct {
+		UserID    int64          `db:"user_id"`
+		ProblemID int64          `db:"problem_id"`
+		Verdict   models.Verdict `db:"verdict"`
+	}
+	err = tx.GetContext(ctx, &current, `
+		SELECT user_id, problem_id, verdict FROM execution.submissions
+		WHERE id = $1 FOR UPDATE`, id)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	if current.Verdict != verdict {
+		if err := adjustUserStats(ctx, tx, current.UserID, current.ProblemID, current.Verdict, -1); err != nil {
+			return err
+		}
+		if err := adjustUserStats(ctx, tx, current.UserID, current.ProblemID, verdict, 1); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// adjustUserStats adds delta to the user's counters for a final verdict.
+// Pending verdicts are not counted.
+func adjustUserStats(ctx context.Context, tx *sqlx.Tx, userID, problemID int64, verdict models.Verdict, delta int) error {
+	if verdict == models.VerdictPending {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO execution.user_verdict_counts (user_id, verdict, count)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, verdict) DO UPDATE SET count = user_verdict_counts.count + EXCLUDED.count`,
+		userID, verdict, delta)
+	if err != nil {
+		return fmt.Errorf("failed to update user verdict counts: %w", err)
+	}
+	if verdict != models.VerdictAccepted {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO execution.user_problem_accepts (user_id, problem_id, accepted)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, problem_id) DO UPDATE SET accepted = user_problem_accepts.accepted + EXCLUDED.accepted`,
+		userID, problemID, delta)
+	if err != nil {
+		return fmt.Errorf("failed to update user problem accepts: %w", err)
+	}
+	return nil
+}
+
+// MarkOutdatedSubmissions flags submissions of a problem judged on a testset
+// other than currentVersion and returns how many were newly flagged.
+func (db *DB) MarkOutdatedSubmissions(ctx context.Context, problemID int64, currentVersion string) (int64, error) {
+	query := `
+		UPDATE execution.submissions
+		SET outdated_tests = TRUE
+		WHERE problem_id = $1 AND testset_version IS NOT NULL
+		AND testset_version != $2 AND NOT outdated_tests`
+
+	result, err := db.conn.ExecContext(ctx, query, problemID, currentVersion)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark outdated submissions: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (db *DB) GetOutdatedSubmissionIDs(ctx context.Context, problemID int64, limit int) ([]int64, error) {
+	query := `
+		SELECT id
+		FROM execution.submissions
+		WHERE problem_id = $1 AND outdated_tests
+		ORDER BY id ASC
+		LIMIT $2`
+
+	var ids []int64
+	err := db.conn.SelectContext(ctx, &ids, query, problemID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outdated submissions: %w", err)
+	}
+
+	return ids, nil
+}
+
 func (db *DB) UpdateSubmissionCompilationError(ctx context.Context, id int64, compileOutput string) error {
 	query := `
 		UPDATE execution.submissions 
-		SET verdict = 'CE', compile_output = $2, judged_at = NOW()
+		SET verdict = 'CE', compile_output = $2, judged_at = NOW(), score = 0
 		WHERE id = $1`
 
-	_, err := db.conn.ExecContext(ctx, query, id, compileOutput)
+	err := db.updateSubmissionVerdict(ctx, id, models.VerdictCompile, query, id, compileOutput)
 	if err != nil {
 		return fmt.Errorf("failed to update compilation error: %w", err)
 	}
@@ -121,6 +415,24 @@ func (db *DB) UpdateSubmissionCompilationError(ctx context.Context, id int64, co
 	return nil
 }
 
+// UpdateSubmissionCodeURL points a spooled submission at its uploaded code.
+func (db *DB) UpdateSubmissionCodeURL(ctx context.Context, id int64, codeURL string) error {
+	_, err := db.conn.ExecContext(ctx, `UPDATE execution.submissions SET code_url = $2 WHERE id = $1`, id, codeURL)
+	if err != nil {
+		return fmt.Errorf("failed to update code URL: %w", err)
+	}
+	return nil
+}
+
+// DeleteSubmission removes a recorded submission that could not be queued.
+func (db *DB) DeleteSubmission(ctx context.Context, id int64) error {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM execution.submissions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete submission: %w", err)
+	}
+	return nil
+}
+
 func (db *DB) CreateSubmissionTestResults(ctx context.Context, results []models.SubmissionTestResult) error {
 	if len(results) == 0 {
 		return nil
@@ -128,8 +440,10 @@ func (db *DB) CreateSubmissionTestResults(ctx context.Context, results []models.
 
 	query := `
 		INSERT INTO execution.submission_test_results 
-		(submission_id, test_case_id, test_number, verdict, execution_time_ms, memory_used_kb, checker_output)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		(submission_id, test_case_id, test_number, verdict, execution_time_ms, memory_used_kb, checker_output,
+		 checker_time_ms, checker_memory_kb, attempts, stderr, stderr_visible, time_limit_ms, memory_limit_kb,
+		 effective_time_limit_ms, effective_memory_limit_kb, usage, usage_visible, seed, is_sample)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`
 
 	tx, err := db.conn.BeginTxx(ctx, nil)
 	if err != nil {
@@ -146,6 +460,19 @@ func (db *DB) CreateSubmissionTestResults(ctx context.Context, results []models.
 			result.ExecutionTimeMs,
 			result.MemoryUsedKb,
 			result.CheckerOutput,
+			result.CheckerTimeMs,
+			result.CheckerMemoryKb,
+			result.Attempts,
+			result.Stderr,
+			result.StderrVisible,
+			result.TimeLimitMs,
+			result.MemoryLimitKb,
+			result.EffectiveTimeLimitMs,
+			result.EffectiveMemoryLimitKb,
+			result.Usage,
+			result.UsageVisible,
+			result.Seed,
+			result.IsSample,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to insert test result: %w", err)
@@ -159,9 +486,96 @@ func (db *DB) CreateSubmissionTestResults(ctx context.Context, results []models.
 	return nil
 }
 
+func (db *DB) GetSubmissionTestResults(ctx context.Context, submissionID int64) ([]models.SubmissionTestResult, error) {
+	query := `
+		SELECT id, submission_id, test_case_id, test_number, verdict, execution_time_ms, memory_used_kb,
+			   checker_output, checker_time_ms, checker_memory_kb, attempts, stderr, stderr_visible,
+			   time_limit_ms, memory_limit_kb, effective_time_limit_ms, effective_memory_limit_kb, usage, usage_visible,
+			   seed, is_sample, created_at
+		FROM execution.submission_test_results
+		WHERE submission_id = $1
+		ORDER BY test_number`
+
+	results := []models.SubmissionTestResult{}
+	if err := db.conn.SelectContext(ctx, &results, query, submissionID); err != nil {
+		return nil, fmt.Errorf("failed to get test results: %w", err)
+	}
+
+	return results, nil
+}
+
+// SaveInterruptedJudging replaces any earlier snapshot of the submission.
+func (db *DB) SaveInterruptedJudging(ctx context.Context, judging *models.InterruptedJudging) error {
+	query := `
+		INSERT INTO execution.interrupted_judgings (submission_id, testset_version, tests, interrupted_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (submission_id) DO UPDATE
+		SET testset_version = EXCLUDED.testset_version, tests = EXCLUDED.tests,
+			interrupted_at = EXCLUDED.interrupted_at`
+
+	_, err := db.conn.ExecContext(ctx, query, judging.SubmissionID, judging.TestsetVersion, judging.Tests)
+	if err != nil {
+		return fmt.Errorf("failed to save interrupted judging: %w", err)
+	}
+
+	return nil
+}
+
+// GetInterruptedJudging returns nil when the submission's judging was not
+// interrupted.
+func (db *DB) GetInterruptedJudging(ctx context.Context, submissionID int64) (*models.InterruptedJudging, error) {
+	query := `
+		SELECT submission_id, testset_version, tests, interrupted_at
+		FROM execution.interrupted_judgings
+		WHERE submission_id = $1`
+
+	var judging models.InterruptedJudging
+	err := db.conn.GetContext(ctx, &judging, query, submissionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get interrupted judging: %w", err)
+	}
+
+	return &judging, nil
+}
+
+func (db *DB) DeleteInterruptedJudging(ctx context.Context, submissionID int64) error {
+	query := `DELETE FROM execution.interrupted_judgings WHERE submission_id = $1`
+
+	_, err := db.conn.ExecContext(ctx, query, submissionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete interrupted judging: %w", err)
+	}
+
+	return nil
+}
+
+// GetTestResultsForSubmissions returns the test results of several
+// submissions, ordered by submission and test number.
+func (db *DB) GetTestResultsForSubmissions(ctx context.Context, submissionIDs []int64) ([]models.SubmissionTestResult, error) {
+	query := `
+		SELECT id, submission_id, test_case_id, test_number, verdict, execution_time_ms, memory_used_kb,
+			   checker_output, checker_time_ms, checker_memory_kb, attempts, stderr, stderr_visible,
+			   time_limit_ms, memory_limit_kb, effective_time_limit_ms, effective_memory_limit_kb, usage, usage_visible,
+			   seed, is_sample, created_at
+		FROM execution.submission_test_results
+		WHERE submission_id = ANY($1)
+		ORDER BY submission_id, test_number`
+
+	results := []models.SubmissionTestResult{}
+	if err := db.conn.SelectContext(ctx, &results, query, pq.Array(submissionIDs)); err != nil {
+		return nil, fmt.Errorf("failed to get test results: %w", err)
+	}
+
+	return results, nil
+}
+
 func (db *DB) GetSupportedLanguages(ctx context.Context) ([]models.SupportedLanguage, error) {
 	query := `
-		SELECT id, language_code, language_name, version, compile_command, execute_command, is_enabled
+		SELECT id, language_code, language_name, version, compile_command, compile_pipeline, execute_command,
+			   COALESCE(file_extension, '') AS file_extension, execution_profile, time_multiplier, memory_multiplier, is_enabled
 		FROM execution.supported_languages
 		WHERE is_enabled = true
 		ORDER BY language_name`
@@ -177,7 +591,8 @@ func (db *DB) GetSupportedLanguages(ctx context.Context) ([]models.SupportedLang
 
 func (db *DB) GetLanguage(ctx context.Context, code string) (*models.SupportedLanguage, error) {
 	query := `
-		SELECT id, language_code, language_name, version, compile_command, execute_command, is_enabled
+		SELECT id, language_code, language_name, version, compile_command, compile_pipeline, execute_command,
+			   COALESCE(file_extension, '') AS file_extension, execution_profile, time_multiplier, memory_multiplier, is_enabled
 		FROM execution.supported_languages
 		WHERE language_code = $1 AND is_enabled = true`
 
@@ -193,25 +608,198 @@ func (db *DB) GetLanguage(ctx context.Context, code string) (*models.SupportedLa
 	return &language, nil
 }
 
-func (db *DB) CreateJudgeWorker(ctx context.Context, worker *models.JudgeWorker) error {
+// GetLanguageByCode returns the language whether or not it is enabled, or
+// nil when no row has the code.
+func (db *DB) GetLanguageByCode(ctx context.Context, code string) (*models.SupportedLanguage, error) {
 	query := `
-		INSERT INTO execution.judge_workers (worker_name, status, box_id)
-		VALUES ($1, $2, $3)
-		RETURNING id, started_at, last_heartbeat`
+		SELECT id, language_code, language_name, version, compile_command, compile_pipeline, execute_command,
+			   COALESCE(file_extension, '') AS file_extension, execution_profile, time_multiplier, memory_multiplier, is_enabled
+		FROM execution.supported_languages
+		WHERE language_code = $1`
+
+	var language models.SupportedLanguage
+	err := db.conn.GetContext(ctx, &language, query, code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get language: %w", err)
+	}
+
+	return &language, nil
+}
+
+// CreateLanguage inserts the language, reporting false when the code is
+// already taken.
+func (db *DB) CreateLanguage(ctx context.Context, language *models.SupportedLanguage) (bool, error) {
+	query := `
+		INSERT INTO execution.supported_languages (language_code, language_name, version, compile_command,
+			compile_pipeline, execute_command, file_extension, execution_profile, time_multiplier, memory_multiplier, is_enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11)
+		ON CONFLICT (language_code) DO NOTHING
+		RETURNING id`
+
+	err := db.conn.QueryRowContext(ctx, query,
+		language.LanguageCode,
+		language.LanguageName,
+		language.Version,
+		language.CompileCommand,
+		language.CompilePipeline,
+		language.ExecuteCommand,
+		language.FileExtension,
+		language.ExecutionProfile,
+		language.TimeMultiplier,
+		language.MemoryMultiplier,
+		language.IsEnabled,
+	).Scan(&language.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create language: %w", err)
+	}
+	return true, nil
+}
+
+// UpdateLanguage overwrites the language with the given code, reporting
+// false when there is none.
+func (db *DB) UpdateLanguage(ctx context.Context, language *models.SupportedLanguage) (bool, error) {
+	query := `
+		UPDATE execution.supported_languages
+		SET language_name = $2, version = $3, compile_command = $4, compile_pipeline = $5, execute_command = $6,
+			file_extension = NULLIF($7, ''), execution_profile = $8, time_multiplier = $9, memory_multiplier = $10,
+			is_enabled = $11
+		WHERE language_code = $1`
+
+	result, err := db.conn.ExecContext(ctx, query,
+		language.LanguageCode,
+		language.LanguageName,
+		language.Version,
+		language.CompileCommand,
+		language.CompilePipeline,
+		language.ExecuteCommand,
+		language.FileExtension,
+		language.ExecutionProfile,
+		language.TimeMultiplier,
+		language.MemoryMultiplier,
+		language.IsEnabled,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update language: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update language: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (db *DB) DeleteLanguage(ctx context.Context, code string) (bool, error) {
+	result, err := db.conn.ExecContext(ctx, `DELETE FROM execution.supported_languages WHERE language_code = $1`, code)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete language: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete language: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// RegisterJudgeWorker claims the row for worker.WorkerName, reusing it once
+// the previous holder has been terminated. It returns ErrWorkerLeased while
+// the existing row is still live.
+func (db *DB) RegisterJudgeWorker(ctx context.Context, worker *models.JudgeWorker, lease time.Duration) error {
+	query := `
+		INSERT INTO execution.judge_workers (worker_name, status, box_id, lease_expires_at)
+		VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 second')
+		ON CONFLICT (worker_name) DO UPDATE
+		SET status = EXCLUDED.status, box_id = EXCLUDED.box_id, current_submission_id = NULL,
+			started_at = NOW(), last_heartbeat = NOW(), lease_expires_at = EXCLUDED.lease_expires_at
+		WHERE execution.judge_workers.status = 'terminated'
+		RETURNING id, started_at, last_heartbeat, lease_expires_at`
 
 	err := db.conn.QueryRowContext(ctx, query,
 		worker.WorkerName,
 		worker.Status,
 		worker.BoxID,
-	).Scan(&worker.ID, &worker.StartedAt, &worker.LastHeartbeat)
+		lease.Seconds(),
+	).Scan(&worker.ID, &worker.StartedAt, &worker.LastHeartbeat, &worker.LeaseExpiresAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrWorkerLeased
+		}
+		return fmt.Errorf("failed to register judge worker: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) RenewWorkerLease(ctx context.Context, workerID int, lease time.Duration) error {
+	query := `
+		UPDATE execution.judge_workers
+		SET last_heartbeat = NOW(), lease_expires_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE id = $1 AND status != 'terminated'`
 
+	result, err := db.conn.ExecContext(ctx, query, workerID, lease.Seconds())
 	if err != nil {
-		return fmt.Errorf("failed to create judge worker: %w", err)
+		return fmt.Errorf("failed to renew worker lease: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err == nil && rows == 0 {
+		return ErrWorkerLeased
 	}
 
 	return nil
 }
 
+// ExpireStaleWorkers marks workers whose lease has lapsed as terminated and
+// returns the still-pending submissions they had claimed.
+func (db *DB) ExpireStaleWorkers(ctx context.Context) ([]int64, error) {
+	query := `
+		WITH stale AS (
+			SELECT id, current_submission_id
+			FROM execution.judge_workers
+			WHERE status != 'terminated' AND lease_expires_at < NOW()
+			FOR UPDATE SKIP LOCKED
+		), expired AS (
+			UPDATE execution.judge_workers w
+			SET status = 'terminated', current_submission_id = NULL
+			FROM stale
+			WHERE w.id = stale.id
+			RETURNING stale.current_submission_id AS submission_id
+		)
+		SELECT s.id
+		FROM expired e
+		JOIN execution.submissions s ON s.id = e.submission_id
+		WHERE s.verdict = 'pending'`
+
+	var submissionIDs []int64
+	err := db.conn.SelectContext(ctx, &submissionIDs, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire stale workers: %w", err)
+	}
+
+	return submissionIDs, nil
+}
+
+func (db *DB) DeleteTerminatedWorkers(ctx context.Context, olderThan time.Duration) (int64, error) {
+	query := `
+		DELETE FROM execution.judge_workers
+		WHERE status = 'terminated' AND lease_expires_at < $1`
+
+	result, err := db.conn.ExecContext(ctx, query, time.Now().Add(-olderThan))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete terminated workers: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
 func (db *DB) UpdateWorkerStatus(ctx context.Context, workerID int, status string, submissionID *int64) error {
 	query := `
 		UPDATE execution.judge_workers 
@@ -245,18 +833,19 @@ func (db *DB) CreateExecutionLog(ctx context.Context, log *models.ExecutionLog)
 	return nil
 }
 
-func (db *DB) GetUserSubmissions(ctx context.Context, userID int64, limit, offset int) ([]models.Submission, error) {
+func (db *DB) GetUserSubmissions(ctx context.Context, userID int64, tags []string, limit, offset int) ([]models.Submission, error) {
 	query := `
-		SELECT id, user_id, problem_id, contest_id, language, code_url, verdict, 
+		SELECT id, user_id, team_id, problem_id, contest_id, language, code_url, verdict, 
 			   score, execution_time_ms, memory_used_kb, test_cases_passed, test_cases_total,
-			   compile_output, is_public, submitted_at, judged_at
+			   compile_output, is_public, metadata, testset_version, outdated_tests,
+			   trusted, repository_url, commit_sha, entry_file, submitted_at, judged_at
 		FROM execution.submissions 
-		WHERE user_id = $1
+		WHERE user_id = $1 AND ($2::jsonb IS NULL OR metadata->'tags' @> $2::jsonb)
 		ORDER BY submitted_at DESC
-		LIMIT $2 OFFSET $3`
+		LIMIT $3 OFFSET $4`
 
 	var submissions []models.Submission
-	err := db.conn.SelectContext(ctx, &submissions, query, userID, limit, offset)
+	err := db.conn.SelectContext(ctx, &submissions, query, userID, tagFilter(tags), limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user submissions: %w", err)
 	}
@@ -264,18 +853,107 @@ func (db *DB) GetUserSubmissions(ctx context.Context, userID int64, limit, offse
 	return submissions, nil
 }
 
-func (db *DB) GetProblemSubmissions(ctx context.Context, problemID int64, limit, offset int) ([]models.Submission, error) {
+// GetUserProblemAttempts returns the user's judged submissions to the problem,
+// oldest first, capped at limit.
+func (db *DB) GetUserProblemAttempts(ctx context.Context, userID, problemID int64, limit int) ([]models.Submission, error) {
 	query := `
-		SELECT id, user_id, problem_id, contest_id, language, code_url, verdict, 
+		SELECT id, user_id, team_id, problem_id, contest_id, language, code_url, verdict,
 			   score, execution_time_ms, memory_used_kb, test_cases_passed, test_cases_total,
-			   compile_output, is_public, submitted_at, judged_at
-		FROM execution.submissions 
-		WHERE problem_id = $1
-		ORDER BY submitted_at DESC
-		LIMIT $2 OFFSET $3`
+			   compile_output, is_public, metadata, testset_version, outdated_tests,
+			   trusted, repository_url, commit_sha, entry_file, submitted_at, judged_at
+		FROM execution.submissions
+		WHERE user_id = $1 AND problem_id = $2 AND verdict <> $3
+		ORDER BY submitted_at ASC, id ASC
+		LIMIT $4`
+
+	var submissions []models.Submission
+	err := db.conn.SelectContext(ctx, &submissions, query, userID, problemID, models.VerdictPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user problem attempts: %w", err)
+	}
+
+	return submissions, nil
+}
+
+func (db *DB) GetTeamSubmissions(ctx context.Context, teamID int64, tags []string, limit, offset int) ([]models.Submission, error) {
+	query := `
+		SELECT id, user_id, team_id, problem_id, contest_id, language, code_url, verdict, 
+			   score, execution_time_ms, memory_used_kb, test_cases_passed, test_cases_total,
+			   compile_output, is_public, metadata, testset_version, outdated_tests,
+			   trusted, repository_url, commit_sha, entry_file, submitted_at, judged_at
+		FROM execution.submissions 
+		WHERE team_id = $1 AND ($2::jsonb IS NULL OR metadata->'tags' @> $2::jsonb)
+		ORDER BY submitted_at DESC
+		LIMIT $3 OFFSET $4`
+
+	var submissions []models.Submission
+	err := db.conn.SelectContext(ctx, &submissions, query, teamID, tagFilter(tags), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team submissions: %w", err)
+	}
+
+	return submissions, nil
+}
+
+func (db *DB) TransferSubmissionOwnership(ctx context.Context, submissionID, userID int64, teamID *int64) error {
+	tx, err := db.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current struct {
+		UserID    int64          `db:"user_id"`
+		ProblemID int64          `db:"problem_id"`
+		Verdict   models.Verdict `db:"verdict"`
+	}
+	err = tx.GetContext(ctx, &current, `
+		SELECT user_id, problem_id, verdict FROM execution.submissions
+		WHERE id = $1 FOR UPDATE`, submissionID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("submission not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to transfer submission: %w", err)
+	}
+
+	query := `
+		UPDATE execution.submissions 
+		SET user_id = $1, team_id = $2
+		WHERE id = $3`
+
+	if _, err := tx.ExecContext(ctx, query, userID, teamID, submissionID); err != nil {
+		return fmt.Errorf("failed to transfer submission: %w", err)
+	}
+	if current.UserID != userID {
+		if err := adjustUserStats(ctx, tx, current.UserID, current.ProblemID, current.Verdict, -1); err != nil {
+			return err
+		}
+		if err := adjustUserStats(ctx, tx, userID, current.ProblemID, current.Verdict, 1); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) GetProblemSubmissions(ctx context.Context, problemID int64, tags []string, limit, offset int) ([]models.Submission, error) {
+	query := `
+		SELECT id, user_id, team_id, problem_id, contest_id, language, code_url, verdict, 
+			   score, execution_time_ms, memory_used_kb, test_cases_passed, test_cases_total,
+			   compile_output, is_public, metadata, testset_version, outdated_tests,
+			   trusted, repository_url, commit_sha, entry_file, submitted_at, judged_at
+		FROM execution.submissions 
+		WHERE problem_id = $1 AND ($2::jsonb IS NULL OR metadata->'tags' @> $2::jsonb)
+		ORDER BY submitted_at DESC
+		LIMIT $3 OFFSET $4`
 
 	var submissions []models.Submission
-	err := db.conn.SelectContext(ctx, &submissions, query, problemID, limit, offset)
+	err := db.conn.SelectContext(ctx, &submissions, query, problemID, tagFilter(tags), limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get problem submissions: %w", err)
 	}
@@ -283,19 +961,46 @@ func (db *DB) GetProblemSubmissions(ctx context.Context, problemID int64, limit,
 	return submissions, nil
 }
 
+// GetAcceptedSubmissions returns the latest accepted submissions to a
+// problem, newest first.
+func (db *DB) GetAcceptedSubmissions(ctx context.Context, problemID int64, limit int) ([]models.Submission, error) {
+	query := `
+		SELECT id, user_id, team_id, problem_id, contest_id, language, code_url, verdict,
+			   score, execution_time_ms, memory_used_kb, test_cases_passed, test_cases_total,
+			   compile_output, is_public, metadata, testset_version, outdated_tests,
+			   trusted, repository_url, commit_sha, entry_file, submitted_at, judged_at
+		FROM execution.submissions
+		WHERE problem_id = $1 AND verdict = $2
+		ORDER BY submitted_at DESC
+		LIMIT $3`
+
+	var submissions []models.Submission
+	err := db.conn.SelectContext(ctx, &submissions, query, problemID, models.VerdictAccepted, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accepted submissions: %w", err)
+	}
+
+	return submissions, nil
+}
+
+// tagFilter encodes tags for a JSONB containment check, or nil when unfiltered.
+func tagFilter(tags []string) interface{} {
+	if len(tags) == 0 {
+		return nil
+	}
+	data, _ := json.Marshal(tags)
+	return string(data)
+}
+
 // Plagiarism detection methods
 func (db *DB) GetUncheckedSubmissions(ctx context.Context, limit int) ([]models.Submission, error) {
 	query := `
-		SELECT id, user_id, problem_id, contest_id, language, code_url, verdict, 
+		SELECT id, user_id, team_id, problem_id, contest_id, language, code_url, verdict, 
 			   score, execution_time_ms, memory_used_kb, test_cases_passed, test_cases_total,
-			   compile_output, is_public, submitted_at, judged_at
+			   compile_output, is_public, metadata, testset_version, outdated_tests,
+			   trusted, repository_url, commit_sha, entry_file, submitted_at, judged_at
 		FROM execution.submissions 
-		WHERE verdict = 'AC' AND judged_at IS NOT NULL
-		AND id NOT IN (
-			SELECT DISTINCT submission1_id FROM execution.plagiarism_reports
-			UNION
-			SELECT DISTINCT submission2_id FROM execution.plagiarism_reports
-		)
+		WHERE verdict = 'AC' AND judged_at IS NOT NULL AND plagiarism_checked_at IS NULL
 		ORDER BY submitted_at DESC
 		LIMIT $1`
 
@@ -308,11 +1013,21 @@ func (db *DB) GetUncheckedSubmissions(ctx context.Context, limit int) ([]models.
 	return submissions, nil
 }
 
+func (db *DB) MarkPlagiarismChecked(ctx context.Context, submissionID int64) error {
+	query := `UPDATE execution.submissions SET plagiarism_checked_at = NOW() WHERE id = $1`
+
+	if _, err := db.conn.ExecContext(ctx, query, submissionID); err != nil {
+		return fmt.Errorf("failed to mark submission checked: %w", err)
+	}
+	return nil
+}
+
 func (db *DB) GetPreviousSubmissions(ctx context.Context, problemID, currentSubmissionID int64) ([]models.Submission, error) {
 	query := `
-		SELECT id, user_id, problem_id, contest_id, language, code_url, verdict, 
+		SELECT id, user_id, team_id, problem_id, contest_id, language, code_url, verdict, 
 			   score, execution_time_ms, memory_used_kb, test_cases_passed, test_cases_total,
-			   compile_output, is_public, submitted_at, judged_at
+			   compile_output, is_public, metadata, testset_version, outdated_tests,
+			   trusted, repository_url, commit_sha, entry_file, submitted_at, judged_at
 		FROM execution.submissions 
 		WHERE problem_id = $1 AND id != $2 AND verdict = 'AC'
 		ORDER BY submitted_at DESC
@@ -327,6 +1042,29 @@ func (db *DB) GetPreviousSubmissions(ctx context.Context, problemID, currentSubm
 	return submissions, nil
 }
 
+// GetSimilarityCandidates returns the latest single-file submissions to the
+// problem other than excludeID, limited to one contest when contestID is set.
+func (db *DB) GetSimilarityCandidates(ctx context.Context, problemID int64, contestID *int64, excludeID int64, limit int) ([]models.Submission, error) {
+	query := `
+		SELECT id, user_id, team_id, problem_id, contest_id, language, code_url, verdict, 
+			   score, execution_time_ms, memory_used_kb, test_cases_passed, test_cases_total,
+			   compile_output, is_public, metadata, testset_version, outdated_tests,
+			   trusted, repository_url, commit_sha, entry_file, submitted_at, judged_at
+		FROM execution.submissions 
+		WHERE problem_id = $1 AND id != $2 AND entry_file IS NULL
+		  AND ($3::BIGINT IS NULL OR contest_id = $3)
+		ORDER BY submitted_at DESC
+		LIMIT $4`
+
+	var submissions []models.Submission
+	err := db.conn.SelectContext(ctx, &submissions, query, problemID, excludeID, contestID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get similarity candidates: %w", err)
+	}
+
+	return submissions, nil
+}
+
 func (db *DB) CreatePlagiarismReport(ctx context.Context, report *models.PlagiarismReport) error {
 	query := `
 		INSERT INTO execution.plagiarism_reports 
@@ -350,16 +1088,19 @@ func (db *DB) CreatePlagiarismReport(ctx context.Context, report *models.Plagiar
 	return nil
 }
 
-func (db *DB) GetPlagiarismReports(ctx context.Context, limit, offset int) ([]models.PlagiarismReport, error) {
+// GetPlagiarismReports lists reports with the given status, or all reports
+// when status is empty.
+func (db *DB) GetPlagiarismReports(ctx context.Context, status string, limit, offset int) ([]models.PlagiarismReport, error) {
 	query := `
 		SELECT id, submission1_id, submission2_id, similarity_score, algorithm, 
-			   is_reviewed, reviewer_id, status, created_at
+			   is_reviewed, reviewer_id, status, reviewed_at, review_notes, created_at
 		FROM execution.plagiarism_reports 
+		WHERE $1 = '' OR status = $1
 		ORDER BY similarity_score DESC, created_at DESC
-		LIMIT $1 OFFSET $2`
+		LIMIT $2 OFFSET $3`
 
-	var reports []models.PlagiarismReport
-	err := db.conn.SelectContext(ctx, &reports, query, limit, offset)
+	reports := []models.PlagiarismReport{}
+	err := db.conn.SelectContext(ctx, &reports, query, status, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get plagiarism reports: %w", err)
 	}
@@ -367,15 +1108,246 @@ func (db *DB) GetPlagiarismReports(ctx context.Context, limit, offset int) ([]mo
 	return reports, nil
 }
 
-func (db *DB) UpdatePlagiarismReportStatus(ctx context.Context, reportID int64, status string, reviewerID *int64) error {
+func (db *DB) GetPlagiarismReport(ctx context.Context, reportID int64) (*models.PlagiarismReport, error) {
+	query := `
+		SELECT id, submission1_id, submission2_id, similarity_score, algorithm,
+			   is_reviewed, reviewer_id, status, reviewed_at, review_notes, created_at
+		FROM execution.plagiarism_reports
+		WHERE id = $1`
+
+	var report models.PlagiarismReport
+	if err := db.conn.GetContext(ctx, &report, query, reportID); err != nil {
+		return nil, fmt.Errorf("failed to get plagiarism report: %w", err)
+	}
+
+	return &report, nil
+}
+
+// ReviewPlagiarismReport records a reviewer's decision, reporting false when
+// the report is no longer pending.
+func (db *DB) ReviewPlagiarismReport(ctx context.Context, reportID int64, status string, reviewerID int64, notes string) (bool, error) {
 	query := `
 		UPDATE execution.plagiarism_reports 
-		SET status = $1, reviewer_id = $2, is_reviewed = true
-		WHERE id = $3`
+		SET status = $1, reviewer_id = $2, review_notes = NULLIF($3, ''), is_reviewed = true, reviewed_at = NOW()
+		WHERE id = $4 AND status = 'pending'`
+
+	result, err := db.conn.ExecContext(ctx, query, status, reviewerID, notes, reportID)
+	if err != nil {
+		return false, fmt.Errorf("failed to review plagiarism report: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to review plagiarism report: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// GetCodeFeatures returns the stored plagiarism features of the given
+// submissions, keyed by submission ID. Rows of another version are left out.
+func (db *DB) GetCodeFeatures(ctx context.Context, submissionIDs []int64, version int) (map[int64][]byte, error) {
+	query := `
+		SELECT submission_id, features
+		FROM execution.code_features
+		WHERE submission_id = ANY($1) AND version = $2`
+
+	var rows []struct {
+		SubmissionID int64  `db:"submission_id"`
+		Features     []byte `db:"features"`
+	}
+	if err := db.conn.SelectContext(ctx, &rows, query, pq.Array(submissionIDs), version); err != nil {
+		return nil, fmt.Errorf("failed to get code features: %w", err)
+	}
+
+	features := make(map[int64][]byte, len(rows))
+	for _, row := range rows {
+		features[row.SubmissionID] = row.Features
+	}
+	return features, nil
+}
+
+func (db *DB) SaveCodeFeatures(ctx context.Context, submissionID int64, version int, features []byte) error {
+	query := `
+		INSERT INTO execution.code_features (submission_id, version, features)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (submission_id) DO UPDATE
+		SET version = EXCLUDED.version, features = EXCLUDED.features, created_at = CURRENT_TIMESTAMP`
+
+	if _, err := db.conn.ExecContext(ctx, query, submissionID, version, features); err != nil {
+		return fmt.Errorf("failed to save code features: %w", err)
+	}
+	return nil
+}
+
+// GetPlagiarismBoilerplate returns the problem's boilerplate. A non-empty
+// language limits it to snippets for that language or for every language.
+func (db *DB) GetPlagiarismBoilerplate(ctx context.Context, problemID int64, language string) ([]models.PlagiarismBoilerplate, error) {
+	query := `
+		SELECT id, problem_id, language, code, created_by, created_at
+		FROM execution.plagiarism_boilerplate
+		WHERE problem_id = $1 AND ($2 = '' OR language IS NULL OR language = $2)
+		ORDER BY id`
+
+	snippets := []models.PlagiarismBoilerplate{}
+	if err := db.conn.SelectContext(ctx, &snippets, query, problemID, language); err != nil {
+		return nil, fmt.Errorf("failed to get plagiarism boilerplate: %w", err)
+	}
+
+	return snippets, nil
+}
+
+func (db *DB) CreatePlagiarismBoilerplate(ctx context.Context, snippet *models.PlagiarismBoilerplate) error {
+	query := `
+		INSERT INTO execution.plagiarism_boilerplate (problem_id, language, code, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	err := db.conn.QueryRowContext(ctx, query, snippet.ProblemID, snippet.Language, snippet.Code, snippet.CreatedBy).
+		Scan(&snippet.ID, &snippet.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create plagiarism boilerplate: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) DeletePlagiarismBoilerplate(ctx context.Context, problemID, snippetID int64) (bool, error) {
+	result, err := db.conn.ExecContext(ctx,
+		`DELETE FROM execution.plagiarism_boilerplate WHERE id = $1 AND problem_id = $2`, snippetID, problemID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete plagiarism boilerplate: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete plagiarism boilerplate: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// DeleteProblemCodeFeatures drops the stored plagiarism features of the
+// problem's submissions, so they are extracted again.
+func (db *DB) DeleteProblemCodeFeatures(ctx context.Context, problemID int64) error {
+	query := `
+		DELETE FROM execution.code_features
+		WHERE submission_id IN (SELECT id FROM execution.submissions WHERE problem_id = $1)`
+
+	if _, err := db.conn.ExecContext(ctx, query, problemID); err != nil {
+		return fmt.Errorf("failed to delete code features: %w", err)
+	}
+	return nil
+}
+
+// GetContestAcceptedSubmissions returns each user's latest accepted
+// single-file submission to each problem of the contest.
+func (db *DB) GetContestAcceptedSubmissions(ctx context.Context, contestID int64) ([]models.Submission, error) {
+	query := `
+		SELECT DISTINCT ON (problem_id, user_id)
+			   id, user_id, team_id, problem_id, contest_id, language, code_url, verdict,
+			   score, execution_time_ms, memory_used_kb, test_cases_passed, test_cases_total,
+			   compile_output, is_public, metadata, testset_version, outdated_tests,
+			   trusted, repository_url, commit_sha, entry_file, submitted_at, judged_at
+		FROM execution.submissions
+		WHERE contest_id = $1 AND verdict = $2 AND entry_file IS NULL
+		ORDER BY problem_id, user_id, submitted_at DESC`
+
+	submissions := []models.Submission{}
+	if err := db.conn.SelectContext(ctx, &submissions, query, contestID, models.VerdictAccepted); err != nil {
+		return nil, fmt.Errorf("failed to get contest submissions: %w", err)
+	}
+
+	return submissions, nil
+}
+
+const plagiarismRunColumns = `
+	id, contest_id, threshold, status, total, processed, pairs, node, error,
+	created_by, created_at, started_at, finished_at`
+
+func (db *DB) CreatePlagiarismRun(ctx context.Context, run *models.PlagiarismRun) error {
+	query := `
+		INSERT INTO execution.plagiarism_runs (contest_id, threshold, created_by)
+		VALUES ($1, $2, $3)
+		RETURNING ` + plagiarismRunColumns
+
+	if err := db.conn.GetContext(ctx, run, query, run.ContestID, run.Threshold, run.CreatedBy); err != nil {
+		return fmt.Errorf("failed to create plagiarism run: %w", err)
+	}
 
-	_, err := db.conn.ExecContext(ctx, query, status, reviewerID, reportID)
+	return nil
+}
+
+// GetPlagiarismRun returns nil when there is no such run.
+func (db *DB) GetPlagiarismRun(ctx context.Context, runID int64) (*models.PlagiarismRun, error) {
+	query := `SELECT ` + plagiarismRunColumns + ` FROM execution.plagiarism_runs WHERE id = $1`
+
+	var run models.PlagiarismRun
+	if err := db.conn.GetContext(ctx, &run, query, runID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get plagiarism run: %w", err)
+	}
+
+	return &run, nil
+}
+
+// ClaimPlagiarismRun leases the oldest pending run, or a running run whose
+// node stopped renewing its lease, to the given node. It returns nil when
+// there is nothing to claim.
+func (db *DB) ClaimPlagiarismRun(ctx context.Context, node string, lease time.Duration) (*models.PlagiarismRun, error) {
+	query := `
+		UPDATE execution.plagiarism_runs
+		SET status = 'running', node = $1, lease_expires_at = NOW() + $2 * INTERVAL '1 second',
+			processed = 0, started_at = COALESCE(started_at, NOW())
+		WHERE id = (
+			SELECT id FROM execution.plagiarism_runs
+			WHERE status = 'pending' OR (status = 'running' AND lease_expires_at < NOW())
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + plagiarismRunColumns
+
+	var run models.PlagiarismRun
+	if err := db.conn.GetContext(ctx, &run, query, node, lease.Seconds()); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim plagiarism run: %w", err)
+	}
+
+	return &run, nil
+}
+
+// UpdatePlagiarismRunProgress records progress and renews the lease. It
+// reports false when the run is no longer leased to node.
+func (db *DB) UpdatePlagiarismRunProgress(ctx context.Context, run *models.PlagiarismRun, node string, lease time.Duration) (bool, error) {
+	query := `
+		UPDATE execution.plagiarism_runs
+		SET total = $1, processed = $2, lease_expires_at = NOW() + $3 * INTERVAL '1 second'
+		WHERE id = $4 AND status = 'running' AND node = $5`
+
+	result, err := db.conn.ExecContext(ctx, query, run.Total, run.Processed, lease.Seconds(), run.ID, node)
+	if err != nil {
+		return false, fmt.Errorf("failed to update plagiarism run: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to update plagiarism report: %w", err)
+		return false, fmt.Errorf("failed to update plagiarism run: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (db *DB) FinishPlagiarismRun(ctx context.Context, run *models.PlagiarismRun, status string, runErr *string) error {
+	query := `
+		UPDATE execution.plagiarism_runs
+		SET status = $1, error = $2, total = $3, processed = $4, pairs = $5,
+			finished_at = NOW(), lease_expires_at = NULL
+		WHERE id = $6`
+
+	if _, err := db.conn.ExecContext(ctx, query, status, runErr, run.Total, run.Processed, run.Pairs, run.ID); err != nil {
+		return fmt.Errorf("failed to finish plagiarism run: %w", err)
 	}
 
 	return nil
@@ -384,9 +1356,9 @@ func (db *DB) UpdatePlagiarismReportStatus(ctx context.Context, reportID int64,
 // Recovery service methods
 func (db *DB) GetUnhealthyWorkers(ctx context.Context, threshold time.Duration) ([]models.JudgeWorker, error) {
 	query := `
-		SELECT id, worker_name, status, current_submission_id, started_at, last_heartbeat, box_id
+		SELECT id, worker_name, status, current_submission_id, started_at, last_heartbeat, lease_expires_at, box_id
 		FROM execution.judge_workers 
-		WHERE status != 'idle' 
+		WHERE status NOT IN ('idle', 'terminated') 
 		AND last_heartbeat < $1
 		ORDER BY last_heartbeat ASC`
 
@@ -417,7 +1389,7 @@ func (db *DB) GetActiveBoxes(ctx context.Context) ([]int, error) {
 	query := `
 		SELECT DISTINCT box_id 
 		FROM execution.judge_workers 
-		WHERE box_id IS NOT NULL AND status != 'idle'`
+		WHERE box_id IS NOT NULL AND status NOT IN ('idle', 'terminated')`
 
 	var boxes []int
 	err := db.conn.SelectContext(ctx, &boxes, query)
@@ -432,7 +1404,7 @@ func (db *DB) IsBoxInUse(ctx context.Context, boxID int) (bool, error) {
 	query := `
 		SELECT COUNT(*) 
 		FROM execution.judge_workers 
-		WHERE box_id = $1 AND status != 'idle'`
+		WHERE box_id = $1 AND status NOT IN ('idle', 'terminated')`
 
 	var count int
 	err := db.conn.GetContext(ctx, &count, query, boxID)
@@ -459,9 +1431,10 @@ func (db *DB) ReleaseBox(ctx context.Context, boxID int) error {
 
 func (db *DB) GetStuckSubmissions(ctx context.Context, threshold time.Duration) ([]models.Submission, error) {
 	query := `
-		SELECT id, user_id, problem_id, contest_id, language, code_url, verdict, 
+		SELECT id, user_id, team_id, problem_id, contest_id, language, code_url, verdict, 
 			   score, execution_time_ms, memory_used_kb, test_cases_passed, test_cases_total,
-			   compile_output, is_public, submitted_at, judged_at
+			   compile_output, is_public, metadata, testset_version, outdated_tests,
+			   trusted, repository_url, commit_sha, entry_file, submitted_at, judged_at
 		FROM execution.submissions 
 		WHERE verdict = 'pending' 
 		AND submitted_at < $1
@@ -481,10 +1454,10 @@ func (db *DB) ResetSubmissionState(ctx context.Context, submissionID int64) erro
 		UPDATE execution.submissions 
 		SET verdict = 'pending', judged_at = NULL, execution_time_ms = NULL, 
 			memory_used_kb = NULL, test_cases_passed = 0, test_cases_total = NULL,
-			compile_output = NULL
+			compile_output = NULL, score = 0
 		WHERE id = $1`
 
-	_, err := db.conn.ExecContext(ctx, query, submissionID)
+	err := db.updateSubmissionVerdict(ctx, submissionID, models.VerdictPending, query, submissionID)
 	if err != nil {
 		return fmt.Errorf("failed to reset submission state: %w", err)
 	}
@@ -503,9 +1476,39 @@ func (db *DB) ClearExecutionLogs(ctx context.Context, submissionID int64) error
 	return nil
 }
 
+func (db *DB) GetRecentFailedSubmissionIDs(ctx context.Context, limit int) ([]int64, error) {
+	query := `
+		SELECT id FROM execution.submissions
+		WHERE verdict = 'IE'
+		ORDER BY judged_at DESC NULLS LAST
+		LIMIT $1`
+
+	var ids []int64
+	if err := db.conn.SelectContext(ctx, &ids, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to get failed submissions: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (db *DB) GetExecutionLogs(ctx context.Context, submissionIDs []int64) ([]models.ExecutionLog, error) {
+	query := `
+		SELECT id, submission_id, level, message, created_at
+		FROM execution.execution_logs
+		WHERE submission_id = ANY($1)
+		ORDER BY submission_id, created_at`
+
+	var logs []models.ExecutionLog
+	if err := db.conn.SelectContext(ctx, &logs, query, pq.Array(submissionIDs)); err != nil {
+		return nil, fmt.Errorf("failed to get execution logs: %w", err)
+	}
+
+	return logs, nil
+}
+
 func (db *DB) GetWorker(ctx context.Context, workerID int) (*models.JudgeWorker, error) {
 	query := `
-		SELECT id, worker_name, status, current_submission_id, started_at, last_heartbeat, box_id
+		SELECT id, worker_name, status, current_submission_id, started_at, last_heartbeat, lease_expires_at, box_id
 		FROM execution.judge_workers 
 		WHERE id = $1`
 
@@ -528,7 +1531,8 @@ func (db *DB) GetWorkerStats(ctx context.Context) (map[string]interface{}, error
 			COUNT(CASE WHEN status = 'idle' THEN 1 END) as idle_workers,
 			COUNT(CASE WHEN status = 'busy' THEN 1 END) as busy_workers,
 			COUNT(CASE WHEN status = 'failed' THEN 1 END) as failed_workers,
-			COUNT(CASE WHEN status = 'recovering' THEN 1 END) as recovering_workers
+			COUNT(CASE WHEN status = 'recovering' THEN 1 END) as recovering_workers,
+			COUNT(CASE WHEN status = 'terminated' THEN 1 END) as terminated_workers
 		FROM execution.judge_workers`
 
 	stats := make(map[string]interface{})
@@ -548,6 +1552,7 @@ func (db *DB) GetSubmissionStats(ctx context.Context) (map[string]interface{}, e
 			COUNT(CASE WHEN verdict = 'AC' THEN 1 END) as accepted_submissions,
 			COUNT(CASE WHEN verdict = 'WA' THEN 1 END) as wrong_answer_submissions,
 			COUNT(CASE WHEN verdict = 'TLE' THEN 1 END) as time_limit_submissions,
+			COUNT(CASE WHEN verdict = 'ILE' THEN 1 END) as idle_limit_submissions,
 			COUNT(CASE WHEN verdict = 'MLE' THEN 1 END) as memory_limit_submissions,
 			COUNT(CASE WHEN verdict = 'RE' THEN 1 END) as runtime_error_submissions,
 			COUNT(CASE WHEN verdict = 'CE' THEN 1 END) as compilation_error_submissions
@@ -562,3 +1567,1180 @@ func (db *DB) GetSubmissionStats(ctx context.Context) (map[string]interface{}, e
 
 	return stats, nil
 }
+
+// CreateEventLogEntry records an unpublished event. Writers hold the table
+// lock for the insert alone, so seqs commit in order and a reader that has
+// seen an event has seen every event before it.
+func (db *DB) CreateEventLogEntry(ctx context.Context, entry *models.EventLogEntry) error {
+	tx, err := db.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE execution.event_log IN EXCLUSIVE MODE`); err != nil {
+		return fmt.Errorf("failed to lock event log: %w", err)
+	}
+
+	query := `
+		INSERT INTO execution.event_log (event_type, routing_key, data)
+		VALUES ($1, $2, $3)
+		RETURNING seq, created_at`
+
+	err = tx.QueryRowContext(ctx, query, entry.EventType, entry.RoutingKey, []byte(entry.Data)).
+		Scan(&entry.Seq, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create event log entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit event log entry: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimEventRelay leases the event relay to the given node, renewing the
+// lease of the node that already holds it. It reports whether the node
+// holds the lease.
+func (db *DB) ClaimEventRelay(ctx context.Context, node string, lease time.Duration) (bool, error) {
+	result, err := db.conn.ExecContext(ctx, `
+		UPDATE execution.event_relay
+		SET node = $1, lease_expires_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE node = $1 OR lease_expires_at < NOW()`,
+		node, lease.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim event relay: %w", err)
+	}
+
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim event relay: %w", err)
+	}
+	return claimed > 0, nil
+}
+
+// GetUnpublishedEvents returns the oldest events the relay has not published.
+func (db *DB) GetUnpublishedEvents(ctx context.Context, limit int) ([]models.EventLogEntry, error) {
+	query := `
+		SELECT seq, event_type, routing_key, data, created_at
+		FROM execution.event_log
+		WHERE published_at IS NULL
+		ORDER BY seq ASC
+		LIMIT $1`
+
+	var entries []models.EventLogEntry
+	if err := db.conn.SelectContext(ctx, &entries, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to get unpublished events: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (db *DB) MarkEventPublished(ctx context.Context, seq int64) error {
+	_, err := db.conn.ExecContext(ctx, `UPDATE execution.event_log SET published_at = NOW() WHERE seq = $1`, seq)
+	if err != nil {
+		return fmt.Errorf("failed to mark event published: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) GetEventsSince(ctx context.Context, since int64, eventType string, limit int) ([]models.EventLogEntry, error) {
+	query := `
+		SELECT seq, event_type, routing_key, data, created_at
+		FROM execution.event_log
+		WHERE seq > $1 AND ($2 = '' OR event_type = $2)
+		ORDER BY seq ASC
+		LIMIT $3`
+
+	var entries []models.EventLogEntry
+	err := db.conn.SelectContext(ctx, &entries, query, since, eventType, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	return entries, nil
+}
+
+// GetStreamEvents returns events of the given types after since, optionally
+// limited to events whose data carries the given contest_id.
+func (db *DB) GetStreamEvents(ctx context.Context, since int64, eventTypes []string, contestID *int64, limit int) ([]models.EventLogEntry, error) {
+	query := `
+		SELECT seq, event_type, routing_key, data, created_at
+		FROM execution.event_log
+		WHERE seq > $1 AND event_type = ANY($2)
+		AND ($3::BIGINT IS NULL OR data->>'contest_id' = $3::TEXT)
+		ORDER BY seq ASC
+		LIMIT $4`
+
+	var entries []models.EventLogEntry
+	err := db.conn.SelectContext(ctx, &entries, query, since, pq.Array(eventTypes), contestID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stream events: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (db *DB) DeleteEventsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	// Unpublished events stay until the relay has sent them
+	result, err := db.conn.ExecContext(ctx, `DELETE FROM execution.event_log WHERE created_at < $1 AND published_at IS NOT NULL`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete events: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+func (db *DB) CreateScalingEvent(ctx context.Context, event *models.ScalingEvent) error {
+	query := `
+		INSERT INTO execution.scaling_events 
+		(trigger, queue_size, active_workers, p95_wait_ms, previous_count, new_count, dry_run, applied, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`
+
+	err := db.conn.QueryRowContext(ctx, query,
+		event.Trigger,
+		event.QueueSize,
+		event.ActiveWorkers,
+		event.P95WaitMs,
+		event.PreviousCount,
+		event.NewCount,
+		event.DryRun,
+		event.Applied,
+		event.Error,
+	).Scan(&event.ID, &event.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create scaling event: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) GetScalingEvents(ctx context.Context, limit, offset int) ([]models.ScalingEvent, error) {
+	query := `
+		SELECT id, trigger, queue_size, active_workers, p95_wait_ms, previous_count, new_count,
+			   dry_run, applied, error, created_at
+		FROM execution.scaling_events
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2`
+
+	var events []models.ScalingEvent
+	err := db.conn.SelectContext(ctx, &events, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scaling events: %w", err)
+	}
+
+	return events, nil
+}
+
+func (db *DB) CreateQueueSnapshot(ctx context.Context, snapshot *models.QueueSnapshot) error {
+	query := `
+		INSERT INTO execution.queue_snapshots (node, queue_depth, entries, taken_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`
+
+	err := db.conn.QueryRowContext(ctx, query, snapshot.Node, snapshot.QueueDepth, snapshot.Entries, snapshot.TakenAt).Scan(&snapshot.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create queue snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// GetQueueSnapshots returns the snapshots taken between from and to, oldest
+// first, optionally from one node only.
+func (db *DB) GetQueueSnapshots(ctx context.Context, from, to time.Time, node string, limit int) ([]models.QueueSnapshot, error) {
+	query := `
+		SELECT id, node, queue_depth, entries, taken_at
+		FROM execution.queue_snapshots
+		WHERE taken_at BETWEEN $1 AND $2 AND ($3 = '' OR node = $3)
+		ORDER BY taken_at, id
+		LIMIT $4`
+
+	var snapshots []models.QueueSnapshot
+	err := db.conn.SelectContext(ctx, &snapshots, query, from, to, node, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+func (db *DB) DeleteQueueSnapshotsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := db.conn.ExecContext(ctx, `DELETE FROM execution.queue_snapshots WHERE taken_at < $1`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete queue snapshots: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// GetPlatformLimits returns the stored platform limits, or nil before any
+// were saved.
+func (db *DB) GetPlatformLimits(ctx context.Context) (*models.PlatformLimits, error) {
+	query := `
+		SELECT default_time_limit_ms, default_memory_limit_kb, max_time_limit_ms, max_memory_limit_kb,
+			   max_stack_size_kb, max_output_size_kb, updated_by, updated_at
+		FROM execution.platform_limits
+		WHERE id = 1`
+
+	var limits models.PlatformLimits
+	err := db.conn.GetContext(ctx, &limits, query)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get platform limits: %w", err)
+	}
+
+	return &limits, nil
+}
+
+func (db *DB) SavePlatformLimits(ctx context.Context, limits *models.PlatformLimits) error {
+	query := `
+		INSERT INTO execution.platform_limits
+		(id, default_time_limit_ms, default_memory_limit_kb, max_time_limit_ms, max_memory_limit_kb,
+		 max_stack_size_kb, max_output_size_kb, updated_by, updated_at)
+		VALUES (1, $1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (id) DO UPDATE
+		SET default_time_limit_ms = EXCLUDED.default_time_limit_ms,
+			default_memory_limit_kb = EXCLUDED.default_memory_limit_kb,
+			max_time_limit_ms = EXCLUDED.max_time_limit_ms,
+			max_memory_limit_kb = EXCLUDED.max_memory_limit_kb,
+			max_stack_size_kb = EXCLUDED.max_stack_size_kb,
+			max_output_size_kb = EXCLUDED.max_output_size_kb,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`
+
+	err := db.conn.QueryRowContext(ctx, query,
+		limits.DefaultTimeLimitMs,
+		limits.DefaultMemoryLimitKb,
+		limits.MaxTimeLimitMs,
+		limits.MaxMemoryLimitKb,
+		limits.MaxStackSizeKb,
+		limits.MaxOutputSizeKb,
+		limits.UpdatedBy,
+	).Scan(&limits.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save platform limits: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) SaveVerdictSignature(ctx context.Context, signature *models.VerdictSignature) error {
+	query := `
+		INSERT INTO execution.verdict_signatures (submission_id, key_id, payload, signature, signed_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (submission_id) DO UPDATE
+		SET key_id = EXCLUDED.key_id, payload = EXCLUDED.payload,
+			signature = EXCLUDED.signature, signed_at = EXCLUDED.signed_at`
+
+	_, err := db.conn.ExecContext(ctx, query,
+		signature.SubmissionID,
+		signature.KeyID,
+		signature.Payload,
+		signature.Signature,
+		signature.SignedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save verdict signature: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) GetVerdictSignature(ctx context.Context, submissionID int64) (*models.VerdictSignature, error) {
+	query := `
+		SELECT submission_id, key_id, payload, signature, signed_at
+		FROM execution.verdict_signatures
+		WHERE submission_id = $1`
+
+	var signature models.VerdictSignature
+	err := db.conn.GetContext(ctx, &signature, query, submissionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("verdict signature not found")
+		}
+		return nil, fmt.Errorf("failed to get verdict signature: %w", err)
+	}
+
+	return &signature, nil
+}
+
+// GetProblemJudgeStats aggregates judged submissions of a problem. Compile and
+// internal errors are excluded because they say nothing about difficulty.
+func (db *DB) GetProblemJudgeStats(ctx context.Context, problemID int64) (*models.ProblemDifficulty, *float64, error) {
+	query := `
+		WITH judged AS (
+			SELECT id, user_id, verdict, execution_time_ms
+			FROM execution.submissions
+			WHERE problem_id = $1 AND judged_at IS NOT NULL
+			AND verdict NOT IN ('pending', 'CE', 'IE')
+		),
+		first_ac AS (
+			SELECT user_id, MIN(id) AS ac_id
+			FROM judged
+			WHERE verdict = 'AC'
+			GROUP BY user_id
+		),
+		attempts AS (
+			SELECT f.user_id, COUNT(j.id) AS failed_before
+			FROM first_ac f
+			LEFT JOIN judged j ON j.user_id = f.user_id AND j.id < f.ac_id
+			GROUP BY f.user_id
+		)
+		SELECT
+			(SELECT COUNT(*) FROM judged) AS total_submissions,
+			(SELECT COUNT(*) FROM judged WHERE verdict = 'AC') AS accepted_submissions,
+			(SELECT COUNT(DISTINCT user_id) FROM judged) AS attempting_users,
+			(SELECT COUNT(*) FROM first_ac) AS solvers,
+			COALESCE((SELECT AVG(failed_before) FROM attempts), 0) AS avg_attempts_before_ac,
+			(SELECT PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY execution_time_ms)
+			 FROM judged WHERE verdict = 'AC' AND execution_time_ms IS NOT NULL) AS median_runtime_ms`
+
+	var row struct {
+		models.ProblemDifficulty
+		MedianRuntimeMs *float64 `db:"median_runtime_ms"`
+	}
+	if err := db.conn.GetContext(ctx, &row, query, problemID); err != nil {
+		return nil, nil, fmt.Errorf("failed to get problem judge stats: %w", err)
+	}
+
+	row.ProblemID = problemID
+	return &row.ProblemDifficulty, row.MedianRuntimeMs, nil
+}
+
+func (db *DB) UpsertProblemDifficulty(ctx context.Context, d *models.ProblemDifficulty) error {
+	query := `
+		INSERT INTO execution.problem_difficulty
+		(problem_id, total_submissions, accepted_submissions, attempting_users, solvers,
+		 acceptance_rate, avg_attempts_before_ac, median_runtime_ratio, score, level, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
+		ON CONFLICT (problem_id) DO UPDATE SET
+			total_submissions = EXCLUDED.total_submissions,
+			accepted_submissions = EXCLUDED.accepted_submissions,
+			attempting_users = EXCLUDED.attempting_users,
+			solvers = EXCLUDED.solvers,
+			acceptance_rate = EXCLUDED.acceptance_rate,
+			avg_attempts_before_ac = EXCLUDED.avg_attempts_before_ac,
+			median_runtime_ratio = EXCLUDED.median_runtime_ratio,
+			score = EXCLUDED.score,
+			level = EXCLUDED.level,
+			updated_at = NOW()
+		RETURNING updated_at`
+
+	err := db.conn.QueryRowContext(ctx, query,
+		d.ProblemID,
+		d.TotalSubmissions,
+		d.AcceptedSubmissions,
+		d.AttemptingUsers,
+		d.Solvers,
+		d.AcceptanceRate,
+		d.AvgAttemptsBeforeAC,
+		d.MedianRuntimeRatio,
+		d.Score,
+		d.Level,
+	).Scan(&d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert problem difficulty: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) GetProblemDifficulties(ctx context.Context, problemIDs []int64) ([]models.ProblemDifficulty, error) {
+	query := `
+		SELECT problem_id, total_submissions, accepted_submissions, attempting_users, solvers,
+			   acceptance_rate, avg_attempts_before_ac, median_runtime_ratio, score, level, updated_at
+		FROM execution.problem_difficulty
+		WHERE problem_id = ANY($1)
+		ORDER BY problem_id`
+
+	var difficulties []models.ProblemDifficulty
+	err := db.conn.SelectContext(ctx, &difficulties, query, pq.Array(problemIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get problem difficulties: %w", err)
+	}
+
+	return difficulties, nil
+}
+
+// GetRecentlyJudgedProblems returns problems with submissions judged after since.
+func (db *DB) GetRecentlyJudgedProblems(ctx context.Context, since time.Time) ([]int64, error) {
+	query := `
+		SELECT DISTINCT problem_id
+		FROM execution.submissions
+		WHERE judged_at > $1`
+
+	var problemIDs []int64
+	err := db.conn.SelectContext(ctx, &problemIDs, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recently judged problems: %w", err)
+	}
+
+	return problemIDs, nil
+}
+
+func (db *DB) PinContestEnvironment(ctx context.Context, pin *models.ContestEnvironment) error {
+	query := `
+		INSERT INTO execution.contest_environments (contest_id, toolchain_id, environment, pinned_by, pinned_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (contest_id) DO UPDATE
+		SET toolchain_id = EXCLUDED.toolchain_id, environment = EXCLUDED.environment,
+			pinned_by = EXCLUDED.pinned_by, pinned_at = EXCLUDED.pinned_at`
+
+	_, err := db.conn.ExecContext(ctx, query,
+		pin.ContestID,
+		pin.Environment.ToolchainID,
+		pin.Environment,
+		pin.PinnedBy,
+		pin.PinnedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to pin contest environment: %w", err)
+	}
+
+	return nil
+}
+
+// GetContestEnvironment returns nil without an error when the contest has no
+// pinned environment.
+func (db *DB) GetContestEnvironment(ctx context.Context, contestID int64) (*models.ContestEnvironment, error) {
+	query := `
+		SELECT contest_id, environment, pinned_by, pinned_at
+		FROM execution.contest_environments
+		WHERE contest_id = $1`
+
+	var pin models.ContestEnvironment
+	err := db.conn.GetContext(ctx, &pin, query, contestID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get contest environment: %w", err)
+	}
+
+	return &pin, nil
+}
+
+func (db *DB) DeleteContestEnvironment(ctx context.Context, contestID int64) (bool, error) {
+	result, err := db.conn.ExecContext(ctx, `DELETE FROM execution.contest_environments WHERE contest_id = $1`, contestID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete contest environment: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete contest environment: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// StartShadowConfig stops any active shadow config of the problem and makes
+// config the active one, filling in its ID and creation time.
+func (db *DB) StartShadowConfig(ctx context.Context, config *models.ShadowConfig) error {
+	tx, err := db.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE execution.shadow_configs SET stopped_at = NOW()
+		WHERE problem_id = $1 AND stopped_at IS NULL`, config.ProblemID)
+	if err != nil {
+		return fmt.Errorf("failed to stop previous shadow config: %w", err)
+	}
+
+	query := `
+		INSERT INTO execution.shadow_configs (problem_id, checker_url, time_limit_ms, memory_limit_kb, note, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		config.ProblemID,
+		config.CheckerURL,
+		config.TimeLimitMs,
+		config.MemoryLimitKb,
+		config.Note,
+		config.CreatedBy,
+	).Scan(&config.ID, &config.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create shadow config: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetShadowConfig returns the problem's most recent shadow config, only
+// considering active ones when activeOnly is set. It returns nil without an
+// error when there is none.
+func (db *DB) GetShadowConfig(ctx context.Context, problemID int64, activeOnly bool) (*models.ShadowConfig, error) {
+	query := `
+		SELECT id, problem_id, checker_url, time_limit_ms, memory_limit_kb, note, created_by, created_at, stopped_at
+		FROM execution.shadow_configs
+		WHERE problem_id = $1 AND (NOT $2 OR stopped_at IS NULL)
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	var config models.ShadowConfig
+	err := db.conn.GetContext(ctx, &config, query, problemID, activeOnly)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get shadow config: %w", err)
+	}
+
+	return &config, nil
+}
+
+func (db *DB) StopShadowConfig(ctx context.Context, problemID int64) (bool, error) {
+	result, err := db.conn.ExecContext(ctx, `
+		UPDATE execution.shadow_configs SET stopped_at = NOW()
+		WHERE problem_id = $1 AND stopped_at IS NULL`, problemID)
+	if err != nil {
+		return false, fmt.Errorf("failed to stop shadow config: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to stop shadow config: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (db *DB) SaveShadowResult(ctx context.Context, result *models.ShadowResult) error {
+	query := `
+		INSERT INTO execution.shadow_results
+		(config_id, submission_id, live_verdict, shadow_verdict, live_passed, shadow_passed,
+		 live_time_ms, shadow_time_ms, differences)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := db.conn.ExecContext(ctx, query,
+		result.ConfigID,
+		result.SubmissionID,
+		result.LiveVerdict,
+		result.ShadowVerdict,
+		result.LivePassed,
+		result.ShadowPassed,
+		result.LiveTimeMs,
+		result.ShadowTimeMs,
+		result.Differences,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save shadow result: %w", err)
+	}
+
+	return nil
+}
+
+// GetShadowReport aggregates the comparisons recorded for a shadow config and
+// includes up to limit of the most recent discrepancies.
+func (db *DB) GetShadowReport(ctx context.Context, config *models.ShadowConfig, limit int) (*models.ShadowReport, error) {
+	query := `
+		SELECT live_verdict, shadow_verdict,
+			(live_verdict <> shadow_verdict OR differences IS NOT NULL) AS differs,
+			COUNT(*) AS count
+		FROM execution.shadow_results
+		WHERE config_id = $1
+		GROUP BY 1, 2, 3`
+
+	var rows []struct {
+		LiveVerdict   models.Verdict `db:"live_verdict"`
+		ShadowVerdict models.Verdict `db:"shadow_verdict"`
+		Differs       bool           `db:"differs"`
+		Count         int            `db:"count"`
+	}
+	if err := db.conn.SelectContext(ctx, &rows, query, config.ID); err != nil {
+		return nil, fmt.Errorf("failed to get shadow report: %w", err)
+	}
+
+	report := &models.ShadowReport{
+		Config:      *config,
+		Transitions: make(map[string]int),
+		Recent:      []models.ShadowResult{},
+	}
+	for _, row := range rows {
+		report.Judged += row.Count
+		if !row.Differs {
+			report.Matching += row.Count
+			continue
+		}
+		report.Discrepancies += row.Count
+		report.Transitions[string(row.LiveVerdict)+"->"+string(row.ShadowVerdict)] += row.Count
+	}
+
+	recentQuery := `
+		SELECT id, config_id, submission_id, live_verdict, shadow_verdict, live_passed, shadow_passed,
+			live_time_ms, shadow_time_ms, differences, created_at
+		FROM execution.shadow_results
+		WHERE config_id = $1 AND (live_verdict <> shadow_verdict OR differences IS NOT NULL)
+		ORDER BY created_at DESC
+		LIMIT $2`
+
+	if err := db.conn.SelectContext(ctx, &report.Recent, recentQuery, config.ID, limit); err != nil {
+		return nil, fmt.Errorf("failed to get shadow discrepancies: %w", err)
+	}
+
+	return report, nil
+}
+
+func (db *DB) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	query := `
+		INSERT INTO execution.api_keys (user_id, name, key_prefix, key_hash, scopes, rate_limit, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	err := db.conn.QueryRowContext(ctx, query,
+		key.UserID,
+		key.Name,
+		key.KeyPrefix,
+		key.KeyHash,
+		key.Scopes,
+		key.RateLimit,
+		key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+// UseAPIKey looks up an unrevoked key by hash and counts the use. It returns
+// nil without an error when no such key exists.
+func (db *DB) UseAPIKey(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := `
+		UPDATE execution.api_keys
+		SET usage_count = usage_count + 1, last_used_at = NOW()
+		WHERE key_hash = $1 AND revoked_at IS NULL
+		RETURNING id, user_id, name, key_prefix, key_hash, scopes, rate_limit, usage_count,
+			last_used_at, expires_at, revoked_at, created_at`
+
+	var key models.APIKey
+	if err := db.conn.GetContext(ctx, &key, query, keyHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to use api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (db *DB) GetUserAPIKeys(ctx context.Context, userID int64) ([]models.APIKey, error) {
+	query := `
+		SELECT id, user_id, name, key_prefix, key_hash, scopes, rate_limit, usage_count,
+			last_used_at, expires_at, revoked_at, created_at
+		FROM execution.api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC`
+
+	keys := []models.APIKey{}
+	if err := db.conn.SelectContext(ctx, &keys, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (db *DB) CountActiveAPIKeys(ctx context.Context, userID int64) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM execution.api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`
+
+	var count int
+	if err := db.conn.GetContext(ctx, &count, query, userID); err != nil {
+		return 0, fmt.Errorf("failed to count api keys: %w", err)
+	}
+
+	return count, nil
+}
+
+// RevokeAPIKey revokes a key; a zero userID revokes any user's key. It
+// returns nil without an error when no matching unrevoked key exists.
+func (db *DB) RevokeAPIKey(ctx context.Context, keyID, userID int64) (*models.APIKey, error) {
+	query := `
+		UPDATE execution.api_keys
+		SET revoked_at = NOW()
+		WHERE id = $1 AND ($2 = 0 OR user_id = $2) AND revoked_at IS NULL
+		RETURNING id, user_id, name, key_prefix, key_hash, scopes, rate_limit, usage_count,
+			last_used_at, expires_at, revoked_at, created_at`
+
+	var key models.APIKey
+	if err := db.conn.GetContext(ctx, &key, query, keyID, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (db *DB) CreateCanary(ctx context.Context, canary *models.Canary) error {
+	query := `
+		INSERT INTO execution.canaries (submission_id, expected_verdict, created_by)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	err := db.conn.QueryRowContext(ctx, query,
+		canary.SubmissionID,
+		canary.ExpectedVerdict,
+		canary.CreatedBy,
+	).Scan(&canary.ID, &canary.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create canary: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) GetCanaries(ctx context.Context) ([]models.Canary, error) {
+	query := `
+		SELECT id, submission_id, expected_verdict, created_by, created_at
+		FROM execution.canaries
+		ORDER BY id`
+
+	canaries := []models.Canary{}
+	if err := db.conn.SelectContext(ctx, &canaries, query); err != nil {
+		return nil, fmt.Errorf("failed to get canaries: %w", err)
+	}
+
+	return canaries, nil
+}
+
+// DeleteCanary reports whether the canary existed.
+func (db *DB) DeleteCanary(ctx context.Context, canaryID int64) (bool, error) {
+	result, err := db.conn.ExecContext(ctx, `DELETE FROM execution.canaries WHERE id = $1`, canaryID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete canary: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete canary: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (db *DB) CreateCanaryRun(ctx context.Context, run *models.CanaryRun) error {
+	query := `
+		INSERT INTO execution.canary_runs (canary_id, node, verdict, max_time_ms, tests_passed)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	err := db.conn.QueryRowContext(ctx, query,
+		run.CanaryID,
+		run.Node,
+		run.Verdict,
+		run.MaxTimeMs,
+		run.TestsPassed,
+	).Scan(&run.ID, &run.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create canary run: %w", err)
+	}
+
+	return nil
+}
+
+// GetLatestCanaryRuns returns each node's most recent run of each canary
+// since the given time.
+func (db *DB) GetLatestCanaryRuns(ctx context.Context, since time.Time) ([]models.CanaryRun, error) {
+	query := `
+		SELECT DISTINCT ON (canary_id, node)
+			id, canary_id, node, verdict, max_time_ms, tests_passed, created_at
+		FROM execution.canary_runs
+		WHERE created_at >= $1
+		ORDER BY canary_id, node, created_at DESC`
+
+	runs := []models.CanaryRun{}
+	if err := db.conn.SelectContext(ctx, &runs, query, since); err != nil {
+		return nil, fmt.Errorf("failed to get canary runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// rejudgeJobFilter selects the submissions a rejudge job covers, given the
+// job's problem, verdicts and submission window as $1 to $4.
+const rejudgeJobFilter = `
+	s.problem_id = $1
+	AND (jsonb_array_length($2::jsonb) = 0 OR $2::jsonb ? s.verdict)
+	AND ($3::timestamp IS NULL OR s.submitted_at >= $3)
+	AND ($4::timestamp IS NULL OR s.submitted_at < $4)`
+
+const rejudgeJobColumns = `
+	id, problem_id, verdicts, submitted_after, submitted_before, status, total,
+	processed, last_submission_id, node, error, created_by, created_at,
+	started_at, finished_at`
+
+// CreateRejudgeJob records a pending job covering the matching submissions
+// that exist now.
+func (db *DB) CreateRejudgeJob(ctx context.Context, job *models.RejudgeJob) error {
+	query := `
+		INSERT INTO execution.rejudge_jobs
+			(problem_id, verdicts, submitted_after, submitted_before, total, max_submission_id, created_by)
+		SELECT $1, $2, $3, $4, COUNT(*), COALESCE(MAX(s.id), 0), $5
+		FROM execution.submissions s
+		WHERE ` + rejudgeJobFilter + `
+		RETURNING ` + rejudgeJobColumns
+
+	err := db.conn.GetContext(ctx, job, query,
+		job.ProblemID,
+		job.Verdicts,
+		job.SubmittedAfter,
+		job.SubmittedBefore,
+		job.CreatedBy,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create rejudge job: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) GetRejudgeJob(ctx context.Context, jobID int64) (*models.RejudgeJob, error) {
+	query := `SELECT ` + rejudgeJobColumns + ` FROM execution.rejudge_jobs WHERE id = $1`
+
+	var job models.RejudgeJob
+	if err := db.conn.GetContext(ctx, &job, query, jobID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get rejudge job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// ClaimRejudgeJob leases the oldest pending job, or a running job whose
+// node stopped renewing its lease, to the given node. It returns nil when
+// there is nothing to claim.
+func (db *DB) ClaimRejudgeJob(ctx context.Context, node string, lease time.Duration) (*models.RejudgeJob, error) {
+	query := `
+		UPDATE execution.rejudge_jobs
+		SET status = 'running', node = $1, lease_expires_at = NOW() + $2 * INTERVAL '1 second',
+			started_at = COALESCE(started_at, NOW())
+		WHERE id = (
+			SELECT id FROM execution.rejudge_jobs
+			WHERE status = 'pending' OR (status = 'running' AND lease_expires_at < NOW())
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + rejudgeJobColumns
+
+	var job models.RejudgeJob
+	if err := db.conn.GetContext(ctx, &job, query, node, lease.Seconds()); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim rejudge job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// GetRejudgeBatch returns the job's next submissions in id order.
+func (db *DB) GetRejudgeBatch(ctx context.Context, job *models.RejudgeJob, limit int) ([]models.Submission, error) {
+	query := `
+		SELECT s.id, s.user_id, s.team_id, s.problem_id, s.contest_id, s.language, s.code_url, s.verdict,
+			   s.score, s.execution_time_ms, s.memory_used_kb, s.test_cases_passed, s.test_cases_total,
+			   s.compile_output, s.is_public, s.metadata, s.testset_version, s.outdated_tests,
+			   s.trusted, s.repository_url, s.commit_sha, s.entry_file, s.submitted_at, s.judged_at
+		FROM execution.submissions s
+		JOIN execution.rejudge_jobs j ON j.id = $5
+		WHERE ` + rejudgeJobFilter + `
+			AND s.id > j.last_submission_id AND s.id <= j.max_submission_id
+		ORDER BY s.id
+		LIMIT $6`
+
+	submissions := []models.Submission{}
+	err := db.conn.SelectContext(ctx, &submissions, query,
+		job.ProblemID,
+		job.Verdicts,
+		job.SubmittedAfter,
+		job.SubmittedBefore,
+		job.ID,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rejudge batch: %w", err)
+	}
+
+	return submissions, nil
+}
+
+// UpdateRejudgeJobProgress records progress and renews the node's lease. It
+// reports false when the job is no longer leased to the node.
+func (db *DB) UpdateRejudgeJobProgress(ctx context.Context, job *models.RejudgeJob, node string, lease time.Duration) (bool, error) {
+	query := `
+		UPDATE execution.rejudge_jobs
+		SET processed = $1, last_submission_id = $2, lease_expires_at = NOW() + $3 * INTERVAL '1 second'
+		WHERE id = $4 AND status = 'running' AND node = $5`
+
+	result, err := db.conn.ExecContext(ctx, query, job.Processed, job.LastSubmissionID, lease.Seconds(), job.ID, node)
+	if err != nil {
+		return false, fmt.Errorf("failed to update rejudge job: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update rejudge job: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (db *DB) FinishRejudgeJob(ctx context.Context, jobID int64, status string, jobErr *string) error {
+	query := `
+		UPDATE execution.rejudge_jobs
+		SET status = $1, error = $2, finished_at = NOW(), lease_expires_at = NULL
+		WHERE id = $3`
+
+	if _, err := db.conn.ExecContext(ctx, query, status, jobErr, jobID); err != nil {
+		return fmt.Errorf("failed to finish rejudge job: %w", err)
+	}
+
+	return nil
+}
+
+// GetProblemScoring returns nil when the problem uses the default aggregation.
+func (db *DB) GetProblemScoring(ctx context.Context, problemID int64) (*models.ProblemScoring, error) {
+	query := `
+		SELECT problem_id, aggregation, updated_by, updated_at
+		FROM execution.problem_scoring
+		WHERE problem_id = $1`
+
+	var scoring models.ProblemScoring
+	if err := db.conn.GetContext(ctx, &scoring, query, problemID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get problem scoring: %w", err)
+	}
+
+	return &scoring, nil
+}
+
+func (db *DB) SetProblemScoring(ctx context.Context, scoring *models.ProblemScoring) error {
+	query := `
+		INSERT INTO execution.problem_scoring (problem_id, aggregation, updated_by, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (problem_id) DO UPDATE
+		SET aggregation = EXCLUDED.aggregation, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING updated_at`
+
+	if err := db.conn.QueryRowContext(ctx, query, scoring.ProblemID, scoring.Aggregation, scoring.UpdatedBy).Scan(&scoring.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to set problem scoring: %w", err)
+	}
+
+	return nil
+}
+
+// userProblemScoreUpsert recomputes user_problem_scores from judged
+// submissions. $1 is the aggregation, $2 the problem; callers append any
+// further filter before the GROUP BY placeholder. System errors are not the
+// user's attempt and are left out.
+const userProblemScoreUpsert = `
+	INSERT INTO execution.user_problem_scores (user_id, problem_id, score, attempts, aggregation, updated_at)
+	SELECT user_id, problem_id,
+		CASE $1::VARCHAR
+			WHEN 'sum' THEN SUM(COALESCE(score, 0))
+			WHEN 'last' THEN (ARRAY_AGG(COALESCE(score, 0) ORDER BY submitted_at DESC, id DESC))[1]
+			ELSE MAX(COALESCE(score, 0))
+		END,
+		COUNT(*), $1::VARCHAR, NOW()
+	FROM execution.submissions
+	WHERE problem_id = $2 AND verdict NOT IN ('pending', 'IE') %s
+	GROUP BY user_id, problem_id
+	ON CONFLICT (user_id, problem_id) DO UPDATE
+	SET score = EXCLUDED.score, attempts = EXCLUDED.attempts,
+		aggregation = EXCLUDED.aggregation, updated_at = NOW()`
+
+func (db *DB) RecomputeUserProblemScore(ctx context.Context, userID, problemID int64, aggregation string) error {
+	query := fmt.Sprintf(userProblemScoreUpsert, "AND user_id = $3")
+
+	if _, err := db.conn.ExecContext(ctx, query, aggregation, problemID, userID); err != nil {
+		return fmt.Errorf("failed to recompute user problem score: %w", err)
+	}
+
+	return nil
+}
+
+// RecomputeProblemScores recomputes every user's score on the problem and
+// returns how many were written.
+func (db *DB) RecomputeProblemScores(ctx context.Context, problemID int64, aggregation string) (int64, error) {
+	query := fmt.Sprintf(userProblemScoreUpsert, "")
+
+	result, err := db.conn.ExecContext(ctx, query, aggregation, problemID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recompute problem scores: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// GetUserProblemScore returns nil when the user has no judged submissions to
+// the problem.
+func (db *DB) GetUserProblemScore(ctx context.Context, userID, problemID int64) (*models.UserProblemScore, error) {
+	query := `
+		SELECT user_id, problem_id, score, attempts, aggregation, updated_at
+		FROM execution.user_problem_scores
+		WHERE user_id = $1 AND problem_id = $2`
+
+	var score models.UserProblemScore
+	if err := db.conn.GetContext(ctx, &score, query, userID, problemID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user problem score: %w", err)
+	}
+
+	return &score, nil
+}
+
+func (db *DB) GetProblemScores(ctx context.Context, problemID int64, limit, offset int) ([]models.UserProblemScore, error) {
+	query := `
+		SELECT user_id, problem_id, score, attempts, aggregation, updated_at
+		FROM execution.user_problem_scores
+		WHERE problem_id = $1
+		ORDER BY score DESC, user_id
+		LIMIT $2 OFFSET $3`
+
+	scores := []models.UserProblemScore{}
+	if err := db.conn.SelectContext(ctx, &scores, query, problemID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to get problem scores: %w", err)
+	}
+
+	return scores, nil
+}
+
+// SetSubmissionDebug flags or unflags a submission for debug logging and
+// reports whether the submission exists.
+func (db *DB) SetSubmissionDebug(ctx context.Context, submissionID int64, enabled bool, userID int64) (bool, error) {
+	query := `DELETE FROM execution.submission_debug WHERE submission_id = $1`
+	args := []interface{}{submissionID}
+	if enabled {
+		query = `
+			INSERT INTO execution.submission_debug (submission_id, enabled_by)
+			SELECT id, $2 FROM execution.submissions WHERE id = $1
+			ON CONFLICT (submission_id) DO UPDATE SET enabled_by = EXCLUDED.enabled_by, created_at = NOW()`
+		args = append(args, userID)
+	}
+
+	result, err := db.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to set submission debug: %w", err)
+	}
+	if enabled {
+		rows, err := result.RowsAffected()
+		return rows > 0, err
+	}
+	return true, nil
+}
+
+func (db *DB) GetDebugSubmissionIDs(ctx context.Context) ([]int64, error) {
+	var ids []int64
+	if err := db.conn.SelectContext(ctx, &ids, `SELECT submission_id FROM execution.submission_debug`); err != nil {
+		return nil, fmt.Errorf("failed to get debug submissions: %w", err)
+	}
+	return ids, nil
+}
+
+// GetUserStats reads the user's pre-aggregated verdict counters.
+func (db *DB) GetUserStats(ctx context.Context, userID int64) (*models.UserStats, error) {
+	var counts []struct {
+		Verdict models.Verdict `db:"verdict"`
+		Count   int64          `db:"count"`
+	}
+	err := db.conn.SelectContext(ctx, &counts, `
+		SELECT verdict, count FROM execution.user_verdict_counts
+		WHERE user_id = $1 AND count > 0`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user verdict counts: %w", err)
+	}
+
+	stats := &models.UserStats{UserID: userID, Verdicts: make(map[models.Verdict]int64, len(counts))}
+	for _, c := range counts {
+		stats.Verdicts[c.Verdict] = c.Count
+		stats.Judged += c.Count
+	}
+	stats.Accepted = stats.Verdicts[models.VerdictAccepted]
+
+	err = db.conn.GetContext(ctx, &stats.SolvedProblems, `
+		SELECT COUNT(*) FROM execution.user_problem_accepts
+		WHERE user_id = $1 AND accepted > 0`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user solved problems: %w", err)
+	}
+
+	return stats, nil
+}
+
+// RepairUserStats recounts every user's counters from the submissions table
+// and rewrites those that drifted, returning how many were rewritten. The
+// counter tables are locked so verdicts recorded meanwhile apply on top of
+// the recount.
+func (db *DB) RepairUserStats(ctx context.Context) (int64, error) {
+	tx, err := db.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `LOCK TABLE execution.user_verdict_counts, execution.user_problem_accepts IN EXCLUSIVE MODE`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock user stats: %w", err)
+	}
+
+	verdicts, err := tx.ExecContext(ctx, `
+		INSERT INTO execution.user_verdict_counts (user_id, verdict, count)
+		SELECT COALESCE(a.user_id, c.user_id), COALESCE(a.verdict, c.verdict), COALESCE(a.count, 0)
+		FROM (
+			SELECT user_id, verdict, COUNT(*) AS count
+			FROM execution.submissions
+			WHERE verdict <> 'pending'
+			GROUP BY user_id, verdict
+		) a
+		FULL JOIN execution.user_verdict_counts c ON c.user_id = a.user_id AND c.verdict = a.verdict
+		WHERE COALESCE(a.count, 0) <> COALESCE(c.count, 0)
+		ON CONFLICT (user_id, verdict) DO UPDATE SET count = EXCLUDED.count`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to repair user verdict counts: %w", err)
+	}
+
+	accepts, err := tx.ExecContext(ctx, `
+		INSERT INTO execution.user_problem_accepts (user_id, problem_id, accepted)
+		SELECT COALESCE(a.user_id, c.user_id), COALESCE(a.problem_id, c.problem_id), COALESCE(a.accepted, 0)
+		FROM (
+			SELECT user_id, problem_id, COUNT(*) AS accepted
+			FROM execution.submissions
+			WHERE verdict = 'AC'
+			GROUP BY user_id, problem_id
+		) a
+		FULL JOIN execution.user_problem_accepts c ON c.user_id = a.user_id AND c.problem_id = a.problem_id
+		WHERE COALESCE(a.accepted, 0) <> COALESCE(c.accepted, 0)
+		ON CONFLICT (user_id, problem_id) DO UPDATE SET accepted = EXCLUDED.accepted`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to repair user problem accepts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	verdictRows, _ := verdicts.RowsAffected()
+	acceptRows, _ := accepts.RowsAffected()
+	return verdictRows + acceptRows, nil
+}
@@ -0,0 +1,280 @@
+// Package grpc serves the internal submission API defined in
+// proto/submission.proto to other CodeHakam services.
+package grpc
+
+//go:generate protoc --proto_path=../../proto --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative submission.proto
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"execution_service/internal/database"
+	"execution_service/internal/grpc/pb"
+	"execution_service/internal/middleware"
+	"execution_service/internal/models"
+	"execution_service/internal/services"
+	"execution_service/internal/validation"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type serviceAccountKey struct{}
+
+type serviceAccount struct {
+	name   string
+	scopes []string
+}
+
+type Server struct {
+	pb.UnimplementedSubmissionServiceServer
+	db          *database.DB
+	submissions *services.SubmissionService
+	security    *middleware.SecurityMiddleware
+	audit       *services.AuditLogService
+	languages   *services.LanguageService
+	server      *grpc.Server
+}
+
+func NewServer(db *database.DB, submissions *services.SubmissionService, security *middleware.SecurityMiddleware, audit *services.AuditLogService) *Server {
+	s := &Server{
+		db:          db,
+		submissions: submissions,
+		security:    security,
+		audit:       audit,
+	}
+	s.server = grpc.NewServer(grpc.UnaryInterceptor(s.authenticate))
+	pb.RegisterSubmissionServiceServer(s.server, s)
+	return s
+}
+
+func (s *Server) SetLanguageService(ls *services.LanguageService) {
+	s.languages = ls
+}
+
+func (s *Server) Serve(port string) error {
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %s: %w", port, err)
+	}
+	return s.server.Serve(listener)
+}
+
+// Stop waits for in-flight calls to finish.
+func (s *Server) Stop() {
+	s.server.GracefulStop()
+}
+
+// authenticate admits only service-account tokens; user tokens and API keys
+// belong on the REST API.
+func (s *Server) authenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	authorization := ""
+	if values := md.Get("authorization"); len(values) > 0 {
+		authorization = values[0]
+	}
+
+	name, scopes, err := s.security.ServiceAccount(authorization)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return handler(context.WithValue(ctx, serviceAccountKey{}, &serviceAccount{name: name, scopes: scopes}), req)
+}
+
+// requireScope returns the calling service when its token carries scope.
+func requireScope(ctx context.Context, scope string) (string, error) {
+	account := ctx.Value(serviceAccountKey{}).(*serviceAccount)
+	for _, s := range account.scopes {
+		if s == scope {
+			return account.name, nil
+		}
+	}
+	return "", status.Errorf(codes.PermissionDenied, "%s scope required", scope)
+}
+
+func (s *Server) CreateSubmission(ctx context.Context, req *pb.CreateSubmissionRequest) (*pb.CreateSubmissionResponse, error) {
+	serviceName, err := requireScope(ctx, middleware.ScopeSubmitOnBehalfOf)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GetUserId() <= 0 || req.GetProblemId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id and problem_id are required")
+	}
+	if req.GetCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+
+	meta := models.SubmissionMetadata{
+		Tags:       req.GetMetadata().GetTags(),
+		Attributes: req.GetMetadata().GetAttributes(),
+	}
+	if err := validation.ValidateSubmissionMetadata(&meta); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.validateLanguage(req.GetLanguage()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	code, err := validation.DecodeSubmissionCode(req.GetCode(), req.GetLanguage())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := validation.ValidateCode(code, req.GetLanguage()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	timeLimit, memoryLimit, err := validation.ValidateSubmissionLimits(int(req.GetTimeLimitMs()), int(req.GetMemoryLimitKb()), s.submissions.PlatformLimits())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	submission := &models.Submission{
+		UserID:    req.GetUserId(),
+		TeamID:    req.TeamId,
+		ProblemID: req.GetProblemId(),
+		ContestID: req.ContestId,
+		Language:  req.GetLanguage(),
+		Metadata:  meta,
+	}
+	if err := s.submissions.Create(ctx, submission, code, timeLimit, memoryLimit); err != nil {
+		log.Printf("Failed to create submission over gRPC: %v", err)
+		return nil, status.Error(codes.Internal, "failed to create submission")
+	}
+
+	s.logServiceScope(ctx, serviceName, middleware.ScopeSubmitOnBehalfOf, map[string]interface{}{
+		"submission_id": submission.ID,
+		"user_id":       submission.UserID,
+		"team_id":       submission.TeamID,
+		"problem_id":    submission.ProblemID,
+	})
+
+	return &pb.CreateSubmissionResponse{SubmissionId: submission.ID, Status: "queued"}, nil
+}
+
+func (s *Server) GetSubmission(ctx context.Context, req *pb.GetSubmissionRequest) (*pb.Submission, error) {
+	submission, err := s.db.GetSubmission(ctx, req.GetSubmissionId())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "submission not found")
+	}
+	return toProto(submission), nil
+}
+
+func (s *Server) RejudgeSubmission(ctx context.Context, req *pb.RejudgeSubmissionRequest) (*pb.RejudgeSubmissionResponse, error) {
+	serviceName, err := requireScope(ctx, middleware.ScopeRejudge)
+	if err != nil {
+		return nil, err
+	}
+
+	submission, err := s.db.GetSubmission(ctx, req.GetSubmissionId())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "submission not found")
+	}
+
+	auditEvent := &services.AuditEvent{
+		Action:     services.AdminActionSubmissionRejudge,
+		Resource:   "submission",
+		ResourceID: &submission.ID,
+		IPAddress:  peerAddress(ctx),
+		Details: map[string]interface{}{
+			"submission_id":   submission.ID,
+			"problem_id":      submission.ProblemID,
+			"user_id":         submission.UserID,
+			"language":        submission.Language,
+			"service_account": serviceName,
+		},
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+	if err := s.audit.LogAdminAction(ctx, auditEvent); err != nil {
+		log.Printf("Failed to log admin action: %v", err)
+	}
+
+	if err := s.submissions.Rejudge(ctx, submission, 5); err != nil {
+		log.Printf("Failed to queue rejudge over gRPC: %v", err)
+		return nil, status.Error(codes.Internal, "failed to queue rejudge")
+	}
+
+	return &pb.RejudgeSubmissionResponse{SubmissionId: submission.ID, Status: "queued"}, nil
+}
+
+func (s *Server) logServiceScope(ctx context.Context, serviceName, scope string, details map[string]interface{}) {
+	details["service_account"] = serviceName
+	details["scope"] = scope
+	if method, ok := grpc.Method(ctx); ok {
+		details["path"] = method
+	}
+
+	event := &services.AuditEvent{
+		Action:    services.SecurityEventServiceScope,
+		Resource:  "service_account",
+		IPAddress: peerAddress(ctx),
+		Details:   details,
+		Timestamp: time.Now(),
+		Severity:  services.SeverityInfo,
+	}
+
+	if err := s.audit.LogSecurityEvent(ctx, event); err != nil {
+		log.Printf("Failed to log service scope use: %v", err)
+	}
+}
+
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
+
+func toProto(submission *models.Submission) *pb.Submission {
+	result := &pb.Submission{
+		Id:              submission.ID,
+		UserId:          submission.UserID,
+		TeamId:          submission.TeamID,
+		ProblemId:       submission.ProblemID,
+		ContestId:       submission.ContestID,
+		Language:        submission.Language,
+		Verdict:         string(submission.Verdict),
+		Score:           submission.Score.String(),
+		ExecutionTimeMs: optionalInt32(submission.ExecutionTimeMs),
+		MemoryUsedKb:    optionalInt32(submission.MemoryUsedKb),
+		TestCasesPassed: int32(submission.TestCasesPassed),
+		TestCasesTotal:  optionalInt32(submission.TestCasesTotal),
+		CompileOutput:   submission.CompileOutput,
+		Metadata: &pb.SubmissionMetadata{
+			Tags:       submission.Metadata.Tags,
+			Attributes: submission.Metadata.Attributes,
+		},
+		OutdatedTests: submission.OutdatedTests,
+		SubmittedAt:   timestamppb.New(submission.SubmittedAt),
+	}
+	if submission.JudgedAt != nil {
+		result.JudgedAt = timestamppb.New(*submission.JudgedAt)
+	}
+	return result
+}
+
+func optionalInt32(v *int) *int32 {
+	if v == nil {
+		return nil
+	}
+	n := int32(*v)
+	return &n
+}
+
+func (s *Server) validateLanguage(code string) error {
+	if s.languages == nil {
+		return validation.ValidateLanguage(code)
+	}
+	return s.languages.Validate(code)
+}
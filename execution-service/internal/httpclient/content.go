@@ -3,33 +3,70 @@ package httpclient
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
+)
 
-	"execution_service/internal/services"
+// Error categories returned by content service calls, usable with errors.Is.
+var (
+	ErrNotFound           = errors.New("content service resource not found")
+	ErrTimeout            = errors.New("content service request timed out")
+	ErrServiceUnavailable = errors.New("content service unavailable")
 )
 
+// RequestObserver receives the outcome of every content service call.
+type RequestObserver func(operation, status string, duration time.Duration)
+
 type ContentServiceClient struct {
 	baseURL    string
 	httpClient *http.Client
+	observer   RequestObserver
 }
 
 type TestCaseResponse struct {
-	ID          int64  `json:"id"`
-	InputURL    string `json:"input_url"`
-	OutputURL   string `json:"output_url"`
-	IsSample    bool   `json:"is_sample"`
-	TimeLimit   int    `json:"time_limit"`
-	MemoryLimit int    `json:"memory_limit"`
+	ID            int64  `json:"id"`
+	InputURL      string `json:"input_url"`
+	OutputURL     string `json:"output_url"`
+	IsSample      bool   `json:"is_sample"`
+	TimeLimit     int    `json:"time_limit"`
+	MemoryLimit   int    `json:"memory_limit"`
+	InteractorURL string `json:"interactor_url"`
+	CheckerURL    string `json:"checker_url"`
+	Subtask       int    `json:"subtask"`
 }
 
 type ProblemResponse struct {
-	ID          int64              `json:"id"`
-	Title       string             `json:"title"`
-	TimeLimit   int                `json:"time_limit_ms"`
-	MemoryLimit int                `json:"memory_limit_kb"`
-	TestCases   []TestCaseResponse `json:"test_cases"`
+	ID                 int64                       `json:"id"`
+	Title              string                      `json:"title"`
+	TimeLimit          int                         `json:"time_limit_ms"`
+	MemoryLimit        int                         `json:"memory_limit_kb"`
+	TestCases          []TestCaseResponse          `json:"test_cases"`
+	RandomizeTestOrder bool                        `json:"randomize_test_order"`
+	SupplementaryFiles []SupplementaryFileResponse `json:"supplementary_files"`
+	CheckerFiles       []SupplementaryFileResponse `json:"checker_files"`
+	ValidatorURL       string                      `json:"validator_url"`
+	StderrVisibility   string                      `json:"stderr_visibility"`
+	JudgingPolicy      string                      `json:"judging_policy"`
+	OutputOnly         bool                        `json:"output_only"`
+	Graders            map[string]GraderResponse   `json:"graders"`
+}
+
+// GraderResponse is a function-only problem's grader for one language. The
+// submission is placed next to it as SolutionFile, which the grader includes
+// or imports.
+type GraderResponse struct {
+	URL          string `json:"url"`
+	SolutionFile string `json:"solution_file"`
+}
+
+type SupplementaryFileResponse struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	SHA256    string `json:"sha256"`
+	SizeBytes int64  `json:"size_bytes"`
 }
 
 func NewContentServiceClient(baseURL string) *ContentServiceClient {
@@ -41,6 +78,10 @@ func NewContentServiceClient(baseURL string) *ContentServiceClient {
 	}
 }
 
+func (c *ContentServiceClient) SetObserver(observer RequestObserver) {
+	c.observer = observer
+}
+
 func (c *ContentServiceClient) GetProblem(ctx context.Context, problemID int64) (*ProblemResponse, error) {
 	url := fmt.Sprintf("%s/api/problems/%d", c.baseURL, problemID)
 
@@ -51,16 +92,12 @@ func (c *ContentServiceClient) GetProblem(ctx context.Context, problemID int64)
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req, "get_problem")
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("content service returned status %d", resp.StatusCode)
-	}
-
 	var problem ProblemResponse
 	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -69,6 +106,42 @@ func (c *ContentServiceClient) GetProblem(ctx context.Context, problemID int64)
 	return &problem, nil
 }
 
+// do executes a request, records its outcome and maps failures onto the error categories.
+func (c *ContentServiceClient) do(req *http.Request, operation string) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.observe(operation, "error", start)
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return nil, fmt.Errorf("%w: %v", ErrTimeout, err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrServiceUnavailable, err)
+	}
+
+	c.observe(operation, fmt.Sprintf("%d", resp.StatusCode), start)
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return resp, nil
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, req.URL.Path)
+	case resp.StatusCode >= 500:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: status %d", ErrServiceUnavailable, resp.StatusCode)
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("content service returned status %d", resp.StatusCode)
+	}
+}
+
+func (c *ContentServiceClient) observe(operation, status string, start time.Time) {
+	if c.observer != nil {
+		c.observer(operation, status, time.Since(start))
+	}
+}
+
 func (c *ContentServiceClient) GetTestCases(ctx context.Context, problemID int64) ([]TestCaseResponse, error) {
 	problem, err := c.GetProblem(ctx, problemID)
 	if err != nil {
@@ -86,15 +159,11 @@ func (c *ContentServiceClient) HealthCheck(ctx context.Context) error {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req, "health")
 	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("content service health check failed with status %d", resp.StatusCode)
+		return fmt.Errorf("content service health check failed: %w", err)
 	}
+	resp.Body.Close()
 
 	return nil
 }
@@ -5,6 +5,8 @@ import (
 	"log"
 	"time"
 
+	"execution_service/internal/services"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -31,6 +33,24 @@ func Recovery() gin.HandlerFunc {
 	})
 }
 
+// Metrics records rate, errors and latency per route template, so
+// /api/submissions/1 and /api/submissions/2 share a series. Requests that
+// match no route are grouped under "unmatched".
+func Metrics(ms *services.MetricsService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+
+		start := time.Now()
+		ms.HTTPRequestStarted(method, route)
+		c.Next()
+		ms.RecordHTTPRequest(method, route, c.Writer.Status(), time.Since(start))
+	}
+}
+
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := c.GetHeader("X-Request-ID")
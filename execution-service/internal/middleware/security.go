@@ -1,22 +1,41 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"execution_service/internal/models"
 	"execution_service/internal/rbac"
 	"execution_service/internal/sandbox"
+	"execution_service/internal/services"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Scopes that may be granted to service-account tokens (token_type "service").
+const (
+	ScopeBypassRateLimit  = "bypass_rate_limit"
+	ScopeSubmitOnBehalfOf = "submit_on_behalf_of"
+	ScopeRejudge          = "rejudge"
+)
+
+// APIKeyHeader carries self-service API keys, which external tools use
+// instead of user JWTs.
+const APIKeyHeader = "X-API-Key"
+
+// ServiceScopeAuditor is called whenever a service account exercises a scope.
+type ServiceScopeAuditor func(c *gin.Context, serviceName, scope string)
+
 type SecurityMiddleware struct {
 	securityValidator *sandbox.SecurityValidator
 	jwtSecret         []byte
 	rbacService       *rbac.RBACService
+	scopeAuditor      ServiceScopeAuditor
+	apiKeys           *services.APIKeyService
 }
 
 type userRequests struct {
@@ -40,6 +59,14 @@ func (sm *SecurityMiddleware) SetRBACService(rbacService *rbac.RBACService) {
 	sm.rbacService = rbacService
 }
 
+func (sm *SecurityMiddleware) SetServiceScopeAuditor(auditor ServiceScopeAuditor) {
+	sm.scopeAuditor = auditor
+}
+
+func (sm *SecurityMiddleware) SetAPIKeyService(apiKeys *services.APIKeyService) {
+	sm.apiKeys = apiKeys
+}
+
 func (sm *SecurityMiddleware) SecurityHeaders() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("X-Content-Type-Options", "nosniff")
@@ -56,6 +83,20 @@ func (sm *SecurityMiddleware) JWTRateLimit(requestsPerMinute int) gin.HandlerFun
 	users := make(map[string]*userRequests)
 
 	return func(c *gin.Context) {
+		// API keys carry their own limit, applied by APIKeyAuth
+		if _, ok := c.Get("api_key"); ok {
+			c.Next()
+			return
+		}
+
+		if claims, _, err := sm.parseBearerClaims(c); err == nil {
+			if name, ok := serviceAccountName(claims); ok && claimsHaveScope(claims, ScopeBypassRateLimit) {
+				sm.AuditServiceScope(c, name, ScopeBypassRateLimit)
+				c.Next()
+				return
+			}
+		}
+
 		userID := sm.extractUserIDFromJWT(c)
 		if userID == "" {
 			sm.handleUnauthenticatedRateLimit(c, requestsPerMinute/10)
@@ -230,59 +271,233 @@ func (sm *SecurityMiddleware) getClientIP(r *http.Request) string {
 
 func (sm *SecurityMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		claims, status, err := sm.parseBearerClaims(c)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Bearer token required"})
+		setClaimsContext(c, claims)
+		c.Next()
+	}
+}
+
+// APIKeyAuth authenticates requests carrying an API key and applies the
+// key's rate limit. A key grants nothing by itself; routes opt in with
+// AcceptAPIKey.
+func (sm *SecurityMiddleware) APIKeyAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader(APIKeyHeader)
+		if token == "" {
+			c.Next()
+			return
+		}
+		if sm.apiKeys == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "API keys are not enabled"})
+			c.Abort()
+			return
+		}
+		if c.GetHeader("Authorization") != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Send either an API key or a bearer token"})
 			c.Abort()
 			return
 		}
 
-		tokenString := parts[1]
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return sm.jwtSecret, nil
-		})
+		key, err := sm.apiKeys.Authenticate(c.Request.Context(), token)
+		switch {
+		case errors.Is(err, services.ErrAPIKeyRateLimited):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "API key rate limit exceeded"})
+			c.Abort()
+			return
+		case errors.Is(err, services.ErrInvalidAPIKey):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			c.Abort()
+			return
+		case err != nil:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to check API key"})
+			c.Abort()
+			return
+		}
 
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token: " + err.Error()})
+		c.Set("api_key", key)
+		c.Next()
+	}
+}
+
+// AcceptAPIKey lets an API key act as its owner on the route when it has the
+// scope. Requests without a key pass through untouched.
+func (sm *SecurityMiddleware) AcceptAPIKey(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("api_key")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		key := value.(*models.APIKey)
+		if !key.HasScope(scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API key lacks the %s scope", scope)})
 			c.Abort()
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// Check token expiration
-			if exp, ok := claims["exp"].(float64); ok {
-				if time.Now().Unix() > int64(exp) {
-					c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
-					c.Abort()
-					return
-				}
-			}
+		c.Set("user_id", key.UserID)
+		c.Set("api_key_id", key.ID)
+		c.Next()
+	}
+}
 
-			// Set user context
-			if userID, ok := claims["user_id"]; ok {
-				c.Set("user_id", userID)
-			}
-			if username, ok := claims["username"]; ok {
-				c.Set("username", username)
+// APIKeyCaller reports whether the request acts through an API key.
+func APIKeyCaller(c *gin.Context) bool {
+	_, ok := c.Get("api_key_id")
+	return ok
+}
+
+// OptionalAuth sets the user context when a valid token is present but never rejects the request.
+func (sm *SecurityMiddleware) OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			if claims, _, err := sm.parseBearerClaims(c); err == nil {
+				setClaimsContext(c, claims)
 			}
-			if role, ok := claims["role"]; ok {
-				c.Set("role", role)
+		}
+		c.Next()
+	}
+}
+
+func (sm *SecurityMiddleware) parseBearerClaims(c *gin.Context) (jwt.MapClaims, int, error) {
+	claims, err := sm.parseAuthorization(c.GetHeader("Authorization"))
+	if err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+	return claims, http.StatusOK, nil
+}
+
+func (sm *SecurityMiddleware) parseAuthorization(authHeader string) (jwt.MapClaims, error) {
+	if authHeader == "" {
+		return nil, fmt.Errorf("Authorization header required")
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, fmt.Errorf("Bearer token required")
+	}
+
+	tokenString := parts[1]
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return sm.jwtSecret, nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Invalid token: %s", err.Error())
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("Invalid token claims")
+	}
+
+	// Check token expiration
+	if exp, ok := claims["exp"].(float64); ok {
+		if time.Now().Unix() > int64(exp) {
+			return nil, fmt.Errorf("Token expired")
+		}
+	}
+
+	return claims, nil
+}
+
+func setClaimsContext(c *gin.Context, claims jwt.MapClaims) {
+	if userID, ok := claims["user_id"]; ok {
+		c.Set("user_id", userID)
+	}
+	if username, ok := claims["username"]; ok {
+		c.Set("username", username)
+	}
+	if role, ok := claims["role"]; ok {
+		c.Set("role", role)
+	}
+	if teamIDs, ok := claims["team_ids"].([]interface{}); ok {
+		ids := make([]int64, 0, len(teamIDs))
+		for _, id := range teamIDs {
+			if v, ok := id.(float64); ok {
+				ids = append(ids, int64(v))
 			}
-			c.Next()
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
 		}
+		c.Set("team_ids", ids)
+	}
+	if name, ok := serviceAccountName(claims); ok {
+		c.Set("service_account", name)
+		c.Set("service_scopes", claimScopes(claims))
+	}
+}
+
+func serviceAccountName(claims jwt.MapClaims) (string, bool) {
+	if tokenType, _ := claims["token_type"].(string); tokenType != "service" {
+		return "", false
+	}
+	name, _ := claims["service_name"].(string)
+	return name, name != ""
+}
+
+func claimScopes(claims jwt.MapClaims) []string {
+	raw, _ := claims["scopes"].([]interface{})
+	scopes := make([]string, 0, len(raw))
+	for _, scope := range raw {
+		if s, ok := scope.(string); ok {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+func claimsHaveScope(claims jwt.MapClaims, scope string) bool {
+	for _, s := range claimScopes(claims) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceScope reports the calling service account when its token carries
+// the given scope.
+func ServiceScope(c *gin.Context, scope string) (string, bool) {
+	name, ok := c.Get("service_account")
+	if !ok {
+		return "", false
+	}
+	scopes, _ := c.Get("service_scopes")
+	list, _ := scopes.([]string)
+	for _, s := range list {
+		if s == scope {
+			return name.(string), true
+		}
+	}
+	return "", false
+}
+
+// ServiceAccount authenticates a service-account bearer token outside of
+// HTTP, returning the service name and its scopes.
+func (sm *SecurityMiddleware) ServiceAccount(authorization string) (string, []string, error) {
+	claims, err := sm.parseAuthorization(authorization)
+	if err != nil {
+		return "", nil, err
+	}
+	name, ok := serviceAccountName(claims)
+	if !ok {
+		return "", nil, fmt.Errorf("Service account token required")
+	}
+	return name, claimScopes(claims), nil
+}
+
+func (sm *SecurityMiddleware) AuditServiceScope(c *gin.Context, serviceName, scope string) {
+	if sm.scopeAuditor != nil {
+		sm.scopeAuditor(c, serviceName, scope)
 	}
 }
 
@@ -357,6 +572,62 @@ func (sm *SecurityMiddleware) RequireAdmin() gin.HandlerFunc {
 	}
 }
 
+// RequireAnyRole allows callers holding at least one of roles, checked via
+// RBAC when available and the token's role claim otherwise.
+func (sm *SecurityMiddleware) RequireAnyRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sm.rbacService == nil {
+			role, _ := c.Get("role")
+			for _, allowed := range roles {
+				if role == allowed {
+					c.Next()
+					return
+				}
+			}
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient role"})
+			c.Abort()
+			return
+		}
+
+		userID, ok := contextUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+			c.Abort()
+			return
+		}
+
+		for _, role := range roles {
+			hasRole, err := sm.rbacService.HasRole(userID, role)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+				c.Abort()
+				return
+			}
+			if hasRole {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient role"})
+		c.Abort()
+	}
+}
+
+func contextUserID(c *gin.Context) (int64, bool) {
+	value, _ := c.Get("user_id")
+	switch v := value.(type) {
+	case string:
+		id, err := strconv.ParseInt(v, 10, 64)
+		return id, err == nil
+	case float64:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
 func (sm *SecurityMiddleware) RequirePermission(resource, action string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if sm.rbacService == nil {
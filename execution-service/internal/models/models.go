@@ -2,6 +2,11 @@ package models
 
 import (
 	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,6 +17,7 @@ const (
 	VerdictAccepted Verdict = "AC"
 	VerdictWrongAns Verdict = "WA"
 	VerdictTimeLim  Verdict = "TLE"
+	VerdictIdleLim  Verdict = "ILE"
 	VerdictMemLim   Verdict = "MLE"
 	VerdictRuntime  Verdict = "RE"
 	VerdictCompile  Verdict = "CE"
@@ -19,44 +25,534 @@ const (
 )
 
 type Submission struct {
-	ID              int64      `json:"id" db:"id"`
-	UserID          int64      `json:"user_id" db:"user_id"`
-	ProblemID       int64      `json:"problem_id" db:"problem_id"`
-	ContestID       *int64     `json:"contest_id,omitempty" db:"contest_id"`
-	Language        string     `json:"language" db:"language"`
-	CodeURL         string     `json:"code_url" db:"code_url"`
-	Verdict         Verdict    `json:"verdict" db:"verdict"`
-	Score           int        `json:"score" db:"score"`
-	ExecutionTimeMs *int       `json:"execution_time_ms,omitempty" db:"execution_time_ms"`
-	MemoryUsedKb    *int       `json:"memory_used_kb,omitempty" db:"memory_used_kb"`
-	TestCasesPassed int        `json:"test_cases_passed" db:"test_cases_passed"`
-	TestCasesTotal  *int       `json:"test_cases_total,omitempty" db:"test_cases_total"`
-	CompileOutput   *string    `json:"compile_output,omitempty" db:"compile_output"`
-	IsPublic        bool       `json:"is_public" db:"is_public"`
-	SubmittedAt     time.Time  `json:"submitted_at" db:"submitted_at"`
-	JudgedAt        *time.Time `json:"judged_at,omitempty" db:"judged_at"`
+	ID              int64              `json:"id" db:"id"`
+	UserID          int64              `json:"user_id" db:"user_id"`
+	TeamID          *int64             `json:"team_id,omitempty" db:"team_id"`
+	ProblemID       int64              `json:"problem_id" db:"problem_id"`
+	ContestID       *int64             `json:"contest_id,omitempty" db:"contest_id"`
+	Language        string             `json:"language" db:"language"`
+	CodeURL         string             `json:"code_url" db:"code_url"`
+	Verdict         Verdict            `json:"verdict" db:"verdict"`
+	Score           Score              `json:"score" db:"score"`
+	ExecutionTimeMs *int               `json:"execution_time_ms,omitempty" db:"execution_time_ms"`
+	MemoryUsedKb    *int               `json:"memory_used_kb,omitempty" db:"memory_used_kb"`
+	TestCasesPassed int                `json:"test_cases_passed" db:"test_cases_passed"`
+	TestCasesTotal  *int               `json:"test_cases_total,omitempty" db:"test_cases_total"`
+	CompileOutput   *string            `json:"compile_output,omitempty" db:"compile_output"`
+	IsPublic        bool               `json:"is_public" db:"is_public"`
+	Metadata        SubmissionMetadata `json:"metadata" db:"metadata"`
+	TestsetVersion  *string            `json:"testset_version,omitempty" db:"testset_version"`
+	OutdatedTests   bool               `json:"outdated_tests" db:"outdated_tests"`
+	Trusted         bool               `json:"trusted,omitempty" db:"trusted"`
+	RepositoryURL   *string            `json:"repository_url,omitempty" db:"repository_url"`
+	CommitSHA       *string            `json:"commit_sha,omitempty" db:"commit_sha"`
+	EntryFile       *string            `json:"entry_file,omitempty" db:"entry_file"`
+	SubmittedAt     time.Time          `json:"submitted_at" db:"submitted_at"`
+	JudgedAt        *time.Time         `json:"judged_at,omitempty" db:"judged_at"`
+}
+
+// AttemptTimeline compacts a user's judged submissions to one problem into
+// runs of consecutive attempts that ended the same way.
+type AttemptTimeline struct {
+	UserID          int64          `json:"user_id"`
+	ProblemID       int64          `json:"problem_id"`
+	TotalAttempts   int            `json:"total_attempts"`
+	SolvedAtAttempt *int           `json:"solved_at_attempt,omitempty"`
+	Entries         []AttemptEntry `json:"entries"`
+	ComputedAt      time.Time      `json:"computed_at"`
 }
 
+// AttemptEntry covers attempts FirstAttempt through LastAttempt, which share
+// verdict, tests passed and score. Deltas compare with the previous entry and
+// are zero on the first.
+type AttemptEntry struct {
+	FirstAttempt         int       `json:"first_attempt"`
+	LastAttempt          int       `json:"last_attempt"`
+	SubmissionIDs        []int64   `json:"submission_ids"`
+	Verdict              Verdict   `json:"verdict"`
+	TestCasesPassed      int       `json:"test_cases_passed"`
+	TestCasesTotal       *int      `json:"test_cases_total,omitempty"`
+	Score                Score     `json:"score"`
+	PassedDelta          int       `json:"passed_delta"`
+	ScoreDelta           Score     `json:"score_delta"`
+	SincePreviousSeconds int64     `json:"since_previous_seconds"`
+	FirstSubmittedAt     time.Time `json:"first_submitted_at"`
+	LastSubmittedAt      time.Time `json:"last_submitted_at"`
+}
+
+// SubmissionMetadata holds client-supplied tags and attributes stored as
+// JSONB. Client is only accepted with SubmissionSchemaV2.
+type SubmissionMetadata struct {
+	Tags       []string          `json:"tags,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Client     *ClientContext    `json:"client,omitempty"`
+}
+
+func (m SubmissionMetadata) IsEmpty() bool {
+	return len(m.Tags) == 0 && len(m.Attributes) == 0 && m.Client == nil
+}
+
+// Submission schema versions. Version 1 is the original request and
+// response; version 2, used by IDE plugins, adds the client block to requests
+// and the structured verdict to responses. Requests without schema_version
+// are version 1.
+const (
+	SubmissionSchemaV1 = 1
+	SubmissionSchemaV2 = 2
+)
+
+// ClientContext describes the editor a submission was sent from.
+type ClientContext struct {
+	Editor        string            `json:"editor"`
+	EditorVersion string            `json:"editor_version,omitempty"`
+	Plugin        string            `json:"plugin"`
+	PluginVersion string            `json:"plugin_version,omitempty"`
+	LocalTests    []LocalTestResult `json:"local_tests,omitempty"`
+}
+
+// LocalTestResult is a test the plugin ran on the user's machine before
+// submitting.
+type LocalTestResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	TimeMs *int   `json:"time_ms,omitempty"`
+}
+
+// StructuredVerdict is the version 2 verdict, shaped for inline display in
+// an editor. Ranges group consecutive tests that ended the same way.
+type StructuredVerdict struct {
+	SchemaVersion int            `json:"schema_version"`
+	SubmissionID  int64          `json:"submission_id"`
+	Verdict       Verdict        `json:"verdict"`
+	Final         bool           `json:"final"`
+	Score         Score          `json:"score"`
+	TestsPassed   int            `json:"tests_passed"`
+	TestsTotal    *int           `json:"tests_total,omitempty"`
+	CompileOutput *string        `json:"compile_output,omitempty"`
+	Ranges        []VerdictRange `json:"ranges"`
+}
+
+// VerdictRange is a run of tests FirstTest to LastTest, inclusive, with the
+// same verdict.
+type VerdictRange struct {
+	FirstTest   int     `json:"first_test"`
+	LastTest    int     `json:"last_test"`
+	Verdict     Verdict `json:"verdict"`
+	MaxTimeMs   int     `json:"max_time_ms"`
+	MaxMemoryKb int     `json:"max_memory_kb"`
+}
+
+func (m SubmissionMetadata) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+func (m *SubmissionMetadata) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = SubmissionMetadata{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	}
+	return fmt.Errorf("unsupported metadata type %T", value)
+}
+
+// IdempotencyKey ties a client-supplied key to the submission its first
+// request created. Keys are scoped to the submitting user; RequestHash tells
+// a retry apart from a different request reusing the key.
+type IdempotencyKey struct {
+	UserID       int64     `json:"user_id" db:"user_id"`
+	Key          string    `json:"idempotency_key" db:"idempotency_key"`
+	RequestHash  string    `json:"request_hash" db:"request_hash"`
+	SubmissionID int64     `json:"submission_id" db:"submission_id"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// OutboxEntry is a recorded submission whose judge request has not been
+// confirmed by the broker yet.
+type OutboxEntry struct {
+	ID            int64     `json:"id" db:"id"`
+	SubmissionID  int64     `json:"submission_id" db:"submission_id"`
+	TimeLimitMs   int       `json:"time_limit_ms" db:"time_limit_ms"`
+	MemoryLimitKb int       `json:"memory_limit_kb" db:"memory_limit_kb"`
+	Priority      int       `json:"priority" db:"priority"`
+	Attempts      int       `json:"attempts" db:"attempts"`
+	LastError     *string   `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// SubmissionTestResult records the test's limits before the language
+// multipliers in TimeLimitMs and MemoryLimitKb, and the limits the run
+// actually got in the effective fields.
 type SubmissionTestResult struct {
-	ID              int64     `json:"id" db:"id"`
-	SubmissionID    int64     `json:"submission_id" db:"submission_id"`
-	TestCaseID      int64     `json:"test_case_id" db:"test_case_id"`
-	TestNumber      int       `json:"test_number" db:"test_number"`
-	Verdict         Verdict   `json:"verdict" db:"verdict"`
-	ExecutionTimeMs *int      `json:"execution_time_ms,omitempty" db:"execution_time_ms"`
-	MemoryUsedKb    *int      `json:"memory_used_kb,omitempty" db:"memory_used_kb"`
-	CheckerOutput   *string   `json:"checker_output,omitempty" db:"checker_output"`
-	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	ID                     int64          `json:"id" db:"id"`
+	SubmissionID           int64          `json:"submission_id" db:"submission_id"`
+	TestCaseID             int64          `json:"test_case_id" db:"test_case_id"`
+	TestNumber             int            `json:"test_number" db:"test_number"`
+	Verdict                Verdict        `json:"verdict" db:"verdict"`
+	ExecutionTimeMs        *int           `json:"execution_time_ms,omitempty" db:"execution_time_ms"`
+	MemoryUsedKb           *int           `json:"memory_used_kb,omitempty" db:"memory_used_kb"`
+	CheckerOutput          *string        `json:"checker_output,omitempty" db:"checker_output"`
+	CheckerTimeMs          *int           `json:"checker_time_ms,omitempty" db:"checker_time_ms"`
+	CheckerMemoryKb        *int           `json:"checker_memory_kb,omitempty" db:"checker_memory_kb"`
+	Attempts               TestAttempts   `json:"attempts,omitempty" db:"attempts"`
+	TimeLimitMs            *int           `json:"time_limit_ms,omitempty" db:"time_limit_ms"`
+	MemoryLimitKb          *int           `json:"memory_limit_kb,omitempty" db:"memory_limit_kb"`
+	EffectiveTimeLimitMs   *int           `json:"effective_time_limit_ms,omitempty" db:"effective_time_limit_ms"`
+	EffectiveMemoryLimitKb *int           `json:"effective_memory_limit_kb,omitempty" db:"effective_memory_limit_kb"`
+	Stderr                 *string        `json:"stderr,omitempty" db:"stderr"`
+	StderrVisible          bool           `json:"-" db:"stderr_visible"`
+	Usage                  *ResourceUsage `json:"usage,omitempty" db:"usage"`
+	UsageVisible           bool           `json:"-" db:"usage_visible"`
+	Seed                   *int64         `json:"seed,omitempty" db:"seed"`
+	IsSample               bool           `json:"is_sample" db:"is_sample"`
+	CreatedAt              time.Time      `json:"created_at" db:"created_at"`
+}
+
+// ResourceUsage is the sandbox's account of one run, kept when usage reports
+// are on. Context switches come from isolate; writes count files in the box
+// the run created or changed, stdout and stderr included. Isolate does not
+// report process counts.
+type ResourceUsage struct {
+	CPUTimeMs         int   `json:"cpu_ms"`
+	WallTimeMs        int   `json:"wall_ms"`
+	MaxRSSKb          int   `json:"max_rss_kb"`
+	CgroupMemoryKb    int   `json:"cg_mem_kb,omitempty"`
+	VoluntarySwitches int   `json:"csw_voluntary"`
+	ForcedSwitches    int   `json:"csw_forced"`
+	FilesWritten      int   `json:"files_written"`
+	BytesWritten      int64 `json:"bytes_written"`
+	OOMKilled         bool  `json:"oom_killed,omitempty"`
+}
+
+func (u ResourceUsage) Value() (driver.Value, error) {
+	return json.Marshal(u)
+}
+
+func (u *ResourceUsage) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, u)
+	case string:
+		return json.Unmarshal([]byte(v), u)
+	}
+	return fmt.Errorf("unsupported resource usage type %T", value)
+}
+
+// UsageReport says whether usage is kept for each test and who sees it:
+// nobody, staff only, or staff and the submitter.
+type UsageReport string
+
+const (
+	UsageReportOff   UsageReport = "off"
+	UsageReportStaff UsageReport = "staff"
+	UsageReportUsers UsageReport = "users"
+)
+
+// StderrVisibility is a problem's policy for showing program stderr to the
+// submitter. Staff always see it.
+type StderrVisibility string
+
+const (
+	StderrSamples StderrVisibility = "samples"
+	StderrAlways  StderrVisibility = "always"
+	StderrNever   StderrVisibility = "never"
+)
+
+// Allows reports whether stderr from a test is shown to the submitter.
+// Unknown policies fall back to samples only.
+func (v StderrVisibility) Allows(isSample bool) bool {
+	switch v {
+	case StderrAlways:
+		return true
+	case StderrNever:
+		return false
+	}
+	return isSample
+}
+
+// JudgingPolicy is a problem's rule for which tests run after one fails.
+// Without a policy judging continues past wrong answers only.
+type JudgingPolicy string
+
+const (
+	JudgingFirstFailure    JudgingPolicy = "first_failure"
+	JudgingRunAll          JudgingPolicy = "run_all"
+	JudgingRunAllInSubtask JudgingPolicy = "run_all_in_subtask"
+)
+
+// Continues reports whether judging goes on after a test fails with verdict.
+// Under JudgingRunAllInSubtask the rest of the failed subtask is skipped.
+func (p JudgingPolicy) Continues(verdict Verdict) bool {
+	switch p {
+	case JudgingFirstFailure:
+		return false
+	case JudgingRunAll, JudgingRunAllInSubtask:
+		return true
+	}
+	return verdict == VerdictWrongAns
+}
+
+// TestAttempt is one run of a test re-executed after a borderline TLE.
+type TestAttempt struct {
+	Verdict         Verdict `json:"verdict"`
+	ExecutionTimeMs int     `json:"execution_time_ms"`
+	WallTimeMs      int     `json:"wall_time_ms"`
+}
+
+// TestAttempts lists every run of a retried test in order, the original first;
+// it is empty when the test ran once.
+type TestAttempts []TestAttempt
+
+func (a TestAttempts) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(a)
+}
+
+func (a *TestAttempts) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	}
+	return fmt.Errorf("unsupported test attempts type %T", value)
+}
+
+// InterruptedTest is a test finished before judging was interrupted, with
+// its share of the points so a resumed run scores it the same way.
+type InterruptedTest struct {
+	Result SubmissionTestResult `json:"result"`
+	Credit float64              `json:"credit"`
+}
+
+type InterruptedTests []InterruptedTest
+
+func (t InterruptedTests) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+func (t *InterruptedTests) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	}
+	return fmt.Errorf("unsupported interrupted tests type %T", value)
+}
+
+// InterruptedJudging holds the tests a submission had finished when a
+// shutdown stopped its judging, so the next run can skip them.
+type InterruptedJudging struct {
+	SubmissionID   int64            `json:"submission_id" db:"submission_id"`
+	TestsetVersion string           `json:"testset_version" db:"testset_version"`
+	Tests          InterruptedTests `json:"tests" db:"tests"`
+	InterruptedAt  time.Time        `json:"interrupted_at" db:"interrupted_at"`
 }
 
+// LanguageOutputOnly is the submission type for output-only problems. The
+// submitted "code" is a zip archive of outputs, one file per test named by
+// its test number, such as 1.out; nothing is compiled or run.
+const LanguageOutputOnly = "output_only"
+
 type SupportedLanguage struct {
-	ID             int     `json:"id" db:"id"`
-	LanguageCode   string  `json:"language_code" db:"language_code"`
-	LanguageName   string  `json:"language_name" db:"language_name"`
-	Version        string  `json:"version,omitempty" db:"version"`
-	CompileCommand *string `json:"compile_command,omitempty" db:"compile_command"`
-	ExecuteCommand string  `json:"execute_command" db:"execute_command"`
-	IsEnabled      bool    `json:"is_enabled" db:"is_enabled"`
+	ID               int              `json:"id" db:"id"`
+	LanguageCode     string           `json:"language_code" db:"language_code"`
+	LanguageName     string           `json:"language_name" db:"language_name"`
+	Version          string           `json:"version,omitempty" db:"version"`
+	CompileCommand   *string          `json:"compile_command,omitempty" db:"compile_command"`
+	CompilePipeline  CompilePipeline  `json:"compile_pipeline,omitempty" db:"compile_pipeline"`
+	ExecuteCommand   string           `json:"execute_command" db:"execute_command"`
+	FileExtension    string           `json:"file_extension,omitempty" db:"file_extension"`
+	ExecutionProfile ExecutionProfile `json:"execution_profile" db:"execution_profile"`
+	TimeMultiplier   float64          `json:"time_multiplier" db:"time_multiplier"`
+	MemoryMultiplier float64          `json:"memory_multiplier" db:"memory_multiplier"`
+	IsEnabled        bool             `json:"is_enabled" db:"is_enabled"`
+}
+
+// LanguageSettings is an admin change to a supported language. Nil fields
+// keep the current value, or the default when creating.
+type LanguageSettings struct {
+	LanguageName     *string           `json:"language_name,omitempty"`
+	Version          *string           `json:"version,omitempty"`
+	CompileCommand   *string           `json:"compile_command,omitempty"`
+	CompilePipeline  *CompilePipeline  `json:"compile_pipeline,omitempty"`
+	ExecuteCommand   *string           `json:"execute_command,omitempty"`
+	FileExtension    *string           `json:"file_extension,omitempty"`
+	ExecutionProfile *ExecutionProfile `json:"execution_profile,omitempty"`
+	TimeMultiplier   *float64          `json:"time_multiplier,omitempty"`
+	MemoryMultiplier *float64          `json:"memory_multiplier,omitempty"`
+	IsEnabled        *bool             `json:"is_enabled,omitempty"`
+}
+
+// Apply copies the set fields onto language. An empty compile command
+// clears it.
+func (s *LanguageSettings) Apply(language *SupportedLanguage) {
+	if s.LanguageName != nil {
+		language.LanguageName = *s.LanguageName
+	}
+	if s.Version != nil {
+		language.Version = *s.Version
+	}
+	if s.CompileCommand != nil {
+		language.CompileCommand = s.CompileCommand
+		if *s.CompileCommand == "" {
+			language.CompileCommand = nil
+		}
+	}
+	if s.CompilePipeline != nil {
+		language.CompilePipeline = *s.CompilePipeline
+	}
+	if s.ExecuteCommand != nil {
+		language.ExecuteCommand = *s.ExecuteCommand
+	}
+	if s.FileExtension != nil {
+		language.FileExtension = *s.FileExtension
+	}
+	if s.ExecutionProfile != nil {
+		language.ExecutionProfile = *s.ExecutionProfile
+	}
+	if s.TimeMultiplier != nil {
+		language.TimeMultiplier = *s.TimeMultiplier
+	}
+	if s.MemoryMultiplier != nil {
+		language.MemoryMultiplier = *s.MemoryMultiplier
+	}
+	if s.IsEnabled != nil {
+		language.IsEnabled = *s.IsEnabled
+	}
+}
+
+// Stages returns the build pipeline, treating a bare CompileCommand as a
+// single compile stage. Interpreted languages have no stages.
+func (l SupportedLanguage) Stages() CompilePipeline {
+	if len(l.CompilePipeline) > 0 {
+		return l.CompilePipeline
+	}
+	if l.CompileCommand != nil {
+		return CompilePipeline{{Name: "compile", Command: *l.CompileCommand}}
+	}
+	return nil
+}
+
+// Stage failure classes: a failing "compile" stage is reported to the
+// submitter as CE, a failing "system" stage is a toolchain fault.
+const (
+	StageFailureCompile = "compile"
+	StageFailureSystem  = "system"
+)
+
+// CompileStage is one step of a language build. Stages run in order in the
+// same box, so artifacts of earlier stages are inputs to later ones. Zero
+// limits fall back to the sandbox compile defaults.
+type CompileStage struct {
+	Name          string `json:"name" yaml:"name"`
+	Command       string `json:"command" yaml:"command"`
+	TimeLimitMs   int    `json:"time_limit_ms,omitempty" yaml:"time_limit_ms"`
+	MemoryLimitKb int    `json:"memory_limit_kb,omitempty" yaml:"memory_limit_kb"`
+	Processes     int    `json:"processes,omitempty" yaml:"processes"`
+	OnFailure     string `json:"on_failure,omitempty" yaml:"on_failure"`
+}
+
+// CompilePipeline is stored as JSONB.
+type CompilePipeline []CompileStage
+
+func (p CompilePipeline) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+func (p *CompilePipeline) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	}
+	return fmt.Errorf("unsupported compile pipeline type %T", value)
+}
+
+// ExecutionProfile is a language's device and descriptor policy. Devices are
+// exposed in every box the language builds or runs in, and must be on the
+// sandbox allowlist. NullStdio points standard descriptors a run does not
+// redirect at /dev/null instead of leaving them to the backend, for runtimes
+// that fail on a closed descriptor. It is stored as JSONB.
+type ExecutionProfile struct {
+	Devices   []string `json:"devices,omitempty"`
+	NullStdio bool     `json:"null_stdio,omitempty"`
+}
+
+func (p ExecutionProfile) IsEmpty() bool {
+	return len(p.Devices) == 0 && !p.NullStdio
+}
+
+func (p ExecutionProfile) Value() (driver.Value, error) {
+	if p.IsEmpty() {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+func (p *ExecutionProfile) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*p = ExecutionProfile{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	}
+	return fmt.Errorf("unsupported execution profile type %T", value)
+}
+
+// SandboxEnvironment is a node's toolchain snapshot. ToolchainID covers the
+// installed compiler and isolate versions, which a node cannot change at
+// runtime; pipelines and commands are configuration a pinned contest keeps
+// using even if the node's defaults change.
+type SandboxEnvironment struct {
+	ToolchainID     string                     `json:"toolchain_id"`
+	Toolchains      map[string]string          `json:"toolchains"`
+	IsolateVersion  string                     `json:"isolate_version"`
+	Pipelines       map[string]CompilePipeline `json:"pipelines"`
+	ExecuteCommands map[string]string          `json:"execute_commands"`
+	JavaClassMode   string                     `json:"java_class_mode"`
+	CapturedAt      time.Time                  `json:"captured_at"`
+}
+
+func (e SandboxEnvironment) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
+func (e *SandboxEnvironment) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	}
+	return fmt.Errorf("unsupported environment type %T", value)
+}
+
+type ContestEnvironment struct {
+	ContestID   int64              `json:"contest_id" db:"contest_id"`
+	Environment SandboxEnvironment `json:"environment" db:"environment"`
+	PinnedBy    int64              `json:"pinned_by" db:"pinned_by"`
+	PinnedAt    time.Time          `json:"pinned_at" db:"pinned_at"`
 }
 
 type JudgeWorker struct {
@@ -66,6 +562,7 @@ type JudgeWorker struct {
 	CurrentSubmissionID *int64    `json:"current_submission_id,omitempty" db:"current_submission_id"`
 	StartedAt           time.Time `json:"started_at" db:"started_at"`
 	LastHeartbeat       time.Time `json:"last_heartbeat" db:"last_heartbeat"`
+	LeaseExpiresAt      time.Time `json:"lease_expires_at" db:"lease_expires_at"`
 	BoxID               *int      `json:"box_id,omitempty" db:"box_id"`
 }
 
@@ -77,24 +574,231 @@ type ExecutionLog struct {
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
 
+// ContestLogEntry is a WARN or ERROR execution log of a contest submission
+// as streamed to proctors, with code contents redacted from Message.
+type ContestLogEntry struct {
+	ContestID    int64     `json:"contest_id"`
+	SubmissionID int64     `json:"submission_id"`
+	Level        string    `json:"level"`
+	Message      string    `json:"message"`
+	Node         string    `json:"node"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
 type JudgeRequest struct {
-	SubmissionID  int64  `json:"submission_id"`
-	UserID        int64  `json:"user_id"`
-	ProblemID     int64  `json:"problem_id"`
-	Language      string `json:"language"`
-	CodeURL       string `json:"code_url"`
-	TimeLimitMs   int    `json:"time_limit_ms"`
-	MemoryLimitKb int    `json:"memory_limit_kb"`
-	Priority      int    `json:"priority"`
+	SubmissionID  int64               `json:"submission_id"`
+	UserID        int64               `json:"user_id"`
+	TeamID        *int64              `json:"team_id,omitempty"`
+	ContestID     *int64              `json:"contest_id,omitempty"`
+	ProblemID     int64               `json:"problem_id"`
+	Language      string              `json:"language"`
+	CodeURL       string              `json:"code_url"`
+	TimeLimitMs   int                 `json:"time_limit_ms"`
+	MemoryLimitKb int                 `json:"memory_limit_kb"`
+	Priority      int                 `json:"priority"`
+	Metadata      *SubmissionMetadata `json:"metadata,omitempty"`
+	Trusted       bool                `json:"trusted,omitempty"`
+	// EntryFile marks a project submission: CodeURL is a gzipped tarball
+	// and EntryFile, at its root, is compiled as the source.
+	EntryFile string `json:"entry_file,omitempty"`
 }
 
 type JudgeResult struct {
-	SubmissionID    int64   `json:"submission_id"`
-	Verdict         Verdict `json:"verdict"`
-	ExecutionTimeMs int     `json:"execution_time_ms"`
-	MemoryUsedKb    int     `json:"memory_used_kb"`
-	TestCasesPassed int     `json:"test_cases_passed"`
-	TestCasesTotal  int     `json:"test_cases_total"`
+	SubmissionID    int64               `json:"submission_id"`
+	UserID          int64               `json:"user_id"`
+	ProblemID       int64               `json:"problem_id"`
+	TeamID          *int64              `json:"team_id,omitempty"`
+	ContestID       *int64              `json:"contest_id,omitempty"`
+	Verdict         Verdict             `json:"verdict"`
+	ExecutionTimeMs int                 `json:"execution_time_ms"`
+	MemoryUsedKb    int                 `json:"memory_used_kb"`
+	TestCasesPassed int                 `json:"test_cases_passed"`
+	TestCasesTotal  int                 `json:"test_cases_total"`
+	Score           Score               `json:"score"`
+	TestsetVersion  string              `json:"testset_version,omitempty"`
+	Metadata        *SubmissionMetadata `json:"metadata,omitempty"`
+}
+
+type ProgressStage string
+
+const (
+	ProgressQueued    ProgressStage = "queued"
+	ProgressCompiling ProgressStage = "compiling"
+	ProgressRunning   ProgressStage = "running"
+	ProgressTested    ProgressStage = "tested"
+	ProgressCompleted ProgressStage = "completed"
+)
+
+// Kinds of cache invalidation target.
+const (
+	CacheTargetProblem      = "problem"
+	CacheTargetLanguage     = "language"
+	CacheTargetUserPerms    = "user-perms"
+	CacheTargetAllTestCases = "all-testcases"
+)
+
+// CacheTarget is one typed target of a cache invalidation, written as
+// problem:123, language:cpp, user-perms:42 or all-testcases.
+type CacheTarget struct {
+	Kind     string `json:"kind"`
+	ID       int64  `json:"id,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+func (t CacheTarget) String() string {
+	switch {
+	case t.Language != "":
+		return t.Kind + ":" + t.Language
+	case t.ID != 0:
+		return fmt.Sprintf("%s:%d", t.Kind, t.ID)
+	default:
+		return t.Kind
+	}
+}
+
+// CacheInvalidation is broadcast to every node so each drops its in-memory
+// copies of the targets.
+type CacheInvalidation struct {
+	Targets  []CacheTarget `json:"targets"`
+	Origin   string        `json:"origin"`
+	IssuedAt time.Time     `json:"issued_at"`
+}
+
+// SubmissionProgress is one step of judging pushed to submission streams.
+// Running steps name the test about to run and tested steps its verdict;
+// the completed step carries the stored submission.
+type SubmissionProgress struct {
+	SubmissionID int64         `json:"submission_id"`
+	Stage        ProgressStage `json:"stage"`
+	TestNumber   int           `json:"test_number,omitempty"`
+	TestVerdict  Verdict       `json:"test_verdict,omitempty"`
+	TestsDone    int           `json:"tests_done"`
+	TestsTotal   int           `json:"tests_total,omitempty"`
+	Submission   *Submission   `json:"submission,omitempty"`
+	Timestamp    time.Time     `json:"timestamp"`
+}
+
+type CompilationFailedEvent struct {
+	SubmissionID int64               `json:"submission_id"`
+	UserID       int64               `json:"user_id"`
+	TeamID       *int64              `json:"team_id,omitempty"`
+	ContestID    *int64              `json:"contest_id,omitempty"`
+	Language     string              `json:"language"`
+	ErrorMessage string              `json:"error_message"`
+	Metadata     *SubmissionMetadata `json:"metadata,omitempty"`
+}
+
+// NodeDeviationEvent alerts admins that a judge node's canary results
+// differ from the other nodes'.
+type NodeDeviationEvent struct {
+	Node     string   `json:"node"`
+	Reasons  []string `json:"reasons"`
+	Drained  bool     `json:"drained"`
+	Canaries int      `json:"canaries"`
+}
+
+// BucketPolicyStatus is what the last check found on a bucket. Encryption is
+// the bucket's default SSE algorithm and RetainedObjects counts the contest
+// objects this node has locked since it started.
+type BucketPolicyStatus struct {
+	Bucket          string    `json:"bucket"`
+	Encryption      string    `json:"encryption"`
+	ObjectLock      bool      `json:"object_lock"`
+	RetainedObjects int       `json:"retained_objects"`
+	TransitionRules int       `json:"transition_rules"`
+	Errors          []string  `json:"errors,omitempty"`
+	CheckedAt       time.Time `json:"checked_at"`
+}
+
+// SpoolStatus reports submission code held on local disk while MinIO is
+// unavailable. Intake is degraded from the first spooled submission until
+// the spool has drained.
+type SpoolStatus struct {
+	Degraded         bool       `json:"degraded"`
+	DegradedSince    *time.Time `json:"degraded_since,omitempty"`
+	Entries          int        `json:"entries"`
+	Bytes            int64      `json:"bytes"`
+	MaxBytes         int64      `json:"max_bytes"`
+	OldestAgeSeconds float64    `json:"oldest_age_seconds"`
+	LastError        string     `json:"last_error,omitempty"`
+}
+
+// StoragePolicyFailedEvent alerts operators that the bucket's encryption,
+// retention or lifecycle policies could not be applied or checked.
+type StoragePolicyFailedEvent struct {
+	Bucket string   `json:"bucket"`
+	Errors []string `json:"errors"`
+}
+
+// JudgingBudgetExceededEvent alerts admins that a problem's worst-case
+// judging time is above the configured budget.
+type JudgingBudgetExceededEvent struct {
+	SubmissionID int64  `json:"submission_id"`
+	ProblemID    int64  `json:"problem_id"`
+	ContestID    *int64 `json:"contest_id,omitempty"`
+	TestCount    int    `json:"test_count"`
+	EstimatedMs  int64  `json:"estimated_ms"`
+	BudgetMs     int64  `json:"budget_ms"`
+}
+
+// PlagiarismConfirmedEvent announces that a reviewer confirmed a plagiarism
+// report.
+type PlagiarismConfirmedEvent struct {
+	ReportID            int64   `json:"report_id"`
+	SubmissionID        int64   `json:"submission_id"`
+	UserID              int64   `json:"user_id"`
+	SimilarSubmissionID int64   `json:"similar_submission_id"`
+	SimilarUserID       int64   `json:"similar_user_id"`
+	ProblemID           int64   `json:"problem_id"`
+	ContestID           *int64  `json:"contest_id,omitempty"`
+	SimilarityScore     float64 `json:"similarity_score"`
+	ReviewerID          int64   `json:"reviewer_id"`
+	ReviewNotes         string  `json:"review_notes,omitempty"`
+}
+
+type PlagiarismDetectedEvent struct {
+	ReportID            int64   `json:"report_id"`
+	SubmissionID        int64   `json:"submission_id"`
+	SimilarSubmissionID int64   `json:"similar_submission_id"`
+	ProblemID           int64   `json:"problem_id"`
+	ContestID           *int64  `json:"contest_id,omitempty"`
+	SimilarityScore     float64 `json:"similarity_score"`
+	Algorithm           string  `json:"algorithm"`
+}
+
+// ProblemDifficulty is the difficulty estimated from judge outcomes.
+// MedianRuntimeRatio is the median accepted runtime divided by the time limit.
+type ProblemDifficulty struct {
+	ProblemID           int64     `json:"problem_id" db:"problem_id"`
+	TotalSubmissions    int       `json:"total_submissions" db:"total_submissions"`
+	AcceptedSubmissions int       `json:"accepted_submissions" db:"accepted_submissions"`
+	AttemptingUsers     int       `json:"attempting_users" db:"attempting_users"`
+	Solvers             int       `json:"solvers" db:"solvers"`
+	AcceptanceRate      float64   `json:"acceptance_rate" db:"acceptance_rate"`
+	AvgAttemptsBeforeAC float64   `json:"avg_attempts_before_ac" db:"avg_attempts_before_ac"`
+	MedianRuntimeRatio  *float64  `json:"median_runtime_ratio,omitempty" db:"median_runtime_ratio"`
+	Score               float64   `json:"score" db:"score"`
+	Level               string    `json:"level" db:"level"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// VerdictCertificate is the canonical payload signed for a final verdict.
+// Field order is part of the signed encoding and must not change.
+type VerdictCertificate struct {
+	SubmissionID int64     `json:"submission_id"`
+	CodeHash     string    `json:"code_hash"`
+	ProblemID    int64     `json:"problem_id"`
+	Verdict      Verdict   `json:"verdict"`
+	Score        Score     `json:"score"`
+	SignedAt     time.Time `json:"signed_at"`
+}
+
+type VerdictSignature struct {
+	SubmissionID int64     `json:"submission_id" db:"submission_id"`
+	KeyID        string    `json:"key_id" db:"key_id"`
+	Payload      string    `json:"payload" db:"payload"`
+	Signature    string    `json:"signature" db:"signature"`
+	SignedAt     time.Time `json:"signed_at" db:"signed_at"`
 }
 
 type TestCase struct {
@@ -105,6 +809,19 @@ type TestCase struct {
 	TimeLimit   int    `json:"time_limit"`
 	MemoryLimit int    `json:"memory_limit"`
 	CheckerURL  string `json:"checker_url,omitempty"`
+	Subtask     int    `json:"subtask,omitempty"`
+	// InteractorURL makes the test interactive; the interactor's exit code
+	// decides the verdict and CheckerURL is ignored.
+	InteractorURL string `json:"interactor_url,omitempty"`
+}
+
+// SupplementaryFile is a read-only data file a problem provides to every
+// run, visible to the program as /data/<Name>.
+type SupplementaryFile struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	SHA256    string `json:"sha256"`
+	SizeBytes int64  `json:"size_bytes"`
 }
 
 func (v Verdict) Value() (driver.Value, error) {
@@ -123,20 +840,581 @@ func (v *Verdict) Scan(value interface{}) error {
 	return nil
 }
 
+// Score is a fixed-precision decimal with two places, stored as hundredths so
+// partial credit adds up without float drift. It is NUMERIC(10,2) in the
+// database and a plain JSON number on the wire.
+type Score int64
+
+const scoreScale = 100
+
+func NewScore(f float64) Score {
+	return Score(math.Round(f * scoreScale))
+}
+
+func ParseScore(s string) (Score, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid score %q: %w", s, err)
+	}
+	return NewScore(f), nil
+}
+
+func (s Score) Float64() float64 {
+	return float64(s) / scoreScale
+}
+
+func (s Score) String() string {
+	sign := ""
+	v := int64(s)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	whole, frac := v/scoreScale, v%scoreScale
+	if frac == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	return strings.TrimRight(fmt.Sprintf("%s%d.%02d", sign, whole, frac), "0")
+}
+
+func (s Score) MarshalJSON() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Score) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseScore(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+func (s Score) Value() (driver.Value, error) {
+	return s.String(), nil
+}
+
+func (s *Score) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*s = 0
+	case int64:
+		*s = Score(v * scoreScale)
+	case float64:
+		*s = NewScore(v)
+	case []byte:
+		return s.scanString(string(v))
+	case string:
+		return s.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Score", value)
+	}
+	return nil
+}
+
+func (s *Score) scanString(v string) error {
+	parsed, err := ParseScore(v)
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
 type EventMessage struct {
+	Sequence  int64                  `json:"sequence,omitempty"`
 	EventType string                 `json:"event_type"`
 	Data      map[string]interface{} `json:"data"`
 	Timestamp time.Time              `json:"timestamp"`
 }
 
 type PlagiarismReport struct {
+	ID              int64      `json:"id" db:"id"`
+	Submission1ID   int64      `json:"submission1_id" db:"submission1_id"`
+	Submission2ID   int64      `json:"submission2_id" db:"submission2_id"`
+	SimilarityScore float64    `json:"similarity_score" db:"similarity_score"`
+	Algorithm       string     `json:"algorithm" db:"algorithm"`
+	IsReviewed      bool       `json:"is_reviewed" db:"is_reviewed"`
+	ReviewerID      *int64     `json:"reviewer_id,omitempty" db:"reviewer_id"`
+	Status          string     `json:"status" db:"status"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	ReviewNotes     *string    `json:"review_notes,omitempty" db:"review_notes"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
+
+// PlagiarismBoilerplate is starter code for a problem. Runs of its lines are
+// stripped from submissions before plagiarism features are extracted.
+type PlagiarismBoilerplate struct {
+	ID        int64     `json:"id" db:"id"`
+	ProblemID int64     `json:"problem_id" db:"problem_id"`
+	Language  *string   `json:"language,omitempty" db:"language"`
+	Code      string    `json:"code" db:"code"`
+	CreatedBy int64     `json:"created_by" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+const (
+	PlagiarismPending   = "pending"
+	PlagiarismConfirmed = "confirmed"
+	PlagiarismDismissed = "dismissed"
+)
+
+const (
+	PlagiarismRunPending   = "pending"
+	PlagiarismRunRunning   = "running"
+	PlagiarismRunCompleted = "completed"
+	PlagiarismRunFailed    = "failed"
+)
+
+// PlagiarismRun cross-compares the accepted submissions of a contest, problem
+// by problem. Total and Processed count pair comparisons; Pairs is filled in
+// when the run completes, most similar first.
+type PlagiarismRun struct {
+	ID         int64           `json:"id" db:"id"`
+	ContestID  int64           `json:"contest_id" db:"contest_id"`
+	Threshold  float64         `json:"threshold" db:"threshold"`
+	Status     string          `json:"status" db:"status"`
+	Total      int             `json:"total" db:"total"`
+	Processed  int             `json:"processed" db:"processed"`
+	Pairs      SuspiciousPairs `json:"pairs" db:"pairs"`
+	Node       *string         `json:"node,omitempty" db:"node"`
+	Error      *string         `json:"error,omitempty" db:"error"`
+	CreatedBy  int64           `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+type SuspiciousPair struct {
+	ProblemID     int64   `json:"problem_id"`
+	Submission1ID int64   `json:"submission1_id"`
+	User1ID       int64   `json:"user1_id"`
+	Submission2ID int64   `json:"submission2_id"`
+	User2ID       int64   `json:"user2_id"`
+	Score         float64 `json:"similarity_score"`
+	Algorithm     string  `json:"algorithm"`
+}
+
+type SuspiciousPairs []SuspiciousPair
+
+func (p SuspiciousPairs) Value() (driver.Value, error) {
+	if p == nil {
+		p = SuspiciousPairs{}
+	}
+	return json.Marshal(p)
+}
+
+func (p *SuspiciousPairs) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	}
+	return fmt.Errorf("unsupported suspicious pairs type %T", value)
+}
+
+type LimitOverride struct {
+	ProblemID     int64     `json:"problem_id"`
+	TimeLimitMs   int       `json:"time_limit_ms"`
+	MemoryLimitKb int       `json:"memory_limit_kb"`
+	Reason        string    `json:"reason"`
+	SetBy         int64     `json:"set_by"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// PlatformLimits are the limits submissions get when they or their problem
+// set none, and the most any submission or problem may ask for.
+type PlatformLimits struct {
+	DefaultTimeLimitMs   int       `json:"default_time_limit_ms" db:"default_time_limit_ms"`
+	DefaultMemoryLimitKb int       `json:"default_memory_limit_kb" db:"default_memory_limit_kb"`
+	MaxTimeLimitMs       int       `json:"max_time_limit_ms" db:"max_time_limit_ms"`
+	MaxMemoryLimitKb     int       `json:"max_memory_limit_kb" db:"max_memory_limit_kb"`
+	MaxStackSizeKb       int       `json:"max_stack_size_kb" db:"max_stack_size_kb"`
+	MaxOutputSizeKb      int       `json:"max_output_size_kb" db:"max_output_size_kb"`
+	UpdatedBy            *int64    `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt            time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// ShadowConfig is a candidate checker or limit change judged alongside the
+// live configuration of a problem without affecting verdicts. Nil fields keep
+// the live value.
+type ShadowConfig struct {
+	ID            int64      `json:"id" db:"id"`
+	ProblemID     int64      `json:"problem_id" db:"problem_id"`
+	CheckerURL    *string    `json:"checker_url,omitempty" db:"checker_url"`
+	TimeLimitMs   *int       `json:"time_limit_ms,omitempty" db:"time_limit_ms"`
+	MemoryLimitKb *int       `json:"memory_limit_kb,omitempty" db:"memory_limit_kb"`
+	Note          string     `json:"note" db:"note"`
+	CreatedBy     int64      `json:"created_by" db:"created_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	StoppedAt     *time.Time `json:"stopped_at,omitempty" db:"stopped_at"`
+}
+
+type ShadowResult struct {
+	ID            int64           `json:"id" db:"id"`
+	ConfigID      int64           `json:"config_id" db:"config_id"`
+	SubmissionID  int64           `json:"submission_id" db:"submission_id"`
+	LiveVerdict   Verdict         `json:"live_verdict" db:"live_verdict"`
+	ShadowVerdict Verdict         `json:"shadow_verdict" db:"shadow_verdict"`
+	LivePassed    int             `json:"live_passed" db:"live_passed"`
+	ShadowPassed  int             `json:"shadow_passed" db:"shadow_passed"`
+	LiveTimeMs    int             `json:"live_time_ms" db:"live_time_ms"`
+	ShadowTimeMs  int             `json:"shadow_time_ms" db:"shadow_time_ms"`
+	Differences   TestDifferences `json:"differences,omitempty" db:"differences"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
+
+// TestDifference is a test whose verdict differs between live and shadow
+// judging. A test run by only one side has an empty verdict on the other.
+type TestDifference struct {
+	TestNumber    int     `json:"test_number"`
+	LiveVerdict   Verdict `json:"live_verdict"`
+	ShadowVerdict Verdict `json:"shadow_verdict"`
+}
+
+type TestDifferences []TestDifference
+
+func (d TestDifferences) Value() (driver.Value, error) {
+	if len(d) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+func (d *TestDifferences) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, d)
+	case string:
+		return json.Unmarshal([]byte(v), d)
+	}
+	return fmt.Errorf("unsupported test differences type %T", value)
+}
+
+// ShadowReport summarizes a shadow config's comparisons. Transitions counts
+// discrepancies by "LIVE->SHADOW" verdict pair.
+type ShadowReport struct {
+	Config        ShadowConfig   `json:"config"`
+	Judged        int            `json:"judged"`
+	Matching      int            `json:"matching"`
+	Discrepancies int            `json:"discrepancies"`
+	Transitions   map[string]int `json:"transitions"`
+	Recent        []ShadowResult `json:"recent_discrepancies"`
+}
+
+type EventLogEntry struct {
+	Seq        int64           `json:"seq" db:"seq"`
+	EventType  string          `json:"event_type" db:"event_type"`
+	RoutingKey string          `json:"routing_key" db:"routing_key"`
+	Data       json.RawMessage `json:"data" db:"data"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
+
+// JudgeLoad is a point-in-time view of how busy the judge pool is. QueueDepth
+// counts submissions waiting in the broker and in the local buffer.
+type JudgeLoad struct {
+	QueueDepth    int `json:"queue_depth"`
+	ActiveWorkers int `json:"active_workers"`
+	TotalWorkers  int `json:"total_workers"`
+}
+
+func (l JudgeLoad) Utilization() float64 {
+	if l.TotalWorkers == 0 {
+		return 0
+	}
+	return float64(l.ActiveWorkers) / float64(l.TotalWorkers)
+}
+
+type ScalingEvent struct {
+	ID            int64     `json:"id" db:"id"`
+	Trigger       string    `json:"trigger" db:"trigger"`
+	QueueSize     int       `json:"queue_size" db:"queue_size"`
+	ActiveWorkers int       `json:"active_workers" db:"active_workers"`
+	P95WaitMs     int       `json:"p95_wait_ms" db:"p95_wait_ms"`
+	PreviousCount int       `json:"previous_count" db:"previous_count"`
+	NewCount      int       `json:"new_count" db:"new_count"`
+	DryRun        bool      `json:"dry_run" db:"dry_run"`
+	Applied       bool      `json:"applied" db:"applied"`
+	Error         *string   `json:"error,omitempty" db:"error"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// QueueSnapshot is what one node held at a point in time: QueueDepth
+// messages still waiting in the broker, which cannot be listed, plus an
+// entry for every submission buffered or running on the node.
+type QueueSnapshot struct {
+	ID         int64                `json:"id" db:"id"`
+	Node       string               `json:"node" db:"node"`
+	QueueDepth int                  `json:"queue_depth" db:"queue_depth"`
+	Entries    QueueSnapshotEntries `json:"entries" db:"entries"`
+	TakenAt    time.Time            `json:"taken_at" db:"taken_at"`
+}
+
+const (
+	QueueEntryBuffered = "buffered"
+	QueueEntryRunning  = "running"
+)
+
+// QueueSnapshotEntry is one submission in a snapshot. AgeMs counts from when
+// the submission was published to the queue.
+type QueueSnapshotEntry struct {
+	SubmissionID int64  `json:"submission_id"`
+	UserID       int64  `json:"user_id"`
+	ContestID    *int64 `json:"contest_id,omitempty"`
+	Priority     int    `json:"priority"`
+	AgeMs        int64  `json:"age_ms"`
+	State        string `json:"state"`
+}
+
+type QueueSnapshotEntries []QueueSnapshotEntry
+
+func (e QueueSnapshotEntries) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
+func (e *QueueSnapshotEntries) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	}
+	return fmt.Errorf("unsupported queue snapshot entries type %T", value)
+}
+
+// Scopes an API key may carry. Keys act as their owner but only on the
+// routes a granted scope opens, and never read other users' submissions.
+const (
+	APIKeyScopeSubmit  = "submit"
+	APIKeyScopeReadOwn = "read_own"
+)
+
+// APIKey is a long-lived credential for external tools. Only a hash of the
+// secret is stored; the plaintext is returned once when the key is issued.
+type APIKey struct {
+	ID         int64        `json:"id" db:"id"`
+	UserID     int64        `json:"user_id" db:"user_id"`
+	Name       string       `json:"name" db:"name"`
+	KeyPrefix  string       `json:"key_prefix" db:"key_prefix"`
+	KeyHash    string       `json:"-" db:"key_hash"`
+	Scopes     APIKeyScopes `json:"scopes" db:"scopes"`
+	RateLimit  int          `json:"rate_limit" db:"rate_limit"`
+	UsageCount int64        `json:"usage_count" db:"usage_count"`
+	LastUsedAt *time.Time   `json:"last_used_at,omitempty" db:"last_used_at"`
+	ExpiresAt  *time.Time   `json:"expires_at,omitempty" db:"expires_at"`
+	RevokedAt  *time.Time   `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type APIKeyScopes []string
+
+func (s APIKeyScopes) Value() (driver.Value, error) {
+	if s == nil {
+		s = APIKeyScopes{}
+	}
+	return json.Marshal(s)
+}
+
+func (s *APIKeyScopes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*s = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	}
+	return fmt.Errorf("unsupported api key scopes type %T", value)
+}
+
+// Canary is a submission re-judged on every node to catch nodes whose
+// hardware or toolchain drifted from the rest.
+type Canary struct {
 	ID              int64     `json:"id" db:"id"`
-	Submission1ID   int64     `json:"submission1_id" db:"submission1_id"`
-	Submission2ID   int64     `json:"submission2_id" db:"submission2_id"`
-	SimilarityScore float64   `json:"similarity_score" db:"similarity_score"`
-	Algorithm       string    `json:"algorithm" db:"algorithm"`
-	IsReviewed      bool      `json:"is_reviewed" db:"is_reviewed"`
-	ReviewerID      *int64    `json:"reviewer_id,omitempty" db:"reviewer_id"`
-	Status          string    `json:"status" db:"status"`
+	SubmissionID    int64     `json:"submission_id" db:"submission_id"`
+	ExpectedVerdict Verdict   `json:"expected_verdict" db:"expected_verdict"`
+	CreatedBy       int64     `json:"created_by" db:"created_by"`
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 }
+
+type CanaryRun struct {
+	ID          int64     `json:"id" db:"id"`
+	CanaryID    int64     `json:"canary_id" db:"canary_id"`
+	Node        string    `json:"node" db:"node"`
+	Verdict     Verdict   `json:"verdict" db:"verdict"`
+	MaxTimeMs   int       `json:"max_time_ms" db:"max_time_ms"`
+	TestsPassed int       `json:"tests_passed" db:"tests_passed"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// NodeConsistency is one node's standing in a consistency report. Reasons
+// explain each canary on which the node deviated.
+type NodeConsistency struct {
+	Node      string      `json:"node"`
+	Deviating bool        `json:"deviating"`
+	Reasons   []string    `json:"reasons,omitempty"`
+	Runs      []CanaryRun `json:"runs"`
+}
+
+type ConsistencyReport struct {
+	Canaries      int               `json:"canaries"`
+	TimeTolerance float64           `json:"time_tolerance"`
+	Nodes         []NodeConsistency `json:"nodes"`
+	GeneratedAt   time.Time         `json:"generated_at"`
+}
+
+// LimitSimulation estimates how a problem's accepted submissions would fare
+// under candidate limits. Estimates come from the stored per-test results;
+// Reruns holds the sample actually re-judged under the candidate limits.
+type LimitSimulation struct {
+	ProblemID     int64        `json:"problem_id"`
+	TimeLimitMs   int          `json:"time_limit_ms"`
+	MemoryLimitKb int          `json:"memory_limit_kb"`
+	Submissions   int          `json:"submissions"`
+	Unchanged     int          `json:"unchanged"`
+	FlippedTLE    int          `json:"flipped_tle"`
+	FlippedMLE    int          `json:"flipped_mle"`
+	Skipped       int          `json:"skipped"`
+	Flips         []LimitFlip  `json:"flips"`
+	Reruns        []LimitRerun `json:"reruns"`
+}
+
+// LimitFlip is an accepted submission whose stored results exceed the
+// candidate limits, at the first test that would fail.
+type LimitFlip struct {
+	SubmissionID int64   `json:"submission_id"`
+	UserID       int64   `json:"user_id"`
+	Language     string  `json:"language"`
+	Verdict      Verdict `json:"verdict"`
+	TestNumber   int     `json:"test_number"`
+	TimeMs       int     `json:"time_ms"`
+	MemoryKb     int     `json:"memory_kb"`
+}
+
+type LimitRerun struct {
+	SubmissionID int64   `json:"submission_id"`
+	Verdict      Verdict `json:"verdict,omitempty"`
+	MaxTimeMs    int     `json:"max_time_ms"`
+	TestsPassed  int     `json:"tests_passed"`
+	Error        string  `json:"error,omitempty"`
+}
+
+const (
+	RejudgeJobPending   = "pending"
+	RejudgeJobRunning   = "running"
+	RejudgeJobCompleted = "completed"
+	RejudgeJobFailed    = "failed"
+)
+
+// RejudgeJob requeues a problem's submissions, optionally only those with
+// one of Verdicts or submitted within [SubmittedAfter, SubmittedBefore).
+// Submissions made after the job was created are left out.
+type RejudgeJob struct {
+	ID               int64       `json:"id" db:"id"`
+	ProblemID        int64       `json:"problem_id" db:"problem_id"`
+	Verdicts         VerdictList `json:"verdicts,omitempty" db:"verdicts"`
+	SubmittedAfter   *time.Time  `json:"submitted_after,omitempty" db:"submitted_after"`
+	SubmittedBefore  *time.Time  `json:"submitted_before,omitempty" db:"submitted_before"`
+	Status           string      `json:"status" db:"status"`
+	Total            int         `json:"total" db:"total"`
+	Processed        int         `json:"processed" db:"processed"`
+	LastSubmissionID int64       `json:"-" db:"last_submission_id"`
+	Node             *string     `json:"node,omitempty" db:"node"`
+	Error            *string     `json:"error,omitempty" db:"error"`
+	CreatedBy        int64       `json:"created_by" db:"created_by"`
+	CreatedAt        time.Time   `json:"created_at" db:"created_at"`
+	StartedAt        *time.Time  `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt       *time.Time  `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+type VerdictList []Verdict
+
+func (l VerdictList) Value() (driver.Value, error) {
+	if l == nil {
+		l = VerdictList{}
+	}
+	return json.Marshal(l)
+}
+
+func (l *VerdictList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, l)
+	case string:
+		return json.Unmarshal([]byte(v), l)
+	}
+	return fmt.Errorf("unsupported verdict list type %T", value)
+}
+
+const (
+	ScoreAggregationSum  = "sum"
+	ScoreAggregationMax  = "max"
+	ScoreAggregationLast = "last"
+)
+
+// ProblemScoring overrides the default aggregation for one problem.
+type ProblemScoring struct {
+	ProblemID   int64      `json:"problem_id" db:"problem_id"`
+	Aggregation string     `json:"aggregation" db:"aggregation"`
+	UpdatedBy   *int64     `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// UserProblemScore is a user's judged submissions to a problem combined
+// under the problem's aggregation.
+type UserProblemScore struct {
+	UserID      int64     `json:"user_id" db:"user_id"`
+	ProblemID   int64     `json:"problem_id" db:"problem_id"`
+	Score       Score     `json:"score" db:"score"`
+	Attempts    int       `json:"attempts" db:"attempts"`
+	Aggregation string    `json:"aggregation" db:"aggregation"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// UserStats summarizes a user's judged submissions for profile pages.
+// Verdicts counts submissions by final verdict; SolvedProblems counts
+// distinct problems with an accepted submission.
+type UserStats struct {
+	UserID         int64             `json:"user_id"`
+	SolvedProblems int64             `json:"solved_problems"`
+	Accepted       int64             `json:"accepted"`
+	Judged         int64             `json:"judged"`
+	Verdicts       map[Verdict]int64 `json:"verdicts"`
+}
+
+// DeadLetterEntry describes a judge request parked in the dead letter queue.
+type DeadLetterEntry struct {
+	SubmissionID  int64      `json:"submission_id"`
+	UserID        int64      `json:"user_id"`
+	ProblemID     int64      `json:"problem_id"`
+	Language      string     `json:"language"`
+	RetryCount    int        `json:"retry_count"`
+	OriginalQueue string     `json:"original_queue,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	Errors        []string   `json:"errors,omitempty"`
+	FirstFailed   *time.Time `json:"first_failed,omitempty"`
+	LastRetry     *time.Time `json:"last_retry,omitempty"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestJudgingPolicyContinues(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  JudgingPolicy
+		verdict Verdict
+		want    bool
+	}{
+		{"first failure stops on wrong answer", JudgingFirstFailure, VerdictWrongAns, false},
+		{"first failure stops on timeout", JudgingFirstFailure, VerdictTimeLim, false},
+		{"run all continues on wrong answer", JudgingRunAll, VerdictWrongAns, true},
+		{"run all continues on runtime error", JudgingRunAll, VerdictRuntime, true},
+		{"run all in subtask continues on timeout", JudgingRunAllInSubtask, VerdictTimeLim, true},
+		{"default continues on wrong answer", "", VerdictWrongAns, true},
+		{"default stops on timeout", "", VerdictTimeLim, false},
+		{"default stops on memory limit", "", VerdictMemLim, false},
+		{"unknown policy falls back to default", "sometimes", VerdictRuntime, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Continues(tt.verdict); got != tt.want {
+				t.Errorf("%q.Continues(%s) = %v, want %v", tt.policy, tt.verdict, got, tt.want)
+			}
+		})
+	}
+}
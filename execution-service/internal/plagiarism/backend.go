@@ -0,0 +1,84 @@
+package plagiarism
+
+import (
+	"context"
+	"fmt"
+
+	"execution_service/internal/config"
+)
+
+const (
+	BackendInternal = "internal"
+	BackendMOSS     = "moss"
+	BackendJPlag    = "jplag"
+)
+
+// Candidate is one submission exported to an external backend.
+type Candidate struct {
+	SubmissionID int64
+	Language     string
+	Code         []byte
+}
+
+// Backend compares a submission against a candidate set in a single batch
+// and returns its similarity, in [0, 1], to each matched submission ID.
+type Backend interface {
+	Name() string
+	Compare(ctx context.Context, target Candidate, others []Candidate) (map[int64]float64, error)
+}
+
+type languageNames struct {
+	extension string
+	moss      string
+	jplag     string
+}
+
+var backendLanguages = map[string]languageNames{
+	"cpp":    {".cpp", "cc", "cpp"},
+	"c":      {".c", "c", "cpp"},
+	"java":   {".java", "java", "java"},
+	"python": {".py", "python", "python3"},
+	"go":     {".go", "ascii", "go"},
+	"rust":   {".rs", "ascii", "rust"},
+	"kotlin": {".kt", "ascii", "kotlin"},
+	"csharp": {".cs", "csharp", "csharp"},
+	"ruby":   {".rb", "ascii", "text"},
+}
+
+func languageFor(language string) languageNames {
+	if names, ok := backendLanguages[language]; ok {
+		return names
+	}
+	return languageNames{".txt", "ascii", "text"}
+}
+
+func candidateFileName(c Candidate) string {
+	return fmt.Sprintf("%d%s", c.SubmissionID, languageFor(c.Language).extension)
+}
+
+func newBackends(cfg *config.PlagiarismConfig) map[string]Backend {
+	backends := map[string]Backend{
+		BackendJPlag: NewJPlagBackend(cfg.JPlagCommand, cfg.ExternalTimeout),
+	}
+	if cfg.MOSSUserID != "" {
+		backends[BackendMOSS] = NewMOSSBackend(cfg.MOSSServer, cfg.MOSSUserID, cfg.ExternalTimeout)
+	}
+	return backends
+}
+
+// mergeScores blends internal and external similarities per submission.
+// Pairs seen by only one side keep that side's score.
+func mergeScores(internal, external map[int64]float64, externalWeight float64) map[int64]float64 {
+	merged := make(map[int64]float64, len(internal))
+	for id, score := range internal {
+		merged[id] = score
+	}
+	for id, score := range external {
+		if internalScore, ok := merged[id]; ok {
+			merged[id] = (1-externalWeight)*internalScore + externalWeight*score
+		} else {
+			merged[id] = score
+		}
+	}
+	return merged
+}
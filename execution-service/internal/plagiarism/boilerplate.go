@@ -0,0 +1,98 @@
+package plagiarism
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"execution_service/internal/models"
+)
+
+// minBoilerplateRun is the fewest consecutive snippet lines stripped at
+// once, so lone lines such as a closing brace stay in the submission.
+const minBoilerplateRun = 3
+
+func (pd *PlagiarismDetector) ListBoilerplate(ctx context.Context, problemID int64) ([]models.PlagiarismBoilerplate, error) {
+	return pd.db.GetPlagiarismBoilerplate(ctx, problemID, "")
+}
+
+// AddBoilerplate registers a snippet and drops the problem's stored
+// features, which were extracted without it.
+func (pd *PlagiarismDetector) AddBoilerplate(ctx context.Context, snippet *models.PlagiarismBoilerplate) error {
+	if err := pd.db.CreatePlagiarismBoilerplate(ctx, snippet); err != nil {
+		return err
+	}
+	return pd.db.DeleteProblemCodeFeatures(ctx, snippet.ProblemID)
+}
+
+// RemoveBoilerplate reports false when the problem has no such snippet.
+func (pd *PlagiarismDetector) RemoveBoilerplate(ctx context.Context, problemID, snippetID int64) (bool, error) {
+	removed, err := pd.db.DeletePlagiarismBoilerplate(ctx, problemID, snippetID)
+	if err != nil || !removed {
+		return removed, err
+	}
+	return true, pd.db.DeleteProblemCodeFeatures(ctx, problemID)
+}
+
+// stripBoilerplate blanks the parts of code taken from the problem's
+// boilerplate, keeping line numbers. Code is left as is when the
+// boilerplate cannot be loaded.
+func (pd *PlagiarismDetector) stripBoilerplate(ctx context.Context, problemID int64, language, code string) string {
+	snippets, err := pd.db.GetPlagiarismBoilerplate(ctx, problemID, language)
+	if err != nil {
+		log.Printf("Failed to load plagiarism boilerplate for problem %d: %v", problemID, err)
+		return code
+	}
+	if len(snippets) == 0 {
+		return code
+	}
+
+	lines := strings.Split(code, "\n")
+	for _, snippet := range snippets {
+		stripSnippet(lines, snippet.Code)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stripSnippet blanks every run of at least minBoilerplateRun non-blank
+// lines, or the whole snippet when it is shorter, that appears in the same
+// order in the snippet. Lines are compared without surrounding whitespace
+// and blank lines are skipped on both sides.
+func stripSnippet(lines []string, snippet string) {
+	var template []string
+	for _, line := range strings.Split(snippet, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			template = append(template, trimmed)
+		}
+	}
+	if len(template) == 0 {
+		return
+	}
+	minRun := min(minBoilerplateRun, len(template))
+
+	var code []int
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			code = append(code, i)
+		}
+	}
+
+	for i := 0; i < len(code); {
+		longest := 0
+		for j := range template {
+			run := 0
+			for i+run < len(code) && j+run < len(template) && strings.TrimSpace(lines[code[i+run]]) == template[j+run] {
+				run++
+			}
+			longest = max(longest, run)
+		}
+		if longest < minRun {
+			i++
+			continue
+		}
+		for _, index := range code[i : i+longest] {
+			lines[index] = ""
+		}
+		i += longest
+	}
+}
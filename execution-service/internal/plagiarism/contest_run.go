@@ -0,0 +1,181 @@
+package plagiarism
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"execution_service/internal/models"
+)
+
+const (
+	contestRunLease        = time.Minute
+	contestRunPollInterval = 5 * time.Second
+	// contestRunBatch is how many comparisons run between progress updates
+	contestRunBatch = 500
+	// maxContestRunPairs caps the pairs kept in a run's report
+	maxContestRunPairs = 500
+)
+
+// CreateContestRun queues a run over the contest's accepted submissions.
+// Runs compare stored features only; external backends are not consulted.
+func (pd *PlagiarismDetector) CreateContestRun(ctx context.Context, run *models.PlagiarismRun) error {
+	if run.Threshold == 0 {
+		run.Threshold = pd.config.SimilarityThreshold
+	}
+	return pd.db.CreatePlagiarismRun(ctx, run)
+}
+
+// GetContestRun returns nil when there is no such run.
+func (pd *PlagiarismDetector) GetContestRun(ctx context.Context, runID int64) (*models.PlagiarismRun, error) {
+	return pd.db.GetPlagiarismRun(ctx, runID)
+}
+
+// StartContestRuns claims and runs contest runs one at a time until ctx is
+// cancelled. A run whose node dies is restarted by another node once its
+// lease expires.
+func (pd *PlagiarismDetector) StartContestRuns(ctx context.Context, node string) {
+	ticker := time.NewTicker(contestRunPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run, err := pd.db.ClaimPlagiarismRun(ctx, node, contestRunLease)
+			if err != nil {
+				log.Printf("Failed to claim plagiarism run: %v", err)
+				continue
+			}
+			if run != nil {
+				pd.runContest(ctx, run, node)
+			}
+		}
+	}
+}
+
+func (pd *PlagiarismDetector) runContest(ctx context.Context, run *models.PlagiarismRun, node string) {
+	log.Printf("Running plagiarism run %d for contest %d", run.ID, run.ContestID)
+
+	submissions, err := pd.db.GetContestAcceptedSubmissions(ctx, run.ContestID)
+	if err != nil {
+		pd.finishContestRun(ctx, run, models.PlagiarismRunFailed, err)
+		return
+	}
+
+	ids := make([]int64, len(submissions))
+	for i, submission := range submissions {
+		ids[i] = submission.ID
+	}
+	stored := pd.storedFeatures(ctx, ids)
+
+	type entry struct {
+		submission *models.Submission
+		features   *CodeFeatures
+	}
+	byProblem := make(map[int64][]entry)
+	var problems []int64
+	extracted := 0
+	for i := range submissions {
+		submission := &submissions[i]
+		features := stored[submission.ID]
+		if features == nil {
+			code, err := pd.storage.DownloadCode(ctx, submission.CodeURL)
+			if err != nil {
+				log.Printf("Plagiarism run %d skipping submission %d: %v", run.ID, submission.ID, err)
+				continue
+			}
+			features, err = pd.extractAndStore(ctx, submission.ID, submission.ProblemID, string(code), submission.Language)
+			if err != nil {
+				log.Printf("Plagiarism run %d skipping submission %d: %v", run.ID, submission.ID, err)
+				continue
+			}
+			// Downloads are slow enough that the lease needs renewing
+			if extracted++; extracted%50 == 0 && !pd.renewContestRun(ctx, run, node) {
+				return
+			}
+		}
+		if _, ok := byProblem[submission.ProblemID]; !ok {
+			problems = append(problems, submission.ProblemID)
+		}
+		byProblem[submission.ProblemID] = append(byProblem[submission.ProblemID], entry{submission, features})
+	}
+
+	run.Total = 0
+	for _, entries := range byProblem {
+		run.Total += len(entries) * (len(entries) - 1) / 2
+	}
+	run.Processed = 0
+	run.Pairs = models.SuspiciousPairs{}
+	if !pd.renewContestRun(ctx, run, node) {
+		return
+	}
+
+	for _, problemID := range problems {
+		entries := byProblem[problemID]
+		for i := 0; i < len(entries); i++ {
+			for j := i + 1; j < len(entries); j++ {
+				if ctx.Err() != nil {
+					return
+				}
+
+				first, second := entries[i], entries[j]
+				pair := models.SuspiciousPair{
+					ProblemID:     problemID,
+					Submission1ID: first.submission.ID,
+					User1ID:       first.submission.UserID,
+					Submission2ID: second.submission.ID,
+					User2ID:       second.submission.UserID,
+				}
+				pair.Score, pair.Algorithm = pd.bestSimilarity(first.features, second.features)
+				if pair.Score >= run.Threshold {
+					run.Pairs = append(run.Pairs, pair)
+				}
+
+				run.Processed++
+				if run.Processed%contestRunBatch == 0 && !pd.renewContestRun(ctx, run, node) {
+					return
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(run.Pairs, func(a, b int) bool { return run.Pairs[a].Score > run.Pairs[b].Score })
+	if len(run.Pairs) > maxContestRunPairs {
+		run.Pairs = run.Pairs[:maxContestRunPairs]
+	}
+	pd.finishContestRun(ctx, run, models.PlagiarismRunCompleted, nil)
+}
+
+// renewContestRun records progress, reporting false when the run should
+// stop because the update failed or another node took it over.
+func (pd *PlagiarismDetector) renewContestRun(ctx context.Context, run *models.PlagiarismRun, node string) bool {
+	leased, err := pd.db.UpdatePlagiarismRunProgress(ctx, run, node, contestRunLease)
+	if err != nil {
+		// Leave the run to be reclaimed once its lease expires
+		log.Printf("Plagiarism run %d stalled: %v", run.ID, err)
+		return false
+	}
+	if !leased {
+		log.Printf("Plagiarism run %d was claimed by another node", run.ID)
+		return false
+	}
+	return true
+}
+
+func (pd *PlagiarismDetector) finishContestRun(ctx context.Context, run *models.PlagiarismRun, status string, runErr error) {
+	var message *string
+	if runErr != nil {
+		text := runErr.Error()
+		message = &text
+		log.Printf("Plagiarism run %d failed: %v", run.ID, runErr)
+	} else {
+		log.Printf("Plagiarism run %d completed: %d comparisons, %d suspicious pairs", run.ID, run.Processed, len(run.Pairs))
+	}
+
+	if err := pd.db.FinishPlagiarismRun(ctx, run, status, message); err != nil {
+		log.Printf("Failed to finish plagiarism run %d: %v", run.ID, err)
+	}
+}
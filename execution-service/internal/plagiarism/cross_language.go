@@ -0,0 +1,107 @@
+package plagiarism
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var crossTokenPattern = regexp.MustCompile(`"(?:[^"\\\n]|\\.)*"|'(?:[^'\\\n]|\\.)*'|[A-Za-z_]\w*|\d+(?:\.\d+)?|&&|\|\||<<=?|>>=?|\+\+|--|[-+*/%<>=!&|^]=?|[{}]`)
+
+// crossKeywords maps the control keywords of the supported languages to one
+// shared vocabulary. Words mapped to "" are types, modifiers and imports,
+// which differ between languages without changing the solution.
+var crossKeywords = map[string]string{
+	"for": "LOOP", "while": "LOOP", "do": "LOOP", "foreach": "LOOP", "loop": "LOOP",
+	"if": "IF", "elif": "ELIF", "else": "ELSE",
+	"switch": "SWITCH", "match": "SWITCH", "case": "CASE", "when": "CASE", "default": "CASE",
+	"return": "RETURN", "break": "BREAK", "continue": "CONTINUE",
+	"def": "FUNC", "fn": "FUNC", "func": "FUNC", "fun": "FUNC",
+	"class": "CLASS", "struct": "CLASS", "interface": "CLASS",
+	"try": "TRY", "catch": "CATCH", "except": "CATCH", "finally": "FINALLY",
+	"throw": "THROW", "raise": "THROW", "new": "NEW",
+	"and": "&&", "or": "||", "not": "!",
+	"true": "BOOL", "false": "BOOL",
+	"null": "NULL", "nullptr": "NULL", "none": "NULL", "nil": "NULL",
+	"int": "", "long": "", "short": "", "float": "", "double": "", "char": "",
+	"bool": "", "boolean": "", "string": "", "void": "", "auto": "", "var": "",
+	"let": "", "const": "", "final": "", "static": "", "public": "", "private": "",
+	"protected": "", "unsigned": "", "signed": "", "mut": "", "import": "",
+	"include": "", "using": "", "namespace": "", "package": "", "std": "",
+	"self": "", "this": "", "in": "", "pass": "",
+}
+
+// crossTokens turns code into a token stream shared by all languages:
+// identifiers, literals and the shape of the control flow, with blocks
+// marked by BEGIN and END whether braces or indentation open them.
+// Qualified names and calls collapse into a single identifier.
+func (pd *PlagiarismDetector) crossTokens(code, language string) ([]string, []int) {
+	lines := strings.Split(pd.removeComments(code), "\n")
+
+	var tokens []string
+	var positions []int
+	emit := func(token string, line int) {
+		if token == "ID" && len(tokens) > 0 && tokens[len(tokens)-1] == "ID" {
+			return
+		}
+		tokens = append(tokens, token)
+		positions = append(positions, line)
+	}
+
+	var indents []int
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if language == "python" {
+			indent := len(line) - len(strings.TrimLeft(line, " \t"))
+			for len(indents) > 1 && indents[len(indents)-1] > indent {
+				indents = indents[:len(indents)-1]
+				emit("END", i)
+			}
+			if len(indents) == 0 || indent > indents[len(indents)-1] {
+				if len(indents) > 0 {
+					emit("BEGIN", i)
+				}
+				indents = append(indents, indent)
+			}
+		}
+
+		for _, token := range crossTokenPattern.FindAllString(trimmed, -1) {
+			switch first := rune(token[0]); {
+			case first == '"' || first == '\'':
+				emit("STR", i)
+			case unicode.IsDigit(first):
+				emit("NUM", i)
+			case first == '_' || unicode.IsLetter(first):
+				canonical, keyword := crossKeywords[strings.ToLower(token)]
+				switch {
+				case !keyword:
+					emit("ID", i)
+				case canonical != "":
+					emit(canonical, i)
+				}
+			case token == "{":
+				if language != "python" {
+					emit("BEGIN", i)
+				}
+			case token == "}":
+				if language != "python" {
+					emit("END", i)
+				}
+			default:
+				emit(token, i)
+			}
+		}
+	}
+	for ; len(indents) > 1; indents = indents[:len(indents)-1] {
+		emit("END", len(lines)-1)
+	}
+	return tokens, positions
+}
+
+// crossFingerprints winnows the language-agnostic token stream of code.
+func (pd *PlagiarismDetector) crossFingerprints(code, language string) []fingerprint {
+	return pd.winnow(pd.crossTokens(code, language))
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"execution_service/internal/config"
@@ -15,12 +16,22 @@ import (
 	"execution_service/internal/storage"
 )
 
+// EventPublisher publishes a domain event; it matches RabbitMQClient.PublishEvent.
+type EventPublisher func(ctx context.Context, eventType string, data any) error
+
 type PlagiarismDetector struct {
 	db         *database.DB
 	storage    *storage.MinIOClient
 	config     *config.PlagiarismConfig
 	workerPool chan *PlagiarismTask
 	stopChan   chan struct{}
+	backends   map[string]Backend
+	publisher  EventPublisher
+	loadSource LoadSource
+
+	throttleMu      sync.Mutex
+	throttle        ThrottleStatus
+	throttleChanged chan struct{}
 }
 
 type PlagiarismConfig struct {
@@ -61,6 +72,11 @@ type CodeFeatures struct {
 	FunctionNames  []string
 	StringLiterals []string
 	Comments       []string
+	Fingerprints   []fingerprint
+	Subtrees       map[uint64]subtreeStats
+	// Language and CrossFingerprints serve cross-language comparisons
+	Language          string
+	CrossFingerprints []fingerprint
 }
 
 func NewPlagiarismDetector(db *database.DB, storage *storage.MinIOClient, config *config.PlagiarismConfig) *PlagiarismDetector {
@@ -70,6 +86,12 @@ func NewPlagiarismDetector(db *database.DB, storage *storage.MinIOClient, config
 		config:     config,
 		workerPool: make(chan *PlagiarismTask, 100),
 		stopChan:   make(chan struct{}),
+		backends:   newBackends(config),
+		throttle: ThrottleStatus{
+			State: ThrottleRunning,
+			Since: time.Now(),
+		},
+		throttleChanged: make(chan struct{}),
 	}
 }
 
@@ -89,9 +111,17 @@ func (pd *PlagiarismDetector) Start(ctx context.Context) error {
 	// Start scheduler
 	go pd.scheduler(ctx)
 
+	if pd.loadSource != nil {
+		go pd.monitorLoad(ctx)
+	}
+
 	return nil
 }
 
+func (pd *PlagiarismDetector) SetEventPublisher(publisher EventPublisher) {
+	pd.publisher = publisher
+}
+
 func (pd *PlagiarismDetector) Stop() {
 	close(pd.stopChan)
 }
@@ -135,6 +165,10 @@ func (pd *PlagiarismDetector) scheduler(ctx context.Context) {
 }
 
 func (pd *PlagiarismDetector) processPendingSubmissions(ctx context.Context) {
+	if pd.paused() {
+		return
+	}
+
 	// Get recent submissions that haven't been checked for plagiarism
 	submissions, err := pd.db.GetUncheckedSubmissions(ctx, pd.config.MaxSubmissionsPerCheck)
 	if err != nil {
@@ -158,6 +192,9 @@ func (pd *PlagiarismDetector) worker(ctx context.Context, workerID int) {
 		case <-pd.stopChan:
 			return
 		case task := <-pd.workerPool:
+			if !pd.waitForJudgeCapacity(ctx) {
+				return
+			}
 			pd.processSubmission(ctx, task, workerID)
 		}
 	}
@@ -180,13 +217,6 @@ func (pd *PlagiarismDetector) processSubmission(ctx context.Context, task *Plagi
 		return
 	}
 
-	// Extract features from current submission
-	currentFeatures, err := pd.extractFeatures(string(code))
-	if err != nil {
-		log.Printf("Worker %d failed to extract features from submission %d: %v", workerID, task.SubmissionID, err)
-		return
-	}
-
 	// Get previous submissions for the same problem
 	previousSubmissions, err := pd.db.GetPreviousSubmissions(ctx, task.ProblemID, task.SubmissionID)
 	if err != nil {
@@ -194,10 +224,32 @@ func (pd *PlagiarismDetector) processSubmission(ctx context.Context, task *Plagi
 		return
 	}
 
+	ids := []int64{task.SubmissionID}
+	for _, prevSub := range previousSubmissions {
+		ids = append(ids, prevSub.ID)
+	}
+	stored := pd.storedFeatures(ctx, ids)
+
+	// Extract features from current submission
+	currentFeatures := stored[task.SubmissionID]
+	if currentFeatures == nil {
+		currentFeatures, err = pd.extractAndStore(ctx, task.SubmissionID, task.ProblemID, string(code), task.Language)
+		if err != nil {
+			log.Printf("Worker %d failed to extract features from submission %d: %v", workerID, task.SubmissionID, err)
+			return
+		}
+	}
+
+	var contestID *int64
+	if submission, err := pd.db.GetSubmission(ctx, task.SubmissionID); err == nil {
+		contestID = submission.ContestID
+	}
+	backend := pd.backendFor(contestID)
+
 	// Compare with each previous submission
-	var maxSimilarity float64
-	var mostSimilar int64
-	var bestAlgorithm string
+	internalScores := make(map[int64]float64)
+	internalAlgorithms := make(map[int64]string)
+	var candidates []Candidate
 
 	for _, prevSub := range previousSubmissions {
 		// Skip submissions from the same user (self-comparison)
@@ -205,27 +257,61 @@ func (pd *PlagiarismDetector) processSubmission(ctx context.Context, task *Plagi
 			continue
 		}
 
-		// Download previous submission code
-		prevCode, err := pd.storage.DownloadCode(ctx, prevSub.CodeURL)
-		if err != nil {
-			continue
+		// Code is only downloaded when its features are not stored yet or
+		// an external backend needs it
+		prevFeatures := stored[prevSub.ID]
+		var prevCode []byte
+		if prevFeatures == nil || backend != nil {
+			prevCode, err = pd.storage.DownloadCode(ctx, prevSub.CodeURL)
+			if err != nil {
+				continue
+			}
+		}
+		if backend != nil {
+			candidates = append(candidates, Candidate{SubmissionID: prevSub.ID, Language: prevSub.Language, Code: prevCode})
+		}
+		if prevFeatures == nil {
+			prevFeatures, err = pd.extractAndStore(ctx, prevSub.ID, prevSub.ProblemID, string(prevCode), prevSub.Language)
+			if err != nil {
+				continue
+			}
+		}
+
+		// Calculate similarity using different algorithms
+		if similarity, algorithm := pd.bestSimilarity(currentFeatures, prevFeatures); similarity > 0 {
+			internalScores[prevSub.ID] = similarity
+			internalAlgorithms[prevSub.ID] = algorithm
 		}
+	}
 
-		// Extract features from previous submission
-		prevFeatures, err := pd.extractFeatures(string(prevCode))
+	scores := internalScores
+	var externalScores map[int64]float64
+	if backend != nil && len(candidates) > 0 {
+		target := Candidate{SubmissionID: task.SubmissionID, Language: task.Language, Code: code}
+		externalScores, err = backend.Compare(ctx, target, candidates)
 		if err != nil {
-			continue
+			log.Printf("Worker %d %s backend failed for submission %d, using internal scores: %v",
+				workerID, backend.Name(), task.SubmissionID, err)
+		} else {
+			scores = mergeScores(internalScores, externalScores, pd.config.ExternalWeight)
 		}
+	}
 
-		// Calculate similarity using different algorithms
-		for _, algorithm := range pd.config.Algorithms {
-			similarity := pd.calculateSimilarity(currentFeatures, prevFeatures, algorithm)
+	var maxSimilarity float64
+	var mostSimilar int64
+	for id, score := range scores {
+		if score > maxSimilarity {
+			maxSimilarity = score
+			mostSimilar = id
+		}
+	}
 
-			if similarity > maxSimilarity {
-				maxSimilarity = similarity
-				mostSimilar = prevSub.ID
-				bestAlgorithm = algorithm
-			}
+	bestAlgorithm := internalAlgorithms[mostSimilar]
+	if _, external := externalScores[mostSimilar]; external {
+		if bestAlgorithm == "" {
+			bestAlgorithm = backend.Name()
+		} else {
+			bestAlgorithm += "+" + backend.Name()
 		}
 	}
 
@@ -237,7 +323,7 @@ func (pd *PlagiarismDetector) processSubmission(ctx context.Context, task *Plagi
 			SimilarityScore: maxSimilarity,
 			Algorithm:       bestAlgorithm,
 			IsReviewed:      false,
-			Status:          "pending",
+			Status:          models.PlagiarismPending,
 		}
 
 		if err := pd.db.CreatePlagiarismReport(ctx, report); err != nil {
@@ -245,6 +331,7 @@ func (pd *PlagiarismDetector) processSubmission(ctx context.Context, task *Plagi
 		} else {
 			log.Printf("Worker %d detected plagiarism: submission %d similar to %d (score: %.2f)",
 				workerID, task.SubmissionID, mostSimilar, maxSimilarity)
+			pd.publishDetection(ctx, report, task.ProblemID, contestID)
 		}
 	}
 
@@ -252,7 +339,48 @@ func (pd *PlagiarismDetector) processSubmission(ctx context.Context, task *Plagi
 	pd.markSubmissionChecked(ctx, task.SubmissionID)
 }
 
-func (pd *PlagiarismDetector) extractFeatures(code string) (*CodeFeatures, error) {
+// backendFor returns the external backend configured for the contest, or the
+// default backend, or nil when only internal checks apply.
+func (pd *PlagiarismDetector) backendFor(contestID *int64) Backend {
+	name := pd.config.Backend
+	if contestID != nil {
+		if contestBackend, ok := pd.config.ContestBackends[*contestID]; ok {
+			name = contestBackend
+		}
+	}
+
+	if name == "" || name == BackendInternal {
+		return nil
+	}
+
+	backend, ok := pd.backends[name]
+	if !ok {
+		log.Printf("Plagiarism backend %q is not available, using internal checks", name)
+		return nil
+	}
+	return backend
+}
+
+func (pd *PlagiarismDetector) publishDetection(ctx context.Context, report *models.PlagiarismReport, problemID int64, contestID *int64) {
+	if pd.publisher == nil {
+		return
+	}
+
+	event := &models.PlagiarismDetectedEvent{
+		ReportID:            report.ID,
+		SubmissionID:        report.Submission1ID,
+		SimilarSubmissionID: report.Submission2ID,
+		ProblemID:           problemID,
+		ContestID:           contestID,
+		SimilarityScore:     report.SimilarityScore,
+		Algorithm:           report.Algorithm,
+	}
+	if err := pd.publisher(ctx, "PlagiarismDetected", event); err != nil {
+		log.Printf("Failed to publish plagiarism event for submission %d: %v", report.Submission1ID, err)
+	}
+}
+
+func (pd *PlagiarismDetector) extractFeatures(code, language string) (*CodeFeatures, error) {
 	features := &CodeFeatures{}
 
 	// Calculate overall hash
@@ -281,6 +409,11 @@ func (pd *PlagiarismDetector) extractFeatures(code string) (*CodeFeatures, error
 	// Extract comments
 	features.Comments = pd.extractComments(code)
 
+	features.Fingerprints = pd.fingerprints(code)
+	features.Subtrees = subtrees(syntaxTree(code, language))
+	features.Language = language
+	features.CrossFingerprints = pd.crossFingerprints(code, language)
+
 	return features, nil
 }
 
@@ -414,11 +547,37 @@ func (pd *PlagiarismDetector) calculateSimilarity(features1, features2 *CodeFeat
 		return pd.identifierSimilarity(features1.FunctionNames, features2.FunctionNames)
 	case "strings":
 		return pd.identifierSimilarity(features1.StringLiterals, features2.StringLiterals)
+	case "winnowing":
+		return pd.winnowingSimilarity(features1.Fingerprints, features2.Fingerprints)
+	case "ast":
+		return pd.astSimilarity(features1.Subtrees, features2.Subtrees)
+	case "crosslang":
+		return pd.winnowingSimilarity(features1.CrossFingerprints, features2.CrossFingerprints)
 	default:
 		return 0.0
 	}
 }
 
+// bestSimilarity scores a pair with every configured algorithm and returns
+// the highest score. With CrossLanguage on, pairs in different languages are
+// also scored on their language-agnostic token streams.
+func (pd *PlagiarismDetector) bestSimilarity(features1, features2 *CodeFeatures) (float64, string) {
+	algorithms := pd.config.Algorithms
+	if pd.config.CrossLanguage && features1.Language != features2.Language {
+		algorithms = append(algorithms[:len(algorithms):len(algorithms)], "crosslang")
+	}
+
+	var best float64
+	var bestAlgorithm string
+	for _, algorithm := range algorithms {
+		if similarity := pd.calculateSimilarity(features1, features2, algorithm); similarity > best {
+			best = similarity
+			bestAlgorithm = algorithm
+		}
+	}
+	return best, bestAlgorithm
+}
+
 func (pd *PlagiarismDetector) hashSimilarity(hash1, hash2 string) float64 {
 	if hash1 == hash2 {
 		return 1.0
@@ -565,9 +724,12 @@ func (pd *PlagiarismDetector) levenshteinDistance(s1, s2 string) int {
 }
 
 func (pd *PlagiarismDetector) removeComments(code string) string {
-	// Remove multi-line comments
+	// Remove multi-line comments, keeping their line breaks so later lines
+	// keep their numbers
 	re := regexp.MustCompile(`/\*[\s\S]*?\*/`)
-	code = re.ReplaceAllString(code, "")
+	code = re.ReplaceAllStringFunc(code, func(comment string) string {
+		return strings.Repeat("\n", strings.Count(comment, "\n"))
+	})
 
 	// Remove single line comments
 	re = regexp.MustCompile(`//.*`)
@@ -608,10 +770,12 @@ func (pd *PlagiarismDetector) isKeyword(token string) bool {
 	return keywords[token]
 }
 
+// markSubmissionChecked keeps the scheduler from queueing the submission
+// again. A failure only means it is checked once more.
 func (pd *PlagiarismDetector) markSubmissionChecked(ctx context.Context, submissionID int64) {
-	// Update submission to mark it as checked for plagiarism
-	// This would typically update a timestamp in the submissions table
-	log.Printf("Marked submission %d as plagiarism-checked", submissionID)
+	if err := pd.db.MarkPlagiarismChecked(ctx, submissionID); err != nil {
+		log.Printf("Failed to mark submission %d as plagiarism-checked: %v", submissionID, err)
+	}
 }
 
 func (pd *PlagiarismDetector) GetDefaultConfig() *config.PlagiarismConfig {
@@ -623,5 +787,7 @@ func (pd *PlagiarismDetector) GetDefaultConfig() *config.PlagiarismConfig {
 		CheckInterval:          5 * time.Minute,
 		MaxSubmissionsPerCheck: 50,
 		Algorithms:             []string{"tokens", "lines", "structure", "variables", "functions"},
+		WinnowingK:             5,
+		WinnowingWindow:        4,
 	}
 }
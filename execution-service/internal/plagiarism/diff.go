@@ -0,0 +1,304 @@
+package plagiarism
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"execution_service/internal/models"
+)
+
+// maxDiffLines caps each side of a report diff, bounding the line table the
+// diff is computed over.
+const maxDiffLines = 2000
+
+// Diff line operations, read from the first submission to the second.
+const (
+	DiffEqual   = "equal"
+	DiffRemoved = "removed"
+	DiffAdded   = "added"
+)
+
+// ReportDiff shows the two submissions of a report side by side. Lines are
+// a line-level diff compared without surrounding whitespace; Matched marks
+// the lines the algorithm that flagged the pair found in both submissions.
+type ReportDiff struct {
+	ReportID  int64           `json:"report_id"`
+	Algorithm string          `json:"algorithm"`
+	Score     float64         `json:"similarity_score"`
+	First     DiffSubmission  `json:"first"`
+	Second    DiffSubmission  `json:"second"`
+	Lines     []DiffLine      `json:"lines"`
+	Regions   []MatchedRegion `json:"regions"`
+	Stats     map[string]int  `json:"stats"`
+	Truncated bool            `json:"truncated"`
+}
+
+type DiffSubmission struct {
+	SubmissionID int64  `json:"submission_id"`
+	UserID       int64  `json:"user_id"`
+	Language     string `json:"language"`
+	LineCount    int    `json:"line_count"`
+	MatchedLines []int  `json:"matched_lines"`
+}
+
+// DiffLine is one line of the diff. Line numbers start at 1 and are zero on
+// the side a line is missing from. An equal line whose indentation differs
+// carries the second submission's text in OtherText.
+type DiffLine struct {
+	Op           string `json:"op"`
+	FirstLine    int    `json:"first_line,omitempty"`
+	SecondLine   int    `json:"second_line,omitempty"`
+	Text         string `json:"text"`
+	OtherText    string `json:"other_text,omitempty"`
+	Matched      bool   `json:"matched"`
+	OtherMatched bool   `json:"other_matched,omitempty"`
+}
+
+// DiffReport downloads both submissions of a report and diffs them.
+func (pd *PlagiarismDetector) DiffReport(ctx context.Context, report *models.PlagiarismReport) (*ReportDiff, error) {
+	first, err := pd.db.GetSubmission(ctx, report.Submission1ID)
+	if err != nil {
+		return nil, err
+	}
+	second, err := pd.db.GetSubmission(ctx, report.Submission2ID)
+	if err != nil {
+		return nil, err
+	}
+	if first.EntryFile != nil || second.EntryFile != nil {
+		return nil, fmt.Errorf("%w: project submissions", ErrUnsupportedSimilarity)
+	}
+
+	firstCode, err := pd.storage.DownloadCode(ctx, first.CodeURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download code: %w", err)
+	}
+	secondCode, err := pd.storage.DownloadCode(ctx, second.CodeURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download code: %w", err)
+	}
+
+	a := strings.Split(string(firstCode), "\n")
+	b := strings.Split(string(secondCode), "\n")
+	truncated := len(a) > maxDiffLines || len(b) > maxDiffLines
+	a, b = a[:min(len(a), maxDiffLines)], b[:min(len(b), maxDiffLines)]
+
+	matchedA, matchedB := pd.matchedLines(report.Algorithm, a, b, first.Language, second.Language)
+	diff := &ReportDiff{
+		ReportID:  report.ID,
+		Algorithm: report.Algorithm,
+		Score:     report.SimilarityScore,
+		First:     diffSubmission(first, a, matchedA),
+		Second:    diffSubmission(second, b, matchedB),
+		Lines:     diffLines(a, b, matchedA, matchedB),
+		Regions:   matchedRegions(a, b),
+		Stats:     map[string]int{DiffEqual: 0, DiffRemoved: 0, DiffAdded: 0},
+		Truncated: truncated,
+	}
+	if diff.Regions == nil {
+		diff.Regions = []MatchedRegion{}
+	}
+	for _, line := range diff.Lines {
+		diff.Stats[line.Op]++
+	}
+	return diff, nil
+}
+
+func diffSubmission(submission *models.Submission, lines []string, matched []bool) DiffSubmission {
+	side := DiffSubmission{
+		SubmissionID: submission.ID,
+		UserID:       submission.UserID,
+		Language:     submission.Language,
+		LineCount:    len(lines),
+		MatchedLines: []int{},
+	}
+	for i, m := range matched {
+		if m {
+			side.MatchedLines = append(side.MatchedLines, i+1)
+		}
+	}
+	return side
+}
+
+// matchedLines marks, on each side, the lines behind the score of the
+// report's algorithm. Scores blended with an external backend, and
+// algorithms without line-level evidence, fall back to shared line runs.
+func (pd *PlagiarismDetector) matchedLines(algorithm string, a, b []string, languageA, languageB string) ([]bool, []bool) {
+	internal, _, _ := strings.Cut(algorithm, "+")
+
+	matchedA, matchedB := make([]bool, len(a)), make([]bool, len(b))
+	switch internal {
+	case "hash":
+		if strings.Join(a, "\n") == strings.Join(b, "\n") {
+			for i := range matchedA {
+				matchedA[i] = true
+			}
+			for j := range matchedB {
+				matchedB[j] = true
+			}
+		}
+	case "lines":
+		for i := 0; i < min(len(a), len(b)); i++ {
+			if strings.TrimSpace(a[i]) == strings.TrimSpace(b[i]) && meaningfulLine(strings.TrimSpace(a[i])) {
+				matchedA[i], matchedB[i] = true, true
+			}
+		}
+	case "tokens":
+		pd.markSharedTrigrams(a, b, matchedA, matchedB)
+	case "variables":
+		markShared(a, b, matchedA, matchedB, pd.extractVariableNames)
+	case "functions":
+		markShared(a, b, matchedA, matchedB, pd.extractFunctionNames)
+	case "strings":
+		markShared(a, b, matchedA, matchedB, pd.extractStringLiterals)
+	case "winnowing":
+		markSharedFingerprints(pd.fingerprints(strings.Join(a, "\n")), pd.fingerprints(strings.Join(b, "\n")), matchedA, matchedB)
+	case "crosslang":
+		markSharedFingerprints(pd.crossFingerprints(strings.Join(a, "\n"), languageA), pd.crossFingerprints(strings.Join(b, "\n"), languageB), matchedA, matchedB)
+	default:
+		for _, region := range matchedRegions(a, b) {
+			for i := region.StartLine - 1; i < region.EndLine; i++ {
+				matchedA[i] = true
+			}
+			for j := region.OtherStartLine - 1; j < region.OtherEndLine; j++ {
+				matchedB[j] = true
+			}
+		}
+	}
+	return matchedA, matchedB
+}
+
+// markSharedTrigrams marks the lines holding token trigrams found in both
+// sources, the unit the tokens algorithm compares.
+func (pd *PlagiarismDetector) markSharedTrigrams(a, b []string, matchedA, matchedB []bool) {
+	type token struct {
+		text string
+		line int
+	}
+	tokenize := func(lines []string) []token {
+		var tokens []token
+		for i, line := range lines {
+			for _, t := range pd.tokenizeCode(line) {
+				tokens = append(tokens, token{t, i})
+			}
+		}
+		return tokens
+	}
+	gram := func(tokens []token, k int) string {
+		return tokens[k].text + " " + tokens[k+1].text + " " + tokens[k+2].text
+	}
+
+	tokensA, tokensB := tokenize(a), tokenize(b)
+	gramsB := make(map[string]bool)
+	for k := 0; k+2 < len(tokensB); k++ {
+		gramsB[gram(tokensB, k)] = true
+	}
+	shared := make(map[string]bool)
+	for k := 0; k+2 < len(tokensA); k++ {
+		if g := gram(tokensA, k); gramsB[g] {
+			shared[g] = true
+			for _, t := range tokensA[k : k+3] {
+				matchedA[t.line] = true
+			}
+		}
+	}
+	for k := 0; k+2 < len(tokensB); k++ {
+		if shared[gram(tokensB, k)] {
+			for _, t := range tokensB[k : k+3] {
+				matchedB[t.line] = true
+			}
+		}
+	}
+}
+
+// markSharedFingerprints marks the lines where a winnowed fingerprint found
+// in both sources starts.
+func markSharedFingerprints(printsA, printsB []fingerprint, matchedA, matchedB []bool) {
+	inA, inB := fingerprintSet(printsA), fingerprintSet(printsB)
+	for _, fp := range printsA {
+		if inB[fp.Hash] {
+			matchedA[fp.Line] = true
+		}
+	}
+	for _, fp := range printsB {
+		if inA[fp.Hash] {
+			matchedB[fp.Line] = true
+		}
+	}
+}
+
+// markShared marks the lines where extract finds a name or literal that
+// also occurs in the other source.
+func markShared(a, b []string, matchedA, matchedB []bool, extract func(string) []string) {
+	inA := make(map[string]bool)
+	for _, item := range extract(strings.Join(a, "\n")) {
+		inA[item] = true
+	}
+	inB := make(map[string]bool)
+	for _, item := range extract(strings.Join(b, "\n")) {
+		inB[item] = true
+	}
+
+	mark := func(lines []string, matched []bool, other map[string]bool) {
+		for i, line := range lines {
+			for _, item := range extract(line) {
+				if other[item] {
+					matched[i] = true
+					break
+				}
+			}
+		}
+	}
+	mark(a, matchedA, inB)
+	mark(b, matchedB, inA)
+}
+
+// diffLines aligns the sources by their longest common subsequence of
+// trimmed lines.
+func diffLines(a, b []string, matchedA, matchedB []bool) []DiffLine {
+	n, m := len(a), len(b)
+	trimmedA, trimmedB := make([]string, n), make([]string, m)
+	for i, line := range a {
+		trimmedA[i] = strings.TrimSpace(line)
+	}
+	for j, line := range b {
+		trimmedB[j] = strings.TrimSpace(line)
+	}
+
+	// lcs[i*(m+1)+j] is the common length of a[i:] and b[j:]
+	lcs := make([]uint16, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case trimmedA[i] == trimmedB[j]:
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j]
+			default:
+				lcs[i*(m+1)+j] = lcs[i*(m+1)+j+1]
+			}
+		}
+	}
+
+	lines := make([]DiffLine, 0, max(n, m))
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && trimmedA[i] == trimmedB[j]:
+			line := DiffLine{Op: DiffEqual, FirstLine: i + 1, SecondLine: j + 1, Text: a[i], Matched: matchedA[i], OtherMatched: matchedB[j]}
+			if b[j] != a[i] {
+				line.OtherText = b[j]
+			}
+			lines = append(lines, line)
+			i++
+			j++
+		case j == m || (i < n && lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]):
+			lines = append(lines, DiffLine{Op: DiffRemoved, FirstLine: i + 1, Text: a[i], Matched: matchedA[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DiffAdded, SecondLine: j + 1, Text: b[j], Matched: matchedB[j]})
+			j++
+		}
+	}
+	return lines
+}
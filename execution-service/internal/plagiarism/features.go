@@ -0,0 +1,57 @@
+package plagiarism
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// featuresVersion is bumped whenever extraction changes, so features stored
+// by the old extraction are extracted again.
+const featuresVersion = 2
+
+// storedFeatures loads the stored features of the given submissions. When
+// the lookup fails every submission is extracted again.
+func (pd *PlagiarismDetector) storedFeatures(ctx context.Context, submissionIDs []int64) map[int64]*CodeFeatures {
+	if len(submissionIDs) == 0 {
+		return nil
+	}
+	rows, err := pd.db.GetCodeFeatures(ctx, submissionIDs, featuresVersion)
+	if err != nil {
+		log.Printf("Failed to load stored code features: %v", err)
+		return nil
+	}
+
+	features := make(map[int64]*CodeFeatures, len(rows))
+	for submissionID, data := range rows {
+		var f CodeFeatures
+		if err := json.Unmarshal(data, &f); err != nil {
+			log.Printf("Ignoring unreadable code features of submission %d: %v", submissionID, err)
+			continue
+		}
+		features[submissionID] = &f
+	}
+	return features
+}
+
+// extractAndStore extracts a submission's features, without the problem's
+// boilerplate, and stores them for later checks. A failed store only costs a
+// later extraction.
+func (pd *PlagiarismDetector) extractAndStore(ctx context.Context, submissionID, problemID int64, code, language string) (*CodeFeatures, error) {
+	features, err := pd.extractFeatures(pd.stripBoilerplate(ctx, problemID, language, code), language)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(features)
+	if err != nil {
+		err = fmt.Errorf("failed to encode code features: %w", err)
+	} else {
+		err = pd.db.SaveCodeFeatures(ctx, submissionID, featuresVersion, data)
+	}
+	if err != nil {
+		log.Printf("Failed to store code features of submission %d: %v", submissionID, err)
+	}
+	return features, nil
+}
@@ -0,0 +1,172 @@
+package plagiarism
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JPlagBackend runs the JPlag CLI over an exported submission tree and reads
+// back its CSV export. The job runs in a throwaway directory with a minimal
+// environment and a hard timeout.
+type JPlagBackend struct {
+	command []string
+	timeout time.Duration
+}
+
+func NewJPlagBackend(command []string, timeout time.Duration) *JPlagBackend {
+	return &JPlagBackend{command: command, timeout: timeout}
+}
+
+func (j *JPlagBackend) Name() string {
+	return BackendJPlag
+}
+
+func (j *JPlagBackend) Compare(ctx context.Context, target Candidate, others []Candidate) (map[int64]float64, error) {
+	if len(j.command) == 0 {
+		return nil, fmt.Errorf("jplag command not configured")
+	}
+
+	workDir, err := os.MkdirTemp("", "jplag-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jplag work dir: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	submissionsDir := filepath.Join(workDir, "submissions")
+	for _, c := range append([]Candidate{target}, others...) {
+		dir := filepath.Join(submissionsDir, strconv.FormatInt(c.SubmissionID, 10))
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to export submission %d: %w", c.SubmissionID, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, candidateFileName(c)), c.Code, 0600); err != nil {
+			return nil, fmt.Errorf("failed to export submission %d: %w", c.SubmissionID, err)
+		}
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, j.timeout)
+	defer cancel()
+
+	resultDir := filepath.Join(workDir, "result")
+	args := append([]string{}, j.command[1:]...)
+	args = append(args,
+		"-l", languageFor(target.Language).jplag,
+		"-r", resultDir,
+		"--csv-export",
+		submissionsDir,
+	)
+	cmd := exec.CommandContext(jobCtx, j.command[0], args...)
+	cmd.Dir = workDir
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "HOME=" + workDir}
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("jplag failed: %w: %s", err, truncate(string(output), 512))
+	}
+
+	csvPath, err := findCSV(workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return readJPlagCSV(csvPath, target.SubmissionID)
+}
+
+func findCSV(root string) (string, error) {
+	var found string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".csv") {
+			found = path
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read jplag results: %w", err)
+	}
+	if found == "" {
+		return "", fmt.Errorf("jplag produced no csv export")
+	}
+	return found, nil
+}
+
+// readJPlagCSV keeps rows that pair the target with another submission,
+// preferring the maximum similarity column when present.
+func readJPlagCSV(path string, targetID int64) (map[int64]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open jplag results: %w", err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jplag results: %w", err)
+	}
+	if len(records) == 0 {
+		return map[int64]float64{}, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	first, okFirst := columns["submissionname1"]
+	second, okSecond := columns["submissionname2"]
+	scoreColumn, okScore := columns["maxsimilarity"]
+	if !okScore {
+		scoreColumn, okScore = columns["averagesimilarity"]
+	}
+	if !okFirst || !okSecond || !okScore {
+		return nil, fmt.Errorf("unexpected jplag csv header: %v", records[0])
+	}
+
+	target := strconv.FormatInt(targetID, 10)
+	scores := make(map[int64]float64)
+	for _, record := range records[1:] {
+		if len(record) <= first || len(record) <= second || len(record) <= scoreColumn {
+			continue
+		}
+
+		var other string
+		switch target {
+		case record[first]:
+			other = record[second]
+		case record[second]:
+			other = record[first]
+		default:
+			continue
+		}
+
+		otherID, err := strconv.ParseInt(other, 10, 64)
+		if err != nil {
+			continue
+		}
+		score, err := strconv.ParseFloat(record[scoreColumn], 64)
+		if err != nil {
+			continue
+		}
+		if score > 1 {
+			score /= 100
+		}
+		scores[otherID] = score
+	}
+
+	return scores, nil
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
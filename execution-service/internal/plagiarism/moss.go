@@ -0,0 +1,169 @@
+package plagiarism
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mossMatchPattern matches one row of the MOSS results index:
+// two file links, each labelled "name (NN%)".
+var mossMatchPattern = regexp.MustCompile(`(?is)<a href="[^"]*">\s*([^<]*?) \((\d+)%\)\s*</a>\s*<td>\s*<a href="[^"]*">\s*([^<]*?) \((\d+)%\)\s*</a>`)
+
+// MOSSBackend submits the candidate set over the MOSS socket protocol and
+// scrapes similarity percentages from the returned results page.
+type MOSSBackend struct {
+	server  string
+	userID  string
+	timeout time.Duration
+	client  *http.Client
+}
+
+func NewMOSSBackend(server, userID string, timeout time.Duration) *MOSSBackend {
+	return &MOSSBackend{
+		server:  server,
+		userID:  userID,
+		timeout: timeout,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (m *MOSSBackend) Name() string {
+	return BackendMOSS
+}
+
+func (m *MOSSBackend) Compare(ctx context.Context, target Candidate, others []Candidate) (map[int64]float64, error) {
+	jobCtx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	resultsURL, err := m.submit(jobCtx, target, others)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(jobCtx, http.MethodGet, resultsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create moss results request: %w", err)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch moss results: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moss results returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read moss results: %w", err)
+	}
+
+	return parseMOSSResults(string(body), target.SubmissionID), nil
+}
+
+func (m *MOSSBackend) submit(ctx context.Context, target Candidate, others []Candidate) (string, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.server)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to moss: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	language := languageFor(target.Language).moss
+	reader := bufio.NewReader(conn)
+
+	header := fmt.Sprintf("moss %s\ndirectory 0\nX 0\nmaxmatches %d\nshow %d\nlanguage %s\n",
+		m.userID, len(others)+1, len(others)+1, language)
+	if _, err := io.WriteString(conn, header); err != nil {
+		return "", fmt.Errorf("failed to send moss header: %w", err)
+	}
+
+	answer, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read moss language check: %w", err)
+	}
+	if strings.TrimSpace(answer) != "yes" {
+		return "", fmt.Errorf("moss does not support language %q", language)
+	}
+
+	for i, c := range append([]Candidate{target}, others...) {
+		fileHeader := fmt.Sprintf("file %d %s %d %s\n", i+1, language, len(c.Code), candidateFileName(c))
+		if _, err := io.WriteString(conn, fileHeader); err != nil {
+			return "", fmt.Errorf("failed to upload submission %d: %w", c.SubmissionID, err)
+		}
+		if _, err := conn.Write(c.Code); err != nil {
+			return "", fmt.Errorf("failed to upload submission %d: %w", c.SubmissionID, err)
+		}
+	}
+
+	if _, err := io.WriteString(conn, fmt.Sprintf("query 0 submission %d\n", target.SubmissionID)); err != nil {
+		return "", fmt.Errorf("failed to send moss query: %w", err)
+	}
+
+	resultsURL, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read moss results url: %w", err)
+	}
+	io.WriteString(conn, "end\n")
+
+	resultsURL = strings.TrimSpace(resultsURL)
+	if !strings.HasPrefix(resultsURL, "http") {
+		return "", fmt.Errorf("moss rejected query: %s", resultsURL)
+	}
+
+	return resultsURL, nil
+}
+
+// parseMOSSResults keeps rows involving the target. MOSS reports the share of
+// each file that matches, so the higher of the two percentages is used.
+func parseMOSSResults(page string, targetID int64) map[int64]float64 {
+	scores := make(map[int64]float64)
+
+	for _, match := range mossMatchPattern.FindAllStringSubmatch(page, -1) {
+		firstID, okFirst := mossSubmissionID(match[1])
+		secondID, okSecond := mossSubmissionID(match[3])
+		if !okFirst || !okSecond {
+			continue
+		}
+
+		var other int64
+		switch targetID {
+		case firstID:
+			other = secondID
+		case secondID:
+			other = firstID
+		default:
+			continue
+		}
+
+		firstPct, _ := strconv.Atoi(match[2])
+		secondPct, _ := strconv.Atoi(match[4])
+		score := float64(max(firstPct, secondPct)) / 100
+		if score > scores[other] {
+			scores[other] = score
+		}
+	}
+
+	return scores
+}
+
+func mossSubmissionID(name string) (int64, bool) {
+	base := path.Base(strings.TrimSpace(name))
+	base = strings.TrimSuffix(base, path.Ext(base))
+	id, err := strconv.ParseInt(base, 10, 64)
+	return id, err == nil
+}
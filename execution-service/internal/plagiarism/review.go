@@ -0,0 +1,70 @@
+package plagiarism
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"execution_service/internal/models"
+)
+
+// ErrReportReviewed means the report already has a reviewer's decision.
+var ErrReportReviewed = errors.New("plagiarism report already reviewed")
+
+// ReviewReport confirms or dismisses a pending report and announces a
+// confirmation on the event exchange.
+func (pd *PlagiarismDetector) ReviewReport(ctx context.Context, reportID int64, status string, reviewerID int64, notes string) (*models.PlagiarismReport, error) {
+	reviewed, err := pd.db.ReviewPlagiarismReport(ctx, reportID, status, reviewerID, notes)
+	if err != nil {
+		return nil, err
+	}
+	if !reviewed {
+		return nil, ErrReportReviewed
+	}
+
+	report, err := pd.db.GetPlagiarismReport(ctx, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if status == models.PlagiarismConfirmed {
+		pd.publishConfirmation(ctx, report)
+	}
+	return report, nil
+}
+
+func (pd *PlagiarismDetector) publishConfirmation(ctx context.Context, report *models.PlagiarismReport) {
+	if pd.publisher == nil {
+		return
+	}
+
+	submission, err := pd.db.GetSubmission(ctx, report.Submission1ID)
+	if err != nil {
+		log.Printf("Failed to publish confirmation of plagiarism report %d: %v", report.ID, err)
+		return
+	}
+	similar, err := pd.db.GetSubmission(ctx, report.Submission2ID)
+	if err != nil {
+		log.Printf("Failed to publish confirmation of plagiarism report %d: %v", report.ID, err)
+		return
+	}
+
+	event := &models.PlagiarismConfirmedEvent{
+		ReportID:            report.ID,
+		SubmissionID:        submission.ID,
+		UserID:              submission.UserID,
+		SimilarSubmissionID: similar.ID,
+		SimilarUserID:       similar.UserID,
+		ProblemID:           submission.ProblemID,
+		ContestID:           submission.ContestID,
+		SimilarityScore:     report.SimilarityScore,
+	}
+	if report.ReviewerID != nil {
+		event.ReviewerID = *report.ReviewerID
+	}
+	if report.ReviewNotes != nil {
+		event.ReviewNotes = *report.ReviewNotes
+	}
+	if err := pd.publisher(ctx, "PlagiarismConfirmed", event); err != nil {
+		log.Printf("Failed to publish confirmation of plagiarism report %d: %v", report.ID, err)
+	}
+}
@@ -0,0 +1,226 @@
+package plagiarism
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"execution_service/internal/models"
+)
+
+// ErrUnsupportedSimilarity means a similarity search cannot run for the
+// submission, such as a contest scope for a practice submission.
+var ErrUnsupportedSimilarity = errors.New("similarity search not supported")
+
+// SimilarityScope selects the submissions FindSimilar compares against:
+// every submission to the problem, or only those in the same contest.
+type SimilarityScope string
+
+const (
+	ScopeProblem SimilarityScope = "problem"
+	ScopeContest SimilarityScope = "contest"
+)
+
+const (
+	// similarityCandidates caps how many recent submissions one search reads.
+	similarityCandidates = 200
+	// minRegionLines is the shortest run of shared meaningful lines reported.
+	minRegionLines  = 3
+	maxRegions      = 5
+	maxPreviewLines = 3
+	maxPreviewChars = 240
+)
+
+// SimilarSubmission is one search hit. Score is the highest score over the
+// configured algorithms, as in automated detection.
+type SimilarSubmission struct {
+	SubmissionID int64           `json:"submission_id"`
+	UserID       int64           `json:"user_id"`
+	ContestID    *int64          `json:"contest_id,omitempty"`
+	Language     string          `json:"language"`
+	Verdict      models.Verdict  `json:"verdict"`
+	Score        float64         `json:"score"`
+	Algorithm    string          `json:"algorithm"`
+	Regions      []MatchedRegion `json:"regions"`
+}
+
+// MatchedRegion is a run of lines, compared without surrounding whitespace,
+// found in both submissions. Line numbers start at 1; Preview is taken from
+// the searched submission.
+type MatchedRegion struct {
+	StartLine      int    `json:"start_line"`
+	EndLine        int    `json:"end_line"`
+	OtherStartLine int    `json:"other_start_line"`
+	OtherEndLine   int    `json:"other_end_line"`
+	Preview        string `json:"preview"`
+}
+
+// FindSimilar ranks the submissions in scope by similarity to the given one
+// and returns the top limit, for investigations outside the threshold
+// pipeline. It reports nothing and changes no state.
+func (pd *PlagiarismDetector) FindSimilar(ctx context.Context, submission *models.Submission, scope SimilarityScope, limit int) ([]SimilarSubmission, error) {
+	if submission.EntryFile != nil {
+		return nil, fmt.Errorf("%w: project submissions", ErrUnsupportedSimilarity)
+	}
+
+	var contestID *int64
+	switch scope {
+	case ScopeProblem:
+	case ScopeContest:
+		if submission.ContestID == nil {
+			return nil, fmt.Errorf("%w: submission is not part of a contest", ErrUnsupportedSimilarity)
+		}
+		contestID = submission.ContestID
+	default:
+		return nil, fmt.Errorf("%w: unknown scope %q", ErrUnsupportedSimilarity, scope)
+	}
+
+	code, err := pd.storage.DownloadCode(ctx, submission.CodeURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download code: %w", err)
+	}
+	lines := strings.Split(string(code), "\n")
+
+	candidates, err := pd.db.GetSimilarityCandidates(ctx, submission.ProblemID, contestID, submission.ID, similarityCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int64{submission.ID}
+	for _, candidate := range candidates {
+		ids = append(ids, candidate.ID)
+	}
+	stored := pd.storedFeatures(ctx, ids)
+
+	features := stored[submission.ID]
+	if features == nil {
+		features, err = pd.extractAndStore(ctx, submission.ID, submission.ProblemID, string(code), submission.Language)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	matches := make([]SimilarSubmission, 0, len(candidates))
+	for _, candidate := range candidates {
+		otherCode, err := pd.storage.DownloadCode(ctx, candidate.CodeURL)
+		if err != nil {
+			continue
+		}
+		otherFeatures := stored[candidate.ID]
+		if otherFeatures == nil {
+			otherFeatures, err = pd.extractAndStore(ctx, candidate.ID, candidate.ProblemID, string(otherCode), candidate.Language)
+			if err != nil {
+				continue
+			}
+		}
+
+		match := SimilarSubmission{
+			SubmissionID: candidate.ID,
+			UserID:       candidate.UserID,
+			ContestID:    candidate.ContestID,
+			Language:     candidate.Language,
+			Verdict:      candidate.Verdict,
+		}
+		match.Score, match.Algorithm = pd.bestSimilarity(features, otherFeatures)
+		if match.Score == 0 {
+			continue
+		}
+		match.Regions = matchedRegions(lines, strings.Split(string(otherCode), "\n"))
+		matches = append(matches, match)
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].Score != matches[b].Score {
+			return matches[a].Score > matches[b].Score
+		}
+		return matches[a].SubmissionID < matches[b].SubmissionID
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// matchedRegions finds the longest runs of identical lines shared by both
+// sources. Runs without minRegionLines meaningful lines, such as stretches of
+// braces and blank lines, are dropped, and regions do not overlap in lines.
+func matchedRegions(lines, otherLines []string) []MatchedRegion {
+	normalize := func(source []string) []string {
+		normalized := make([]string, len(source))
+		for i, line := range source {
+			normalized[i] = strings.TrimSpace(line)
+		}
+		return normalized
+	}
+	a, b := normalize(lines), normalize(otherLines)
+
+	positions := make(map[string][]int)
+	for j, line := range b {
+		if meaningfulLine(line) {
+			positions[line] = append(positions[line], j)
+		}
+	}
+
+	type run struct{ start, otherStart, length, meaningful int }
+	var runs []run
+	for i, line := range a {
+		for _, j := range positions[line] {
+			// Only start at the beginning of a run
+			if i > 0 && j > 0 && a[i-1] == b[j-1] {
+				continue
+			}
+			r := run{start: i, otherStart: j}
+			for i+r.length < len(a) && j+r.length < len(b) && a[i+r.length] == b[j+r.length] {
+				if meaningfulLine(a[i+r.length]) {
+					r.meaningful++
+				}
+				r.length++
+			}
+			if r.meaningful >= minRegionLines {
+				runs = append(runs, r)
+			}
+		}
+	}
+	sort.SliceStable(runs, func(x, y int) bool { return runs[x].length > runs[y].length })
+
+	used := make([]bool, len(a))
+	var regions []MatchedRegion
+	for _, r := range runs {
+		if len(regions) == maxRegions {
+			break
+		}
+		overlaps := false
+		for k := r.start; k < r.start+r.length; k++ {
+			if used[k] {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			continue
+		}
+		for k := r.start; k < r.start+r.length; k++ {
+			used[k] = true
+		}
+
+		preview := strings.Join(lines[r.start:r.start+min(r.length, maxPreviewLines)], "\n")
+		regions = append(regions, MatchedRegion{
+			StartLine:      r.start + 1,
+			EndLine:        r.start + r.length,
+			OtherStartLine: r.otherStart + 1,
+			OtherEndLine:   r.otherStart + r.length,
+			Preview:        truncate(preview, maxPreviewChars),
+		})
+	}
+
+	sort.Slice(regions, func(x, y int) bool { return regions[x].StartLine < regions[y].StartLine })
+	return regions
+}
+
+// meaningfulLine reports whether a trimmed line carries code rather than
+// just punctuation, so shared braces alone never make a region.
+func meaningfulLine(line string) bool {
+	return len(line) > 2
+}
@@ -0,0 +1,148 @@
+package plagiarism
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/c"
+	"github.com/smacker/go-tree-sitter/cpp"
+	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/ruby"
+	"github.com/smacker/go-tree-sitter/rust"
+)
+
+// syntaxParseTimeout bounds the parse of one submission.
+const syntaxParseTimeout = 2 * time.Second
+
+// syntaxGrammars are the tree-sitter grammars of the languages the ast
+// algorithm understands. Submissions in other languages have no syntax tree.
+var syntaxGrammars = map[string]*sitter.Language{
+	"c":      c.GetLanguage(),
+	"cpp":    cpp.GetLanguage(),
+	"go":     golang.GetLanguage(),
+	"java":   java.GetLanguage(),
+	"python": python.GetLanguage(),
+	"ruby":   ruby.GetLanguage(),
+	"rust":   rust.GetLanguage(),
+}
+
+// syntaxNode is a normalized syntax tree node. Its label is the node type
+// followed by the keywords and operators it holds, so renamed identifiers,
+// changed literals and comments do not change the tree.
+type syntaxNode struct {
+	label    string
+	children []*syntaxNode
+}
+
+// subtreeStats counts the occurrences of one subtree shape and its size in
+// nodes.
+type subtreeStats struct {
+	Count int `json:"c"`
+	Size  int `json:"s"`
+}
+
+// syntaxTree parses code with the tree-sitter grammar of its language and
+// normalizes the tree. It returns nil for a language without a grammar or
+// code that does not parse in time; code with syntax errors still parses,
+// with the errors as nodes.
+func syntaxTree(code, language string) *syntaxNode {
+	grammar, ok := syntaxGrammars[language]
+	if !ok {
+		return nil
+	}
+
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(grammar)
+
+	ctx, cancel := context.WithTimeout(context.Background(), syntaxParseTimeout)
+	defer cancel()
+	tree, err := parser.ParseCtx(ctx, nil, []byte(code))
+	if err != nil {
+		return nil
+	}
+	defer tree.Close()
+
+	return normalizeSyntax(tree.RootNode())
+}
+
+// normalizeSyntax keeps the named nodes. Unnamed children, the keywords,
+// operators and punctuation, join their parent's label, except brackets and
+// separators, which every language spells differently.
+func normalizeSyntax(node *sitter.Node) *syntaxNode {
+	label := []string{node.Type()}
+	var children []*syntaxNode
+	for i := 0; i < int(node.ChildCount()); i++ {
+		child := node.Child(i)
+		switch {
+		case child.IsExtra():
+			// Comments
+		case child.IsNamed():
+			children = append(children, normalizeSyntax(child))
+		case strings.Trim(child.Type(), "(){}[];,") != "":
+			label = append(label, child.Type())
+		}
+	}
+	return &syntaxNode{label: strings.Join(label, " "), children: children}
+}
+
+// subtrees hashes every subtree below the root by its label and the hashes
+// of its children, counting how often each shape occurs.
+func subtrees(root *syntaxNode) map[uint64]subtreeStats {
+	stats := make(map[uint64]subtreeStats)
+	if root == nil {
+		return stats
+	}
+
+	var walk func(node *syntaxNode) (uint64, int)
+	walk = func(node *syntaxNode) (uint64, int) {
+		h := fnv.New64a()
+		h.Write([]byte(node.label))
+		h.Write([]byte{'('})
+		size := 1
+		for _, child := range node.children {
+			childHash, childSize := walk(child)
+			h.Write(binary.LittleEndian.AppendUint64(nil, childHash))
+			size += childSize
+		}
+		h.Write([]byte{')'})
+
+		hash := h.Sum64()
+		entry := stats[hash]
+		entry.Count++
+		entry.Size = size
+		stats[hash] = entry
+		return hash, size
+	}
+	for _, child := range root.children {
+		walk(child)
+	}
+	return stats
+}
+
+// astSimilarity is the size-weighted share of subtree shapes the two trees
+// have in common, so a shared function outweighs a shared statement. Code
+// without a syntax tree shares nothing.
+func (pd *PlagiarismDetector) astSimilarity(trees1, trees2 map[uint64]subtreeStats) float64 {
+	if len(trees1) == 0 || len(trees2) == 0 {
+		return 0.0
+	}
+
+	var total, shared int
+	for hash, stats := range trees1 {
+		total += stats.Count * stats.Size
+		if other, ok := trees2[hash]; ok {
+			shared += 2 * min(stats.Count, other.Count) * stats.Size
+		}
+	}
+	for _, stats := range trees2 {
+		total += stats.Count * stats.Size
+	}
+	return float64(shared) / float64(total)
+}
@@ -0,0 +1,142 @@
+package plagiarism
+
+import "testing"
+
+const sumCpp = `#include <vector>
+int sum(const std::vector<int>& values) {
+    int total = 0;
+    for (int i = 0; i < (int)values.size(); i++) {
+        total += values[i];
+    }
+    return total;
+}`
+
+func TestASTSimilarity(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		a, b     string
+		min, max float64
+	}{
+		{
+			name:     "renamed identifiers, literals and comments",
+			language: "cpp",
+			a:        sumCpp,
+			b: `#include <vector>
+// Adds everything up
+int add(const std::vector<int>& xs) {
+    int acc = 7; /* start */
+    for (int k = 1; k < (int)xs.size(); k++) {
+        acc += xs[k];
+    }
+    return acc;
+}`,
+			min: 1, max: 1,
+		},
+		{
+			name:     "unrelated programs",
+			language: "cpp",
+			a:        sumCpp,
+			b: `#include <cstdio>
+int main() {
+    char name[32];
+    if (scanf("%31s", name) == 1) printf("hello %s\n", name);
+    while (true) break;
+}`,
+			min: 0, max: 0.3,
+		},
+		{
+			name:     "statement split across lines",
+			language: "python",
+			a:        "def f(x):\n    y = (x +\n         1)\n    return y\n",
+			b:        "def g(a):\n    b = (a + 1)\n    return b\n",
+			min:      1, max: 1,
+		},
+		{
+			name:     "changed operator",
+			language: "python",
+			a:        "def f(x):\n    return x + 1\n",
+			b:        "def f(x):\n    return x - 1\n",
+			min:      0.1, max: 0.9,
+		},
+		{
+			name:     "ruby blocks nest",
+			language: "ruby",
+			a:        "def f(n)\n  if n > 0\n    n * 2\n  end\nend\n",
+			b:        "def g(m)\n  if m > 3\n    m * 5\n  end\nend\n",
+			min:      1, max: 1,
+		},
+		{
+			name:     "java method",
+			language: "java",
+			a:        "class A { int f(int x) { return x * x; } }",
+			b:        "class B { int g(int y) { return y * y; } }",
+			min:      1, max: 1,
+		},
+		{
+			name:     "language without a grammar",
+			language: "kotlin",
+			a:        "fun main() { println(1) }",
+			b:        "fun main() { println(1) }",
+			min:      0, max: 0,
+		},
+	}
+
+	pd := &PlagiarismDetector{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pd.astSimilarity(subtrees(syntaxTree(tt.a, tt.language)), subtrees(syntaxTree(tt.b, tt.language)))
+			if got < tt.min || got > tt.max {
+				t.Errorf("astSimilarity = %.3f, want within [%.2f, %.2f]", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestSyntaxTreeNesting(t *testing.T) {
+	tree := syntaxTree("def f(x):\n    if x:\n        return 1\n    return 2\n", "python")
+	if tree == nil || len(tree.children) != 1 {
+		t.Fatalf("want one top-level function, got %+v", tree)
+	}
+
+	function := tree.children[0]
+	if function.label != "function_definition def :" {
+		t.Errorf("function label = %q", function.label)
+	}
+	block := function.children[len(function.children)-1]
+	if len(block.children) != 2 || block.children[0].label != "if_statement if :" {
+		t.Errorf("function body = %+v, want the if statement and a return", block.children)
+	}
+}
+
+func TestSubtrees(t *testing.T) {
+	leaf := func(label string) *syntaxNode { return &syntaxNode{label: label} }
+	root := &syntaxNode{children: []*syntaxNode{
+		{label: "call", children: []*syntaxNode{leaf("identifier")}},
+		{label: "call", children: []*syntaxNode{leaf("identifier")}},
+		leaf("identifier"),
+	}}
+
+	stats := subtrees(root)
+	if len(stats) != 2 {
+		t.Fatalf("got %d shapes, want 2", len(stats))
+	}
+	for _, s := range stats {
+		switch s.Size {
+		case 1:
+			if s.Count != 3 {
+				t.Errorf("identifier count = %d, want 3", s.Count)
+			}
+		case 2:
+			if s.Count != 2 {
+				t.Errorf("call count = %d, want 2", s.Count)
+			}
+		default:
+			t.Errorf("unexpected subtree size %d", s.Size)
+		}
+	}
+
+	if len(subtrees(nil)) != 0 {
+		t.Errorf("subtrees(nil) should be empty")
+	}
+}
@@ -0,0 +1,133 @@
+package plagiarism
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"execution_service/internal/models"
+)
+
+// LoadSource reports judge load; it matches JudgePool.Load.
+type LoadSource func() (models.JudgeLoad, error)
+
+type ThrottleState string
+
+const (
+	ThrottleRunning ThrottleState = "running"
+	ThrottleSlowed  ThrottleState = "slowed"
+	ThrottlePaused  ThrottleState = "paused"
+)
+
+type ThrottleStatus struct {
+	State       ThrottleState    `json:"state"`
+	Since       time.Time        `json:"since"`
+	Load        models.JudgeLoad `json:"load"`
+	Utilization float64          `json:"utilization"`
+	CheckedAt   time.Time        `json:"checked_at"`
+	Backlog     int              `json:"backlog"`
+}
+
+// SetLoadSource enables backpressure: detection slows down or pauses while
+// the judge is saturated. Call before Start.
+func (pd *PlagiarismDetector) SetLoadSource(source LoadSource) {
+	pd.loadSource = source
+}
+
+func (pd *PlagiarismDetector) ThrottleStatus() ThrottleStatus {
+	pd.throttleMu.Lock()
+	status := pd.throttle
+	pd.throttleMu.Unlock()
+
+	status.Backlog = len(pd.workerPool)
+	return status
+}
+
+func (pd *PlagiarismDetector) monitorLoad(ctx context.Context) {
+	ticker := time.NewTicker(pd.config.LoadCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-pd.stopChan:
+			return
+		case <-ticker.C:
+			pd.checkLoad()
+		}
+	}
+}
+
+func (pd *PlagiarismDetector) checkLoad() {
+	load, err := pd.loadSource()
+	if err != nil {
+		log.Printf("Failed to read judge load, keeping plagiarism throttle state: %v", err)
+		return
+	}
+
+	state := pd.throttleStateFor(load)
+	now := time.Now()
+
+	pd.throttleMu.Lock()
+	defer pd.throttleMu.Unlock()
+
+	pd.throttle.Load = load
+	pd.throttle.Utilization = load.Utilization()
+	pd.throttle.CheckedAt = now
+	if state == pd.throttle.State {
+		return
+	}
+
+	log.Printf("Plagiarism detection %s (queue depth %d, %d/%d judge workers busy)",
+		state, load.QueueDepth, load.ActiveWorkers, load.TotalWorkers)
+	pd.throttle.State = state
+	pd.throttle.Since = now
+	close(pd.throttleChanged)
+	pd.throttleChanged = make(chan struct{})
+}
+
+func (pd *PlagiarismDetector) throttleStateFor(load models.JudgeLoad) ThrottleState {
+	if load.Utilization() < pd.config.ThrottleUtilization {
+		return ThrottleRunning
+	}
+	if load.QueueDepth >= pd.config.PauseQueueDepth {
+		return ThrottlePaused
+	}
+	return ThrottleSlowed
+}
+
+// waitForJudgeCapacity blocks while detection is paused and adds the throttle
+// delay while it is slowed. It returns false once the detector is stopping.
+func (pd *PlagiarismDetector) waitForJudgeCapacity(ctx context.Context) bool {
+	for {
+		pd.throttleMu.Lock()
+		state := pd.throttle.State
+		changed := pd.throttleChanged
+		pd.throttleMu.Unlock()
+
+		var wait <-chan time.Time
+		switch state {
+		case ThrottleRunning:
+			return true
+		case ThrottleSlowed:
+			wait = time.After(pd.config.ThrottleDelay)
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-pd.stopChan:
+			return false
+		case <-changed:
+		case <-wait:
+			return true
+		}
+	}
+}
+
+func (pd *PlagiarismDetector) paused() bool {
+	pd.throttleMu.Lock()
+	defer pd.throttleMu.Unlock()
+	return pd.throttle.State == ThrottlePaused
+}
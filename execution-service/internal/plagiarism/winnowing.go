@@ -0,0 +1,109 @@
+package plagiarism
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var winnowTokenPattern = regexp.MustCompile(`[A-Za-z_]\w*|\d+(\.\d+)?|[^\w\s]`)
+
+// fingerprint is a winnowed k-gram hash and the line its first token is on.
+type fingerprint struct {
+	Hash uint64 `json:"h"`
+	Line int    `json:"l"`
+}
+
+// winnowTokens tokenizes code for winnowing with identifiers and numbers
+// replaced by placeholders, so renaming variables leaves the fingerprints
+// unchanged. Each token is paired with its zero-based line.
+func (pd *PlagiarismDetector) winnowTokens(lines []string) ([]string, []int) {
+	var tokens []string
+	var positions []int
+	for i, line := range lines {
+		for _, token := range winnowTokenPattern.FindAllString(line, -1) {
+			switch {
+			case unicode.IsDigit(rune(token[0])):
+				token = "N"
+			case token[0] == '_' || unicode.IsLetter(rune(token[0])):
+				if lower := strings.ToLower(token); pd.isKeyword(lower) {
+					token = lower
+				} else {
+					token = "V"
+				}
+			}
+			tokens = append(tokens, token)
+			positions = append(positions, i)
+		}
+	}
+	return tokens, positions
+}
+
+// fingerprints selects the winnowed fingerprints of code.
+func (pd *PlagiarismDetector) fingerprints(code string) []fingerprint {
+	lines := strings.Split(pd.removeCommentsAndStrings(code), "\n")
+	return pd.winnow(pd.winnowTokens(lines))
+}
+
+// winnow hashes the k-grams of a token stream and keeps the minimum hash of
+// every window, taking the rightmost on ties and recording each selected
+// position once.
+func (pd *PlagiarismDetector) winnow(tokens []string, positions []int) []fingerprint {
+	k, window := max(pd.config.WinnowingK, 1), max(pd.config.WinnowingWindow, 1)
+	if len(tokens) < k {
+		return nil
+	}
+
+	hashes := make([]uint64, len(tokens)-k+1)
+	for i := range hashes {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(tokens[i:i+k], " ")))
+		hashes[i] = h.Sum64()
+	}
+
+	window = min(window, len(hashes))
+	var selected []fingerprint
+	last := -1
+	for start := 0; start+window <= len(hashes); start++ {
+		minimum := start
+		for i := start + 1; i < start+window; i++ {
+			if hashes[i] <= hashes[minimum] {
+				minimum = i
+			}
+		}
+		if minimum != last {
+			selected = append(selected, fingerprint{Hash: hashes[minimum], Line: positions[minimum]})
+			last = minimum
+		}
+	}
+	return selected
+}
+
+// winnowingSimilarity is the share of distinct fingerprints the two sources
+// have in common.
+func (pd *PlagiarismDetector) winnowingSimilarity(prints1, prints2 []fingerprint) float64 {
+	if len(prints1) == 0 && len(prints2) == 0 {
+		return 1.0
+	}
+	if len(prints1) == 0 || len(prints2) == 0 {
+		return 0.0
+	}
+
+	set1, set2 := fingerprintSet(prints1), fingerprintSet(prints2)
+	intersection := 0
+	for hash := range set1 {
+		if set2[hash] {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(set1)+len(set2)-intersection)
+}
+
+func fingerprintSet(prints []fingerprint) map[uint64]bool {
+	set := make(map[uint64]bool, len(prints))
+	for _, fp := range prints {
+		set[fp.Hash] = true
+	}
+	return set
+}
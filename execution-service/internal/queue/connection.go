@@ -0,0 +1,213 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// heartbeatInterval is how often the connection is checked in case a
+	// close notification is missed
+	heartbeatInterval = 30 * time.Second
+	// reconnectInterval spaces reconnect attempts and attempts to register
+	// a consumer again
+	reconnectInterval = 5 * time.Second
+)
+
+// StartHeartbeat reconnects as soon as the connection closes. Consumers
+// opened through the client are registered again on the new channel, and
+// the OnReconnect listeners run once it is up.
+func (r *RabbitMQClient) StartHeartbeat() {
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			conn := r.currentConn()
+			closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		watch:
+			for {
+				select {
+				case err := <-closed:
+					log.Printf("RabbitMQ connection closed: %v", err)
+					break watch
+				case <-ticker.C:
+					if conn.IsClosed() {
+						break watch
+					}
+				}
+			}
+
+			log.Printf("RabbitMQ connection lost, attempting to reconnect...")
+			for {
+				err := r.reconnect()
+				if err == nil {
+					break
+				}
+				log.Printf("Failed to reconnect to RabbitMQ: %v", err)
+				time.Sleep(reconnectInterval)
+			}
+			r.notifyReconnect()
+		}
+	}()
+}
+
+// OnReconnect registers a listener run after every reconnect, once the
+// client's consumers are being registered again. Deliveries received
+// before the reconnect can no longer be acknowledged; the broker has
+// already requeued them.
+func (r *RabbitMQClient) OnReconnect(listener func()) {
+	r.reconnectMu.Lock()
+	defer r.reconnectMu.Unlock()
+	r.reconnectListeners = append(r.reconnectListeners, listener)
+}
+
+// reconnectSignal returns a channel closed at the next reconnect.
+func (r *RabbitMQClient) reconnectSignal() <-chan struct{} {
+	r.reconnectMu.Lock()
+	defer r.reconnectMu.Unlock()
+	return r.reconnected
+}
+
+func (r *RabbitMQClient) notifyReconnect() {
+	r.reconnectMu.Lock()
+	close(r.reconnected)
+	r.reconnected = make(chan struct{})
+	listeners := r.reconnectListeners
+	r.reconnectMu.Unlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
+// keepConsuming opens a consumer and forwards its deliveries to the returned
+// channel, opening it again whenever the channel underneath closes, promptly
+// after a reconnect. The returned channel closes once ctx is cancelled.
+func (r *RabbitMQClient) keepConsuming(ctx context.Context, open func() (<-chan amqp.Delivery, error)) (<-chan amqp.Delivery, error) {
+	reconnected := r.reconnectSignal()
+	msgs, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan amqp.Delivery)
+	go func() {
+		defer close(out)
+		for {
+			for msgs != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case msg, ok := <-msgs:
+					if !ok {
+						msgs = nil
+						continue
+					}
+					select {
+					case out <- msg:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-reconnected:
+			case <-time.After(reconnectInterval):
+			}
+			// Until the reconnect the old channel is closed and open would fail
+			if r.currentChannel().IsClosed() {
+				continue
+			}
+			reconnected = r.reconnectSignal()
+			if msgs, err = open(); err != nil {
+				log.Printf("Failed to register consumer again: %v", err)
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (r *RabbitMQClient) reconnect() error {
+	conn, err := amqp.Dial(r.config.URL)
+	if err != nil {
+		return fmt.Errorf("failed to reconnect to RabbitMQ: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open channel on reconnect: %w", err)
+	}
+
+	err = ch.Qos(
+		r.config.PrefetchCount,
+		0,
+		false,
+	)
+	if err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("failed to set QoS on reconnect: %w", err)
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("failed to enable publisher confirms on reconnect: %w", err)
+	}
+
+	queue, contestQueue, err := declareTopology(ch, r.config)
+	if err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("failed to declare topology on reconnect: %w", err)
+	}
+
+	r.connMu.Lock()
+	oldConn, oldChannel := r.conn, r.channel
+	r.conn = conn
+	r.channel = ch
+	r.queue = queue
+	r.contestQueue = contestQueue
+	r.connMu.Unlock()
+
+	if oldChannel != nil {
+		oldChannel.Close()
+	}
+	if oldConn != nil {
+		oldConn.Close()
+	}
+
+	log.Printf("Successfully reconnected to RabbitMQ")
+	return nil
+}
+
+// currentConn returns the connection, which reconnect replaces.
+func (r *RabbitMQClient) currentConn() *amqp.Connection {
+	r.connMu.RLock()
+	defer r.connMu.RUnlock()
+	return r.conn
+}
+
+// currentChannel returns the channel, which reconnect replaces. Callers take
+// it once per operation.
+func (r *RabbitMQClient) currentChannel() *amqp.Channel {
+	r.connMu.RLock()
+	defer r.connMu.RUnlock()
+	return r.channel
+}
+
+// queueNames returns the practice and contest judge queue names.
+func (r *RabbitMQClient) queueNames() (string, string) {
+	r.connMu.RLock()
+	defer r.connMu.RUnlock()
+	return r.queue.Name, r.contestQueue.Name
+}
@@ -0,0 +1,216 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"execution_service/internal/models"
+)
+
+// Routing key templates may reference {event_type} and any top-level field
+// of the event data, e.g. "submission.judged.{contest_id}". Dot-separated
+// segments whose placeholders have no value are dropped.
+const defaultRoutingKeyTemplate = "submission.{event_type}"
+
+var defaultRoutingKeys = map[string]string{
+	"SubmissionJudged":      "submission.judged",
+	"JudgingBudgetExceeded": "admin.alert.judging_budget",
+	"JudgeNodeDeviation":    "admin.alert.node_deviation",
+	"PlagiarismDetected":    "plagiarism.detected",
+	"PlagiarismConfirmed":   "plagiarism.confirmed",
+}
+
+// eventPayloads lists every event the service publishes together with the
+// type of its data, which drives the generated schema.
+var eventPayloads = []struct {
+	eventType string
+	payload   any
+}{
+	{"SubmissionJudged", models.JudgeResult{}},
+	{"SubmissionCompilationFailed", models.CompilationFailedEvent{}},
+	{"JudgingBudgetExceeded", models.JudgingBudgetExceededEvent{}},
+	{"PlagiarismDetected", models.PlagiarismDetectedEvent{}},
+	{"PlagiarismConfirmed", models.PlagiarismConfirmedEvent{}},
+	{"JudgeNodeDeviation", models.NodeDeviationEvent{}},
+}
+
+var placeholderPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+type EventContract struct {
+	EventType  string         `json:"event_type"`
+	Exchange   string         `json:"exchange"`
+	RoutingKey string         `json:"routing_key"`
+	Encrypted  bool           `json:"encrypted"`
+	Schema     map[string]any `json:"schema"`
+}
+
+func (r *RabbitMQClient) routingKeyTemplate(eventType string) string {
+	if template, ok := r.config.RoutingKeys[eventType]; ok {
+		return template
+	}
+	if template, ok := defaultRoutingKeys[eventType]; ok {
+		return template
+	}
+	return defaultRoutingKeyTemplate
+}
+
+// BindingKey returns a topic pattern matching every routing key the event can
+// be published with. Segments with data placeholders become "#".
+func (r *RabbitMQClient) BindingKey(eventType string) string {
+	segments := strings.Split(r.routingKeyTemplate(eventType), ".")
+	bound := make([]string, 0, len(segments))
+
+	for _, segment := range segments {
+		wildcard := false
+		segment = placeholderPattern.ReplaceAllStringFunc(segment, func(match string) string {
+			if match == "{event_type}" {
+				return eventType
+			}
+			wildcard = true
+			return ""
+		})
+		if wildcard {
+			segment = "#"
+		}
+		if segment == "" || (segment == "#" && len(bound) > 0 && bound[len(bound)-1] == "#") {
+			continue
+		}
+		bound = append(bound, segment)
+	}
+
+	return strings.Join(bound, ".")
+}
+
+func renderRoutingKey(template, eventType string, data map[string]any) string {
+	segments := strings.Split(template, ".")
+	rendered := make([]string, 0, len(segments))
+
+	for _, segment := range segments {
+		missing := false
+		segment = placeholderPattern.ReplaceAllStringFunc(segment, func(match string) string {
+			name := match[1 : len(match)-1]
+			if name == "event_type" {
+				return eventType
+			}
+			value := routingValue(data[name])
+			if value == "" {
+				missing = true
+			}
+			return value
+		})
+		if !missing && segment != "" {
+			rendered = append(rendered, segment)
+		}
+	}
+
+	return strings.Join(rendered, ".")
+}
+
+func routingValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// eventData converts a typed payload into the map carried by EventMessage.
+func eventData(data any) (map[string]any, error) {
+	if m, ok := data.(map[string]any); ok {
+		return m, nil
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event data: %w", err)
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(encoded, &m); err != nil {
+		return nil, fmt.Errorf("unsupported event data type %T", data)
+	}
+	return m, nil
+}
+
+// EventContracts describes the exchange, routing key template and JSON
+// schema of every published event.
+func (r *RabbitMQClient) EventContracts() []EventContract {
+	contracts := make([]EventContract, 0, len(eventPayloads))
+
+	for _, event := range eventPayloads {
+		envelope := jsonSchema(reflect.TypeOf(models.EventMessage{}))
+		envelope["properties"].(map[string]any)["data"] = jsonSchema(reflect.TypeOf(event.payload))
+		envelope["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+		envelope["title"] = event.eventType
+
+		contracts = append(contracts, EventContract{
+			EventType:  event.eventType,
+			Exchange:   r.config.EventsExchange,
+			RoutingKey: r.routingKeyTemplate(event.eventType),
+			Encrypted:  r.encryptsEvent(event.eventType),
+			Schema:     envelope,
+		})
+	}
+
+	return contracts
+}
+
+func jsonSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		required := make([]string, 0)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = jsonSchema(field.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]any{"type": "object", "properties": properties, "required": required}
+	default:
+		return map[string]any{}
+	}
+}
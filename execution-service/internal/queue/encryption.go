@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"execution_service/internal/config"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	encryptedContentEncoding = "aes-256-gcm"
+	encryptionKeyHeader      = "x-encryption-key-id"
+)
+
+// ErrUnknownEncryptionKey means a message was sealed with a key this node
+// does not have; it stays undecryptable until the key is configured again.
+var ErrUnknownEncryptionKey = errors.New("unknown queue encryption key")
+
+// messageCipher seals message bodies with AES-256-GCM. New messages use the
+// active key; any configured key can open, so rotating means adding the new
+// key as active and removing the old one once its messages have drained.
+type messageCipher struct {
+	activeKeyID string
+	keys        map[string]cipher.AEAD
+}
+
+func newMessageCipher(cfg *config.QueueEncryptionConfig) (*messageCipher, error) {
+	mc := &messageCipher{
+		activeKeyID: cfg.ActiveKeyID,
+		keys:        make(map[string]cipher.AEAD),
+	}
+
+	for keyID, encoded := range cfg.Keys {
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode queue encryption key %s: %w", keyID, err)
+		}
+		if len(raw) != 32 {
+			return nil, fmt.Errorf("queue encryption key %s must be 32 bytes, got %d", keyID, len(raw))
+		}
+
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher for key %s: %w", keyID, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher for key %s: %w", keyID, err)
+		}
+		mc.keys[keyID] = aead
+	}
+
+	if _, ok := mc.keys[mc.activeKeyID]; !ok {
+		return nil, fmt.Errorf("active queue encryption key %q is not configured", mc.activeKeyID)
+	}
+	return mc, nil
+}
+
+// seal encrypts the publishing's body in place and marks it so consumers
+// know which key to use. The nonce is prepended to the ciphertext.
+func (mc *messageCipher) seal(msg *amqp.Publishing) error {
+	aead := mc.keys[mc.activeKeyID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	msg.Body = aead.Seal(nonce, nonce, msg.Body, []byte(mc.activeKeyID))
+	msg.ContentEncoding = encryptedContentEncoding
+	if msg.Headers == nil {
+		msg.Headers = amqp.Table{}
+	}
+	msg.Headers[encryptionKeyHeader] = mc.activeKeyID
+	return nil
+}
+
+func (mc *messageCipher) open(msg amqp.Delivery) ([]byte, error) {
+	keyID, _ := msg.Headers[encryptionKeyHeader].(string)
+	aead, ok := mc.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEncryptionKey, keyID)
+	}
+
+	if len(msg.Body) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted message is too short")
+	}
+	nonce, ciphertext := msg.Body[:aead.NonceSize()], msg.Body[aead.NonceSize():]
+	body, err := aead.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt message: %w", err)
+	}
+	return body, nil
+}
+
+// MessageBody returns the plaintext body of a delivery. Messages published
+// without encryption pass through, so encryption can be enabled while older
+// messages are still queued.
+func (r *RabbitMQClient) MessageBody(msg amqp.Delivery) ([]byte, error) {
+	if msg.ContentEncoding != encryptedContentEncoding {
+		return msg.Body, nil
+	}
+	if r.cipher == nil {
+		return nil, fmt.Errorf("received an encrypted message but queue encryption is disabled")
+	}
+	return r.cipher.open(msg)
+}
+
+func (r *RabbitMQClient) encryptsEvent(eventType string) bool {
+	if r.cipher == nil {
+		return false
+	}
+	for _, encrypted := range r.config.Encryption.Events {
+		if encrypted == eventType {
+			return true
+		}
+	}
+	return false
+}
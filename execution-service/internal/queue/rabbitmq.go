@@ -4,8 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"execution_service/internal/config"
 	"execution_service/internal/models"
@@ -13,11 +14,26 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// EventRecorder persists an event for the event log relay, which publishes
+// recorded events in sequence order.
+type EventRecorder func(ctx context.Context, event *models.EventMessage, routingKey string) error
+
 type RabbitMQClient struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	queue   amqp.Queue
-	config  *config.RabbitMQConfig
+	// connMu guards the connection, channel and queues, which reconnect
+	// replaces
+	connMu       sync.RWMutex
+	conn         *amqp.Connection
+	channel      *amqp.Channel
+	queue        amqp.Queue
+	contestQueue amqp.Queue
+
+	config        *config.RabbitMQConfig
+	eventRecorder EventRecorder
+	cipher        *messageCipher
+
+	reconnectMu        sync.Mutex
+	reconnected        chan struct{}
+	reconnectListeners []func()
 }
 
 func NewRabbitMQClient(cfg *config.RabbitMQConfig) (*RabbitMQClient, error) {
@@ -40,44 +56,36 @@ func NewRabbitMQClient(cfg *config.RabbitMQConfig) (*RabbitMQClient, error) {
 		return nil, fmt.Errorf("failed to set QoS: %w", err)
 	}
 
-	queue, err := ch.QueueDeclare(
-		cfg.QueueName,
-		true,
-		false,
-		false,
-		false,
-		amqp.Table{
-			"x-max-priority":         10,
-			"x-dead-letter-exchange": "judge.failed",
-			"x-message-ttl":          300000,
-		},
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to declare queue: %w", err)
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
 	}
 
-	err = ch.ExchangeDeclare(
-		"codehakam.events",
-		"topic",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
+	queue, contestQueue, err := declareTopology(ch, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to declare exchange: %w", err)
+		return nil, err
+	}
+
+	client := &RabbitMQClient{
+		conn:         conn,
+		channel:      ch,
+		queue:        queue,
+		contestQueue: contestQueue,
+		config:       cfg,
+		reconnected:  make(chan struct{}),
+	}
+	if cfg.Encryption.Enabled {
+		client.cipher, err = newMessageCipher(&cfg.Encryption)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return &RabbitMQClient{
-		conn:    conn,
-		channel: ch,
-		queue:   queue,
-		config:  cfg,
-	}, nil
+	return client, nil
 }
 
 func (r *RabbitMQClient) Close() error {
+	r.connMu.Lock()
+	defer r.connMu.Unlock()
 	if r.channel != nil {
 		r.channel.Close()
 	}
@@ -87,98 +95,383 @@ func (r *RabbitMQClient) Close() error {
 	return nil
 }
 
+// publishConfirmTimeout bounds the wait for the broker to confirm a publish.
+const publishConfirmTimeout = 10 * time.Second
+
+const (
+	// attemptsHeader carries how many times a judge request has already failed.
+	attemptsHeader = "x-judge-attempts"
+	// firstFailedHeader carries when the request first failed.
+	firstFailedHeader = "x-judge-first-failed"
+	// failuresHeader carries the most recent failure messages, oldest first.
+	failuresHeader = "x-judge-failures"
+	// maxFailureHistory and maxFailureLength bound the errors a retried
+	// request carries
+	maxFailureHistory = 10
+	maxFailureLength  = 500
+)
+
+// FailureHistory records the failed attempts at processing a judge request.
+// Errors holds the most recent ones, oldest first.
+type FailureHistory struct {
+	Attempts    int
+	FirstFailed time.Time
+	Errors      []string
+}
+
+// Record adds a failed attempt.
+func (h *FailureHistory) Record(err error, at time.Time) {
+	h.Attempts++
+	if h.FirstFailed.IsZero() {
+		h.FirstFailed = at
+	}
+	message := err.Error()
+	if len(message) > maxFailureLength {
+		// Cut on a rune boundary so the header stays valid UTF-8
+		cut := maxFailureLength
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		message = message[:cut]
+	}
+	h.Errors = append(h.Errors, message)
+	if len(h.Errors) > maxFailureHistory {
+		h.Errors = h.Errors[len(h.Errors)-maxFailureHistory:]
+	}
+}
+
 func (r *RabbitMQClient) PublishSubmission(ctx context.Context, request *models.JudgeRequest) error {
+	return r.publishSubmission(ctx, request, nil)
+}
+
+// RetrySubmission publishes a request that failed to process again, carrying
+// its failure history for DeliveryFailures.
+func (r *RabbitMQClient) RetrySubmission(ctx context.Context, request *models.JudgeRequest, history FailureHistory) error {
+	failures := make([]any, len(history.Errors))
+	for i, message := range history.Errors {
+		failures[i] = message
+	}
+	return r.publishSubmission(ctx, request, amqp.Table{
+		attemptsHeader:    int32(history.Attempts),
+		firstFailedHeader: history.FirstFailed,
+		failuresHeader:    failures,
+	})
+}
+
+// DeliveryFailures reads the failure history a retried message carries. A
+// redelivered message without one, such as one a crashed worker never
+// acknowledged, counts as a single failure.
+func DeliveryFailures(msg amqp.Delivery) FailureHistory {
+	var history FailureHistory
+	switch attempts := msg.Headers[attemptsHeader].(type) {
+	case int32:
+		history.Attempts = int(attempts)
+	case int64:
+		history.Attempts = int(attempts)
+	default:
+		if msg.Redelivered {
+			history.Attempts = 1
+		}
+		return history
+	}
+	if firstFailed, ok := msg.Headers[firstFailedHeader].(time.Time); ok {
+		history.FirstFailed = firstFailed
+	}
+	if failures, ok := msg.Headers[failuresHeader].([]any); ok {
+		for _, failure := range failures {
+			if message, ok := failure.(string); ok {
+				history.Errors = append(history.Errors, message)
+			}
+		}
+	}
+	return history
+}
+
+func (r *RabbitMQClient) publishSubmission(ctx context.Context, request *models.JudgeRequest, headers amqp.Table) error {
 	body, err := json.Marshal(request)
 	if err != nil {
 		return fmt.Errorf("failed to marshal judge request: %w", err)
 	}
 
-	err = r.channel.PublishWithContext(
-		ctx,
-		"",
-		r.queue.Name,
+	msg := amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Headers:      headers,
+		Body:         body,
+		Priority:     uint8(request.Priority),
+		Timestamp:    time.Now(),
+	}
+	if r.cipher != nil {
+		if err := r.cipher.seal(&msg); err != nil {
+			return fmt.Errorf("failed to encrypt judge request: %w", err)
+		}
+	}
+
+	// Contest submissions take their own lane, so a practice backlog never
+	// sits in front of them
+	queueName, contestQueueName := r.queueNames()
+	if request.ContestID != nil {
+		queueName = contestQueueName
+	}
+
+	if err := r.publishConfirmed(ctx, "", queueName, msg); err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	return nil
+}
+
+// publishConfirmed publishes msg to the exchange, or to the queue named by
+// key on the default exchange, and waits until the broker confirms it has
+// taken responsibility for it.
+func (r *RabbitMQClient) publishConfirmed(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	confirmation, err := r.currentChannel().PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, msg)
+	if err != nil {
+		return err
+	}
+	// A channel that dies before confirming never answers
+	confirmCtx, cancel := context.WithTimeout(ctx, publishConfirmTimeout)
+	defer cancel()
+	acked, err := confirmation.WaitContext(confirmCtx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for publish confirmation: %w", err)
+	}
+	if !acked {
+		return fmt.Errorf("broker rejected the message")
+	}
+	return nil
+}
+
+// declareTopology declares the practice and contest judge queues, the
+// events exchange and the cache broadcast exchange. Declarations are idempotent, so it is safe on every start
+// and reconnect.
+func declareTopology(ch *amqp.Channel, cfg *config.RabbitMQConfig) (queue, contestQueue amqp.Queue, err error) {
+	queue, err = declareJudgeQueue(ch, cfg.QueueName, cfg)
+	if err != nil {
+		return amqp.Queue{}, amqp.Queue{}, err
+	}
+	contestQueue, err = declareJudgeQueue(ch, cfg.ContestQueueName, cfg)
+	if err != nil {
+		return amqp.Queue{}, amqp.Queue{}, err
+	}
+
+	err = ch.ExchangeDeclare(
+		cfg.EventsExchange,
+		"topic",
+		true,
 		false,
 		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-			Priority:    uint8(request.Priority),
-			Timestamp:   time.Now(),
-		},
+		false,
+		nil,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to publish message: %w", err)
+		return amqp.Queue{}, amqp.Queue{}, fmt.Errorf("failed to declare exchange: %w", err)
 	}
 
-	return nil
+	err = ch.ExchangeDeclare(cfg.CacheExchange, "fanout", true, false, false, false, nil)
+	if err != nil {
+		return amqp.Queue{}, amqp.Queue{}, fmt.Errorf("failed to declare cache exchange: %w", err)
+	}
+
+	return queue, contestQueue, nil
+}
+
+func declareJudgeQueue(ch *amqp.Channel, name string, cfg *config.RabbitMQConfig) (amqp.Queue, error) {
+	queue, err := ch.QueueDeclare(
+		name,
+		true,
+		false,
+		false,
+		false,
+		amqp.Table{
+			"x-max-priority":         10,
+			"x-dead-letter-exchange": cfg.DeadLetterExchange,
+			"x-message-ttl":          300000,
+		},
+	)
+	if err != nil {
+		return amqp.Queue{}, fmt.Errorf("failed to declare queue %s: %w", name, err)
+	}
+	return queue, nil
 }
 
 func (r *RabbitMQClient) PublishEvent(ctx context.Context, eventType string, data any) error {
+	payload, err := eventData(data)
+	if err != nil {
+		return err
+	}
+
 	event := models.EventMessage{
 		EventType: eventType,
-		Data:      make(map[string]any),
+		Data:      payload,
 		Timestamp: time.Now(),
 	}
 
-	switch v := data.(type) {
-	case *models.JudgeResult:
-		event.Data["submission_id"] = v.SubmissionID
-		event.Data["verdict"] = v.Verdict
-		event.Data["execution_time_ms"] = v.ExecutionTimeMs
-		event.Data["memory_used_kb"] = v.MemoryUsedKb
-		event.Data["test_cases_passed"] = v.TestCasesPassed
-		event.Data["test_cases_total"] = v.TestCasesTotal
-	case map[string]any:
-		event.Data = v
-	default:
-		return fmt.Errorf("unsupported event data type")
+	routingKey := renderRoutingKey(r.routingKeyTemplate(eventType), eventType, payload)
+
+	if r.eventRecorder == nil {
+		return r.publishEvent(ctx, &event, routingKey)
+	}
+
+	// The event log relay publishes the event once it is recorded
+	if err := r.eventRecorder(ctx, &event, routingKey); err != nil {
+		return fmt.Errorf("failed to record %s event: %w", eventType, err)
+	}
+
+	return nil
+}
+
+// PublishLoggedEvent publishes an event recorded in the event log with its
+// sequence number and returns once the broker has confirmed it.
+func (r *RabbitMQClient) PublishLoggedEvent(ctx context.Context, entry *models.EventLogEntry) error {
+	var data map[string]any
+	if err := json.Unmarshal(entry.Data, &data); err != nil {
+		return fmt.Errorf("failed to unmarshal event data: %w", err)
 	}
 
+	event := models.EventMessage{
+		Sequence:  entry.Seq,
+		EventType: entry.EventType,
+		Data:      data,
+		Timestamp: entry.CreatedAt,
+	}
+	return r.publishEvent(ctx, &event, entry.RoutingKey)
+}
+
+func (r *RabbitMQClient) publishEvent(ctx context.Context, event *models.EventMessage, routingKey string) error {
 	body, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	routingKey := fmt.Sprintf("submission.%s", eventType)
-	if eventType == "SubmissionJudged" {
-		routingKey = "submission.judged"
+	msg := amqp.Publishing{
+		ContentType: "application/json",
+		Body:        body,
+		Timestamp:   time.Now(),
+	}
+	if r.encryptsEvent(event.EventType) {
+		if err := r.cipher.seal(&msg); err != nil {
+			return fmt.Errorf("failed to encrypt event: %w", err)
+		}
 	}
 
-	err = r.channel.PublishWithContext(
-		ctx,
-		"codehakam.events",
-		routingKey,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-			Timestamp:   time.Now(),
-		},
-	)
-	if err != nil {
+	if err := r.publishConfirmed(ctx, r.config.EventsExchange, routingKey, msg); err != nil {
 		return fmt.Errorf("failed to publish event: %w", err)
 	}
 
 	return nil
 }
 
+func (r *RabbitMQClient) SetEventRecorder(recorder EventRecorder) {
+	r.eventRecorder = recorder
+}
+
 func (r *RabbitMQClient) ConsumeSubmissions(ctx context.Context) (<-chan amqp.Delivery, error) {
-	msgs, err := r.channel.ConsumeWithContext(
-		ctx,
-		r.queue.Name,
-		"judge-worker",
-		false,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to register consumer: %w", err)
+	queueName, _ := r.queueNames()
+	return r.consumeJudgeQueue(ctx, queueName, "judge-worker")
+}
+
+// ConsumeContestSubmissions consumes the contest lane.
+func (r *RabbitMQClient) ConsumeContestSubmissions(ctx context.Context) (<-chan amqp.Delivery, error) {
+	_, contestQueueName := r.queueNames()
+	return r.consumeJudgeQueue(ctx, contestQueueName, "judge-contest-worker")
+}
+
+func (r *RabbitMQClient) consumeJudgeQueue(ctx context.Context, queueName, consumer string) (<-chan amqp.Delivery, error) {
+	return r.keepConsuming(ctx, func() (<-chan amqp.Delivery, error) {
+		msgs, err := r.currentChannel().ConsumeWithContext(
+			ctx,
+			queueName,
+			consumer,
+			false,
+			false,
+			false,
+			false,
+			nil,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register consumer: %w", err)
+		}
+
+		return msgs, nil
+	})
+}
+
+// ConsumeEvents declares a durable queue bound to the events exchange for the
+// given routing keys and consumes from it.
+func (r *RabbitMQClient) ConsumeEvents(ctx context.Context, queueName string, routingKeys ...string) (<-chan amqp.Delivery, error) {
+	return r.keepConsuming(ctx, func() (<-chan amqp.Delivery, error) {
+		ch := r.currentChannel()
+		queue, err := ch.QueueDeclare(queueName, true, false, false, false, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to declare queue %s: %w", queueName, err)
+		}
+
+		for _, key := range routingKeys {
+			if err := ch.QueueBind(queue.Name, key, r.config.EventsExchange, false, nil); err != nil {
+				return nil, fmt.Errorf("failed to bind %s: %w", key, err)
+			}
+		}
+
+		msgs, err := ch.ConsumeWithContext(ctx, queue.Name, "", false, false, false, false, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register consumer on %s: %w", queueName, err)
+		}
+
+		return msgs, nil
+	})
+}
+
+// PublishBroadcast sends a message to every node through the cache
+// exchange.
+func (r *RabbitMQClient) PublishBroadcast(ctx context.Context, body []byte) error {
+	msg := amqp.Publishing{
+		ContentType: "application/json",
+		Body:        body,
+		Timestamp:   time.Now(),
+	}
+	if r.cipher != nil {
+		if err := r.cipher.seal(&msg); err != nil {
+			return fmt.Errorf("failed to encrypt broadcast: %w", err)
+		}
 	}
 
-	return msgs, nil
+	if err := r.currentChannel().PublishWithContext(ctx, r.config.CacheExchange, "", false, false, msg); err != nil {
+		return fmt.Errorf("failed to publish broadcast: %w", err)
+	}
+	return nil
+}
+
+// ConsumeBroadcast consumes the cache exchange through a queue of this
+// node's own, which is removed when the node disconnects. A new queue is
+// declared after each reconnect.
+func (r *RabbitMQClient) ConsumeBroadcast(ctx context.Context) (<-chan amqp.Delivery, error) {
+	return r.keepConsuming(ctx, func() (<-chan amqp.Delivery, error) {
+		ch := r.currentChannel()
+		queue, err := ch.QueueDeclare("", false, true, true, false, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to declare broadcast queue: %w", err)
+		}
+		if err := ch.QueueBind(queue.Name, "", r.config.CacheExchange, false, nil); err != nil {
+			return nil, fmt.Errorf("failed to bind broadcast queue: %w", err)
+		}
+
+		msgs, err := ch.ConsumeWithContext(ctx, queue.Name, "", false, true, false, false, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to register broadcast consumer: %w", err)
+		}
+		return msgs, nil
+	})
+}
+
+// SetPrefetch changes the per-consumer prefetch for consumers registered
+// afterwards, including those re-registered after a reconnect.
+func (r *RabbitMQClient) SetPrefetch(count int) error {
+	if err := r.currentChannel().Qos(count, 0, false); err != nil {
+		return fmt.Errorf("failed to set QoS: %w", err)
+	}
+	r.config.PrefetchCount = count
+	return nil
 }
 
 func (r *RabbitMQClient) AcknowledgeMessage(msg amqp.Delivery) error {
@@ -189,33 +482,43 @@ func (r *RabbitMQClient) RejectMessage(msg amqp.Delivery, requeue bool) error {
 	return msg.Nack(false, requeue)
 }
 
+// GetQueueInfo returns the number of submissions waiting in both lanes.
 func (r *RabbitMQClient) GetQueueInfo() (int, error) {
-	queue, err := r.channel.QueueDeclarePassive(
-		r.queue.Name,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		return 0, fmt.Errorf("failed to inspect queue: %w", err)
+	ch := r.currentChannel()
+	queueName, contestQueueName := r.queueNames()
+	total := 0
+	for _, name := range []string{queueName, contestQueueName} {
+		queue, err := ch.QueueDeclarePassive(
+			name,
+			true,
+			false,
+			false,
+			false,
+			nil,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to inspect queue: %w", err)
+		}
+		total += queue.Messages
 	}
 
-	return queue.Messages, nil
+	return total, nil
 }
 
 func (r *RabbitMQClient) PurgeQueue() error {
-	_, err := r.channel.QueuePurge(r.queue.Name, false)
-	if err != nil {
-		return fmt.Errorf("failed to purge queue: %w", err)
+	ch := r.currentChannel()
+	queueName, contestQueueName := r.queueNames()
+	for _, name := range []string{queueName, contestQueueName} {
+		if _, err := ch.QueuePurge(name, false); err != nil {
+			return fmt.Errorf("failed to purge queue: %w", err)
+		}
 	}
 
 	return nil
 }
 
 func (r *RabbitMQClient) PurgeQueueByName(queueName string) error {
-	_, err := r.channel.QueuePurge(queueName, false)
+	_, err := r.currentChannel().QueuePurge(queueName, false)
 	if err != nil {
 		return fmt.Errorf("failed to purge queue %s: %w", queueName, err)
 	}
@@ -224,20 +527,24 @@ func (r *RabbitMQClient) PurgeQueueByName(queueName string) error {
 }
 
 func (r *RabbitMQClient) IsHealthy() bool {
-	if r.conn == nil || r.conn.IsClosed() {
+	r.connMu.RLock()
+	conn, ch, queueName := r.conn, r.channel, r.queue.Name
+	r.connMu.RUnlock()
+
+	if conn == nil || conn.IsClosed() {
 		return false
 	}
-	if r.channel == nil || r.channel.IsClosed() {
+	if ch == nil || ch.IsClosed() {
 		return false
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := r.channel.PublishWithContext(
+	err := ch.PublishWithContext(
 		ctx,
 		"",
-		r.queue.Name,
+		queueName,
 		false,
 		false,
 		amqp.Publishing{
@@ -251,90 +558,23 @@ func (r *RabbitMQClient) IsHealthy() bool {
 	return err == nil
 }
 
-func ParseJudgeRequest(msg amqp.Delivery) (*models.JudgeRequest, error) {
-	var request models.JudgeRequest
-	err := json.Unmarshal(msg.Body, &request)
+func (r *RabbitMQClient) ParseJudgeRequest(msg amqp.Delivery) (*models.JudgeRequest, error) {
+	body, err := r.MessageBody(msg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal judge request: %w", err)
+		return nil, err
 	}
-	return &request, nil
-}
-
-func (r *RabbitMQClient) StartHeartbeat() {
-	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
 
-		for range ticker.C {
-			if r.conn == nil || r.conn.IsClosed() {
-				log.Printf("RabbitMQ connection lost, attempting to reconnect...")
-				if err := r.reconnect(); err != nil {
-					log.Printf("Failed to reconnect to RabbitMQ: %v", err)
-				}
-			}
-		}
-	}()
-}
-
-func (r *RabbitMQClient) reconnect() error {
-	conn, err := amqp.Dial(r.config.URL)
-	if err != nil {
-		return fmt.Errorf("failed to reconnect to RabbitMQ: %w", err)
-	}
-
-	ch, err := conn.Channel()
-	if err != nil {
-		conn.Close()
-		return fmt.Errorf("failed to open channel on reconnect: %w", err)
-	}
-
-	err = ch.Qos(
-		r.config.PrefetchCount,
-		0,
-		false,
-	)
-	if err != nil {
-		ch.Close()
-		conn.Close()
-		return fmt.Errorf("failed to set QoS on reconnect: %w", err)
-	}
-
-	queue, err := ch.QueueDeclare(
-		r.config.QueueName,
-		true,
-		false,
-		false,
-		false,
-		amqp.Table{
-			"x-max-priority":         10,
-			"x-dead-letter-exchange": "judge.failed",
-			"x-message-ttl":          300000,
-		},
-	)
+	var request models.JudgeRequest
+	err = json.Unmarshal(body, &request)
 	if err != nil {
-		ch.Close()
-		conn.Close()
-		return fmt.Errorf("failed to declare queue on reconnect: %w", err)
-	}
-
-	if r.conn != nil {
-		r.conn.Close()
-	}
-	if r.channel != nil {
-		r.channel.Close()
+		return nil, fmt.Errorf("failed to unmarshal judge request: %w", err)
 	}
-
-	r.conn = conn
-	r.channel = ch
-	r.queue = queue
-
-	log.Printf("Successfully reconnected to RabbitMQ")
-	return nil
+	return &request, nil
 }
 
 // Additional methods for dead letter queue and retry queue management
 func (r *RabbitMQClient) DeclareExchange(ctx context.Context, name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
-	return r.channel.ExchangeDeclare(
+	return r.currentChannel().ExchangeDeclare(
 		name,
 		kind,
 		durable,
@@ -346,7 +586,7 @@ func (r *RabbitMQClient) DeclareExchange(ctx context.Context, name, kind string,
 }
 
 func (r *RabbitMQClient) DeclareQueue(ctx context.Context, name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
-	return r.channel.QueueDeclare(
+	return r.currentChannel().QueueDeclare(
 		name,
 		durable,
 		autoDelete,
@@ -357,7 +597,7 @@ func (r *RabbitMQClient) DeclareQueue(ctx context.Context, name string, durable,
 }
 
 func (r *RabbitMQClient) BindQueue(ctx context.Context, queueName, exchangeName, routingKey string) error {
-	return r.channel.QueueBind(
+	return r.currentChannel().QueueBind(
 		queueName,
 		exchangeName,
 		routingKey,
@@ -367,34 +607,48 @@ func (r *RabbitMQClient) BindQueue(ctx context.Context, queueName, exchangeName,
 }
 
 func (r *RabbitMQClient) ConsumeFromQueue(ctx context.Context, queueName, consumer string) (<-chan amqp.Delivery, error) {
-	return r.channel.Consume(
-		queueName,
-		consumer,
-		false,
-		false,
-		false,
-		false,
-		nil,
-	)
+	return r.keepConsuming(ctx, func() (<-chan amqp.Delivery, error) {
+		return r.currentChannel().ConsumeWithContext(
+			ctx,
+			queueName,
+			consumer,
+			false,
+			false,
+			false,
+			false,
+			nil,
+		)
+	})
+}
+
+// GetFromQueue fetches one message without consuming the queue; ok is false
+// when the queue is empty. The caller must acknowledge or reject it.
+func (r *RabbitMQClient) GetFromQueue(ctx context.Context, queueName string) (msg amqp.Delivery, ok bool, err error) {
+	msg, ok, err = r.currentChannel().Get(queueName, false)
+	if err != nil {
+		return msg, false, fmt.Errorf("failed to get from queue %s: %w", queueName, err)
+	}
+	return msg, ok, nil
 }
 
 func (r *RabbitMQClient) PublishToQueue(ctx context.Context, queueName string, body []byte) error {
-	return r.channel.PublishWithContext(
-		ctx,
-		"",
-		queueName,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-			Timestamp:   time.Now(),
-		},
-	)
+	msg := amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Body:         body,
+		Timestamp:    time.Now(),
+	}
+	if r.cipher != nil {
+		if err := r.cipher.seal(&msg); err != nil {
+			return fmt.Errorf("failed to encrypt message: %w", err)
+		}
+	}
+
+	return r.publishConfirmed(ctx, "", queueName, msg)
 }
 
 func (r *RabbitMQClient) GetQueueSize(ctx context.Context, queueName string) (int, error) {
-	queue, err := r.channel.QueueDeclarePassive(
+	queue, err := r.currentChannel().QueueDeclarePassive(
 		queueName,
 		true,
 		false,
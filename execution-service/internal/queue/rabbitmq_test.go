@@ -0,0 +1,147 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestFailureHistoryRecord(t *testing.T) {
+	first := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var history FailureHistory
+	history.Record(errors.New("content service unavailable"), first)
+	history.Record(errors.New("sandbox busy"), first.Add(time.Minute))
+
+	if history.Attempts != 2 {
+		t.Errorf("Attempts = %d, want 2", history.Attempts)
+	}
+	if !history.FirstFailed.Equal(first) {
+		t.Errorf("FirstFailed = %v, want %v", history.FirstFailed, first)
+	}
+	want := []string{"content service unavailable", "sandbox busy"}
+	if strings.Join(history.Errors, "|") != strings.Join(want, "|") {
+		t.Errorf("Errors = %q, want %q", history.Errors, want)
+	}
+}
+
+func TestFailureHistoryRecordKeepsRecentErrors(t *testing.T) {
+	var history FailureHistory
+	for i := 0; i < maxFailureHistory+3; i++ {
+		history.Record(fmt.Errorf("failure %d", i), time.Now())
+	}
+
+	if history.Attempts != maxFailureHistory+3 {
+		t.Errorf("Attempts = %d, want %d", history.Attempts, maxFailureHistory+3)
+	}
+	if len(history.Errors) != maxFailureHistory {
+		t.Fatalf("len(Errors) = %d, want %d", len(history.Errors), maxFailureHistory)
+	}
+	if history.Errors[0] != "failure 3" || history.Errors[maxFailureHistory-1] != fmt.Sprintf("failure %d", maxFailureHistory+2) {
+		t.Errorf("Errors = %q, want failures 3 through %d", history.Errors, maxFailureHistory+2)
+	}
+}
+
+func TestFailureHistoryRecordTruncates(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		wantLen int
+	}{
+		{"short message is kept", "boom", 4},
+		{"ascii is cut at the limit", strings.Repeat("a", maxFailureLength+10), maxFailureLength},
+		// "é" is two bytes, so the limit falls inside the last rune
+		{"multibyte rune is not split", "a" + strings.Repeat("é", maxFailureLength/2), maxFailureLength - 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var history FailureHistory
+			history.Record(errors.New(tt.message), time.Now())
+
+			got := history.Errors[0]
+			if len(got) != tt.wantLen {
+				t.Errorf("len = %d, want %d", len(got), tt.wantLen)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncated message is not valid UTF-8: %q", got)
+			}
+		})
+	}
+}
+
+func TestDeliveryFailures(t *testing.T) {
+	firstFailed := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		msg             amqp.Delivery
+		wantAttempts    int
+		wantFirstFailed time.Time
+		wantErrors      []string
+	}{
+		{
+			name: "first delivery",
+			msg:  amqp.Delivery{},
+		},
+		{
+			name:         "redelivered without history",
+			msg:          amqp.Delivery{Redelivered: true},
+			wantAttempts: 1,
+		},
+		{
+			name: "retried with history",
+			msg: amqp.Delivery{Headers: amqp.Table{
+				attemptsHeader:    int32(2),
+				firstFailedHeader: firstFailed,
+				failuresHeader:    []any{"first", "second"},
+			}},
+			wantAttempts:    2,
+			wantFirstFailed: firstFailed,
+			wantErrors:      []string{"first", "second"},
+		},
+		{
+			name: "attempts as int64",
+			msg: amqp.Delivery{Headers: amqp.Table{
+				attemptsHeader: int64(4),
+			}},
+			wantAttempts: 4,
+		},
+		{
+			name: "non-string failures are skipped",
+			msg: amqp.Delivery{Headers: amqp.Table{
+				attemptsHeader: int32(1),
+				failuresHeader: []any{"kept", int32(7)},
+			}},
+			wantAttempts: 1,
+			wantErrors:   []string{"kept"},
+		},
+		{
+			name: "unreadable attempts on a redelivery",
+			msg: amqp.Delivery{Redelivered: true, Headers: amqp.Table{
+				attemptsHeader: "three",
+			}},
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := DeliveryFailures(tt.msg)
+			if history.Attempts != tt.wantAttempts {
+				t.Errorf("Attempts = %d, want %d", history.Attempts, tt.wantAttempts)
+			}
+			if !history.FirstFailed.Equal(tt.wantFirstFailed) {
+				t.Errorf("FirstFailed = %v, want %v", history.FirstFailed, tt.wantFirstFailed)
+			}
+			if strings.Join(history.Errors, "|") != strings.Join(tt.wantErrors, "|") {
+				t.Errorf("Errors = %q, want %q", history.Errors, tt.wantErrors)
+			}
+		})
+	}
+}
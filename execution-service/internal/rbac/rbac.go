@@ -1,11 +1,14 @@
 package rbac
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
+	"time"
 
+	"execution_service/internal/cache"
 	"execution_service/internal/database"
 
 	"github.com/casbin/casbin/v2"
@@ -13,9 +16,15 @@ import (
 	_ "github.com/lib/pq"
 )
 
+// DecisionObserver receives the outcome of every permission or role check.
+type DecisionObserver func(check string, cached bool, duration time.Duration)
+
 type RBACService struct {
 	enforcer *casbin.Enforcer
 	db       *database.DB
+	cache    *cache.ValkeyClient
+	cacheTTL time.Duration
+	observer DecisionObserver
 }
 
 type Permission struct {
@@ -57,33 +66,111 @@ func NewRBACService(databaseURL string, db *database.DB) (*RBACService, error) {
 	return rbac, nil
 }
 
+// SetCache enables caching of enforcement decisions in Valkey. Cached
+// decisions are keyed by a policy generation that every mutation bumps, so
+// role and permission changes take effect immediately.
+func (r *RBACService) SetCache(valkey *cache.ValkeyClient, ttl time.Duration) {
+	r.cache = valkey
+	r.cacheTTL = ttl
+}
+
+func (r *RBACService) SetObserver(observer DecisionObserver) {
+	r.observer = observer
+}
+
 func (r *RBACService) CheckPermission(userID int64, resource, action string) (bool, error) {
-	userIDStr := strconv.FormatInt(userID, 10)
+	return r.decide(userID, fmt.Sprintf("perm:%s:%s", resource, action), func() (bool, error) {
+		userIDStr := strconv.FormatInt(userID, 10)
 
-	// Check direct permission
-	allowed, err := r.enforcer.Enforce(userIDStr, resource, action)
-	if err != nil {
-		return false, fmt.Errorf("failed to check permission: %w", err)
-	}
+		// Check direct permission
+		allowed, err := r.enforcer.Enforce(userIDStr, resource, action)
+		if err != nil {
+			return false, fmt.Errorf("failed to check permission: %w", err)
+		}
 
-	return allowed, nil
+		return allowed, nil
+	})
 }
 
 func (r *RBACService) HasRole(userID int64, role string) (bool, error) {
-	userIDStr := strconv.FormatInt(userID, 10)
+	return r.decide(userID, "role:"+role, func() (bool, error) {
+		userIDStr := strconv.FormatInt(userID, 10)
 
-	roles, err := r.enforcer.GetRolesForUser(userIDStr)
-	if err != nil {
-		return false, fmt.Errorf("failed to get user roles: %w", err)
+		roles, err := r.enforcer.GetRolesForUser(userIDStr)
+		if err != nil {
+			return false, fmt.Errorf("failed to get user roles: %w", err)
+		}
+
+		for _, userRole := range roles {
+			if userRole == role {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+}
+
+func (r *RBACService) decide(userID int64, check string, enforce func() (bool, error)) (bool, error) {
+	kind := strings.SplitN(check, ":", 2)[0]
+
+	if r.cache == nil {
+		start := time.Now()
+		allowed, err := enforce()
+		r.observe(kind, false, time.Since(start))
+		return allowed, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	generation, err := r.cache.GetRBACGeneration(ctx)
+	if err == nil {
+		if allowed, err := r.cache.GetCachedRBACDecision(ctx, generation, userID, check); err == nil {
+			r.observe(kind, true, 0)
+			return allowed, nil
+		}
+	}
+
+	start := time.Now()
+	allowed, enforceErr := enforce()
+	r.observe(kind, false, time.Since(start))
+	if enforceErr != nil {
+		return false, enforceErr
 	}
 
-	for _, userRole := range roles {
-		if userRole == role {
-			return true, nil
+	if err == nil {
+		if err := r.cache.CacheRBACDecision(ctx, generation, userID, check, allowed, r.cacheTTL); err != nil {
+			log.Printf("Failed to cache RBAC decision: %v", err)
 		}
 	}
 
-	return false, nil
+	return allowed, nil
+}
+
+func (r *RBACService) observe(kind string, cached bool, duration time.Duration) {
+	if r.observer != nil {
+		r.observer(kind, cached, duration)
+	}
+}
+
+func (r *RBACService) invalidateDecisions() {
+	if r.cache == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := r.cache.BumpRBACGeneration(ctx); err != nil {
+		log.Printf("Failed to invalidate RBAC decision cache: %v", err)
+	}
+}
+
+// savePolicy persists the enforcer state and drops every cached decision.
+func (r *RBACService) savePolicy() error {
+	defer r.invalidateDecisions()
+	return r.enforcer.SavePolicy()
 }
 
 func (r *RBACService) AssignRole(userID int64, role string) error {
@@ -94,7 +181,7 @@ func (r *RBACService) AssignRole(userID int64, role string) error {
 		return fmt.Errorf("failed to assign role: %w", err)
 	}
 
-	return r.enforcer.SavePolicy()
+	return r.savePolicy()
 }
 
 func (r *RBACService) RemoveRole(userID int64, role string) error {
@@ -105,7 +192,7 @@ func (r *RBACService) RemoveRole(userID int64, role string) error {
 		return fmt.Errorf("failed to remove role: %w", err)
 	}
 
-	return r.enforcer.SavePolicy()
+	return r.savePolicy()
 }
 
 func (r *RBACService) AddPermission(role, resource, action string) error {
@@ -114,7 +201,7 @@ func (r *RBACService) AddPermission(role, resource, action string) error {
 		return fmt.Errorf("failed to add permission: %w", err)
 	}
 
-	return r.enforcer.SavePolicy()
+	return r.savePolicy()
 }
 
 func (r *RBACService) RemovePermission(role, resource, action string) error {
@@ -123,7 +210,7 @@ func (r *RBACService) RemovePermission(role, resource, action string) error {
 		return fmt.Errorf("failed to remove permission: %w", err)
 	}
 
-	return r.enforcer.SavePolicy()
+	return r.savePolicy()
 }
 
 func (r *RBACService) GetUserRoles(userID int64) ([]string, error) {
@@ -210,6 +297,7 @@ func (r *RBACService) initializeDefaultRoles() error {
 			{Resource: "testcase", Action: "edit:own", Scope: "own"},
 			{Resource: "testcase", Action: "delete:own", Scope: "own"},
 			{Resource: "submission", Action: "rejudge:own", Scope: "own"},
+			{Resource: "submission", Action: "trusted", Scope: ""},
 		},
 		"moderator": {
 			{Resource: "submission", Action: "read:any", Scope: "any"},
@@ -221,6 +309,7 @@ func (r *RBACService) initializeDefaultRoles() error {
 			{Resource: "user", Action: "manage", Scope: ""},
 			{Resource: "problem", Action: "manage", Scope: ""},
 			{Resource: "submission", Action: "rejudge:any", Scope: "any"},
+			{Resource: "submission", Action: "trusted", Scope: ""},
 			{Resource: "contest", Action: "manage", Scope: ""},
 			{Resource: "system", Action: "configure", Scope: ""},
 			{Resource: "audit", Action: "view", Scope: ""},
@@ -272,7 +361,7 @@ func (r *RBACService) ValidateAction(resource, action string) bool {
 
 	if len(parts) == 1 {
 		// Simple action like "create", "read"
-		validActions := []string{"create", "read", "update", "delete", "manage"}
+		validActions := []string{"create", "read", "update", "delete", "manage", "trusted"}
 		for _, validAction := range validActions {
 			if parts[0] == validAction {
 				return true
@@ -321,7 +410,7 @@ func (r *RBACService) CreateCustomRole(name, description string, permissions []P
 		}
 	}
 
-	return r.enforcer.SavePolicy()
+	return r.savePolicy()
 }
 
 func (r *RBACService) DeleteRole(role string) error {
@@ -341,9 +430,32 @@ func (r *RBACService) DeleteRole(role string) error {
 		return fmt.Errorf("failed to delete role assignments: %w", err)
 	}
 
-	return r.enforcer.SavePolicy()
+	return r.savePolicy()
 }
 
 func (r *RBACService) RefreshPolicy() error {
+	defer r.invalidateDecisions()
+	return r.enforcer.LoadPolicy()
+}
+
+// ReloadPolicy reloads this node's enforcer, picking up changes saved
+// through another node, without dropping every cached decision.
+func (r *RBACService) ReloadPolicy() error {
 	return r.enforcer.LoadPolicy()
 }
+
+// InvalidateUser drops the user's cached decisions.
+func (r *RBACService) InvalidateUser(ctx context.Context, userID int64) error {
+	if r.cache == nil {
+		return nil
+	}
+
+	generation, err := r.cache.GetRBACGeneration(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.cache.InvalidateRBACDecisions(ctx, generation, userID); err != nil {
+		return fmt.Errorf("failed to invalidate rbac decisions: %w", err)
+	}
+	return nil
+}
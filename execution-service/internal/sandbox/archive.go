@@ -0,0 +1,191 @@
+package sandbox
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"execution_service/internal/models"
+)
+
+// ErrInvalidArchive means a project archive is malformed or breaks a limit.
+var ErrInvalidArchive = errors.New("invalid project archive")
+
+// ArchiveFile is one regular file of a project archive; Name is a clean
+// slash-separated path relative to the project root.
+type ArchiveFile struct {
+	Name string
+	Mode int64
+	Data []byte
+}
+
+// ReadArchive unpacks a gzipped tarball in memory. Only regular files and
+// directories are accepted, paths may not leave the root, and at most
+// maxFiles files totalling maxBytes bytes are read.
+func ReadArchive(archive []byte, maxFiles int, maxBytes int64) ([]ArchiveFile, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+
+	var files []ArchiveFile
+	var total int64
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if name == "." && header.Typeflag == tar.TypeDir {
+			continue
+		}
+		if name == "." || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("%w: unsafe path %q", ErrInvalidArchive, header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return nil, fmt.Errorf("%w: %s is not a regular file", ErrInvalidArchive, name)
+		}
+
+		if len(files) >= maxFiles {
+			return nil, fmt.Errorf("%w: more than %d files", ErrInvalidArchive, maxFiles)
+		}
+		total += header.Size
+		if total > maxBytes {
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrInvalidArchive, maxBytes)
+		}
+
+		data, err := io.ReadAll(io.LimitReader(tr, header.Size))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		files = append(files, ArchiveFile{Name: name, Mode: header.Mode, Data: data})
+	}
+
+	return files, nil
+}
+
+// ReadZip unpacks a zip archive in memory under the same rules as
+// ReadArchive.
+func ReadZip(archive []byte, maxFiles int, maxBytes int64) ([]ArchiveFile, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	var files []ArchiveFile
+	var total int64
+	for _, file := range zr.File {
+		name := path.Clean(strings.TrimPrefix(file.Name, "./"))
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if name == "." || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("%w: unsafe path %q", ErrInvalidArchive, file.Name)
+		}
+		if !file.Mode().IsRegular() {
+			return nil, fmt.Errorf("%w: %s is not a regular file", ErrInvalidArchive, name)
+		}
+
+		if len(files) >= maxFiles {
+			return nil, fmt.Errorf("%w: more than %d files", ErrInvalidArchive, maxFiles)
+		}
+		// The declared size can lie, so the read itself is bounded too
+		total += int64(file.UncompressedSize64)
+		if total > maxBytes {
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrInvalidArchive, maxBytes)
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, int64(file.UncompressedSize64)+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if int64(len(data)) > int64(file.UncompressedSize64) {
+			return nil, fmt.Errorf("%w: %s is larger than declared", ErrInvalidArchive, name)
+		}
+		files = append(files, ArchiveFile{Name: name, Mode: int64(file.Mode().Perm()), Data: data})
+	}
+
+	return files, nil
+}
+
+// CompileProjectWith builds a multi-file submission: the project files are
+// written to the box and entryFile, a file at the project root, is compiled
+// as the submission's source so its includes and imports resolve against
+// the rest of the project.
+func (i *IsolateSandbox) CompileProjectWith(ctx context.Context, env *models.SandboxEnvironment, language string, files []ArchiveFile, entryFile string, timeLimit time.Duration) (*CompileResult, error) {
+	var entry []byte
+	found := false
+	for _, file := range files {
+		if file.Name == entryFile {
+			entry, found = file.Data, true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: entry file %s not found", ErrInvalidArchive, entryFile)
+	}
+
+	boxID, err := i.AcquireBox()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create isolate box: %w", err)
+	}
+	defer i.ReleaseBox(boxID)
+
+	if err := writeFiles(i.GetBoxDir(boxID), files, false); err != nil {
+		return nil, fmt.Errorf("failed to write project files: %w", err)
+	}
+
+	result, err := i.compileInBox(ctx, boxID, env, language, entry, timeLimit)
+	if err != nil || !result.Success {
+		return result, err
+	}
+	return i.withArtifact(boxID, result)
+}
+
+// writeFiles places archive files under dir. Modes are only kept when asked,
+// so submitted projects cannot ship their own executables.
+func writeFiles(dir string, files []ArchiveFile, keepModes bool) error {
+	for _, file := range files {
+		target := filepath.Join(dir, filepath.FromSlash(file.Name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		mode := os.FileMode(0644)
+		if keepModes {
+			mode = os.FileMode(file.Mode) & 0755
+		}
+		if err := os.WriteFile(target, file.Data, mode); err != nil {
+			return err
+		}
+		if err := os.Chmod(target, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
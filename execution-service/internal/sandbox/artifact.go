@@ -0,0 +1,204 @@
+package sandbox
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"execution_service/internal/models"
+)
+
+// Artifacts are produced by our own builds, so these only guard against a
+// build that fills the box.
+const (
+	artifactMaxFiles = 10000
+	artifactMaxBytes = 256 << 20
+)
+
+// artifactScratch are the stage outputs left in the box, which are not part
+// of the built program.
+var artifactScratch = map[string]bool{"output.txt": true, "error.txt": true, "meta.txt": true}
+
+// ArtifactKey identifies a build: the same source built by the same
+// toolchain, pipeline and Java class mode gives the same program, so a
+// cached artifact under the key can stand in for compiling. It is empty
+// before the first environment snapshot.
+func (i *IsolateSandbox) ArtifactKey(env *models.SandboxEnvironment, language string, source []byte, entryFile string) string {
+	current := i.Environment()
+	if current == nil {
+		return ""
+	}
+
+	stages := i.compilePipeline(language)
+	javaClassMode := i.config.JavaClassMode
+	if pinned, ok := pinnedPipeline(env, language); ok {
+		stages = pinned
+	}
+	if env != nil && env.JavaClassMode != "" {
+		javaClassMode = env.JavaClassMode
+	}
+	pipeline, _ := json.Marshal(stages)
+	toolchain := current.Toolchains[language]
+	if env != nil {
+		toolchain = env.Toolchains[language]
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "language=%s\ntoolchain=%s\npipeline=%s\njava=%s\nentry=%s\n", language, toolchain, pipeline, javaClassMode, entryFile)
+	sourceSum := sha256.Sum256(source)
+	hash.Write(sourceSum[:])
+	return language + "/" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// withArtifact attaches what the build left in the box to a successful
+// result, so runs in other boxes start from the built program.
+func (i *IsolateSandbox) withArtifact(boxID int, result *CompileResult) (*CompileResult, error) {
+	artifact, err := packArtifact(i.GetBoxDir(boxID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to package build: %w", err)
+	}
+	result.Artifact = artifact
+	return result, nil
+}
+
+func packArtifact(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		if artifactScratch[name] {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{
+			Name:    name,
+			Mode:    int64(info.Mode().Perm()),
+			Size:    int64(len(data)),
+			ModTime: info.ModTime(),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// PrepareBuild extracts a build into its own work dir once per submission;
+// every test's box then gets a copy of the directory. Callers remove it with
+// ReleaseBuild once the submission's runs are done.
+func (i *IsolateSandbox) PrepareBuild(artifact []byte) (string, error) {
+	files, err := ReadArchive(artifact, artifactMaxFiles, artifactMaxBytes)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(i.config.WorkDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create work dir: %w", err)
+	}
+	dir, err := os.MkdirTemp(i.config.WorkDir, "build-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create build dir: %w", err)
+	}
+	if err := writeFiles(dir, files, true); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to extract build: %w", err)
+	}
+	return dir, nil
+}
+
+// ReleaseBuild removes a work dir created by PrepareBuild.
+func (i *IsolateSandbox) ReleaseBuild(dir string) {
+	if dir == "" {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		log.Printf("Failed to remove build dir %s: %v", dir, err)
+	}
+}
+
+// installBuild copies a prepared build into a box. Copies rather than links
+// keep one run from changing the program the next run starts from.
+func installBuild(boxDir, buildDir string) error {
+	return filepath.WalkDir(buildDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(buildDir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(boxDir, name)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(source, target string, mode os.FileMode) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(target, mode)
+}
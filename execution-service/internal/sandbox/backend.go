@@ -0,0 +1,109 @@
+package sandbox
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"execution_service/internal/config"
+)
+
+const (
+	BackendIsolate = "isolate"
+	BackendRunsc   = "runsc"
+)
+
+// Capabilities tell callers which guarantees a backend gives, so features
+// built on isolate can degrade instead of producing wrong verdicts.
+type Capabilities struct {
+	Backend string `json:"backend"`
+	// CgroupMetrics means CPU time and peak memory are measured per run by
+	// cgroups. Without it both include sandbox overhead and memory verdicts
+	// rely on the enforced limit alone.
+	CgroupMetrics bool `json:"cgroup_metrics"`
+	Seccomp       bool `json:"seccomp"`
+	ProcessLimit  bool `json:"process_limit"`
+	// Devices is filled in by a device probe and says whether each
+	// allowlisted device works inside a box.
+	Devices map[string]bool `json:"devices,omitempty"`
+}
+
+// Mount exposes a host directory inside the box; Source defaults to Path.
+// Device mounts expose a single device node from the allowlist.
+type Mount struct {
+	Path     string
+	Source   string
+	Writable bool
+	Device   bool
+}
+
+// RunSpec is one shell command run in a box. Stdin, Stdout and Stderr name
+// files in the box directory; when one is empty the matching pipe is used
+// instead, or /dev/null when NullStdio is set and there is no pipe. Meta
+// names the file that receives the run's statistics in isolate's meta format.
+// Network shares the host network and is only for fetching, never for
+// submitted code.
+type RunSpec struct {
+	Command    string
+	TimeLimit  time.Duration
+	MemoryKb   int
+	Processes  int
+	Seccomp    bool
+	Mounts     []Mount
+	Stdin      string
+	Stdout     string
+	Stderr     string
+	Meta       string
+	StdinPipe  io.Reader
+	StdoutPipe io.Writer
+	StderrPipe io.Writer
+	NullStdio  bool
+	Network    bool
+	// Env holds NAME=value pairs added to the run's environment.
+	Env []string
+}
+
+// Process is a started run. Wait returns an error when the command failed
+// or broke a limit; the meta file has the details.
+type Process interface {
+	Wait() error
+}
+
+// Backend creates boxes and runs commands in them. IsolateSandbox builds
+// every compile stage, execution, checker and interactor run on top of it.
+type Backend interface {
+	Name() string
+	Capabilities() Capabilities
+	Version(ctx context.Context) string
+	CreateBox() (int, error)
+	InitBox(boxID int) error
+	CleanupBox(boxID int)
+	BoxDir(boxID int) string
+	Start(ctx context.Context, boxID int, spec RunSpec) (Process, error)
+}
+
+func newBackend(cfg *config.IsolateConfig) Backend {
+	if cfg.Backend == BackendRunsc {
+		return newRunscBackend(cfg)
+	}
+	return newIsolateBackend(cfg)
+}
+
+// SystemMounts are the read-only host directories every run needs.
+func SystemMounts() []Mount {
+	return []Mount{{Path: "/etc"}, {Path: "/usr"}, {Path: "/lib"}, {Path: "/lib64"}}
+}
+
+// WorkMounts adds the writable scratch directories used by builds and runs.
+func WorkMounts() []Mount {
+	return append(SystemMounts(), Mount{Path: "/tmp", Writable: true}, Mount{Path: "/box", Writable: true})
+}
+
+// limitSeconds rounds a limit down to whole seconds, never below one.
+func limitSeconds(limit time.Duration) int {
+	seconds := int(limit.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
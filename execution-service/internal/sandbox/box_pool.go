@@ -0,0 +1,221 @@
+package sandbox
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// boxPoolFirstID keeps pooled box IDs clear of the ad hoc boxes handed out
+// by CreateBox.
+const boxPoolFirstID = 100
+
+type BoxPoolStats struct {
+	Size        int     `json:"size"`
+	Available   int     `json:"available"`
+	Warm        int     `json:"warm"`
+	InUse       int     `json:"in_use"`
+	Utilization float64 `json:"utilization"`
+	Acquired    int64   `json:"acquired"`
+	Fallbacks   int64   `json:"fallbacks"`
+	Resets      int64   `json:"resets"`
+	Reinits     int64   `json:"reinits"`
+	Dropped     int64   `json:"dropped"`
+}
+
+// BoxPool keeps sandbox boxes initialized between runs. A released box is
+// emptied in place and only re-initialized when that fails, so short
+// programs no longer pay for box setup and teardown on every run.
+// When every pooled box is busy, Acquire falls back to a fresh box instead
+// of waiting, so callers holding one box can safely take another.
+type BoxPool struct {
+	sandbox   *IsolateSandbox
+	size      int
+	available chan int
+	listener  func(operation, result string, stats BoxPoolStats)
+
+	warmListener func(event string, stats WarmPoolStats)
+
+	mu     sync.Mutex
+	pooled map[int]bool
+	warm   map[string]*warmPool
+	stats  BoxPoolStats
+}
+
+func NewBoxPool(sb *IsolateSandbox, size int) *BoxPool {
+	return &BoxPool{
+		sandbox:   sb,
+		size:      size,
+		available: make(chan int, size),
+		pooled:    make(map[int]bool),
+		warm:      make(map[string]*warmPool),
+	}
+}
+
+// SetListener is called after every pool operation, for metrics.
+func (bp *BoxPool) SetListener(listener func(operation, result string, stats BoxPoolStats)) {
+	bp.listener = listener
+}
+
+// Start initializes the pool's boxes, discarding anything left over from a
+// previous run. Boxes that fail to initialize are skipped.
+func (bp *BoxPool) Start() error {
+	for boxID := boxPoolFirstID; boxID < boxPoolFirstID+bp.size; boxID++ {
+		if err := bp.initBox(boxID); err != nil {
+			log.Printf("Skipping pooled box %d: %v", boxID, err)
+			continue
+		}
+
+		bp.mu.Lock()
+		bp.pooled[boxID] = true
+		bp.stats.Size++
+		bp.stats.Available++
+		bp.mu.Unlock()
+		bp.available <- boxID
+	}
+
+	if bp.Stats().Size == 0 && bp.size > 0 {
+		return fmt.Errorf("failed to initialize any of %d pooled boxes", bp.size)
+	}
+	return nil
+}
+
+// Acquire returns a ready box, falling back to a freshly created one when
+// the pool is exhausted.
+func (bp *BoxPool) Acquire() (int, error) {
+	select {
+	case boxID := <-bp.available:
+		bp.record("box_acquire", "pooled", func(s *BoxPoolStats) {
+			s.Acquired++
+			s.Available--
+			s.InUse++
+		})
+		return boxID, nil
+	default:
+	}
+
+	boxID, err := bp.sandbox.CreateBox()
+	if err != nil {
+		bp.record("box_acquire", "error", nil)
+		return 0, err
+	}
+	bp.record("box_acquire", "fallback", func(s *BoxPoolStats) {
+		s.Fallbacks++
+	})
+	return boxID, nil
+}
+
+// Release resets a pooled box and returns it to the pool; boxes created by
+// the fallback path are cleaned up as before.
+func (bp *BoxPool) Release(boxID int) {
+	bp.mu.Lock()
+	pooled := bp.pooled[boxID]
+	bp.mu.Unlock()
+	if !pooled {
+		bp.sandbox.CleanupBox(boxID)
+		return
+	}
+
+	if err := bp.resetBox(boxID); err == nil {
+		bp.record("box_reset", "ok", func(s *BoxPoolStats) {
+			s.Resets++
+		})
+	} else if err := bp.initBox(boxID); err == nil {
+		log.Printf("Re-initialized pooled box %d after failed reset", boxID)
+		bp.record("box_reset", "reinit", func(s *BoxPoolStats) {
+			s.Reinits++
+		})
+	} else {
+		log.Printf("Dropping pooled box %d: %v", boxID, err)
+		bp.record("box_reset", "dropped", func(s *BoxPoolStats) {
+			delete(bp.pooled, boxID)
+			s.Dropped++
+			s.Size--
+			s.InUse--
+		})
+		return
+	}
+
+	bp.record("box_release", "ok", func(s *BoxPoolStats) {
+		s.InUse--
+		s.Available++
+	})
+	bp.available <- boxID
+}
+
+func (bp *BoxPool) Stats() BoxPoolStats {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	stats := bp.stats
+	if stats.Size > 0 {
+		stats.Utilization = float64(stats.InUse) / float64(stats.Size)
+	}
+	return stats
+}
+
+// Close cleans up every pooled box that is currently idle or warm.
+func (bp *BoxPool) Close() {
+	bp.mu.Lock()
+	var warm []warmBox
+	for _, pool := range bp.warm {
+		warm = append(warm, pool.ready...)
+		pool.target, pool.ready = 0, nil
+	}
+	bp.mu.Unlock()
+	for _, box := range warm {
+		bp.sandbox.CleanupBox(box.id)
+		bp.record("box_close", "ok", func(s *BoxPoolStats) {
+			delete(bp.pooled, box.id)
+			s.Size--
+			s.Warm--
+		})
+	}
+
+	for {
+		select {
+		case boxID := <-bp.available:
+			bp.sandbox.CleanupBox(boxID)
+			bp.record("box_close", "ok", func(s *BoxPoolStats) {
+				delete(bp.pooled, boxID)
+				s.Size--
+				s.Available--
+			})
+		default:
+			return
+		}
+	}
+}
+
+func (bp *BoxPool) initBox(boxID int) error {
+	return bp.sandbox.backend.InitBox(boxID)
+}
+
+// resetBox empties the box directory so the next run starts clean.
+func (bp *BoxPool) resetBox(boxID int) error {
+	boxDir := bp.sandbox.GetBoxDir(boxID)
+	entries, err := os.ReadDir(boxDir)
+	if err != nil {
+		return fmt.Errorf("failed to read box dir: %w", err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(boxDir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to reset box: %w", err)
+		}
+	}
+	return nil
+}
+
+func (bp *BoxPool) record(operation, result string, update func(*BoxPoolStats)) {
+	bp.mu.Lock()
+	if update != nil {
+		update(&bp.stats)
+	}
+	bp.mu.Unlock()
+
+	if bp.listener != nil {
+		bp.listener(operation, result, bp.Stats())
+	}
+}
@@ -0,0 +1,101 @@
+package sandbox
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"execution_service/internal/models"
+)
+
+const devNull = "/dev/null"
+
+// allowedDevices are the only devices an execution profile may expose, and
+// whether runs may write to them. Everything else stays hidden.
+var allowedDevices = map[string]bool{
+	devNull:        true,
+	"/dev/zero":    false,
+	"/dev/random":  false,
+	"/dev/urandom": false,
+}
+
+// AllowedDevices lists the devices an execution profile may name.
+func AllowedDevices() []string {
+	devices := make([]string, 0, len(allowedDevices))
+	for device := range allowedDevices {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+	return devices
+}
+
+// allowedProfile drops devices that are not on the allowlist.
+func allowedProfile(language string, profile models.ExecutionProfile) models.ExecutionProfile {
+	devices := make([]string, 0, len(profile.Devices))
+	for _, device := range profile.Devices {
+		if _, ok := allowedDevices[device]; !ok {
+			log.Printf("Ignoring device %s in the %s execution profile: not on the allowlist", device, language)
+			continue
+		}
+		devices = append(devices, device)
+	}
+	profile.Devices = devices
+	return profile
+}
+
+// deviceMounts binds the profile's devices into the box. NullStdio needs
+// /dev/null inside the box, so it is exposed whenever that is set.
+func deviceMounts(profile models.ExecutionProfile) []Mount {
+	devices := profile.Devices
+	if profile.NullStdio {
+		devices = append([]string{devNull}, devices...)
+	}
+
+	seen := make(map[string]bool)
+	var mounts []Mount
+	for _, device := range devices {
+		writable, ok := allowedDevices[device]
+		if !ok || seen[device] {
+			continue
+		}
+		seen[device] = true
+		mounts = append(mounts, Mount{Path: device, Writable: writable, Device: true})
+	}
+	return mounts
+}
+
+// ProbeDevices checks each allowlisted device inside a box: it must be a
+// character device, readable, and writable when the allowlist says so.
+func (i *IsolateSandbox) ProbeDevices(ctx context.Context) map[string]bool {
+	results := make(map[string]bool, len(allowedDevices))
+	for _, device := range AllowedDevices() {
+		results[device] = i.probeDevice(ctx, device, allowedDevices[device])
+	}
+	return results
+}
+
+func (i *IsolateSandbox) probeDevice(ctx context.Context, device string, writable bool) bool {
+	boxID, err := i.AcquireBox()
+	if err != nil {
+		log.Printf("Device probe for %s could not get a box: %v", device, err)
+		return false
+	}
+	defer i.ReleaseBox(boxID)
+
+	command := "test -c " + device + " && test -r " + device
+	if writable {
+		command += " && test -w " + device
+	}
+
+	spec := RunSpec{
+		Command:   command,
+		TimeLimit: time.Second,
+		MemoryKb:  65536,
+		Processes: 1,
+		Seccomp:   true,
+		Mounts:    append(WorkMounts(), Mount{Path: device, Writable: writable, Device: true}),
+		Meta:      "meta.txt",
+	}
+	return i.Run(ctx, boxID, spec) == nil
+}
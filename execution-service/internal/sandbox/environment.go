@@ -0,0 +1,125 @@
+package sandbox
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"execution_service/internal/models"
+)
+
+// SnapshotEnvironment records the toolchain versions and build configuration
+// this node judges with. It runs at startup after the language configs are
+// loaded, and again whenever a refresh changes them.
+func (i *IsolateSandbox) SnapshotEnvironment(ctx context.Context) *models.SandboxEnvironment {
+	env := &models.SandboxEnvironment{
+		Toolchains:      make(map[string]string),
+		IsolateVersion:  i.backend.Version(ctx),
+		Pipelines:       make(map[string]models.CompilePipeline),
+		ExecuteCommands: make(map[string]string),
+		JavaClassMode:   i.config.JavaClassMode,
+		CapturedAt:      time.Now(),
+	}
+
+	for _, language := range i.Languages() {
+		config := i.languageConfig(language)
+		pipeline := config.Stages()
+		env.Pipelines[language] = pipeline
+		env.ExecuteCommands[language] = config.ExecuteCommand
+
+		var versions []string
+		for _, tool := range languageTools(pipeline, env.ExecuteCommands[language]) {
+			versions = append(versions, toolVersion(ctx, tool, versionFlag(tool)))
+		}
+		env.Toolchains[language] = strings.Join(versions, "; ")
+	}
+
+	env.ToolchainID = toolchainID(env)
+
+	i.envMu.Lock()
+	i.environment = env
+	i.envMu.Unlock()
+
+	log.Printf("Sandbox toolchain snapshot %s captured (%s backend)", env.ToolchainID, i.backend.Name())
+	return env
+}
+
+// Environment returns the latest snapshot, or nil before the first one.
+func (i *IsolateSandbox) Environment() *models.SandboxEnvironment {
+	i.envMu.RLock()
+	defer i.envMu.RUnlock()
+	return i.environment
+}
+
+// toolchainID hashes only what the node cannot change without a redeploy:
+// the compiler, runtime and isolate versions.
+func toolchainID(env *models.SandboxEnvironment) string {
+	languages := make([]string, 0, len(env.Toolchains))
+	for language := range env.Toolchains {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	hash := sha256.New()
+	hash.Write([]byte("isolate=" + env.IsolateVersion + "\n"))
+	for _, language := range languages {
+		hash.Write([]byte(language + "=" + env.Toolchains[language] + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// languageTools lists the distinct programs a language invokes, in order of
+// first use. Relative commands such as ./program are the submission itself.
+func languageTools(pipeline models.CompilePipeline, executeCommand string) []string {
+	commands := make([]string, 0, len(pipeline)+1)
+	for _, stage := range pipeline {
+		commands = append(commands, stage.Command)
+	}
+	commands = append(commands, executeCommand)
+
+	seen := make(map[string]bool)
+	var tools []string
+	for _, command := range commands {
+		fields := strings.Fields(command)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], ".") || seen[fields[0]] {
+			continue
+		}
+		seen[fields[0]] = true
+		tools = append(tools, fields[0])
+	}
+	return tools
+}
+
+func versionFlag(tool string) string {
+	switch tool {
+	case "go":
+		return "version"
+	case "java", "javac", "kotlinc":
+		return "-version"
+	}
+	return "--version"
+}
+
+// toolVersion returns the first line of the tool's version output, or
+// "unavailable" so a missing tool still changes the toolchain ID.
+func toolVersion(ctx context.Context, tool, flag string) string {
+	output, err := exec.CommandContext(ctx, tool, flag).CombinedOutput()
+	if err != nil {
+		return tool + " unavailable"
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(line)
+}
+
+func pinnedPipeline(env *models.SandboxEnvironment, language string) (models.CompilePipeline, bool) {
+	if env == nil {
+		return nil, false
+	}
+	pipeline, ok := env.Pipelines[language]
+	return pipeline, ok
+}
@@ -0,0 +1,73 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const fetchArchiveName = "project.tar.gz"
+
+// FetchRepository checks out one commit of a git repository in a box with
+// network access and returns the tree, without .git, as a gzipped tarball.
+// Callers must have checked repoURL against their host allowlist; the
+// archive is capped at maxBytes before it is read.
+func (i *IsolateSandbox) FetchRepository(ctx context.Context, repoURL, commit string, timeLimit time.Duration, maxBytes int64) ([]byte, error) {
+	if strings.ContainsAny(repoURL, "'\n") || strings.ContainsAny(commit, "'\n") {
+		return nil, fmt.Errorf("invalid repository reference")
+	}
+
+	boxID, err := i.AcquireBox()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create isolate box: %w", err)
+	}
+	defer i.ReleaseBox(boxID)
+
+	// Only https is allowed, so submodules and redirects to other protocols
+	// cannot reach anything the allowlist did not approve
+	git := "git -c protocol.allow=never -c protocol.https.allow=always -c http.followRedirects=false"
+	command := strings.Join([]string{
+		"export GIT_TERMINAL_PROMPT=0 GIT_CONFIG_NOSYSTEM=1",
+		"git init -q project",
+		fmt.Sprintf("%s -C project fetch -q --depth 1 -- '%s' '%s'", git, repoURL, commit),
+		"git -C project -c advice.detachedHead=false checkout -q FETCH_HEAD",
+		"tar -C project --exclude=.git -czf " + fetchArchiveName + " .",
+	}, " && ")
+
+	// The judge seccomp policy blocks sockets, so the fetch box runs without
+	// it; only git and tar run here, never submitted code
+	spec := RunSpec{
+		Command:   command,
+		TimeLimit: timeLimit,
+		MemoryKb:  524288,
+		Processes: 16,
+		Mounts:    WorkMounts(),
+		Network:   true,
+		Stdout:    "fetch.log",
+		Stderr:    "fetch.err",
+		Meta:      "meta.txt",
+	}
+	boxDir := i.GetBoxDir(boxID)
+	if err := i.Run(ctx, boxID, spec); err != nil {
+		stderr, _ := os.ReadFile(filepath.Join(boxDir, "fetch.err"))
+		return nil, fmt.Errorf("failed to fetch repository: %s", truncateStderr(stderr, 1024))
+	}
+
+	archivePath := filepath.Join(boxDir, fetchArchiveName)
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fetched repository: %w", err)
+	}
+	if info.Size() > maxBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrInvalidArchive, maxBytes)
+	}
+
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fetched repository: %w", err)
+	}
+	return archive, nil
+}
@@ -0,0 +1,39 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"execution_service/internal/models"
+)
+
+// CompileWithGrader builds a function-only submission. The code is written
+// to the box as solutionFile and the problem's grader, which includes or
+// imports it, is compiled as the program.
+func (i *IsolateSandbox) CompileWithGrader(ctx context.Context, env *models.SandboxEnvironment, language string, grader []byte, solutionFile string, code []byte, timeLimit time.Duration) (*CompileResult, error) {
+	if solutionFile == "" || solutionFile != filepath.Base(solutionFile) || strings.HasPrefix(solutionFile, ".") {
+		return nil, fmt.Errorf("invalid grader solution file %q", solutionFile)
+	}
+	if solutionFile == i.SourceFileName(language) {
+		return nil, fmt.Errorf("grader solution file %s clashes with the program source", solutionFile)
+	}
+
+	boxID, err := i.AcquireBox()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create isolate box: %w", err)
+	}
+	defer i.ReleaseBox(boxID)
+
+	if err := writeFiles(i.GetBoxDir(boxID), []ArchiveFile{{Name: solutionFile, Data: code}}, false); err != nil {
+		return nil, fmt.Errorf("failed to write solution file: %w", err)
+	}
+
+	result, err := i.compileInBox(ctx, boxID, env, language, grader, timeLimit)
+	if err != nil || !result.Success {
+		return result, err
+	}
+	return i.withArtifact(boxID, result)
+}
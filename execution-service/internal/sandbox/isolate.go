@@ -2,38 +2,116 @@ package sandbox
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"execution_service/internal/config"
 	"execution_service/internal/models"
 )
 
+// Wall timeouts whose CPU time stays below this fraction of the limit are
+// reported as idle limit exceeded.
+const idleCPURatio = 0.5
+
+// ErrInteractorFailed means the problem's interactor could not be built or
+// started; the submission is not at fault.
+var ErrInteractorFailed = errors.New("interactor failed")
+
+// Interactors get a fixed memory limit and twice the program's time, so a
+// slow submission is charged before the interactor gives up on it.
+const (
+	interactorMemoryKb    = 262144 // 256MB
+	interactorCompileTime = 30 * time.Second
+)
+
+// Interactor exit codes, following the testlib convention.
+const (
+	interactorAccepted     = 0
+	interactorWrongAnswer  = 1
+	interactorPresentation = 2
+)
+
+// dataMountPoint is where a problem's supplementary files appear inside
+// execution boxes.
+const dataMountPoint = "/data"
+
 type IsolateSandbox struct {
 	config            *config.IsolateConfig
+	backend           Backend
 	securityValidator *SecurityValidator
+
+	pchMu   sync.RWMutex
+	pchDirs map[string]string
+
+	languageMu sync.RWMutex
+	languages  map[string]models.SupportedLanguage
+
+	envMu       sync.RWMutex
+	environment *models.SandboxEnvironment
+
+	boxPool *BoxPool
 }
 
 type ExecutionResult struct {
 	Verdict       models.Verdict
 	Output        string
 	Error         string
+	Stderr        string
 	ExecutionTime int
 	MemoryUsed    int
 	ExitCode      int
 	WallTime      int
 	Signals       string
+	Usage         *models.ResourceUsage
+}
+
+// RunOptions adjust a single execution. Environment pins the execute command
+// and DataDir is a host directory mounted read-only at /data.
+type RunOptions struct {
+	Environment *models.SandboxEnvironment
+	DataDir     string
+	// BuildDir is the compiled program from PrepareBuild, copied into each
+	// box before the program runs.
+	BuildDir string
+	// CheckerDataDir is mounted at /data for custom checkers instead of
+	// DataDir; it adds the judge-only files the program never sees.
+	CheckerDataDir string
+	// Env is added to the program's environment, not the checker's.
+	Env []string
+}
+
+// Interactor is the judge program of an interactive problem. It reads the
+// test from input.txt and the reference answer from answer.txt, talks to the
+// submission over stdin and stdout, and reports the outcome by exit code.
+type Interactor struct {
+	Language string
+	Code     []byte
+	Answer   []byte
+}
+
+// InteractiveResult is the submission's execution result with the verdict
+// already combined with the interactor's.
+type InteractiveResult struct {
+	*ExecutionResult
+	InteractorExitCode int
+	InteractorMessage  string
 }
 
 type CompileResult struct {
-	Success bool
-	Output  string
-	Error   string
+	Success    bool
+	Output     string
+	Error      string
+	EntryPoint string
+	// Artifact holds what a successful build left in the box as a gzipped
+	// tarball, for caching and PrepareBuild.
+	Artifact []byte
 }
 
 func NewIsolateSandbox(cfg *config.IsolateConfig) *IsolateSandbox {
@@ -42,108 +120,121 @@ func NewIsolateSandbox(cfg *config.IsolateConfig) *IsolateSandbox {
 
 	return &IsolateSandbox{
 		config:            cfg,
+		backend:           newBackend(cfg),
 		securityValidator: validator,
+		pchDirs:           make(map[string]string),
 	}
 }
 
 func (i *IsolateSandbox) Compile(ctx context.Context, language string, code []byte, timeLimit time.Duration) (*CompileResult, error) {
-	boxID, err := i.CreateBox()
+	return i.CompileWith(ctx, nil, language, code, timeLimit)
+}
+
+// CompileWith builds with a pinned environment's pipeline and Java class mode
+// instead of the node's current ones; a nil env uses the node's.
+func (i *IsolateSandbox) CompileWith(ctx context.Context, env *models.SandboxEnvironment, language string, code []byte, timeLimit time.Duration) (*CompileResult, error) {
+	boxID, err := i.AcquireBox()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create isolate box: %w", err)
 	}
-	defer i.CleanupBox(boxID)
+	defer i.ReleaseBox(boxID)
 
+	result, err := i.compileInBox(ctx, boxID, env, language, code, timeLimit)
+	if err != nil || !result.Success {
+		return result, err
+	}
+	return i.withArtifact(boxID, result)
+}
+
+// compileInBox builds the code in an existing box, leaving the program there.
+func (i *IsolateSandbox) compileInBox(ctx context.Context, boxID int, env *models.SandboxEnvironment, language string, code []byte, timeLimit time.Duration) (*CompileResult, error) {
 	boxDir := i.GetBoxDir(boxID)
-	codeFile := filepath.Join(boxDir, "code"+getFileExtension(language))
+	sourceName := i.SourceFileName(language)
+	entryPoint := defaultJavaClass
+	if language == "java" {
+		javaClassMode := i.config.JavaClassMode
+		if env != nil && env.JavaClassMode != "" {
+			javaClassMode = env.JavaClassMode
+		}
+		source := PrepareJavaSource(code, javaClassMode)
+		code, sourceName, entryPoint = source.Code, source.FileName, source.ClassName
+	}
 
-	err = os.WriteFile(codeFile, code, 0644)
+	codeFile := filepath.Join(boxDir, sourceName)
+	err := os.WriteFile(codeFile, code, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("failed to write code file: %w", err)
 	}
 
-	langConfig := getLanguageConfig(language)
+	stages := i.compilePipeline(language)
+	if pinned, ok := pinnedPipeline(env, language); ok {
+		stages = pinned
+	}
 
 	// If no compilation required, return success
-	if langConfig.CompileCommand == nil {
+	if len(stages) == 0 {
 		return &CompileResult{
-			Success: true,
-			Output:  "No compilation required",
-			Error:   "",
+			Success:    true,
+			Output:     "No compilation required",
+			Error:      "",
+			EntryPoint: entryPoint,
 		}, nil
 	}
 
-	compileCmd := strings.ReplaceAll(*langConfig.CompileCommand, "{executable}", "program")
-	compileCmd = strings.ReplaceAll(compileCmd, "{input}", "code"+getFileExtension(language))
-	compileCmd = strings.ReplaceAll(compileCmd, "{classname}", "Main")
-
-	// Convert time limit to seconds for isolate, ensure minimum 1 second
-	timeSec := int(timeLimit.Seconds())
-	if timeSec < 1 {
-		timeSec = 1
-	}
-	wallTimeSec := timeSec * 2
-	memoryLimit := 524288 // 512MB default for compilation
-
-	args := []string{
-		"--box-id=" + strconv.Itoa(boxID),
-		"--cg",
-		"--cg-timing",
-		"--seccomp=/etc/isolate/seccomp.policy",
-		"--processes=1",
-		"--mem=" + strconv.Itoa(memoryLimit),
-		"--time=" + strconv.Itoa(timeSec),
-		"--wall-time=" + strconv.Itoa(wallTimeSec),
-		"--extra-time=0.5",
-		"--stack=65536",
-		"--fsize=16384",
-		"--chdir=/box",
-		"--env=HOME=/tmp",
-		"--env=PATH=/usr/bin:/bin",
-		"--dir=/etc:noexec",
-		"--dir=/usr:noexec",
-		"--dir=/lib:noexec",
-		"--dir=/lib64:noexec",
-		"--dir=/tmp:rw",
-		"--dir=/box:rw",
-		"--net=none",
-		"--stdout=output.txt",
-		"--stderr=error.txt",
-		"--meta=meta.txt",
-		"--run",
-		"--",
-		"/bin/bash",
-		"-c",
-		compileCmd,
-	}
-
-	cmd := exec.CommandContext(ctx, i.config.Path, args...)
-	cmd.Dir = boxDir
-
-	err = cmd.Run()
-	if err != nil {
-		return i.parseCompilationResult(boxID, err, timeLimit, memoryLimit)
-	}
+	var output, errorOutput strings.Builder
+	for _, stage := range stages {
+		pchDir, command := i.pchFor(language, code, stage.Command)
+		command = expandCompileCommand(command, sourceName, entryPoint)
 
-	// Read compilation output
-	outputFile := filepath.Join(boxDir, "output.txt")
-	errorFile := filepath.Join(boxDir, "error.txt")
+		stageLimit := timeLimit
+		if stage.TimeLimitMs > 0 {
+			stageLimit = time.Duration(stage.TimeLimitMs) * time.Millisecond
+		}
 
-	output, _ := os.ReadFile(outputFile)
-	errorMsg, _ := os.ReadFile(errorFile)
+		result, err := i.runStage(ctx, boxID, language, stage, command, pchDir, stageLimit)
+		if err != nil {
+			return nil, err
+		}
+		output.WriteString(result.Output)
+		errorOutput.WriteString(result.Error)
+
+		if !result.Success {
+			if stage.OnFailure == models.StageFailureSystem {
+				return nil, fmt.Errorf("compile stage %q failed: %s", stage.Name, strings.TrimSpace(result.Error))
+			}
+			if len(stages) > 1 {
+				result.Error = fmt.Sprintf("[%s] %s", stage.Name, result.Error)
+			}
+			return result, nil
+		}
+	}
 
 	return &CompileResult{
-		Success: true,
-		Output:  string(output),
-		Error:   string(errorMsg),
+		Success:    true,
+		Output:     output.String(),
+		Error:      errorOutput.String(),
+		EntryPoint: entryPoint,
 	}, nil
 }
 
-func (i *IsolateSandbox) Execute(ctx context.Context, language string, input []byte, timeLimit time.Duration, memoryLimit int) (*ExecutionResult, error) {
-	boxID, err := i.CreateBox()
+// Execute runs the program; entryPoint is the class reported by Compile and
+// only matters for languages launched by class name.
+func (i *IsolateSandbox) Execute(ctx context.Context, language, entryPoint string, input []byte, timeLimit time.Duration, memoryLimit int) (*ExecutionResult, error) {
+	return i.ExecuteWith(ctx, RunOptions{}, language, entryPoint, input, timeLimit, memoryLimit)
+}
+
+func (i *IsolateSandbox) ExecuteWith(ctx context.Context, opts RunOptions, language, entryPoint string, input []byte, timeLimit time.Duration, memoryLimit int) (*ExecutionResult, error) {
+	boxID, err := i.acquireBoxFor(language)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create isolate box: %w", err)
 	}
-	defer i.CleanupBox(boxID)
+	defer i.ReleaseBox(boxID)
+
+	if opts.BuildDir != "" {
+		if err := installBuild(i.GetBoxDir(boxID), opts.BuildDir); err != nil {
+			return nil, fmt.Errorf("failed to install build: %w", err)
+		}
+	}
 
 	boxDir := i.GetBoxDir(boxID)
 	inputFile := filepath.Join(boxDir, "input.txt")
@@ -153,63 +244,205 @@ func (i *IsolateSandbox) Execute(ctx context.Context, language string, input []b
 		return nil, fmt.Errorf("failed to write input file: %w", err)
 	}
 
-	langConfig := getLanguageConfig(language)
-	runCmd := strings.ReplaceAll(langConfig.ExecuteCommand, "{executable}", "program")
-	runCmd = strings.ReplaceAll(runCmd, "{input}", "input.txt")
-	runCmd = strings.ReplaceAll(runCmd, "{classname}", "Main")
-
-	// Convert time limit to seconds for isolate, ensure minimum 1 second
-	timeSec := int(timeLimit.Seconds())
-	if timeSec < 1 {
-		timeSec = 1
-	}
-	wallTimeSec := timeSec * 2
-
-	args := []string{
-		"--box-id=" + strconv.Itoa(boxID),
-		"--cg",
-		"--cg-timing",
-		"--seccomp=/etc/isolate/seccomp.policy",
-		"--processes=1",
-		"--mem=" + strconv.Itoa(memoryLimit),
-		"--time=" + strconv.Itoa(timeSec),
-		"--wall-time=" + strconv.Itoa(wallTimeSec),
-		"--extra-time=0.5",
-		"--stack=65536",
-		"--fsize=16384",
-		"--chdir=/box",
-		"--env=HOME=/tmp",
-		"--env=PATH=/usr/bin:/bin",
-		"--dir=/etc:noexec",
-		"--dir=/usr:noexec",
-		"--dir=/lib:noexec",
-		"--dir=/lib64:noexec",
-		"--dir=/tmp:rw",
-		"--dir=/box:rw",
-		"--net=none",
-		"--stdin=input.txt",
-		"--stdout=output.txt",
-		"--stderr=error.txt",
-		"--meta=meta.txt",
-		"--run",
-		"--",
-		"/bin/bash",
-		"-c",
-		runCmd,
-	}
-
-	cmd := exec.CommandContext(ctx, i.config.Path, args...)
-	cmd.Dir = boxDir
-
-	err = cmd.Run()
+	spec := i.runSpec(opts, language, timeLimit, memoryLimit)
+	spec.Command = i.executeCommand(opts, language, entryPoint)
+	spec.Stdin = "input.txt"
+	spec.Stdout = "output.txt"
+	spec.Stderr = "error.txt"
+	spec.Meta = "meta.txt"
+
+	started := time.Now()
+	if err := i.Run(ctx, boxID, spec); err != nil {
+		return i.parseExecutionResult(boxID, 1, started, timeLimit, memoryLimit)
+	}
+
+	return i.parseExecutionResult(boxID, 0, started, timeLimit, memoryLimit)
+}
+
+// ExecuteInteractive runs the program against an interactor, each in its own
+// box, with the program's stdout piped to the interactor's stdin and back.
+// Errors mean the interactor itself could not be built or started.
+func (i *IsolateSandbox) ExecuteInteractive(ctx context.Context, opts RunOptions, language, entryPoint string, interactor *Interactor, input []byte, timeLimit time.Duration, memoryLimit int) (*InteractiveResult, error) {
+	programBox, err := i.acquireBoxFor(language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create isolate box: %w", err)
+	}
+	defer i.ReleaseBox(programBox)
+
+	if opts.BuildDir != "" {
+		if err := installBuild(i.GetBoxDir(programBox), opts.BuildDir); err != nil {
+			return nil, fmt.Errorf("failed to install build: %w", err)
+		}
+	}
+
+	interactorBox, err := i.AcquireBox()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create isolate box: %w", err)
+	}
+	defer i.ReleaseBox(interactorBox)
+
+	build, err := i.compileInBox(ctx, interactorBox, nil, interactor.Language, interactor.Code, interactorCompileTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile interactor: %w", err)
+	}
+	if !build.Success {
+		return nil, fmt.Errorf("%w: compilation failed: %s", ErrInteractorFailed, strings.TrimSpace(build.Error))
+	}
+
+	interactorDir := i.GetBoxDir(interactorBox)
+	if err := os.WriteFile(filepath.Join(interactorDir, "input.txt"), input, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write input file: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(interactorDir, "answer.txt"), interactor.Answer, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write answer file: %w", err)
+	}
+
+	toProgram, fromInteractor, err := os.Pipe()
 	if err != nil {
-		return i.parseExecutionResult(boxID, 1, timeLimit, memoryLimit)
+		return nil, fmt.Errorf("failed to create pipe: %w", err)
 	}
+	toInteractor, fromProgram, err := os.Pipe()
+	if err != nil {
+		toProgram.Close()
+		fromInteractor.Close()
+		return nil, fmt.Errorf("failed to create pipe: %w", err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	programSpec := i.runSpec(opts, language, timeLimit, memoryLimit)
+	programSpec.Command = i.executeCommand(opts, language, entryPoint)
+	programSpec.Stderr = "error.txt"
+	programSpec.Meta = "meta.txt"
+	programSpec.StdinPipe = toProgram
+	programSpec.StdoutPipe = fromProgram
+
+	interactorSpec := i.runSpec(RunOptions{}, interactor.Language, 2*timeLimit, interactorMemoryKb)
+	interactorSpec.Command = i.executeCommand(RunOptions{}, interactor.Language, build.EntryPoint) + " input.txt answer.txt"
+	interactorSpec.Stderr = "error.txt"
+	interactorSpec.Meta = "meta.txt"
+	interactorSpec.StdinPipe = toInteractor
+	interactorSpec.StdoutPipe = fromInteractor
+
+	started := time.Now()
+	interactorProcess, interactorErr := i.backend.Start(runCtx, interactorBox, interactorSpec)
+	var programProcess Process
+	var programErr error
+	if interactorErr == nil {
+		programProcess, programErr = i.backend.Start(runCtx, programBox, programSpec)
+	}
+
+	// The children hold their own copies; ours would keep the pipes open
+	toProgram.Close()
+	fromInteractor.Close()
+	toInteractor.Close()
+	fromProgram.Close()
 
-	return i.parseExecutionResult(boxID, 0, timeLimit, memoryLimit)
+	if interactorErr != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInteractorFailed, interactorErr)
+	}
+	if programErr != nil {
+		cancel()
+		interactorProcess.Wait()
+		return nil, fmt.Errorf("failed to start program: %w", programErr)
+	}
+
+	programExit := 0
+	if programProcess.Wait() != nil {
+		programExit = 1
+	}
+	interactorProcess.Wait()
+
+	program, err := i.parseExecutionResult(programBox, programExit, started, timeLimit, memoryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, _ := os.ReadFile(filepath.Join(interactorDir, "meta.txt"))
+	message, _ := os.ReadFile(filepath.Join(interactorDir, "error.txt"))
+	result := &InteractiveResult{
+		ExecutionResult:    program,
+		InteractorExitCode: interactorExitCode(string(meta)),
+		InteractorMessage:  strings.TrimSpace(string(message)),
+	}
+	result.Verdict = interactiveVerdict(program.Verdict, result.InteractorExitCode)
+	return result, nil
+}
+
+// interactorExitCode reads the exit code from isolate's meta file, or -1 when
+// the interactor was killed or broke the sandbox rules.
+func interactorExitCode(meta string) int {
+	exitCode := 0
+	for _, line := range strings.Split(meta, "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), ":")
+		switch key {
+		case "status":
+			if value != "RE" {
+				return -1
+			}
+		case "exitcode":
+			if code, err := strconv.Atoi(value); err == nil {
+				exitCode = code
+			}
+		}
+	}
+	return exitCode
+}
+
+// interactiveVerdict combines both sides. Resource limits on the program win,
+// since an interactor starved of output reports a wrong answer; the
+// interactor's verdict comes next because a rejected program often dies on a
+// closed pipe.
+func interactiveVerdict(program models.Verdict, interactorExit int) models.Verdict {
+	switch program {
+	case models.VerdictTimeLim, models.VerdictIdleLim, models.VerdictMemLim:
+		return program
+	}
+
+	switch interactorExit {
+	case interactorAccepted:
+		return program
+	case interactorWrongAnswer, interactorPresentation:
+		return models.VerdictWrongAns
+	default:
+		return models.VerdictInternal
+	}
+}
+
+// runSpec holds the limits and mounts shared by every execution of a
+// submission in language; callers add the command and stdio.
+func (i *IsolateSandbox) runSpec(opts RunOptions, language string, timeLimit time.Duration, memoryLimit int) RunSpec {
+	mounts := WorkMounts()
+	if opts.DataDir != "" {
+		mounts = append(mounts, Mount{Path: dataMountPoint, Source: opts.DataDir})
+	}
+	profile := i.languageConfig(language).ExecutionProfile
+	return RunSpec{
+		TimeLimit: timeLimit,
+		MemoryKb:  memoryLimit,
+		Processes: 1,
+		Seccomp:   true,
+		Mounts:    append(mounts, deviceMounts(profile)...),
+		NullStdio: profile.NullStdio,
+		Env:       opts.Env,
+	}
+}
+
+func (i *IsolateSandbox) executeCommand(opts RunOptions, language, entryPoint string) string {
+	command := i.languageConfig(language).ExecuteCommand
+	if env := opts.Environment; env != nil && env.ExecuteCommands[language] != "" {
+		command = env.ExecuteCommands[language]
+	}
+	command = strings.ReplaceAll(command, "{executable}", "program")
+	command = strings.ReplaceAll(command, "{input}", i.SourceFileName(language))
+	if entryPoint == "" {
+		entryPoint = defaultJavaClass
+	}
+	return strings.ReplaceAll(command, "{classname}", entryPoint)
 }
 
-func (i *IsolateSandbox) parseExecutionResult(boxID int, exitCode int, timeLimit time.Duration, memoryLimit int) (*ExecutionResult, error) {
+func (i *IsolateSandbox) parseExecutionResult(boxID int, exitCode int, started time.Time, timeLimit time.Duration, memoryLimit int) (*ExecutionResult, error) {
 	boxDir := i.GetBoxDir(boxID)
 
 	outputFile := filepath.Join(boxDir, "output.txt")
@@ -223,10 +456,13 @@ func (i *IsolateSandbox) parseExecutionResult(boxID int, exitCode int, timeLimit
 	result := &ExecutionResult{
 		Output:   string(output),
 		Error:    string(errorStr),
+		Stderr:   truncateStderr(errorStr, i.config.StderrMaxBytes),
 		ExitCode: exitCode,
 	}
 
 	result.ExecutionTime, result.MemoryUsed, result.WallTime, result.Signals = i.parseMetaFile(string(meta))
+	result.Usage = parseUsage(string(meta))
+	result.Usage.FilesWritten, result.Usage.BytesWritten = boxWrites(boxDir, started)
 
 	result.Verdict = i.determineVerdict(exitCode, result.ExecutionTime, result.MemoryUsed, result.WallTime, timeLimit, memoryLimit)
 
@@ -256,6 +492,15 @@ func (i *IsolateSandbox) parseExecutionResult(boxID int, exitCode int, timeLimit
 	return result, nil
 }
 
+// truncateStderr keeps at most maxBytes of the program's own stderr, which
+// unlike Error is never replaced by sandbox messages.
+func truncateStderr(stderr []byte, maxBytes int) string {
+	if maxBytes <= 0 || len(stderr) <= maxBytes {
+		return string(stderr)
+	}
+	return strings.ToValidUTF8(string(stderr[:maxBytes]), "") + "\n...(truncated)"
+}
+
 func (i *IsolateSandbox) parseCompilationResult(boxID int, err error, timeLimit time.Duration, memoryLimit int) (*CompileResult, error) {
 	boxDir := i.GetBoxDir(boxID)
 
@@ -325,6 +570,53 @@ func (i *IsolateSandbox) parseMetaFile(meta string) (timeMs, memoryKb, wallTimeM
 	return
 }
 
+// parseUsage reads the run's resource usage from isolate's meta file.
+func parseUsage(meta string) *models.ResourceUsage {
+	usage := &models.ResourceUsage{}
+	for _, line := range strings.Split(meta, "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), ":")
+		switch key {
+		case "time":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+				usage.CPUTimeMs = int(seconds * 1000)
+			}
+		case "time-wall":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+				usage.WallTimeMs = int(seconds * 1000)
+			}
+		case "max-rss":
+			usage.MaxRSSKb, _ = strconv.Atoi(value)
+		case "cg-mem":
+			usage.CgroupMemoryKb, _ = strconv.Atoi(value)
+		case "csw-voluntary":
+			usage.VoluntarySwitches, _ = strconv.Atoi(value)
+		case "csw-forced":
+			usage.ForcedSwitches, _ = strconv.Atoi(value)
+		case "cg-oom-killed":
+			usage.OOMKilled = value == "1"
+		}
+	}
+	return usage
+}
+
+// boxWrites counts the files in the box modified since the run started and
+// their total size. The meta file is isolate's own and is left out.
+func boxWrites(boxDir string, since time.Time) (files int, bytes int64) {
+	filepath.WalkDir(boxDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || path == filepath.Join(boxDir, "meta.txt") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || !info.ModTime().After(since) {
+			return nil
+		}
+		files++
+		bytes += info.Size()
+		return nil
+	})
+	return files, bytes
+}
+
 func (i *IsolateSandbox) determineVerdict(exitCode, timeMs, memoryKb, wallTimeMs int, timeLimit time.Duration, memoryLimit int) models.Verdict {
 	timeLimitMs := int(timeLimit.Milliseconds())
 
@@ -334,13 +626,18 @@ func (i *IsolateSandbox) determineVerdict(exitCode, timeMs, memoryKb, wallTimeMs
 		effectiveTime = timeMs
 	}
 
-	// Check time limit exceeded
+	// Check time limit exceeded; a wall timeout with little CPU use means the
+	// program was sleeping or blocked rather than computing
 	if effectiveTime > timeLimitMs {
+		if wallTimeMs > 0 && float64(timeMs) < float64(timeLimitMs)*idleCPURatio {
+			return models.VerdictIdleLim
+		}
 		return models.VerdictTimeLim
 	}
 
-	// Check memory limit exceeded
-	if memoryKb > memoryLimit {
+	// Without cgroup metrics the measured peak includes sandbox overhead, so
+	// only the enforced limit can produce a memory verdict
+	if memoryKb > memoryLimit && i.backend.Capabilities().CgroupMetrics {
 		return models.VerdictMemLim
 	}
 
@@ -376,29 +673,65 @@ func (i *IsolateSandbox) determineVerdict(exitCode, timeMs, memoryKb, wallTimeMs
 	return models.VerdictAccepted
 }
 
-func (i *IsolateSandbox) CreateBox() (int, error) {
-	cmd := exec.Command(i.config.Path, "--init")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return 0, fmt.Errorf("failed to initialize isolate box: %w, output: %s", err, string(output))
+// SetBoxPool makes runs take boxes from the pool. Call before judging starts.
+func (i *IsolateSandbox) SetBoxPool(pool *BoxPool) {
+	i.boxPool = pool
+}
+
+func (i *IsolateSandbox) BoxPool() *BoxPool {
+	return i.boxPool
+}
+
+// AcquireBox returns a box for one run, from the pool when there is one.
+// Pair it with ReleaseBox.
+func (i *IsolateSandbox) AcquireBox() (int, error) {
+	if i.boxPool != nil {
+		return i.boxPool.Acquire()
 	}
+	return i.CreateBox()
+}
 
-	boxIDStr := strings.TrimSpace(string(output))
-	boxID, err := strconv.Atoi(boxIDStr)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse box ID: %w", err)
+// acquireBoxFor prefers a box warmed for the language's runtime.
+func (i *IsolateSandbox) acquireBoxFor(language string) (int, error) {
+	if i.boxPool != nil {
+		return i.boxPool.acquireWarm(language)
 	}
+	return i.CreateBox()
+}
 
-	return boxID, nil
+func (i *IsolateSandbox) ReleaseBox(boxID int) {
+	if i.boxPool != nil {
+		i.boxPool.Release(boxID)
+		return
+	}
+	i.CleanupBox(boxID)
+}
+
+func (i *IsolateSandbox) CreateBox() (int, error) {
+	return i.backend.CreateBox()
 }
 
 func (i *IsolateSandbox) CleanupBox(boxID int) {
-	cmd := exec.Command(i.config.Path, "--box-id="+strconv.Itoa(boxID), "--cleanup")
-	cmd.Run()
+	i.backend.CleanupBox(boxID)
 }
 
 func (i *IsolateSandbox) GetBoxDir(boxID int) string {
-	return filepath.Join(i.config.BoxRoot, fmt.Sprintf("%d", boxID))
+	return i.backend.BoxDir(boxID)
+}
+
+// Run runs one command in a box and waits for it. The error only says the
+// command failed or broke a limit; the spec's meta file has the details.
+func (i *IsolateSandbox) Run(ctx context.Context, boxID int, spec RunSpec) error {
+	process, err := i.backend.Start(ctx, boxID, spec)
+	if err != nil {
+		return err
+	}
+	return process.Wait()
+}
+
+// Capabilities reports what the configured backend can enforce and measure.
+func (i *IsolateSandbox) Capabilities() Capabilities {
+	return i.backend.Capabilities()
 }
 
 func (i *IsolateSandbox) CleanupAll() error {
@@ -406,28 +739,66 @@ func (i *IsolateSandbox) CleanupAll() error {
 	return cmd.Run()
 }
 
+// jvmProfile covers SecureRandom seeding and the JVM's writes to /dev/null.
+var jvmProfile = models.ExecutionProfile{Devices: []string{"/dev/urandom"}, NullStdio: true}
+
 func getLanguageConfig(language string) models.SupportedLanguage {
 	configs := map[string]models.SupportedLanguage{
 		"cpp": {
-			CompileCommand: stringPtr("g++ -O2 -std=c++17 -o program code.cpp"),
-			ExecuteCommand: "./program",
+			CompilePipeline: compileStage("g++ -O2 -std=c++17 -o program code.cpp"),
+			ExecuteCommand:  "./program",
 		},
 		"c": {
-			CompileCommand: stringPtr("gcc -O2 -std=c11 -o program code.c"),
-			ExecuteCommand: "./program",
+			CompilePipeline: compileStage("gcc -O2 -std=c11 -o program code.c"),
+			ExecuteCommand:  "./program",
 		},
 		"java": {
-			CompileCommand: stringPtr("javac code.java"),
-			ExecuteCommand: "java Main",
+			CompilePipeline:  compileStage("javac {input}"),
+			ExecuteCommand:   "java {classname}",
+			ExecutionProfile: jvmProfile,
 		},
 		"python": {
-			CompileCommand: nil,
-			ExecuteCommand: "python3 code.py",
+			ExecuteCommand:   "python3 code.py",
+			ExecutionProfile: models.ExecutionProfile{Devices: []string{"/dev/urandom"}},
 		},
 		"go": {
-			CompileCommand: stringPtr("go build -o program code.go"),
+			CompilePipeline: compileStage("go build -o program code.go"),
+			ExecuteCommand:  "./program",
+		},
+		"rust": {
+			CompilePipeline: models.CompilePipeline{{
+				Name:        "compile",
+				Command:     "rustc -O --edition 2021 -o program code.rs",
+				TimeLimitMs: 30000,
+				Processes:   8,
+			}},
 			ExecuteCommand: "./program",
 		},
+		"kotlin": {
+			CompilePipeline: models.CompilePipeline{{
+				Name:          "compile",
+				Command:       "kotlinc code.kt -include-runtime -d program.jar",
+				TimeLimitMs:   60000,
+				MemoryLimitKb: 1048576,
+				Processes:     64,
+			}},
+			ExecuteCommand:   "java -jar program.jar",
+			ExecutionProfile: jvmProfile,
+		},
+		"csharp": {
+			CompilePipeline: models.CompilePipeline{{
+				Name:        "compile",
+				Command:     "mcs -optimize+ -out:program.exe code.cs",
+				TimeLimitMs: 30000,
+				Processes:   16,
+			}},
+			ExecuteCommand:   "mono program.exe",
+			ExecutionProfile: models.ExecutionProfile{Devices: []string{"/dev/urandom"}, NullStdio: true},
+		},
+		"ruby": {
+			ExecuteCommand:   "ruby code.rb",
+			ExecutionProfile: models.ExecutionProfile{Devices: []string{"/dev/urandom"}},
+		},
 	}
 
 	if config, exists := configs[language]; exists {
@@ -435,7 +806,6 @@ func getLanguageConfig(language string) models.SupportedLanguage {
 	}
 
 	return models.SupportedLanguage{
-		CompileCommand: nil,
 		ExecuteCommand: "python3 code.py",
 	}
 }
@@ -447,6 +817,10 @@ func getFileExtension(language string) string {
 		"java":   ".java",
 		"python": ".py",
 		"go":     ".go",
+		"rust":   ".rs",
+		"kotlin": ".kt",
+		"csharp": ".cs",
+		"ruby":   ".rb",
 	}
 
 	if ext, exists := extensions[language]; exists {
@@ -455,10 +829,6 @@ func getFileExtension(language string) string {
 	return ".txt"
 }
 
-func stringPtr(s string) *string {
-	return &s
-}
-
 func (i *IsolateSandbox) GetPath() string {
 	return i.config.Path
 }
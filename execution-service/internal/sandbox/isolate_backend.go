@@ -0,0 +1,157 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"execution_service/internal/config"
+)
+
+type isolateBackend struct {
+	config *config.IsolateConfig
+}
+
+func newIsolateBackend(cfg *config.IsolateConfig) *isolateBackend {
+	return &isolateBackend{config: cfg}
+}
+
+func (b *isolateBackend) Name() string {
+	return BackendIsolate
+}
+
+func (b *isolateBackend) Capabilities() Capabilities {
+	return Capabilities{
+		Backend:       BackendIsolate,
+		CgroupMetrics: true,
+		Seccomp:       true,
+		ProcessLimit:  true,
+	}
+}
+
+func (b *isolateBackend) Version(ctx context.Context) string {
+	return toolVersion(ctx, b.config.Path, "--version")
+}
+
+func (b *isolateBackend) CreateBox() (int, error) {
+	cmd := exec.Command(b.config.Path, "--init")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize isolate box: %w, output: %s", err, string(output))
+	}
+
+	boxIDStr := strings.TrimSpace(string(output))
+	boxID, err := strconv.Atoi(boxIDStr)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse box ID: %w", err)
+	}
+
+	return boxID, nil
+}
+
+func (b *isolateBackend) InitBox(boxID int) error {
+	b.CleanupBox(boxID)
+
+	cmd := exec.Command(b.config.Path, "--box-id="+strconv.Itoa(boxID), "--init")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to initialize isolate box: %w, output: %s", err, string(output))
+	}
+	return nil
+}
+
+func (b *isolateBackend) CleanupBox(boxID int) {
+	cmd := exec.Command(b.config.Path, "--box-id="+strconv.Itoa(boxID), "--cleanup")
+	cmd.Run()
+}
+
+func (b *isolateBackend) BoxDir(boxID int) string {
+	return filepath.Join(b.config.BoxRoot, fmt.Sprintf("%d", boxID))
+}
+
+func (b *isolateBackend) Start(ctx context.Context, boxID int, spec RunSpec) (Process, error) {
+	cmd := exec.CommandContext(ctx, b.config.Path, b.args(boxID, spec)...)
+	cmd.Dir = b.BoxDir(boxID)
+	cmd.Stdin = spec.StdinPipe
+	cmd.Stdout = spec.StdoutPipe
+	cmd.Stderr = spec.StderrPipe
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+func (b *isolateBackend) args(boxID int, spec RunSpec) []string {
+	timeSec := limitSeconds(spec.TimeLimit)
+	processes := spec.Processes
+	if processes <= 0 {
+		processes = 1
+	}
+
+	args := []string{
+		"--box-id=" + strconv.Itoa(boxID),
+		"--cg",
+		"--cg-timing",
+	}
+	if spec.Seccomp {
+		args = append(args, "--seccomp=/etc/isolate/seccomp.policy")
+	}
+	args = append(args,
+		"--processes="+strconv.Itoa(processes),
+		"--mem="+strconv.Itoa(spec.MemoryKb),
+		"--time="+strconv.Itoa(timeSec),
+		"--wall-time="+strconv.Itoa(timeSec*2),
+		"--extra-time=0.5",
+		"--stack=65536",
+		"--fsize=16384",
+		"--chdir=/box",
+		"--env=HOME=/tmp",
+		"--env=PATH=/usr/bin:/bin",
+	)
+	for _, variable := range spec.Env {
+		args = append(args, "--env="+variable)
+	}
+	for _, mount := range spec.Mounts {
+		rule := mount.Path
+		if mount.Source != "" {
+			rule += "=" + mount.Source
+		}
+		options := []string{"noexec"}
+		if mount.Writable {
+			options = []string{"rw"}
+		}
+		if mount.Device {
+			options = append(options, "dev")
+		}
+		args = append(args, "--dir="+rule+":"+strings.Join(options, ","))
+	}
+	if spec.Network {
+		args = append(args, "--share-net")
+	} else {
+		args = append(args, "--net=none")
+	}
+
+	args = append(args, stdioArg("--stdin=", spec.Stdin, spec.StdinPipe != nil, spec.NullStdio)...)
+	args = append(args, stdioArg("--stdout=", spec.Stdout, spec.StdoutPipe != nil, spec.NullStdio)...)
+	args = append(args, stdioArg("--stderr=", spec.Stderr, spec.StderrPipe != nil, spec.NullStdio)...)
+	if spec.Meta != "" {
+		args = append(args, "--meta="+spec.Meta)
+	}
+
+	return append(args, "--run", "--", "/bin/bash", "-c", spec.Command)
+}
+
+// stdioArg redirects one standard descriptor to a box file, or to /dev/null
+// when nothing else feeds it and the language asks for it.
+func stdioArg(flag, file string, piped, nullStdio bool) []string {
+	switch {
+	case file != "":
+		return []string{flag + file}
+	case nullStdio && !piped:
+		return []string{flag + "/dev/null"}
+	}
+	return nil
+}
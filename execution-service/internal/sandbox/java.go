@@ -0,0 +1,69 @@
+package sandbox
+
+import (
+	"regexp"
+)
+
+const (
+	JavaClassModeRename  = "rename"
+	JavaClassModeRewrite = "rewrite"
+
+	defaultJavaClass = "Main"
+)
+
+var (
+	javaBlockComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	javaLineComment  = regexp.MustCompile(`//[^\n]*`)
+	javaStringLit    = regexp.MustCompile(`"(?:\\.|[^"\\])*"`)
+	javaPublicClass  = regexp.MustCompile(`\bpublic\s+(?:(?:final|abstract|strictfp)\s+)*(?:class|interface|enum|record)\s+([A-Za-z_$][A-Za-z0-9_$]*)`)
+	javaAnyClass     = regexp.MustCompile(`\b(?:class|interface|enum|record)\s+([A-Za-z_$][A-Za-z0-9_$]*)`)
+	javaMainMethod   = regexp.MustCompile(`\bstatic\s+void\s+main\s*\(`)
+)
+
+// JavaSource is a Java submission prepared for compilation: the source to
+// write, the file name javac expects and the class to launch.
+type JavaSource struct {
+	Code      []byte
+	FileName  string
+	ClassName string
+}
+
+// PrepareJavaSource detects the entry class of a Java submission. In rename
+// mode the file is named after the detected class; in rewrite mode the class
+// is renamed to Main so the file and launch command never change.
+func PrepareJavaSource(code []byte, mode string) *JavaSource {
+	className := DetectJavaClass(code)
+
+	if mode == JavaClassModeRewrite && className != defaultJavaClass {
+		pattern := regexp.MustCompile(`\b` + regexp.QuoteMeta(className) + `\b`)
+		code = pattern.ReplaceAll(code, []byte(defaultJavaClass))
+		className = defaultJavaClass
+	}
+
+	return &JavaSource{
+		Code:      code,
+		FileName:  className + ".java",
+		ClassName: className,
+	}
+}
+
+// DetectJavaClass returns the public top-level class, falling back to the
+// class declaring main and finally to Main.
+func DetectJavaClass(code []byte) string {
+	stripped := javaStringLit.ReplaceAll(code, []byte(`""`))
+	stripped = javaBlockComment.ReplaceAll(stripped, nil)
+	stripped = javaLineComment.ReplaceAll(stripped, nil)
+
+	if match := javaPublicClass.FindSubmatch(stripped); match != nil {
+		return string(match[1])
+	}
+
+	if loc := javaMainMethod.FindIndex(stripped); loc != nil {
+		matches := javaAnyClass.FindAllSubmatch(stripped[:loc[0]], -1)
+		if len(matches) > 0 {
+			return string(matches[len(matches)-1][1])
+		}
+	}
+
+	return defaultJavaClass
+}
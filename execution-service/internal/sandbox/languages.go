@@ -0,0 +1,115 @@
+package sandbox
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"execution_service/internal/models"
+)
+
+var builtinLanguages = []string{"cpp", "c", "java", "python", "go", "rust", "kotlin", "csharp", "ruby"}
+
+// SetLanguages replaces the language configs loaded from supported_languages.
+// Fields a row leaves empty fall back to the built-in config for that code.
+func (i *IsolateSandbox) SetLanguages(languages []models.SupportedLanguage) {
+	configs := make(map[string]models.SupportedLanguage, len(languages))
+	for _, language := range languages {
+		language.ExecutionProfile = allowedProfile(language.LanguageCode, language.ExecutionProfile)
+		configs[language.LanguageCode] = language
+	}
+
+	i.languageMu.Lock()
+	defer i.languageMu.Unlock()
+	i.languages = configs
+}
+
+// Languages lists the built-in languages and any added through the database.
+func (i *IsolateSandbox) Languages() []string {
+	seen := make(map[string]bool)
+	languages := append([]string{}, builtinLanguages...)
+	for _, language := range languages {
+		seen[language] = true
+	}
+
+	i.languageMu.RLock()
+	for language := range i.languages {
+		if !seen[language] {
+			languages = append(languages, language)
+		}
+	}
+	i.languageMu.RUnlock()
+
+	sort.Strings(languages)
+	return languages
+}
+
+// Extensions lists the source file extensions of every known language.
+func (i *IsolateSandbox) Extensions() []string {
+	languages := i.Languages()
+	extensions := make([]string, 0, len(languages))
+	for _, language := range languages {
+		extensions = append(extensions, i.languageConfig(language).FileExtension)
+	}
+	return extensions
+}
+
+// SourceFileName is the name submitted code is written to in the box.
+func (i *IsolateSandbox) SourceFileName(language string) string {
+	return "code" + i.languageConfig(language).FileExtension
+}
+
+// LanguageForFile maps a source file name or URL to a sandbox language, or
+// "" when the extension is not supported.
+func (i *IsolateSandbox) LanguageForFile(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, language := range i.Languages() {
+		if i.languageConfig(language).FileExtension == ext {
+			return language
+		}
+	}
+	return ""
+}
+
+// ScaleLimits applies the language's time and memory multipliers, so slower
+// runtimes such as the JVM get proportionally more room than the problem's
+// base limits.
+func (i *IsolateSandbox) ScaleLimits(language string, timeLimit time.Duration, memoryKb int) (time.Duration, int) {
+	config := i.languageConfig(language)
+	if config.TimeMultiplier > 0 {
+		timeLimit = time.Duration(float64(timeLimit) * config.TimeMultiplier)
+	}
+	if config.MemoryMultiplier > 0 {
+		memoryKb = int(float64(memoryKb) * config.MemoryMultiplier)
+	}
+	return timeLimit, memoryKb
+}
+
+func (i *IsolateSandbox) languageConfig(language string) models.SupportedLanguage {
+	config := getLanguageConfig(language)
+	config.FileExtension = getFileExtension(language)
+
+	i.languageMu.RLock()
+	loaded, ok := i.languages[language]
+	i.languageMu.RUnlock()
+	if !ok {
+		return config
+	}
+
+	if stages := loaded.Stages(); len(stages) > 0 {
+		config.CompilePipeline = stages
+	}
+	if loaded.ExecuteCommand != "" {
+		config.ExecuteCommand = loaded.ExecuteCommand
+	}
+	if loaded.FileExtension != "" {
+		config.FileExtension = loaded.FileExtension
+	}
+	if !loaded.ExecutionProfile.IsEmpty() {
+		config.ExecutionProfile = loaded.ExecutionProfile
+	}
+	config.TimeMultiplier = loaded.TimeMultiplier
+	config.MemoryMultiplier = loaded.MemoryMultiplier
+	return config
+}
@@ -0,0 +1,128 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// pchMountPoint is where the precompiled header directory appears inside
+// compile boxes; GCC picks up bits/stdc++.h.gch from it via -I.
+const pchMountPoint = "/pch"
+
+var umbrellaHeaderPattern = regexp.MustCompile(`(?m)^\s*#\s*include\s*<bits/stdc\+\+\.h>`)
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._+-]+`)
+
+// PreparePCH precompiles bits/stdc++.h on this node for every g++ stage of
+// the C++ pipeline, keyed by compiler version and flags. The header must be
+// built with the same flags the submissions use, otherwise GCC ignores it and
+// falls back to parsing the real header.
+func (i *IsolateSandbox) PreparePCH(ctx context.Context) {
+	if !i.config.PCHEnabled {
+		return
+	}
+
+	for _, stage := range i.compilePipeline("cpp") {
+		if compiler, _ := pchFlags(stage.Command); !strings.HasSuffix(compiler, "g++") {
+			continue
+		}
+
+		dir, err := i.buildPCH(ctx, stage.Command)
+		if err != nil {
+			log.Printf("Precompiled header unavailable, C++ compiles will parse headers: %v", err)
+			continue
+		}
+
+		i.pchMu.Lock()
+		i.pchDirs[stage.Command] = dir
+		i.pchMu.Unlock()
+		log.Printf("Using precompiled bits/stdc++.h from %s", dir)
+	}
+}
+
+func (i *IsolateSandbox) buildPCH(ctx context.Context, compileCmd string) (string, error) {
+	compiler, flags := pchFlags(compileCmd)
+
+	version, err := exec.CommandContext(ctx, compiler, "-dumpfullversion").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s version: %w", compiler, err)
+	}
+
+	key := unsafePathChars.ReplaceAllString(compiler+"-"+strings.TrimSpace(string(version))+"-"+strings.Join(flags, ""), "_")
+	dir := filepath.Join(i.config.PCHDir, key)
+	gch := filepath.Join(dir, "bits", "stdc++.h.gch")
+	if _, err := os.Stat(gch); err == nil {
+		return dir, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(gch), 0755); err != nil {
+		return "", fmt.Errorf("failed to create pch dir: %w", err)
+	}
+
+	wrapper := filepath.Join(dir, "stdc++.h")
+	if err := os.WriteFile(wrapper, []byte("#include <bits/stdc++.h>\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write pch source: %w", err)
+	}
+	defer os.Remove(wrapper)
+
+	// Write to a temporary name so a crash never leaves a truncated header
+	// that other nodes sharing the directory would pick up.
+	tmp := gch + ".tmp"
+	args := append(append([]string{}, flags...), "-x", "c++-header", wrapper, "-o", tmp)
+	if output, err := exec.CommandContext(ctx, compiler, args...).CombinedOutput(); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to precompile header: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	if err := os.Chmod(tmp, 0644); err != nil {
+		return "", fmt.Errorf("failed to publish pch: %w", err)
+	}
+	if err := os.Rename(tmp, gch); err != nil {
+		return "", fmt.Errorf("failed to publish pch: %w", err)
+	}
+
+	return dir, nil
+}
+
+// pchFlags splits a compile command into the compiler and the flags that
+// affect header compatibility, dropping the output and source arguments.
+func pchFlags(compileCmd string) (string, []string) {
+	fields := strings.Fields(compileCmd)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	var flags []string
+	for n := 1; n < len(fields); n++ {
+		switch {
+		case fields[n] == "-o":
+			n++
+		case strings.HasPrefix(fields[n], "-"):
+			flags = append(flags, fields[n])
+		}
+	}
+	return fields[0], flags
+}
+
+// pchFor returns the host PCH directory to mount and the compile command
+// rewritten to use it, or an empty directory when the submission does not
+// include the umbrella header or no PCH was built for the command.
+func (i *IsolateSandbox) pchFor(language string, code []byte, compileCmd string) (string, string) {
+	if language != "cpp" || !umbrellaHeaderPattern.Match(code) {
+		return "", compileCmd
+	}
+
+	i.pchMu.RLock()
+	dir, ok := i.pchDirs[compileCmd]
+	i.pchMu.RUnlock()
+	if !ok {
+		return "", compileCmd
+	}
+
+	compiler, rest, _ := strings.Cut(compileCmd, " ")
+	return dir, compiler + " -I" + pchMountPoint + " " + rest
+}
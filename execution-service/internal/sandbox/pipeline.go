@@ -0,0 +1,77 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"execution_service/internal/models"
+)
+
+const (
+	defaultStageMemoryKb  = 524288 // 512MB default for compilation
+	defaultStageProcesses = 1
+)
+
+func (i *IsolateSandbox) compilePipeline(language string) models.CompilePipeline {
+	return i.languageConfig(language).Stages()
+}
+
+func compileStage(command string) models.CompilePipeline {
+	return models.CompilePipeline{{Name: "compile", Command: command}}
+}
+
+func expandCompileCommand(command, sourceName, entryPoint string) string {
+	command = strings.ReplaceAll(command, "{executable}", "program")
+	command = strings.ReplaceAll(command, "{output}", "program")
+	command = strings.ReplaceAll(command, "{input}", sourceName)
+	return strings.ReplaceAll(command, "{classname}", entryPoint)
+}
+
+// runStage runs one pipeline stage in an existing box. Files left by earlier
+// stages stay in /box; only the stage's own stdout, stderr and meta files are
+// overwritten.
+func (i *IsolateSandbox) runStage(ctx context.Context, boxID int, language string, stage models.CompileStage, command, pchDir string, timeLimit time.Duration) (*CompileResult, error) {
+	memoryLimit := stage.MemoryLimitKb
+	if memoryLimit <= 0 {
+		memoryLimit = defaultStageMemoryKb
+	}
+	processes := stage.Processes
+	if processes <= 0 {
+		processes = defaultStageProcesses
+	}
+
+	profile := i.languageConfig(language).ExecutionProfile
+	mounts := WorkMounts()
+	if pchDir != "" {
+		mounts = append(mounts, Mount{Path: pchMountPoint, Source: pchDir})
+	}
+
+	spec := RunSpec{
+		Command:   command,
+		TimeLimit: timeLimit,
+		MemoryKb:  memoryLimit,
+		Processes: processes,
+		Seccomp:   true,
+		Mounts:    append(mounts, deviceMounts(profile)...),
+		NullStdio: profile.NullStdio,
+		Stdout:    "output.txt",
+		Stderr:    "error.txt",
+		Meta:      "meta.txt",
+	}
+	if err := i.Run(ctx, boxID, spec); err != nil {
+		return i.parseCompilationResult(boxID, err, timeLimit, memoryLimit)
+	}
+
+	boxDir := i.GetBoxDir(boxID)
+	output, _ := os.ReadFile(filepath.Join(boxDir, "output.txt"))
+	errorMsg, _ := os.ReadFile(filepath.Join(boxDir, "error.txt"))
+
+	return &CompileResult{
+		Success: true,
+		Output:  string(output),
+		Error:   string(errorMsg),
+	}, nil
+}
@@ -0,0 +1,242 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"execution_service/internal/config"
+)
+
+// runscFirstBoxID keeps ad hoc boxes clear of pooled ones; gVisor boxes are
+// plain directories, so there is no isolate-style ID range to respect.
+const runscFirstBoxID = 1 << 16
+
+// runscBackend runs each command in a rootless gVisor sandbox for hosts that
+// cannot install isolate. Limits are enforced with rlimits inside the
+// sandbox and a wall clock outside it. There are no cgroups, so CPU time and
+// peak memory are measured on the runsc process tree and include gVisor's
+// own overhead.
+type runscBackend struct {
+	config  *config.IsolateConfig
+	nextBox atomic.Int64
+}
+
+func newRunscBackend(cfg *config.IsolateConfig) *runscBackend {
+	return &runscBackend{config: cfg}
+}
+
+func (b *runscBackend) Name() string {
+	return BackendRunsc
+}
+
+func (b *runscBackend) Capabilities() Capabilities {
+	return Capabilities{
+		Backend:      BackendRunsc,
+		ProcessLimit: true,
+	}
+}
+
+func (b *runscBackend) Version(ctx context.Context) string {
+	return toolVersion(ctx, b.config.RunscPath, "--version")
+}
+
+func (b *runscBackend) CreateBox() (int, error) {
+	boxID := runscFirstBoxID + int(b.nextBox.Add(1))
+	if err := b.InitBox(boxID); err != nil {
+		return 0, err
+	}
+	return boxID, nil
+}
+
+func (b *runscBackend) InitBox(boxID int) error {
+	boxDir := b.BoxDir(boxID)
+	if err := os.RemoveAll(boxDir); err != nil {
+		return fmt.Errorf("failed to clear box dir: %w", err)
+	}
+	if err := os.MkdirAll(boxDir, 0755); err != nil {
+		return fmt.Errorf("failed to create box dir: %w", err)
+	}
+	return nil
+}
+
+func (b *runscBackend) CleanupBox(boxID int) {
+	os.RemoveAll(b.BoxDir(boxID))
+}
+
+func (b *runscBackend) BoxDir(boxID int) string {
+	return filepath.Join(b.config.BoxRoot, fmt.Sprintf("%d", boxID))
+}
+
+func (b *runscBackend) Start(ctx context.Context, boxID int, spec RunSpec) (Process, error) {
+	boxDir := b.BoxDir(boxID)
+	timeSec := limitSeconds(spec.TimeLimit)
+	wallLimit := time.Duration(timeSec*2)*time.Second + 500*time.Millisecond
+
+	runCtx, cancel := context.WithTimeout(ctx, wallLimit)
+	cmd := exec.CommandContext(runCtx, b.config.RunscPath, b.args(boxDir, timeSec, spec)...)
+	cmd.Dir = boxDir
+
+	process := &runscProcess{cmd: cmd, cancel: cancel, runCtx: runCtx, spec: spec, boxDir: boxDir}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = spec.StdinPipe, spec.StdoutPipe, spec.StderrPipe
+	if spec.Stdin != "" {
+		file, err := process.open(spec.Stdin, os.O_RDONLY)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Stdin = file
+	}
+	if spec.Stdout != "" {
+		file, err := process.open(spec.Stdout, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Stdout = file
+	}
+	if spec.Stderr != "" {
+		file, err := process.open(spec.Stderr, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Stderr = file
+	}
+
+	process.started = time.Now()
+	if err := cmd.Start(); err != nil {
+		process.close()
+		return nil, err
+	}
+	return process, nil
+}
+
+func (b *runscBackend) args(boxDir string, timeSec int, spec RunSpec) []string {
+	processes := spec.Processes
+	if processes <= 0 {
+		processes = 1
+	}
+
+	network := "--network=none"
+	if spec.Network {
+		network = "--network=host"
+	}
+
+	args := []string{
+		"--rootless",
+		network,
+		"--ignore-cgroups",
+		"do",
+		"--cwd=/box",
+		"--volume=" + boxDir + ":/box",
+	}
+	// System directories come from the host root, which runsc overlays
+	for _, mount := range spec.Mounts {
+		if mount.Source != "" {
+			args = append(args, "--volume="+mount.Source+":"+mount.Path)
+		}
+	}
+
+	args = append(args,
+		"--",
+		"prlimit",
+		"--cpu="+strconv.Itoa(timeSec)+":"+strconv.Itoa(timeSec+1),
+		"--as="+strconv.Itoa(spec.MemoryKb*1024),
+		"--nproc="+strconv.Itoa(processes),
+		"--stack="+strconv.Itoa(65536*1024),
+		"--fsize="+strconv.Itoa(16384*1024),
+		"--",
+		"/usr/bin/env",
+		"HOME=/tmp",
+		"PATH=/usr/bin:/bin",
+	)
+	args = append(args, spec.Env...)
+	return append(args, "/bin/bash", "-c", spec.Command)
+}
+
+type runscProcess struct {
+	cmd     *exec.Cmd
+	cancel  context.CancelFunc
+	runCtx  context.Context
+	spec    RunSpec
+	boxDir  string
+	files   []*os.File
+	started time.Time
+}
+
+// Wait reaps the run and writes an isolate-style meta file so results are
+// parsed the same way for both backends.
+func (p *runscProcess) Wait() error {
+	waitErr := p.cmd.Wait()
+	wall := time.Since(p.started)
+	timedOut := p.runCtx.Err() == context.DeadlineExceeded
+	p.close()
+
+	state := p.cmd.ProcessState
+	var cpu time.Duration
+	var maxRSS int64
+	exitCode := -1
+	if state != nil {
+		cpu = state.UserTime() + state.SystemTime()
+		if usage, ok := state.SysUsage().(*syscall.Rusage); ok {
+			maxRSS = usage.Maxrss
+		}
+		exitCode = state.ExitCode()
+	}
+
+	var meta strings.Builder
+	fmt.Fprintf(&meta, "time:%.3f\ntime-wall:%.3f\nmax-rss:%d\n", cpu.Seconds(), wall.Seconds(), maxRSS)
+
+	status := ""
+	switch {
+	case timedOut || cpu >= time.Duration(limitSeconds(p.spec.TimeLimit))*time.Second:
+		status = "TO"
+		meta.WriteString("killed:1\nmessage:Time limit exceeded\n")
+	case exitCode > 128:
+		status = "SG"
+		fmt.Fprintf(&meta, "exitsig:%d\nmessage:Caught fatal signal %d\n", exitCode-128, exitCode-128)
+	case exitCode != 0:
+		status = "RE"
+		fmt.Fprintf(&meta, "exitcode:%d\nmessage:Exited with error status %d\n", exitCode, exitCode)
+	}
+	if status != "" {
+		meta.WriteString("status:" + status + "\n")
+	}
+
+	if p.spec.Meta != "" {
+		if err := os.WriteFile(filepath.Join(p.boxDir, p.spec.Meta), []byte(meta.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write meta file: %w", err)
+		}
+	}
+
+	if waitErr != nil {
+		return waitErr
+	}
+	if status != "" {
+		return fmt.Errorf("run failed with status %s", status)
+	}
+	return nil
+}
+
+// open opens a stdio file in the box, releasing everything on failure.
+func (p *runscProcess) open(name string, flag int) (*os.File, error) {
+	file, err := os.OpenFile(filepath.Join(p.boxDir, name), flag, 0644)
+	if err != nil {
+		p.close()
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	p.files = append(p.files, file)
+	return file, nil
+}
+
+func (p *runscProcess) close() {
+	for _, file := range p.files {
+		file.Close()
+	}
+	p.cancel()
+}
@@ -215,7 +215,7 @@ func (sv *SecurityValidator) hasSandboxEscapeAttempts(boxID int) bool {
 func (sv *SecurityValidator) GetDefaultSecurityConfig() *SecurityConfig {
 	return &SecurityConfig{
 		MaxCodeSize:       65536, // 64KB
-		AllowedExtensions: []string{".cpp", ".c", ".java", ".py", ".go", ".rs", ".js", ".ts"},
+		AllowedExtensions: []string{".cpp", ".c", ".java", ".py", ".go", ".rs", ".kt", ".cs", ".rb"},
 		BlacklistedPatterns: []string{
 			`(?i)system\s*\(`,         // system() calls
 			`(?i)exec\s*\(`,           // exec() calls
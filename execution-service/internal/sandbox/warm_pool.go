@@ -0,0 +1,310 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// ErrNoWarmup means a language has no runtime worth warming.
+var ErrNoWarmup = errors.New("language has no warm-up")
+
+// warmupCommands start each runtime once in a box so its files are hot in
+// the page cache before a submission needs them. Natively compiled
+// languages gain nothing from this and are not warmed.
+var warmupCommands = map[string]string{
+	"python": "python3 -c pass",
+	"java":   "java -version",
+	"kotlin": "java -version",
+	"ruby":   "ruby -e 0",
+	"csharp": "dotnet --version",
+}
+
+const (
+	warmupTimeLimit = 10 * time.Second
+	warmupMemoryKb  = 524288 // 512MB, enough for a JVM
+)
+
+// WarmPoolStats describes one language's warm boxes. A hit is an execution
+// that found a warm box ready; OldestAgeSeconds is how long the oldest ready
+// box has been waiting since it was warmed.
+type WarmPoolStats struct {
+	Language         string  `json:"language"`
+	Target           int     `json:"target"`
+	Ready            int     `json:"ready"`
+	Hits             int64   `json:"hits"`
+	Misses           int64   `json:"misses"`
+	HitRate          float64 `json:"hit_rate"`
+	Reinits          int64   `json:"reinits"`
+	OldestAgeSeconds float64 `json:"oldest_age_seconds"`
+}
+
+type warmBox struct {
+	id       int
+	warmedAt time.Time
+}
+
+// warmPool holds pooled boxes set aside for one language. filling counts
+// boxes being warmed, so concurrent refills do not overshoot the target.
+type warmPool struct {
+	target  int
+	filling int
+	ready   []warmBox
+	stats   WarmPoolStats
+}
+
+// SetWarmListener is called after every warm pool event, for metrics.
+func (bp *BoxPool) SetWarmListener(listener func(event string, stats WarmPoolStats)) {
+	bp.warmListener = listener
+}
+
+// HasWarmup reports whether a language can be warmed.
+func HasWarmup(language string) bool {
+	_, ok := warmupCommands[language]
+	return ok
+}
+
+// ResizeWarm sets how many of the pool's boxes are kept warm for a language.
+// Boxes above a lowered target go back to the general pool at once; a raised
+// target is filled in the background from idle pooled boxes.
+func (bp *BoxPool) ResizeWarm(language string, target int) error {
+	if !HasWarmup(language) {
+		return fmt.Errorf("%w: %s", ErrNoWarmup, language)
+	}
+	if target < 0 || target > bp.size {
+		return fmt.Errorf("warm pool size must be between 0 and %d", bp.size)
+	}
+
+	bp.mu.Lock()
+	pool := bp.warmPool(language)
+	pool.target = target
+	var surplus []warmBox
+	if len(pool.ready) > target {
+		surplus = pool.ready[target:]
+		pool.ready = pool.ready[:target]
+	}
+	bp.mu.Unlock()
+
+	for _, box := range surplus {
+		bp.returnWarm(box.id)
+	}
+	go bp.fillWarm(language)
+	return nil
+}
+
+// FlushWarm re-initializes a language's ready boxes, dropping whatever the
+// runtime left behind, and warms them again.
+func (bp *BoxPool) FlushWarm(language string) error {
+	if !HasWarmup(language) {
+		return fmt.Errorf("%w: %s", ErrNoWarmup, language)
+	}
+
+	bp.mu.Lock()
+	pool := bp.warmPool(language)
+	flushed := pool.ready
+	pool.ready = nil
+	bp.mu.Unlock()
+
+	for _, box := range flushed {
+		if err := bp.initBox(box.id); err != nil {
+			log.Printf("Dropping warm box %d: %v", box.id, err)
+			bp.dropBox(box.id)
+			continue
+		}
+		bp.recordWarm(language, "reinit", func(s *WarmPoolStats) {
+			s.Reinits++
+		})
+		bp.returnWarm(box.id)
+	}
+	go bp.fillWarm(language)
+	return nil
+}
+
+// WarmStats lists every language that has had a warm pool, by language.
+func (bp *BoxPool) WarmStats() []WarmPoolStats {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	stats := make([]WarmPoolStats, 0, len(bp.warm))
+	for language := range bp.warm {
+		stats = append(stats, bp.warmStatsLocked(language))
+	}
+	sort.Slice(stats, func(a, b int) bool { return stats[a].Language < stats[b].Language })
+	return stats
+}
+
+// acquireWarm hands out a ready warm box for the language, or falls back to
+// Acquire. Languages without a warm pool are not counted.
+func (bp *BoxPool) acquireWarm(language string) (int, error) {
+	bp.mu.Lock()
+	pool, ok := bp.warm[language]
+	if !ok || pool.target == 0 {
+		bp.mu.Unlock()
+		return bp.Acquire()
+	}
+	if len(pool.ready) == 0 {
+		bp.mu.Unlock()
+		bp.recordWarm(language, "miss", func(s *WarmPoolStats) {
+			s.Misses++
+		})
+		go bp.fillWarm(language)
+		return bp.Acquire()
+	}
+	// The most recently warmed box is the likeliest to still be hot
+	box := pool.ready[len(pool.ready)-1]
+	pool.ready = pool.ready[:len(pool.ready)-1]
+	bp.mu.Unlock()
+
+	bp.recordWarm(language, "hit", func(s *WarmPoolStats) {
+		s.Hits++
+	})
+	bp.record("box_acquire", "warm", func(s *BoxPoolStats) {
+		s.Acquired++
+		s.Warm--
+		s.InUse++
+	})
+	go bp.fillWarm(language)
+	return box.id, nil
+}
+
+// fillWarm warms idle pooled boxes until the language reaches its target or
+// the general pool runs dry.
+func (bp *BoxPool) fillWarm(language string) {
+	for {
+		bp.mu.Lock()
+		pool := bp.warm[language]
+		if len(pool.ready)+pool.filling >= pool.target {
+			bp.mu.Unlock()
+			return
+		}
+		pool.filling++
+		bp.mu.Unlock()
+
+		boxID, ok := bp.takeIdle()
+		if ok {
+			ok = bp.warmUp(language, boxID)
+		}
+
+		bp.mu.Lock()
+		pool.filling--
+		bp.mu.Unlock()
+		if !ok {
+			return
+		}
+	}
+}
+
+// takeIdle moves an idle box out of the general pool without falling back to
+// a fresh one.
+func (bp *BoxPool) takeIdle() (int, bool) {
+	select {
+	case boxID := <-bp.available:
+		bp.record("box_warm", "taken", func(s *BoxPoolStats) {
+			s.Available--
+			s.Warm++
+		})
+		return boxID, true
+	default:
+		return 0, false
+	}
+}
+
+// warmUp runs the language's warm-up in the box and adds it to the warm
+// pool, or hands the box back when warming fails or the target has dropped.
+func (bp *BoxPool) warmUp(language string, boxID int) bool {
+	spec := bp.sandbox.runSpec(RunOptions{}, language, warmupTimeLimit, warmupMemoryKb)
+	spec.Command = warmupCommands[language]
+	ctx, cancel := context.WithTimeout(context.Background(), 2*warmupTimeLimit)
+	err := bp.sandbox.Run(ctx, boxID, spec)
+	cancel()
+	if err == nil {
+		err = bp.resetBox(boxID)
+	}
+	if err != nil {
+		log.Printf("Failed to warm box %d for %s: %v", boxID, language, err)
+		if err := bp.initBox(boxID); err != nil {
+			bp.dropBox(boxID)
+			return false
+		}
+		bp.recordWarm(language, "reinit", func(s *WarmPoolStats) {
+			s.Reinits++
+		})
+		bp.returnWarm(boxID)
+		return false
+	}
+
+	bp.mu.Lock()
+	pool := bp.warm[language]
+	keep := len(pool.ready) < pool.target
+	if keep {
+		pool.ready = append(pool.ready, warmBox{id: boxID, warmedAt: time.Now()})
+	}
+	bp.mu.Unlock()
+	if !keep {
+		bp.returnWarm(boxID)
+		return false
+	}
+	bp.recordWarm(language, "warmed", nil)
+	return true
+}
+
+// returnWarm puts a box taken for warming back in the general pool.
+func (bp *BoxPool) returnWarm(boxID int) {
+	bp.record("box_warm", "returned", func(s *BoxPoolStats) {
+		s.Warm--
+		s.Available++
+	})
+	bp.available <- boxID
+}
+
+// dropBox removes a warm box that could not be re-initialized.
+func (bp *BoxPool) dropBox(boxID int) {
+	bp.record("box_warm", "dropped", func(s *BoxPoolStats) {
+		delete(bp.pooled, boxID)
+		s.Dropped++
+		s.Size--
+		s.Warm--
+	})
+}
+
+// warmPool returns the language's pool, creating it. Callers hold bp.mu.
+func (bp *BoxPool) warmPool(language string) *warmPool {
+	pool, ok := bp.warm[language]
+	if !ok {
+		pool = &warmPool{stats: WarmPoolStats{Language: language}}
+		bp.warm[language] = pool
+	}
+	return pool
+}
+
+// warmStatsLocked fills in a language's derived stats. Callers hold bp.mu.
+func (bp *BoxPool) warmStatsLocked(language string) WarmPoolStats {
+	pool := bp.warm[language]
+	stats := pool.stats
+	stats.Target = pool.target
+	stats.Ready = len(pool.ready)
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	for _, box := range pool.ready {
+		stats.OldestAgeSeconds = max(stats.OldestAgeSeconds, time.Since(box.warmedAt).Seconds())
+	}
+	return stats
+}
+
+func (bp *BoxPool) recordWarm(language, event string, update func(*WarmPoolStats)) {
+	bp.mu.Lock()
+	pool := bp.warmPool(language)
+	if update != nil {
+		update(&pool.stats)
+	}
+	stats := bp.warmStatsLocked(language)
+	bp.mu.Unlock()
+
+	if bp.warmListener != nil {
+		bp.warmListener(event, stats)
+	}
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"execution_service/internal/config"
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+)
+
+var (
+	ErrInvalidAPIKey      = errors.New("invalid API key")
+	ErrAPIKeyRateLimited  = errors.New("API key rate limit exceeded")
+	ErrAPIKeyLimitReached = errors.New("API key limit reached")
+)
+
+// apiKeyTokenPrefix marks secrets as API keys so they are easy to spot in
+// leaked configs and logs.
+const apiKeyTokenPrefix = "chk_"
+
+// APIKeyService issues and checks self-service API keys. Keys are stored as
+// SHA-256 hashes; their 256-bit secrets make salting unnecessary. Rate
+// limits are enforced per key on each node, like the JWT rate limit.
+type APIKeyService struct {
+	db       *database.DB
+	config   *config.APIKeysConfig
+	observer func(result string)
+
+	mu      sync.Mutex
+	windows map[int64][]time.Time
+}
+
+func NewAPIKeyService(db *database.DB, cfg *config.APIKeysConfig) *APIKeyService {
+	return &APIKeyService{
+		db:      db,
+		config:  cfg,
+		windows: make(map[int64][]time.Time),
+	}
+}
+
+// SetObserver is called with the outcome of every authentication, for
+// metrics.
+func (s *APIKeyService) SetObserver(observer func(result string)) {
+	s.observer = observer
+}
+
+// MaxRateLimit is the default and highest per-key limit.
+func (s *APIKeyService) MaxRateLimit() int {
+	return s.config.RateLimit
+}
+
+// Issue creates a key and returns it with its secret, which is never stored
+// and cannot be shown again. A zero rateLimit uses the default.
+func (s *APIKeyService) Issue(ctx context.Context, userID int64, name string, scopes []string, rateLimit int, expiresAt *time.Time) (*models.APIKey, string, error) {
+	active, err := s.db.CountActiveAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if active >= s.config.MaxPerUser {
+		return nil, "", fmt.Errorf("%w: %d active keys", ErrAPIKeyLimitReached, active)
+	}
+
+	if rateLimit <= 0 {
+		rateLimit = s.config.RateLimit
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	token := apiKeyTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		KeyPrefix: token[:len(apiKeyTokenPrefix)+8],
+		KeyHash:   hashAPIKey(token),
+		Scopes:    scopes,
+		RateLimit: rateLimit,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.db.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	return key, token, nil
+}
+
+// Authenticate resolves a presented key, counting the use and applying the
+// key's rate limit.
+func (s *APIKeyService) Authenticate(ctx context.Context, token string) (*models.APIKey, error) {
+	if !strings.HasPrefix(token, apiKeyTokenPrefix) {
+		s.observe("invalid")
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.db.UseAPIKey(ctx, hashAPIKey(token))
+	if err != nil {
+		s.observe("error")
+		return nil, err
+	}
+	if key == nil {
+		s.observe("invalid")
+		return nil, ErrInvalidAPIKey
+	}
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		s.observe("expired")
+		return nil, fmt.Errorf("%w: key expired", ErrInvalidAPIKey)
+	}
+	if !s.allow(key) {
+		s.observe("rate_limited")
+		return nil, ErrAPIKeyRateLimited
+	}
+
+	s.observe("ok")
+	return key, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, userID int64) ([]models.APIKey, error) {
+	return s.db.GetUserAPIKeys(ctx, userID)
+}
+
+// Revoke revokes one of the user's keys; a zero userID revokes any key. It
+// returns nil when there is no such unrevoked key.
+func (s *APIKeyService) Revoke(ctx context.Context, keyID, userID int64) (*models.APIKey, error) {
+	key, err := s.db.RevokeAPIKey(ctx, keyID, userID)
+	if err != nil || key == nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.windows, key.ID)
+	s.mu.Unlock()
+	return key, nil
+}
+
+// allow applies a sliding one-minute window to the key.
+func (s *APIKeyService) allow(key *models.APIKey) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-time.Minute)
+	recent := s.windows[key.ID][:0]
+	for _, t := range s.windows[key.ID] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= key.RateLimit {
+		s.windows[key.ID] = recent
+		return false
+	}
+	s.windows[key.ID] = append(recent, now)
+
+	if len(s.windows) > 10000 {
+		for id, window := range s.windows {
+			if len(window) == 0 || window[len(window)-1].Before(cutoff) {
+				delete(s.windows, id)
+			}
+		}
+	}
+	return true
+}
+
+func (s *APIKeyService) observe(result string) {
+	if s.observer != nil {
+		s.observer(result)
+	}
+}
+
+func hashAPIKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
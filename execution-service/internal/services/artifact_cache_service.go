@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"execution_service/internal/sandbox"
+	"execution_service/internal/storage"
+)
+
+// ArtifactCacheService keeps successful builds in MinIO under
+// sandbox.ArtifactKey, so rejudges and resubmissions of the same code on the
+// same toolchain skip compiling. Cache failures only cost a recompile.
+type ArtifactCacheService struct {
+	storage *storage.MinIOClient
+	metrics *MetricsService
+}
+
+func NewArtifactCacheService(s *storage.MinIOClient, metrics *MetricsService) *ArtifactCacheService {
+	return &ArtifactCacheService{
+		storage: s,
+		metrics: metrics,
+	}
+}
+
+// Get returns the cached build as a successful compile result, or nil.
+func (ac *ArtifactCacheService) Get(ctx context.Context, key string) *sandbox.CompileResult {
+	if key == "" {
+		return nil
+	}
+
+	artifact, entryPoint, err := ac.storage.DownloadArtifact(ctx, key)
+	if err != nil {
+		log.Printf("Failed to read cached build %s: %v", key, err)
+	}
+	if artifact == nil {
+		ac.record(false)
+		return nil
+	}
+
+	ac.record(true)
+	return &sandbox.CompileResult{
+		Success:    true,
+		Output:     "Reused cached build",
+		EntryPoint: entryPoint,
+		Artifact:   artifact,
+	}
+}
+
+// Put stores a successful build; failed builds are never cached.
+func (ac *ArtifactCacheService) Put(ctx context.Context, key string, result *sandbox.CompileResult) {
+	if key == "" || !result.Success || result.Artifact == nil {
+		return
+	}
+	if err := ac.storage.UploadArtifact(ctx, key, result.Artifact, result.EntryPoint); err != nil {
+		log.Printf("Failed to cache build %s: %v", key, err)
+	}
+}
+
+func (ac *ArtifactCacheService) record(hit bool) {
+	if ac.metrics == nil {
+		return
+	}
+	if hit {
+		ac.metrics.RecordCacheHit("compile_artifact")
+	} else {
+		ac.metrics.RecordCacheMiss("compile_artifact")
+	}
+}
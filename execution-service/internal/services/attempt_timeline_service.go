@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"execution_service/internal/cache"
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+)
+
+// maxTimelineAttempts bounds the submissions read for one timeline.
+const maxTimelineAttempts = 1000
+
+// AttemptTimelineService builds compacted attempt timelines and caches them
+// until the user's next verdict on the problem.
+type AttemptTimelineService struct {
+	db    *database.DB
+	cache *cache.ValkeyClient
+}
+
+func NewAttemptTimelineService(db *database.DB, cache *cache.ValkeyClient) *AttemptTimelineService {
+	return &AttemptTimelineService{
+		db:    db,
+		cache: cache,
+	}
+}
+
+func (s *AttemptTimelineService) Get(ctx context.Context, userID, problemID int64) (*models.AttemptTimeline, error) {
+	if s.cache != nil {
+		if timeline, err := s.cache.GetCachedAttemptTimeline(ctx, userID, problemID); err == nil {
+			return timeline, nil
+		}
+	}
+
+	submissions, err := s.db.GetUserProblemAttempts(ctx, userID, problemID, maxTimelineAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := compactAttempts(userID, problemID, submissions)
+	if s.cache != nil {
+		if err := s.cache.CacheAttemptTimeline(ctx, timeline); err != nil {
+			log.Printf("Failed to cache attempt timeline: %v", err)
+		}
+	}
+	return timeline, nil
+}
+
+func (s *AttemptTimelineService) Invalidate(ctx context.Context, userID, problemID int64) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.InvalidateAttemptTimeline(ctx, userID, problemID); err != nil {
+		log.Printf("Failed to invalidate attempt timeline for user %d problem %d: %v", userID, problemID, err)
+	}
+}
+
+// compactAttempts folds submissions, oldest first, into timeline entries.
+// Consecutive attempts join an entry while verdict, tests passed and score
+// stay the same.
+func compactAttempts(userID, problemID int64, submissions []models.Submission) *models.AttemptTimeline {
+	timeline := &models.AttemptTimeline{
+		UserID:        userID,
+		ProblemID:     problemID,
+		TotalAttempts: len(submissions),
+		Entries:       []models.AttemptEntry{},
+		ComputedAt:    time.Now(),
+	}
+
+	for i, submission := range submissions {
+		attempt := i + 1
+		if submission.Verdict == models.VerdictAccepted && timeline.SolvedAtAttempt == nil {
+			timeline.SolvedAtAttempt = &attempt
+		}
+
+		if n := len(timeline.Entries); n > 0 {
+			last := &timeline.Entries[n-1]
+			if last.Verdict == submission.Verdict && last.TestCasesPassed == submission.TestCasesPassed && last.Score == submission.Score {
+				last.LastAttempt = attempt
+				last.SubmissionIDs = append(last.SubmissionIDs, submission.ID)
+				last.LastSubmittedAt = submission.SubmittedAt
+				continue
+			}
+		}
+
+		entry := models.AttemptEntry{
+			FirstAttempt:     attempt,
+			LastAttempt:      attempt,
+			SubmissionIDs:    []int64{submission.ID},
+			Verdict:          submission.Verdict,
+			TestCasesPassed:  submission.TestCasesPassed,
+			TestCasesTotal:   submission.TestCasesTotal,
+			Score:            submission.Score,
+			FirstSubmittedAt: submission.SubmittedAt,
+			LastSubmittedAt:  submission.SubmittedAt,
+		}
+		if n := len(timeline.Entries); n > 0 {
+			previous := timeline.Entries[n-1]
+			entry.PassedDelta = entry.TestCasesPassed - previous.TestCasesPassed
+			entry.ScoreDelta = entry.Score - previous.Score
+			entry.SincePreviousSeconds = int64(entry.FirstSubmittedAt.Sub(previous.LastSubmittedAt).Seconds())
+		}
+		timeline.Entries = append(timeline.Entries, entry)
+	}
+
+	return timeline
+}
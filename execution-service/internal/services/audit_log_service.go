@@ -119,20 +119,54 @@ func (a *AuditLogService) CleanupOldLogs(ctx context.Context, olderThan time.Dur
 
 // Predefined admin actions for consistency
 const (
-	AdminActionUserCreate        = "USER_CREATE"
-	AdminActionUserUpdate        = "USER_UPDATE"
-	AdminActionUserDelete        = "USER_DELETE"
-	AdminActionUserBan           = "USER_BAN"
-	AdminActionUserUnban         = "USER_UNBAN"
-	AdminActionProblemCreate     = "PROBLEM_CREATE"
-	AdminActionProblemUpdate     = "PROBLEM_UPDATE"
-	AdminActionProblemDelete     = "PROBLEM_DELETE"
-	AdminActionSubmissionRejudge = "SUBMISSION_REJUDGE"
-	AdminActionWorkerScale       = "WORKER_SCALE"
-	AdminActionSystemConfig      = "SYSTEM_CONFIG"
-	AdminActionBoxCleanup        = "BOX_CLEANUP"
-	AdminActionRoleAssign        = "ROLE_ASSIGN"
-	AdminActionRoleRevoke        = "ROLE_REVOKE"
+	AdminActionUserCreate         = "USER_CREATE"
+	AdminActionUserUpdate         = "USER_UPDATE"
+	AdminActionUserDelete         = "USER_DELETE"
+	AdminActionUserBan            = "USER_BAN"
+	AdminActionUserUnban          = "USER_UNBAN"
+	AdminActionProblemCreate      = "PROBLEM_CREATE"
+	AdminActionProblemUpdate      = "PROBLEM_UPDATE"
+	AdminActionProblemDelete      = "PROBLEM_DELETE"
+	AdminActionSubmissionRejudge  = "SUBMISSION_REJUDGE"
+	AdminActionWorkerScale        = "WORKER_SCALE"
+	AdminActionSystemConfig       = "SYSTEM_CONFIG"
+	AdminActionBoxCleanup         = "BOX_CLEANUP"
+	AdminActionRoleAssign         = "ROLE_ASSIGN"
+	AdminActionRoleRevoke         = "ROLE_REVOKE"
+	AdminActionLimitOverride      = "LIMIT_OVERRIDE"
+	AdminActionLimitOverrideClear = "LIMIT_OVERRIDE_CLEAR"
+	AdminActionPlatformLimits     = "PLATFORM_LIMITS_UPDATE"
+	AdminActionSubmissionTransfer = "SUBMISSION_TRANSFER"
+	AdminActionDiagnostics        = "DIAGNOSTICS_BUNDLE"
+	AdminActionEnvironmentPin     = "ENVIRONMENT_PIN"
+	AdminActionEnvironmentUnpin   = "ENVIRONMENT_UNPIN"
+	AdminActionShadowStart        = "SHADOW_START"
+	AdminActionShadowStop         = "SHADOW_STOP"
+	AdminActionCanaryAdd          = "CANARY_ADD"
+	AdminActionCanaryRemove       = "CANARY_REMOVE"
+	AdminActionConsistencyRun     = "CONSISTENCY_RUN"
+	AdminActionProblemRejudge     = "PROBLEM_REJUDGE"
+	AdminActionScoringUpdate      = "SCORING_UPDATE"
+	AdminActionDLQRequeue         = "DLQ_REQUEUE"
+	AdminActionDLQReplay          = "DLQ_REPLAY"
+	AdminActionDLQPurge           = "DLQ_PURGE"
+	AdminActionNodeDrain          = "NODE_DRAIN"
+	AdminActionCacheInvalidate    = "CACHE_INVALIDATE"
+	AdminActionSubmissionDebug    = "SUBMISSION_DEBUG"
+	AdminActionTrustedSubmission  = "TRUSTED_SUBMISSION"
+	AdminActionLanguageRefresh    = "LANGUAGE_REFRESH"
+	AdminActionLanguageCreate     = "LANGUAGE_CREATE"
+	AdminActionLanguageUpdate     = "LANGUAGE_UPDATE"
+	AdminActionLanguageDelete     = "LANGUAGE_DELETE"
+	AdminActionWarmPoolResize     = "WARM_POOL_RESIZE"
+	AdminActionWarmPoolFlush      = "WARM_POOL_FLUSH"
+	AdminActionInputValidation    = "INPUT_VALIDATION"
+	AdminActionUserStatsRepair    = "USER_STATS_REPAIR"
+	AdminActionSubmissionCodeRead = "SUBMISSION_CODE_READ"
+	AdminActionPlagiarismReview   = "PLAGIARISM_REVIEW"
+	AdminActionPlagiarismRun      = "PLAGIARISM_CONTEST_RUN"
+	AdminActionBoilerplateAdd     = "PLAGIARISM_BOILERPLATE_ADD"
+	AdminActionBoilerplateRemove  = "PLAGIARISM_BOILERPLATE_REMOVE"
 )
 
 // Predefined security events
@@ -143,6 +177,9 @@ const (
 	SecurityEventRateLimit      = "RATE_LIMIT"
 	SecurityEventSuspiciousCode = "SUSPICIOUS_CODE"
 	SecurityEventResourceAbuse  = "RESOURCE_ABUSE"
+	SecurityEventServiceScope   = "SERVICE_SCOPE_USE"
+	SecurityEventAPIKeyIssue    = "API_KEY_ISSUE"
+	SecurityEventAPIKeyRevoke   = "API_KEY_REVOKE"
 )
 
 // Severity levels
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"execution_service/internal/cache"
+	"execution_service/internal/models"
+	"execution_service/internal/queue"
+	"execution_service/internal/rbac"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// CacheInvalidationService drops cached data ahead of its TTL. The node
+// handling a request drops the shared Valkey entries, then broadcasts the
+// targets so every node, itself included, drops its in-memory copies.
+type CacheInvalidationService struct {
+	cache         *cache.ValkeyClient
+	queue         *queue.RabbitMQClient
+	node          string
+	shadow        *ShadowJudgingService
+	languages     *LanguageService
+	rbac          *rbac.RBACService
+	retryInterval time.Duration
+}
+
+func NewCacheInvalidationService(cache *cache.ValkeyClient, q *queue.RabbitMQClient, node string) *CacheInvalidationService {
+	return &CacheInvalidationService{
+		cache:         cache,
+		queue:         q,
+		node:          node,
+		retryInterval: 10 * time.Second,
+	}
+}
+
+func (cs *CacheInvalidationService) SetShadowJudging(shadow *ShadowJudgingService) {
+	cs.shadow = shadow
+}
+
+func (cs *CacheInvalidationService) SetLanguageService(languages *LanguageService) {
+	cs.languages = languages
+}
+
+func (cs *CacheInvalidationService) SetRBAC(rbac *rbac.RBACService) {
+	cs.rbac = rbac
+}
+
+// Invalidate drops the targets' shared entries and broadcasts them to every
+// node. Failures on one target do not stop the others.
+func (cs *CacheInvalidationService) Invalidate(ctx context.Context, targets []models.CacheTarget) error {
+	var errs []error
+	for _, target := range targets {
+		if err := cs.invalidateShared(ctx, target); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", target, err))
+		}
+	}
+
+	body, err := json.Marshal(&models.CacheInvalidation{Targets: targets, Origin: cs.node, IssuedAt: time.Now()})
+	if err == nil {
+		err = cs.queue.PublishBroadcast(ctx, body)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("broadcast: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (cs *CacheInvalidationService) invalidateShared(ctx context.Context, target models.CacheTarget) error {
+	switch target.Kind {
+	case models.CacheTargetProblem:
+		return cs.cache.InvalidateProblem(ctx, target.ID)
+	case models.CacheTargetLanguage:
+		return cs.cache.InvalidateLanguage(ctx, target.Language)
+	case models.CacheTargetUserPerms:
+		if cs.rbac != nil {
+			return cs.rbac.InvalidateUser(ctx, target.ID)
+		}
+	case models.CacheTargetAllTestCases:
+		return cs.cache.InvalidateAllTestCases(ctx)
+	}
+	return nil
+}
+
+// invalidateLocal drops this node's in-memory copies of a target.
+func (cs *CacheInvalidationService) invalidateLocal(ctx context.Context, target models.CacheTarget) error {
+	switch target.Kind {
+	case models.CacheTargetProblem:
+		if cs.shadow != nil {
+			cs.shadow.forget(target.ID)
+		}
+	case models.CacheTargetLanguage:
+		if cs.languages != nil {
+			_, err := cs.languages.Refresh(ctx)
+			return err
+		}
+	case models.CacheTargetUserPerms:
+		if cs.rbac != nil {
+			if err := cs.rbac.ReloadPolicy(); err != nil {
+				return err
+			}
+			// Decisions cached from the old policy before this node reloaded
+			return cs.rbac.InvalidateUser(ctx, target.ID)
+		}
+	}
+	return nil
+}
+
+// Start applies broadcast invalidations until ctx is cancelled,
+// resubscribing if the consumer channel closes.
+func (cs *CacheInvalidationService) Start(ctx context.Context) {
+	for {
+		msgs, err := cs.queue.ConsumeBroadcast(ctx)
+		if err != nil {
+			log.Printf("Failed to consume cache invalidations: %v", err)
+		} else {
+			cs.consume(ctx, msgs)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cs.retryInterval):
+		}
+	}
+}
+
+func (cs *CacheInvalidationService) consume(ctx context.Context, msgs <-chan amqp.Delivery) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+
+			var invalidation models.CacheInvalidation
+			body, err := cs.queue.MessageBody(msg)
+			if err == nil {
+				err = json.Unmarshal(body, &invalidation)
+			}
+			if err != nil {
+				log.Printf("Dropping malformed cache invalidation: %v", err)
+				msg.Nack(false, false)
+				continue
+			}
+
+			for _, target := range invalidation.Targets {
+				if err := cs.invalidateLocal(ctx, target); err != nil {
+					log.Printf("Failed to invalidate %s from %s: %v", target, invalidation.Origin, err)
+				}
+			}
+			msg.Ack(false)
+		}
+	}
+}
@@ -122,65 +122,23 @@ func (cs *CleanupService) GetCleanupStats(ctx context.Context) (map[string]inter
 	stats := map[string]interface{}{
 		"submissions_by_age": map[string]int{
 			"last_24h": 0,
-			"last_7d": 0,
+			"last_7d":  0,
 			"last_30d": 0,
 			"older":    0,
 		},
 		"table_sizes": map[string]string{
-			"submissions":         "unknown",
-			"execution_logs":      "unknown",
+			"submissions":             "unknown",
+			"execution_logs":          "unknown",
 			"submission_test_results": "unknown",
-			"plagiarism_reports":  "unknown",
+			"plagiarism_reports":      "unknown",
 		},
 	}
 
 	return stats, nil
 }
-	
-	stats["table_sizes"] = map[string]string{
-		"submissions":         "unknown",
-		"execution_logs":      "unknown",
-		"submission_test_results": "unknown",
-		"plagiarism_reports":  "unknown",
-	}
-
-	return stats, nil
-}
-
-	submissionCounts := make(map[string]int)
-	for period, whereClause := range submissionQueries {
-		query := fmt.Sprintf("SELECT COUNT(*) FROM execution.submissions %s", whereClause)
-		var count int
-		err := cs.db.conn.GetContext(ctx, &count, query)
-		if err == nil {
-			submissionCounts[period] = count
-		}
-	}
-	stats["submissions_by_age"] = submissionCounts
-
-	// Get table sizes
-	tables := []string{"submissions", "execution_logs", "submission_test_results", "plagiarism_reports"}
-	tableSizes := make(map[string]interface{})
-
-	for _, table := range tables {
-		query := fmt.Sprintf(`
-			SELECT 
-				pg_size_pretty(pg_total_relation_size('execution.%s')) as size
-		`, table)
-
-		var size string
-		err := cs.db.conn.GetContext(ctx, &size, query)
-		if err == nil {
-			tableSizes[table] = size
-		}
-	}
-	stats["table_sizes"] = tableSizes
-
-	return stats, nil
-}
 
 func (cs *CleanupService) ForceCleanup(ctx context.Context, dataType string, olderThan time.Duration) error {
-	cutoffDate := time.Now().Add(-olderThan)
+	log.Printf("Forcing cleanup of %s older than %v", dataType, olderThan)
 
 	switch dataType {
 	case "submissions":
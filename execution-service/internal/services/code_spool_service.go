@@ -0,0 +1,361 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"execution_service/internal/config"
+	"execution_service/internal/models"
+	"execution_service/internal/storage"
+)
+
+// ErrSpoolFull means the spool has no room left for more code.
+var ErrSpoolFull = errors.New("submission spool is full")
+
+// spoolURLPrefix marks the code URL of a submission whose code is still in
+// the spool.
+const spoolURLPrefix = "spool://"
+
+// spoolEntry is one submission's code in the spool. The sealed code is kept
+// in <token>.code; <token>.json is written once the submission is recorded,
+// and only then is the entry uploaded and queued.
+type spoolEntry struct {
+	Token         string    `json:"token"`
+	SubmissionID  int64     `json:"submission_id"`
+	Language      string    `json:"language"`
+	TimeLimitMs   int       `json:"time_limit_ms"`
+	MemoryLimitKb int       `json:"memory_limit_kb"`
+	Size          int64     `json:"size"`
+	SpooledAt     time.Time `json:"spooled_at"`
+	committed     bool
+}
+
+// IsSpooled reports whether codeURL points at code still waiting in the
+// spool rather than in MinIO.
+func IsSpooled(codeURL string) bool {
+	return strings.HasPrefix(codeURL, spoolURLPrefix)
+}
+
+func (e *spoolEntry) url() string {
+	return spoolURLPrefix + e.Token
+}
+
+// CodeSpoolService keeps submission intake alive through short MinIO
+// outages. Code that cannot be uploaded is sealed to local disk, and a
+// background uploader moves it to MinIO and queues the submission once the
+// MinIO circuit lets requests through again.
+type CodeSpoolService struct {
+	storage       *storage.MinIOClient
+	breaker       *CircuitBreakerService
+	metrics       *MetricsService
+	config        *config.SpoolConfig
+	aead          cipher.AEAD
+	queue         func(ctx context.Context, entry *spoolEntry, codeURL string) error
+	entries       map[string]*spoolEntry
+	bytes         int64
+	degradedSince *time.Time
+	lastError     string
+	mutex         sync.Mutex
+}
+
+func NewCodeSpoolService(storage *storage.MinIOClient, metrics *MetricsService, cfg *config.SpoolConfig) (*CodeSpoolService, error) {
+	raw, err := base64.StdEncoding.DecodeString(cfg.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode spool key: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("spool key must be 32 bytes, got %d", len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool cipher: %w", err)
+	}
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	cs := &CodeSpoolService{
+		storage: storage,
+		breaker: NewCircuitBreakerService(),
+		metrics: metrics,
+		config:  cfg,
+		aead:    aead,
+		entries: make(map[string]*spoolEntry),
+	}
+	if err := cs.load(); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// load picks up entries spooled before a restart. Code never committed
+// belonged to a request that failed or died before recording it.
+func (cs *CodeSpoolService) load() error {
+	files, err := os.ReadDir(cs.config.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	for _, file := range files {
+		token, ok := strings.CutSuffix(file.Name(), ".code")
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(cs.path(token, ".json"))
+		if os.IsNotExist(err) {
+			log.Printf("Removing uncommitted spooled code %s", token)
+			os.Remove(cs.path(token, ".code"))
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read spool entry %s: %w", token, err)
+		}
+		var entry spoolEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return fmt.Errorf("failed to parse spool entry %s: %w", token, err)
+		}
+		entry.committed = true
+		cs.entries[token] = &entry
+		cs.bytes += entry.Size
+	}
+
+	if len(cs.entries) > 0 {
+		now := time.Now()
+		cs.degradedSince = &now
+		log.Printf("ALERT: %d submissions are spooled from before restart; intake is degraded until they reach MinIO", len(cs.entries))
+	}
+	cs.record()
+	return nil
+}
+
+// uploadCode stores code in MinIO through the spool's circuit breaker, so an
+// open circuit fails fast instead of waiting on MinIO.
+func (cs *CodeSpoolService) uploadCode(ctx context.Context, submissionID int64, language string, code []byte) (string, error) {
+	var codeURL string
+	result := cs.breaker.ExecuteMinIOOperation(ctx, func() error {
+		var err error
+		codeURL, err = cs.storage.UploadCode(ctx, submissionID, language, code)
+		return err
+	})
+	if !result.Success {
+		return "", result.Error
+	}
+	return codeURL, nil
+}
+
+// spool seals code to disk. The entry is not uploaded until commit.
+func (cs *CodeSpoolService) spool(language string, code []byte, cause error) (*spoolEntry, error) {
+	cs.mutex.Lock()
+	if cs.bytes+int64(len(code)) > cs.config.MaxBytes {
+		cs.mutex.Unlock()
+		return nil, ErrSpoolFull
+	}
+	cs.bytes += int64(len(code))
+	cs.mutex.Unlock()
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		cs.release(int64(len(code)))
+		return nil, fmt.Errorf("failed to generate spool token: %w", err)
+	}
+	entry := &spoolEntry{
+		Token:     hex.EncodeToString(token),
+		Language:  language,
+		Size:      int64(len(code)),
+		SpooledAt: time.Now(),
+	}
+
+	nonce := make([]byte, cs.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		cs.release(entry.Size)
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := cs.aead.Seal(nonce, nonce, code, []byte(entry.Token))
+	if err := os.WriteFile(cs.path(entry.Token, ".code"), sealed, 0600); err != nil {
+		cs.release(entry.Size)
+		return nil, fmt.Errorf("failed to write spooled code: %w", err)
+	}
+
+	cs.mutex.Lock()
+	cs.entries[entry.Token] = entry
+	cs.lastError = cause.Error()
+	if cs.degradedSince == nil {
+		now := time.Now()
+		cs.degradedSince = &now
+		log.Printf("ALERT: MinIO unavailable, spooling submission code to %s: %v", cs.config.Dir, cause)
+	}
+	cs.mutex.Unlock()
+	cs.record()
+	return entry, nil
+}
+
+// commit hands a recorded submission's spooled code to the uploader.
+func (cs *CodeSpoolService) commit(entry *spoolEntry, submissionID int64, timeLimitMs, memoryLimitKb int) error {
+	entry.SubmissionID = submissionID
+	entry.TimeLimitMs = timeLimitMs
+	entry.MemoryLimitKb = memoryLimitKb
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode spool entry: %w", err)
+	}
+	pending := cs.path(entry.Token, ".json.tmp")
+	if err := os.WriteFile(pending, data, 0600); err != nil {
+		return fmt.Errorf("failed to write spool entry: %w", err)
+	}
+	if err := os.Rename(pending, cs.path(entry.Token, ".json")); err != nil {
+		os.Remove(pending)
+		return fmt.Errorf("failed to write spool entry: %w", err)
+	}
+
+	cs.mutex.Lock()
+	entry.committed = true
+	cs.mutex.Unlock()
+	return nil
+}
+
+// discard drops spooled code whose submission was never recorded.
+func (cs *CodeSpoolService) discard(entry *spoolEntry) {
+	cs.remove(entry)
+}
+
+func (cs *CodeSpoolService) Start(ctx context.Context) {
+	ticker := time.NewTicker(cs.config.UploadInterval)
+	defer ticker.Stop()
+
+	log.Printf("Starting submission spool uploader every %v", cs.config.UploadInterval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cs.drain(ctx)
+		}
+	}
+}
+
+// drain uploads committed entries oldest first, stopping at the first
+// failure since MinIO is then still unavailable.
+func (cs *CodeSpoolService) drain(ctx context.Context) {
+	cs.mutex.Lock()
+	var pending []*spoolEntry
+	for _, entry := range cs.entries {
+		if entry.committed {
+			pending = append(pending, entry)
+		}
+	}
+	cs.mutex.Unlock()
+	sort.Slice(pending, func(a, b int) bool { return pending[a].SpooledAt.Before(pending[b].SpooledAt) })
+
+	for _, entry := range pending {
+		if err := cs.uploadEntry(ctx, entry); err != nil {
+			cs.mutex.Lock()
+			cs.lastError = err.Error()
+			cs.mutex.Unlock()
+			log.Printf("Failed to upload spooled submission %d: %v", entry.SubmissionID, err)
+			return
+		}
+		cs.remove(entry)
+		log.Printf("Uploaded spooled submission %d after %v", entry.SubmissionID, time.Since(entry.SpooledAt).Round(time.Second))
+	}
+
+	cs.mutex.Lock()
+	if cs.degradedSince != nil && len(cs.entries) == 0 {
+		log.Printf("Submission spool drained after %v of degraded intake", time.Since(*cs.degradedSince).Round(time.Second))
+		cs.degradedSince = nil
+		cs.lastError = ""
+	}
+	cs.mutex.Unlock()
+	cs.record()
+}
+
+func (cs *CodeSpoolService) uploadEntry(ctx context.Context, entry *spoolEntry) error {
+	sealed, err := os.ReadFile(cs.path(entry.Token, ".code"))
+	if err != nil {
+		return fmt.Errorf("failed to read spooled code: %w", err)
+	}
+	nonceSize := cs.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return fmt.Errorf("spooled code is truncated")
+	}
+	code, err := cs.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(entry.Token))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt spooled code: %w", err)
+	}
+
+	codeURL, err := cs.uploadCode(ctx, entry.SubmissionID, entry.Language, code)
+	if err != nil {
+		return err
+	}
+	return cs.queue(ctx, entry, codeURL)
+}
+
+func (cs *CodeSpoolService) remove(entry *spoolEntry) {
+	os.Remove(cs.path(entry.Token, ".json"))
+	os.Remove(cs.path(entry.Token, ".code"))
+
+	cs.mutex.Lock()
+	if _, ok := cs.entries[entry.Token]; ok {
+		delete(cs.entries, entry.Token)
+		cs.bytes -= entry.Size
+	}
+	cs.mutex.Unlock()
+	cs.record()
+}
+
+// release returns space reserved for code that was never spooled.
+func (cs *CodeSpoolService) release(size int64) {
+	cs.mutex.Lock()
+	cs.bytes -= size
+	cs.mutex.Unlock()
+}
+
+func (cs *CodeSpoolService) path(token, suffix string) string {
+	return filepath.Join(cs.config.Dir, token+suffix)
+}
+
+// Status reports how much code is waiting for MinIO.
+func (cs *CodeSpoolService) Status() models.SpoolStatus {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	status := models.SpoolStatus{
+		Degraded:      cs.degradedSince != nil,
+		DegradedSince: cs.degradedSince,
+		Entries:       len(cs.entries),
+		Bytes:         cs.bytes,
+		MaxBytes:      cs.config.MaxBytes,
+		LastError:     cs.lastError,
+	}
+	for _, entry := range cs.entries {
+		status.OldestAgeSeconds = max(status.OldestAgeSeconds, time.Since(entry.SpooledAt).Seconds())
+	}
+	return status
+}
+
+func (cs *CodeSpoolService) record() {
+	if cs.metrics == nil {
+		return
+	}
+	status := cs.Status()
+	cs.metrics.RecordSpool(status.Entries, status.Bytes, status.Degraded)
+}
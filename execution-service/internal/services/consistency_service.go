@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"execution_service/internal/config"
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+	"execution_service/internal/queue"
+)
+
+// consistencyTimeFloorMs keeps timing noise on fast canaries from counting
+// as a deviation.
+const consistencyTimeFloorMs = 50
+
+// ConsistencyService re-judges canary submissions on this node and compares
+// the results with the runs recorded by every other node.
+type ConsistencyService struct {
+	db     *database.DB
+	queue  *queue.RabbitMQClient
+	config *config.ConsistencyConfig
+	node   string
+	judge  func(ctx context.Context, submission *models.Submission) (*models.CanaryRun, error)
+	drain  func(drained bool)
+
+	mutex   sync.Mutex
+	drained bool
+}
+
+func NewConsistencyService(db *database.DB, q *queue.RabbitMQClient, cfg *config.ConsistencyConfig, node string) *ConsistencyService {
+	return &ConsistencyService{
+		db:     db,
+		queue:  q,
+		config: cfg,
+		node:   node,
+	}
+}
+
+func (cs *ConsistencyService) SetCanaryJudge(judge func(ctx context.Context, submission *models.Submission) (*models.CanaryRun, error)) {
+	cs.judge = judge
+}
+
+// SetDrainer is called to stop or resume judging on this node when Drain is
+// configured.
+func (cs *ConsistencyService) SetDrainer(drain func(drained bool)) {
+	cs.drain = drain
+}
+
+func (cs *ConsistencyService) Start(ctx context.Context) {
+	ticker := time.NewTicker(cs.config.Interval)
+	defer ticker.Stop()
+
+	log.Printf("Starting consistency checker on %s (interval: %v, time tolerance: %.0f%%)", cs.node, cs.config.Interval, cs.config.TimeTolerance*100)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := cs.RunOnce(ctx); err != nil {
+				log.Printf("Consistency check failed: %v", err)
+			}
+		}
+	}
+}
+
+func (cs *ConsistencyService) AddCanary(ctx context.Context, canary *models.Canary) error {
+	return cs.db.CreateCanary(ctx, canary)
+}
+
+func (cs *ConsistencyService) ListCanaries(ctx context.Context) ([]models.Canary, error) {
+	return cs.db.GetCanaries(ctx)
+}
+
+func (cs *ConsistencyService) RemoveCanary(ctx context.Context, canaryID int64) (bool, error) {
+	return cs.db.DeleteCanary(ctx, canaryID)
+}
+
+// RunOnce judges every canary on this node, records the runs and acts on
+// this node's standing in the resulting report.
+func (cs *ConsistencyService) RunOnce(ctx context.Context) (*models.ConsistencyReport, error) {
+	if cs.judge == nil {
+		return nil, fmt.Errorf("canary judging not available")
+	}
+
+	canaries, err := cs.db.GetCanaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, canary := range canaries {
+		submission, err := cs.db.GetSubmission(ctx, canary.SubmissionID)
+		if err != nil {
+			log.Printf("Consistency check skipped canary %d: %v", canary.ID, err)
+			continue
+		}
+
+		run, err := cs.judge(ctx, submission)
+		if err != nil {
+			log.Printf("Consistency check failed to judge canary %d: %v", canary.ID, err)
+			continue
+		}
+		run.CanaryID = canary.ID
+		run.Node = cs.node
+		if err := cs.db.CreateCanaryRun(ctx, run); err != nil {
+			return nil, err
+		}
+	}
+
+	report, err := cs.Report(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range report.Nodes {
+		if node.Node == cs.node {
+			cs.apply(ctx, &node, report.Canaries)
+		}
+	}
+
+	return report, nil
+}
+
+// Report compares each node's latest run of every canary from the last two
+// intervals, so nodes that stopped checking drop out.
+func (cs *ConsistencyService) Report(ctx context.Context) (*models.ConsistencyReport, error) {
+	canaries, err := cs.db.GetCanaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := cs.db.GetLatestCanaryRuns(ctx, time.Now().Add(-2*cs.config.Interval))
+	if err != nil {
+		return nil, err
+	}
+
+	expected := make(map[int64]models.Verdict, len(canaries))
+	for _, canary := range canaries {
+		expected[canary.ID] = canary.ExpectedVerdict
+	}
+
+	times := make(map[int64][]int)
+	for _, run := range runs {
+		times[run.CanaryID] = append(times[run.CanaryID], run.MaxTimeMs)
+	}
+	medians := make(map[int64]int, len(times))
+	for canaryID, ts := range times {
+		sort.Ints(ts)
+		medians[canaryID] = ts[len(ts)/2]
+	}
+
+	nodes := make(map[string]*models.NodeConsistency)
+	var names []string
+	for _, run := range runs {
+		verdict, ok := expected[run.CanaryID]
+		if !ok {
+			continue
+		}
+
+		node := nodes[run.Node]
+		if node == nil {
+			node = &models.NodeConsistency{Node: run.Node}
+			nodes[run.Node] = node
+			names = append(names, run.Node)
+		}
+		node.Runs = append(node.Runs, run)
+
+		if run.Verdict != verdict {
+			node.Reasons = append(node.Reasons, fmt.Sprintf("canary %d: verdict %s, expected %s", run.CanaryID, run.Verdict, verdict))
+		}
+		limit := float64(medians[run.CanaryID]) * (1 + cs.config.TimeTolerance)
+		if len(times[run.CanaryID]) > 1 && run.MaxTimeMs > consistencyTimeFloorMs && float64(run.MaxTimeMs) > limit {
+			node.Reasons = append(node.Reasons, fmt.Sprintf("canary %d: %dms, median %dms", run.CanaryID, run.MaxTimeMs, medians[run.CanaryID]))
+		}
+		node.Deviating = len(node.Reasons) > 0
+	}
+
+	sort.Strings(names)
+	report := &models.ConsistencyReport{
+		Canaries:      len(canaries),
+		TimeTolerance: cs.config.TimeTolerance,
+		Nodes:         make([]models.NodeConsistency, 0, len(names)),
+		GeneratedAt:   time.Now(),
+	}
+	for _, name := range names {
+		report.Nodes = append(report.Nodes, *nodes[name])
+	}
+
+	return report, nil
+}
+
+// apply alerts on a deviating node and drains it if configured. Only a drain
+// made here is lifted once the node is consistent again.
+func (cs *ConsistencyService) apply(ctx context.Context, node *models.NodeConsistency, canaries int) {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	if !node.Deviating {
+		if cs.drained && cs.drain != nil {
+			log.Printf("Node %s is consistent again, resuming judging", cs.node)
+			cs.drain(false)
+		}
+		cs.drained = false
+		return
+	}
+
+	drain := cs.config.Drain && cs.drain != nil
+	log.Printf("ALERT: Judge node %s deviates on canaries: %s", cs.node, strings.Join(node.Reasons, "; "))
+	if drain && !cs.drained {
+		cs.drain(true)
+		cs.drained = true
+	}
+
+	event := &models.NodeDeviationEvent{
+		Node:     cs.node,
+		Reasons:  node.Reasons,
+		Drained:  cs.drained,
+		Canaries: canaries,
+	}
+	if err := cs.queue.PublishEvent(ctx, "JudgeNodeDeviation", event); err != nil {
+		log.Printf("Failed to publish node deviation alert: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+	"execution_service/internal/sandbox"
+)
+
+// ErrEnvironmentUnavailable means a contest is pinned to a toolchain this node
+// does not have, so its submissions must be judged elsewhere.
+var ErrEnvironmentUnavailable = errors.New("pinned sandbox environment unavailable on this node")
+
+type cachedPin struct {
+	pin       *models.ContestEnvironment
+	expiresAt time.Time
+}
+
+// ContestEnvironmentService pins contests to a sandbox toolchain snapshot so
+// node upgrades during a contest cannot change how its submissions are judged.
+type ContestEnvironmentService struct {
+	db       *database.DB
+	sandbox  *sandbox.IsolateSandbox
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[int64]cachedPin
+}
+
+func NewContestEnvironmentService(db *database.DB, sb *sandbox.IsolateSandbox) *ContestEnvironmentService {
+	return &ContestEnvironmentService{
+		db:       db,
+		sandbox:  sb,
+		cacheTTL: 30 * time.Second,
+		cache:    make(map[int64]cachedPin),
+	}
+}
+
+// Pin records this node's current snapshot as the contest's environment.
+func (s *ContestEnvironmentService) Pin(ctx context.Context, contestID, pinnedBy int64) (*models.ContestEnvironment, error) {
+	env := s.sandbox.Environment()
+	if env == nil {
+		return nil, fmt.Errorf("sandbox environment snapshot not captured")
+	}
+
+	pin := &models.ContestEnvironment{
+		ContestID:   contestID,
+		Environment: *env,
+		PinnedBy:    pinnedBy,
+		PinnedAt:    time.Now(),
+	}
+	if err := s.db.PinContestEnvironment(ctx, pin); err != nil {
+		return nil, err
+	}
+
+	s.forget(contestID)
+	return pin, nil
+}
+
+func (s *ContestEnvironmentService) Unpin(ctx context.Context, contestID int64) (bool, error) {
+	removed, err := s.db.DeleteContestEnvironment(ctx, contestID)
+	s.forget(contestID)
+	return removed, err
+}
+
+// Current returns this node's snapshot.
+func (s *ContestEnvironmentService) Current() *models.SandboxEnvironment {
+	return s.sandbox.Environment()
+}
+
+func (s *ContestEnvironmentService) Get(ctx context.Context, contestID int64) (*models.ContestEnvironment, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[contestID]
+	s.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.pin, nil
+	}
+
+	pin, err := s.db.GetContestEnvironment(ctx, contestID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[contestID] = cachedPin{pin: pin, expiresAt: time.Now().Add(s.cacheTTL)}
+	s.mu.Unlock()
+	return pin, nil
+}
+
+// ForContest returns the environment to judge a contest submission with: nil
+// when the contest is not pinned, the pinned snapshot when this node matches
+// it, and ErrEnvironmentUnavailable otherwise.
+func (s *ContestEnvironmentService) ForContest(ctx context.Context, contestID int64) (*models.SandboxEnvironment, error) {
+	pin, err := s.Get(ctx, contestID)
+	if err != nil || pin == nil {
+		return nil, err
+	}
+
+	if !s.Available(pin) {
+		return nil, ErrEnvironmentUnavailable
+	}
+	return &pin.Environment, nil
+}
+
+func (s *ContestEnvironmentService) Available(pin *models.ContestEnvironment) bool {
+	env := s.sandbox.Environment()
+	return env != nil && env.ToolchainID == pin.Environment.ToolchainID
+}
+
+func (s *ContestEnvironmentService) forget(contestID int64) {
+	s.mu.Lock()
+	delete(s.cache, contestID)
+	s.mu.Unlock()
+}
@@ -28,6 +28,7 @@ type RetryableSubmission struct {
 	RetryCount    int       `json:"retry_count"`
 	OriginalQueue string    `json:"original_queue"`
 	LastError     string    `json:"last_error"`
+	Errors        []string  `json:"errors,omitempty"`
 	FirstFailed   time.Time `json:"first_failed"`
 	LastRetry     time.Time `json:"last_retry"`
 }
@@ -158,7 +159,10 @@ func (dlqs *DeadLetterQueueService) processRetryMessages(ctx context.Context) {
 
 func (dlqs *DeadLetterQueueService) handleDeadLetterMessage(ctx context.Context, msg amqp.Delivery) {
 	var retryableSubmission RetryableSubmission
-	err := json.Unmarshal(msg.Body, &retryableSubmission)
+	body, err := dlqs.queue.MessageBody(msg)
+	if err == nil {
+		err = json.Unmarshal(body, &retryableSubmission)
+	}
 	if err != nil {
 		log.Printf("Failed to unmarshal dead letter message: %v", err)
 		dlqs.queue.AcknowledgeMessage(msg)
@@ -187,7 +191,10 @@ func (dlqs *DeadLetterQueueService) handleDeadLetterMessage(ctx context.Context,
 
 func (dlqs *DeadLetterQueueService) handleRetryMessage(ctx context.Context, msg amqp.Delivery) {
 	var retryableSubmission RetryableSubmission
-	err := json.Unmarshal(msg.Body, &retryableSubmission)
+	body, err := dlqs.queue.MessageBody(msg)
+	if err == nil {
+		err = json.Unmarshal(body, &retryableSubmission)
+	}
 	if err != nil {
 		log.Printf("Failed to unmarshal retry message: %v", err)
 		dlqs.queue.AcknowledgeMessage(msg)
@@ -245,6 +252,20 @@ func (dlqs *DeadLetterQueueService) sendToDeadLetterQueue(ctx context.Context, s
 	}
 }
 
+// Park puts a submission that keeps failing in the dead letter queue, where
+// it stays until an operator inspects, requeues or purges it.
+func (dlqs *DeadLetterQueueService) Park(ctx context.Context, submission *RetryableSubmission) error {
+	if err := dlqs.setupQueues(ctx); err != nil {
+		return fmt.Errorf("failed to setup queues: %w", err)
+	}
+
+	body, err := json.Marshal(submission)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter submission: %w", err)
+	}
+	return dlqs.queue.PublishToQueue(ctx, dlqs.dlqName, body)
+}
+
 func (dlqs *DeadLetterQueueService) markAsPermanentlyFailed(ctx context.Context, submission *RetryableSubmission) {
 	log.Printf("Marking submission %d as permanently failed after %d retries",
 		submission.SubmissionID, submission.RetryCount)
@@ -297,3 +318,127 @@ func (dlqs *DeadLetterQueueService) PurgeDLQ(ctx context.Context) error {
 	log.Println("Purged dead letter and retry queues")
 	return nil
 }
+
+// Inspect lists up to limit messages from the head of the dead letter queue
+// and returns them all to the queue.
+func (dlqs *DeadLetterQueueService) Inspect(ctx context.Context, limit int) ([]models.DeadLetterEntry, error) {
+	entries := []models.DeadLetterEntry{}
+	err := dlqs.scan(ctx, limit, func(submission *RetryableSubmission) bool {
+		entries = append(entries, deadLetterEntry(submission))
+		return false
+	})
+	return entries, err
+}
+
+// Requeue publishes dead-lettered submissions back to the judge queue. Only
+// the first limit messages are examined; when submissionIDs is not empty,
+// only those submissions are requeued and the rest stay parked.
+func (dlqs *DeadLetterQueueService) Requeue(ctx context.Context, submissionIDs []int64, limit int) ([]int64, error) {
+	wanted := make(map[int64]bool, len(submissionIDs))
+	for _, id := range submissionIDs {
+		wanted[id] = true
+	}
+
+	requeued := []int64{}
+	err := dlqs.scan(ctx, limit, func(submission *RetryableSubmission) bool {
+		if len(wanted) > 0 && !wanted[submission.SubmissionID] {
+			return false
+		}
+		if err := dlqs.queue.PublishSubmission(ctx, submission.JudgeRequest); err != nil {
+			log.Printf("Failed to requeue dead-lettered submission %d: %v", submission.SubmissionID, err)
+			return false
+		}
+		requeued = append(requeued, submission.SubmissionID)
+		return true
+	})
+	return requeued, err
+}
+
+// Discard drops dead-lettered submissions for good, examining only the
+// first limit messages. A message is dropped when its submission is in
+// submissionIDs or its problem is problemID; other messages stay parked.
+func (dlqs *DeadLetterQueueService) Discard(ctx context.Context, submissionIDs []int64, problemID int64, limit int) ([]int64, error) {
+	wanted := make(map[int64]bool, len(submissionIDs))
+	for _, id := range submissionIDs {
+		wanted[id] = true
+	}
+
+	discarded := []int64{}
+	err := dlqs.scan(ctx, limit, func(submission *RetryableSubmission) bool {
+		if !wanted[submission.SubmissionID] && (problemID == 0 || submission.ProblemID != problemID) {
+			return false
+		}
+		discarded = append(discarded, submission.SubmissionID)
+		return true
+	})
+	if len(discarded) > 0 {
+		log.Printf("Discarded %d dead-lettered submissions", len(discarded))
+	}
+	return discarded, err
+}
+
+// scan fetches up to limit dead letter messages and hands each to visit.
+// Messages visit consumes are acknowledged; the rest, including ones that
+// cannot be parsed, are returned to the queue once the scan ends so none is
+// seen twice.
+func (dlqs *DeadLetterQueueService) scan(ctx context.Context, limit int, visit func(submission *RetryableSubmission) bool) error {
+	if err := dlqs.setupQueues(ctx); err != nil {
+		return fmt.Errorf("failed to setup queues: %w", err)
+	}
+
+	var kept []amqp.Delivery
+	defer func() {
+		for _, msg := range kept {
+			dlqs.queue.RejectMessage(msg, true)
+		}
+	}()
+
+	for i := 0; i < limit; i++ {
+		msg, ok, err := dlqs.queue.GetFromQueue(ctx, dlqs.dlqName)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		var submission RetryableSubmission
+		body, err := dlqs.queue.MessageBody(msg)
+		if err == nil {
+			err = json.Unmarshal(body, &submission)
+		}
+		if err != nil || submission.JudgeRequest == nil {
+			log.Printf("Skipping unreadable dead letter message: %v", err)
+			kept = append(kept, msg)
+			continue
+		}
+
+		if visit(&submission) {
+			dlqs.queue.AcknowledgeMessage(msg)
+		} else {
+			kept = append(kept, msg)
+		}
+	}
+
+	return nil
+}
+
+func deadLetterEntry(submission *RetryableSubmission) models.DeadLetterEntry {
+	entry := models.DeadLetterEntry{
+		SubmissionID:  submission.SubmissionID,
+		UserID:        submission.UserID,
+		ProblemID:     submission.ProblemID,
+		Language:      submission.Language,
+		RetryCount:    submission.RetryCount,
+		OriginalQueue: submission.OriginalQueue,
+		LastError:     submission.LastError,
+		Errors:        submission.Errors,
+	}
+	if !submission.FirstFailed.IsZero() {
+		entry.FirstFailed = &submission.FirstFailed
+	}
+	if !submission.LastRetry.IsZero() {
+		entry.LastRetry = &submission.LastRetry
+	}
+	return entry
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"execution_service/internal/config"
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+	"execution_service/internal/queue"
+	"execution_service/internal/sandbox"
+	"execution_service/internal/storage"
+
+	"gopkg.in/yaml.v3"
+)
+
+const diagnosticsLinkExpiry = time.Hour
+
+var secretConfigKeys = []string{"password", "secret", "private_key", "access_key", "token", "user_id"}
+
+// WorkerStateSource snapshots the judge pool without importing the worker
+// package.
+type WorkerStateSource func() any
+
+// DiagnosticsService gathers a support bundle for incident triage and stores
+// it in MinIO as a tar.gz.
+type DiagnosticsService struct {
+	db      *database.DB
+	queue   *queue.RabbitMQClient
+	storage *storage.MinIOClient
+	sandbox *sandbox.IsolateSandbox
+	cfg     *config.Config
+	logs    *LogBuffer
+	workers WorkerStateSource
+}
+
+type DiagnosticsBundle struct {
+	ObjectURL   string    `json:"object_url"`
+	DownloadURL string    `json:"download_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	SizeBytes   int       `json:"size_bytes"`
+}
+
+type diagnosticsSummary struct {
+	GeneratedAt   time.Time      `json:"generated_at"`
+	Host          string         `json:"host"`
+	RequestedBy   int64          `json:"requested_by"`
+	Queue         map[string]any `json:"queue"`
+	Workers       any            `json:"workers"`
+	WorkerStats   map[string]any `json:"worker_stats"`
+	IsolateProbe  CheckResult    `json:"isolate_probe"`
+	FailedSubmits []int64        `json:"failed_submissions"`
+	Errors        []string       `json:"collection_errors,omitempty"`
+}
+
+func NewDiagnosticsService(db *database.DB, q *queue.RabbitMQClient, s *storage.MinIOClient, sb *sandbox.IsolateSandbox, cfg *config.Config, logs *LogBuffer) *DiagnosticsService {
+	return &DiagnosticsService{
+		db:      db,
+		queue:   q,
+		storage: s,
+		sandbox: sb,
+		cfg:     cfg,
+		logs:    logs,
+	}
+}
+
+func (ds *DiagnosticsService) SetWorkerStateSource(source WorkerStateSource) {
+	ds.workers = source
+}
+
+// Generate collects the bundle and uploads it. Sections that fail to collect
+// are noted in the summary rather than failing the whole bundle.
+func (ds *DiagnosticsService) Generate(ctx context.Context, requestedBy int64, failedLimit int) (*DiagnosticsBundle, error) {
+	host, _ := os.Hostname()
+	summary := diagnosticsSummary{
+		GeneratedAt: time.Now().UTC(),
+		Host:        host,
+		RequestedBy: requestedBy,
+	}
+
+	queueSize, err := ds.queue.GetQueueInfo()
+	if err != nil {
+		summary.Errors = append(summary.Errors, fmt.Sprintf("queue: %v", err))
+	}
+	summary.Queue = map[string]any{
+		"size":    queueSize,
+		"healthy": ds.queue.IsHealthy(),
+	}
+
+	if ds.workers != nil {
+		summary.Workers = ds.workers()
+	}
+
+	if summary.WorkerStats, err = ds.db.GetWorkerStats(ctx); err != nil {
+		summary.Errors = append(summary.Errors, fmt.Sprintf("worker stats: %v", err))
+	}
+
+	summary.IsolateProbe = probeIsolate(ds.sandbox)
+
+	var executionLogs []models.ExecutionLog
+	if summary.FailedSubmits, err = ds.db.GetRecentFailedSubmissionIDs(ctx, failedLimit); err != nil {
+		summary.Errors = append(summary.Errors, fmt.Sprintf("failed submissions: %v", err))
+	} else if len(summary.FailedSubmits) > 0 {
+		if executionLogs, err = ds.db.GetExecutionLogs(ctx, summary.FailedSubmits); err != nil {
+			summary.Errors = append(summary.Errors, fmt.Sprintf("execution logs: %v", err))
+		}
+	}
+
+	redacted, err := redactedConfig(ds.cfg)
+	if err != nil {
+		summary.Errors = append(summary.Errors, fmt.Sprintf("config: %v", err))
+	}
+
+	archive, err := buildArchive(summary, redacted, ds.logs.Recent(), executionLogs)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%s-%s.tar.gz", summary.GeneratedAt.Format("20060102T150405Z"), host)
+	objectURL, err := ds.storage.UploadDiagnostics(ctx, name, archive)
+	if err != nil {
+		return nil, err
+	}
+
+	downloadURL, err := ds.storage.PresignedURL(ctx, objectURL, diagnosticsLinkExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DiagnosticsBundle{
+		ObjectURL:   objectURL,
+		DownloadURL: downloadURL,
+		ExpiresAt:   time.Now().Add(diagnosticsLinkExpiry),
+		SizeBytes:   len(archive),
+	}, nil
+}
+
+func buildArchive(summary diagnosticsSummary, cfg map[string]any, recentErrors []string, executionLogs []models.ExecutionLog) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	summaryJSON, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode diagnostics summary: %w", err)
+	}
+	configYAML, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode redacted config: %w", err)
+	}
+
+	var submissionLogs strings.Builder
+	for _, entry := range executionLogs {
+		fmt.Fprintf(&submissionLogs, "%s submission=%d %s %s\n",
+			entry.CreatedAt.UTC().Format(time.RFC3339), entry.SubmissionID, entry.Level, entry.Message)
+	}
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"summary.json", summaryJSON},
+		{"config.yaml", configYAML},
+		{"recent_errors.log", []byte(strings.Join(recentErrors, "\n"))},
+		{"failed_submissions.log", []byte(submissionLogs.String())},
+	}
+
+	for _, file := range files {
+		header := &tar.Header{
+			Name:    file.name,
+			Mode:    0644,
+			Size:    int64(len(file.data)),
+			ModTime: summary.GeneratedAt,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish diagnostics archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish diagnostics archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// redactedConfig round-trips the config through YAML and masks credentials,
+// including passwords embedded in connection URLs.
+func redactedConfig(cfg *config.Config) (map[string]any, error) {
+	raw, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]any
+	if err := yaml.Unmarshal(raw, &tree); err != nil {
+		return nil, err
+	}
+
+	redactTree(tree)
+	return tree, nil
+}
+
+func redactTree(tree map[string]any) {
+	for key, value := range tree {
+		switch v := value.(type) {
+		case map[string]any:
+			redactTree(v)
+		case string:
+			if v != "" && isSecretKey(key) {
+				tree[key] = "[REDACTED]"
+			} else if parsed, err := url.Parse(v); err == nil && parsed.User != nil {
+				if _, hasPassword := parsed.User.Password(); hasPassword {
+					parsed.User = url.UserPassword(parsed.User.Username(), "REDACTED")
+					tree[key] = parsed.String()
+				}
+			}
+		}
+	}
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range secretConfigKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
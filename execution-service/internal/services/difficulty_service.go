@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"execution_service/internal/database"
+	"execution_service/internal/httpclient"
+	"execution_service/internal/models"
+	"execution_service/internal/queue"
+)
+
+const difficultyEventsQueue = "execution.difficulty"
+
+// Difficulty levels reported alongside the numeric score
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// DifficultyService estimates problem difficulty from judge outcomes. Problems
+// are marked dirty by SubmissionJudged events and recomputed in batches; a
+// periodic sweep catches anything judged while events were not flowing.
+type DifficultyService struct {
+	db             *database.DB
+	queue          *queue.RabbitMQClient
+	contentClient  *httpclient.ContentServiceClient
+	flushInterval  time.Duration
+	sweepInterval  time.Duration
+	lastSweep      time.Time
+	minSubmissions int
+
+	mu    sync.Mutex
+	dirty map[int64]struct{}
+}
+
+func NewDifficultyService(db *database.DB, q *queue.RabbitMQClient, contentClient *httpclient.ContentServiceClient) *DifficultyService {
+	return &DifficultyService{
+		db:             db,
+		queue:          q,
+		contentClient:  contentClient,
+		flushInterval:  30 * time.Second,
+		sweepInterval:  time.Hour,
+		lastSweep:      time.Now(),
+		minSubmissions: 5,
+		dirty:          make(map[int64]struct{}),
+	}
+}
+
+func (ds *DifficultyService) Start(ctx context.Context) {
+	go ds.consumeEvents(ctx)
+
+	flush := time.NewTicker(ds.flushInterval)
+	defer flush.Stop()
+	sweep := time.NewTicker(ds.sweepInterval)
+	defer sweep.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-flush.C:
+			ds.flush(ctx)
+		case <-sweep.C:
+			ds.sweep(ctx)
+		}
+	}
+}
+
+func (ds *DifficultyService) MarkDirty(problemID int64) {
+	ds.mu.Lock()
+	ds.dirty[problemID] = struct{}{}
+	ds.mu.Unlock()
+}
+
+// Recalculate recomputes and stores the difficulty of one problem.
+func (ds *DifficultyService) Recalculate(ctx context.Context, problemID int64) (*models.ProblemDifficulty, error) {
+	difficulty, medianRuntimeMs, err := ds.db.GetProblemJudgeStats(ctx, problemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if difficulty.TotalSubmissions > 0 {
+		difficulty.AcceptanceRate = float64(difficulty.AcceptedSubmissions) / float64(difficulty.TotalSubmissions)
+	}
+
+	if medianRuntimeMs != nil {
+		if problem, err := ds.contentClient.GetProblem(ctx, problemID); err == nil && problem.TimeLimit > 0 {
+			ratio := *medianRuntimeMs / float64(problem.TimeLimit)
+			difficulty.MedianRuntimeRatio = &ratio
+		}
+	}
+
+	difficulty.Score, difficulty.Level = ds.score(difficulty)
+
+	if err := ds.db.UpsertProblemDifficulty(ctx, difficulty); err != nil {
+		return nil, err
+	}
+
+	return difficulty, nil
+}
+
+func (ds *DifficultyService) GetDifficulties(ctx context.Context, problemIDs []int64) ([]models.ProblemDifficulty, error) {
+	return ds.db.GetProblemDifficulties(ctx, problemIDs)
+}
+
+// score maps the signals to 0-100: rejection rate weighs most, then failed
+// attempts before the first AC (saturating at 5), then how close accepted
+// solutions run to the limit. Problems with too little data stay "medium".
+func (ds *DifficultyService) score(d *models.ProblemDifficulty) (float64, string) {
+	score := 50.0 * (1 - d.AcceptanceRate)
+	score += 30.0 * math.Min(d.AvgAttemptsBeforeAC/5, 1)
+	if d.MedianRuntimeRatio != nil {
+		score += 20.0 * math.Min(*d.MedianRuntimeRatio, 1)
+	}
+	if d.Solvers == 0 && d.TotalSubmissions > 0 {
+		score = 100
+	}
+	score = math.Round(score*10) / 10
+
+	switch {
+	case d.TotalSubmissions < ds.minSubmissions:
+		return score, DifficultyMedium
+	case score < 35:
+		return score, DifficultyEasy
+	case score < 65:
+		return score, DifficultyMedium
+	default:
+		return score, DifficultyHard
+	}
+}
+
+func (ds *DifficultyService) flush(ctx context.Context) {
+	ds.mu.Lock()
+	dirty := ds.dirty
+	ds.dirty = make(map[int64]struct{})
+	ds.mu.Unlock()
+
+	for problemID := range dirty {
+		if _, err := ds.Recalculate(ctx, problemID); err != nil {
+			log.Printf("Failed to recalculate difficulty for problem %d: %v", problemID, err)
+			ds.MarkDirty(problemID)
+		}
+	}
+}
+
+func (ds *DifficultyService) sweep(ctx context.Context) {
+	since := ds.lastSweep
+	ds.lastSweep = time.Now()
+
+	problemIDs, err := ds.db.GetRecentlyJudgedProblems(ctx, since)
+	if err != nil {
+		log.Printf("Failed to find recently judged problems: %v", err)
+		return
+	}
+
+	for _, problemID := range problemIDs {
+		ds.MarkDirty(problemID)
+	}
+}
+
+func (ds *DifficultyService) consumeEvents(ctx context.Context) {
+	bindingKey := ds.queue.BindingKey("SubmissionJudged")
+
+	for {
+		msgs, err := ds.queue.ConsumeEvents(ctx, difficultyEventsQueue, bindingKey)
+		if err != nil {
+			log.Printf("Failed to consume judged events: %v", err)
+		} else {
+			for msg := range msgs {
+				var event models.EventMessage
+				body, err := ds.queue.MessageBody(msg)
+				if err == nil {
+					err = json.Unmarshal(body, &event)
+				}
+				if err == nil && event.EventType == "SubmissionJudged" {
+					if problemID, ok := event.Data["problem_id"].(float64); ok && problemID > 0 {
+						ds.MarkDirty(int64(problemID))
+					}
+				}
+				msg.Ack(false)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ds.flushInterval):
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"execution_service/internal/config"
+)
+
+type DiskUsage struct {
+	Path        string           `json:"path"`
+	TotalBytes  uint64           `json:"total_bytes"`
+	FreeBytes   uint64           `json:"free_bytes"`
+	UsedRatio   float64          `json:"used_ratio"`
+	BoxRootSize int64            `json:"box_root_bytes"`
+	CacheSizes  map[string]int64 `json:"cache_bytes"`
+	Pressure    string           `json:"pressure"` // ok, warning, critical
+	CheckedAt   time.Time        `json:"checked_at"`
+}
+
+// DiskWatcherService monitors the filesystem hosting the isolate box root and
+// frees cache space before judging starts failing on a full disk.
+type DiskWatcherService struct {
+	boxRoot       string
+	cacheDirs     []string
+	warnRatio     float64
+	criticalRatio float64
+	interval      time.Duration
+	metrics       *MetricsService
+	usage         DiskUsage
+	mutex         sync.RWMutex
+}
+
+func NewDiskWatcherService(cfg *config.IsolateConfig, metrics *MetricsService) *DiskWatcherService {
+	return &DiskWatcherService{
+		boxRoot:       cfg.BoxRoot,
+		cacheDirs:     cfg.CacheDirs,
+		warnRatio:     cfg.DiskWarnRatio,
+		criticalRatio: cfg.DiskCriticalRatio,
+		interval:      cfg.DiskCheckInterval,
+		metrics:       metrics,
+		usage:         DiskUsage{Path: cfg.BoxRoot, Pressure: "ok"},
+	}
+}
+
+func (dw *DiskWatcherService) Start(ctx context.Context) {
+	ticker := time.NewTicker(dw.interval)
+	defer ticker.Stop()
+
+	log.Printf("Starting disk watcher for %s (warn: %.0f%%, critical: %.0f%%)", dw.boxRoot, dw.warnRatio*100, dw.criticalRatio*100)
+	dw.check()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			dw.check()
+		}
+	}
+}
+
+func (dw *DiskWatcherService) check() {
+	usage, err := dw.measure()
+	if err != nil {
+		log.Printf("Disk watcher failed to stat %s: %v", dw.boxRoot, err)
+		return
+	}
+
+	if usage.UsedRatio >= dw.warnRatio {
+		target := uint64(float64(usage.TotalBytes) * (usage.UsedRatio - dw.warnRatio))
+		freed := dw.cleanCaches(target)
+		if freed > 0 {
+			log.Printf("Disk watcher freed %d bytes from caches under pressure", freed)
+			if remeasured, err := dw.measure(); err == nil {
+				usage = remeasured
+			}
+		}
+	}
+
+	switch {
+	case usage.UsedRatio >= dw.criticalRatio:
+		usage.Pressure = "critical"
+		log.Printf("Disk usage critical on %s: %.1f%%, refusing new jobs", dw.boxRoot, usage.UsedRatio*100)
+	case usage.UsedRatio >= dw.warnRatio:
+		usage.Pressure = "warning"
+	default:
+		usage.Pressure = "ok"
+	}
+
+	if dw.metrics != nil {
+		dw.metrics.RecordDiskUsage(dw.boxRoot, usage.UsedRatio, float64(usage.FreeBytes))
+	}
+
+	dw.mutex.Lock()
+	dw.usage = *usage
+	dw.mutex.Unlock()
+}
+
+func (dw *DiskWatcherService) measure() (*DiskUsage, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dw.boxRoot, &stat); err != nil {
+		return nil, err
+	}
+
+	total := stat.Blocks * uint64(stat.Bsize)
+	free := stat.Bavail * uint64(stat.Bsize)
+
+	usage := &DiskUsage{
+		Path:        dw.boxRoot,
+		TotalBytes:  total,
+		FreeBytes:   free,
+		BoxRootSize: dirSize(dw.boxRoot),
+		CacheSizes:  make(map[string]int64),
+		CheckedAt:   time.Now(),
+	}
+	if total > 0 {
+		usage.UsedRatio = float64(total-free) / float64(total)
+	}
+	for _, dir := range dw.cacheDirs {
+		usage.CacheSizes[dir] = dirSize(dir)
+	}
+
+	return usage, nil
+}
+
+// cleanCaches removes the oldest cache files until at least target bytes are freed.
+func (dw *DiskWatcherService) cleanCaches(target uint64) uint64 {
+	type cacheFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var files []cacheFile
+	for _, dir := range dw.cacheDirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				files = append(files, cacheFile{path: path, size: info.Size(), modTime: info.ModTime()})
+			}
+			return nil
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	var freed uint64
+	for _, f := range files {
+		if freed >= target {
+			break
+		}
+		if err := os.Remove(f.path); err == nil {
+			freed += uint64(f.size)
+		}
+	}
+
+	return freed
+}
+
+func (dw *DiskWatcherService) Usage() DiskUsage {
+	dw.mutex.RLock()
+	defer dw.mutex.RUnlock()
+	return dw.usage
+}
+
+// IsCritical reports whether the node should stop accepting new jobs.
+func (dw *DiskWatcherService) IsCritical() bool {
+	dw.mutex.RLock()
+	defer dw.mutex.RUnlock()
+	return dw.usage.Pressure == "critical"
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"execution_service/internal/config"
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+)
+
+// StreamEventTypes are the events relayed to live dashboards.
+var StreamEventTypes = []string{"SubmissionJudged", "SubmissionCompilationFailed", "PlagiarismDetected"}
+
+const (
+	eventRelayInterval = time.Second
+	eventRelayBatch    = 100
+	// eventRelayLease is how long another node waits before taking over the
+	// relay from a node that stopped renewing it
+	eventRelayLease = 30 * time.Second
+)
+
+// EventPublisher publishes a recorded event and returns once the broker has
+// confirmed it.
+type EventPublisher func(ctx context.Context, entry *models.EventLogEntry) error
+
+type EventLogService struct {
+	db            *database.DB
+	publisher     EventPublisher
+	recorded      chan struct{}
+	retention     time.Duration
+	pruneInterval time.Duration
+}
+
+func NewEventLogService(db *database.DB, cfg *config.EventsConfig) *EventLogService {
+	return &EventLogService{
+		db:            db,
+		recorded:      make(chan struct{}, 1),
+		retention:     cfg.RetentionPeriod,
+		pruneInterval: time.Hour,
+	}
+}
+
+// SetPublisher sets how the relay publishes recorded events.
+func (es *EventLogService) SetPublisher(publisher EventPublisher) {
+	es.publisher = publisher
+}
+
+// Record persists an outgoing event for the relay to publish.
+func (es *EventLogService) Record(ctx context.Context, event *models.EventMessage, routingKey string) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event data: %w", err)
+	}
+
+	entry := &models.EventLogEntry{
+		EventType:  event.EventType,
+		RoutingKey: routingKey,
+		Data:       data,
+	}
+
+	if err := es.db.CreateEventLogEntry(ctx, entry); err != nil {
+		return err
+	}
+	event.Sequence = entry.Seq
+
+	// Wake the relay rather than wait for its next poll
+	select {
+	case es.recorded <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (es *EventLogService) Replay(ctx context.Context, since int64, eventType string, limit int) ([]models.EventLogEntry, error) {
+	return es.db.GetEventsSince(ctx, since, eventType, limit)
+}
+
+func (es *EventLogService) Stream(ctx context.Context, since int64, eventTypes []string, contestID *int64, limit int) ([]models.EventLogEntry, error) {
+	return es.db.GetStreamEvents(ctx, since, eventTypes, contestID, limit)
+}
+
+func (es *EventLogService) Start(ctx context.Context) {
+	ticker := time.NewTicker(es.pruneInterval)
+	defer ticker.Stop()
+
+	log.Printf("Starting event log pruning with retention: %v", es.retention)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := es.db.DeleteEventsBefore(ctx, time.Now().Add(-es.retention))
+			if err != nil {
+				log.Printf("Failed to prune event log: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("Pruned %d events from event log", deleted)
+			}
+		}
+	}
+}
+
+// StartRelay publishes recorded events in seq order. The relay is leased to
+// one node at a time, so events leave in order across nodes, and no database
+// lock is held while publishing.
+func (es *EventLogService) StartRelay(ctx context.Context, node string) {
+	ticker := time.NewTicker(eventRelayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-es.recorded:
+		}
+		es.relay(ctx, node)
+	}
+}
+
+func (es *EventLogService) relay(ctx context.Context, node string) {
+	if es.publisher == nil {
+		return
+	}
+
+	for {
+		claimed, err := es.db.ClaimEventRelay(ctx, node, eventRelayLease)
+		if err != nil {
+			log.Printf("Failed to claim event relay: %v", err)
+			return
+		}
+		if !claimed {
+			return
+		}
+
+		entries, err := es.db.GetUnpublishedEvents(ctx, eventRelayBatch)
+		if err != nil {
+			log.Printf("Failed to get unpublished events: %v", err)
+			return
+		}
+
+		for i := range entries {
+			entry := &entries[i]
+			// Stop at the first failure so later events never overtake it
+			if err := es.publisher(ctx, entry); err != nil {
+				log.Printf("Event relay failed to publish event %d: %v", entry.Seq, err)
+				return
+			}
+			if err := es.db.MarkEventPublished(ctx, entry.Seq); err != nil {
+				log.Printf("Failed to mark event %d published: %v", entry.Seq, err)
+				return
+			}
+		}
+
+		if len(entries) < eventRelayBatch {
+			return
+		}
+	}
+}
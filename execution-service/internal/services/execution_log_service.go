@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"execution_service/internal/config"
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+)
+
+// debugFlagRefresh bounds how long a debug toggle made on another node takes
+// to apply here.
+const debugFlagRefresh = 10 * time.Second
+
+// tailMessageLimit caps a streamed message; the stored log keeps all of it.
+const tailMessageLimit = 300
+
+// ExecutionLogService writes execution logs according to the configured
+// per-level sample rates. Sampling is by submission, so a sampled submission
+// keeps its whole log at that level.
+type ExecutionLogService struct {
+	db     *database.DB
+	config *config.ExecutionLogConfig
+
+	mutex     sync.Mutex
+	debug     map[int64]bool
+	refreshed time.Time
+
+	tail        chan *models.ContestLogEntry
+	publishTail func(ctx context.Context, entry *models.ContestLogEntry) error
+	node        string
+}
+
+func NewExecutionLogService(db *database.DB, cfg *config.ExecutionLogConfig) *ExecutionLogService {
+	return &ExecutionLogService{
+		db:     db,
+		config: cfg,
+		tail:   make(chan *models.ContestLogEntry, 256),
+	}
+}
+
+// SetTailPublisher streams WARN and ERROR logs of contest submissions to
+// proctors through publish.
+func (ls *ExecutionLogService) SetTailPublisher(publish func(ctx context.Context, entry *models.ContestLogEntry) error, node string) {
+	ls.publishTail = publish
+	ls.node = node
+}
+
+// Log persists the entry if its level and submission pass sampling.
+func (ls *ExecutionLogService) Log(ctx context.Context, submissionID int64, level, message string) {
+	if !ls.persisted(ctx, submissionID, strings.ToUpper(level)) {
+		return
+	}
+
+	entry := &models.ExecutionLog{SubmissionID: submissionID, Level: level, Message: message}
+	if err := ls.db.CreateExecutionLog(ctx, entry); err != nil {
+		log.Printf("Failed to store execution log: %v", err)
+	}
+}
+
+// Tail queues a contest submission's log for the proctor streams. WARN and
+// ERROR entries are streamed regardless of sampling; when the queue is full
+// the entry is dropped rather than holding up judging.
+func (ls *ExecutionLogService) Tail(contestID, submissionID int64, level, message string) {
+	level = strings.ToUpper(level)
+	if ls.publishTail == nil || (level != "WARN" && level != "ERROR") {
+		return
+	}
+
+	entry := &models.ContestLogEntry{
+		ContestID:    contestID,
+		SubmissionID: submissionID,
+		Level:        level,
+		Message:      redactCode(message),
+		Node:         ls.node,
+		Timestamp:    time.Now(),
+	}
+	select {
+	case ls.tail <- entry:
+	default:
+	}
+}
+
+// Start publishes queued contest logs until ctx is done.
+func (ls *ExecutionLogService) Start(ctx context.Context) {
+	if ls.publishTail == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-ls.tail:
+			if err := ls.publishTail(ctx, entry); err != nil {
+				log.Printf("Failed to publish contest log for submission %d: %v", entry.SubmissionID, err)
+			}
+		}
+	}
+}
+
+// redactCode keeps only the first line of a message, since compiler, checker
+// and interactor output quoted after it can echo the submitted code.
+func redactCode(message string) string {
+	first, rest, multiline := strings.Cut(strings.TrimSpace(message), "\n")
+	first = strings.TrimSpace(first)
+	if len(first) > tailMessageLimit {
+		first = strings.ToValidUTF8(first[:tailMessageLimit], "") + "..."
+	}
+	if multiline {
+		first += fmt.Sprintf(" [%d lines redacted]", strings.Count(rest, "\n")+1)
+	}
+	return first
+}
+
+// SetDebug toggles full logging for a submission; it reports false when the
+// submission does not exist.
+func (ls *ExecutionLogService) SetDebug(ctx context.Context, submissionID int64, enabled bool, userID int64) (bool, error) {
+	found, err := ls.db.SetSubmissionDebug(ctx, submissionID, enabled, userID)
+	if err != nil || !found {
+		return found, err
+	}
+
+	ls.mutex.Lock()
+	if ls.debug != nil {
+		ls.debug[submissionID] = enabled
+	}
+	ls.mutex.Unlock()
+	return true, nil
+}
+
+func (ls *ExecutionLogService) persisted(ctx context.Context, submissionID int64, level string) bool {
+	rate, sampled := ls.config.SampleRates[level]
+	if !sampled || rate >= 1 {
+		return true
+	}
+	if submissionID != 0 && ls.debugging(ctx, submissionID) {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	if submissionID == 0 {
+		return rand.Float64() < rate
+	}
+	return sampleFraction(submissionID) < rate
+}
+
+func (ls *ExecutionLogService) debugging(ctx context.Context, submissionID int64) bool {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+
+	if time.Since(ls.refreshed) > debugFlagRefresh {
+		ids, err := ls.db.GetDebugSubmissionIDs(ctx)
+		if err != nil {
+			// Keep the previous flags; sampling still applies
+			log.Printf("Failed to refresh debug submissions: %v", err)
+		} else {
+			ls.debug = make(map[int64]bool, len(ids))
+			for _, id := range ids {
+				ls.debug[id] = true
+			}
+		}
+		ls.refreshed = time.Now()
+	}
+
+	return ls.debug[submissionID]
+}
+
+// sampleFraction maps a submission ID to a stable, evenly spread value in
+// [0, 1).
+func sampleFraction(submissionID int64) float64 {
+	h := uint64(submissionID) * 0x9E3779B97F4A7C15
+	return float64(h>>11) / (1 << 53)
+}
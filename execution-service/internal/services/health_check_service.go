@@ -12,12 +12,13 @@ import (
 )
 
 type HealthCheckService struct {
-	db      *database.DB
-	queue   *queue.RabbitMQClient
-	storage *storage.MinIOClient
-	cache   *cache.ValkeyClient
-	sandbox *sandbox.IsolateSandbox
-	timeout time.Duration
+	db          *database.DB
+	queue       *queue.RabbitMQClient
+	storage     *storage.MinIOClient
+	cache       *cache.ValkeyClient
+	sandbox     *sandbox.IsolateSandbox
+	criticality map[string]Criticality
+	timeout     time.Duration
 }
 
 type HealthStatus string
@@ -28,6 +29,29 @@ const (
 	StatusUnhealthy HealthStatus = "unhealthy"
 )
 
+// Criticality is how much a dependency matters to serving traffic. Only a
+// failing critical dependency makes the service unhealthy or not ready; the
+// others degrade it.
+type Criticality string
+
+const (
+	CriticalityCritical  Criticality = "critical"
+	CriticalityImportant Criticality = "important"
+	CriticalityOptional  Criticality = "optional"
+)
+
+// defaultCriticality reflects what each dependency is needed for: nothing is
+// accepted or judged without the database and queue, storage and the sandbox
+// are needed for judging but submissions can still queue up, and the cache
+// only saves work.
+var defaultCriticality = map[string]Criticality{
+	"database": CriticalityCritical,
+	"rabbitmq": CriticalityCritical,
+	"minio":    CriticalityImportant,
+	"isolate":  CriticalityImportant,
+	"cache":    CriticalityOptional,
+}
+
 type HealthCheckResult struct {
 	Status    HealthStatus           `json:"status"`
 	Timestamp time.Time              `json:"timestamp"`
@@ -37,20 +61,22 @@ type HealthCheckResult struct {
 }
 
 type CheckResult struct {
-	Status  HealthStatus  `json:"status"`
-	Message string        `json:"message"`
-	Details interface{}   `json:"details,omitempty"`
-	Latency time.Duration `json:"latency,omitempty"`
+	Status      HealthStatus  `json:"status"`
+	Criticality Criticality   `json:"criticality,omitempty"`
+	Message     string        `json:"message"`
+	Details     interface{}   `json:"details,omitempty"`
+	Latency     time.Duration `json:"latency,omitempty"`
 }
 
 func NewHealthCheckService(db *database.DB, queue *queue.RabbitMQClient, storage *storage.MinIOClient, cache *cache.ValkeyClient, sandbox *sandbox.IsolateSandbox) *HealthCheckService {
 	return &HealthCheckService{
-		db:      db,
-		queue:   queue,
-		storage: storage,
-		cache:   cache,
-		sandbox: sandbox,
-		timeout: 10 * time.Second,
+		db:          db,
+		queue:       queue,
+		storage:     storage,
+		cache:       cache,
+		sandbox:     sandbox,
+		criticality: defaultCriticality,
+		timeout:     10 * time.Second,
 	}
 }
 
@@ -74,19 +100,13 @@ func (hcs *HealthCheckService) CheckHealth(ctx context.Context) *HealthCheckResu
 	// Isolate sandbox health check
 	checks["isolate"] = hcs.checkIsolate(ctx)
 
-	// Determine overall status
-	overallStatus := StatusHealthy
-	for _, check := range checks {
-		if check.Status == StatusUnhealthy {
-			overallStatus = StatusUnhealthy
-			break
-		} else if check.Status == StatusDegraded {
-			overallStatus = StatusDegraded
-		}
+	for name, check := range checks {
+		check.Criticality = hcs.criticalityOf(name)
+		checks[name] = check
 	}
 
 	return &HealthCheckResult{
-		Status:    overallStatus,
+		Status:    overallStatus(checks),
 		Timestamp: time.Now().UTC(),
 		Uptime:    time.Since(startTime),
 		Checks:    checks,
@@ -94,6 +114,29 @@ func (hcs *HealthCheckService) CheckHealth(ctx context.Context) *HealthCheckResu
 	}
 }
 
+func (hcs *HealthCheckService) criticalityOf(dependency string) Criticality {
+	if level, ok := hcs.criticality[dependency]; ok {
+		return level
+	}
+	return CriticalityImportant
+}
+
+// overallStatus is unhealthy only when a critical dependency is; any other
+// failing or degraded dependency leaves the service degraded.
+func overallStatus(checks map[string]CheckResult) HealthStatus {
+	status := StatusHealthy
+	for _, check := range checks {
+		if check.Status == StatusHealthy {
+			continue
+		}
+		if check.Status == StatusUnhealthy && check.Criticality == CriticalityCritical {
+			return StatusUnhealthy
+		}
+		status = StatusDegraded
+	}
+	return status
+}
+
 func (hcs *HealthCheckService) checkDatabase(ctx context.Context) CheckResult {
 	start := time.Now()
 
@@ -223,10 +266,14 @@ func (hcs *HealthCheckService) checkCache(ctx context.Context) CheckResult {
 }
 
 func (hcs *HealthCheckService) checkIsolate(ctx context.Context) CheckResult {
+	return probeIsolate(hcs.sandbox)
+}
+
+func probeIsolate(sb *sandbox.IsolateSandbox) CheckResult {
 	start := time.Now()
 
 	// Try to create and cleanup a test box
-	boxID, err := hcs.sandbox.CreateBox()
+	boxID, err := sb.CreateBox()
 	if err != nil {
 		return CheckResult{
 			Status:  StatusUnhealthy,
@@ -237,7 +284,7 @@ func (hcs *HealthCheckService) checkIsolate(ctx context.Context) CheckResult {
 	}
 
 	// Cleanup the test box
-	hcs.sandbox.CleanupBox(boxID)
+	sb.CleanupBox(boxID)
 	latency := time.Since(start)
 
 	return CheckResult{
@@ -303,27 +350,27 @@ func (hcs *HealthCheckService) countUnhealthyChecks(checks map[string]CheckResul
 	return count
 }
 
-// Readiness probe (for Kubernetes)
+// Readiness probe (for Kubernetes). The service stays in rotation while only
+// important or optional dependencies fail; those are still reported.
 func (hcs *HealthCheckService) CheckReadiness(ctx context.Context) CheckResult {
-	// Check if all critical dependencies are healthy
-	checks := map[string]CheckResult{
-		"database": hcs.checkDatabase(ctx),
-		"rabbitmq": hcs.checkRabbitMQ(ctx),
-	}
+	result := hcs.CheckHealth(ctx)
 
-	for _, check := range checks {
-		if check.Status != StatusHealthy {
-			return CheckResult{
-				Status:  StatusUnhealthy,
-				Message: "Service is not ready",
-				Details: checks,
-			}
+	if result.Status == StatusUnhealthy {
+		return CheckResult{
+			Status:  StatusUnhealthy,
+			Message: "Service is not ready",
+			Details: result.Checks,
 		}
 	}
 
+	message := "Service is ready"
+	if result.Status == StatusDegraded {
+		message = "Service is ready with degraded dependencies"
+	}
 	return CheckResult{
-		Status:  StatusHealthy,
-		Message: "Service is ready",
+		Status:  result.Status,
+		Message: message,
+		Details: result.Checks,
 	}
 }
 
@@ -2,25 +2,138 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
 
 	"execution_service/internal/cache"
 	"execution_service/internal/database"
 	"execution_service/internal/models"
+	"execution_service/internal/sandbox"
+	"execution_service/internal/validation"
 )
 
+var (
+	ErrInvalidLanguage  = errors.New("invalid language")
+	ErrLanguageExists   = errors.New("language already exists")
+	ErrLanguageNotFound = errors.New("language not found")
+)
+
+// LanguageService keeps the enabled languages from supported_languages and
+// pushes them to the sandbox, so languages can be added or tuned without a
+// redeploy. Every node reloads periodically; the refresh endpoint applies a
+// change to one node at once.
 type LanguageService struct {
-	db    *database.DB
-	cache *cache.ValkeyClient
+	db              *database.DB
+	cache           *cache.ValkeyClient
+	sandbox         *sandbox.IsolateSandbox
+	refreshInterval time.Duration
+	listener        func()
+
+	mu     sync.RWMutex
+	loaded []models.SupportedLanguage
 }
 
 func NewLanguageService(db *database.DB, cache *cache.ValkeyClient) *LanguageService {
 	return &LanguageService{
-		db:    db,
-		cache: cache,
+		db:              db,
+		cache:           cache,
+		refreshInterval: 5 * time.Minute,
 	}
 }
 
+func (ls *LanguageService) SetSandbox(sb *sandbox.IsolateSandbox) {
+	ls.sandbox = sb
+}
+
+// SetRefreshListener registers a callback invoked after a refresh changed
+// the languages.
+func (ls *LanguageService) SetRefreshListener(listener func()) {
+	ls.mu.Lock()
+	ls.listener = listener
+	ls.mu.Unlock()
+}
+
+func (ls *LanguageService) Start(ctx context.Context) {
+	ticker := time.NewTicker(ls.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := ls.Refresh(ctx); err != nil {
+				log.Printf("Failed to refresh languages: %v", err)
+			}
+		}
+	}
+}
+
+// Refresh reloads the enabled languages. When they changed, the sandbox gets
+// the new configs and a fresh environment snapshot, and cached copies are
+// dropped.
+func (ls *LanguageService) Refresh(ctx context.Context) ([]models.SupportedLanguage, error) {
+	languages, err := ls.db.GetSupportedLanguages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load languages: %w", err)
+	}
+
+	ls.mu.Lock()
+	previous := ls.loaded
+	changed := previous == nil || !reflect.DeepEqual(previous, languages)
+	ls.loaded = languages
+	listener := ls.listener
+	ls.mu.Unlock()
+
+	if !changed {
+		return languages, nil
+	}
+
+	if ls.sandbox != nil {
+		ls.sandbox.SetLanguages(languages)
+		ls.sandbox.SnapshotEnvironment(ctx)
+	}
+	if ls.cache != nil {
+		ls.cache.InvalidateLanguageList(ctx)
+		for _, language := range append(previous, languages...) {
+			ls.cache.InvalidateLanguage(ctx, language.LanguageCode)
+		}
+	}
+	if listener != nil {
+		listener()
+	}
+	return languages, nil
+}
+
+// Validate accepts enabled languages, falling back to the built-in set
+// before the first successful load.
+func (ls *LanguageService) Validate(code string) error {
+	if code == models.LanguageOutputOnly {
+		return nil
+	}
+	if err := validation.ValidateLanguageFormat(code); err != nil {
+		return err
+	}
+
+	ls.mu.RLock()
+	loaded := ls.loaded
+	ls.mu.RUnlock()
+	if loaded == nil {
+		return validation.ValidateLanguage(code)
+	}
+
+	for _, language := range loaded {
+		if language.LanguageCode == code {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported language: %s", code)
+}
+
 func (ls *LanguageService) GetSupportedLanguages(ctx context.Context) ([]models.SupportedLanguage, error) {
 	// Get from database
 	languages, err := ls.db.GetSupportedLanguages(ctx)
@@ -55,16 +168,64 @@ func (ls *LanguageService) GetLanguage(ctx context.Context, code string) (*model
 	return language, nil
 }
 
+// CreateLanguage stores a new language and applies it on this node.
+func (ls *LanguageService) CreateLanguage(ctx context.Context, language *models.SupportedLanguage) error {
+	if err := validation.ValidateLanguageConfig(language, sandbox.AllowedDevices()); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidLanguage, err)
+	}
+
+	created, err := ls.db.CreateLanguage(ctx, language)
+	if err != nil {
+		return err
+	}
+	if !created {
+		return ErrLanguageExists
+	}
+
+	ls.applyChange(ctx)
+	return nil
+}
+
+// UpdateLanguage overwrites the language with the same code and applies it
+// on this node. Disabling a language is an update with IsEnabled false.
 func (ls *LanguageService) UpdateLanguage(ctx context.Context, language *models.SupportedLanguage) error {
-	// Update in database
-	// TODO: Implement database update method
+	if err := validation.ValidateLanguageConfig(language, sandbox.AllowedDevices()); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidLanguage, err)
+	}
+
+	updated, err := ls.db.UpdateLanguage(ctx, language)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrLanguageNotFound
+	}
+
+	ls.applyChange(ctx)
+	return nil
+}
 
-	// Invalidate cache
-	ls.cache.InvalidateLanguage(ctx, language.LanguageCode)
+func (ls *LanguageService) DeleteLanguage(ctx context.Context, code string) error {
+	deleted, err := ls.db.DeleteLanguage(ctx, code)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrLanguageNotFound
+	}
 
+	ls.applyChange(ctx)
 	return nil
 }
 
+// applyChange refreshes after an admin edit. The edit is already stored, so
+// a failed refresh is left to the periodic one.
+func (ls *LanguageService) applyChange(ctx context.Context) {
+	if _, err := ls.Refresh(ctx); err != nil {
+		log.Printf("Failed to refresh languages after an update: %v", err)
+	}
+}
+
 func (ls *LanguageService) GetLanguageConfig(code string) (*models.SupportedLanguage, error) {
 	ctx := context.Background()
 	language, err := ls.GetLanguage(ctx, code)
@@ -117,6 +278,38 @@ func (ls *LanguageService) getHardcodedLanguageConfig(code string) *models.Suppo
 			ExecuteCommand: "./{executable}",
 			IsEnabled:      true,
 		},
+		"rust": {
+			LanguageCode:   "rust",
+			LanguageName:   "Rust 2021",
+			Version:        "2021",
+			CompileCommand: stringPtr("rustc -O --edition 2021 -o {output} {input}"),
+			ExecuteCommand: "./{executable}",
+			IsEnabled:      true,
+		},
+		"kotlin": {
+			LanguageCode:   "kotlin",
+			LanguageName:   "Kotlin 1.9",
+			Version:        "1.9",
+			CompileCommand: stringPtr("kotlinc {input} -include-runtime -d {output}.jar"),
+			ExecuteCommand: "java -jar {executable}.jar",
+			IsEnabled:      true,
+		},
+		"csharp": {
+			LanguageCode:   "csharp",
+			LanguageName:   "C# (Mono)",
+			Version:        "6.12",
+			CompileCommand: stringPtr("mcs -optimize+ -out:{output}.exe {input}"),
+			ExecuteCommand: "mono {executable}.exe",
+			IsEnabled:      true,
+		},
+		"ruby": {
+			LanguageCode:   "ruby",
+			LanguageName:   "Ruby 3",
+			Version:        "3.2",
+			CompileCommand: nil,
+			ExecuteCommand: "ruby {input}",
+			IsEnabled:      true,
+		},
 	}
 
 	if config, exists := configs[code]; exists {
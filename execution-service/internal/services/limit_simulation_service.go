@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+)
+
+// MaxLimitRerunSample caps how many submissions one simulation re-judges.
+const MaxLimitRerunSample = 10
+
+// LimitSimulationService replays a problem's accepted submissions against
+// candidate limits so setters can see the effect before changing them.
+type LimitSimulationService struct {
+	db    *database.DB
+	rerun func(ctx context.Context, submission *models.Submission, timeLimitMs, memoryLimitKb int) (*models.CanaryRun, error)
+}
+
+func NewLimitSimulationService(db *database.DB) *LimitSimulationService {
+	return &LimitSimulationService{db: db}
+}
+
+// SetRerunJudge sets the judge used for re-runs. Without one, simulations
+// use stored results only.
+func (ls *LimitSimulationService) SetRerunJudge(rerun func(ctx context.Context, submission *models.Submission, timeLimitMs, memoryLimitKb int) (*models.CanaryRun, error)) {
+	ls.rerun = rerun
+}
+
+// Simulate estimates the effect of the candidate limits on up to limit of
+// the problem's latest accepted submissions, then re-judges the rerunSample
+// slowest of them under those limits.
+func (ls *LimitSimulationService) Simulate(ctx context.Context, problemID int64, timeLimitMs, memoryLimitKb, limit, rerunSample int) (*models.LimitSimulation, error) {
+	submissions, err := ls.db.GetAcceptedSubmissions(ctx, problemID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	simulation := &models.LimitSimulation{
+		ProblemID:     problemID,
+		TimeLimitMs:   timeLimitMs,
+		MemoryLimitKb: memoryLimitKb,
+		Submissions:   len(submissions),
+		Flips:         []models.LimitFlip{},
+		Reruns:        []models.LimitRerun{},
+	}
+	if len(submissions) == 0 {
+		return simulation, nil
+	}
+
+	ids := make([]int64, len(submissions))
+	for i, submission := range submissions {
+		ids[i] = submission.ID
+	}
+	results, err := ls.db.GetTestResultsForSubmissions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	bySubmission := make(map[int64][]models.SubmissionTestResult, len(submissions))
+	for _, result := range results {
+		bySubmission[result.SubmissionID] = append(bySubmission[result.SubmissionID], result)
+	}
+
+	var measured []*models.Submission
+	for i := range submissions {
+		submission := &submissions[i]
+		tests := bySubmission[submission.ID]
+		if len(tests) == 0 {
+			simulation.Skipped++
+			continue
+		}
+		measured = append(measured, submission)
+
+		flip := estimateFlip(tests, timeLimitMs, memoryLimitKb)
+		switch {
+		case flip == nil:
+			simulation.Unchanged++
+			continue
+		case flip.Verdict == models.VerdictTimeLim:
+			simulation.FlippedTLE++
+		default:
+			simulation.FlippedMLE++
+		}
+		flip.SubmissionID = submission.ID
+		flip.UserID = submission.UserID
+		flip.Language = submission.Language
+		simulation.Flips = append(simulation.Flips, *flip)
+	}
+
+	if ls.rerun == nil || rerunSample <= 0 {
+		return simulation, nil
+	}
+	sort.SliceStable(measured, func(a, b int) bool {
+		return derefInt(measured[a].ExecutionTimeMs) > derefInt(measured[b].ExecutionTimeMs)
+	})
+	for _, submission := range measured[:min(len(measured), rerunSample)] {
+		rerun := models.LimitRerun{SubmissionID: submission.ID}
+		run, err := ls.rerun(ctx, submission, timeLimitMs, memoryLimitKb)
+		if err != nil {
+			rerun.Error = err.Error()
+		} else {
+			rerun.Verdict = run.Verdict
+			rerun.MaxTimeMs = run.MaxTimeMs
+			rerun.TestsPassed = run.TestsPassed
+		}
+		simulation.Reruns = append(simulation.Reruns, rerun)
+	}
+	return simulation, nil
+}
+
+// estimateFlip returns the first test whose stored usage exceeds the
+// candidate limits, or nil when every test still fits. The candidates are
+// scaled by the language factor each test was judged with.
+func estimateFlip(tests []models.SubmissionTestResult, timeLimitMs, memoryLimitKb int) *models.LimitFlip {
+	for _, test := range tests {
+		timeMs, memoryKb := derefInt(test.ExecutionTimeMs), derefInt(test.MemoryUsedKb)
+		flip := &models.LimitFlip{TestNumber: test.TestNumber, TimeMs: timeMs, MemoryKb: memoryKb}
+		switch {
+		case timeMs > scaledLimit(timeLimitMs, test.TimeLimitMs, test.EffectiveTimeLimitMs):
+			flip.Verdict = models.VerdictTimeLim
+		case memoryKb > scaledLimit(memoryLimitKb, test.MemoryLimitKb, test.EffectiveMemoryLimitKb):
+			flip.Verdict = models.VerdictMemLim
+		default:
+			continue
+		}
+		return flip
+	}
+	return nil
+}
+
+func scaledLimit(candidate int, raw, effective *int) int {
+	if raw == nil || effective == nil || *raw <= 0 {
+		return candidate
+	}
+	return int(int64(candidate) * int64(*effective) / int64(*raw))
+}
+
+func derefInt(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
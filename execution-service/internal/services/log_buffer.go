@@ -0,0 +1,66 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+var errorLogMarkers = []string{"error", "failed", "alert", "panic", "fatal"}
+
+// LogBuffer is an io.Writer for the standard logger that keeps the most
+// recent error-looking lines in memory for diagnostics bundles.
+type LogBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	next    int
+	full    bool
+	partial []byte
+}
+
+func NewLogBuffer(capacity int) *LogBuffer {
+	return &LogBuffer{lines: make([]string, capacity)}
+}
+
+func (lb *LogBuffer) Write(p []byte) (int, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	data := append(lb.partial, p...)
+	for {
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			break
+		}
+		lb.add(string(data[:idx]))
+		data = data[idx+1:]
+	}
+	lb.partial = append([]byte(nil), data...)
+
+	return len(p), nil
+}
+
+func (lb *LogBuffer) add(line string) {
+	lower := strings.ToLower(line)
+	for _, marker := range errorLogMarkers {
+		if strings.Contains(lower, marker) {
+			lb.lines[lb.next] = line
+			lb.next = (lb.next + 1) % len(lb.lines)
+			if lb.next == 0 {
+				lb.full = true
+			}
+			return
+		}
+	}
+}
+
+// Recent returns the buffered lines, oldest first.
+func (lb *LogBuffer) Recent() []string {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if !lb.full {
+		return append([]string(nil), lb.lines[:lb.next]...)
+	}
+	return append(append([]string(nil), lb.lines[lb.next:]...), lb.lines[:lb.next]...)
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -23,11 +24,32 @@ type MetricsService struct {
 	executionTime   *prometheus.HistogramVec
 	memoryUsage     *prometheus.HistogramVec
 	compilationTime *prometheus.HistogramVec
+	checkerTime     *prometheus.HistogramVec
+	checkerMemory   *prometheus.HistogramVec
 
 	// System metrics
 	circuitBreakerState *prometheus.GaugeVec
 	sandboxOperations   *prometheus.CounterVec
 	storageOperations   *prometheus.CounterVec
+	cacheRequests       *prometheus.CounterVec
+	diskUsageRatio      *prometheus.GaugeVec
+	diskFreeBytes       *prometheus.GaugeVec
+	boxPoolBoxes        *prometheus.GaugeVec
+	spoolEntries        prometheus.Gauge
+	spoolBytes          prometheus.Gauge
+	spoolDegraded       prometheus.Gauge
+	warmPoolRequests    *prometheus.CounterVec
+	warmPoolReinits     *prometheus.CounterVec
+	warmPoolReady       *prometheus.GaugeVec
+	warmPoolOldestAge   *prometheus.GaugeVec
+	contentRequests     *prometheus.HistogramVec
+	rbacEnforcement     *prometheus.HistogramVec
+	apiKeyRequests      *prometheus.CounterVec
+
+	// HTTP metrics, labelled by route template rather than raw path
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	httpInFlight *prometheus.GaugeVec
 
 	// Error metrics
 	errorTotal         *prometheus.CounterVec
@@ -116,6 +138,24 @@ func NewMetricsService() *MetricsService {
 			[]string{"language"},
 		),
 
+		checkerTime: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "judge_checker_time_milliseconds",
+				Help:    "Execution time of custom checkers per test case",
+				Buckets: []float64{10, 50, 100, 250, 500, 1000, 2000, 5000, 10000},
+			},
+			[]string{"result"},
+		),
+
+		checkerMemory: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "judge_checker_memory_kb",
+				Help:    "Memory usage of custom checkers per test case",
+				Buckets: []float64{1024, 4096, 16384, 65536, 131072, 262144},
+			},
+			[]string{"result"},
+		),
+
 		circuitBreakerState: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name: "judge_circuit_breaker_state",
@@ -140,6 +180,142 @@ func NewMetricsService() *MetricsService {
 			[]string{"operation", "result"},
 		),
 
+		cacheRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "judge_cache_requests_total",
+				Help: "Number of cache lookups by cache and result (hit/miss)",
+			},
+			[]string{"cache", "result"},
+		),
+
+		diskUsageRatio: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "judge_disk_usage_ratio",
+				Help: "Used fraction of the filesystem hosting sandbox boxes",
+			},
+			[]string{"path"},
+		),
+
+		diskFreeBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "judge_disk_free_bytes",
+				Help: "Free bytes on the filesystem hosting sandbox boxes",
+			},
+			[]string{"path"},
+		),
+
+		boxPoolBoxes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "judge_box_pool_boxes",
+				Help: "Pooled sandbox boxes by state",
+			},
+			[]string{"state"},
+		),
+
+		spoolEntries: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "judge_spool_entries",
+				Help: "Submissions whose code is spooled to local disk awaiting MinIO",
+			},
+		),
+
+		spoolBytes: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "judge_spool_bytes",
+				Help: "Bytes of submission code spooled to local disk",
+			},
+		),
+
+		spoolDegraded: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "judge_spool_degraded",
+				Help: "1 while submission intake is spooling because MinIO is unavailable",
+			},
+		),
+
+		warmPoolRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "judge_warm_pool_requests_total",
+				Help: "Executions in languages with a warm pool, by whether a warm box was ready",
+			},
+			[]string{"language", "result"},
+		),
+
+		warmPoolReinits: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "judge_warm_pool_reinits_total",
+				Help: "Warm boxes re-initialized after a failed warm-up or a flush",
+			},
+			[]string{"language"},
+		),
+
+		warmPoolReady: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "judge_warm_pool_ready_boxes",
+				Help: "Warm boxes ready per language",
+			},
+			[]string{"language"},
+		),
+
+		warmPoolOldestAge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "judge_warm_pool_oldest_box_age_seconds",
+				Help: "Time since the oldest ready warm box was warmed",
+			},
+			[]string{"language"},
+		),
+
+		contentRequests: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "judge_content_service_request_duration_seconds",
+				Help:    "Latency of content service calls by operation and status",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"operation", "status"},
+		),
+
+		rbacEnforcement: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "judge_rbac_enforcement_duration_seconds",
+				Help:    "Latency of uncached RBAC enforcement by check type",
+				Buckets: []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1},
+			},
+			[]string{"check"},
+		),
+
+		apiKeyRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "judge_api_key_requests_total",
+				Help: "Requests authenticated with API keys by outcome",
+			},
+			[]string{"result"},
+		),
+
+		httpRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "judge_http_requests_total",
+				Help: "HTTP requests by method, route template and status code",
+			},
+			[]string{"method", "route", "status"},
+		),
+
+		httpDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "judge_http_request_duration_seconds",
+				Help:    "HTTP request latency by method and route template",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"method", "route"},
+		),
+
+		httpInFlight: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "judge_http_requests_in_flight",
+				Help: "HTTP requests currently being served by method and route template",
+			},
+			[]string{"method", "route"},
+		),
+
 		errorTotal: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "judge_errors_total",
@@ -168,9 +344,28 @@ func NewMetricsService() *MetricsService {
 		ms.executionTime,
 		ms.memoryUsage,
 		ms.compilationTime,
+		ms.checkerTime,
+		ms.checkerMemory,
 		ms.circuitBreakerState,
 		ms.sandboxOperations,
 		ms.storageOperations,
+		ms.cacheRequests,
+		ms.diskUsageRatio,
+		ms.diskFreeBytes,
+		ms.boxPoolBoxes,
+		ms.spoolEntries,
+		ms.spoolBytes,
+		ms.spoolDegraded,
+		ms.warmPoolRequests,
+		ms.warmPoolReinits,
+		ms.warmPoolReady,
+		ms.warmPoolOldestAge,
+		ms.contentRequests,
+		ms.rbacEnforcement,
+		ms.apiKeyRequests,
+		ms.httpRequests,
+		ms.httpDuration,
+		ms.httpInFlight,
 		ms.errorTotal,
 		ms.securityViolations,
 	)
@@ -215,6 +410,11 @@ func (ms *MetricsService) RecordCompilationTime(language string, timeMs float64)
 	ms.compilationTime.WithLabelValues(language).Observe(timeMs)
 }
 
+func (ms *MetricsService) RecordCheckerUsage(result string, timeMs, memoryKb float64) {
+	ms.checkerTime.WithLabelValues(result).Observe(timeMs)
+	ms.checkerMemory.WithLabelValues(result).Observe(memoryKb)
+}
+
 func (ms *MetricsService) RecordCircuitBreakerState(service string, state float64) {
 	ms.circuitBreakerState.WithLabelValues(service).Set(state)
 }
@@ -227,6 +427,77 @@ func (ms *MetricsService) RecordStorageOperation(operation, result string) {
 	ms.storageOperations.WithLabelValues(operation, result).Inc()
 }
 
+func (ms *MetricsService) RecordCacheHit(cache string) {
+	ms.cacheRequests.WithLabelValues(cache, "hit").Inc()
+}
+
+func (ms *MetricsService) RecordCacheMiss(cache string) {
+	ms.cacheRequests.WithLabelValues(cache, "miss").Inc()
+}
+
+func (ms *MetricsService) RecordDiskUsage(path string, usedRatio, freeBytes float64) {
+	ms.diskUsageRatio.WithLabelValues(path).Set(usedRatio)
+	ms.diskFreeBytes.WithLabelValues(path).Set(freeBytes)
+}
+
+func (ms *MetricsService) RecordBoxPool(available, warm, inUse int) {
+	ms.boxPoolBoxes.WithLabelValues("available").Set(float64(available))
+	ms.boxPoolBoxes.WithLabelValues("warm").Set(float64(warm))
+	ms.boxPoolBoxes.WithLabelValues("in_use").Set(float64(inUse))
+}
+
+func (ms *MetricsService) RecordSpool(entries int, bytes int64, degraded bool) {
+	ms.spoolEntries.Set(float64(entries))
+	ms.spoolBytes.Set(float64(bytes))
+	if degraded {
+		ms.spoolDegraded.Set(1)
+	} else {
+		ms.spoolDegraded.Set(0)
+	}
+}
+
+// RecordWarmPool counts a warm pool hit, miss or re-initialization and
+// updates the language's ready boxes and their oldest age.
+func (ms *MetricsService) RecordWarmPool(language, event string, ready int, oldestAgeSeconds float64) {
+	switch event {
+	case "hit", "miss":
+		ms.warmPoolRequests.WithLabelValues(language, event).Inc()
+	case "reinit":
+		ms.warmPoolReinits.WithLabelValues(language).Inc()
+	}
+	ms.warmPoolReady.WithLabelValues(language).Set(float64(ready))
+	ms.warmPoolOldestAge.WithLabelValues(language).Set(oldestAgeSeconds)
+}
+
+func (ms *MetricsService) RecordContentServiceRequest(operation, status string, duration time.Duration) {
+	ms.contentRequests.WithLabelValues(operation, status).Observe(duration.Seconds())
+}
+
+func (ms *MetricsService) RecordRBACDecision(check string, cached bool, duration time.Duration) {
+	if cached {
+		ms.RecordCacheHit("rbac")
+		return
+	}
+	ms.RecordCacheMiss("rbac")
+	ms.rbacEnforcement.WithLabelValues(check).Observe(duration.Seconds())
+}
+
+func (ms *MetricsService) RecordAPIKeyRequest(result string) {
+	ms.apiKeyRequests.WithLabelValues(result).Inc()
+}
+
+// HTTPRequestStarted counts a request as in flight until RecordHTTPRequest
+// is called for it.
+func (ms *MetricsService) HTTPRequestStarted(method, route string) {
+	ms.httpInFlight.WithLabelValues(method, route).Inc()
+}
+
+func (ms *MetricsService) RecordHTTPRequest(method, route string, status int, duration time.Duration) {
+	ms.httpInFlight.WithLabelValues(method, route).Dec()
+	ms.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	ms.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
 func (ms *MetricsService) RecordError(component, errorType string) {
 	ms.errorTotal.WithLabelValues(component, errorType).Inc()
 }
@@ -237,7 +508,7 @@ func (ms *MetricsService) RecordSecurityViolation(violationType, severity string
 
 // HTTP handler for Prometheus metrics
 func (ms *MetricsService) Handler() http.Handler {
-	return promhttp.Handler()
+	return promhttp.HandlerFor(ms.registry, promhttp.HandlerOpts{})
 }
 
 // Get registry for custom metrics
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsServiceCacheRequests(t *testing.T) {
+	ms := NewMetricsService()
+	ms.RecordCacheHit("language_list")
+	ms.RecordCacheHit("language_list")
+	ms.RecordCacheMiss("judge_status")
+
+	rec := httptest.NewRecorder()
+	ms.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`judge_cache_requests_total{cache="language_list",result="hit"} 2`,
+		`judge_cache_requests_total{cache="judge_status",result="miss"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output is missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"execution_service/internal/config"
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+)
+
+// QueueSnapshotService periodically records what this node has queued and
+// prunes snapshots past retention.
+type QueueSnapshotService struct {
+	db        *database.DB
+	source    func() (*models.QueueSnapshot, error)
+	interval  time.Duration
+	retention time.Duration
+}
+
+func NewQueueSnapshotService(db *database.DB, source func() (*models.QueueSnapshot, error), cfg *config.QueueSnapshotConfig) *QueueSnapshotService {
+	return &QueueSnapshotService{
+		db:        db,
+		source:    source,
+		interval:  cfg.Interval,
+		retention: cfg.Retention,
+	}
+}
+
+// Around returns the snapshots taken within window either side of at.
+func (qs *QueueSnapshotService) Around(ctx context.Context, at time.Time, window time.Duration, node string, limit int) ([]models.QueueSnapshot, error) {
+	return qs.db.GetQueueSnapshots(ctx, at.Add(-window), at.Add(window), node, limit)
+}
+
+func (qs *QueueSnapshotService) Start(ctx context.Context) {
+	ticker := time.NewTicker(qs.interval)
+	defer ticker.Stop()
+
+	log.Printf("Starting queue snapshots every %v with retention: %v", qs.interval, qs.retention)
+
+	lastPrune := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			qs.take(ctx)
+			if time.Since(lastPrune) >= time.Hour {
+				lastPrune = time.Now()
+				qs.prune(ctx)
+			}
+		}
+	}
+}
+
+func (qs *QueueSnapshotService) take(ctx context.Context) {
+	snapshot, err := qs.source()
+	if err != nil {
+		log.Printf("Failed to take queue snapshot: %v", err)
+		return
+	}
+	if err := qs.db.CreateQueueSnapshot(ctx, snapshot); err != nil {
+		log.Printf("Failed to save queue snapshot: %v", err)
+	}
+}
+
+func (qs *QueueSnapshotService) prune(ctx context.Context) {
+	deleted, err := qs.db.DeleteQueueSnapshotsBefore(ctx, time.Now().Add(-qs.retention))
+	if err != nil {
+		log.Printf("Failed to prune queue snapshots: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Pruned %d queue snapshots", deleted)
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"execution_service/internal/config"
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+)
+
+const (
+	rejudgeJobLease        = time.Minute
+	rejudgeJobPollInterval = 5 * time.Second
+)
+
+// RejudgeJobService runs bulk rejudge jobs. Any node may claim a job; a job
+// whose node dies is resumed by another once its lease expires, so a few
+// submissions may be requeued twice.
+type RejudgeJobService struct {
+	db          *database.DB
+	submissions *SubmissionService
+	config      *config.RejudgeConfig
+	node        string
+}
+
+func NewRejudgeJobService(db *database.DB, submissions *SubmissionService, cfg *config.RejudgeConfig, node string) *RejudgeJobService {
+	return &RejudgeJobService{
+		db:          db,
+		submissions: submissions,
+		config:      cfg,
+		node:        node,
+	}
+}
+
+func (rs *RejudgeJobService) Create(ctx context.Context, job *models.RejudgeJob) error {
+	return rs.db.CreateRejudgeJob(ctx, job)
+}
+
+// Get returns nil when there is no such job.
+func (rs *RejudgeJobService) Get(ctx context.Context, jobID int64) (*models.RejudgeJob, error) {
+	return rs.db.GetRejudgeJob(ctx, jobID)
+}
+
+// Start claims and runs jobs one at a time until ctx is cancelled.
+func (rs *RejudgeJobService) Start(ctx context.Context) {
+	ticker := time.NewTicker(rejudgeJobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			job, err := rs.db.ClaimRejudgeJob(ctx, rs.node, rejudgeJobLease)
+			if err != nil {
+				log.Printf("Failed to claim rejudge job: %v", err)
+				continue
+			}
+			if job != nil {
+				rs.run(ctx, job)
+			}
+		}
+	}
+}
+
+func (rs *RejudgeJobService) run(ctx context.Context, job *models.RejudgeJob) {
+	log.Printf("Running rejudge job %d for problem %d (%d/%d submissions done)", job.ID, job.ProblemID, job.Processed, job.Total)
+
+	limiter := time.NewTicker(time.Second / time.Duration(rs.config.Rate))
+	defer limiter.Stop()
+
+	for {
+		batch, err := rs.db.GetRejudgeBatch(ctx, job, rs.config.BatchSize)
+		if err != nil {
+			// Leave the job to be reclaimed once its lease expires
+			log.Printf("Rejudge job %d stalled: %v", job.ID, err)
+			return
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for i := range batch {
+			select {
+			case <-ctx.Done():
+				return
+			case <-limiter.C:
+			}
+
+			if err := rs.submissions.Rejudge(ctx, &batch[i], 0); err != nil {
+				rs.finish(ctx, job, models.RejudgeJobFailed, err)
+				return
+			}
+			job.Processed++
+			job.LastSubmissionID = batch[i].ID
+		}
+
+		leased, err := rs.db.UpdateRejudgeJobProgress(ctx, job, rs.node, rejudgeJobLease)
+		if err != nil {
+			log.Printf("Rejudge job %d stalled: %v", job.ID, err)
+			return
+		}
+		if !leased {
+			log.Printf("Rejudge job %d was claimed by another node", job.ID)
+			return
+		}
+	}
+
+	rs.finish(ctx, job, models.RejudgeJobCompleted, nil)
+}
+
+func (rs *RejudgeJobService) finish(ctx context.Context, job *models.RejudgeJob, status string, jobErr error) {
+	var message *string
+	if jobErr != nil {
+		text := jobErr.Error()
+		message = &text
+		log.Printf("Rejudge job %d failed after %d submissions: %v", job.ID, job.Processed, jobErr)
+	} else {
+		log.Printf("Rejudge job %d completed: %d submissions requeued", job.ID, job.Processed)
+	}
+
+	if err := rs.db.FinishRejudgeJob(ctx, job.ID, status, message); err != nil {
+		log.Printf("Failed to finish rejudge job %d: %v", job.ID, err)
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"execution_service/internal/config"
+	"execution_service/internal/sandbox"
+)
+
+// ErrInvalidRepository means a repository submission points somewhere the
+// intake does not allow, or the checkout breaks the intake limits.
+var ErrInvalidRepository = errors.New("invalid repository submission")
+
+var commitRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// RepositoryProject is a fetched and checked repository submission.
+type RepositoryProject struct {
+	Archive   []byte
+	EntryFile string
+	FileCount int
+	SizeBytes int64
+}
+
+// RepositoryIntakeService turns a git URL and commit into a project archive
+// for the multi-file pipeline. Fetching happens in a sandbox box with
+// network access, and only hosts on the allowlist are contacted.
+type RepositoryIntakeService struct {
+	config  *config.GitIntakeConfig
+	sandbox *sandbox.IsolateSandbox
+}
+
+func NewRepositoryIntakeService(cfg *config.GitIntakeConfig, sb *sandbox.IsolateSandbox) *RepositoryIntakeService {
+	return &RepositoryIntakeService{
+		config:  cfg,
+		sandbox: sb,
+	}
+}
+
+// Validate checks the URL and commit without fetching anything.
+func (rs *RepositoryIntakeService) Validate(repoURL, commit string) error {
+	parsed, err := url.Parse(repoURL)
+	if err != nil {
+		return fmt.Errorf("%w: malformed repository URL", ErrInvalidRepository)
+	}
+	if parsed.Scheme != "https" || parsed.User != nil || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return fmt.Errorf("%w: repository URL must be a plain https URL", ErrInvalidRepository)
+	}
+	if parsed.Port() != "" && parsed.Port() != "443" {
+		return fmt.Errorf("%w: repository URL must use the default port", ErrInvalidRepository)
+	}
+	if strings.Trim(parsed.Path, "/") == "" || strings.ContainsAny(repoURL, "' \n") {
+		return fmt.Errorf("%w: malformed repository URL", ErrInvalidRepository)
+	}
+	if !rs.hostAllowed(parsed.Hostname()) {
+		return fmt.Errorf("%w: host %s is not allowed", ErrInvalidRepository, parsed.Hostname())
+	}
+	if !commitRegex.MatchString(commit) {
+		return fmt.Errorf("%w: commit must be a full 40-character SHA", ErrInvalidRepository)
+	}
+	return nil
+}
+
+func (rs *RepositoryIntakeService) hostAllowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range rs.config.AllowedHosts {
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// EntryFile resolves the file compiled as the submission's source. It must
+// sit at the project root and carry the language's extension; empty picks
+// main with that extension.
+func (rs *RepositoryIntakeService) EntryFile(language, entryFile string) (string, error) {
+	ext := filepath.Ext(rs.sandbox.SourceFileName(language))
+	if entryFile == "" {
+		return "main" + ext, nil
+	}
+	if strings.ContainsAny(entryFile, `/\`) || strings.HasPrefix(entryFile, ".") || filepath.Ext(entryFile) != ext {
+		return "", fmt.Errorf("%w: entry file must be a %s file at the repository root", ErrInvalidRepository, ext)
+	}
+	return entryFile, nil
+}
+
+// Fetch checks out the commit and verifies the checkout fits the file and
+// size limits and contains the entry file.
+func (rs *RepositoryIntakeService) Fetch(ctx context.Context, repoURL, commit, entryFile string) (*RepositoryProject, error) {
+	if err := rs.Validate(repoURL, commit); err != nil {
+		return nil, err
+	}
+
+	archive, err := rs.sandbox.FetchRepository(ctx, repoURL, commit, rs.config.FetchTimeout, rs.config.MaxBytes)
+	if err != nil {
+		if errors.Is(err, sandbox.ErrInvalidArchive) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRepository, err)
+		}
+		return nil, err
+	}
+
+	files, err := sandbox.ReadArchive(archive, rs.config.MaxFiles, rs.config.MaxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRepository, err)
+	}
+
+	project := &RepositoryProject{
+		Archive:   archive,
+		EntryFile: entryFile,
+		FileCount: len(files),
+	}
+	found := false
+	for _, file := range files {
+		project.SizeBytes += int64(len(file.Data))
+		if file.Name == entryFile {
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: entry file %s not found at commit %s", ErrInvalidRepository, entryFile, commit)
+	}
+	return project, nil
+}
@@ -4,19 +4,38 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
+	"time"
 
+	"execution_service/internal/cache"
 	"execution_service/internal/config"
+	"execution_service/internal/database"
 	"execution_service/internal/httpclient"
+	"execution_service/internal/models"
+	"execution_service/internal/validation"
 )
 
+// Sources reported for the effective limits of a problem
+const (
+	LimitSourceOverride       = "override"
+	LimitSourceContentService = "content_service"
+	LimitSourceDefault        = "default"
+)
+
+// ResourceValidationService resolves the limits a submission runs under.
+// Platform-wide defaults and maximums start from the judge config and are
+// replaced by the stored platform limits once saved, which every node
+// reloads periodically.
 type ResourceValidationService struct {
-	config         *config.JudgeConfig
-	contentClient  *httpclient.ContentServiceClient
-	cbService      *CircuitBreakerService
-	maxTimeLimit   int
-	maxMemoryLimit int
-	maxStackSize   int
-	maxOutputSize  int
+	config          *config.JudgeConfig
+	contentClient   *httpclient.ContentServiceClient
+	cache           *cache.ValkeyClient
+	db              *database.DB
+	cbService       *CircuitBreakerService
+	refreshInterval time.Duration
+
+	mu       sync.RWMutex
+	platform models.PlatformLimits
 }
 
 type ResourceLimits struct {
@@ -26,6 +45,25 @@ type ResourceLimits struct {
 	OutputSizeKb  int
 }
 
+type EffectiveLimits struct {
+	ProblemID     int64                 `json:"problem_id"`
+	Source        string                `json:"source"`
+	TimeLimitMs   int                   `json:"time_limit_ms"`
+	MemoryLimitKb int                   `json:"memory_limit_kb"`
+	Override      *models.LimitOverride `json:"override,omitempty"`
+	ContentError  string                `json:"content_error,omitempty"`
+	Budget        *JudgingBudget        `json:"budget,omitempty"`
+}
+
+// JudgingBudget is the worst-case time a submission can hold a worker,
+// assuming every test runs to its time limit.
+type JudgingBudget struct {
+	TestCount   int   `json:"test_count"`
+	EstimatedMs int64 `json:"estimated_ms"`
+	LimitMs     int64 `json:"limit_ms"`
+	Exceeded    bool  `json:"exceeded"`
+}
+
 type ValidationResult struct {
 	IsValid    bool
 	Violations []ResourceViolation
@@ -40,13 +78,97 @@ type ResourceViolation struct {
 func NewResourceValidationService(cfg *config.JudgeConfig, contentClient *httpclient.ContentServiceClient) *ResourceValidationService {
 	cbService := NewCircuitBreakerService()
 	return &ResourceValidationService{
-		config:         cfg,
-		contentClient:  contentClient,
-		cbService:      cbService,
-		maxTimeLimit:   int(cfg.MaxTimeLimit.Milliseconds()),
-		maxMemoryLimit: cfg.MaxMemoryLimit,
-		maxStackSize:   cfg.MaxStackSize,
-		maxOutputSize:  cfg.MaxOutputSize,
+		config:          cfg,
+		contentClient:   contentClient,
+		cbService:       cbService,
+		refreshInterval: time.Minute,
+		platform:        configPlatformLimits(cfg),
+	}
+}
+
+func configPlatformLimits(cfg *config.JudgeConfig) models.PlatformLimits {
+	return models.PlatformLimits{
+		DefaultTimeLimitMs:   int(cfg.DefaultTimeLimit.Milliseconds()),
+		DefaultMemoryLimitKb: cfg.DefaultMemoryLimit,
+		MaxTimeLimitMs:       int(cfg.MaxTimeLimit.Milliseconds()),
+		MaxMemoryLimitKb:     cfg.MaxMemoryLimit,
+		MaxStackSizeKb:       cfg.MaxStackSize,
+		MaxOutputSizeKb:      cfg.MaxOutputSize,
+	}
+}
+
+func (rvs *ResourceValidationService) SetCache(c *cache.ValkeyClient) {
+	rvs.cache = c
+}
+
+// SetDatabase enables stored platform limits.
+func (rvs *ResourceValidationService) SetDatabase(db *database.DB) {
+	rvs.db = db
+}
+
+// PlatformLimits returns a copy of the limits in effect.
+func (rvs *ResourceValidationService) PlatformLimits() *models.PlatformLimits {
+	rvs.mu.RLock()
+	defer rvs.mu.RUnlock()
+	limits := rvs.platform
+	return &limits
+}
+
+// RefreshPlatformLimits reloads the stored platform limits; the judge config
+// applies until some are saved.
+func (rvs *ResourceValidationService) RefreshPlatformLimits(ctx context.Context) error {
+	if rvs.db == nil {
+		return nil
+	}
+
+	stored, err := rvs.db.GetPlatformLimits(ctx)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return nil
+	}
+
+	rvs.mu.Lock()
+	rvs.platform = *stored
+	rvs.mu.Unlock()
+	return nil
+}
+
+// UpdatePlatformLimits validates and stores new platform limits, which apply
+// here at once and on other nodes at their next refresh.
+func (rvs *ResourceValidationService) UpdatePlatformLimits(ctx context.Context, limits *models.PlatformLimits) error {
+	if rvs.db == nil {
+		return fmt.Errorf("platform limits require a database")
+	}
+	if err := validation.ValidatePlatformLimits(limits); err != nil {
+		return err
+	}
+	if err := rvs.db.SavePlatformLimits(ctx, limits); err != nil {
+		return err
+	}
+
+	rvs.mu.Lock()
+	rvs.platform = *limits
+	rvs.mu.Unlock()
+
+	log.Printf("Platform limits updated: default %dms/%dKB, max %dms/%dKB", limits.DefaultTimeLimitMs, limits.DefaultMemoryLimitKb, limits.MaxTimeLimitMs, limits.MaxMemoryLimitKb)
+	return nil
+}
+
+func (rvs *ResourceValidationService) Start(ctx context.Context) {
+	ticker := time.NewTicker(rvs.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := rvs.RefreshPlatformLimits(ctx); err != nil {
+				log.Printf("Failed to refresh platform limits: %v", err)
+			}
+		}
 	}
 }
 
@@ -55,17 +177,13 @@ func (rvs *ResourceValidationService) ValidateAndNormalizeLimits(ctx context.Con
 		IsValid:    true,
 		Violations: []ResourceViolation{},
 	}
+	platform := rvs.PlatformLimits()
 
 	// Get problem-specific limits from content service
 	problemLimits, err := rvs.getProblemLimits(ctx, problemID)
 	if err != nil {
 		log.Printf("Failed to get problem limits for %d: %v, using defaults", problemID, err)
-		problemLimits = &ResourceLimits{
-			TimeLimitMs:   int(rvs.config.DefaultTimeLimit.Milliseconds()),
-			MemoryLimitKb: rvs.config.DefaultMemoryLimit,
-			StackSizeKb:   rvs.config.MaxStackSize,
-			OutputSizeKb:  rvs.config.MaxOutputSize,
-		}
+		problemLimits = rvs.GetDefaultLimits()
 	}
 
 	// Use problem-specific limits if available, otherwise use requested
@@ -85,24 +203,24 @@ func (rvs *ResourceValidationService) ValidateAndNormalizeLimits(ctx context.Con
 	}
 
 	// Validate against maximum allowed limits
-	if finalLimits.TimeLimitMs > rvs.maxTimeLimit {
+	if finalLimits.TimeLimitMs > platform.MaxTimeLimitMs {
 		result.IsValid = false
 		result.Violations = append(result.Violations, ResourceViolation{
 			Type:        "time_limit_exceeded",
-			Description: fmt.Sprintf("Time limit %dms exceeds maximum allowed %dms", finalLimits.TimeLimitMs, rvs.maxTimeLimit),
+			Description: fmt.Sprintf("Time limit %dms exceeds maximum allowed %dms", finalLimits.TimeLimitMs, platform.MaxTimeLimitMs),
 			Severity:    "error",
 		})
-		finalLimits.TimeLimitMs = rvs.maxTimeLimit
+		finalLimits.TimeLimitMs = platform.MaxTimeLimitMs
 	}
 
-	if finalLimits.MemoryLimitKb > rvs.maxMemoryLimit {
+	if finalLimits.MemoryLimitKb > platform.MaxMemoryLimitKb {
 		result.IsValid = false
 		result.Violations = append(result.Violations, ResourceViolation{
 			Type:        "memory_limit_exceeded",
-			Description: fmt.Sprintf("Memory limit %dKB exceeds maximum allowed %dKB", finalLimits.MemoryLimitKb, rvs.maxMemoryLimit),
+			Description: fmt.Sprintf("Memory limit %dKB exceeds maximum allowed %dKB", finalLimits.MemoryLimitKb, platform.MaxMemoryLimitKb),
 			Severity:    "error",
 		})
-		finalLimits.MemoryLimitKb = rvs.maxMemoryLimit
+		finalLimits.MemoryLimitKb = platform.MaxMemoryLimitKb
 	}
 
 	// Validate minimum limits
@@ -137,22 +255,163 @@ func (rvs *ResourceValidationService) ValidateAndNormalizeLimits(ctx context.Con
 }
 
 func (rvs *ResourceValidationService) getProblemLimits(ctx context.Context, problemID int64) (*ResourceLimits, error) {
-	// Try to get problem details from content service
+	// Emergency overrides take precedence over the content service
+	if override := rvs.getLimitOverride(ctx, problemID); override != nil {
+		platform := rvs.PlatformLimits()
+		return &ResourceLimits{
+			TimeLimitMs:   override.TimeLimitMs,
+			MemoryLimitKb: override.MemoryLimitKb,
+			StackSizeKb:   platform.MaxStackSizeKb,
+			OutputSizeKb:  platform.MaxOutputSizeKb,
+		}, nil
+	}
+
+	return rvs.getContentServiceLimits(ctx, problemID)
+}
+
+func (rvs *ResourceValidationService) getContentServiceLimits(ctx context.Context, problemID int64) (*ResourceLimits, error) {
 	problem, err := rvs.contentClient.GetProblem(ctx, problemID)
 	if err != nil {
 		return nil, err
 	}
 
+	platform := rvs.PlatformLimits()
 	limits := &ResourceLimits{
 		TimeLimitMs:   problem.TimeLimit,
 		MemoryLimitKb: problem.MemoryLimit,
-		StackSizeKb:   rvs.maxStackSize,
-		OutputSizeKb:  rvs.maxOutputSize,
+		StackSizeKb:   platform.MaxStackSizeKb,
+		OutputSizeKb:  platform.MaxOutputSizeKb,
 	}
 
 	return limits, nil
 }
 
+func (rvs *ResourceValidationService) getLimitOverride(ctx context.Context, problemID int64) *models.LimitOverride {
+	if rvs.cache == nil {
+		return nil
+	}
+
+	override, err := rvs.cache.GetLimitOverride(ctx, problemID)
+	if err != nil {
+		return nil
+	}
+
+	return override
+}
+
+// GetEffectiveLimits reports the limits that would be applied to a problem,
+// where they come from, and the judging budget they imply.
+func (rvs *ResourceValidationService) GetEffectiveLimits(ctx context.Context, problemID int64) *EffectiveLimits {
+	effective := rvs.resolveEffectiveLimits(ctx, problemID)
+
+	problem, err := rvs.contentClient.GetProblem(ctx, problemID)
+	if err == nil {
+		testTimeLimits := make([]int, len(problem.TestCases))
+		for i, tc := range problem.TestCases {
+			testTimeLimits[i] = tc.TimeLimit
+		}
+		effective.Budget = rvs.EstimateJudgingBudget(testTimeLimits, effective.TimeLimitMs)
+	}
+
+	return effective
+}
+
+// EstimateJudgingBudget sums each test's time limit, falling back to
+// defaultTimeLimitMs, plus the configured per-test overhead.
+func (rvs *ResourceValidationService) EstimateJudgingBudget(testTimeLimitsMs []int, defaultTimeLimitMs int) *JudgingBudget {
+	overhead := rvs.config.TestOverhead.Milliseconds()
+
+	var estimated int64
+	for _, limit := range testTimeLimitsMs {
+		if limit <= 0 {
+			limit = defaultTimeLimitMs
+		}
+		estimated += int64(limit) + overhead
+	}
+
+	budget := &JudgingBudget{
+		TestCount:   len(testTimeLimitsMs),
+		EstimatedMs: estimated,
+		LimitMs:     rvs.config.JudgingBudget.Milliseconds(),
+	}
+	budget.Exceeded = budget.LimitMs > 0 && budget.EstimatedMs > budget.LimitMs
+
+	return budget
+}
+
+func (rvs *ResourceValidationService) resolveEffectiveLimits(ctx context.Context, problemID int64) *EffectiveLimits {
+	if override := rvs.getLimitOverride(ctx, problemID); override != nil {
+		return &EffectiveLimits{
+			ProblemID:     problemID,
+			Source:        LimitSourceOverride,
+			TimeLimitMs:   override.TimeLimitMs,
+			MemoryLimitKb: override.MemoryLimitKb,
+			Override:      override,
+		}
+	}
+
+	defaults := rvs.GetDefaultLimits()
+	limits, err := rvs.getContentServiceLimits(ctx, problemID)
+	if err != nil {
+		return &EffectiveLimits{
+			ProblemID:     problemID,
+			Source:        LimitSourceDefault,
+			TimeLimitMs:   defaults.TimeLimitMs,
+			MemoryLimitKb: defaults.MemoryLimitKb,
+			ContentError:  err.Error(),
+		}
+	}
+
+	effective := &EffectiveLimits{
+		ProblemID:     problemID,
+		Source:        LimitSourceContentService,
+		TimeLimitMs:   limits.TimeLimitMs,
+		MemoryLimitKb: limits.MemoryLimitKb,
+	}
+	if effective.TimeLimitMs <= 0 {
+		effective.TimeLimitMs = defaults.TimeLimitMs
+	}
+	if effective.MemoryLimitKb <= 0 {
+		effective.MemoryLimitKb = defaults.MemoryLimitKb
+	}
+
+	return effective
+}
+
+func (rvs *ResourceValidationService) SetLimitOverride(ctx context.Context, override *models.LimitOverride) error {
+	if rvs.cache == nil {
+		return fmt.Errorf("limit overrides require a cache")
+	}
+
+	platform := rvs.PlatformLimits()
+	if override.TimeLimitMs < 100 || override.TimeLimitMs > platform.MaxTimeLimitMs {
+		return fmt.Errorf("time limit must be between 100ms and %dms", platform.MaxTimeLimitMs)
+	}
+	if override.MemoryLimitKb < 1024 || override.MemoryLimitKb > platform.MaxMemoryLimitKb {
+		return fmt.Errorf("memory limit must be between 1024KB and %dKB", platform.MaxMemoryLimitKb)
+	}
+
+	if err := rvs.cache.SetLimitOverride(ctx, override); err != nil {
+		return fmt.Errorf("failed to store limit override: %w", err)
+	}
+
+	log.Printf("Limit override set for problem %d: %dms/%dKB until %v", override.ProblemID, override.TimeLimitMs, override.MemoryLimitKb, override.ExpiresAt)
+	return nil
+}
+
+func (rvs *ResourceValidationService) ClearLimitOverride(ctx context.Context, problemID int64) error {
+	if rvs.cache == nil {
+		return fmt.Errorf("limit overrides require a cache")
+	}
+
+	if err := rvs.cache.DeleteLimitOverride(ctx, problemID); err != nil {
+		return fmt.Errorf("failed to clear limit override: %w", err)
+	}
+
+	log.Printf("Limit override cleared for problem %d", problemID)
+	return nil
+}
+
 func (rvs *ResourceValidationService) LogResourceUsage(submissionID int64, limits *ResourceLimits, actualTime, actualMemory int) {
 	// Check for resource limit violations
 	violations := []ResourceViolation{}
@@ -187,19 +446,21 @@ func (rvs *ResourceValidationService) LogResourceUsage(submissionID int64, limit
 }
 
 func (rvs *ResourceValidationService) GetMaxLimits() *ResourceLimits {
+	platform := rvs.PlatformLimits()
 	return &ResourceLimits{
-		TimeLimitMs:   rvs.maxTimeLimit,
-		MemoryLimitKb: rvs.maxMemoryLimit,
-		StackSizeKb:   rvs.maxStackSize,
-		OutputSizeKb:  rvs.maxOutputSize,
+		TimeLimitMs:   platform.MaxTimeLimitMs,
+		MemoryLimitKb: platform.MaxMemoryLimitKb,
+		StackSizeKb:   platform.MaxStackSizeKb,
+		OutputSizeKb:  platform.MaxOutputSizeKb,
 	}
 }
 
 func (rvs *ResourceValidationService) GetDefaultLimits() *ResourceLimits {
+	platform := rvs.PlatformLimits()
 	return &ResourceLimits{
-		TimeLimitMs:   int(rvs.config.DefaultTimeLimit.Milliseconds()),
-		MemoryLimitKb: rvs.config.DefaultMemoryLimit,
-		StackSizeKb:   rvs.maxStackSize,
-		OutputSizeKb:  rvs.maxOutputSize,
+		TimeLimitMs:   platform.DefaultTimeLimitMs,
+		MemoryLimitKb: platform.DefaultMemoryLimitKb,
+		StackSizeKb:   platform.MaxStackSizeKb,
+		OutputSizeKb:  platform.MaxOutputSizeKb,
 	}
 }
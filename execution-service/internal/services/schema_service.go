@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"sync"
+	"time"
+
+	"execution_service/internal/database"
+
+	"github.com/pressly/goose/v3"
+)
+
+type SchemaStatus struct {
+	CurrentVersion int64     `json:"current_version"`
+	TargetVersion  int64     `json:"target_version"`
+	Ready          bool      `json:"ready"`
+	Error          string    `json:"error,omitempty"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// SchemaService compares the applied goose version against the embedded
+// migrations and keeps the service unready until the schema is current.
+type SchemaService struct {
+	provider      *goose.Provider
+	autoMigrate   bool
+	checkInterval time.Duration
+
+	mu     sync.RWMutex
+	status SchemaStatus
+}
+
+func NewSchemaService(db *database.DB, migrations fs.FS, autoMigrate bool) (*SchemaService, error) {
+	provider, err := goose.NewProvider(goose.DialectPostgres, db.SQL(), migrations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration provider: %w", err)
+	}
+
+	return &SchemaService{
+		provider:      provider,
+		autoMigrate:   autoMigrate,
+		checkInterval: 10 * time.Second,
+	}, nil
+}
+
+// Check refreshes the schema status, applying pending migrations first when
+// auto-migration is enabled.
+func (s *SchemaService) Check(ctx context.Context) SchemaStatus {
+	status := SchemaStatus{CheckedAt: time.Now()}
+
+	current, target, err := s.provider.GetVersions(ctx)
+	if err == nil && current < target && s.autoMigrate {
+		log.Printf("Applying migrations from version %d to %d", current, target)
+		if _, err = s.provider.Up(ctx); err == nil {
+			current, target, err = s.provider.GetVersions(ctx)
+		}
+	}
+
+	if err != nil {
+		status.Error = err.Error()
+	} else {
+		status.CurrentVersion = current
+		status.TargetVersion = target
+		status.Ready = current >= target
+		if !status.Ready {
+			status.Error = fmt.Sprintf("schema version %d is behind embedded migrations (%d)", current, target)
+		}
+	}
+
+	s.mu.Lock()
+	s.status = status
+	s.mu.Unlock()
+
+	return status
+}
+
+func (s *SchemaService) Status() SchemaStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+func (s *SchemaService) IsReady() bool {
+	return s.Status().Ready
+}
+
+// Start re-checks the schema until it becomes current.
+func (s *SchemaService) Start(ctx context.Context) {
+	if s.IsReady() {
+		return
+	}
+
+	ticker := time.NewTicker(s.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			status := s.Check(ctx)
+			if status.Ready {
+				log.Printf("Database schema is current at version %d", status.CurrentVersion)
+				return
+			}
+			log.Printf("Waiting for database migrations: %s", status.Error)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"execution_service/internal/config"
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+)
+
+// ScoringService keeps each user's per-problem score, combined from their
+// judged submissions by the problem's aggregation.
+type ScoringService struct {
+	db     *database.DB
+	config *config.ScoringConfig
+}
+
+func NewScoringService(db *database.DB, cfg *config.ScoringConfig) *ScoringService {
+	return &ScoringService{
+		db:     db,
+		config: cfg,
+	}
+}
+
+// Aggregation returns the problem's scoring, falling back to the default.
+func (ss *ScoringService) Aggregation(ctx context.Context, problemID int64) (*models.ProblemScoring, error) {
+	scoring, err := ss.db.GetProblemScoring(ctx, problemID)
+	if err != nil {
+		return nil, err
+	}
+	if scoring == nil {
+		scoring = &models.ProblemScoring{ProblemID: problemID, Aggregation: ss.config.DefaultAggregation}
+	}
+	return scoring, nil
+}
+
+// SetAggregation stores the problem's aggregation and recomputes every
+// user's score on it, returning how many were recomputed.
+func (ss *ScoringService) SetAggregation(ctx context.Context, scoring *models.ProblemScoring) (int64, error) {
+	if err := ss.db.SetProblemScoring(ctx, scoring); err != nil {
+		return 0, err
+	}
+
+	updated, err := ss.db.RecomputeProblemScores(ctx, scoring.ProblemID, scoring.Aggregation)
+	if err != nil {
+		return 0, fmt.Errorf("failed to apply aggregation: %w", err)
+	}
+	return updated, nil
+}
+
+// Record recomputes the user's score after one of their submissions to the
+// problem is judged. Failures are only logged: the verdict is already stored
+// and the next judged submission recomputes the score.
+func (ss *ScoringService) Record(ctx context.Context, userID, problemID int64) {
+	scoring, err := ss.Aggregation(ctx, problemID)
+	if err == nil {
+		err = ss.db.RecomputeUserProblemScore(ctx, userID, problemID, scoring.Aggregation)
+	}
+	if err != nil {
+		log.Printf("Failed to record score for user %d on problem %d: %v", userID, problemID, err)
+	}
+}
+
+// UserScore returns nil when the user has no judged submissions to the
+// problem.
+func (ss *ScoringService) UserScore(ctx context.Context, userID, problemID int64) (*models.UserProblemScore, error) {
+	return ss.db.GetUserProblemScore(ctx, userID, problemID)
+}
+
+func (ss *ScoringService) ProblemScores(ctx context.Context, problemID int64, limit, offset int) ([]models.UserProblemScore, error) {
+	return ss.db.GetProblemScores(ctx, problemID, limit, offset)
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+)
+
+type cachedShadowConfig struct {
+	config    *models.ShadowConfig
+	expiresAt time.Time
+}
+
+// ShadowJudgingService manages blue/green judging: while a problem has an
+// active shadow config, workers judge its submissions a second time with the
+// candidate checker or limits and record how the verdicts compare. Only the
+// live result is ever stored on the submission.
+type ShadowJudgingService struct {
+	db       *database.DB
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[int64]cachedShadowConfig
+}
+
+func NewShadowJudgingService(db *database.DB) *ShadowJudgingService {
+	return &ShadowJudgingService{
+		db:       db,
+		cacheTTL: 30 * time.Second,
+		cache:    make(map[int64]cachedShadowConfig),
+	}
+}
+
+func (s *ShadowJudgingService) Start(ctx context.Context, config *models.ShadowConfig) error {
+	if err := s.db.StartShadowConfig(ctx, config); err != nil {
+		return err
+	}
+	s.forget(config.ProblemID)
+	return nil
+}
+
+func (s *ShadowJudgingService) Stop(ctx context.Context, problemID int64) (bool, error) {
+	stopped, err := s.db.StopShadowConfig(ctx, problemID)
+	s.forget(problemID)
+	return stopped, err
+}
+
+// Active returns the problem's active shadow config, or nil when shadow
+// judging is off for it.
+func (s *ShadowJudgingService) Active(ctx context.Context, problemID int64) (*models.ShadowConfig, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[problemID]
+	s.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.config, nil
+	}
+
+	config, err := s.db.GetShadowConfig(ctx, problemID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[problemID] = cachedShadowConfig{config: config, expiresAt: time.Now().Add(s.cacheTTL)}
+	s.mu.Unlock()
+	return config, nil
+}
+
+func (s *ShadowJudgingService) Record(ctx context.Context, result *models.ShadowResult) error {
+	return s.db.SaveShadowResult(ctx, result)
+}
+
+// Report summarizes the problem's most recent shadow config, active or not.
+// It returns nil when the problem was never shadow judged.
+func (s *ShadowJudgingService) Report(ctx context.Context, problemID int64, limit int) (*models.ShadowReport, error) {
+	config, err := s.db.GetShadowConfig(ctx, problemID, false)
+	if err != nil || config == nil {
+		return nil, err
+	}
+	return s.db.GetShadowReport(ctx, config, limit)
+}
+
+func (s *ShadowJudgingService) forget(problemID int64) {
+	s.mu.Lock()
+	delete(s.cache, problemID)
+	s.mu.Unlock()
+}
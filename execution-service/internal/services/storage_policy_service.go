@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"execution_service/internal/config"
+	"execution_service/internal/models"
+	"execution_service/internal/queue"
+	"execution_service/internal/storage"
+)
+
+// StoragePolicyService keeps the configured encryption, contest retention and
+// code transition policies applied to the bucket and alerts when it cannot.
+type StoragePolicyService struct {
+	storage       *storage.MinIOClient
+	queue         *queue.RabbitMQClient
+	policies      *config.StoragePolicyConfig
+	retainedSince time.Time
+	status        models.BucketPolicyStatus
+	mutex         sync.RWMutex
+}
+
+func NewStoragePolicyService(storage *storage.MinIOClient, queue *queue.RabbitMQClient, policies *config.StoragePolicyConfig) *StoragePolicyService {
+	return &StoragePolicyService{
+		storage:  storage,
+		queue:    queue,
+		policies: policies,
+		status:   models.BucketPolicyStatus{Bucket: storage.Bucket},
+	}
+}
+
+func (ps *StoragePolicyService) Start(ctx context.Context) {
+	ticker := time.NewTicker(ps.policies.CheckInterval)
+	defer ticker.Stop()
+
+	log.Printf("Starting storage policy checks for bucket %s every %v", ps.storage.Bucket, ps.policies.CheckInterval)
+	ps.apply(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ps.apply(ctx)
+		}
+	}
+}
+
+// Status returns the bucket's status as of the last check.
+func (ps *StoragePolicyService) Status() []models.BucketPolicyStatus {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+	status := ps.status
+	status.Errors = append([]string(nil), ps.status.Errors...)
+	return []models.BucketPolicyStatus{status}
+}
+
+func (ps *StoragePolicyService) apply(ctx context.Context) {
+	status := models.BucketPolicyStatus{Bucket: ps.storage.Bucket, CheckedAt: time.Now()}
+	fail := func(err error) {
+		status.Errors = append(status.Errors, err.Error())
+	}
+
+	if ps.policies.Encryption != "" {
+		if err := ps.storage.EnsureEncryption(ctx, ps.policies); err != nil {
+			fail(err)
+		}
+	}
+	if err := ps.storage.EnsureTransitions(ctx, ps.policies.TransitionDays, ps.policies.TransitionStorageClass); err != nil {
+		fail(err)
+	}
+
+	var err error
+	if status.Encryption, err = ps.storage.Encryption(ctx); err != nil {
+		fail(err)
+	}
+	if status.TransitionRules, err = ps.storage.TransitionRules(ctx); err != nil {
+		fail(err)
+	}
+
+	ps.mutex.RLock()
+	status.RetainedObjects = ps.status.RetainedObjects
+	ps.mutex.RUnlock()
+	if status.ObjectLock, err = ps.storage.ObjectLockEnabled(ctx); err != nil {
+		fail(err)
+	} else if ps.policies.ContestRetention > 0 {
+		if !status.ObjectLock {
+			fail(fmt.Errorf("bucket %s has no object locking, contest retention not applied", ps.storage.Bucket))
+		} else {
+			retained, err := ps.storage.RetainContestObjects(ctx, ps.retainedSince, ps.policies.ContestRetention, ps.policies.RetentionMode)
+			status.RetainedObjects += retained
+			if err != nil {
+				fail(err)
+			} else {
+				ps.retainedSince = status.CheckedAt
+			}
+		}
+	}
+
+	ps.mutex.Lock()
+	ps.status = status
+	ps.mutex.Unlock()
+
+	if len(status.Errors) > 0 {
+		ps.alert(ctx, &status)
+	}
+}
+
+func (ps *StoragePolicyService) alert(ctx context.Context, status *models.BucketPolicyStatus) {
+	log.Printf("ALERT: Storage policies for bucket %s failed: %s", status.Bucket, strings.Join(status.Errors, "; "))
+
+	event := &models.StoragePolicyFailedEvent{
+		Bucket: status.Bucket,
+		Errors: status.Errors,
+	}
+	if err := ps.queue.PublishEvent(ctx, "StoragePolicyFailed", event); err != nil {
+		log.Printf("Failed to publish storage policy alert: %v", err)
+	}
+}
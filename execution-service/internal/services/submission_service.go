@@ -0,0 +1,409 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+	"execution_service/internal/queue"
+	"execution_service/internal/storage"
+	"execution_service/internal/validation"
+)
+
+// ErrIdempotencyKeyReused means an idempotency key was sent again with a
+// different request.
+var ErrIdempotencyKeyReused = errors.New("idempotency key was used for a different request")
+
+var errInvalidJudgeRequest = errors.New("invalid judge request")
+
+const (
+	// outboxGrace is how long the request that recorded a submission has to
+	// publish it before the outbox relay takes over
+	outboxGrace        = 30 * time.Second
+	outboxPollInterval = 5 * time.Second
+	outboxBatch        = 100
+	outboxLease        = time.Minute
+	outboxMaxBackoff   = 5 * time.Minute
+)
+
+// SubmissionService stores and queues submissions for the REST and gRPC
+// APIs. Callers validate input and authorize the caller first.
+type SubmissionService struct {
+	db      *database.DB
+	storage *storage.MinIOClient
+	queue   *queue.RabbitMQClient
+	limits  *ResourceValidationService
+	spool   *CodeSpoolService
+}
+
+func NewSubmissionService(db *database.DB, s *storage.MinIOClient, q *queue.RabbitMQClient) *SubmissionService {
+	return &SubmissionService{
+		db:      db,
+		storage: s,
+		queue:   q,
+	}
+}
+
+func (ss *SubmissionService) SetResourceValidator(rvs *ResourceValidationService) {
+	ss.limits = rvs
+}
+
+// SetSpool spools code to local disk when MinIO is unavailable. Spooled
+// submissions are recorded at once and queued when their code is uploaded.
+func (ss *SubmissionService) SetSpool(spool *CodeSpoolService) {
+	ss.spool = spool
+	spool.queue = ss.queueSpooled
+}
+
+// PlatformLimits returns the platform-wide default and maximum limits.
+func (ss *SubmissionService) PlatformLimits() *models.PlatformLimits {
+	if ss.limits == nil {
+		return validation.DefaultPlatformLimits()
+	}
+	return ss.limits.PlatformLimits()
+}
+
+// Create uploads the code, records the pending submission and queues it.
+// Contest submissions are judged ahead of practice ones.
+func (ss *SubmissionService) Create(ctx context.Context, submission *models.Submission, code []byte, timeLimitMs, memoryLimitKb int) error {
+	submission.Verdict = models.VerdictPending
+
+	spooled, err := ss.storeCode(ctx, submission, code)
+	if err != nil {
+		return err
+	}
+	if spooled == nil {
+		return ss.record(ctx, submission, timeLimitMs, memoryLimitKb)
+	}
+
+	if err := ss.db.CreateSubmission(ctx, submission); err != nil {
+		ss.spool.discard(spooled)
+		return err
+	}
+	return ss.commitSpooled(ctx, submission, spooled, timeLimitMs, memoryLimitKb)
+}
+
+// storeCode uploads the code and sets the submission's code URL. When MinIO
+// fails and spooling is on, the code is spooled instead and its entry
+// returned; the caller records the submission and then commits the entry.
+func (ss *SubmissionService) storeCode(ctx context.Context, submission *models.Submission, code []byte) (*spoolEntry, error) {
+	if ss.spool == nil {
+		codeURL, err := ss.storage.UploadCode(ctx, submission.ID, submission.Language, code)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload code: %w", err)
+		}
+		submission.CodeURL = codeURL
+		return nil, nil
+	}
+
+	codeURL, err := ss.spool.uploadCode(ctx, submission.ID, submission.Language, code)
+	if err == nil {
+		submission.CodeURL = codeURL
+		return nil, nil
+	}
+	entry, spoolErr := ss.spool.spool(submission.Language, code, err)
+	if spoolErr != nil {
+		return nil, fmt.Errorf("failed to upload code: %w (spooling failed: %v)", err, spoolErr)
+	}
+	submission.CodeURL = entry.url()
+	return entry, nil
+}
+
+// commitSpooled hands a recorded submission's code to the spool uploader,
+// which queues the submission once the code is in MinIO. When the entry
+// cannot be committed nothing would ever queue the submission, so it is
+// deleted along with its code.
+func (ss *SubmissionService) commitSpooled(ctx context.Context, submission *models.Submission, entry *spoolEntry, timeLimitMs, memoryLimitKb int) error {
+	if err := ss.spool.commit(entry, submission.ID, timeLimitMs, memoryLimitKb); err != nil {
+		ss.spool.discard(entry)
+		if deleteErr := ss.db.DeleteSubmission(ctx, submission.ID); deleteErr != nil {
+			log.Printf("Failed to delete unqueued submission %d: %v", submission.ID, deleteErr)
+		}
+		return err
+	}
+
+	ss.db.CreateExecutionLog(ctx, &models.ExecutionLog{
+		SubmissionID: submission.ID,
+		Level:        "WARN",
+		Message:      fmt.Sprintf("Submission created for user %d, problem %d, language %s; code spooled until object storage recovers", submission.UserID, submission.ProblemID, submission.Language),
+	})
+	return nil
+}
+
+// queueSpooled points a spooled submission at its uploaded code and queues it.
+func (ss *SubmissionService) queueSpooled(ctx context.Context, entry *spoolEntry, codeURL string) error {
+	if err := ss.db.UpdateSubmissionCodeURL(ctx, entry.SubmissionID, codeURL); err != nil {
+		return err
+	}
+	submission, err := ss.db.GetSubmission(ctx, entry.SubmissionID)
+	if err != nil {
+		return err
+	}
+	outbox := outboxEntry(submission, entry.TimeLimitMs, entry.MemoryLimitKb)
+	if err := ss.db.CreateOutboxEntry(ctx, outbox); err != nil {
+		return err
+	}
+	return ss.queueCreated(ctx, submission, outbox)
+}
+
+// CreateIdempotent is Create for requests carrying a client idempotency key.
+// A retry with the key gets the original submission back, with replayed set,
+// instead of queuing the code again.
+func (ss *SubmissionService) CreateIdempotent(ctx context.Context, submission *models.Submission, code []byte, timeLimitMs, memoryLimitKb int, key string) (bool, error) {
+	idempotencyKey := &models.IdempotencyKey{
+		UserID:      submission.UserID,
+		Key:         key,
+		RequestHash: requestHash(submission, code, timeLimitMs, memoryLimitKb),
+	}
+	if replayed, err := ss.replay(ctx, submission, idempotencyKey); replayed || err != nil {
+		return replayed, err
+	}
+
+	submission.Verdict = models.VerdictPending
+	spooled, err := ss.storeCode(ctx, submission, code)
+	if err != nil {
+		return false, err
+	}
+
+	var outbox *models.OutboxEntry
+	if spooled == nil {
+		outbox = outboxEntry(submission, timeLimitMs, memoryLimitKb)
+	}
+	err = ss.db.CreateSubmissionWithIdempotencyKey(ctx, submission, idempotencyKey, outbox)
+	if err != nil && spooled != nil {
+		ss.spool.discard(spooled)
+	}
+	if errors.Is(err, database.ErrIdempotencyKeyExists) {
+		// A concurrent request with the key won
+		return ss.replay(ctx, submission, idempotencyKey)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if spooled != nil {
+		err = ss.commitSpooled(ctx, submission, spooled, timeLimitMs, memoryLimitKb)
+	} else {
+		err = ss.queueCreated(ctx, submission, outbox)
+	}
+	if err != nil {
+		// Free the key so a retry can submit again rather than get back a
+		// submission that was never queued
+		if deleteErr := ss.db.DeleteIdempotencyKey(ctx, idempotencyKey.UserID, key); deleteErr != nil {
+			log.Printf("Failed to release idempotency key for submission %d: %v", submission.ID, deleteErr)
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// replay loads the submission recorded under the key into submission and
+// reports whether there was one.
+func (ss *SubmissionService) replay(ctx context.Context, submission *models.Submission, key *models.IdempotencyKey) (bool, error) {
+	existing, err := ss.db.GetIdempotencyKey(ctx, key.UserID, key.Key)
+	if err != nil || existing == nil {
+		return false, err
+	}
+	if existing.RequestHash != key.RequestHash {
+		return false, ErrIdempotencyKeyReused
+	}
+
+	original, err := ss.db.GetSubmission(ctx, existing.SubmissionID)
+	if err != nil {
+		return false, err
+	}
+	*submission = *original
+	return true, nil
+}
+
+// requestHash fingerprints what a submission request asks for, so a retry
+// matches and a different request under the same key does not.
+func requestHash(submission *models.Submission, code []byte, timeLimitMs, memoryLimitKb int) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "problem=%d\nlanguage=%s\ntime=%d\nmemory=%d\n", submission.ProblemID, submission.Language, timeLimitMs, memoryLimitKb)
+	if submission.ContestID != nil {
+		fmt.Fprintf(hash, "contest=%d\n", *submission.ContestID)
+	}
+	if submission.TeamID != nil {
+		fmt.Fprintf(hash, "team=%d\n", *submission.TeamID)
+	}
+	fmt.Fprintf(hash, "trusted=%t\n", submission.Trusted)
+	hash.Write(code)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// CreateProject stores a project archive fetched for a repository
+// submission, then records and queues it like Create.
+func (ss *SubmissionService) CreateProject(ctx context.Context, submission *models.Submission, archive []byte, timeLimitMs, memoryLimitKb int) error {
+	submission.Verdict = models.VerdictPending
+
+	codeURL, err := ss.storage.UploadProject(ctx, archive)
+	if err != nil {
+		return err
+	}
+	submission.CodeURL = codeURL
+
+	return ss.record(ctx, submission, timeLimitMs, memoryLimitKb)
+}
+
+func (ss *SubmissionService) record(ctx context.Context, submission *models.Submission, timeLimitMs, memoryLimitKb int) error {
+	outbox := outboxEntry(submission, timeLimitMs, memoryLimitKb)
+	if err := ss.db.CreateSubmissionWithOutbox(ctx, submission, outbox); err != nil {
+		return err
+	}
+	return ss.queueCreated(ctx, submission, outbox)
+}
+
+// outboxEntry queues a new submission. Contest submissions are judged ahead
+// of practice ones.
+func outboxEntry(submission *models.Submission, timeLimitMs, memoryLimitKb int) *models.OutboxEntry {
+	priority := 0
+	if submission.ContestID != nil {
+		priority = 5
+	}
+	return &models.OutboxEntry{
+		SubmissionID:  submission.ID,
+		TimeLimitMs:   timeLimitMs,
+		MemoryLimitKb: memoryLimitKb,
+		Priority:      priority,
+		NextAttemptAt: time.Now().Add(outboxGrace),
+	}
+}
+
+// queueCreated publishes a recorded submission. When the broker cannot take
+// it, the outbox relay publishes it later, so only a request that can never
+// be judged is an error.
+func (ss *SubmissionService) queueCreated(ctx context.Context, submission *models.Submission, outbox *models.OutboxEntry) error {
+	err := ss.publishOutbox(ctx, submission, outbox)
+	if errors.Is(err, errInvalidJudgeRequest) {
+		return err
+	}
+	if err != nil {
+		log.Printf("Submission %d left to the outbox relay: %v", submission.ID, err)
+	}
+
+	ss.db.CreateExecutionLog(ctx, &models.ExecutionLog{
+		SubmissionID: submission.ID,
+		Level:        "INFO",
+		Message:      fmt.Sprintf("Submission created for user %d, problem %d, language %s", submission.UserID, submission.ProblemID, submission.Language),
+	})
+
+	return nil
+}
+
+// publishOutbox queues the submission and clears its outbox entry. An
+// invalid request would fail on every retry, so its entry is cleared too.
+func (ss *SubmissionService) publishOutbox(ctx context.Context, submission *models.Submission, outbox *models.OutboxEntry) error {
+	err := ss.enqueue(ctx, submission, outbox.TimeLimitMs, outbox.MemoryLimitKb, outbox.Priority)
+	if err != nil && !errors.Is(err, errInvalidJudgeRequest) {
+		return err
+	}
+	if deleteErr := ss.db.DeleteOutboxEntry(ctx, outbox.ID); deleteErr != nil {
+		log.Printf("Failed to delete outbox entry for submission %d: %v", submission.ID, deleteErr)
+	}
+	return err
+}
+
+// StartOutboxRelay publishes recorded submissions that never reached the
+// broker until ctx is cancelled. Entries are leased, so several nodes can
+// run the relay.
+func (ss *SubmissionService) StartOutboxRelay(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ss.relayOutbox(ctx)
+		}
+	}
+}
+
+func (ss *SubmissionService) relayOutbox(ctx context.Context) {
+	entries, err := ss.db.ClaimOutboxEntries(ctx, outboxBatch, outboxLease)
+	if err != nil {
+		log.Printf("Failed to claim outbox entries: %v", err)
+		return
+	}
+
+	for i := range entries {
+		outbox := &entries[i]
+		submission, err := ss.db.GetSubmission(ctx, outbox.SubmissionID)
+		if err == nil {
+			err = ss.publishOutbox(ctx, submission, outbox)
+		}
+
+		switch {
+		case err == nil:
+			log.Printf("Outbox relay queued submission %d", outbox.SubmissionID)
+		case errors.Is(err, errInvalidJudgeRequest):
+			log.Printf("ALERT: dropping outbox entry for submission %d: %v", outbox.SubmissionID, err)
+		default:
+			delay := min(time.Duration(1<<min(outbox.Attempts, 10))*time.Second, outboxMaxBackoff)
+			if retryErr := ss.db.RetryOutboxEntry(ctx, outbox.ID, err.Error(), delay); retryErr != nil {
+				log.Printf("Failed to reschedule outbox entry for submission %d: %v", outbox.SubmissionID, retryErr)
+			}
+			// The broker is likely down; the rest retry once their lease ends
+			log.Printf("Outbox relay failed to queue submission %d: %v", outbox.SubmissionID, err)
+			return
+		}
+	}
+}
+
+// Rejudge queues the submission again. Single rejudges use contest
+// priority; bulk jobs use practice priority so live judging goes first.
+func (ss *SubmissionService) Rejudge(ctx context.Context, submission *models.Submission, priority int) error {
+	timeLimitMs, memoryLimitKb := ss.problemLimits(ctx, submission.ProblemID)
+	return ss.enqueue(ctx, submission, timeLimitMs, memoryLimitKb, priority)
+}
+
+// problemLimits returns the limits the problem is judged under now: an
+// emergency override, else the content service limits, else the current
+// platform defaults.
+func (ss *SubmissionService) problemLimits(ctx context.Context, problemID int64) (int, int) {
+	if ss.limits == nil {
+		limits := validation.DefaultPlatformLimits()
+		return limits.DefaultTimeLimitMs, limits.DefaultMemoryLimitKb
+	}
+	effective := ss.limits.resolveEffectiveLimits(ctx, problemID)
+	return effective.TimeLimitMs, effective.MemoryLimitKb
+}
+
+func (ss *SubmissionService) enqueue(ctx context.Context, submission *models.Submission, timeLimitMs, memoryLimitKb, priority int) error {
+	request := &models.JudgeRequest{
+		SubmissionID:  submission.ID,
+		UserID:        submission.UserID,
+		TeamID:        submission.TeamID,
+		ContestID:     submission.ContestID,
+		ProblemID:     submission.ProblemID,
+		Language:      submission.Language,
+		CodeURL:       submission.CodeURL,
+		TimeLimitMs:   timeLimitMs,
+		MemoryLimitKb: memoryLimitKb,
+		Priority:      priority,
+		Trusted:       submission.Trusted,
+	}
+	if !submission.Metadata.IsEmpty() {
+		request.Metadata = &submission.Metadata
+	}
+	if submission.EntryFile != nil {
+		request.EntryFile = *submission.EntryFile
+	}
+
+	if err := validation.ValidateJudgeRequest(request); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidJudgeRequest, err)
+	}
+
+	if err := ss.queue.PublishSubmission(ctx, request); err != nil {
+		return fmt.Errorf("failed to queue submission: %w", err)
+	}
+	return nil
+}
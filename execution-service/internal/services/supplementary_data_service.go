@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"execution_service/internal/config"
+	"execution_service/internal/models"
+	"execution_service/internal/storage"
+)
+
+// ErrInvalidSupplementaryData means a problem declares data files that can
+// never be served: bad names, oversized sets or checksums that do not match
+// the stored objects. Retrying cannot help.
+var ErrInvalidSupplementaryData = errors.New("invalid supplementary data")
+
+var (
+	dataFileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+	sha256Pattern       = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// SupplementaryDataService keeps problem data files on local disk. Each
+// distinct file set gets its own directory, keyed by names and checksums, so
+// an updated file never changes what a running submission sees.
+type SupplementaryDataService struct {
+	storage  *storage.MinIOClient
+	dir      string
+	maxBytes int64
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func NewSupplementaryDataService(s *storage.MinIOClient, cfg *config.IsolateConfig) *SupplementaryDataService {
+	return &SupplementaryDataService{
+		storage:  s,
+		dir:      cfg.DataDir,
+		maxBytes: cfg.DataMaxBytes,
+		locks:    make(map[string]*sync.Mutex),
+	}
+}
+
+// Prepare makes the file set available locally and returns the directory to
+// mount, or "" when there are no files.
+func (sd *SupplementaryDataService) Prepare(ctx context.Context, files []models.SupplementaryFile) (string, error) {
+	if len(files) == 0 {
+		return "", nil
+	}
+	if err := sd.validate(files); err != nil {
+		return "", err
+	}
+
+	key := fileSetKey(files)
+	dir := filepath.Join(sd.dir, key)
+
+	lock := sd.lockFor(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create data dir: %w", err)
+	}
+
+	now := time.Now()
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name)
+
+		// The disk watcher may evict cached files, so check each one
+		if info, err := os.Stat(path); err == nil && info.Size() == file.SizeBytes {
+			// Keep hot files young so eviction takes the stale ones first
+			os.Chtimes(path, now, now)
+			continue
+		}
+
+		if err := sd.fetch(ctx, file, path); err != nil {
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
+
+func (sd *SupplementaryDataService) fetch(ctx context.Context, file models.SupplementaryFile, path string) error {
+	tmp := path + ".tmp"
+	defer os.Remove(tmp)
+
+	checksum, size, err := sd.storage.DownloadToFile(ctx, file.URL, tmp, file.SizeBytes)
+	if err != nil {
+		return fmt.Errorf("failed to download data file %s: %w", file.Name, err)
+	}
+	if size != file.SizeBytes {
+		return fmt.Errorf("%w: %s is not %d bytes", ErrInvalidSupplementaryData, file.Name, file.SizeBytes)
+	}
+	if checksum != file.SHA256 {
+		return fmt.Errorf("%w: checksum mismatch for %s", ErrInvalidSupplementaryData, file.Name)
+	}
+
+	if err := os.Chmod(tmp, 0444); err != nil {
+		return fmt.Errorf("failed to publish data file %s: %w", file.Name, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to publish data file %s: %w", file.Name, err)
+	}
+	return nil
+}
+
+func (sd *SupplementaryDataService) validate(files []models.SupplementaryFile) error {
+	var total int64
+	seen := make(map[string]bool)
+	for _, file := range files {
+		if !dataFileNamePattern.MatchString(file.Name) || seen[file.Name] {
+			return fmt.Errorf("%w: bad or duplicate file name %q", ErrInvalidSupplementaryData, file.Name)
+		}
+		seen[file.Name] = true
+
+		if !sha256Pattern.MatchString(file.SHA256) {
+			return fmt.Errorf("%w: %s has no valid sha256", ErrInvalidSupplementaryData, file.Name)
+		}
+		if file.SizeBytes <= 0 {
+			return fmt.Errorf("%w: %s has no size", ErrInvalidSupplementaryData, file.Name)
+		}
+		total += file.SizeBytes
+	}
+
+	if total > sd.maxBytes {
+		return fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrInvalidSupplementaryData, total, sd.maxBytes)
+	}
+	return nil
+}
+
+func (sd *SupplementaryDataService) lockFor(key string) *sync.Mutex {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	lock, ok := sd.locks[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		sd.locks[key] = lock
+	}
+	return lock
+}
+
+func fileSetKey(files []models.SupplementaryFile) string {
+	entries := make([]string, len(files))
+	for i, file := range files {
+		entries[i] = file.Name + ":" + file.SHA256
+	}
+	sort.Strings(entries)
+
+	hash := sha256.New()
+	for _, entry := range entries {
+		hash.Write([]byte(entry + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:24]
+}
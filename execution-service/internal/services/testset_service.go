@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"execution_service/internal/database"
+	"execution_service/internal/httpclient"
+	"execution_service/internal/models"
+	"execution_service/internal/queue"
+	"execution_service/internal/storage"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const testsetEventsQueue = "execution.content_events"
+
+// testsetRoutingKeys are the content service events that change a testset.
+var testsetRoutingKeys = []string{"testcase.uploaded", "testcase.updated", "testcase.deleted"}
+
+// TestsetService tracks which testset version each submission was judged on
+// and flags submissions whose problem has since received new tests.
+type TestsetService struct {
+	db            *database.DB
+	storage       *storage.MinIOClient
+	contentClient *httpclient.ContentServiceClient
+	queue         *queue.RabbitMQClient
+	retryInterval time.Duration
+}
+
+func NewTestsetService(db *database.DB, s *storage.MinIOClient, contentClient *httpclient.ContentServiceClient, q *queue.RabbitMQClient) *TestsetService {
+	return &TestsetService{
+		db:            db,
+		storage:       s,
+		contentClient: contentClient,
+		queue:         q,
+		retryInterval: 10 * time.Second,
+	}
+}
+
+// TestsetVersion hashes the test case list together with the ETags of each
+// input and output, so editing a file in place also changes the version.
+// Objects that cannot be inspected fall back to their URL.
+func TestsetVersion(ctx context.Context, s *storage.MinIOClient, testCases []models.TestCase) string {
+	sorted := make([]models.TestCase, len(testCases))
+	copy(sorted, testCases)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a].ID < sorted[b].ID })
+
+	hash := sha256.New()
+	for _, tc := range sorted {
+		fmt.Fprintf(hash, "%d|%s|%s|%d|%d", tc.ID,
+			objectVersion(ctx, s, tc.InputURL),
+			objectVersion(ctx, s, tc.OutputURL),
+			tc.TimeLimit, tc.MemoryLimit)
+		// Appended only when set so existing versions stay stable
+		if tc.InteractorURL != "" {
+			fmt.Fprintf(hash, "|%s", objectVersion(ctx, s, tc.InteractorURL))
+		}
+		hash.Write([]byte("\n"))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func objectVersion(ctx context.Context, s *storage.MinIOClient, url string) string {
+	if etag, err := s.ObjectETag(ctx, url); err == nil {
+		return etag
+	}
+	return url
+}
+
+// Refresh recomputes the problem's testset version and flags submissions
+// judged on any other version.
+func (ts *TestsetService) Refresh(ctx context.Context, problemID int64) (string, int64, error) {
+	problem, err := ts.contentClient.GetProblem(ctx, problemID)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get problem %d: %w", problemID, err)
+	}
+
+	testCases := make([]models.TestCase, len(problem.TestCases))
+	for i, tc := range problem.TestCases {
+		testCases[i] = models.TestCase{
+			ID:            tc.ID,
+			InputURL:      tc.InputURL,
+			OutputURL:     tc.OutputURL,
+			TimeLimit:     tc.TimeLimit,
+			MemoryLimit:   tc.MemoryLimit,
+			InteractorURL: tc.InteractorURL,
+		}
+	}
+
+	version := TestsetVersion(ctx, ts.storage, testCases)
+	flagged, err := ts.db.MarkOutdatedSubmissions(ctx, problemID, version)
+	if err != nil {
+		return version, 0, err
+	}
+
+	if flagged > 0 {
+		log.Printf("Flagged %d submissions of problem %d as judged on outdated tests", flagged, problemID)
+	}
+
+	return version, flagged, nil
+}
+
+func (ts *TestsetService) GetOutdatedSubmissions(ctx context.Context, problemID int64, limit int) ([]int64, error) {
+	return ts.db.GetOutdatedSubmissionIDs(ctx, problemID, limit)
+}
+
+// Start consumes test case change events from the content service until ctx
+// is cancelled, resubscribing if the consumer channel closes.
+func (ts *TestsetService) Start(ctx context.Context) {
+	for {
+		msgs, err := ts.queue.ConsumeEvents(ctx, testsetEventsQueue, testsetRoutingKeys...)
+		if err != nil {
+			log.Printf("Failed to consume content events: %v", err)
+		} else {
+			ts.consume(ctx, msgs)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ts.retryInterval):
+		}
+	}
+}
+
+func (ts *TestsetService) consume(ctx context.Context, msgs <-chan amqp.Delivery) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+
+			var event struct {
+				ProblemID int64 `json:"problemId"`
+			}
+			body, err := ts.queue.MessageBody(msg)
+			if err == nil {
+				err = json.Unmarshal(body, &event)
+			}
+			if err != nil || event.ProblemID == 0 {
+				log.Printf("Dropping malformed %s event: %v", msg.RoutingKey, err)
+				msg.Nack(false, false)
+				continue
+			}
+
+			if _, _, err := ts.Refresh(ctx, event.ProblemID); err != nil {
+				log.Printf("Failed to refresh testset for problem %d: %v", event.ProblemID, err)
+				time.Sleep(ts.retryInterval)
+				msg.Nack(false, true)
+				continue
+			}
+			msg.Ack(false)
+		}
+	}
+}
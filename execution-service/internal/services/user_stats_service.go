@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"execution_service/internal/config"
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+)
+
+// UserStatsService serves the per-user verdict counters, which are updated
+// alongside each final verdict, and periodically repairs any drift from the
+// submissions table.
+type UserStatsService struct {
+	db     *database.DB
+	config *config.UserStatsConfig
+}
+
+func NewUserStatsService(db *database.DB, cfg *config.UserStatsConfig) *UserStatsService {
+	return &UserStatsService{
+		db:     db,
+		config: cfg,
+	}
+}
+
+func (us *UserStatsService) Get(ctx context.Context, userID int64) (*models.UserStats, error) {
+	return us.db.GetUserStats(ctx, userID)
+}
+
+func (us *UserStatsService) Start(ctx context.Context) {
+	ticker := time.NewTicker(us.config.RepairInterval)
+	defer ticker.Stop()
+
+	log.Printf("Starting user stats repair every %v", us.config.RepairInterval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := us.Repair(ctx); err != nil {
+				log.Printf("User stats repair failed: %v", err)
+			}
+		}
+	}
+}
+
+// Repair recounts the counters and returns how many had drifted.
+func (us *UserStatsService) Repair(ctx context.Context) (int64, error) {
+	repaired, err := us.db.RepairUserStats(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if repaired > 0 {
+		log.Printf("Repaired %d drifted user stat counters", repaired)
+	}
+	return repaired, nil
+}
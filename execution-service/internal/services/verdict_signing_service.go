@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"execution_service/internal/config"
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+)
+
+const SigningAlgorithm = "ed25519"
+
+type SigningPublicKey struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+}
+
+// VerdictSigningService signs the canonical payload of final verdicts so
+// third parties can verify results without trusting the database.
+type VerdictSigningService struct {
+	db         *database.DB
+	keyID      string
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+func NewVerdictSigningService(cfg *config.SigningConfig, db *database.DB) (*VerdictSigningService, error) {
+	raw, err := base64.StdEncoding.DecodeString(cfg.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signing key: %w", err)
+	}
+
+	var privateKey ed25519.PrivateKey
+	switch len(raw) {
+	case ed25519.SeedSize:
+		privateKey = ed25519.NewKeyFromSeed(raw)
+	case ed25519.PrivateKeySize:
+		privateKey = ed25519.PrivateKey(raw)
+	default:
+		return nil, fmt.Errorf("signing key must be %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+	}
+
+	return &VerdictSigningService{
+		db:         db,
+		keyID:      cfg.KeyID,
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+	}, nil
+}
+
+// HashCode returns the hex SHA-256 digest used as the certificate's code hash.
+func HashCode(code []byte) string {
+	sum := sha256.Sum256(code)
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign encodes the certificate, signs it and stores the signature,
+// replacing any signature from a previous judging of the submission.
+func (s *VerdictSigningService) Sign(ctx context.Context, certificate *models.VerdictCertificate) (*models.VerdictSignature, error) {
+	certificate.SignedAt = certificate.SignedAt.UTC().Truncate(time.Second)
+
+	payload, err := json.Marshal(certificate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode verdict certificate: %w", err)
+	}
+
+	signature := &models.VerdictSignature{
+		SubmissionID: certificate.SubmissionID,
+		KeyID:        s.keyID,
+		Payload:      string(payload),
+		Signature:    base64.StdEncoding.EncodeToString(ed25519.Sign(s.privateKey, payload)),
+		SignedAt:     certificate.SignedAt,
+	}
+
+	if err := s.db.SaveVerdictSignature(ctx, signature); err != nil {
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+// Verify reports whether signature is a valid signature of payload under the
+// service key.
+func (s *VerdictSigningService) Verify(payload, signature string) (bool, error) {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, fmt.Errorf("signature is not valid base64: %w", err)
+	}
+
+	return ed25519.Verify(s.publicKey, []byte(payload), raw), nil
+}
+
+func (s *VerdictSigningService) GetSignature(ctx context.Context, submissionID int64) (*models.VerdictSignature, error) {
+	return s.db.GetVerdictSignature(ctx, submissionID)
+}
+
+func (s *VerdictSigningService) PublicKey() (*SigningPublicKey, error) {
+	der, err := x509.MarshalPKIXPublicKey(s.publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	return &SigningPublicKey{
+		KeyID:     s.keyID,
+		Algorithm: SigningAlgorithm,
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}, nil
+}
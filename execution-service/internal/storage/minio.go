@@ -3,10 +3,14 @@ package storage
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/url"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"execution_service/internal/config"
@@ -18,6 +22,10 @@ import (
 type MinIOClient struct {
 	Client *minio.Client
 	Bucket string
+
+	config   *config.MinIOConfig
+	scopedMu sync.Mutex
+	scoped   map[string]*minio.Client
 }
 
 func NewMinIOClient(cfg *config.MinIOConfig) (*MinIOClient, error) {
@@ -38,7 +46,10 @@ func NewMinIOClient(cfg *config.MinIOConfig) (*MinIOClient, error) {
 	}
 
 	if !exists {
-		err = client.MakeBucket(ctx, cfg.BucketName, minio.MakeBucketOptions{})
+		// Object locking can only be turned on when the bucket is made
+		err = client.MakeBucket(ctx, cfg.BucketName, minio.MakeBucketOptions{
+			ObjectLocking: cfg.Policies.Enabled && cfg.Policies.ContestRetention > 0,
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to create bucket: %w", err)
 		}
@@ -47,6 +58,8 @@ func NewMinIOClient(cfg *config.MinIOConfig) (*MinIOClient, error) {
 	return &MinIOClient{
 		Client: client,
 		Bucket: cfg.BucketName,
+		config: cfg,
+		scoped: make(map[string]*minio.Client),
 	}, nil
 }
 
@@ -64,13 +77,74 @@ func (m *MinIOClient) UploadCode(ctx context.Context, submissionID int64, langua
 	return m.getObjectURL(objectName), nil
 }
 
+// UploadProject stores a project archive under its content hash, so
+// resubmitting the same commit reuses the object.
+func (m *MinIOClient) UploadProject(ctx context.Context, archive []byte) (string, error) {
+	sum := sha256.Sum256(archive)
+	objectName := fmt.Sprintf("projects/%s.tar.gz", hex.EncodeToString(sum[:]))
+
+	_, err := m.Client.PutObject(ctx, m.Bucket, objectName, bytes.NewReader(archive), int64(len(archive)), minio.PutObjectOptions{
+		ContentType: "application/gzip",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload project: %w", err)
+	}
+
+	return m.getObjectURL(objectName), nil
+}
+
+// UploadArtifact stores a compiled build under key with the entry point the
+// build reported.
+func (m *MinIOClient) UploadArtifact(ctx context.Context, key string, artifact []byte, entryPoint string) error {
+	objectName := fmt.Sprintf("artifacts/%s.tar.gz", key)
+
+	_, err := m.Client.PutObject(ctx, m.Bucket, objectName, bytes.NewReader(artifact), int64(len(artifact)), minio.PutObjectOptions{
+		ContentType:  "application/gzip",
+		UserMetadata: map[string]string{"Entry-Point": entryPoint},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload artifact: %w", err)
+	}
+	return nil
+}
+
+// DownloadArtifact returns the build stored under key and its entry point,
+// or nil when there is none.
+func (m *MinIOClient) DownloadArtifact(ctx context.Context, key string) ([]byte, string, error) {
+	objectName := fmt.Sprintf("artifacts/%s.tar.gz", key)
+
+	obj, err := m.Client.GetObject(ctx, m.Bucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get artifact: %w", err)
+	}
+	defer obj.Close()
+
+	info, err := obj.Stat()
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("failed to stat artifact: %w", err)
+	}
+
+	artifact, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read artifact: %w", err)
+	}
+	return artifact, info.UserMetadata["Entry-Point"], nil
+}
+
 func (m *MinIOClient) DownloadCode(ctx context.Context, codeURL string) ([]byte, error) {
 	objectName, err := m.parseURL(codeURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid code URL: %w", err)
 	}
+	client, err := m.readClient(ctx, objectName)
+	if err != nil {
+		return nil, err
+	}
 
-	obj, err := m.Client.GetObject(ctx, m.Bucket, objectName, minio.GetObjectOptions{})
+	obj, err := client.GetObject(ctx, m.Bucket, objectName, minio.GetObjectOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get object: %w", err)
 	}
@@ -84,6 +158,48 @@ func (m *MinIOClient) DownloadCode(ctx context.Context, codeURL string) ([]byte,
 	return code, nil
 }
 
+// OpenCode streams stored code, returning its size alongside the reader.
+func (m *MinIOClient) OpenCode(ctx context.Context, codeURL string) (io.ReadCloser, int64, error) {
+	objectName, err := m.parseURL(codeURL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid code URL: %w", err)
+	}
+	client, err := m.readClient(ctx, objectName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	obj, err := client.GetObject(ctx, m.Bucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get object: %w", err)
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, 0, fmt.Errorf("failed to stat object: %w", err)
+	}
+	return obj, info.Size, nil
+}
+
+// ObjectETag returns the stored object's ETag, which changes with its content.
+func (m *MinIOClient) ObjectETag(ctx context.Context, fileURL string) (string, error) {
+	objectName, err := m.parseURL(fileURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid file URL: %w", err)
+	}
+	client, err := m.readClient(ctx, objectName)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := client.StatObject(ctx, m.Bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to stat object: %w", err)
+	}
+
+	return info.ETag, nil
+}
+
 func (m *MinIOClient) UploadTestCase(ctx context.Context, problemID int64, testNumber int, input, output []byte) (inputURL, outputURL string, err error) {
 	inputName := fmt.Sprintf("problems/%d/testcases/%d/input.txt", problemID, testNumber)
 	outputName := fmt.Sprintf("problems/%d/testcases/%d/output.txt", problemID, testNumber)
@@ -115,8 +231,16 @@ func (m *MinIOClient) DownloadTestCase(ctx context.Context, inputURL, outputURL
 	if err != nil {
 		return nil, nil, fmt.Errorf("invalid output URL: %w", err)
 	}
+	inputClient, err := m.readClient(ctx, inputName)
+	if err != nil {
+		return nil, nil, err
+	}
+	outputClient, err := m.readClient(ctx, outputName)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	inputObj, err := m.Client.GetObject(ctx, m.Bucket, inputName, minio.GetObjectOptions{})
+	inputObj, err := inputClient.GetObject(ctx, m.Bucket, inputName, minio.GetObjectOptions{})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get input object: %w", err)
 	}
@@ -127,7 +251,7 @@ func (m *MinIOClient) DownloadTestCase(ctx context.Context, inputURL, outputURL
 		return nil, nil, fmt.Errorf("failed to read input object: %w", err)
 	}
 
-	outputObj, err := m.Client.GetObject(ctx, m.Bucket, outputName, minio.GetObjectOptions{})
+	outputObj, err := outputClient.GetObject(ctx, m.Bucket, outputName, minio.GetObjectOptions{})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get output object: %w", err)
 	}
@@ -141,6 +265,71 @@ func (m *MinIOClient) DownloadTestCase(ctx context.Context, inputURL, outputURL
 	return input, output, nil
 }
 
+// DownloadToFile streams an object into path without buffering it in memory
+// and returns its SHA-256 and size. At most maxBytes+1 bytes are read, so a
+// size above maxBytes means the object is larger than allowed.
+func (m *MinIOClient) DownloadToFile(ctx context.Context, fileURL, path string, maxBytes int64) (string, int64, error) {
+	objectName, err := m.parseURL(fileURL)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid file URL: %w", err)
+	}
+	client, err := m.readClient(ctx, objectName)
+	if err != nil {
+		return "", 0, err
+	}
+
+	obj, err := client.GetObject(ctx, m.Bucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get object: %w", err)
+	}
+	defer obj.Close()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(file, hash), io.LimitReader(obj, maxBytes+1))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to download object: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), written, nil
+}
+
+func (m *MinIOClient) UploadDiagnostics(ctx context.Context, name string, data []byte) (string, error) {
+	objectName := fmt.Sprintf("diagnostics/%s", name)
+
+	_, err := m.Client.PutObject(ctx, m.Bucket, objectName, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/gzip",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload diagnostics bundle: %w", err)
+	}
+
+	return m.getObjectURL(objectName), nil
+}
+
+// PresignedURL returns a time-limited download link for an s3:// object URL.
+func (m *MinIOClient) PresignedURL(ctx context.Context, fileURL string, expiry time.Duration) (string, error) {
+	objectName, err := m.parseURL(fileURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid file URL: %w", err)
+	}
+
+	presignedURL, err := m.Client.PresignedGetObject(ctx, m.Bucket, objectName, expiry, make(url.Values))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
+	}
+
+	return presignedURL.String(), nil
+}
+
 func (m *MinIOClient) DeleteFile(ctx context.Context, fileURL string) error {
 	objectName, err := m.parseURL(fileURL)
 	if err != nil {
@@ -198,6 +387,12 @@ func getFileExtension(language string) string {
 		"java":   "java",
 		"python": "py",
 		"go":     "go",
+		"rust":   "rs",
+		"kotlin": "kt",
+		"csharp": "cs",
+		"ruby":   "rb",
+		// Output-only submissions are zip archives of outputs
+		"output_only": "zip",
 	}
 
 	if ext, exists := extensions[language]; exists {
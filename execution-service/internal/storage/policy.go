@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"execution_service/internal/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/minio/minio-go/v7/pkg/sse"
+)
+
+// codePrefixes hold submitted code, which the transition rules move.
+var codePrefixes = []string{"submissions/", "projects/"}
+
+// policyRulePrefix marks the lifecycle rules the service owns; rules added
+// by operators are left alone.
+const policyRulePrefix = "codehakam-transition-"
+
+// Encryption returns the bucket's default encryption algorithm, or empty
+// when it has none.
+func (m *MinIOClient) Encryption(ctx context.Context) (string, error) {
+	encryption, err := m.Client.GetBucketEncryption(ctx, m.Bucket)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "ServerSideEncryptionConfigurationNotFoundError" {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get bucket encryption: %w", err)
+	}
+	if len(encryption.Rules) == 0 {
+		return "", nil
+	}
+	return encryption.Rules[0].Apply.SSEAlgorithm, nil
+}
+
+// EnsureEncryption sets the bucket's default encryption to the configured
+// SSE mode.
+func (m *MinIOClient) EnsureEncryption(ctx context.Context, policies *config.StoragePolicyConfig) error {
+	encryption := sse.NewConfigurationSSES3()
+	if policies.Encryption == "sse-kms" {
+		encryption = sse.NewConfigurationSSEKMS(policies.KMSKeyID)
+	}
+	if err := m.Client.SetBucketEncryption(ctx, m.Bucket, encryption); err != nil {
+		return fmt.Errorf("failed to set bucket encryption: %w", err)
+	}
+	return nil
+}
+
+// ObjectLockEnabled reports whether the bucket was made with object locking.
+func (m *MinIOClient) ObjectLockEnabled(ctx context.Context) (bool, error) {
+	status, _, _, _, err := m.Client.GetObjectLockConfig(ctx, m.Bucket)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "ObjectLockConfigurationNotFoundError" {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get object lock config: %w", err)
+	}
+	return status == "Enabled", nil
+}
+
+// RetainContestObjects locks every object under the contests prefix modified
+// after since until retention past its upload, and returns how many it
+// locked. Objects whose retention has already run out are skipped.
+func (m *MinIOClient) RetainContestObjects(ctx context.Context, since time.Time, retention time.Duration, mode string) (int, error) {
+	retentionMode := minio.RetentionMode(mode)
+	retained := 0
+	for object := range m.Client.ListObjects(ctx, m.Bucket, minio.ListObjectsOptions{Prefix: contestsPrefix, Recursive: true}) {
+		if object.Err != nil {
+			return retained, fmt.Errorf("failed to list contest objects: %w", object.Err)
+		}
+		until := object.LastModified.Add(retention)
+		if !object.LastModified.After(since) || until.Before(time.Now()) {
+			continue
+		}
+		err := m.Client.PutObjectRetention(ctx, m.Bucket, object.Key, minio.PutObjectRetentionOptions{
+			Mode:            &retentionMode,
+			RetainUntilDate: &until,
+			VersionID:       object.VersionID,
+		})
+		if err != nil {
+			return retained, fmt.Errorf("failed to retain %s: %w", object.Key, err)
+		}
+		retained++
+	}
+	return retained, nil
+}
+
+// EnsureTransitions replaces the service's lifecycle rules with ones moving
+// code objects older than days to storageClass, or removes them when days is
+// zero.
+func (m *MinIOClient) EnsureTransitions(ctx context.Context, days int, storageClass string) error {
+	current, err := m.Client.GetBucketLifecycle(ctx, m.Bucket)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code != "NoSuchLifecycleConfiguration" {
+			return fmt.Errorf("failed to get bucket lifecycle: %w", err)
+		}
+		current = lifecycle.NewConfiguration()
+	}
+
+	rules := lifecycle.NewConfiguration()
+	for _, rule := range current.Rules {
+		if !strings.HasPrefix(rule.ID, policyRulePrefix) {
+			rules.Rules = append(rules.Rules, rule)
+		}
+	}
+	if days > 0 {
+		for _, prefix := range codePrefixes {
+			rules.Rules = append(rules.Rules, lifecycle.Rule{
+				ID:         policyRulePrefix + strings.TrimSuffix(prefix, "/"),
+				Status:     "Enabled",
+				RuleFilter: lifecycle.Filter{Prefix: prefix},
+				Transition: lifecycle.Transition{
+					Days:         lifecycle.ExpirationDays(days),
+					StorageClass: storageClass,
+				},
+			})
+		}
+	}
+
+	if err := m.Client.SetBucketLifecycle(ctx, m.Bucket, rules); err != nil {
+		return fmt.Errorf("failed to set bucket lifecycle: %w", err)
+	}
+	return nil
+}
+
+// TransitionRules counts the service's lifecycle rules on the bucket.
+func (m *MinIOClient) TransitionRules(ctx context.Context) (int, error) {
+	current, err := m.Client.GetBucketLifecycle(ctx, m.Bucket)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchLifecycleConfiguration" {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get bucket lifecycle: %w", err)
+	}
+	count := 0
+	for _, rule := range current.Rules {
+		if strings.HasPrefix(rule.ID, policyRulePrefix) && rule.Status == "Enabled" {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// ErrOutOfScope means a scoped read asked for another contest's object.
+var ErrOutOfScope = errors.New("object is outside the contest scope")
+
+const contestsPrefix = "contests/"
+
+// ContestPrefix is where a contest's private objects, such as its test data
+// and checkers, are stored.
+func ContestPrefix(contestID int64) string {
+	return fmt.Sprintf("%s%d/", contestsPrefix, contestID)
+}
+
+type contestScopeKey struct{}
+
+// WithContestScope limits reads made with ctx to objects outside any contest
+// prefix and to the given contest's own prefix; a nil contest may read no
+// contest's objects. Judging runs under a scope, so a test, checker or data
+// URL cannot pull in another contest's files. Contexts without a scope are
+// the service's own and read anything.
+func WithContestScope(ctx context.Context, contestID *int64) context.Context {
+	prefix := ""
+	if contestID != nil {
+		prefix = ContestPrefix(*contestID)
+	}
+	return context.WithValue(ctx, contestScopeKey{}, prefix)
+}
+
+// readClient returns the client to read objectName with under the context's
+// scope. With scoped credentials, the read also goes through credentials
+// whose policy MinIO enforces, not only this check.
+func (m *MinIOClient) readClient(ctx context.Context, objectName string) (*minio.Client, error) {
+	prefix, scoped := ctx.Value(contestScopeKey{}).(string)
+	if !scoped {
+		return m.Client, nil
+	}
+	if strings.HasPrefix(objectName, contestsPrefix) && (prefix == "" || !strings.HasPrefix(objectName, prefix)) {
+		return nil, fmt.Errorf("%w: %s", ErrOutOfScope, objectName)
+	}
+	if !m.config.ScopedCredentials {
+		return m.Client, nil
+	}
+	return m.scopedClient(prefix)
+}
+
+// scopedClient returns a client on temporary credentials for one scope. The
+// credentials refresh themselves, so each scope's client is kept.
+func (m *MinIOClient) scopedClient(prefix string) (*minio.Client, error) {
+	m.scopedMu.Lock()
+	defer m.scopedMu.Unlock()
+
+	if client, ok := m.scoped[prefix]; ok {
+		return client, nil
+	}
+
+	scheme := "http"
+	if m.config.UseSSL {
+		scheme = "https"
+	}
+	creds, err := credentials.NewSTSAssumeRole(scheme+"://"+m.config.Endpoint, credentials.STSAssumeRoleOptions{
+		AccessKey: m.config.AccessKey,
+		SecretKey: m.config.SecretKey,
+		Policy:    scopePolicy(m.Bucket, prefix),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scoped credentials: %w", err)
+	}
+	client, err := minio.New(m.config.Endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: m.config.UseSSL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scoped MinIO client: %w", err)
+	}
+
+	m.scoped[prefix] = client
+	return client, nil
+}
+
+// scopePolicy is the session policy for a scope: reads of everything outside
+// the contests prefix, plus the scope's own contest prefix.
+func scopePolicy(bucket, prefix string) string {
+	statements := []map[string]any{{
+		"Effect":      "Allow",
+		"Action":      []string{"s3:GetObject"},
+		"NotResource": []string{fmt.Sprintf("arn:aws:s3:::%s/%s*", bucket, contestsPrefix)},
+	}}
+	if prefix != "" {
+		statements = append(statements, map[string]any{
+			"Effect":   "Allow",
+			"Action":   []string{"s3:GetObject"},
+			"Resource": []string{fmt.Sprintf("arn:aws:s3:::%s/%s*", bucket, prefix)},
+		})
+	}
+
+	policy, _ := json.Marshal(map[string]any{
+		"Version":   "2012-10-17",
+		"Statement": statements,
+	})
+	return string(policy)
+}
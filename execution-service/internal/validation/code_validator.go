@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"unicode/utf8"
 )
 
 type CodeValidator struct {
-	config *ValidationConfig
+	config      *ValidationConfig
+	extensionMu sync.RWMutex
 }
 
 type ValidationConfig struct {
@@ -322,7 +324,30 @@ func (cv *CodeValidator) validateJavaScript(code string, result *ValidationResul
 }
 
 // Helper functions
+// AllowExtensions adds extensions to the whitelist, for languages configured
+// after the validator was built.
+func (cv *CodeValidator) AllowExtensions(extensions ...string) {
+	cv.extensionMu.Lock()
+	defer cv.extensionMu.Unlock()
+	for _, ext := range extensions {
+		if !cv.allowsExtension(ext) {
+			cv.config.AllowedExtensions = append(cv.config.AllowedExtensions, ext)
+		}
+	}
+}
+
+func (cv *CodeValidator) allowsExtension(ext string) bool {
+	for _, allowed := range cv.config.AllowedExtensions {
+		if allowed == ext {
+			return true
+		}
+	}
+	return false
+}
+
 func (cv *CodeValidator) isValidExtension(filename string) bool {
+	cv.extensionMu.RLock()
+	defer cv.extensionMu.RUnlock()
 	for _, ext := range cv.config.AllowedExtensions {
 		if strings.HasSuffix(strings.ToLower(filename), ext) {
 			return true
@@ -426,7 +451,7 @@ func (cv *CodeValidator) getExtension(filename string) string {
 func (cv *CodeValidator) GetDefaultConfig() *ValidationConfig {
 	return &ValidationConfig{
 		MaxCodeSize:       65536, // 64KB
-		AllowedExtensions: []string{".cpp", ".c", ".java", ".py", ".go", ".rs", ".js", ".ts"},
+		AllowedExtensions: []string{".cpp", ".c", ".java", ".py", ".go", ".rs", ".kt", ".cs", ".rb"},
 		BlacklistedPatterns: []string{
 			`(?i)system\s*\(`,
 			`(?i)exec\s*\(`,
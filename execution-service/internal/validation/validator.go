@@ -1,6 +1,10 @@
 package validation
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -10,8 +14,31 @@ import (
 )
 
 var (
-	languageRegex = regexp.MustCompile(`^[a-z]+$`)
-	idRegex       = regexp.MustCompile(`^\d+$`)
+	languageRegex  = regexp.MustCompile(`^[a-z]+$`)
+	idRegex        = regexp.MustCompile(`^\d+$`)
+	tagRegex       = regexp.MustCompile(`^[A-Za-z0-9_.:/-]{1,64}$`)
+	extensionRegex = regexp.MustCompile(`^\.[a-z0-9]{1,9}$`)
+	// Printable ASCII, which covers UUIDs and the usual client key formats
+	idempotencyKeyRegex = regexp.MustCompile(`^[\x21-\x7e]{1,255}$`)
+)
+
+// Hard ceilings on any time and memory limit; platform limits are tuned
+// within them.
+const (
+	MaxTimeLimitMs   = 30000
+	MaxMemoryLimitKb = 524288
+)
+
+const (
+	maxSubmissionTags       = 20
+	maxSubmissionAttributes = 20
+	maxAttributeValueLength = 256
+	maxMetadataSize         = 4096
+	maxClientFieldLength    = 64
+	maxLocalTests           = 50
+	maxLanguageCodeLength   = 20
+	maxLanguageNameLength   = 50
+	maxLanguageMultiplier   = 10
 )
 
 func ValidateJudgeRequest(req *models.JudgeRequest) error {
@@ -35,12 +62,12 @@ func ValidateJudgeRequest(req *models.JudgeRequest) error {
 		return fmt.Errorf("code URL is required")
 	}
 
-	if req.TimeLimitMs <= 0 || req.TimeLimitMs > 30000 {
-		return fmt.Errorf("time limit must be between 1 and 30000 ms")
+	if req.TimeLimitMs <= 0 || req.TimeLimitMs > MaxTimeLimitMs {
+		return fmt.Errorf("time limit must be between 1 and %d ms", MaxTimeLimitMs)
 	}
 
-	if req.MemoryLimitKb <= 0 || req.MemoryLimitKb > 524288 {
-		return fmt.Errorf("memory limit must be between 1 and 524288 KB")
+	if req.MemoryLimitKb <= 0 || req.MemoryLimitKb > MaxMemoryLimitKb {
+		return fmt.Errorf("memory limit must be between 1 and %d KB", MaxMemoryLimitKb)
 	}
 
 	if req.Priority < 0 || req.Priority > 10 {
@@ -101,9 +128,168 @@ func ValidateProblemID(idStr string) (int64, error) {
 	return id, nil
 }
 
+func ValidateTeamID(idStr string) (int64, error) {
+	if !idRegex.MatchString(idStr) {
+		return 0, fmt.Errorf("invalid team ID format")
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid team ID")
+	}
+
+	if id <= 0 {
+		return 0, fmt.Errorf("team ID must be positive")
+	}
+
+	return id, nil
+}
+
+func ValidateContestID(idStr string) (int64, error) {
+	if !idRegex.MatchString(idStr) {
+		return 0, fmt.Errorf("invalid contest ID format")
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid contest ID")
+	}
+
+	if id <= 0 {
+		return 0, fmt.Errorf("contest ID must be positive")
+	}
+
+	return id, nil
+}
+
+func ValidateAPIKeyID(idStr string) (int64, error) {
+	if !idRegex.MatchString(idStr) {
+		return 0, fmt.Errorf("invalid API key ID format")
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid API key ID")
+	}
+
+	if id <= 0 {
+		return 0, fmt.Errorf("API key ID must be positive")
+	}
+
+	return id, nil
+}
+
+func ValidateCanaryID(idStr string) (int64, error) {
+	if !idRegex.MatchString(idStr) {
+		return 0, fmt.Errorf("invalid canary ID format")
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid canary ID")
+	}
+
+	if id <= 0 {
+		return 0, fmt.Errorf("canary ID must be positive")
+	}
+
+	return id, nil
+}
+
+func ValidateRejudgeJobID(idStr string) (int64, error) {
+	if !idRegex.MatchString(idStr) {
+		return 0, fmt.Errorf("invalid rejudge job ID format")
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rejudge job ID")
+	}
+
+	if id <= 0 {
+		return 0, fmt.Errorf("rejudge job ID must be positive")
+	}
+
+	return id, nil
+}
+
+func ValidateReportID(idStr string) (int64, error) {
+	if !idRegex.MatchString(idStr) {
+		return 0, fmt.Errorf("invalid report ID format")
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid report ID")
+	}
+
+	if id <= 0 {
+		return 0, fmt.Errorf("report ID must be positive")
+	}
+
+	return id, nil
+}
+
+func ValidatePlagiarismRunID(idStr string) (int64, error) {
+	if !idRegex.MatchString(idStr) {
+		return 0, fmt.Errorf("invalid plagiarism run ID format")
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid plagiarism run ID")
+	}
+
+	if id <= 0 {
+		return 0, fmt.Errorf("plagiarism run ID must be positive")
+	}
+
+	return id, nil
+}
+
+func ValidateBoilerplateID(idStr string) (int64, error) {
+	if !idRegex.MatchString(idStr) {
+		return 0, fmt.Errorf("invalid boilerplate ID format")
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid boilerplate ID")
+	}
+
+	if id <= 0 {
+		return 0, fmt.Errorf("boilerplate ID must be positive")
+	}
+
+	return id, nil
+}
+
+// ValidateAPIKeyScopes requires at least one known scope and no repeats.
+func ValidateAPIKeyScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("at least one scope is required")
+	}
+
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		if scope != models.APIKeyScopeSubmit && scope != models.APIKeyScopeReadOwn {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+		if seen[scope] {
+			return fmt.Errorf("duplicate scope %q", scope)
+		}
+		seen[scope] = true
+	}
+
+	return nil
+}
+
 func ValidateLanguage(code string) error {
-	if !languageRegex.MatchString(code) {
-		return fmt.Errorf("invalid language format")
+	if code == models.LanguageOutputOnly {
+		return nil
+	}
+	if err := ValidateLanguageFormat(code); err != nil {
+		return err
 	}
 
 	supportedLanguages := map[string]bool{
@@ -112,6 +298,10 @@ func ValidateLanguage(code string) error {
 		"java":   true,
 		"python": true,
 		"go":     true,
+		"rust":   true,
+		"kotlin": true,
+		"csharp": true,
+		"ruby":   true,
 	}
 
 	if !supportedLanguages[code] {
@@ -121,6 +311,185 @@ func ValidateLanguage(code string) error {
 	return nil
 }
 
+// ValidateLanguageFormat checks only the shape of a language code, for
+// callers that look the code up in the configured languages themselves.
+// ValidateCacheTarget parses a cache invalidation target such as problem:123,
+// language:cpp, user-perms:42 or all-testcases.
+func ValidateCacheTarget(raw string) (models.CacheTarget, error) {
+	kind, value, _ := strings.Cut(raw, ":")
+	target := models.CacheTarget{Kind: kind}
+
+	var err error
+	switch kind {
+	case models.CacheTargetProblem:
+		target.ID, err = ValidateProblemID(value)
+	case models.CacheTargetUserPerms:
+		target.ID, err = ValidateUserID(value)
+	case models.CacheTargetLanguage:
+		target.Language = value
+		err = ValidateLanguageFormat(value)
+	case models.CacheTargetAllTestCases:
+		if value != "" {
+			err = fmt.Errorf("%s takes no argument", kind)
+		}
+	default:
+		err = fmt.Errorf("unknown cache target kind %q", kind)
+	}
+	if err != nil {
+		return models.CacheTarget{}, fmt.Errorf("invalid cache target %q: %w", raw, err)
+	}
+	return target, nil
+}
+
+func ValidateLanguageFormat(code string) error {
+	if !languageRegex.MatchString(code) {
+		return fmt.Errorf("invalid language format")
+	}
+	return nil
+}
+
+// ValidateLanguageConfig checks a language an admin is saving. Devices in
+// the execution profile must be in allowedDevices.
+func ValidateLanguageConfig(language *models.SupportedLanguage, allowedDevices []string) error {
+	if err := ValidateLanguageFormat(language.LanguageCode); err != nil {
+		return err
+	}
+	if len(language.LanguageCode) > maxLanguageCodeLength {
+		return fmt.Errorf("language code must be at most %d characters", maxLanguageCodeLength)
+	}
+	if strings.TrimSpace(language.LanguageName) == "" || len(language.LanguageName) > maxLanguageNameLength {
+		return fmt.Errorf("language name must be 1 to %d characters", maxLanguageNameLength)
+	}
+	if strings.TrimSpace(language.ExecuteCommand) == "" {
+		return fmt.Errorf("execute command is required")
+	}
+	if language.FileExtension != "" && !extensionRegex.MatchString(language.FileExtension) {
+		return fmt.Errorf("invalid file extension %q", language.FileExtension)
+	}
+	if language.TimeMultiplier <= 0 || language.TimeMultiplier > maxLanguageMultiplier {
+		return fmt.Errorf("time multiplier must be above 0 and at most %d", maxLanguageMultiplier)
+	}
+	if language.MemoryMultiplier <= 0 || language.MemoryMultiplier > maxLanguageMultiplier {
+		return fmt.Errorf("memory multiplier must be above 0 and at most %d", maxLanguageMultiplier)
+	}
+
+	for _, stage := range language.CompilePipeline {
+		if strings.TrimSpace(stage.Name) == "" || strings.TrimSpace(stage.Command) == "" {
+			return fmt.Errorf("compile stages need a name and a command")
+		}
+		if stage.OnFailure != "" && stage.OnFailure != models.StageFailureCompile && stage.OnFailure != models.StageFailureSystem {
+			return fmt.Errorf("stage %s: unknown on_failure %q", stage.Name, stage.OnFailure)
+		}
+		if stage.TimeLimitMs < 0 || stage.MemoryLimitKb < 0 || stage.Processes < 0 {
+			return fmt.Errorf("stage %s: limits must not be negative", stage.Name)
+		}
+	}
+
+	allowed := make(map[string]bool, len(allowedDevices))
+	for _, device := range allowedDevices {
+		allowed[device] = true
+	}
+	for _, device := range language.ExecutionProfile.Devices {
+		if !allowed[device] {
+			return fmt.Errorf("device %s is not allowed", device)
+		}
+	}
+
+	return nil
+}
+
+func ValidateSubmissionMetadata(metadata *models.SubmissionMetadata) error {
+	if len(metadata.Tags) > maxSubmissionTags {
+		return fmt.Errorf("at most %d tags are allowed", maxSubmissionTags)
+	}
+	for _, tag := range metadata.Tags {
+		if err := ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+
+	if len(metadata.Attributes) > maxSubmissionAttributes {
+		return fmt.Errorf("at most %d attributes are allowed", maxSubmissionAttributes)
+	}
+	for key, value := range metadata.Attributes {
+		if !tagRegex.MatchString(key) {
+			return fmt.Errorf("invalid attribute key: %q", key)
+		}
+		if len(value) > maxAttributeValueLength {
+			return fmt.Errorf("attribute %s exceeds %d characters", key, maxAttributeValueLength)
+		}
+	}
+
+	if metadata.Client != nil {
+		if err := validateClientContext(metadata.Client); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("invalid metadata")
+	}
+	if len(data) > maxMetadataSize {
+		return fmt.Errorf("metadata exceeds maximum size of %d bytes", maxMetadataSize)
+	}
+
+	return nil
+}
+
+func validateClientContext(client *models.ClientContext) error {
+	if client.Editor == "" || client.Plugin == "" {
+		return fmt.Errorf("client editor and plugin are required")
+	}
+	for _, field := range []string{client.Editor, client.EditorVersion, client.Plugin, client.PluginVersion} {
+		if len(field) > maxClientFieldLength {
+			return fmt.Errorf("client fields must be at most %d characters", maxClientFieldLength)
+		}
+	}
+	if len(client.LocalTests) > maxLocalTests {
+		return fmt.Errorf("at most %d local tests are allowed", maxLocalTests)
+	}
+	for _, test := range client.LocalTests {
+		if test.Name == "" || len(test.Name) > maxClientFieldLength {
+			return fmt.Errorf("local test names must be 1-%d characters", maxClientFieldLength)
+		}
+		if test.TimeMs != nil && *test.TimeMs < 0 {
+			return fmt.Errorf("local test time must not be negative")
+		}
+	}
+	return nil
+}
+
+// ValidateSubmissionSchema checks a request's schema_version and that the
+// fields it carries belong to that version, returning the version in effect.
+func ValidateSubmissionSchema(version int, metadata *models.SubmissionMetadata) (int, error) {
+	switch version {
+	case 0, models.SubmissionSchemaV1:
+		if metadata.Client != nil {
+			return 0, fmt.Errorf("client metadata requires schema_version %d", models.SubmissionSchemaV2)
+		}
+		return models.SubmissionSchemaV1, nil
+	case models.SubmissionSchemaV2:
+		return version, nil
+	}
+	return 0, fmt.Errorf("unsupported schema_version %d", version)
+}
+
+// ValidateIdempotencyKey checks an Idempotency-Key header value.
+func ValidateIdempotencyKey(key string) error {
+	if !idempotencyKeyRegex.MatchString(key) {
+		return fmt.Errorf("Idempotency-Key must be 1-255 printable ASCII characters")
+	}
+	return nil
+}
+
+func ValidateTag(tag string) error {
+	if !tagRegex.MatchString(tag) {
+		return fmt.Errorf("invalid tag: %q", tag)
+	}
+	return nil
+}
+
 func ValidatePagination(limitStr, offsetStr string) (int, int, error) {
 	limit := 20
 	offset := 0
@@ -144,6 +513,62 @@ func ValidatePagination(limitStr, offsetStr string) (int, int, error) {
 	return limit, offset, nil
 }
 
+// ValidateSubmissionLimits applies the platform's default limits in place of
+// zero values and rejects limits above its maximums.
+func ValidateSubmissionLimits(timeLimitMs, memoryLimitKb int, limits *models.PlatformLimits) (int, int, error) {
+	if timeLimitMs <= 0 {
+		timeLimitMs = limits.DefaultTimeLimitMs
+	}
+	if memoryLimitKb <= 0 {
+		memoryLimitKb = limits.DefaultMemoryLimitKb
+	}
+
+	if timeLimitMs > limits.MaxTimeLimitMs {
+		return 0, 0, fmt.Errorf("time limit must be <= %dms", limits.MaxTimeLimitMs)
+	}
+	if memoryLimitKb > limits.MaxMemoryLimitKb {
+		return 0, 0, fmt.Errorf("memory limit must be <= %dKB", limits.MaxMemoryLimitKb)
+	}
+
+	return timeLimitMs, memoryLimitKb, nil
+}
+
+// DefaultPlatformLimits are the limits used before any are configured.
+func DefaultPlatformLimits() *models.PlatformLimits {
+	return &models.PlatformLimits{
+		DefaultTimeLimitMs:   2000,
+		DefaultMemoryLimitKb: 262144,
+		MaxTimeLimitMs:       MaxTimeLimitMs,
+		MaxMemoryLimitKb:     MaxMemoryLimitKb,
+		MaxStackSizeKb:       65536,
+		MaxOutputSizeKb:      16384,
+	}
+}
+
+// ValidatePlatformLimits checks limits before they replace the platform's.
+// Defaults must fit under the maximums, which must fit under the ceilings.
+func ValidatePlatformLimits(limits *models.PlatformLimits) error {
+	if limits.MaxTimeLimitMs < 100 || limits.MaxTimeLimitMs > MaxTimeLimitMs {
+		return fmt.Errorf("max time limit must be between 100 and %d ms", MaxTimeLimitMs)
+	}
+	if limits.MaxMemoryLimitKb < 1024 || limits.MaxMemoryLimitKb > MaxMemoryLimitKb {
+		return fmt.Errorf("max memory limit must be between 1024 and %d KB", MaxMemoryLimitKb)
+	}
+	if limits.DefaultTimeLimitMs < 100 || limits.DefaultTimeLimitMs > limits.MaxTimeLimitMs {
+		return fmt.Errorf("default time limit must be between 100 and %d ms", limits.MaxTimeLimitMs)
+	}
+	if limits.DefaultMemoryLimitKb < 1024 || limits.DefaultMemoryLimitKb > limits.MaxMemoryLimitKb {
+		return fmt.Errorf("default memory limit must be between 1024 and %d KB", limits.MaxMemoryLimitKb)
+	}
+	if limits.MaxStackSizeKb <= 0 || limits.MaxStackSizeKb > limits.MaxMemoryLimitKb {
+		return fmt.Errorf("max stack size must be between 1 and %d KB", limits.MaxMemoryLimitKb)
+	}
+	if limits.MaxOutputSizeKb <= 0 {
+		return fmt.Errorf("max output size must be positive")
+	}
+	return nil
+}
+
 func SanitizeString(input string) string {
 	input = strings.TrimSpace(input)
 	input = strings.ReplaceAll(input, "\x00", "")
@@ -159,6 +584,24 @@ func SanitizeString(input string) string {
 }
 
 func ValidateCode(code []byte, language string) error {
+	if language == models.LanguageOutputOnly {
+		return ValidateOutputArchive(code)
+	}
+	if err := ValidateCodeSize(code); err != nil {
+		return err
+	}
+
+	codeStr := string(code)
+	if strings.Contains(codeStr, "system(") || strings.Contains(codeStr, "exec(") {
+		return fmt.Errorf("code contains potentially dangerous system calls")
+	}
+
+	return nil
+}
+
+// ValidateCodeSize checks only the size of the code, for trusted submissions
+// that skip pattern checks.
+func ValidateCodeSize(code []byte) error {
 	maxCodeSize := 65536
 
 	if len(code) > maxCodeSize {
@@ -169,10 +612,38 @@ func ValidateCode(code []byte, language string) error {
 		return fmt.Errorf("code cannot be empty")
 	}
 
-	codeStr := string(code)
-	if strings.Contains(codeStr, "system(") || strings.Contains(codeStr, "exec(") {
-		return fmt.Errorf("code contains potentially dangerous system calls")
+	return nil
+}
+
+// maxOutputArchiveSize caps an output-only submission's zip archive, which
+// arrives base64 encoded within the request size limit.
+const maxOutputArchiveSize = 768 << 10
+
+// DecodeSubmissionCode returns the stored form of a submission's code field.
+// Output-only submissions send their zip archive base64 encoded.
+func DecodeSubmissionCode(code, language string) ([]byte, error) {
+	if language != models.LanguageOutputOnly {
+		return []byte(code), nil
+	}
+	archive, err := base64.StdEncoding.DecodeString(code)
+	if err != nil {
+		return nil, fmt.Errorf("output archive must be base64 encoded")
 	}
+	return archive, nil
+}
 
+// ValidateOutputArchive checks an output-only submission's archive is a zip
+// file within the size limit. Its files are checked against the tests when
+// judged.
+func ValidateOutputArchive(archive []byte) error {
+	if len(archive) == 0 {
+		return fmt.Errorf("output archive cannot be empty")
+	}
+	if len(archive) > maxOutputArchiveSize {
+		return fmt.Errorf("output archive exceeds maximum allowed size of %d bytes", maxOutputArchiveSize)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive))); err != nil {
+		return fmt.Errorf("output archive is not a valid zip file")
+	}
 	return nil
 }
@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"execution_service/internal/models"
+	"execution_service/internal/sandbox"
+	"execution_service/internal/storage"
+)
+
+// NodeName identifies this judge node in consistency reports.
+func (jp *JudgePool) NodeName() string {
+	return nodeName()
+}
+
+// JudgeCanary judges a stored submission on this node without touching its
+// result, for comparison against the other nodes.
+func (jp *JudgePool) JudgeCanary(ctx context.Context, submission *models.Submission) (*models.CanaryRun, error) {
+	// An empty shadow config keeps the live checker and limits
+	return jp.judgeDetached(ctx, submission, &models.ShadowConfig{})
+}
+
+// JudgeWithLimits judges a stored submission under candidate limits, applied
+// to every test before language scaling, without touching its result.
+func (jp *JudgePool) JudgeWithLimits(ctx context.Context, submission *models.Submission, timeLimitMs, memoryLimitKb int) (*models.CanaryRun, error) {
+	return jp.judgeDetached(ctx, submission, &models.ShadowConfig{TimeLimitMs: &timeLimitMs, MemoryLimitKb: &memoryLimitKb})
+}
+
+// judgeDetached judges a submission as shadow judging would. The shadow
+// config also silences progress updates, which would otherwise reach the
+// submission's watchers.
+func (jp *JudgePool) judgeDetached(ctx context.Context, submission *models.Submission, shadow *models.ShadowConfig) (*models.CanaryRun, error) {
+	jp.mutex.RLock()
+	if len(jp.workers) == 0 {
+		jp.mutex.RUnlock()
+		return nil, fmt.Errorf("no judge workers available")
+	}
+	jw := jp.workers[0]
+	jp.mutex.RUnlock()
+
+	request := &models.JudgeRequest{
+		SubmissionID:  submission.ID,
+		UserID:        submission.UserID,
+		ContestID:     submission.ContestID,
+		ProblemID:     submission.ProblemID,
+		Language:      submission.Language,
+		CodeURL:       submission.CodeURL,
+		TimeLimitMs:   2000,
+		MemoryLimitKb: 262144,
+	}
+
+	ctx = storage.WithContestScope(ctx, request.ContestID)
+	code, err := jw.storage.DownloadCode(ctx, request.CodeURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download code: %w", err)
+	}
+
+	setup, err := jw.getJudgingSetup(ctx, request.ProblemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test cases: %w", err)
+	}
+
+	var runOptions sandbox.RunOptions
+	if jw.dataFiles != nil {
+		runOptions.DataDir, err = jw.dataFiles.Prepare(ctx, setup.dataFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare supplementary data: %w", err)
+		}
+		runOptions.CheckerDataDir, err = jw.prepareCheckerData(ctx, setup, runOptions.DataDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare checker data: %w", err)
+		}
+	}
+
+	run := &models.CanaryRun{Node: nodeName()}
+
+	var compileResult *sandbox.CompileResult
+	if len(setup.graders) > 0 {
+		compileResult, err = compileWithGrader(ctx, jw.sandbox, jw.storage, nil, request.Language, code, setup.graders, 30*time.Second)
+	} else {
+		compileResult, err = jw.sandbox.CompileWith(ctx, nil, request.Language, code, 30*time.Second)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("compilation error: %w", err)
+	}
+	if !compileResult.Success {
+		run.Verdict = models.VerdictCompile
+		return run, nil
+	}
+	runOptions.BuildDir, err = jw.sandbox.PrepareBuild(compileResult.Artifact)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare build: %w", err)
+	}
+	defer jw.sandbox.ReleaseBuild(runOptions.BuildDir)
+
+	order := executionOrder(len(setup.testCases), request.SubmissionID, setup.randomizeOrder)
+	result, err := jw.runTests(ctx, request, compileResult.EntryPoint, nil, setup.testCases, order, setup.judgingPolicy, runOptions, shadow)
+	if err != nil {
+		return nil, err
+	}
+
+	run.Verdict = result.verdict
+	run.MaxTimeMs = result.maxTime
+	run.TestsPassed = result.passed
+	return run, nil
+}
@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"execution_service/internal/httpclient"
+	"execution_service/internal/models"
+	"execution_service/internal/sandbox"
+	"execution_service/internal/storage"
+)
+
+// compileWithGrader links a function-only submission against the problem's
+// grader for its language. A language without a grader fails to compile
+// like any other unbuildable submission.
+func compileWithGrader(ctx context.Context, sb *sandbox.IsolateSandbox, store *storage.MinIOClient, env *models.SandboxEnvironment, language string, code []byte, graders map[string]httpclient.GraderResponse, timeLimit time.Duration) (*sandbox.CompileResult, error) {
+	grader, ok := graders[language]
+	if !ok {
+		return &sandbox.CompileResult{Error: fmt.Sprintf("This problem has no grader for %s", language)}, nil
+	}
+
+	source, err := store.DownloadCode(ctx, grader.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download grader: %w", err)
+	}
+	return sb.CompileWithGrader(ctx, env, language, source, grader.SolutionFile, code, timeLimit)
+}
@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"execution_service/internal/checker"
+)
+
+// ValidateInputs runs a problem's input validator over every test input.
+// validatorURL overrides the validator configured on the problem.
+func (jp *JudgePool) ValidateInputs(ctx context.Context, problemID int64, validatorURL string) (*checker.ValidationReport, error) {
+	problem, err := jp.contentClient.GetProblem(ctx, problemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test cases: %w", err)
+	}
+	if validatorURL == "" {
+		validatorURL = problem.ValidatorURL
+	}
+	if validatorURL == "" {
+		return nil, fmt.Errorf("problem has no input validator")
+	}
+	if len(problem.TestCases) == 0 {
+		return nil, fmt.Errorf("problem has no tests")
+	}
+
+	inputs := make([]checker.ValidatorInput, 0, len(problem.TestCases))
+	for i, tc := range problem.TestCases {
+		input, err := jp.storage.DownloadCode(ctx, tc.InputURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download test input: %w", err)
+		}
+		inputs = append(inputs, checker.ValidatorInput{
+			TestCaseID: tc.ID,
+			TestNumber: i + 1,
+			Input:      input,
+		})
+	}
+
+	return jp.customChecker.ValidateInputs(ctx, validatorURL, inputs)
+}
@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"execution_service/internal/models"
+)
+
+// partialJudging collects the tests of the submission a worker is judging as
+// they finish, so a shutdown that cannot wait for the rest can save them.
+type partialJudging struct {
+	submissionID   int64
+	testsetVersion string
+	// resumed holds the tests an interrupted run finished, by test number
+	resumed map[int]models.InterruptedTest
+	mu      sync.Mutex
+	tests   models.InterruptedTests
+}
+
+// beginPartial starts tracking a judging, picking up the tests an earlier
+// run finished if it was interrupted on the same testset.
+func (jw *JudgeWorker) beginPartial(ctx context.Context, submissionID int64, testsetVersion string) {
+	partial := &partialJudging{submissionID: submissionID, testsetVersion: testsetVersion}
+
+	saved, err := jw.db.GetInterruptedJudging(ctx, submissionID)
+	if err != nil {
+		jw.logWarn(submissionID, fmt.Sprintf("Failed to load interrupted judging, starting over: %v", err))
+	} else if saved != nil && saved.TestsetVersion == testsetVersion {
+		partial.resumed = make(map[int]models.InterruptedTest, len(saved.Tests))
+		for _, test := range saved.Tests {
+			partial.resumed[test.Result.TestNumber] = test
+		}
+		jw.logInfo(submissionID, fmt.Sprintf("Resuming interrupted judging after %d finished tests", len(saved.Tests)))
+	}
+
+	jw.mutex.Lock()
+	jw.partial = partial
+	jw.mutex.Unlock()
+}
+
+func (jw *JudgeWorker) endPartial() {
+	jw.mutex.Lock()
+	jw.partial = nil
+	jw.mutex.Unlock()
+}
+
+func (jw *JudgeWorker) currentPartial() *partialJudging {
+	jw.mutex.RLock()
+	defer jw.mutex.RUnlock()
+	return jw.partial
+}
+
+// resumedTest returns the outcome an interrupted run saved for the test.
+func (jw *JudgeWorker) resumedTest(testNumber int) (models.InterruptedTest, bool) {
+	partial := jw.currentPartial()
+	if partial == nil {
+		return models.InterruptedTest{}, false
+	}
+	test, ok := partial.resumed[testNumber]
+	return test, ok
+}
+
+func (jw *JudgeWorker) recordPartial(result models.SubmissionTestResult, credit float64) {
+	partial := jw.currentPartial()
+	if partial == nil {
+		return
+	}
+	partial.mu.Lock()
+	partial.tests = append(partial.tests, models.InterruptedTest{Result: result, Credit: credit})
+	partial.mu.Unlock()
+}
+
+// saveInterrupted stores the finished tests of the judging in progress, if
+// any, for whichever worker receives the submission next.
+func (jw *JudgeWorker) saveInterrupted(ctx context.Context) {
+	partial := jw.currentPartial()
+	if partial == nil {
+		return
+	}
+
+	partial.mu.Lock()
+	judging := &models.InterruptedJudging{
+		SubmissionID:   partial.submissionID,
+		TestsetVersion: partial.testsetVersion,
+		Tests:          append(models.InterruptedTests(nil), partial.tests...),
+	}
+	partial.mu.Unlock()
+	if len(judging.Tests) == 0 {
+		return
+	}
+
+	if err := jw.db.SaveInterruptedJudging(ctx, judging); err != nil {
+		log.Printf("Worker %d failed to save interrupted judging of submission %d: %v", jw.id, judging.SubmissionID, err)
+		return
+	}
+	jw.logWarn(judging.SubmissionID, fmt.Sprintf("Judging interrupted by shutdown after %d tests", len(judging.Tests)))
+}
+
+// clearInterrupted drops the saved tests of a judging that has completed, so
+// a later rejudge starts over.
+func (jw *JudgeWorker) clearInterrupted(ctx context.Context, submissionID int64) {
+	if err := jw.db.DeleteInterruptedJudging(ctx, submissionID); err != nil {
+		log.Printf("Failed to clear interrupted judging of submission %d: %v", submissionID, err)
+	}
+}
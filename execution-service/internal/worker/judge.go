@@ -2,13 +2,21 @@ package worker
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"math/rand"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"execution_service/internal/checker"
+	"execution_service/internal/config"
 	"execution_service/internal/database"
 	"execution_service/internal/httpclient"
 	"execution_service/internal/models"
@@ -21,6 +29,19 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+var errNoTestCases = errors.New("problem has no test cases")
+
+// errCodeRejected means static validation finished the submission as a
+// compilation error, so the message is done rather than failed.
+var errCodeRejected = errors.New("code rejected by validation")
+
+// Project archives were checked against the intake limits when submitted;
+// these only bound what a worker will unpack.
+const (
+	projectMaxFiles = 1000
+	projectMaxBytes = 64 << 20
+)
+
 type JudgeWorker struct {
 	id                  int
 	db                  *database.DB
@@ -32,9 +53,35 @@ type JudgeWorker struct {
 	resourceValidator   *services.ResourceValidationService
 	circuitBreaker      *services.CircuitBreakerService
 	plagiarismEnqueuer  func(submissionID, userID, problemID int64, language, codeURL string)
+	metrics             *services.MetricsService
+	checkerBudget       time.Duration
+	stderrVisibility    models.StderrVisibility
+	usageReport         models.UsageReport
+	seedEnvVar          string
+	seedSecret          []byte
+	tleRetry            config.TLERetryConfig
+	waitTracker         *waitTracker
+	diskWatcher         *services.DiskWatcherService
+	contentClient       *httpclient.ContentServiceClient
+	verdictSigner       *services.VerdictSigningService
+	environments        *services.ContestEnvironmentService
+	dataFiles           *services.SupplementaryDataService
+	testParallelism     int
+	artifacts           *services.ArtifactCacheService
+	shadow              *services.ShadowJudgingService
+	timelines           *services.AttemptTimelineService
+	scoring             *services.ScoringService
+	logs                *services.ExecutionLogService
+	deadLetters         *services.DeadLetterQueueService
+	maxAttempts         int
+	progress            func(ctx context.Context, progress *models.SubmissionProgress) error
+	scheduler           *scheduler
 	currentJob          *models.JudgeRequest
+	partial             *partialJudging
+	currentQueuedAt     time.Time
 	isProcessing        bool
 	workerID            int64
+	name                string
 	lastHeartbeat       time.Time
 	failureCount        int
 	maxFailures         int
@@ -51,6 +98,32 @@ type JudgePool struct {
 	storage             *storage.MinIOClient
 	sandbox             *sandbox.IsolateSandbox
 	customChecker       *checker.CustomChecker
+	validator           *validation.CodeValidator
+	resourceValidator   *services.ResourceValidationService
+	contentClient       *httpclient.ContentServiceClient
+	metrics             *services.MetricsService
+	plagiarismEnqueuer  func(submissionID, userID, problemID int64, language, codeURL string)
+	checkerBudget       time.Duration
+	stderrVisibility    models.StderrVisibility
+	usageReport         models.UsageReport
+	seedEnvVar          string
+	seedSecret          []byte
+	tleRetry            config.TLERetryConfig
+	waitTracker         *waitTracker
+	diskWatcher         *services.DiskWatcherService
+	verdictSigner       *services.VerdictSigningService
+	environments        *services.ContestEnvironmentService
+	dataFiles           *services.SupplementaryDataService
+	testParallelism     int
+	artifacts           *services.ArtifactCacheService
+	shadow              *services.ShadowJudgingService
+	timelines           *services.AttemptTimelineService
+	scoring             *services.ScoringService
+	logs                *services.ExecutionLogService
+	deadLetters         *services.DeadLetterQueueService
+	maxAttempts         int
+	progress            func(ctx context.Context, progress *models.SubmissionProgress) error
+	scheduler           *scheduler
 	workerCount         int
 	minWorkers          int
 	maxWorkers          int
@@ -61,6 +134,9 @@ type JudgePool struct {
 	shutdownTimeout     time.Duration
 	isRunning           bool
 	autoScalingEnabled  bool
+	autoScaleDryRun     bool
+	drained             bool
+	scaleListener       func(workerCount int)
 	mutex               sync.RWMutex
 }
 
@@ -68,15 +144,23 @@ func NewJudgePool(workerCount int, db *database.DB, q *queue.RabbitMQClient, s *
 	// Initialize advanced code validator
 	validatorConfig := validation.NewCodeValidator(&validation.ValidationConfig{}).GetDefaultConfig()
 	validator := validation.NewCodeValidator(validatorConfig)
+	validator.AllowExtensions(sb.Extensions()...)
 
 	// Initialize custom checker
 	checkerConfig := checker.NewCustomChecker(nil, nil, nil).GetDefaultConfig()
 	customChecker := checker.NewCustomChecker(sb, s, checkerConfig)
 
+	tracker := newWaitTracker(200)
+	contentClient := httpclient.NewContentServiceClient("http://localhost:3002")
+
+	pool := &JudgePool{}
+	sched := newScheduler(q, pool.currentWorkerCount, pool.paused)
+
 	workers := make([]*JudgeWorker, workerCount)
 	for i := 0; i < workerCount; i++ {
 		worker := &JudgeWorker{
 			id:                  i + 1,
+			name:                workerName(i + 1),
 			db:                  db,
 			queue:               q,
 			storage:             s,
@@ -85,6 +169,9 @@ func NewJudgePool(workerCount int, db *database.DB, q *queue.RabbitMQClient, s *
 			customChecker:       customChecker,
 			resourceValidator:   resourceValidator,
 			circuitBreaker:      services.NewCircuitBreakerService(),
+			waitTracker:         tracker,
+			contentClient:       contentClient,
+			scheduler:           sched,
 			maxFailures:         3,
 			healthCheckInterval: 30 * time.Second,
 			recoveryInterval:    60 * time.Second,
@@ -92,28 +179,25 @@ func NewJudgePool(workerCount int, db *database.DB, q *queue.RabbitMQClient, s *
 			lastHeartbeat:       time.Now(),
 		}
 
-		workerModel := &models.JudgeWorker{
-			WorkerName: fmt.Sprintf("judge-worker-%d", i+1),
-			Status:     "idle",
-		}
-
-		if err := db.CreateJudgeWorker(context.Background(), workerModel); err != nil {
-			log.Printf("Failed to create worker record: %v", err)
-			worker.workerID = int64(i + 1)
-		} else {
-			worker.workerID = int64(workerModel.ID)
+		if err := worker.register(context.Background()); err != nil {
+			log.Printf("Failed to register worker %s: %v", worker.name, err)
 		}
 
 		workers[i] = worker
 	}
 
-	return &JudgePool{
+	*pool = JudgePool{
 		workers:             workers,
 		db:                  db,
 		queue:               q,
 		storage:             s,
 		sandbox:             sb,
 		customChecker:       customChecker,
+		validator:           validator,
+		resourceValidator:   resourceValidator,
+		contentClient:       contentClient,
+		waitTracker:         tracker,
+		scheduler:           sched,
 		workerCount:         workerCount,
 		minWorkers:          2,
 		maxWorkers:          20,
@@ -124,6 +208,51 @@ func NewJudgePool(workerCount int, db *database.DB, q *queue.RabbitMQClient, s *
 		shutdownTimeout:     30 * time.Second,
 		autoScalingEnabled:  true,
 	}
+	return pool
+}
+
+func (jp *JudgePool) currentWorkerCount() int {
+	jp.mutex.RLock()
+	defer jp.mutex.RUnlock()
+	return len(jp.workers)
+}
+
+func (jp *JudgePool) diskCritical() bool {
+	return jp.diskWatcher != nil && jp.diskWatcher.IsCritical()
+}
+
+// paused leaves new submissions to other nodes while local disk is nearly
+// full or the node is drained.
+func (jp *JudgePool) paused() bool {
+	return jp.diskCritical() || jp.IsDrained()
+}
+
+// SetDrained stops or resumes taking submissions from the queue. Jobs
+// already buffered or running still finish.
+func (jp *JudgePool) SetDrained(drained bool) {
+	jp.mutex.Lock()
+	defer jp.mutex.Unlock()
+	jp.drained = drained
+}
+
+func (jp *JudgePool) IsDrained() bool {
+	jp.mutex.RLock()
+	defer jp.mutex.RUnlock()
+	return jp.drained
+}
+
+// SetSchedulerLimits sizes the in-memory scheduling buffer and reserves
+// workers for submissions at or above reservedPriority. Call before Start.
+func (jp *JudgePool) SetSchedulerLimits(bufferSize, reservedWorkers, reservedPriority int) {
+	jp.scheduler.capacity = bufferSize
+	jp.scheduler.reservedWorkers = reservedWorkers
+	jp.scheduler.reservedPriority = reservedPriority
+}
+
+// SetContestWorkers keeps workers free for contest submissions, so contest
+// judging latency holds up under a practice backlog.
+func (jp *JudgePool) SetContestWorkers(contestWorkers int) {
+	jp.scheduler.contestWorkers = contestWorkers
 }
 
 func (jp *JudgePool) Start(ctx context.Context) error {
@@ -143,11 +272,21 @@ func (jp *JudgePool) Start(ctx context.Context) error {
 	// Start heartbeat reporter
 	go jp.heartbeatReporter(ctx)
 
+	jp.reapStaleWorkers(ctx)
+	go jp.leaseReaper(ctx)
+
 	// Start auto-scaling if enabled
 	if jp.autoScalingEnabled {
 		go jp.autoScaler(ctx)
 	}
 
+	// Buffered and running submissions both stay unacknowledged, so the
+	// broker must allow that many deliveries in flight
+	if err := jp.queue.SetPrefetch(jp.scheduler.capacity + jp.maxWorkers); err != nil {
+		log.Printf("Failed to raise prefetch for scheduler: %v", err)
+	}
+	go jp.scheduler.run(ctx)
+
 	// Start all workers
 	for _, worker := range jp.workers {
 		go worker.start(ctx)
@@ -164,32 +303,28 @@ func (jw *JudgeWorker) start(ctx context.Context) {
 	defer cancelHeartbeat()
 	go jw.heartbeatLoop(heartbeatCtx)
 
-	msgs, err := jw.queue.ConsumeSubmissions(ctx)
-	if err != nil {
-		log.Printf("Worker %d failed to start consuming: %v", jw.id, err)
-		jw.markUnhealthy()
-		return
-	}
-
 	for {
-		select {
-		case <-ctx.Done():
-			log.Printf("Worker %d shutting down", jw.id)
-			return
-		case msg := <-msgs:
-			jw.mutex.RLock()
-			isProcessing := jw.isProcessing
-			isHealthy := jw.isHealthy
-			jw.mutex.RUnlock()
-
-			if isProcessing || !isHealthy {
-				log.Printf("Worker %d is busy or unhealthy, rejecting message", jw.id)
-				jw.queue.RejectMessage(msg, true)
-				continue
+		jw.mutex.RLock()
+		isHealthy := jw.isHealthy
+		jw.mutex.RUnlock()
+
+		// Unhealthy workers stop pulling until the health monitor recovers them
+		if !isHealthy {
+			if !sleepContext(ctx, time.Second) {
+				log.Printf("Worker %d shutting down", jw.id)
+				return
 			}
+			continue
+		}
 
-			jw.processMessage(ctx, msg)
+		job, err := jw.scheduler.next(ctx)
+		if err != nil {
+			log.Printf("Worker %d shutting down", jw.id)
+			return
 		}
+
+		jw.processMessage(ctx, job.msg)
+		jw.scheduler.done(job)
 	}
 }
 
@@ -204,15 +339,15 @@ func (jw *JudgeWorker) processMessage(ctx context.Context, msg amqp.Delivery) {
 		jw.currentJob = nil
 		jw.mutex.Unlock()
 
-		if jw.workerID > 0 {
-			jw.db.UpdateWorkerStatus(ctx, int(jw.workerID), "idle", nil)
+		if workerID := jw.registeredID(); workerID > 0 {
+			jw.db.UpdateWorkerStatus(ctx, int(workerID), "idle", nil)
 		}
 
 		// Update heartbeat after processing
 		jw.updateHeartbeat()
 	}()
 
-	request, err := queue.ParseJudgeRequest(msg)
+	request, err := jw.queue.ParseJudgeRequest(msg)
 	if err != nil {
 		log.Printf("Worker %d failed to parse message: %v", jw.id, err)
 		jw.markUnhealthy()
@@ -220,40 +355,102 @@ func (jw *JudgeWorker) processMessage(ctx context.Context, msg amqp.Delivery) {
 		return
 	}
 
+	if !msg.Timestamp.IsZero() && jw.waitTracker != nil {
+		jw.waitTracker.record(time.Since(msg.Timestamp))
+	}
+
+	jw.mutex.Lock()
 	jw.currentJob = request
-	if jw.workerID > 0 {
-		jw.db.UpdateWorkerStatus(ctx, int(jw.workerID), "busy", &request.SubmissionID)
+	jw.currentQueuedAt = queuedAt(msg, time.Now())
+	jw.mutex.Unlock()
+	if workerID := jw.registeredID(); workerID > 0 {
+		jw.db.UpdateWorkerStatus(ctx, int(workerID), "busy", &request.SubmissionID)
 	}
 	log.Printf("Worker %d processing submission %d", jw.id, request.SubmissionID)
 
 	err = jw.processSubmission(ctx, request)
+	if errors.Is(err, services.ErrEnvironmentUnavailable) {
+		// Back off so a node with the pinned toolchain picks the message up
+		log.Printf("ALERT: worker %d cannot judge submission %d: contest %d is pinned to a toolchain this node lacks",
+			jw.id, request.SubmissionID, *request.ContestID)
+		jw.queue.RejectMessage(msg, true)
+		sleepContext(ctx, 5*time.Second)
+		return
+	}
+	if errors.Is(err, errCodeRejected) {
+		log.Printf("Worker %d rejected submission %d: %v", jw.id, request.SubmissionID, err)
+		err = nil
+	}
 	if err != nil {
 		log.Printf("Worker %d failed to process submission %d: %v", jw.id, request.SubmissionID, err)
 		jw.logError(request.SubmissionID, fmt.Sprintf("Processing failed: %v", err))
-		jw.queue.RejectMessage(msg, true)
+		jw.retryOrPark(ctx, msg, request, err)
 		return
 	}
 
 	jw.queue.AcknowledgeMessage(msg)
+	if jw.timelines != nil {
+		jw.timelines.Invalidate(ctx, request.UserID, request.ProblemID)
+	}
+	if jw.scoring != nil {
+		jw.scoring.Record(ctx, request.UserID, request.ProblemID)
+	}
+	jw.publishProgress(ctx, &models.SubmissionProgress{SubmissionID: request.SubmissionID, Stage: models.ProgressCompleted})
 	log.Printf("Worker %d completed submission %d", jw.id, request.SubmissionID)
 }
 
-func (jw *JudgeWorker) processSubmission(ctx context.Context, request *models.JudgeRequest) error {
-	// Use circuit breaker for storage operations
-	var code []byte
-	_, err := jw.circuitBreaker.Execute("minio", func() (interface{}, error) {
-		downloadedCode, downloadErr := jw.storage.DownloadCode(ctx, request.CodeURL)
-		code = downloadedCode
-		return nil, downloadErr
-	})
-	if err != nil {
-		return fmt.Errorf("failed to download code (circuit breaker open): %w", err)
+// retryOrPark publishes a submission that failed to process again with the
+// attempt recorded, so a message that always fails cannot loop forever. Once
+// maxAttempts is reached the submission is parked in the dead letter queue
+// with the last error and finished as an internal error. Without a dead
+// letter queue the message is simply requeued.
+func (jw *JudgeWorker) retryOrPark(ctx context.Context, msg amqp.Delivery, request *models.JudgeRequest, cause error) {
+	if jw.deadLetters == nil {
+		jw.queue.RejectMessage(msg, true)
+		return
 	}
 
+	history := queue.DeliveryFailures(msg)
+	history.Record(cause, time.Now())
+	if history.Attempts < jw.maxAttempts {
+		if err := jw.queue.RetrySubmission(ctx, request, history); err != nil {
+			log.Printf("Failed to retry submission %d: %v", request.SubmissionID, err)
+			jw.queue.RejectMessage(msg, true)
+			return
+		}
+		jw.queue.AcknowledgeMessage(msg)
+		return
+	}
+
+	parked := &services.RetryableSubmission{
+		JudgeRequest:  request,
+		RetryCount:    history.Attempts,
+		OriginalQueue: msg.RoutingKey,
+		LastError:     cause.Error(),
+		Errors:        history.Errors,
+		FirstFailed:   history.FirstFailed,
+		LastRetry:     time.Now(),
+	}
+	if err := jw.deadLetters.Park(ctx, parked); err != nil {
+		log.Printf("Failed to dead-letter submission %d: %v", request.SubmissionID, err)
+		jw.queue.RejectMessage(msg, true)
+		return
+	}
+	log.Printf("ALERT: submission %d moved to the dead letter queue after %d attempts: %v",
+		request.SubmissionID, history.Attempts, cause)
+
+	if err := jw.finishWithSystemError(ctx, request); err != nil {
+		log.Printf("Failed to mark dead-lettered submission %d as internal error: %v", request.SubmissionID, err)
+	}
+	jw.queue.AcknowledgeMessage(msg)
+}
+
+// validateCode runs the static code checks, finishing the submission as a
+// compilation error and returning errCodeRejected when they fail.
+func (jw *JudgeWorker) validateCode(ctx context.Context, request *models.JudgeRequest, code []byte, fileName string) error {
 	jw.logInfo(request.SubmissionID, "Starting advanced code validation")
 
-	// Advanced code validation
-	validationResult := jw.validator.ValidateCode(code, "code."+request.Language)
+	validationResult := jw.validator.ValidateCode(code, fileName)
 	if !validationResult.IsValid {
 		errorMsg := "Code validation failed: "
 		for _, violation := range validationResult.Violations {
@@ -267,52 +464,81 @@ func (jw *JudgeWorker) processSubmission(ctx context.Context, request *models.Ju
 		if err != nil {
 			return fmt.Errorf("failed to update compilation error: %w", err)
 		}
-		return fmt.Errorf("code validation failed: %s", errorMsg)
+		return fmt.Errorf("%w: %s", errCodeRejected, errorMsg)
 	}
 
 	// Log non-critical violations
 	for _, violation := range validationResult.Violations {
 		if violation.Severity != "critical" {
-			jw.logInfo(request.SubmissionID, fmt.Sprintf("Security warning: [%s] %s at line %d",
+			jw.logWarn(request.SubmissionID, fmt.Sprintf("Security warning: [%s] %s at line %d",
 				violation.Type, violation.Description, violation.Line))
 		}
 	}
+	return nil
+}
 
-	jw.logInfo(request.SubmissionID, "Starting compilation")
+func (jw *JudgeWorker) processSubmission(ctx context.Context, request *models.JudgeRequest) error {
+	ctx = storage.WithContestScope(ctx, request.ContestID)
 
-	// Use separate compilation time limit (30 seconds max)
-	compileTimeLimit := time.Duration(30) * time.Second
-	if time.Duration(request.TimeLimitMs)*time.Millisecond < compileTimeLimit {
-		compileTimeLimit = time.Duration(request.TimeLimitMs) * time.Millisecond
+	var env *models.SandboxEnvironment
+	if request.ContestID != nil && jw.environments != nil {
+		pinned, err := jw.environments.ForContest(ctx, *request.ContestID)
+		if err != nil {
+			return err
+		}
+		env = pinned
 	}
 
-	compileResult, err := jw.sandbox.Compile(ctx, request.Language, code, compileTimeLimit)
+	// Use circuit breaker for storage operations
+	var code []byte
+	_, err := jw.circuitBreaker.Execute("minio", func() (interface{}, error) {
+		downloadedCode, downloadErr := jw.storage.DownloadCode(ctx, request.CodeURL)
+		code = downloadedCode
+		return nil, downloadErr
+	})
 	if err != nil {
-		return fmt.Errorf("compilation error: %w", err)
+		return fmt.Errorf("failed to download code (circuit breaker open): %w", err)
 	}
 
-	if !compileResult.Success {
-		jw.logInfo(request.SubmissionID, fmt.Sprintf("Compilation failed: %s", compileResult.Error))
-		err := jw.db.UpdateSubmissionCompilationError(ctx, request.SubmissionID, compileResult.Error)
+	outputOnly := request.Language == models.LanguageOutputOnly
+	var project []sandbox.ArchiveFile
+	if request.EntryFile != "" {
+		project, err = sandbox.ReadArchive(code, projectMaxFiles, projectMaxBytes)
 		if err != nil {
-			return fmt.Errorf("failed to update compilation error: %w", err)
+			jw.logError(request.SubmissionID, fmt.Sprintf("Project archive unusable: %v", err))
+			return jw.finishWithSystemError(ctx, request)
 		}
+	}
 
-		eventData := map[string]any{
-			"submission_id": request.SubmissionID,
-			"language":      request.Language,
-			"error_message": compileResult.Error,
+	// Trusted setter submissions skip the static pattern checks; the sandbox
+	// still enforces its limits.
+	if request.Trusted {
+		jw.logInfo(request.SubmissionID, "Skipping static code validation for trusted submission")
+	} else if project != nil {
+		// Every file is checked as source, since headers and modules are
+		// compiled or loaded along with the entry file
+		for _, file := range project {
+			if err := jw.validateCode(ctx, request, file.Data, jw.sandbox.SourceFileName(request.Language)); err != nil {
+				return err
+			}
+		}
+	} else if !outputOnly {
+		if err := jw.validateCode(ctx, request, code, jw.sandbox.SourceFileName(request.Language)); err != nil {
+			return err
 		}
-		jw.queue.PublishEvent(ctx, "SubmissionCompilationFailed", eventData)
-		return nil
 	}
 
-	jw.logInfo(request.SubmissionID, "Compilation successful, starting execution")
-
-	testCases, err := jw.getTestCases(ctx, request.ProblemID)
+	setup, err := jw.getJudgingSetup(ctx, request.ProblemID)
+	if errors.Is(err, errNoTestCases) {
+		// Retrying cannot help, so finish the submission with a system error
+		jw.logError(request.SubmissionID, fmt.Sprintf("Problem %d has no test cases", request.ProblemID))
+		return jw.finishWithSystemError(ctx, request)
+	}
 	if err != nil {
+		// Returning the error requeues the submission for a later retry
 		return fmt.Errorf("failed to get test cases: %w", err)
 	}
+	testCases := setup.testCases
 
 	// Validate and normalize resource limits
 	limits, validationRes := jw.resourceValidator.ValidateAndNormalizeLimits(ctx, request.ProblemID, request.TimeLimitMs, request.MemoryLimitKb)
@@ -321,239 +547,1023 @@ func (jw *JudgeWorker) processSubmission(ctx context.Context, request *models.Ju
 		// Continue with normalized limits but log the violation
 	}
 
-	results := make([]models.SubmissionTestResult, 0, len(testCases))
-	finalVerdict := models.VerdictAccepted
-	maxTime := 0
-	maxMemory := 0
-	passedCount := 0
+	testsetVersion := services.TestsetVersion(ctx, jw.storage, testCases)
+	jw.beginPartial(ctx, request.SubmissionID, testsetVersion)
+	defer jw.endPartial()
 
+	testTimeLimits := make([]int, len(testCases))
 	for i, testCase := range testCases {
-		jw.logInfo(request.SubmissionID, fmt.Sprintf("Running test case %d", i+1))
+		testTimeLimits[i] = testCase.TimeLimit
+	}
+	if budget := jw.resourceValidator.EstimateJudgingBudget(testTimeLimits, limits.TimeLimitMs); budget.Exceeded {
+		jw.logError(request.SubmissionID, fmt.Sprintf("Judging budget exceeded: %d tests need up to %dms, budget is %dms",
+			budget.TestCount, budget.EstimatedMs, budget.LimitMs))
+		jw.alertBudgetExceeded(ctx, request, budget)
+		return jw.finishWithSystemError(ctx, request)
+	}
 
-		input, err := jw.storage.DownloadCode(ctx, testCase.InputURL)
+	runOptions := sandbox.RunOptions{Environment: env}
+	if jw.dataFiles != nil {
+		runOptions.DataDir, err = jw.dataFiles.Prepare(ctx, setup.dataFiles)
+		if errors.Is(err, services.ErrInvalidSupplementaryData) || errors.Is(err, storage.ErrOutOfScope) {
+			jw.logError(request.SubmissionID, fmt.Sprintf("Problem %d supplementary data unusable: %v", request.ProblemID, err))
+			return jw.finishWithSystemError(ctx, request)
+		}
 		if err != nil {
-			return fmt.Errorf("failed to download test input: %w", err)
+			return fmt.Errorf("failed to prepare supplementary data: %w", err)
 		}
 
-		expectedOutput, err := jw.storage.DownloadCode(ctx, testCase.OutputURL)
+		runOptions.CheckerDataDir, err = jw.prepareCheckerData(ctx, setup, runOptions.DataDir)
+		if errors.Is(err, services.ErrInvalidSupplementaryData) || errors.Is(err, storage.ErrOutOfScope) {
+			jw.logError(request.SubmissionID, fmt.Sprintf("Problem %d checker data unusable: %v", request.ProblemID, err))
+			return jw.finishWithSystemError(ctx, request)
+		}
 		if err != nil {
-			return fmt.Errorf("failed to download test output: %w", err)
+			return fmt.Errorf("failed to prepare checker data: %w", err)
 		}
+	}
 
-		// Validate and normalize resource limits
-		limits, validationResult := jw.resourceValidator.ValidateAndNormalizeLimits(ctx, request.ProblemID, request.TimeLimitMs, request.MemoryLimitKb)
-		if !validationResult.IsValid {
-			jw.logError(request.SubmissionID, fmt.Sprintf("Resource validation failed: %v", validationResult.Violations))
-			// Continue with normalized limits but log the violation
+	var entryPoint string
+	var outputs map[int][]byte
+	if outputOnly {
+		if !setup.outputOnly {
+			err = fmt.Errorf("problem does not accept output-only submissions")
+		} else {
+			outputs, err = outputFiles(code, len(testCases))
+		}
+		if err != nil {
+			jw.logInfo(request.SubmissionID, fmt.Sprintf("Output archive rejected: %v", err))
+			return jw.finishCompileError(ctx, request, code, err.Error())
 		}
+	} else {
+		jw.logInfo(request.SubmissionID, "Starting compilation")
 
-		// Use per-test-case limits if available, otherwise fall back to problem limits
-		timeLimit := time.Duration(testCase.TimeLimit) * time.Millisecond
-		memoryLimit := testCase.MemoryLimit
+		// Use separate compilation time limit (30 seconds max)
+		compileTimeLimit := time.Duration(30) * time.Second
+		if time.Duration(request.TimeLimitMs)*time.Millisecond < compileTimeLimit {
+			compileTimeLimit = time.Duration(request.TimeLimitMs) * time.Millisecond
+		}
 
-		if timeLimit <= 0 {
-			timeLimit = time.Duration(limits.TimeLimitMs) * time.Millisecond
+		if len(setup.graders) > 0 && project != nil {
+			return jw.finishCompileError(ctx, request, code, "This problem takes a single source file linked against its grader")
+		}
+
+		jw.publishProgress(ctx, &models.SubmissionProgress{SubmissionID: request.SubmissionID, Stage: models.ProgressCompiling})
+		var compileResult *sandbox.CompileResult
+		var artifactKey string
+		// Builds against a grader are not cached, since the key cannot see
+		// the grader
+		if jw.artifacts != nil && len(setup.graders) == 0 {
+			artifactKey = jw.sandbox.ArtifactKey(env, request.Language, code, request.EntryFile)
+			compileResult = jw.artifacts.Get(ctx, artifactKey)
+		}
+		if compileResult != nil {
+			jw.logInfo(request.SubmissionID, "Reusing cached build")
+		} else {
+			switch {
+			case len(setup.graders) > 0:
+				compileResult, err = compileWithGrader(ctx, jw.sandbox, jw.storage, env, request.Language, code, setup.graders, compileTimeLimit)
+			case project != nil:
+				compileResult, err = jw.sandbox.CompileProjectWith(ctx, env, request.Language, project, request.EntryFile, compileTimeLimit)
+			default:
+				compileResult, err = jw.sandbox.CompileWith(ctx, env, request.Language, code, compileTimeLimit)
+			}
+			if err != nil {
+				return fmt.Errorf("compilation error: %w", err)
+			}
+			if jw.artifacts != nil {
+				jw.artifacts.Put(ctx, artifactKey, compileResult)
+			}
 		}
-		if memoryLimit <= 0 {
-			memoryLimit = limits.MemoryLimitKb
+
+		if !compileResult.Success {
+			jw.logInfo(request.SubmissionID, fmt.Sprintf("Compilation failed: %s", compileResult.Error))
+			return jw.finishCompileError(ctx, request, code, compileResult.Error)
 		}
 
-		execResult, err := jw.sandbox.Execute(ctx, request.Language, input, timeLimit, memoryLimit)
+		jw.logInfo(request.SubmissionID, "Compilation successful, starting execution")
+		runOptions.BuildDir, err = jw.sandbox.PrepareBuild(compileResult.Artifact)
 		if err != nil {
-			return fmt.Errorf("execution error: %w", err)
+			return fmt.Errorf("failed to prepare build: %w", err)
 		}
+		defer jw.sandbox.ReleaseBuild(runOptions.BuildDir)
+		entryPoint = compileResult.EntryPoint
+	}
+
+	order := executionOrder(len(testCases), request.SubmissionID, setup.randomizeOrder)
+	run, err := jw.runTests(ctx, request, entryPoint, outputs, testCases, order, setup.judgingPolicy, runOptions, nil)
+	if errors.Is(err, storage.ErrOutOfScope) {
+		// The problem points at another contest's files; retrying cannot help
+		jw.logError(request.SubmissionID, fmt.Sprintf("Problem %d test data unusable: %v", request.ProblemID, err))
+		return jw.finishWithSystemError(ctx, request)
+	}
+	if err != nil {
+		return err
+	}
+	for i := range run.results {
+		run.results[i].StderrVisible = setup.stderrVisibility.Allows(testCases[run.results[i].TestNumber-1].IsSample)
+	}
+
+	judgeResult := &models.JudgeResult{
+		SubmissionID:    request.SubmissionID,
+		UserID:          request.UserID,
+		ProblemID:       request.ProblemID,
+		TeamID:          request.TeamID,
+		ContestID:       request.ContestID,
+		Verdict:         run.verdict,
+		ExecutionTimeMs: run.maxTime,
+		MemoryUsedKb:    run.maxMemory,
+		TestCasesPassed: run.passed,
+		TestCasesTotal:  len(testCases),
+		Score:           run.score(len(testCases)),
+		TestsetVersion:  testsetVersion,
+		Metadata:        request.Metadata,
+	}
+
+	err = jw.db.UpdateSubmissionResult(ctx, request.SubmissionID, judgeResult)
+	if err != nil {
+		return fmt.Errorf("failed to update submission result: %w", err)
+	}
+
+	err = jw.db.CreateSubmissionTestResults(ctx, run.results)
+	if err != nil {
+		return fmt.Errorf("failed to create test results: %w", err)
+	}
+	jw.clearInterrupted(ctx, request.SubmissionID)
+
+	jw.signVerdict(ctx, request, code, run.verdict, judgeResult.Score)
 
-		if execResult.ExecutionTime > maxTime {
-			maxTime = execResult.ExecutionTime
+	jw.logInfo(request.SubmissionID, fmt.Sprintf("Judging completed: %s (%d/%d)", run.verdict, run.passed, len(testCases)))
+
+	// Log resource usage
+	jw.resourceValidator.LogResourceUsage(request.SubmissionID, limits, run.maxTime, run.maxMemory)
+
+	err = jw.queue.PublishEvent(ctx, "SubmissionJudged", judgeResult)
+	if err != nil {
+		return fmt.Errorf("failed to publish judged event: %w", err)
+	}
+
+	// Enqueue for plagiarism check if submission was accepted
+	if run.verdict == models.VerdictAccepted && jw.plagiarismEnqueuer != nil && !outputOnly {
+		jw.plagiarismEnqueuer(request.SubmissionID, request.UserID, request.ProblemID, request.Language, request.CodeURL)
+	}
+
+	jw.runShadow(ctx, request, entryPoint, outputs, testCases, order, setup.judgingPolicy, runOptions, run)
+
+	return nil
+}
+
+// runShadow judges the submission again under the problem's shadow config,
+// if any, and records the comparison. Failures are only logged: the live
+// result is already final.
+func (jw *JudgeWorker) runShadow(ctx context.Context, request *models.JudgeRequest, entryPoint string, outputs map[int][]byte, testCases []models.TestCase, order []int, policy models.JudgingPolicy, opts sandbox.RunOptions, live *testRun) {
+	if jw.shadow == nil {
+		return
+	}
+
+	config, err := jw.shadow.Active(ctx, request.ProblemID)
+	if err != nil {
+		jw.logError(request.SubmissionID, fmt.Sprintf("Failed to load shadow config: %v", err))
+		return
+	}
+	if config == nil {
+		return
+	}
+
+	shadowRun, err := jw.runTests(ctx, request, entryPoint, outputs, testCases, order, policy, opts, config)
+	if err != nil {
+		jw.logError(request.SubmissionID, fmt.Sprintf("Shadow judging failed: %v", err))
+		return
+	}
+
+	result := &models.ShadowResult{
+		ConfigID:      config.ID,
+		SubmissionID:  request.SubmissionID,
+		LiveVerdict:   live.verdict,
+		ShadowVerdict: shadowRun.verdict,
+		LivePassed:    live.passed,
+		ShadowPassed:  shadowRun.passed,
+		LiveTimeMs:    live.maxTime,
+		ShadowTimeMs:  shadowRun.maxTime,
+		Differences:   testDifferences(live.results, shadowRun.results),
+	}
+	if err := jw.shadow.Record(ctx, result); err != nil {
+		jw.logError(request.SubmissionID, fmt.Sprintf("Failed to record shadow result: %v", err))
+		return
+	}
+
+	if result.LiveVerdict != result.ShadowVerdict {
+		jw.logWarn(request.SubmissionID, fmt.Sprintf("Shadow verdict %s differs from live %s", result.ShadowVerdict, result.LiveVerdict))
+	}
+}
+
+func testDifferences(live, shadow []models.SubmissionTestResult) models.TestDifferences {
+	verdicts := make(map[int]*models.TestDifference)
+	var numbers []int
+	entry := func(testNumber int) *models.TestDifference {
+		if diff, ok := verdicts[testNumber]; ok {
+			return diff
 		}
-		if execResult.MemoryUsed > maxMemory {
-			maxMemory = execResult.MemoryUsed
+		diff := &models.TestDifference{TestNumber: testNumber}
+		verdicts[testNumber] = diff
+		numbers = append(numbers, testNumber)
+		return diff
+	}
+	for _, result := range live {
+		entry(result.TestNumber).LiveVerdict = result.Verdict
+	}
+	for _, result := range shadow {
+		entry(result.TestNumber).ShadowVerdict = result.Verdict
+	}
+
+	var differences models.TestDifferences
+	for _, testNumber := range numbers {
+		if diff := verdicts[testNumber]; diff.LiveVerdict != diff.ShadowVerdict {
+			differences = append(differences, *diff)
 		}
+	}
+	return differences
+}
 
-		testVerdict := execResult.Verdict
-		if testVerdict == models.VerdictAccepted {
-			// Check output using appropriate checker
-			isCorrect, _ := jw.checkOutput(testCase.InputURL, string(expectedOutput), execResult.Output, testCase.CheckerURL)
-			if !isCorrect {
-				testVerdict = models.VerdictWrongAns
-			} else {
+// testRun is the outcome of running a compiled submission against tests.
+type testRun struct {
+	results   []models.SubmissionTestResult
+	verdict   models.Verdict
+	maxTime   int
+	maxMemory int
+	passed    int
+	// credit sums each test's share of its points, from 0 to 1.
+	credit float64
+}
+
+// runTests executes the tests in order and checks each output, stopping or
+// skipping after failures as policy says. The verdict is the first failure
+// other than a wrong answer, if any. A shadow config replaces the checker and
+// limits it sets; shadow runs skip per-test progress logs.
+//
+// Up to testParallelism tests run at once in their own boxes. Outcomes are
+// merged in execution order as if the tests had run one by one, so tests
+// run past a stopping failure are discarded and the result does not depend
+// on parallelism. Output-only submissions pass their outputs instead of a
+// program to run.
+func (jw *JudgeWorker) runTests(ctx context.Context, request *models.JudgeRequest, entryPoint string, outputs map[int][]byte, testCases []models.TestCase, order []int, policy models.JudgingPolicy, opts sandbox.RunOptions, shadow *models.ShadowConfig) (*testRun, error) {
+	results := make([]models.SubmissionTestResult, 0, len(testCases))
+	finalVerdict := models.VerdictAccepted
+	maxTime := 0
+	maxMemory := 0
+	passedCount := 0
+	credit := 0.0
+	failedSubtasks := make(map[int]bool)
+	skipped := func(testCase models.TestCase) bool {
+		return policy == models.JudgingRunAllInSubtask && failedSubtasks[testCase.Subtask]
+	}
+
+	parallelism := max(jw.testParallelism, 1)
+	stopped := false
+	for start := 0; start < len(order) && !stopped; start += parallelism {
+		batch := order[start:min(start+parallelism, len(order))]
+
+		outcomes := make([]*testOutcome, len(batch))
+		var wg sync.WaitGroup
+		for k, i := range batch {
+			if skipped(testCases[i]) {
+				continue
+			}
+			if saved, ok := jw.resumedTest(i + 1); ok && shadow == nil {
+				// Visibility is not saved with the result
+				saved.Result.UsageVisible = saved.Result.Usage != nil && jw.usageReport == models.UsageReportUsers
+				outcomes[k] = &testOutcome{result: saved.Result, credit: saved.Credit}
+				continue
+			}
+			if shadow == nil {
+				jw.logDebug(request.SubmissionID, fmt.Sprintf("Running test case %d", i+1))
+				jw.publishProgress(ctx, &models.SubmissionProgress{
+					SubmissionID: request.SubmissionID,
+					Stage:        models.ProgressRunning,
+					TestNumber:   i + 1,
+					TestsDone:    start + k,
+					TestsTotal:   len(order),
+				})
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				outcomes[k] = jw.runTest(ctx, request, entryPoint, outputs, testCases[i], i+1, opts, shadow)
+			}()
+		}
+		wg.Wait()
+
+		for k, i := range batch {
+			outcome := outcomes[k]
+			// A failure earlier in the batch may have skipped this test's subtask
+			if outcome == nil || skipped(testCases[i]) {
+				continue
+			}
+			if outcome.err != nil {
+				return nil, outcome.err
+			}
+
+			result := outcome.result
+			maxTime = max(maxTime, *result.ExecutionTimeMs)
+			maxMemory = max(maxMemory, *result.MemoryUsedKb)
+			credit += outcome.credit
+			if result.Verdict == models.VerdictAccepted {
 				passedCount++
+			} else if finalVerdict == models.VerdictAccepted || finalVerdict == models.VerdictWrongAns {
+				finalVerdict = result.Verdict
+			}
+
+			results = append(results, result)
+			if shadow == nil {
+				jw.recordPartial(result, outcome.credit)
+				jw.publishProgress(ctx, &models.SubmissionProgress{
+					SubmissionID: request.SubmissionID,
+					Stage:        models.ProgressTested,
+					TestNumber:   i + 1,
+					TestVerdict:  result.Verdict,
+					TestsDone:    start + k + 1,
+					TestsTotal:   len(order),
+				})
+			}
+
+			if result.Verdict != models.VerdictAccepted {
+				if !policy.Continues(result.Verdict) {
+					stopped = true
+					break
+				}
+				failedSubtasks[testCases[i].Subtask] = true
 			}
 		}
+	}
+
+	// Report results in canonical order regardless of execution order
+	sort.Slice(results, func(a, b int) bool { return results[a].TestNumber < results[b].TestNumber })
+
+	return &testRun{
+		results:   results,
+		verdict:   finalVerdict,
+		maxTime:   maxTime,
+		maxMemory: maxMemory,
+		passed:    passedCount,
+		credit:    credit,
+	}, nil
+}
+
+// testOutcome is one test's judged result and its share of the points.
+type testOutcome struct {
+	result models.SubmissionTestResult
+	credit float64
+	err    error
+}
+
+// runTest executes and checks a single test. It only reads shared state, so
+// several can run at once.
+func (jw *JudgeWorker) runTest(ctx context.Context, request *models.JudgeRequest, entryPoint string, outputs map[int][]byte, testCase models.TestCase, testNumber int, opts sandbox.RunOptions, shadow *models.ShadowConfig) *testOutcome {
+	if shadow != nil && shadow.CheckerURL != nil {
+		testCase.CheckerURL = *shadow.CheckerURL
+	}
+
+	input, err := jw.storage.DownloadCode(ctx, testCase.InputURL)
+	if err != nil {
+		return &testOutcome{err: fmt.Errorf("failed to download test input: %w", err)}
+	}
+
+	expectedOutput, err := jw.storage.DownloadCode(ctx, testCase.OutputURL)
+	if err != nil {
+		return &testOutcome{err: fmt.Errorf("failed to download test output: %w", err)}
+	}
+
+	// Validate and normalize resource limits
+	limits, validationResult := jw.resourceValidator.ValidateAndNormalizeLimits(ctx, request.ProblemID, request.TimeLimitMs, request.MemoryLimitKb)
+	if !validationResult.IsValid {
+		jw.logError(request.SubmissionID, fmt.Sprintf("Resource validation failed: %v", validationResult.Violations))
+		// Continue with normalized limits but log the violation
+	}
+
+	// Use per-test-case limits if available, otherwise fall back to problem limits
+	timeLimit := time.Duration(testCase.TimeLimit) * time.Millisecond
+	memoryLimit := testCase.MemoryLimit
+
+	if timeLimit <= 0 {
+		timeLimit = time.Duration(limits.TimeLimitMs) * time.Millisecond
+	}
+	if memoryLimit <= 0 {
+		memoryLimit = limits.MemoryLimitKb
+	}
+	if shadow != nil && shadow.TimeLimitMs != nil {
+		timeLimit = time.Duration(*shadow.TimeLimitMs) * time.Millisecond
+	}
+	if shadow != nil && shadow.MemoryLimitKb != nil {
+		memoryLimit = *shadow.MemoryLimitKb
+	}
+	rawTimeLimitMs, rawMemoryLimitKb := int(timeLimit.Milliseconds()), memoryLimit
+	timeLimit, memoryLimit = jw.sandbox.ScaleLimits(request.Language, timeLimit, memoryLimit)
+	effectiveTimeLimitMs := int(timeLimit.Milliseconds())
+
+	var seed *int64
+	if jw.seedEnvVar != "" {
+		value := testSeed(jw.seedSecret, request.SubmissionID, testNumber)
+		seed = &value
+		opts.Env = []string{fmt.Sprintf("%s=%d", jw.seedEnvVar, value)}
+	}
 
-		if testVerdict != models.VerdictAccepted {
-			finalVerdict = testVerdict
+	var execResult *sandbox.ExecutionResult
+	var attempts models.TestAttempts
+	var interactive *sandbox.InteractiveResult
+	switch {
+	case outputs != nil:
+		execResult = submittedOutput(outputs, testNumber)
+	case testCase.InteractorURL != "":
+		interactive, err = jw.executeInteractive(ctx, opts, request, entryPoint, &testCase, input, expectedOutput, timeLimit, memoryLimit)
+		if err == nil {
+			execResult = interactive.ExecutionResult
 		}
+	default:
+		execResult, attempts, err = jw.executeWithRetry(ctx, opts, request, testNumber, entryPoint, input, timeLimit, memoryLimit)
+	}
+	if err != nil {
+		return &testOutcome{err: fmt.Errorf("execution error: %w", err)}
+	}
+
+	result := models.SubmissionTestResult{
+		SubmissionID:    request.SubmissionID,
+		TestCaseID:      testCase.ID,
+		TestNumber:      testNumber,
+		ExecutionTimeMs: &execResult.ExecutionTime,
+		MemoryUsedKb:    &execResult.MemoryUsed,
+		Attempts:        attempts,
+		IsSample:        testCase.IsSample,
+
+		TimeLimitMs:            &rawTimeLimitMs,
+		MemoryLimitKb:          &rawMemoryLimitKb,
+		EffectiveTimeLimitMs:   &effectiveTimeLimitMs,
+		EffectiveMemoryLimitKb: &memoryLimit,
+		Seed:                   seed,
+	}
+	if execResult.Stderr != "" {
+		result.Stderr = &execResult.Stderr
+	}
+	if jw.usageReport == models.UsageReportStaff || jw.usageReport == models.UsageReportUsers {
+		result.Usage = execResult.Usage
+		result.UsageVisible = jw.usageReport == models.UsageReportUsers
+	}
 
-		result := models.SubmissionTestResult{
-			SubmissionID:    request.SubmissionID,
-			TestCaseID:      testCase.ID,
-			TestNumber:      i + 1,
-			Verdict:         testVerdict,
-			ExecutionTimeMs: &execResult.ExecutionTime,
-			MemoryUsedKb:    &execResult.MemoryUsed,
+	outcome := &testOutcome{}
+	testVerdict := execResult.Verdict
+	if interactive != nil {
+		// The interactor has already judged the exchange
+		if testVerdict == models.VerdictAccepted {
+			outcome.credit = 1
 		}
+		if interactive.InteractorMessage != "" {
+			result.CheckerOutput = &interactive.InteractorMessage
+		}
+	} else if testVerdict == models.VerdictAccepted {
+		// Check output using appropriate checker
+		checkResult := jw.checkOutput(ctx, &testCase, &checker.CheckInput{
+			Input:    input,
+			Output:   execResult.Output,
+			Expected: string(expectedOutput),
+			DataDir:  opts.CheckerDataDir,
+		})
+		if testCase.CheckerURL != "" {
+			result.CheckerTimeMs = &checkResult.ExecutionTime
+			result.CheckerMemoryKb = &checkResult.MemoryUsed
+		}
+
+		switch {
+		case jw.checkerOverBudget(checkResult):
+			// A slow checker is a judging failure, not a wrong answer
+			testVerdict = models.VerdictInternal
+			jw.logError(request.SubmissionID, fmt.Sprintf("Checker exceeded budget on test %d: %dms", testNumber, checkResult.ExecutionTime))
+		case checkResult.Failed:
+			testVerdict = models.VerdictInternal
+			jw.logError(request.SubmissionID, fmt.Sprintf("Checker failed on test %d: %s", testNumber, checkResult.Message))
+		case !checkResult.IsCorrect:
+			testVerdict = models.VerdictWrongAns
+			outcome.credit = testCredit(checkResult.Score)
+		default:
+			outcome.credit = testCredit(checkResult.Score)
+		}
+
+		if checkResult.Message != "" {
+			result.CheckerOutput = &checkResult.Message
+		}
+	} else {
+		result.CheckerOutput = &execResult.Error
+	}
+
+	result.Verdict = testVerdict
+	outcome.result = result
+	return outcome
+}
+
+// finishCompileError records a compilation error, or a rejected output
+// archive, as the submission's final verdict.
+func (jw *JudgeWorker) finishCompileError(ctx context.Context, request *models.JudgeRequest, code []byte, message string) error {
+	err := jw.db.UpdateSubmissionCompilationError(ctx, request.SubmissionID, message)
+	if err != nil {
+		return fmt.Errorf("failed to update compilation error: %w", err)
+	}
+	jw.signVerdict(ctx, request, code, models.VerdictCompile, 0)
+
+	event := &models.CompilationFailedEvent{
+		SubmissionID: request.SubmissionID,
+		UserID:       request.UserID,
+		TeamID:       request.TeamID,
+		ContestID:    request.ContestID,
+		Language:     request.Language,
+		ErrorMessage: message,
+		Metadata:     request.Metadata,
+	}
+	jw.queue.PublishEvent(ctx, "SubmissionCompilationFailed", event)
+	return nil
+}
+
+// judgingSetup is the problem's judging configuration from the content service.
+type judgingSetup struct {
+	testCases        []models.TestCase
+	randomizeOrder   bool
+	dataFiles        []models.SupplementaryFile
+	checkerFiles     []models.SupplementaryFile
+	stderrVisibility models.StderrVisibility
+	judgingPolicy    models.JudgingPolicy
+	outputOnly       bool
+	graders          map[string]httpclient.GraderResponse
+}
+
+func (jw *JudgeWorker) getJudgingSetup(ctx context.Context, problemID int64) (*judgingSetup, error) {
+	// Use circuit breaker for content service calls
+	var problem *httpclient.ProblemResponse
+	_, err := jw.circuitBreaker.Execute("content-service", func() (interface{}, error) {
+		response, getErr := jw.contentClient.GetProblem(ctx, problemID)
+		problem = response
+		// A missing problem is a valid answer, not a service failure
+		if errors.Is(getErr, httpclient.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, getErr
+	})
+	if err != nil {
+		return nil, fmt.Errorf("content service unavailable: %w", err)
+	}
+
+	if problem == nil || len(problem.TestCases) == 0 {
+		return nil, errNoTestCases
+	}
+
+	testCases := make([]models.TestCase, len(problem.TestCases))
+	for i, tc := range problem.TestCases {
+		testCases[i] = models.TestCase{
+			ID:            tc.ID,
+			InputURL:      tc.InputURL,
+			OutputURL:     tc.OutputURL,
+			IsSample:      tc.IsSample,
+			TimeLimit:     tc.TimeLimit,
+			MemoryLimit:   tc.MemoryLimit,
+			InteractorURL: tc.InteractorURL,
+			CheckerURL:    tc.CheckerURL,
+			Subtask:       tc.Subtask,
+		}
+	}
+
+	stderrVisibility := models.StderrVisibility(problem.StderrVisibility)
+	if stderrVisibility == "" {
+		stderrVisibility = jw.stderrVisibility
+	}
+
+	return &judgingSetup{
+		testCases:        testCases,
+		randomizeOrder:   problem.RandomizeTestOrder,
+		dataFiles:        supplementaryFiles(problem.SupplementaryFiles),
+		checkerFiles:     supplementaryFiles(problem.CheckerFiles),
+		stderrVisibility: stderrVisibility,
+		judgingPolicy:    models.JudgingPolicy(problem.JudgingPolicy),
+		outputOnly:       problem.OutputOnly,
+		graders:          problem.Graders,
+	}, nil
+}
+
+func supplementaryFiles(responses []httpclient.SupplementaryFileResponse) []models.SupplementaryFile {
+	files := make([]models.SupplementaryFile, len(responses))
+	for i, file := range responses {
+		files[i] = models.SupplementaryFile{
+			Name:      file.Name,
+			URL:       file.URL,
+			SHA256:    file.SHA256,
+			SizeBytes: file.SizeBytes,
+		}
+	}
+	return files
+}
+
+// prepareCheckerData returns the directory custom checkers see: the
+// problem's data plus its judge-only checker files, or dataDir when it has
+// none.
+func (jw *JudgeWorker) prepareCheckerData(ctx context.Context, setup *judgingSetup, dataDir string) (string, error) {
+	if len(setup.checkerFiles) == 0 {
+		return dataDir, nil
+	}
+	return jw.dataFiles.Prepare(ctx, append(setup.checkerFiles, setup.dataFiles...))
+}
+
+// executionOrder returns test indices to run, shuffled deterministically by
+// submission ID when randomize is set so rejudges reproduce the same order.
+func executionOrder(count int, submissionID int64, randomize bool) []int {
+	order := make([]int, count)
+	for i := range order {
+		order[i] = i
+	}
+
+	if randomize {
+		rng := rand.New(rand.NewSource(submissionID))
+		rng.Shuffle(count, func(a, b int) { order[a], order[b] = order[b], order[a] })
+	}
+
+	return order
+}
+
+// signVerdict records a certificate for the final verdict; failures are
+// logged rather than failing a submission that was judged correctly.
+func (jw *JudgeWorker) signVerdict(ctx context.Context, request *models.JudgeRequest, code []byte, verdict models.Verdict, score models.Score) {
+	if jw.verdictSigner == nil {
+		return
+	}
+
+	certificate := &models.VerdictCertificate{
+		SubmissionID: request.SubmissionID,
+		CodeHash:     services.HashCode(code),
+		ProblemID:    request.ProblemID,
+		Verdict:      verdict,
+		Score:        score,
+		SignedAt:     time.Now(),
+	}
+	if _, err := jw.verdictSigner.Sign(ctx, certificate); err != nil {
+		jw.logError(request.SubmissionID, fmt.Sprintf("Failed to sign verdict: %v", err))
+	}
+}
+
+// testCredit clamps a checker's partial score to a single test's share.
+func testCredit(score float64) float64 {
+	return math.Max(0, math.Min(1, score))
+}
+
+// score is the percentage of the available credit earned, so a checker that
+// awards partial points contributes a fraction of a test.
+func (run *testRun) score(total int) models.Score {
+	if total == 0 {
+		return 0
+	}
+	return models.NewScore(run.credit * 100 / float64(total))
+}
+
+func (jw *JudgeWorker) alertBudgetExceeded(ctx context.Context, request *models.JudgeRequest, budget *services.JudgingBudget) {
+	log.Printf("ALERT: Problem %d exceeds judging budget (%d tests, %dms > %dms), submission %d refused",
+		request.ProblemID, budget.TestCount, budget.EstimatedMs, budget.LimitMs, request.SubmissionID)
+
+	event := &models.JudgingBudgetExceededEvent{
+		SubmissionID: request.SubmissionID,
+		ProblemID:    request.ProblemID,
+		ContestID:    request.ContestID,
+		TestCount:    budget.TestCount,
+		EstimatedMs:  budget.EstimatedMs,
+		BudgetMs:     budget.LimitMs,
+	}
+	if err := jw.queue.PublishEvent(ctx, "JudgingBudgetExceeded", event); err != nil {
+		log.Printf("Failed to publish judging budget alert: %v", err)
+	}
+}
+
+func (jw *JudgeWorker) finishWithSystemError(ctx context.Context, request *models.JudgeRequest) error {
+	judgeResult := &models.JudgeResult{
+		SubmissionID: request.SubmissionID,
+		UserID:       request.UserID,
+		ProblemID:    request.ProblemID,
+		TeamID:       request.TeamID,
+		ContestID:    request.ContestID,
+		Verdict:      models.VerdictInternal,
+		Metadata:     request.Metadata,
+	}
+
+	if err := jw.db.UpdateSubmissionResult(ctx, request.SubmissionID, judgeResult); err != nil {
+		return fmt.Errorf("failed to update submission result: %w", err)
+	}
+
+	if err := jw.queue.PublishEvent(ctx, "SubmissionJudged", judgeResult); err != nil {
+		return fmt.Errorf("failed to publish judged event: %w", err)
+	}
+
+	return nil
+}
+
+// publishProgress is best effort: a lost step only delays a stream until the
+// next one, and streams re-read the stored submission before closing.
+func (jw *JudgeWorker) publishProgress(ctx context.Context, progress *models.SubmissionProgress) {
+	if jw.progress == nil {
+		return
+	}
+	progress.Timestamp = time.Now()
+	if err := jw.progress(ctx, progress); err != nil {
+		log.Printf("Failed to publish progress for submission %d: %v", progress.SubmissionID, err)
+	}
+}
+
+func (jw *JudgeWorker) logDebug(submissionID int64, message string) {
+	jw.persistLog(submissionID, "DEBUG", message)
+}
 
-		// Store checker output if available
-		if testVerdict == models.VerdictAccepted {
-			_, checkerOutput := jw.checkOutput(testCase.InputURL, string(expectedOutput), execResult.Output, testCase.CheckerURL)
-			if checkerOutput != "" {
-				result.CheckerOutput = &checkerOutput
-			}
-		} else {
-			result.CheckerOutput = &execResult.Error
-		}
+func (jw *JudgeWorker) logInfo(submissionID int64, message string) {
+	log.Printf("[Submission %d] %s", submissionID, message)
+	jw.persistLog(submissionID, "INFO", message)
+}
 
-		results = append(results, result)
+func (jw *JudgeWorker) logWarn(submissionID int64, message string) {
+	log.Printf("[Submission %d] WARN: %s", submissionID, message)
+	jw.persistLog(submissionID, "WARN", message)
+}
 
-		if finalVerdict != models.VerdictAccepted && finalVerdict != models.VerdictWrongAns {
-			break
+// persistLog stores the entry, subject to sampling when configured.
+func (jw *JudgeWorker) persistLog(submissionID int64, level, message string) {
+	ctx := context.Background()
+	if jw.logs != nil {
+		jw.logs.Log(ctx, submissionID, level, message)
+		if contestID := jw.contestOf(submissionID); contestID != nil {
+			jw.logs.Tail(*contestID, submissionID, level, message)
 		}
+		return
 	}
+	jw.db.CreateExecutionLog(ctx, &models.ExecutionLog{
+		SubmissionID: submissionID,
+		Level:        level,
+		Message:      message,
+	})
+}
 
-	judgeResult := &models.JudgeResult{
-		SubmissionID:    request.SubmissionID,
-		Verdict:         finalVerdict,
-		ExecutionTimeMs: maxTime,
-		MemoryUsedKb:    maxMemory,
-		TestCasesPassed: passedCount,
-		TestCasesTotal:  len(testCases),
+func (jw *JudgeWorker) checkOutput(ctx context.Context, testCase *models.TestCase, in *checker.CheckInput) *checker.CheckerResult {
+	// If no custom checker, use exact string matching
+	if testCase.CheckerURL == "" {
+		return exactMatch(in.Expected, in.Output, "")
 	}
 
-	err = jw.db.UpdateSubmissionResult(ctx, request.SubmissionID, judgeResult)
+	checkerResult, err := jw.customChecker.ValidateOutput(ctx, testCase, in)
 	if err != nil {
-		return fmt.Errorf("failed to update submission result: %w", err)
+		jw.logError(0, fmt.Sprintf("Custom checker execution failed: %v", err))
+		// Fall back to exact matching if checker fails
+		return exactMatch(in.Expected, in.Output, "Custom checker failed, used exact matching")
 	}
 
-	err = jw.db.CreateSubmissionTestResults(ctx, results)
-	if err != nil {
-		return fmt.Errorf("failed to create test results: %w", err)
+	if jw.metrics != nil {
+		outcome := "ok"
+		if jw.checkerOverBudget(checkerResult) {
+			outcome = "over_budget"
+		}
+		jw.metrics.RecordCheckerUsage(outcome, float64(checkerResult.ExecutionTime), float64(checkerResult.MemoryUsed))
 	}
 
-	jw.logInfo(request.SubmissionID, fmt.Sprintf("Judging completed: %s (%d/%d)", finalVerdict, passedCount, len(testCases)))
+	return checkerResult
+}
 
-	// Log resource usage
-	jw.resourceValidator.LogResourceUsage(request.SubmissionID, limits, maxTime, maxMemory)
+func exactMatch(expectedOutput, actualOutput, message string) *checker.CheckerResult {
+	result := &checker.CheckerResult{
+		IsCorrect: strings.TrimSpace(expectedOutput) == strings.TrimSpace(actualOutput),
+		Message:   message,
+	}
+	if result.IsCorrect {
+		result.Score = 1
+	}
+	return result
+}
 
-	err = jw.queue.PublishEvent(ctx, "SubmissionJudged", judgeResult)
+// executeWithRetry runs a test and, when the TLE retry policy applies, re-runs
+// a borderline timeout until a run fits the limit. The best run counts; the
+// returned attempts record every run and are empty when there was no retry.
+func (jw *JudgeWorker) executeWithRetry(ctx context.Context, opts sandbox.RunOptions, request *models.JudgeRequest, testNumber int, entryPoint string, input []byte, timeLimit time.Duration, memoryLimit int) (*sandbox.ExecutionResult, models.TestAttempts, error) {
+	result, err := jw.sandbox.ExecuteWith(ctx, opts, request.Language, entryPoint, input, timeLimit, memoryLimit)
 	if err != nil {
-		return fmt.Errorf("failed to publish judged event: %w", err)
+		return nil, nil, err
 	}
 
-	// Enqueue for plagiarism check if submission was accepted
-	if finalVerdict == models.VerdictAccepted && jw.plagiarismEnqueuer != nil {
-		jw.plagiarismEnqueuer(request.SubmissionID, request.UserID, request.ProblemID, request.Language, request.CodeURL)
+	policy := jw.tleRetry.For(request.ContestID)
+	if !policy.Enabled || result.Verdict != models.VerdictTimeLim {
+		return result, nil, nil
+	}
+	if float64(effectiveTime(result)) > float64(timeLimit.Milliseconds())*(1+policy.MarginPercent/100) {
+		return result, nil, nil
 	}
 
-	return nil
+	attempts := models.TestAttempts{testAttempt(result)}
+	for n := 0; n < policy.MaxRetries && result.Verdict == models.VerdictTimeLim; n++ {
+		retry, err := jw.sandbox.ExecuteWith(ctx, opts, request.Language, entryPoint, input, timeLimit, memoryLimit)
+		if err != nil {
+			return nil, nil, err
+		}
+		attempts = append(attempts, testAttempt(retry))
+
+		if retry.Verdict != models.VerdictTimeLim || effectiveTime(retry) < effectiveTime(result) {
+			result = retry
+		}
+	}
+
+	jw.logInfo(request.SubmissionID, fmt.Sprintf("Test %d retried after borderline TLE: %d runs, counted %s in %dms",
+		testNumber, len(attempts), result.Verdict, effectiveTime(result)))
+	return result, attempts, nil
 }
 
-func (jw *JudgeWorker) getTestCases(ctx context.Context, problemID int64) ([]models.TestCase, error) {
-	// Use circuit breaker for content service calls
-	var testCaseResponses []httpclient.TestCaseResponse
-	_, err := jw.circuitBreaker.Execute("content-service", func() (interface{}, error) {
-		contentClient := httpclient.NewContentServiceClient("http://localhost:3002")
-		responses, getErr := contentClient.GetTestCases(ctx, problemID)
-		testCaseResponses = responses
-		return nil, getErr
-	})
+// executeInteractive runs one interactive test. A broken interactor is a
+// judging failure on that test, not a reason to requeue the submission.
+func (jw *JudgeWorker) executeInteractive(ctx context.Context, opts sandbox.RunOptions, request *models.JudgeRequest, entryPoint string, testCase *models.TestCase, input, answer []byte, timeLimit time.Duration, memoryLimit int) (*sandbox.InteractiveResult, error) {
+	language := jw.sandbox.LanguageForFile(testCase.InteractorURL)
+	if language == "" {
+		return interactorFailure(fmt.Sprintf("unsupported interactor language: %s", testCase.InteractorURL)), nil
+	}
 
+	code, err := jw.storage.DownloadCode(ctx, testCase.InteractorURL)
 	if err != nil {
-		jw.logError(problemID, fmt.Sprintf("Failed to get test cases from content service (circuit breaker open): %v", err))
-
-		testCases := []models.TestCase{
-			{
-				ID:          1,
-				InputURL:    fmt.Sprintf("s3://testcases/problems/%d/testcases/1/input.txt", problemID),
-				OutputURL:   fmt.Sprintf("s3://testcases/problems/%d/testcases/1/output.txt", problemID),
-				IsSample:    true,
-				TimeLimit:   2000,
-				MemoryLimit: 262144,
-			},
-		}
-		return testCases, nil
+		return nil, fmt.Errorf("failed to download interactor: %w", err)
 	}
 
-	testCases := make([]models.TestCase, len(testCaseResponses))
-	for i, tc := range testCaseResponses {
-		testCases[i] = models.TestCase{
-			ID:          tc.ID,
-			InputURL:    tc.InputURL,
-			OutputURL:   tc.OutputURL,
-			IsSample:    tc.IsSample,
-			TimeLimit:   tc.TimeLimit,
-			MemoryLimit: tc.MemoryLimit,
-		}
+	interactor := &sandbox.Interactor{Language: language, Code: code, Answer: answer}
+	result, err := jw.sandbox.ExecuteInteractive(ctx, opts, request.Language, entryPoint, interactor, input, timeLimit, memoryLimit)
+	if errors.Is(err, sandbox.ErrInteractorFailed) {
+		jw.logError(request.SubmissionID, err.Error())
+		return interactorFailure(err.Error()), nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return testCases, nil
+	if result.Verdict == models.VerdictInternal {
+		jw.logError(request.SubmissionID, fmt.Sprintf("Interactor exited with code %d: %s", result.InteractorExitCode, result.InteractorMessage))
+	}
+	return result, nil
 }
 
-func (jw *JudgeWorker) logInfo(submissionID int64, message string) {
-	log.Printf("[Submission %d] %s", submissionID, message)
-	ctx := context.Background()
-	jw.db.CreateExecutionLog(ctx, &models.ExecutionLog{
-		SubmissionID: submissionID,
-		Level:        "INFO",
-		Message:      message,
-	})
+func interactorFailure(message string) *sandbox.InteractiveResult {
+	return &sandbox.InteractiveResult{
+		ExecutionResult:    &sandbox.ExecutionResult{Verdict: models.VerdictInternal},
+		InteractorExitCode: -1,
+		InteractorMessage:  message,
+	}
 }
 
-func (jw *JudgeWorker) checkOutput(inputURL, expectedOutput, actualOutput, checkerURL string) (bool, string) {
-	// If no custom checker, use exact string matching
-	if checkerURL == "" {
-		expected := strings.TrimSpace(expectedOutput)
-		actual := strings.TrimSpace(actualOutput)
-		return expected == actual, ""
+func effectiveTime(result *sandbox.ExecutionResult) int {
+	if result.WallTime > 0 {
+		return result.WallTime
 	}
+	return result.ExecutionTime
+}
 
-	// Use custom checker for validation
-	ctx := context.Background()
-
-	// Create a test case model for the checker
-	testCase := &models.TestCase{
-		CheckerURL: checkerURL,
+func testAttempt(result *sandbox.ExecutionResult) models.TestAttempt {
+	return models.TestAttempt{
+		Verdict:         result.Verdict,
+		ExecutionTimeMs: result.ExecutionTime,
+		WallTimeMs:      result.WallTime,
 	}
+}
 
-	// Validate output using custom checker
-	checkerResult, err := jw.customChecker.ValidateOutput(ctx, testCase, actualOutput, expectedOutput)
-	if err != nil {
-		jw.logError(0, fmt.Sprintf("Custom checker execution failed: %v", err))
-		// Fall back to exact matching if checker fails
-		expected := strings.TrimSpace(expectedOutput)
-		actual := strings.TrimSpace(actualOutput)
-		return expected == actual, "Custom checker failed, used exact matching"
+func (jw *JudgeWorker) checkerOverBudget(result *checker.CheckerResult) bool {
+	if result.TimedOut {
+		return true
 	}
+	return jw.checkerBudget > 0 && time.Duration(result.ExecutionTime)*time.Millisecond > jw.checkerBudget
+}
 
-	return checkerResult.IsCorrect, checkerResult.Message
+// contestOf returns the contest of the submission being judged, if any.
+func (jw *JudgeWorker) contestOf(submissionID int64) *int64 {
+	jw.mutex.RLock()
+	defer jw.mutex.RUnlock()
+	if jw.currentJob == nil || jw.currentJob.SubmissionID != submissionID {
+		return nil
+	}
+	return jw.currentJob.ContestID
 }
 
 func (jw *JudgeWorker) logError(submissionID int64, message string) {
 	log.Printf("[Submission %d] ERROR: %s", submissionID, message)
-	ctx := context.Background()
-	jw.db.CreateExecutionLog(ctx, &models.ExecutionLog{
-		SubmissionID: submissionID,
-		Level:        "ERROR",
-		Message:      message,
-	})
+	jw.persistLog(submissionID, "ERROR", message)
 }
 
 func (jp *JudgePool) GetStatus() map[string]any {
+	jp.mutex.RLock()
+	workers := append([]*JudgeWorker(nil), jp.workers...)
+	workerCount := jp.workerCount
+	jp.mutex.RUnlock()
+
 	activeWorkers := 0
-	for _, worker := range jp.workers {
+	for _, worker := range workers {
+		worker.mutex.RLock()
 		if worker.isProcessing {
 			activeWorkers++
 		}
+		worker.mutex.RUnlock()
 	}
 
 	queueSize, _ := jp.queue.GetQueueInfo()
 
 	return map[string]any{
-		"total_workers":  jp.workerCount,
+		"total_workers":  workerCount,
 		"active_workers": activeWorkers,
 		"queue_size":     queueSize,
+		"buffered":       jp.scheduler.buffered(),
 		"is_healthy":     jp.queue.IsHealthy(),
+		"drained":        jp.IsDrained(),
+	}
+}
+
+// Load reports judge pressure for background work that competes with judging.
+func (jp *JudgePool) Load() (models.JudgeLoad, error) {
+	jp.mutex.RLock()
+	workers := append([]*JudgeWorker(nil), jp.workers...)
+	jp.mutex.RUnlock()
+
+	load := models.JudgeLoad{TotalWorkers: len(workers)}
+	for _, worker := range workers {
+		worker.mutex.RLock()
+		if worker.isProcessing {
+			load.ActiveWorkers++
+		}
+		worker.mutex.RUnlock()
+	}
+
+	queueSize, err := jp.queue.GetQueueInfo()
+	if err != nil {
+		return load, err
+	}
+	load.QueueDepth = queueSize + jp.scheduler.buffered()
+	return load, nil
+}
+
+// WorkerState is a point-in-time view of one worker for diagnostics.
+type WorkerState struct {
+	Name              string            `json:"name"`
+	WorkerID          int64             `json:"worker_id"`
+	Processing        bool              `json:"processing"`
+	CurrentSubmission *int64            `json:"current_submission,omitempty"`
+	Healthy           bool              `json:"healthy"`
+	FailureCount      int               `json:"failure_count"`
+	LastHeartbeat     time.Time         `json:"last_heartbeat"`
+	CircuitBreakers   map[string]string `json:"circuit_breakers"`
+}
+
+func (jp *JudgePool) WorkerStates() []WorkerState {
+	jp.mutex.RLock()
+	workers := append([]*JudgeWorker(nil), jp.workers...)
+	jp.mutex.RUnlock()
+
+	states := make([]WorkerState, 0, len(workers))
+	for _, worker := range workers {
+		worker.mutex.RLock()
+		state := WorkerState{
+			Name:            worker.name,
+			WorkerID:        worker.workerID,
+			Processing:      worker.isProcessing,
+			Healthy:         worker.isHealthy,
+			FailureCount:    worker.failureCount,
+			LastHeartbeat:   worker.lastHeartbeat,
+			CircuitBreakers: make(map[string]string),
+		}
+		if worker.currentJob != nil {
+			submissionID := worker.currentJob.SubmissionID
+			state.CurrentSubmission = &submissionID
+		}
+		worker.mutex.RUnlock()
+
+		for name, breakerState := range worker.circuitBreaker.GetStates() {
+			state.CircuitBreakers[name] = breakerState.String()
+		}
+		states = append(states, state)
+	}
+
+	return states
+}
+
+// QueueSnapshot captures what this node holds: the broker's depth, the
+// buffered submissions and the one each worker is running.
+func (jp *JudgePool) QueueSnapshot() (*models.QueueSnapshot, error) {
+	jp.mutex.RLock()
+	workers := append([]*JudgeWorker(nil), jp.workers...)
+	jp.mutex.RUnlock()
+
+	now := time.Now()
+	snapshot := &models.QueueSnapshot{
+		Node:    nodeName(),
+		Entries: jp.scheduler.snapshot(now),
+		TakenAt: now,
+	}
+	for _, worker := range workers {
+		worker.mutex.RLock()
+		if worker.currentJob != nil {
+			snapshot.Entries = append(snapshot.Entries, queueEntry(worker.currentJob, worker.currentQueuedAt, now, models.QueueEntryRunning))
+		}
+		worker.mutex.RUnlock()
 	}
+
+	queueSize, err := jp.queue.GetQueueInfo()
+	if err != nil {
+		return nil, err
+	}
+	snapshot.QueueDepth = queueSize
+	return snapshot, nil
 }
 
 func (jp *JudgePool) GetSandbox() *sandbox.IsolateSandbox {
@@ -583,10 +1593,39 @@ func (jp *JudgePool) ScaleWorkers(newWorkerCount int) error {
 		for i := currentCount; i < newWorkerCount; i++ {
 			worker := &JudgeWorker{
 				id:                  i + 1,
+				name:                workerName(i + 1),
 				db:                  jp.db,
 				queue:               jp.queue,
 				storage:             jp.storage,
 				sandbox:             jp.sandbox,
+				validator:           jp.validator,
+				customChecker:       jp.customChecker,
+				resourceValidator:   jp.resourceValidator,
+				circuitBreaker:      services.NewCircuitBreakerService(),
+				plagiarismEnqueuer:  jp.plagiarismEnqueuer,
+				metrics:             jp.metrics,
+				checkerBudget:       jp.checkerBudget,
+				stderrVisibility:    jp.stderrVisibility,
+				usageReport:         jp.usageReport,
+				seedEnvVar:          jp.seedEnvVar,
+				seedSecret:          jp.seedSecret,
+				tleRetry:            jp.tleRetry,
+				waitTracker:         jp.waitTracker,
+				diskWatcher:         jp.diskWatcher,
+				contentClient:       jp.contentClient,
+				verdictSigner:       jp.verdictSigner,
+				environments:        jp.environments,
+				dataFiles:           jp.dataFiles,
+				testParallelism:     jp.testParallelism,
+				artifacts:           jp.artifacts,
+				shadow:              jp.shadow,
+				timelines:           jp.timelines,
+				scoring:             jp.scoring,
+				deadLetters:         jp.deadLetters,
+				maxAttempts:         jp.maxAttempts,
+				logs:                jp.logs,
+				progress:            jp.progress,
+				scheduler:           jp.scheduler,
 				maxFailures:         3,
 				healthCheckInterval: 30 * time.Second,
 				recoveryInterval:    60 * time.Second,
@@ -594,16 +1633,8 @@ func (jp *JudgePool) ScaleWorkers(newWorkerCount int) error {
 				lastHeartbeat:       time.Now(),
 			}
 
-			workerModel := &models.JudgeWorker{
-				WorkerName: fmt.Sprintf("judge-worker-%d", i+1),
-				Status:     "idle",
-			}
-
-			if err := jp.db.CreateJudgeWorker(context.Background(), workerModel); err != nil {
-				log.Printf("Failed to create worker record: %v", err)
-				worker.workerID = int64(i + 1)
-			} else {
-				worker.workerID = int64(workerModel.ID)
+			if err := worker.register(context.Background()); err != nil {
+				log.Printf("Failed to register worker %s: %v", worker.name, err)
 			}
 
 			jp.workers = append(jp.workers, worker)
@@ -629,12 +1660,19 @@ func (jp *JudgePool) ScaleWorkers(newWorkerCount int) error {
 			}
 		}
 
+		for _, worker := range excessWorkers {
+			worker.terminate(context.Background())
+		}
+
 		// Remove excess workers from slice
 		jp.workers = jp.workers[:newWorkerCount]
 		log.Printf("Scaled down workers from %d to %d", currentCount, newWorkerCount)
 	}
 
 	jp.workerCount = newWorkerCount
+	if jp.scaleListener != nil {
+		jp.scaleListener(newWorkerCount)
+	}
 	return nil
 }
 
@@ -693,17 +1731,225 @@ func (jp *JudgePool) Stop() {
 		log.Printf("All workers finished gracefully")
 	case <-ctx.Done():
 		log.Printf("Shutdown timeout reached, forcing stop")
+		saveCtx, cancelSave := context.WithTimeout(context.Background(), 5*time.Second)
+		for _, worker := range jp.workers {
+			worker.saveInterrupted(saveCtx)
+		}
+		cancelSave()
+	}
+
+	terminateCtx, cancelTerminate := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelTerminate()
+	for _, worker := range jp.workers {
+		worker.terminate(terminateCtx)
 	}
 
 	log.Printf("Judge pool stopped")
 }
 
 func (jp *JudgePool) SetPlagiarismEnqueuer(enqueuer func(submissionID, userID, problemID int64, language, codeURL string)) {
+	jp.plagiarismEnqueuer = enqueuer
 	for _, worker := range jp.workers {
 		worker.plagiarismEnqueuer = enqueuer
 	}
 }
 
+func (jp *JudgePool) SetMetricsService(metrics *services.MetricsService) {
+	jp.metrics = metrics
+	for _, worker := range jp.workers {
+		worker.metrics = metrics
+	}
+}
+
+func (jp *JudgePool) SetContentClient(client *httpclient.ContentServiceClient) {
+	jp.contentClient = client
+	for _, worker := range jp.workers {
+		worker.contentClient = client
+	}
+}
+
+func (jp *JudgePool) SetDiskWatcher(dw *services.DiskWatcherService) {
+	jp.diskWatcher = dw
+	for _, worker := range jp.workers {
+		worker.diskWatcher = dw
+	}
+}
+
+// SetVerdictSigner enables signing of final verdicts.
+func (jp *JudgePool) SetVerdictSigner(signer *services.VerdictSigningService) {
+	jp.verdictSigner = signer
+	for _, worker := range jp.workers {
+		worker.verdictSigner = signer
+	}
+}
+
+// SetContestEnvironments makes workers judge pinned contests with their
+// pinned sandbox environment.
+func (jp *JudgePool) SetContestEnvironments(environments *services.ContestEnvironmentService) {
+	jp.environments = environments
+	for _, worker := range jp.workers {
+		worker.environments = environments
+	}
+}
+
+// SetCheckerTimeBudget caps custom checker runtime per test; overruns yield an internal error verdict.
+// SetShutdownGracePeriod sets how long Stop waits for running judgings before
+// saving their finished tests.
+func (jp *JudgePool) SetShutdownGracePeriod(grace time.Duration) {
+	jp.shutdownTimeout = grace
+}
+
+func (jp *JudgePool) SetCheckerTimeBudget(budget time.Duration) {
+	jp.checkerBudget = budget
+	jp.customChecker.SetMaxCheckerTime(budget)
+	for _, worker := range jp.workers {
+		worker.checkerBudget = budget
+	}
+}
+
+// SetStderrVisibility sets the stderr policy for problems that do not
+// declare their own.
+func (jp *JudgePool) SetStderrVisibility(visibility models.StderrVisibility) {
+	jp.stderrVisibility = visibility
+	for _, worker := range jp.workers {
+		worker.stderrVisibility = visibility
+	}
+}
+
+// SetUsageReport sets whether test results keep sandbox usage and whether
+// submitters see it.
+func (jp *JudgePool) SetUsageReport(report models.UsageReport) {
+	jp.usageReport = report
+	for _, worker := range jp.workers {
+		worker.usageReport = report
+	}
+}
+
+// SetSeedInjection gives every test run a reproducible seed in envVar,
+// derived from secret.
+func (jp *JudgePool) SetSeedInjection(envVar, secret string) {
+	jp.seedEnvVar = envVar
+	jp.seedSecret = []byte(secret)
+	for _, worker := range jp.workers {
+		worker.seedEnvVar = jp.seedEnvVar
+		worker.seedSecret = jp.seedSecret
+	}
+}
+
+// SetSupplementaryData mounts problem data files into execution boxes.
+func (jp *JudgePool) SetSupplementaryData(dataFiles *services.SupplementaryDataService) {
+	jp.dataFiles = dataFiles
+	for _, worker := range jp.workers {
+		worker.dataFiles = dataFiles
+	}
+}
+
+// SetTestParallelism sets how many of a submission's tests each worker runs
+// at once.
+func (jp *JudgePool) SetTestParallelism(parallelism int) {
+	jp.testParallelism = parallelism
+	for _, worker := range jp.workers {
+		worker.testParallelism = parallelism
+	}
+}
+
+// SetArtifactCache lets workers reuse builds of identical code across
+// rejudges and resubmissions instead of compiling again.
+func (jp *JudgePool) SetArtifactCache(artifacts *services.ArtifactCacheService) {
+	jp.artifacts = artifacts
+	for _, worker := range jp.workers {
+		worker.artifacts = artifacts
+	}
+}
+
+// SetShadowJudging re-judges submissions to problems with an active shadow
+// config and records how the results compare.
+func (jp *JudgePool) SetShadowJudging(shadow *services.ShadowJudgingService) {
+	jp.shadow = shadow
+	for _, worker := range jp.workers {
+		worker.shadow = shadow
+	}
+}
+
+// SetAttemptTimelines drops a user's cached attempt timeline once a new
+// verdict on the problem is stored.
+func (jp *JudgePool) SetAttemptTimelines(timelines *services.AttemptTimelineService) {
+	jp.timelines = timelines
+	for _, worker := range jp.workers {
+		worker.timelines = timelines
+	}
+}
+
+// SetScoring recomputes the user's problem score once a new verdict on the
+// problem is stored.
+func (jp *JudgePool) SetScoring(scoring *services.ScoringService) {
+	jp.scoring = scoring
+	for _, worker := range jp.workers {
+		worker.scoring = scoring
+	}
+}
+
+// SetExecutionLogs samples execution logs written to the database instead of
+// storing every entry.
+func (jp *JudgePool) SetExecutionLogs(logs *services.ExecutionLogService) {
+	jp.logs = logs
+	for _, worker := range jp.workers {
+		worker.logs = logs
+	}
+}
+
+// SetProgressPublisher streams each judging step to clients watching the
+// submission.
+func (jp *JudgePool) SetProgressPublisher(publish func(ctx context.Context, progress *models.SubmissionProgress) error) {
+	jp.progress = publish
+	for _, worker := range jp.workers {
+		worker.progress = publish
+	}
+}
+
+// SetTLERetry enables re-running tests that time out within the policy's
+// margin of the limit.
+func (jp *JudgePool) SetTLERetry(policy config.TLERetryConfig) {
+	jp.tleRetry = policy
+	for _, worker := range jp.workers {
+		worker.tleRetry = policy
+	}
+}
+
+// SetDeadLetterQueue parks submissions that fail to process maxAttempts
+// times instead of requeueing them forever.
+func (jp *JudgePool) SetDeadLetterQueue(deadLetters *services.DeadLetterQueueService, maxAttempts int) {
+	jp.deadLetters = deadLetters
+	jp.maxAttempts = maxAttempts
+	for _, worker := range jp.workers {
+		worker.deadLetters = deadLetters
+		worker.maxAttempts = maxAttempts
+	}
+}
+
+// HandleReconnect is called once the queue client has reconnected. The
+// scheduler's consumers keep running on the new channel; submissions it had
+// buffered are dropped, as the broker redelivers them. Submissions being
+// judged finish, but their acknowledgement is lost and they are judged again.
+func (jp *JudgePool) HandleReconnect() {
+	if dropped := jp.scheduler.dropPending(); dropped > 0 {
+		log.Printf("Dropped %d buffered submissions after RabbitMQ reconnect", dropped)
+	}
+}
+
+// SyncLanguages lets the code validator accept the source files of languages
+// added to the sandbox since the pool started.
+func (jp *JudgePool) SyncLanguages() {
+	jp.validator.AllowExtensions(jp.sandbox.Extensions()...)
+}
+
+// SetScaleListener registers a callback invoked after every manual or automatic scaling event.
+func (jp *JudgePool) SetScaleListener(listener func(workerCount int)) {
+	jp.mutex.Lock()
+	jp.scaleListener = listener
+	jp.mutex.Unlock()
+}
+
 func (jp *JudgePool) healthMonitor(ctx context.Context) {
 	ticker := time.NewTicker(jp.healthCheckInterval)
 	defer ticker.Stop()
@@ -793,8 +2039,8 @@ func (jp *JudgePool) recoverWorker(ctx context.Context, worker *JudgeWorker) {
 	worker.mutex.Unlock()
 
 	// Update worker status in database
-	if worker.workerID > 0 {
-		err := jp.db.UpdateWorkerStatus(ctx, int(worker.workerID), "idle", nil)
+	if workerID := worker.registeredID(); workerID > 0 {
+		err := jp.db.UpdateWorkerStatus(ctx, int(workerID), "idle", nil)
 		if err != nil {
 			log.Printf("Failed to update recovered worker %d status: %v", worker.id, err)
 		}
@@ -805,8 +2051,8 @@ func (jp *JudgePool) recoverWorker(ctx context.Context, worker *JudgeWorker) {
 
 func (jp *JudgePool) handleFailedWorker(ctx context.Context, worker *JudgeWorker) {
 	// Update worker status in database
-	if worker.workerID > 0 {
-		err := jp.db.UpdateWorkerStatus(ctx, int(worker.workerID), "failed", nil)
+	if workerID := worker.registeredID(); workerID > 0 {
+		err := jp.db.UpdateWorkerStatus(ctx, int(workerID), "failed", nil)
 		if err != nil {
 			log.Printf("Failed to update failed worker %d status: %v", worker.id, err)
 		}
@@ -860,9 +2106,15 @@ func (jp *JudgePool) reportPoolHealth(ctx context.Context) {
 	}
 
 	// Create health log entry
+	message := fmt.Sprintf("Pool health report: %+v", healthData)
+	if jp.logs != nil {
+		jp.logs.Log(ctx, 0, "INFO", message)
+		return
+	}
+
 	logEntry := &models.ExecutionLog{
 		Level:   "INFO",
-		Message: fmt.Sprintf("Pool health report: %+v", healthData),
+		Message: message,
 	}
 
 	if err := jp.db.CreateExecutionLog(ctx, logEntry); err != nil {
@@ -880,6 +2132,7 @@ func (jw *JudgeWorker) heartbeatLoop(ctx context.Context) {
 			return
 		case <-ticker.C:
 			jw.updateHeartbeat()
+			jw.renewLease(ctx)
 		}
 	}
 }
@@ -946,15 +2199,43 @@ func (jp *JudgePool) performAutoScaling(ctx context.Context) {
 	// Calculate optimal worker count
 	optimalWorkers := jp.calculateOptimalWorkers(queueSize, activeWorkers, currentWorkers)
 
-	if optimalWorkers != currentWorkers {
-		log.Printf("Auto-scaling: %d -> %d workers (queue: %d, active: %d)",
-			currentWorkers, optimalWorkers, queueSize, activeWorkers)
+	if optimalWorkers == currentWorkers {
+		return
+	}
+
+	jp.mutex.RLock()
+	dryRun := jp.autoScaleDryRun
+	jp.mutex.RUnlock()
 
-		err := jp.ScaleWorkers(optimalWorkers)
-		if err != nil {
+	event := &models.ScalingEvent{
+		Trigger:       "auto",
+		QueueSize:     queueSize,
+		ActiveWorkers: activeWorkers,
+		P95WaitMs:     int(jp.waitTracker.p95().Milliseconds()),
+		PreviousCount: currentWorkers,
+		NewCount:      optimalWorkers,
+		DryRun:        dryRun,
+	}
+
+	if dryRun {
+		log.Printf("Auto-scaling (dry run): would scale %d -> %d workers (queue: %d, active: %d, p95 wait: %dms)",
+			currentWorkers, optimalWorkers, queueSize, activeWorkers, event.P95WaitMs)
+	} else {
+		log.Printf("Auto-scaling: %d -> %d workers (queue: %d, active: %d, p95 wait: %dms)",
+			currentWorkers, optimalWorkers, queueSize, activeWorkers, event.P95WaitMs)
+
+		if err := jp.ScaleWorkers(optimalWorkers); err != nil {
 			log.Printf("Auto-scaling failed: %v", err)
+			errMsg := err.Error()
+			event.Error = &errMsg
+		} else {
+			event.Applied = true
 		}
 	}
+
+	if err := jp.db.CreateScalingEvent(ctx, event); err != nil {
+		log.Printf("Failed to record scaling event: %v", err)
+	}
 }
 
 func (jp *JudgePool) calculateOptimalWorkers(queueSize, activeWorkers, currentWorkers int) int {
@@ -1004,6 +2285,20 @@ func (jp *JudgePool) EnableAutoScaling() {
 	log.Printf("Auto-scaling enabled")
 }
 
+// SetAutoScaleDryRun makes the auto-scaler record recommendations without applying them.
+func (jp *JudgePool) SetAutoScaleDryRun(dryRun bool) {
+	jp.mutex.Lock()
+	jp.autoScaleDryRun = dryRun
+	jp.mutex.Unlock()
+	log.Printf("Auto-scaling dry run: %v", dryRun)
+}
+
+func (jp *JudgePool) IsAutoScaleDryRun() bool {
+	jp.mutex.RLock()
+	defer jp.mutex.RUnlock()
+	return jp.autoScaleDryRun
+}
+
 func (jp *JudgePool) DisableAutoScaling() {
 	jp.mutex.Lock()
 	jp.autoScalingEnabled = false
@@ -1024,3 +2319,12 @@ func (jp *JudgePool) SetAutoScalingLimits(minWorkers, maxWorkers int) error {
 	log.Printf("Auto-scaling limits updated: min=%d, max=%d", minWorkers, maxWorkers)
 	return nil
 }
+
+// testSeed is the seed a submission's run sees on a test: 63 bits of an
+// HMAC over the submission and test number, so reruns repeat it while
+// submitters cannot predict the seed of anyone else's submission.
+func testSeed(secret []byte, submissionID int64, testNumber int) int64 {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d:%d", submissionID, testNumber)
+	return int64(binary.BigEndian.Uint64(mac.Sum(nil)) & math.MaxInt64)
+}
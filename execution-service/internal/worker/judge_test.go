@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestScaleWorkersNotifiesListener(t *testing.T) {
+	jp := &JudgePool{
+		isRunning:   true,
+		workers:     []*JudgeWorker{{}, {}, {}},
+		workerCount: 3,
+	}
+	var notified []int
+	jp.SetScaleListener(func(workerCount int) {
+		notified = append(notified, workerCount)
+	})
+
+	if err := jp.ScaleWorkers(3); err != nil {
+		t.Fatalf("ScaleWorkers(3) error = %v", err)
+	}
+	if err := jp.ScaleWorkers(0); err == nil {
+		t.Fatal("ScaleWorkers(0): want an error")
+	}
+	if err := jp.ScaleWorkers(1); err != nil {
+		t.Fatalf("ScaleWorkers(1) error = %v", err)
+	}
+
+	// Only the resize that changed the pool is reported
+	if want := []int{1}; !slices.Equal(notified, want) {
+		t.Errorf("listener saw %v, want %v", notified, want)
+	}
+}
@@ -0,0 +1,142 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"execution_service/internal/database"
+	"execution_service/internal/models"
+)
+
+const (
+	workerLeaseDuration       = 60 * time.Second
+	leaseReapInterval         = time.Minute
+	terminatedWorkerRetention = 24 * time.Hour
+)
+
+// workerName is stable per host and slot so restarts reuse the same row.
+func workerName(index int) string {
+	return fmt.Sprintf("judge-worker-%s-%d", nodeName(), index)
+}
+
+func nodeName() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "local"
+	}
+	return host
+}
+
+func (jw *JudgeWorker) register(ctx context.Context) error {
+	workerModel := &models.JudgeWorker{
+		WorkerName: jw.name,
+		Status:     "idle",
+	}
+
+	err := jw.db.RegisterJudgeWorker(ctx, workerModel, workerLeaseDuration)
+	if errors.Is(err, database.ErrWorkerLeased) {
+		if _, releaseErr := releaseStaleWorkers(ctx, jw.db); releaseErr != nil {
+			return releaseErr
+		}
+		err = jw.db.RegisterJudgeWorker(ctx, workerModel, workerLeaseDuration)
+	}
+	if err != nil {
+		return err
+	}
+
+	jw.mutex.Lock()
+	jw.workerID = int64(workerModel.ID)
+	jw.mutex.Unlock()
+	return nil
+}
+
+func (jw *JudgeWorker) registeredID() int64 {
+	jw.mutex.RLock()
+	defer jw.mutex.RUnlock()
+	return jw.workerID
+}
+
+func (jw *JudgeWorker) renewLease(ctx context.Context) {
+	workerID := jw.registeredID()
+	if workerID == 0 {
+		if err := jw.register(ctx); err != nil {
+			log.Printf("Worker %d registration retry failed: %v", jw.id, err)
+		}
+		return
+	}
+
+	err := jw.db.RenewWorkerLease(ctx, int(workerID), workerLeaseDuration)
+	if errors.Is(err, database.ErrWorkerLeased) {
+		log.Printf("Worker %d lost its lease, re-registering", jw.id)
+		jw.mutex.Lock()
+		jw.workerID = 0
+		jw.mutex.Unlock()
+		return
+	}
+	if err != nil {
+		log.Printf("Worker %d failed to renew lease: %v", jw.id, err)
+	}
+}
+
+func (jw *JudgeWorker) terminate(ctx context.Context) {
+	if workerID := jw.registeredID(); workerID > 0 {
+		if err := jw.db.UpdateWorkerStatus(ctx, int(workerID), "terminated", nil); err != nil {
+			log.Printf("Failed to mark worker %d terminated: %v", jw.id, err)
+		}
+	}
+}
+
+// releaseStaleWorkers terminates workers whose lease lapsed and returns their
+// pending submissions to a clean state so redelivery can judge them again.
+func releaseStaleWorkers(ctx context.Context, db *database.DB) (int, error) {
+	submissionIDs, err := db.ExpireStaleWorkers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, submissionID := range submissionIDs {
+		if err := db.ResetSubmissionState(ctx, submissionID); err != nil {
+			log.Printf("Failed to release submission %d from stale worker: %v", submissionID, err)
+		}
+	}
+
+	return len(submissionIDs), nil
+}
+
+func (jp *JudgePool) leaseReaper(ctx context.Context) {
+	ticker := time.NewTicker(leaseReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			jp.reapStaleWorkers(ctx)
+		}
+	}
+}
+
+func (jp *JudgePool) reapStaleWorkers(ctx context.Context) {
+	released, err := releaseStaleWorkers(ctx, jp.db)
+	if err != nil {
+		log.Printf("Failed to expire stale workers: %v", err)
+		return
+	}
+	if released > 0 {
+		log.Printf("Released %d submissions from stale workers", released)
+	}
+
+	deleted, err := jp.db.DeleteTerminatedWorkers(ctx, terminatedWorkerRetention)
+	if err != nil {
+		log.Printf("Failed to delete terminated workers: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Deleted %d terminated worker records", deleted)
+	}
+}
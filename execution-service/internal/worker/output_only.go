@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"execution_service/internal/models"
+	"execution_service/internal/sandbox"
+)
+
+// outputFiles maps each test number to its submitted output. Files are named
+// by test number with any extension, such as 1.out or outputs/02.txt. Tests
+// without a file are judged as wrong answers.
+func outputFiles(archive []byte, tests int) (map[int][]byte, error) {
+	files, err := sandbox.ReadZip(archive, projectMaxFiles, projectMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make(map[int][]byte, len(files))
+	for _, file := range files {
+		base := path.Base(file.Name)
+		testNumber, err := strconv.Atoi(strings.TrimSuffix(base, path.Ext(base)))
+		if err != nil || testNumber < 1 || testNumber > tests {
+			return nil, fmt.Errorf("%s does not name one of the %d tests", file.Name, tests)
+		}
+		if _, ok := outputs[testNumber]; ok {
+			return nil, fmt.Errorf("more than one output for test %d", testNumber)
+		}
+		outputs[testNumber] = file.Data
+	}
+	return outputs, nil
+}
+
+// submittedOutput stands in for running a program on the test, so the
+// submitted output goes through the checker like a program's would.
+func submittedOutput(outputs map[int][]byte, testNumber int) *sandbox.ExecutionResult {
+	output, ok := outputs[testNumber]
+	if !ok {
+		return &sandbox.ExecutionResult{
+			Verdict: models.VerdictWrongAns,
+			Error:   fmt.Sprintf("No output submitted for test %d", testNumber),
+		}
+	}
+	return &sandbox.ExecutionResult{Verdict: models.VerdictAccepted, Output: string(output)}
+}
@@ -0,0 +1,202 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"execution_service/internal/checker"
+	"execution_service/internal/models"
+	"execution_service/internal/sandbox"
+)
+
+const (
+	sampleRunMaxTimeLimitMs   = 2000
+	sampleRunMaxMemoryLimitKb = 131072
+	sampleRunCompileTimeLimit = 10 * time.Second
+	sampleRunMaxOutputBytes   = 4096
+)
+
+type SampleRunResult struct {
+	Verdict      models.Verdict     `json:"verdict"`
+	CompileError string             `json:"compile_error,omitempty"`
+	TestsPassed  int                `json:"tests_passed"`
+	TestsTotal   int                `json:"tests_total"`
+	Tests        []SampleTestResult `json:"tests"`
+}
+
+type SampleTestResult struct {
+	TestNumber      int            `json:"test_number"`
+	TestCaseID      int64          `json:"test_case_id"`
+	Verdict         models.Verdict `json:"verdict"`
+	ExecutionTimeMs int            `json:"execution_time_ms"`
+	MemoryUsedKb    int            `json:"memory_used_kb"`
+	Input           string         `json:"input"`
+	ExpectedOutput  string         `json:"expected_output"`
+	ActualOutput    string         `json:"actual_output"`
+	Error           string         `json:"error,omitempty"`
+}
+
+// RunSamples judges code synchronously against the sample tests of a problem.
+// Nothing is persisted and limits are capped below regular judging limits.
+func (jp *JudgePool) RunSamples(ctx context.Context, problemID int64, language string, code []byte) (*SampleRunResult, error) {
+	validationResult := jp.validator.ValidateCode(code, jp.sandbox.SourceFileName(language))
+	if !validationResult.IsValid {
+		for _, violation := range validationResult.Violations {
+			if violation.Severity == "critical" {
+				return nil, fmt.Errorf("code validation failed: [%s] %s", violation.Type, violation.Description)
+			}
+		}
+		return nil, fmt.Errorf("code validation failed")
+	}
+
+	// Sample verdicts go through a worker's checker, as regular judging does
+	jp.mutex.RLock()
+	if len(jp.workers) == 0 {
+		jp.mutex.RUnlock()
+		return nil, fmt.Errorf("no judge workers available")
+	}
+	jw := jp.workers[0]
+	jp.mutex.RUnlock()
+
+	problem, err := jp.contentClient.GetProblem(ctx, problemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get test cases: %w", err)
+	}
+
+	var samples []models.TestCase
+	for _, tc := range problem.TestCases {
+		if tc.InteractorURL != "" {
+			return nil, fmt.Errorf("sample runs are not supported for interactive problems")
+		}
+		if tc.IsSample {
+			samples = append(samples, models.TestCase{
+				ID:          tc.ID,
+				InputURL:    tc.InputURL,
+				OutputURL:   tc.OutputURL,
+				IsSample:    tc.IsSample,
+				TimeLimit:   tc.TimeLimit,
+				MemoryLimit: tc.MemoryLimit,
+				CheckerURL:  tc.CheckerURL,
+			})
+		}
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("problem has no sample tests")
+	}
+
+	limits, _ := jp.resourceValidator.ValidateAndNormalizeLimits(ctx, problemID, sampleRunMaxTimeLimitMs, sampleRunMaxMemoryLimitKb)
+	timeLimitMs := min(limits.TimeLimitMs, sampleRunMaxTimeLimitMs)
+	memoryLimitKb := min(limits.MemoryLimitKb, sampleRunMaxMemoryLimitKb)
+
+	result := &SampleRunResult{
+		Verdict:    models.VerdictAccepted,
+		TestsTotal: len(samples),
+		Tests:      make([]SampleTestResult, 0, len(samples)),
+	}
+
+	var runOptions sandbox.RunOptions
+	if jp.dataFiles != nil {
+		setup := &judgingSetup{
+			dataFiles:    supplementaryFiles(problem.SupplementaryFiles),
+			checkerFiles: supplementaryFiles(problem.CheckerFiles),
+		}
+		runOptions.DataDir, err = jp.dataFiles.Prepare(ctx, setup.dataFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare supplementary data: %w", err)
+		}
+		runOptions.CheckerDataDir, err = jw.prepareCheckerData(ctx, setup, runOptions.DataDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare checker data: %w", err)
+		}
+	}
+
+	var compileResult *sandbox.CompileResult
+	if len(problem.Graders) > 0 {
+		compileResult, err = compileWithGrader(ctx, jp.sandbox, jp.storage, nil, language, code, problem.Graders, sampleRunCompileTimeLimit)
+	} else {
+		compileResult, err = jp.sandbox.Compile(ctx, language, code, sampleRunCompileTimeLimit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("compilation error: %w", err)
+	}
+	if !compileResult.Success {
+		result.Verdict = models.VerdictCompile
+		result.CompileError = compileResult.Error
+		return result, nil
+	}
+	runOptions.BuildDir, err = jp.sandbox.PrepareBuild(compileResult.Artifact)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare build: %w", err)
+	}
+	defer jp.sandbox.ReleaseBuild(runOptions.BuildDir)
+
+	for i, testCase := range samples {
+		input, err := jp.storage.DownloadCode(ctx, testCase.InputURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download test input: %w", err)
+		}
+
+		expectedOutput, err := jp.storage.DownloadCode(ctx, testCase.OutputURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download test output: %w", err)
+		}
+
+		timeLimit := time.Duration(timeLimitMs) * time.Millisecond
+		if testCase.TimeLimit > 0 && testCase.TimeLimit < timeLimitMs {
+			timeLimit = time.Duration(testCase.TimeLimit) * time.Millisecond
+		}
+		memoryLimit := memoryLimitKb
+		if testCase.MemoryLimit > 0 && testCase.MemoryLimit < memoryLimitKb {
+			memoryLimit = testCase.MemoryLimit
+		}
+		timeLimit, memoryLimit = jp.sandbox.ScaleLimits(language, timeLimit, memoryLimit)
+
+		execResult, err := jp.sandbox.ExecuteWith(ctx, runOptions, language, compileResult.EntryPoint, input, timeLimit, memoryLimit)
+		if err != nil {
+			return nil, fmt.Errorf("execution error: %w", err)
+		}
+
+		verdict := execResult.Verdict
+		if verdict == models.VerdictAccepted {
+			checkResult := jw.checkOutput(ctx, &testCase, &checker.CheckInput{
+				Input:    input,
+				Output:   execResult.Output,
+				Expected: string(expectedOutput),
+				DataDir:  runOptions.CheckerDataDir,
+			})
+			switch {
+			case jw.checkerOverBudget(checkResult), checkResult.Failed:
+				verdict = models.VerdictInternal
+			case !checkResult.IsCorrect:
+				verdict = models.VerdictWrongAns
+			default:
+				result.TestsPassed++
+			}
+		}
+		if verdict != models.VerdictAccepted && result.Verdict == models.VerdictAccepted {
+			result.Verdict = verdict
+		}
+
+		result.Tests = append(result.Tests, SampleTestResult{
+			TestNumber:      i + 1,
+			TestCaseID:      testCase.ID,
+			Verdict:         verdict,
+			ExecutionTimeMs: execResult.ExecutionTime,
+			MemoryUsedKb:    execResult.MemoryUsed,
+			Input:           truncateSampleOutput(string(input)),
+			ExpectedOutput:  truncateSampleOutput(string(expectedOutput)),
+			ActualOutput:    truncateSampleOutput(execResult.Output),
+			Error:           truncateSampleOutput(execResult.Error),
+		})
+	}
+
+	return result, nil
+}
+
+func truncateSampleOutput(s string) string {
+	if len(s) <= sampleRunMaxOutputBytes {
+		return s
+	}
+	return s[:sampleRunMaxOutputBytes] + "\n...(truncated)"
+}
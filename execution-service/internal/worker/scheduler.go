@@ -0,0 +1,332 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"execution_service/internal/models"
+	"execution_service/internal/queue"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// priorityAging raises the effective priority of a buffered submission by one
+// for every interval it waits, so practice submissions cannot starve.
+const priorityAging = 30 * time.Second
+
+type scheduledJob struct {
+	msg      amqp.Delivery
+	request  *models.JudgeRequest
+	received time.Time
+	contest  bool
+}
+
+// scheduler decouples consumption from execution. One dispatcher per queue
+// lane moves deliveries from RabbitMQ into a bounded buffer and workers pull
+// the best job from it. Messages stay unacknowledged until the worker
+// finishes, so a crash still redelivers everything buffered or running on
+// this node.
+//
+// Contest submissions arrive on their own lane with its own share of the
+// buffer, are picked before practice ones and may use the contest workers
+// that practice submissions leave free.
+type scheduler struct {
+	queue            *queue.RabbitMQClient
+	capacity         int
+	reservedWorkers  int
+	reservedPriority int
+	contestWorkers   int
+	workerCount      func() int
+	paused           func() bool
+
+	mu              sync.Mutex
+	changed         chan struct{}
+	pending         []*scheduledJob
+	running         map[int64]int
+	runningLow      int
+	runningPractice int
+}
+
+func newScheduler(q *queue.RabbitMQClient, workerCount func() int, paused func() bool) *scheduler {
+	return &scheduler{
+		queue:            q,
+		capacity:         32,
+		reservedPriority: 5,
+		workerCount:      workerCount,
+		paused:           paused,
+		changed:          make(chan struct{}),
+		running:          make(map[int64]int),
+	}
+}
+
+// run consumes both lanes until ctx is cancelled, then hands every buffered
+// message back to the broker.
+func (s *scheduler) run(ctx context.Context) {
+	defer s.requeuePending()
+
+	var wg sync.WaitGroup
+	for _, contest := range []bool{false, true} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.consume(ctx, contest)
+		}()
+	}
+	wg.Wait()
+}
+
+// consume feeds one lane into the buffer until ctx is cancelled.
+func (s *scheduler) consume(ctx context.Context, contest bool) {
+	lane := "practice"
+	subscribe := s.queue.ConsumeSubmissions
+	if contest {
+		lane = "contest"
+		subscribe = s.queue.ConsumeContestSubmissions
+	}
+
+	var msgs <-chan amqp.Delivery
+	for {
+		if !s.waitForSpace(ctx, contest) {
+			return
+		}
+
+		if msgs == nil {
+			consumed, err := subscribe(ctx)
+			if err != nil {
+				log.Printf("Scheduler failed to start consuming %s submissions: %v", lane, err)
+				if !sleepContext(ctx, 5*time.Second) {
+					return
+				}
+				continue
+			}
+			msgs = consumed
+		}
+
+		var msg amqp.Delivery
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok = <-msgs:
+		}
+		if !ok {
+			log.Printf("%s submission delivery channel closed, resubscribing", lane)
+			msgs = nil
+			continue
+		}
+
+		// Leave the job for another node while this one is paused
+		if s.paused() {
+			log.Printf("Scheduler refusing message while paused by disk usage or drain")
+			s.requeuePending()
+			s.queue.RejectMessage(msg, true)
+			sleepContext(ctx, 5*time.Second)
+			continue
+		}
+
+		request, err := s.queue.ParseJudgeRequest(msg)
+		if err != nil {
+			log.Printf("Scheduler dropping unparseable message: %v", err)
+			s.queue.RejectMessage(msg, false)
+			continue
+		}
+
+		s.mu.Lock()
+		s.pending = append(s.pending, &scheduledJob{msg: msg, request: request, received: time.Now(), contest: contest})
+		s.notifyLocked()
+		s.mu.Unlock()
+	}
+}
+
+// waitForSpace blocks until the lane has room in the buffer; each lane gets
+// the full capacity, so a practice backlog cannot stall contest consumption.
+func (s *scheduler) waitForSpace(ctx context.Context, contest bool) bool {
+	for {
+		s.mu.Lock()
+		buffered := 0
+		for _, job := range s.pending {
+			if job.contest == contest {
+				buffered++
+			}
+		}
+		full := buffered >= s.capacity
+		changed := s.changed
+		s.mu.Unlock()
+		if !full {
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-changed:
+		}
+	}
+}
+
+// next blocks until a job is eligible for a worker and marks it running.
+func (s *scheduler) next(ctx context.Context) (*scheduledJob, error) {
+	for {
+		s.mu.Lock()
+		if index := s.pickLocked(); index >= 0 {
+			job := s.pending[index]
+			s.pending = append(s.pending[:index], s.pending[index+1:]...)
+			s.running[job.request.UserID]++
+			if job.request.Priority < s.reservedPriority {
+				s.runningLow++
+			}
+			if !job.contest {
+				s.runningPractice++
+			}
+			s.notifyLocked()
+			s.mu.Unlock()
+			return job, nil
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// done releases a job taken by next once the worker acked or rejected it.
+func (s *scheduler) done(job *scheduledJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userID := job.request.UserID
+	if s.running[userID]--; s.running[userID] <= 0 {
+		delete(s.running, userID)
+	}
+	if job.request.Priority < s.reservedPriority {
+		s.runningLow--
+	}
+	if !job.contest {
+		s.runningPractice--
+	}
+	s.notifyLocked()
+}
+
+// pickLocked returns the index of the best eligible job, or -1. Contest jobs
+// come first; within a lane jobs are ranked by aged priority, then by how
+// many submissions the user already has running, then by arrival.
+// Submissions below the reserved priority may not occupy the reserved
+// workers, and practice submissions may not occupy the contest workers.
+func (s *scheduler) pickLocked() int {
+	workers := s.workerCount()
+	lowAllowed := s.runningLow < max(workers-s.reservedWorkers, 1)
+	practiceAllowed := s.runningPractice < max(workers-s.contestWorkers, 1)
+	now := time.Now()
+
+	best := -1
+	var bestContest bool
+	var bestPriority, bestRunning int
+	for index, job := range s.pending {
+		if job.request.Priority < s.reservedPriority && !lowAllowed {
+			continue
+		}
+		if !job.contest && !practiceAllowed {
+			continue
+		}
+
+		priority := job.request.Priority + int(now.Sub(job.received)/priorityAging)
+		running := s.running[job.request.UserID]
+		if best >= 0 {
+			if bestContest && !job.contest {
+				continue
+			}
+			if bestContest == job.contest {
+				if priority < bestPriority {
+					continue
+				}
+				if priority == bestPriority && running >= bestRunning {
+					continue
+				}
+			}
+		}
+		best, bestContest, bestPriority, bestRunning = index, job.contest, priority, running
+	}
+	return best
+}
+
+func (s *scheduler) requeuePending() {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.notifyLocked()
+	s.mu.Unlock()
+
+	for _, job := range pending {
+		s.queue.RejectMessage(job.msg, true)
+	}
+}
+
+// dropPending forgets the buffered deliveries after a reconnect. They came
+// on the closed channel, so they can no longer be acknowledged, and the
+// broker has already requeued them.
+func (s *scheduler) dropPending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dropped := len(s.pending)
+	s.pending = nil
+	s.notifyLocked()
+	return dropped
+}
+
+func (s *scheduler) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *scheduler) buffered() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+// snapshot lists the buffered jobs for a queue snapshot.
+func (s *scheduler) snapshot(now time.Time) []models.QueueSnapshotEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]models.QueueSnapshotEntry, 0, len(s.pending))
+	for _, job := range s.pending {
+		entries = append(entries, queueEntry(job.request, queuedAt(job.msg, job.received), now, models.QueueEntryBuffered))
+	}
+	return entries
+}
+
+// queuedAt is when a message was published, or fallback for publishers that
+// leave the timestamp unset.
+func queuedAt(msg amqp.Delivery, fallback time.Time) time.Time {
+	if msg.Timestamp.IsZero() {
+		return fallback
+	}
+	return msg.Timestamp
+}
+
+func queueEntry(request *models.JudgeRequest, queued, now time.Time, state string) models.QueueSnapshotEntry {
+	return models.QueueSnapshotEntry{
+		SubmissionID: request.SubmissionID,
+		UserID:       request.UserID,
+		ContestID:    request.ContestID,
+		Priority:     request.Priority,
+		AgeMs:        now.Sub(queued).Milliseconds(),
+		State:        state,
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
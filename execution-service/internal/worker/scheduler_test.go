@@ -0,0 +1,126 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"execution_service/internal/models"
+)
+
+func TestSchedulerPickLocked(t *testing.T) {
+	now := time.Now()
+	job := func(userID int64, priority int, contest bool, age time.Duration) *scheduledJob {
+		return &scheduledJob{
+			request:  &models.JudgeRequest{UserID: userID, Priority: priority},
+			received: now.Add(-age),
+			contest:  contest,
+		}
+	}
+
+	tests := []struct {
+		name            string
+		workers         int
+		reservedWorkers int
+		contestWorkers  int
+		running         map[int64]int
+		runningLow      int
+		runningPractice int
+		pending         []*scheduledJob
+		want            int
+	}{
+		{
+			name:    "empty buffer",
+			workers: 4,
+			want:    -1,
+		},
+		{
+			name:    "contest before higher priority practice",
+			workers: 4,
+			pending: []*scheduledJob{job(1, 9, false, 0), job(2, 5, true, 0)},
+			want:    1,
+		},
+		{
+			name:    "higher priority first within a lane",
+			workers: 4,
+			pending: []*scheduledJob{job(1, 5, false, 0), job(2, 7, false, 0)},
+			want:    1,
+		},
+		{
+			name:    "waiting raises priority",
+			workers: 4,
+			pending: []*scheduledJob{job(1, 6, false, 0), job(2, 5, false, 2*priorityAging)},
+			want:    1,
+		},
+		{
+			name:    "user with fewer running submissions first",
+			workers: 4,
+			running: map[int64]int{1: 2},
+			pending: []*scheduledJob{job(1, 5, false, 0), job(2, 5, false, 0)},
+			want:    1,
+		},
+		{
+			name:    "arrival order breaks ties",
+			workers: 4,
+			pending: []*scheduledJob{job(1, 5, false, 0), job(2, 5, false, 0)},
+			want:    0,
+		},
+		{
+			name:            "low priority kept off reserved workers",
+			workers:         4,
+			reservedWorkers: 1,
+			runningLow:      3,
+			pending:         []*scheduledJob{job(1, 0, false, 0)},
+			want:            -1,
+		},
+		{
+			name:            "reserved workers still take high priority",
+			workers:         4,
+			reservedWorkers: 1,
+			runningLow:      3,
+			pending:         []*scheduledJob{job(1, 0, false, 0), job(2, 5, false, 0)},
+			want:            1,
+		},
+		{
+			name:            "practice kept off contest workers",
+			workers:         4,
+			contestWorkers:  2,
+			runningPractice: 2,
+			pending:         []*scheduledJob{job(1, 5, false, 0)},
+			want:            -1,
+		},
+		{
+			name:            "contest workers take contest submissions",
+			workers:         4,
+			contestWorkers:  2,
+			runningPractice: 2,
+			pending:         []*scheduledJob{job(1, 5, false, 0), job(2, 0, true, 0)},
+			want:            1,
+		},
+		{
+			name:            "a single worker is never fully reserved",
+			workers:         1,
+			reservedWorkers: 1,
+			contestWorkers:  1,
+			pending:         []*scheduledJob{job(1, 0, false, 0)},
+			want:            0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScheduler(nil, func() int { return tt.workers }, func() bool { return false })
+			s.reservedWorkers = tt.reservedWorkers
+			s.contestWorkers = tt.contestWorkers
+			s.runningLow = tt.runningLow
+			s.runningPractice = tt.runningPractice
+			s.pending = tt.pending
+			for userID, count := range tt.running {
+				s.running[userID] = count
+			}
+
+			if got := s.pickLocked(); got != tt.want {
+				t.Errorf("pickLocked() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// waitTracker keeps a fixed window of recent queue wait times.
+type waitTracker struct {
+	samples []time.Duration
+	next    int
+	filled  bool
+	mutex   sync.Mutex
+}
+
+func newWaitTracker(size int) *waitTracker {
+	return &waitTracker{samples: make([]time.Duration, size)}
+}
+
+func (wt *waitTracker) record(wait time.Duration) {
+	wt.mutex.Lock()
+	defer wt.mutex.Unlock()
+
+	wt.samples[wt.next] = wait
+	wt.next = (wt.next + 1) % len(wt.samples)
+	if wt.next == 0 {
+		wt.filled = true
+	}
+}
+
+func (wt *waitTracker) p95() time.Duration {
+	wt.mutex.Lock()
+	count := wt.next
+	if wt.filled {
+		count = len(wt.samples)
+	}
+	window := make([]time.Duration, count)
+	copy(window, wt.samples[:count])
+	wt.mutex.Unlock()
+
+	if count == 0 {
+		return 0
+	}
+
+	sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+	return window[(count*95-1)/100]
+}
@@ -0,0 +1,873 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CreateSubmission submits code for judging. With an IdempotencyKey the
+// request is retried like reads, since a retry returns the original
+// submission instead of creating another.
+func (c *Client) CreateSubmission(ctx context.Context, request *CreateSubmissionRequest) (*CreateSubmissionResponse, error) {
+	return c.createSubmission(ctx, "/api/submissions", request)
+}
+
+// CreateTrustedSubmission submits without the static code checks. The caller
+// needs the submission/trusted permission, normally held by setters.
+func (c *Client) CreateTrustedSubmission(ctx context.Context, request *CreateSubmissionRequest) (*CreateSubmissionResponse, error) {
+	return c.createSubmission(ctx, "/api/submissions/trusted", request)
+}
+
+func (c *Client) createSubmission(ctx context.Context, path string, request *CreateSubmissionRequest) (*CreateSubmissionResponse, error) {
+	var response CreateSubmissionResponse
+	var err error
+	if request.IdempotencyKey != "" {
+		header := http.Header{"Idempotency-Key": []string{request.IdempotencyKey}}
+		err = c.call(ctx, http.MethodPost, path, nil, header, request, &response, true)
+	} else {
+		err = c.post(ctx, path, request, &response)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CreateRepositorySubmission has the service fetch the commit and judge the
+// whole project. The host must be on the service's allowlist.
+func (c *Client) CreateRepositorySubmission(ctx context.Context, request *CreateRepositorySubmissionRequest) (*CreateRepositorySubmissionResponse, error) {
+	var response CreateRepositorySubmissionResponse
+	if err := c.post(ctx, "/api/submissions/repository", request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *Client) GetSubmission(ctx context.Context, submissionID int64) (*Submission, error) {
+	var submission Submission
+	if err := c.get(ctx, fmt.Sprintf("/api/submissions/%d", submissionID), nil, &submission); err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+func (c *Client) ListUserSubmissions(ctx context.Context, userID int64, opts ListOptions) (*SubmissionPage, error) {
+	return c.listSubmissions(ctx, fmt.Sprintf("/api/submissions/user/%d", userID), opts)
+}
+
+func (c *Client) ListProblemSubmissions(ctx context.Context, problemID int64, opts ListOptions) (*SubmissionPage, error) {
+	return c.listSubmissions(ctx, fmt.Sprintf("/api/submissions/problem/%d", problemID), opts)
+}
+
+func (c *Client) ListTeamSubmissions(ctx context.Context, teamID int64, opts ListOptions) (*SubmissionPage, error) {
+	return c.listSubmissions(ctx, fmt.Sprintf("/api/submissions/team/%d", teamID), opts)
+}
+
+func (c *Client) listSubmissions(ctx context.Context, path string, opts ListOptions) (*SubmissionPage, error) {
+	query := pageQuery(opts.Limit, opts.Offset)
+	for _, tag := range opts.Tags {
+		query.Add("tag", tag)
+	}
+
+	var page SubmissionPage
+	if err := c.get(ctx, path, query, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (c *Client) GetAttemptTimeline(ctx context.Context, userID, problemID int64) (*AttemptTimeline, error) {
+	var timeline AttemptTimeline
+	if err := c.get(ctx, fmt.Sprintf("/api/users/%d/problems/%d/attempts", userID, problemID), nil, &timeline); err != nil {
+		return nil, err
+	}
+	return &timeline, nil
+}
+
+func (c *Client) GetUserProblemScore(ctx context.Context, userID, problemID int64) (*UserProblemScore, error) {
+	var score UserProblemScore
+	if err := c.get(ctx, fmt.Sprintf("/api/users/%d/problems/%d/score", userID, problemID), nil, &score); err != nil {
+		return nil, err
+	}
+	return &score, nil
+}
+
+func (c *Client) GetUserStats(ctx context.Context, userID int64) (*UserStats, error) {
+	var stats UserStats
+	if err := c.get(ctx, fmt.Sprintf("/api/users/%d/stats", userID), nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// RepairUserStats recounts the per-user verdict counters and returns how
+// many had drifted.
+func (c *Client) RepairUserStats(ctx context.Context) (int64, error) {
+	var response struct {
+		Repaired int64 `json:"repaired"`
+	}
+	if err := c.post(ctx, "/api/admin/users/stats/repair", nil, &response); err != nil {
+		return 0, err
+	}
+	return response.Repaired, nil
+}
+
+func (c *Client) RejudgeSubmission(ctx context.Context, submissionID int64) error {
+	return c.post(ctx, fmt.Sprintf("/api/submissions/%d/rejudge", submissionID), nil, nil)
+}
+
+// RejudgeProblem starts a background rejudge of the problem's submissions;
+// poll GetRejudgeJob for progress. A nil request rejudges all of them.
+func (c *Client) RejudgeProblem(ctx context.Context, problemID int64, request *RejudgeProblemRequest) (*RejudgeJob, error) {
+	if request == nil {
+		request = &RejudgeProblemRequest{}
+	}
+	var job RejudgeJob
+	if err := c.post(ctx, fmt.Sprintf("/api/submissions/problem/%d/rejudge", problemID), request, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (c *Client) GetRejudgeJob(ctx context.Context, jobID int64) (*RejudgeJob, error) {
+	var job RejudgeJob
+	if err := c.get(ctx, fmt.Sprintf("/api/admin/rejudge-jobs/%d", jobID), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (c *Client) SampleRun(ctx context.Context, problemID int64, language, code string) (*SampleRunResult, error) {
+	request := map[string]string{"language": language, "code": code}
+
+	var result SampleRunResult
+	if err := c.post(ctx, fmt.Sprintf("/api/problems/%d/sample-run", problemID), request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) GetSubmissionTests(ctx context.Context, submissionID int64) (*SubmissionTests, error) {
+	var tests SubmissionTests
+	if err := c.get(ctx, fmt.Sprintf("/api/submissions/%d/tests", submissionID), nil, &tests); err != nil {
+		return nil, err
+	}
+	return &tests, nil
+}
+
+func (c *Client) GetSubmissionResults(ctx context.Context, submissionID int64) (*SubmissionResults, error) {
+	var results SubmissionResults
+	if err := c.get(ctx, fmt.Sprintf("/api/submissions/%d/results", submissionID), nil, &results); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+func (c *Client) GetStructuredVerdict(ctx context.Context, submissionID int64) (*StructuredVerdict, error) {
+	var verdict StructuredVerdict
+	if err := c.get(ctx, fmt.Sprintf("/api/submissions/%d/verdict", submissionID), nil, &verdict); err != nil {
+		return nil, err
+	}
+	return &verdict, nil
+}
+
+// GetSubmissionCode returns the submitted code; project submissions come back
+// as their gzipped tarball and output-only submissions as their zip.
+func (c *Client) GetSubmissionCode(ctx context.Context, submissionID int64) ([]byte, error) {
+	var code []byte
+	if err := c.get(ctx, fmt.Sprintf("/api/submissions/%d/code", submissionID), nil, &code); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+func (c *Client) GetCertificate(ctx context.Context, submissionID int64) (*VerdictSignature, error) {
+	var signature VerdictSignature
+	if err := c.get(ctx, fmt.Sprintf("/api/submissions/%d/certificate", submissionID), nil, &signature); err != nil {
+		return nil, err
+	}
+	return &signature, nil
+}
+
+func (c *Client) GetSigningPublicKey(ctx context.Context) (*SigningPublicKey, error) {
+	var key SigningPublicKey
+	if err := c.get(ctx, "/api/certificates/public-key", nil, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// VerifyCertificate is safe to retry, so it bypasses the POST no-retry rule.
+func (c *Client) VerifyCertificate(ctx context.Context, payload, signature string) (*CertificateVerification, error) {
+	request := map[string]string{"payload": payload, "signature": signature}
+
+	var verification CertificateVerification
+	if err := c.call(ctx, "POST", "/api/certificates/verify", nil, nil, request, &verification, true); err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+func (c *Client) GetLanguages(ctx context.Context) ([]Language, error) {
+	var response struct {
+		Languages []Language `json:"languages"`
+	}
+	if err := c.get(ctx, "/api/languages/", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Languages, nil
+}
+
+func (c *Client) GetLanguage(ctx context.Context, code string) (*Language, error) {
+	var language Language
+	if err := c.get(ctx, "/api/languages/"+url.PathEscape(code), nil, &language); err != nil {
+		return nil, err
+	}
+	return &language, nil
+}
+
+func (c *Client) GetProblemDifficulties(ctx context.Context, problemIDs []int64) ([]ProblemDifficulty, error) {
+	ids := make([]string, len(problemIDs))
+	for i, id := range problemIDs {
+		ids[i] = strconv.FormatInt(id, 10)
+	}
+
+	var response struct {
+		Difficulties []ProblemDifficulty `json:"difficulties"`
+	}
+	if err := c.get(ctx, "/api/problems/difficulty", url.Values{"ids": {strings.Join(ids, ",")}}, &response); err != nil {
+		return nil, err
+	}
+	return response.Difficulties, nil
+}
+
+func (c *Client) GetJudgeStatus(ctx context.Context) (*JudgeStatus, error) {
+	var status JudgeStatus
+	if err := c.get(ctx, "/api/judge/status", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) GetQueueStatus(ctx context.Context) (*QueueStatus, error) {
+	var status QueueStatus
+	if err := c.get(ctx, "/api/judge/queue", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) ScaleWorkers(ctx context.Context, workerCount int) (*ScaleResult, error) {
+	var result ScaleResult
+	if err := c.post(ctx, "/api/judge/workers/scale", map[string]int{"worker_count": workerCount}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) GetVersion(ctx context.Context) (*Version, error) {
+	var version Version
+	if err := c.get(ctx, "/api/version", nil, &version); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// Ready reports whether the service accepts traffic; a not-ready service
+// answers 503, which surfaces as ErrUnavailable.
+func (c *Client) Ready(ctx context.Context) error {
+	return c.call(ctx, "GET", "/ready", nil, nil, nil, nil, false)
+}
+
+// GetPlatformLimits returns the platform-wide default and maximum limits.
+func (c *Client) GetPlatformLimits(ctx context.Context) (*PlatformLimits, error) {
+	var limits PlatformLimits
+	if err := c.get(ctx, "/api/admin/limits", nil, &limits); err != nil {
+		return nil, err
+	}
+	return &limits, nil
+}
+
+// UpdatePlatformLimits replaces the platform-wide limits; every field is
+// required.
+func (c *Client) UpdatePlatformLimits(ctx context.Context, limits *PlatformLimits) (*PlatformLimits, error) {
+	var updated PlatformLimits
+	if err := c.put(ctx, "/api/admin/limits", limits, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (c *Client) GetProblemLimits(ctx context.Context, problemID int64) (*EffectiveLimits, error) {
+	var limits EffectiveLimits
+	if err := c.get(ctx, fmt.Sprintf("/api/admin/problems/%d/limits", problemID), nil, &limits); err != nil {
+		return nil, err
+	}
+	return &limits, nil
+}
+
+func (c *Client) SetLimitOverride(ctx context.Context, problemID int64, request *LimitOverrideRequest) (*LimitOverride, error) {
+	var override LimitOverride
+	if err := c.put(ctx, fmt.Sprintf("/api/admin/problems/%d/limits/override", problemID), request, &override); err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
+
+func (c *Client) ClearLimitOverride(ctx context.Context, problemID int64) error {
+	return c.delete(ctx, fmt.Sprintf("/api/admin/problems/%d/limits/override", problemID), nil)
+}
+
+func (c *Client) GetOutdatedSubmissions(ctx context.Context, problemID int64, limit int) (*OutdatedSubmissions, error) {
+	var outdated OutdatedSubmissions
+	if err := c.get(ctx, fmt.Sprintf("/api/admin/problems/%d/outdated-submissions", problemID), pageQuery(limit, 0), &outdated); err != nil {
+		return nil, err
+	}
+	return &outdated, nil
+}
+
+func (c *Client) RefreshTestset(ctx context.Context, problemID int64) (*TestsetRefresh, error) {
+	var refresh TestsetRefresh
+	if err := c.post(ctx, fmt.Sprintf("/api/admin/problems/%d/testset/refresh", problemID), nil, &refresh); err != nil {
+		return nil, err
+	}
+	return &refresh, nil
+}
+
+// ValidateProblemInputs runs an input validator over a problem's tests. An
+// empty validatorURL uses the validator configured on the problem.
+func (c *Client) ValidateProblemInputs(ctx context.Context, problemID int64, validatorURL string) (*InputValidationReport, error) {
+	request := map[string]string{"validator_url": validatorURL}
+
+	var report InputValidationReport
+	if err := c.post(ctx, fmt.Sprintf("/api/admin/problems/%d/validate-inputs", problemID), request, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (c *Client) RecalculateDifficulty(ctx context.Context, problemID int64) (*ProblemDifficulty, error) {
+	var difficulty ProblemDifficulty
+	if err := c.post(ctx, fmt.Sprintf("/api/admin/problems/%d/difficulty/recalculate", problemID), nil, &difficulty); err != nil {
+		return nil, err
+	}
+	return &difficulty, nil
+}
+
+func (c *Client) TransferSubmission(ctx context.Context, submissionID int64, request *TransferRequest) error {
+	return c.post(ctx, fmt.Sprintf("/api/admin/submissions/%d/transfer", submissionID), request, nil)
+}
+
+func (c *Client) ClearBox(ctx context.Context, boxID int) error {
+	return c.post(ctx, fmt.Sprintf("/api/admin/clear-box/%d", boxID), nil, nil)
+}
+
+// ReplayEvents pages the event log after sequence since; eventType may be empty.
+func (c *Client) ReplayEvents(ctx context.Context, since int64, eventType string, limit int) (*EventPage, error) {
+	query := url.Values{"since": {strconv.FormatInt(since, 10)}}
+	if eventType != "" {
+		query.Set("type", eventType)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var page EventPage
+	if err := c.get(ctx, "/api/admin/events", query, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (c *Client) GetScalingEvents(ctx context.Context, limit, offset int) (*ScalingEventPage, error) {
+	var page ScalingEventPage
+	if err := c.get(ctx, "/api/admin/scaling-events", pageQuery(limit, offset), &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetQueueSnapshots returns the queue snapshots taken within window either
+// side of at, from every node when node is empty.
+func (c *Client) GetQueueSnapshots(ctx context.Context, at time.Time, window time.Duration, node string, limit int) (*QueueSnapshotPage, error) {
+	query := url.Values{}
+	query.Set("at", at.Format(time.RFC3339))
+	query.Set("window", window.String())
+	if node != "" {
+		query.Set("node", node)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var page QueueSnapshotPage
+	if err := c.get(ctx, "/api/admin/queue-snapshots", query, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetStoragePolicies returns each bucket's policy status as of the last check.
+func (c *Client) GetStoragePolicies(ctx context.Context) ([]BucketPolicyStatus, error) {
+	var resp struct {
+		Buckets []BucketPolicyStatus `json:"buckets"`
+	}
+	if err := c.get(ctx, "/api/admin/storage/policies", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Buckets, nil
+}
+
+// GetSpoolStatus reports submission code spooled locally while MinIO is down.
+func (c *Client) GetSpoolStatus(ctx context.Context) (*SpoolStatus, error) {
+	var status SpoolStatus
+	if err := c.get(ctx, "/api/admin/storage/spool", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) SetAutoScaleDryRun(ctx context.Context, enabled bool) error {
+	return c.put(ctx, "/api/admin/autoscaler/dry-run", map[string]bool{"enabled": enabled}, nil)
+}
+
+func (c *Client) CreateDiagnosticsBundle(ctx context.Context, failedLimit int) (*DiagnosticsBundle, error) {
+	path := "/api/admin/diagnostics?failed_limit=" + strconv.Itoa(failedLimit)
+
+	var bundle DiagnosticsBundle
+	if err := c.post(ctx, path, nil, &bundle); err != nil {
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+// GetSandboxEnvironment returns the toolchain snapshot of the node that
+// serves the request.
+func (c *Client) GetSandboxCapabilities(ctx context.Context) (*SandboxCapabilities, error) {
+	var capabilities SandboxCapabilities
+	if err := c.get(ctx, "/api/admin/sandbox/capabilities", nil, &capabilities); err != nil {
+		return nil, err
+	}
+	return &capabilities, nil
+}
+
+// ProbeSandboxDevices returns the capabilities with each allowlisted device
+// checked inside a box on the serving node.
+func (c *Client) ProbeSandboxDevices(ctx context.Context) (*SandboxCapabilities, error) {
+	var capabilities SandboxCapabilities
+	query := url.Values{"probe": {"devices"}}
+	if err := c.get(ctx, "/api/admin/sandbox/capabilities", query, &capabilities); err != nil {
+		return nil, err
+	}
+	return &capabilities, nil
+}
+
+func (c *Client) GetBoxPoolStats(ctx context.Context) (*BoxPoolStats, error) {
+	var stats BoxPoolStats
+	if err := c.get(ctx, "/api/admin/box-pool", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func (c *Client) GetWarmPools(ctx context.Context) ([]WarmPoolStats, error) {
+	var resp struct {
+		Pools []WarmPoolStats `json:"pools"`
+	}
+	if err := c.get(ctx, "/api/admin/box-pool/warm", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Pools, nil
+}
+
+// ResizeWarmPool sets how many boxes the node keeps warm for a language.
+func (c *Client) ResizeWarmPool(ctx context.Context, language string, size int) error {
+	return c.put(ctx, "/api/admin/box-pool/warm/"+url.PathEscape(language), map[string]int{"size": size}, nil)
+}
+
+func (c *Client) FlushWarmPool(ctx context.Context, language string) error {
+	return c.post(ctx, "/api/admin/box-pool/warm/"+url.PathEscape(language)+"/flush", nil, nil)
+}
+
+func (c *Client) GetSandboxEnvironment(ctx context.Context) (*SandboxEnvironment, error) {
+	var env SandboxEnvironment
+	if err := c.get(ctx, "/api/admin/environment", nil, &env); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+func (c *Client) GetContestEnvironment(ctx context.Context, contestID int64) (*ContestEnvironmentStatus, error) {
+	var status ContestEnvironmentStatus
+	if err := c.get(ctx, fmt.Sprintf("/api/admin/contests/%d/environment", contestID), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) PinContestEnvironment(ctx context.Context, contestID int64) (*ContestEnvironment, error) {
+	var pin ContestEnvironment
+	if err := c.put(ctx, fmt.Sprintf("/api/admin/contests/%d/environment", contestID), nil, &pin); err != nil {
+		return nil, err
+	}
+	return &pin, nil
+}
+
+func (c *Client) UnpinContestEnvironment(ctx context.Context, contestID int64) error {
+	return c.delete(ctx, fmt.Sprintf("/api/admin/contests/%d/environment", contestID), nil)
+}
+
+func (c *Client) GetShadowReport(ctx context.Context, problemID int64, limit int) (*ShadowReport, error) {
+	var report ShadowReport
+	if err := c.get(ctx, fmt.Sprintf("/api/admin/problems/%d/shadow", problemID), pageQuery(limit, 0), &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (c *Client) StartShadowJudging(ctx context.Context, problemID int64, request *StartShadowRequest) (*ShadowConfig, error) {
+	var config ShadowConfig
+	if err := c.put(ctx, fmt.Sprintf("/api/admin/problems/%d/shadow", problemID), request, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *Client) StopShadowJudging(ctx context.Context, problemID int64) error {
+	return c.delete(ctx, fmt.Sprintf("/api/admin/problems/%d/shadow", problemID), nil)
+}
+
+func (c *Client) SimulateLimits(ctx context.Context, problemID int64, request *SimulateLimitsRequest) (*LimitSimulation, error) {
+	var simulation LimitSimulation
+	if err := c.post(ctx, fmt.Sprintf("/api/admin/problems/%d/simulate-limits", problemID), request, &simulation); err != nil {
+		return nil, err
+	}
+	return &simulation, nil
+}
+
+func (c *Client) GetProblemScoring(ctx context.Context, problemID int64) (*ProblemScoring, error) {
+	var scoring ProblemScoring
+	if err := c.get(ctx, fmt.Sprintf("/api/admin/problems/%d/scoring", problemID), nil, &scoring); err != nil {
+		return nil, err
+	}
+	return &scoring, nil
+}
+
+// SetProblemScoring changes how the problem's submission scores combine and
+// recomputes every user's score on it.
+func (c *Client) SetProblemScoring(ctx context.Context, problemID int64, aggregation string) (*ProblemScoringUpdate, error) {
+	request := map[string]string{"aggregation": aggregation}
+
+	var update ProblemScoringUpdate
+	if err := c.put(ctx, fmt.Sprintf("/api/admin/problems/%d/scoring", problemID), request, &update); err != nil {
+		return nil, err
+	}
+	return &update, nil
+}
+
+func (c *Client) GetProblemScores(ctx context.Context, problemID int64, limit, offset int) (*ProblemScoresPage, error) {
+	var page ProblemScoresPage
+	if err := c.get(ctx, fmt.Sprintf("/api/admin/problems/%d/scores", problemID), pageQuery(limit, offset), &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (c *Client) GetPlagiarismThrottle(ctx context.Context) (*PlagiarismThrottle, error) {
+	var throttle PlagiarismThrottle
+	if err := c.get(ctx, "/api/admin/plagiarism/throttle", nil, &throttle); err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+func (c *Client) ListPlagiarismReports(ctx context.Context, limit, offset int) (*PlagiarismReportPage, error) {
+	var page PlagiarismReportPage
+	if err := c.get(ctx, "/api/admin/plagiarism/reports", pageQuery(limit, offset), &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ListPendingPlagiarismReports lists the reports still awaiting review.
+func (c *Client) ListPendingPlagiarismReports(ctx context.Context, limit, offset int) (*PlagiarismReportPage, error) {
+	query := pageQuery(limit, offset)
+	query.Set("status", "pending")
+
+	var page PlagiarismReportPage
+	if err := c.get(ctx, "/api/admin/plagiarism/reports", query, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (c *Client) GetPlagiarismReport(ctx context.Context, reportID int64) (*PlagiarismReportDetail, error) {
+	var detail PlagiarismReportDetail
+	if err := c.get(ctx, fmt.Sprintf("/api/admin/plagiarism/%d", reportID), nil, &detail); err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+// ReviewPlagiarismReport confirms or dismisses a pending report; status is
+// "confirmed" or "dismissed".
+func (c *Client) ReviewPlagiarismReport(ctx context.Context, reportID int64, request *ReviewPlagiarismRequest) (*PlagiarismReport, error) {
+	var report PlagiarismReport
+	if err := c.put(ctx, fmt.Sprintf("/api/admin/plagiarism/%d/review", reportID), request, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (c *Client) GetPlagiarismDiff(ctx context.Context, reportID int64) (*PlagiarismDiff, error) {
+	var diff PlagiarismDiff
+	if err := c.get(ctx, fmt.Sprintf("/api/admin/plagiarism/%d/diff", reportID), nil, &diff); err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
+
+// StartPlagiarismRun queues a contest-wide plagiarism run. A zero threshold
+// uses the detector's threshold.
+func (c *Client) StartPlagiarismRun(ctx context.Context, contestID int64, threshold float64) (*PlagiarismRun, error) {
+	request := map[string]float64{}
+	if threshold > 0 {
+		request["threshold"] = threshold
+	}
+	var run PlagiarismRun
+	if err := c.post(ctx, fmt.Sprintf("/api/admin/plagiarism/contest/%d/run", contestID), request, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (c *Client) GetPlagiarismRun(ctx context.Context, runID int64) (*PlagiarismRun, error) {
+	var run PlagiarismRun
+	if err := c.get(ctx, fmt.Sprintf("/api/admin/plagiarism/runs/%d", runID), nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (c *Client) ListPlagiarismBoilerplate(ctx context.Context, problemID int64) ([]Boilerplate, error) {
+	var response struct {
+		Boilerplate []Boilerplate `json:"boilerplate"`
+	}
+	if err := c.get(ctx, fmt.Sprintf("/api/admin/problems/%d/plagiarism/boilerplate", problemID), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Boilerplate, nil
+}
+
+// AddPlagiarismBoilerplate registers starter code for a problem. An empty
+// language applies it to every language.
+func (c *Client) AddPlagiarismBoilerplate(ctx context.Context, problemID int64, language, code string) (*Boilerplate, error) {
+	request := map[string]string{"code": code}
+	if language != "" {
+		request["language"] = language
+	}
+	var snippet Boilerplate
+	if err := c.post(ctx, fmt.Sprintf("/api/admin/problems/%d/plagiarism/boilerplate", problemID), request, &snippet); err != nil {
+		return nil, err
+	}
+	return &snippet, nil
+}
+
+func (c *Client) RemovePlagiarismBoilerplate(ctx context.Context, problemID, snippetID int64) error {
+	return c.delete(ctx, fmt.Sprintf("/api/admin/problems/%d/plagiarism/boilerplate/%d", problemID, snippetID), nil)
+}
+
+// FindSimilarSubmissions returns up to limit submissions most similar to the
+// given one; scope is "problem" or "contest".
+func (c *Client) FindSimilarSubmissions(ctx context.Context, submissionID int64, scope string, limit int) (*SimilarSubmissions, error) {
+	query := pageQuery(limit, 0)
+	if scope != "" {
+		query.Set("scope", scope)
+	}
+
+	var result SimilarSubmissions
+	if err := c.get(ctx, fmt.Sprintf("/api/admin/submissions/%d/similar", submissionID), query, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// InspectDeadLetters lists up to limit messages from the dead letter queue,
+// leaving them in place.
+func (c *Client) InspectDeadLetters(ctx context.Context, limit int) (*DeadLetters, error) {
+	var dlq DeadLetters
+	if err := c.get(ctx, "/api/admin/dlq", pageQuery(limit, 0), &dlq); err != nil {
+		return nil, err
+	}
+	return &dlq, nil
+}
+
+// RequeueDeadLetters returns the IDs of the submissions sent back to the
+// judge queue.
+func (c *Client) RequeueDeadLetters(ctx context.Context, request *RequeueDeadLettersRequest) ([]int64, error) {
+	var response struct {
+		SubmissionIDs []int64 `json:"submission_ids"`
+	}
+	if err := c.post(ctx, "/api/admin/dlq/requeue", request, &response); err != nil {
+		return nil, err
+	}
+	return response.SubmissionIDs, nil
+}
+
+// ReplayDeadLetter sends one dead-lettered submission back to the judge
+// queue.
+func (c *Client) ReplayDeadLetter(ctx context.Context, submissionID int64) error {
+	return c.post(ctx, fmt.Sprintf("/api/admin/dlq/%d/replay", submissionID), nil, nil)
+}
+
+// PurgeDeadLetters returns the IDs of the submissions dropped from the dead
+// letter queue.
+func (c *Client) PurgeDeadLetters(ctx context.Context, request *PurgeDeadLettersRequest) ([]int64, error) {
+	var response struct {
+		SubmissionIDs []int64 `json:"submission_ids"`
+	}
+	if err := c.post(ctx, "/api/admin/dlq/purge", request, &response); err != nil {
+		return nil, err
+	}
+	return response.SubmissionIDs, nil
+}
+
+// SetNodeDrained drains or resumes the node the client is pointed at; behind
+// a load balancer, target the node directly.
+func (c *Client) SetNodeDrained(ctx context.Context, drained bool) (*NodeDrainStatus, error) {
+	request := map[string]bool{"drained": drained}
+
+	var status NodeDrainStatus
+	if err := c.put(ctx, "/api/admin/drain", request, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) CreateLanguage(ctx context.Context, request *CreateLanguageRequest) (*Language, error) {
+	var language Language
+	if err := c.post(ctx, "/api/admin/languages", request, &language); err != nil {
+		return nil, err
+	}
+	return &language, nil
+}
+
+// UpdateLanguage changes only the fields set in settings.
+func (c *Client) UpdateLanguage(ctx context.Context, code string, settings *LanguageSettings) (*Language, error) {
+	var language Language
+	if err := c.put(ctx, "/api/admin/languages/"+url.PathEscape(code), settings, &language); err != nil {
+		return nil, err
+	}
+	return &language, nil
+}
+
+func (c *Client) DeleteLanguage(ctx context.Context, code string) error {
+	return c.delete(ctx, "/api/admin/languages/"+url.PathEscape(code), nil)
+}
+
+// RefreshLanguages reloads the language configs on the node the client points
+// at and returns the enabled languages.
+func (c *Client) RefreshLanguages(ctx context.Context) ([]Language, error) {
+	var response struct {
+		Languages []Language `json:"languages"`
+	}
+	if err := c.post(ctx, "/api/admin/languages/refresh", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Languages, nil
+}
+
+// SetSubmissionDebug keeps every execution log of the submission when it is
+// next judged, bypassing log sampling.
+func (c *Client) SetSubmissionDebug(ctx context.Context, submissionID int64, enabled bool) error {
+	request := map[string]bool{"enabled": enabled}
+	return c.put(ctx, fmt.Sprintf("/api/admin/submissions/%d/debug", submissionID), request, nil)
+}
+
+func (c *Client) InvalidateCache(ctx context.Context, request *InvalidateCacheRequest) error {
+	return c.post(ctx, "/api/admin/cache/invalidate", request, nil)
+}
+
+func (c *Client) GetConsistencyReport(ctx context.Context) (*ConsistencyReport, error) {
+	var report ConsistencyReport
+	if err := c.get(ctx, "/api/admin/consistency", nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// RunConsistencyCheck judges the canaries on whichever node serves the
+// request and returns the updated report.
+func (c *Client) RunConsistencyCheck(ctx context.Context) (*ConsistencyReport, error) {
+	var report ConsistencyReport
+	if err := c.post(ctx, "/api/admin/consistency/run", nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (c *Client) ListCanaries(ctx context.Context) ([]Canary, error) {
+	var response struct {
+		Canaries []Canary `json:"canaries"`
+	}
+	if err := c.get(ctx, "/api/admin/canaries", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Canaries, nil
+}
+
+func (c *Client) AddCanary(ctx context.Context, request *AddCanaryRequest) (*Canary, error) {
+	var canary Canary
+	if err := c.post(ctx, "/api/admin/canaries", request, &canary); err != nil {
+		return nil, err
+	}
+	return &canary, nil
+}
+
+func (c *Client) RemoveCanary(ctx context.Context, canaryID int64) error {
+	return c.delete(ctx, fmt.Sprintf("/api/admin/canaries/%d", canaryID), nil)
+}
+
+// CreateAPIKey issues a key to the calling user; the returned secret cannot
+// be retrieved again.
+func (c *Client) CreateAPIKey(ctx context.Context, request *CreateAPIKeyRequest) (*IssuedAPIKey, error) {
+	var issued IssuedAPIKey
+	if err := c.post(ctx, "/api/api-keys", request, &issued); err != nil {
+		return nil, err
+	}
+	return &issued, nil
+}
+
+func (c *Client) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	var response struct {
+		APIKeys []APIKey `json:"api_keys"`
+	}
+	if err := c.get(ctx, "/api/api-keys", nil, &response); err != nil {
+		return nil, err
+	}
+	return response.APIKeys, nil
+}
+
+func (c *Client) RevokeAPIKey(ctx context.Context, keyID int64) (*APIKey, error) {
+	var key APIKey
+	if err := c.delete(ctx, fmt.Sprintf("/api/api-keys/%d", keyID), &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func pageQuery(limit, offset int) url.Values {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	return query
+}
@@ -0,0 +1,273 @@
+// Package client is a typed Go client for the execution service HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error categories returned by API calls, usable with errors.Is.
+var (
+	ErrBadRequest   = errors.New("execution service rejected the request")
+	ErrUnauthorized = errors.New("execution service authentication failed")
+	ErrForbidden    = errors.New("execution service denied access")
+	ErrNotFound     = errors.New("execution service resource not found")
+	ErrRateLimited  = errors.New("execution service rate limit exceeded")
+	ErrUnavailable  = errors.New("execution service unavailable")
+)
+
+// APIError carries the status and error message of a failed call.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("execution service returned status %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnavailable:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// TokenSource returns the bearer token for a request, allowing callers to
+// rotate service tokens without rebuilding the client.
+type TokenSource func(ctx context.Context) (string, error)
+
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	tokenSource TokenSource
+	apiKey      string
+	maxRetries  int
+	retryDelay  time.Duration
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		maxRetries: 3,
+		retryDelay: 200 * time.Millisecond,
+	}
+}
+
+// SetToken authenticates every request with a fixed bearer token.
+func (c *Client) SetToken(token string) {
+	c.tokenSource = func(context.Context) (string, error) { return token, nil }
+}
+
+func (c *Client) SetTokenSource(source TokenSource) {
+	c.tokenSource = source
+}
+
+// SetAPIKey authenticates every request with a self-service API key instead
+// of a bearer token.
+func (c *Client) SetAPIKey(key string) {
+	c.apiKey = key
+	c.tokenSource = nil
+}
+
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
+}
+
+// SetRetryPolicy configures retries of idempotent requests on transport
+// errors, 429 and 5xx responses. Delays double after each attempt.
+func (c *Client) SetRetryPolicy(maxRetries int, baseDelay time.Duration) {
+	c.maxRetries = maxRetries
+	c.retryDelay = baseDelay
+}
+
+// get, put, post and delete decode a JSON response into out when non-nil;
+// a *[]byte out receives the raw body instead.
+func (c *Client) get(ctx context.Context, path string, query url.Values, out any) error {
+	return c.call(ctx, http.MethodGet, path, query, nil, nil, out, true)
+}
+
+func (c *Client) put(ctx context.Context, path string, body, out any) error {
+	return c.call(ctx, http.MethodPut, path, nil, nil, body, out, true)
+}
+
+func (c *Client) delete(ctx context.Context, path string, out any) error {
+	return c.call(ctx, http.MethodDelete, path, nil, nil, nil, out, true)
+}
+
+// post is not retried: a lost response would otherwise duplicate the action.
+func (c *Client) post(ctx context.Context, path string, body, out any) error {
+	return c.call(ctx, http.MethodPost, path, nil, nil, body, out, false)
+}
+
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, header http.Header, body, out any, retry bool) error {
+	var payload []byte
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		payload = encoded
+	}
+
+	attempts := 1
+	if retry {
+		attempts += c.maxRetries
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.backoff(attempt, lastErr)):
+			}
+		}
+
+		resp, err := c.send(ctx, method, path, query, payload, header)
+		if err == nil {
+			err = decodeResponse(resp, out)
+		}
+		lastErr = err
+		if !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+
+	return lastErr
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, payload []byte, header http.Header) (*http.Response, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if err := c.authenticate(ctx, req.Header); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	return resp, nil
+}
+
+func (c *Client) authenticate(ctx context.Context, header http.Header) error {
+	if c.apiKey != "" {
+		header.Set("X-API-Key", c.apiKey)
+		return nil
+	}
+	if c.tokenSource != nil {
+		token, err := c.tokenSource(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get token: %w", err)
+		}
+		if token != "" {
+			header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	return nil
+}
+
+func decodeResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var body struct {
+			Error string `json:"error"`
+		}
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(raw, &body) == nil && body.Error != "" {
+			apiErr.Message = body.Error
+		} else {
+			apiErr.Message = strings.TrimSpace(string(raw))
+		}
+		if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return &retryAfterError{APIError: apiErr, delay: time.Duration(retryAfter) * time.Second}
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if raw, ok := out.(*[]byte); ok {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		*raw = data
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// retryAfterError keeps the server's Retry-After hint alongside the APIError.
+type retryAfterError struct {
+	*APIError
+	delay time.Duration
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.APIError
+}
+
+func retryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnavailable)
+}
+
+func (c *Client) backoff(attempt int, lastErr error) time.Duration {
+	var hinted *retryAfterError
+	if errors.As(lastErr, &hinted) && hinted.delay > 0 {
+		return hinted.delay
+	}
+	return c.retryDelay << (attempt - 1)
+}
@@ -0,0 +1,257 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// StreamEvent is one Server-Sent Event from /api/events/stream.
+type StreamEvent struct {
+	Seq       int64
+	EventType string
+	Data      json.RawMessage
+}
+
+type StreamOptions struct {
+	Since      int64
+	ContestID  *int64
+	EventTypes []string
+}
+
+// StreamEvents delivers dashboard events to handle until ctx is cancelled or
+// handle returns an error. Dropped connections are resumed from the last
+// delivered sequence, so handle sees each event at most once.
+func (c *Client) StreamEvents(ctx context.Context, opts StreamOptions, handle func(StreamEvent) error) error {
+	since := opts.Since
+	delay := c.retryDelay
+
+	for {
+		resumedFrom := since
+		err := c.streamOnce(ctx, opts, &since, handle)
+		if since > resumedFrom {
+			delay = c.retryDelay
+		}
+
+		var handlerErr *streamHandlerError
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.As(err, &handlerErr):
+			return handlerErr.err
+		case err != nil && !retryable(err):
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, 30*time.Second)
+	}
+}
+
+// StreamVerdicts streams SubmissionJudged events decoded as judge results.
+func (c *Client) StreamVerdicts(ctx context.Context, since int64, contestID *int64, handle func(seq int64, result JudgeResult) error) error {
+	opts := StreamOptions{Since: since, ContestID: contestID, EventTypes: []string{"SubmissionJudged"}}
+
+	return c.StreamEvents(ctx, opts, func(event StreamEvent) error {
+		var result JudgeResult
+		if err := json.Unmarshal(event.Data, &result); err != nil {
+			return fmt.Errorf("failed to decode verdict %d: %w", event.Seq, err)
+		}
+		return handle(event.Seq, result)
+	})
+}
+
+// errStreamDone stops readEvents once a stream has delivered its last event.
+var errStreamDone = errors.New("stream done")
+
+type streamHandlerError struct {
+	err error
+}
+
+func (e *streamHandlerError) Error() string {
+	return e.err.Error()
+}
+
+func (c *Client) streamOnce(ctx context.Context, opts StreamOptions, since *int64, handle func(StreamEvent) error) error {
+	query := url.Values{}
+	if opts.ContestID != nil {
+		query.Set("contest_id", strconv.FormatInt(*opts.ContestID, 10))
+	}
+	for _, eventType := range opts.EventTypes {
+		query.Add("type", eventType)
+	}
+
+	header := http.Header{"Accept": {"text/event-stream"}}
+	if *since > 0 {
+		header.Set("Last-Event-ID", strconv.FormatInt(*since, 10))
+	}
+
+	// The shared client's timeout would cut the stream, so use a copy without it.
+	streamClient := *c
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+	streamClient.httpClient = &httpClient
+
+	resp, err := streamClient.send(ctx, http.MethodGet, "/api/events/stream", query, nil, header)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return decodeResponse(resp, nil)
+	}
+	defer resp.Body.Close()
+
+	return readEvents(resp.Body, func(event StreamEvent) error {
+		if err := handle(event); err != nil {
+			return &streamHandlerError{err: err}
+		}
+		if event.Seq > *since {
+			*since = event.Seq
+		}
+		return nil
+	})
+}
+
+// readEvents parses a Server-Sent Events body until it ends or handle fails.
+func readEvents(body io.Reader, handle func(StreamEvent) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+
+	var event StreamEvent
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "id":
+			event.Seq, _ = strconv.ParseInt(value, 10, 64)
+		case "event":
+			event.EventType = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		case "":
+			if line != "" || data.Len() == 0 {
+				continue
+			}
+			event.Data = json.RawMessage(data.String())
+			if err := handle(event); err != nil {
+				return err
+			}
+			event = StreamEvent{}
+			data.Reset()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return fmt.Errorf("%w: event stream closed", ErrUnavailable)
+}
+
+// WatchSubmission delivers judging progress for a submission until it is
+// judged and returns the judged submission. It uses a WebSocket and falls
+// back to Server-Sent Events when one cannot be opened, for example behind
+// proxies that drop upgrades. Progress steps are not replayed, so a dropped
+// connection returns ErrUnavailable and callers fall back to GetSubmission.
+func (c *Client) WatchSubmission(ctx context.Context, submissionID int64, handle func(SubmissionProgress) error) (*Submission, error) {
+	origin := c.baseURL
+	location := "ws" + strings.TrimPrefix(origin, "http") + fmt.Sprintf("/api/submissions/%d/stream", submissionID)
+	config, err := websocket.NewConfig(location, origin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build stream URL: %w", err)
+	}
+	if err := c.authenticate(ctx, config.Header); err != nil {
+		return nil, err
+	}
+
+	ws, err := config.DialContext(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return c.watchSubmissionEvents(ctx, submissionID, handle)
+	}
+	defer ws.Close()
+
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+
+	for {
+		var progress SubmissionProgress
+		if err := websocket.JSON.Receive(ws, &progress); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("%w: submission stream closed", ErrUnavailable)
+			}
+			return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+		}
+		if err := handle(progress); err != nil {
+			return nil, err
+		}
+		if progress.Stage == ProgressCompleted && progress.Submission != nil {
+			return progress.Submission, nil
+		}
+	}
+}
+
+func (c *Client) watchSubmissionEvents(ctx context.Context, submissionID int64, handle func(SubmissionProgress) error) (*Submission, error) {
+	// The shared client's timeout would cut the stream, so use a copy without it.
+	streamClient := *c
+	httpClient := *c.httpClient
+	httpClient.Timeout = 0
+	streamClient.httpClient = &httpClient
+
+	header := http.Header{"Accept": {"text/event-stream"}}
+	resp, err := streamClient.send(ctx, http.MethodGet, fmt.Sprintf("/api/submissions/%d/events", submissionID), nil, nil, header)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, decodeResponse(resp, nil)
+	}
+	defer resp.Body.Close()
+
+	var judged *Submission
+	err = readEvents(resp.Body, func(event StreamEvent) error {
+		var progress SubmissionProgress
+		if err := json.Unmarshal(event.Data, &progress); err != nil {
+			return fmt.Errorf("failed to decode progress: %w", err)
+		}
+		if err := handle(progress); err != nil {
+			return err
+		}
+		if progress.Stage == ProgressCompleted && progress.Submission != nil {
+			judged = progress.Submission
+			return errStreamDone
+		}
+		return nil
+	})
+	if judged != nil {
+		return judged, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return nil, err
+}
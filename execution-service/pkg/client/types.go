@@ -0,0 +1,551 @@
+package client
+
+import (
+	"time"
+
+	"execution_service/internal/models"
+)
+
+// Resource types shared with the service, so responses decode into the exact
+// structures the handlers encode.
+type (
+	Verdict            = models.Verdict
+	Submission         = models.Submission
+	SubmissionMetadata = models.SubmissionMetadata
+	ClientContext      = models.ClientContext
+	LocalTestResult    = models.LocalTestResult
+	StructuredVerdict  = models.StructuredVerdict
+	VerdictRange       = models.VerdictRange
+	Language           = models.SupportedLanguage
+	LanguageSettings   = models.LanguageSettings
+	JudgeResult        = models.JudgeResult
+	VerdictCertificate = models.VerdictCertificate
+	VerdictSignature   = models.VerdictSignature
+	ProblemDifficulty  = models.ProblemDifficulty
+	LimitOverride      = models.LimitOverride
+	PlatformLimits     = models.PlatformLimits
+	EventLogEntry      = models.EventLogEntry
+	ScalingEvent       = models.ScalingEvent
+	SandboxEnvironment = models.SandboxEnvironment
+	ContestEnvironment = models.ContestEnvironment
+	ShadowConfig       = models.ShadowConfig
+	ShadowReport       = models.ShadowReport
+	LimitSimulation    = models.LimitSimulation
+	LimitFlip          = models.LimitFlip
+	LimitRerun         = models.LimitRerun
+	JudgeLoad          = models.JudgeLoad
+	AttemptTimeline    = models.AttemptTimeline
+	AttemptEntry       = models.AttemptEntry
+	TestResult         = models.SubmissionTestResult
+	ResourceUsage      = models.ResourceUsage
+	SpoolStatus        = models.SpoolStatus
+	SubmissionProgress = models.SubmissionProgress
+	APIKey             = models.APIKey
+	Canary             = models.Canary
+	CanaryRun          = models.CanaryRun
+	ConsistencyReport  = models.ConsistencyReport
+	NodeConsistency    = models.NodeConsistency
+	RejudgeJob         = models.RejudgeJob
+	Score              = models.Score
+	ProblemScoring     = models.ProblemScoring
+	UserProblemScore   = models.UserProblemScore
+	UserStats          = models.UserStats
+	PlagiarismReport   = models.PlagiarismReport
+	PlagiarismRun      = models.PlagiarismRun
+	SuspiciousPair     = models.SuspiciousPair
+	Boilerplate        = models.PlagiarismBoilerplate
+	DeadLetterEntry    = models.DeadLetterEntry
+	QueueSnapshot      = models.QueueSnapshot
+	BucketPolicyStatus = models.BucketPolicyStatus
+	QueueSnapshotEntry = models.QueueSnapshotEntry
+)
+
+const (
+	VerdictPending  = models.VerdictPending
+	VerdictAccepted = models.VerdictAccepted
+	VerdictWrongAns = models.VerdictWrongAns
+	VerdictTimeLim  = models.VerdictTimeLim
+	VerdictIdleLim  = models.VerdictIdleLim
+	VerdictMemLim   = models.VerdictMemLim
+	VerdictRuntime  = models.VerdictRuntime
+	VerdictCompile  = models.VerdictCompile
+	VerdictInternal = models.VerdictInternal
+)
+
+const (
+	RejudgeJobPending   = models.RejudgeJobPending
+	RejudgeJobRunning   = models.RejudgeJobRunning
+	RejudgeJobCompleted = models.RejudgeJobCompleted
+	RejudgeJobFailed    = models.RejudgeJobFailed
+)
+
+const (
+	ScoreAggregationSum  = models.ScoreAggregationSum
+	ScoreAggregationMax  = models.ScoreAggregationMax
+	ScoreAggregationLast = models.ScoreAggregationLast
+)
+
+const (
+	APIKeyScopeSubmit  = models.APIKeyScopeSubmit
+	APIKeyScopeReadOwn = models.APIKeyScopeReadOwn
+)
+
+const (
+	ProgressQueued    = models.ProgressQueued
+	ProgressCompiling = models.ProgressCompiling
+	ProgressRunning   = models.ProgressRunning
+	ProgressTested    = models.ProgressTested
+	ProgressCompleted = models.ProgressCompleted
+)
+
+type CreateSubmissionRequest struct {
+	UserID        int64  `json:"user_id"`
+	TeamID        *int64 `json:"team_id,omitempty"`
+	ProblemID     int64  `json:"problem_id"`
+	ContestID     *int64 `json:"contest_id,omitempty"`
+	Language      string `json:"language"`
+	Code          string `json:"code"`
+	TimeLimitMs   int    `json:"time_limit_ms,omitempty"`
+	MemoryLimitKb int    `json:"memory_limit_kb,omitempty"`
+	// SchemaVersion 2 allows SubmissionMetadata.Client and returns Verdict.
+	SchemaVersion int `json:"schema_version,omitempty"`
+	SubmissionMetadata
+	// IdempotencyKey, when set, is sent as the Idempotency-Key header so a
+	// retried request returns the submission the first one created.
+	IdempotencyKey string `json:"-"`
+}
+
+// CreateRepositorySubmissionRequest submits a commit of a git repository.
+// EntryFile sits at the repository root and defaults to main with the
+// language's extension.
+type CreateRepositorySubmissionRequest struct {
+	UserID        int64  `json:"user_id"`
+	TeamID        *int64 `json:"team_id,omitempty"`
+	ProblemID     int64  `json:"problem_id"`
+	ContestID     *int64 `json:"contest_id,omitempty"`
+	Language      string `json:"language"`
+	RepositoryURL string `json:"repository_url"`
+	CommitSHA     string `json:"commit_sha"`
+	EntryFile     string `json:"entry_file,omitempty"`
+	TimeLimitMs   int    `json:"time_limit_ms,omitempty"`
+	MemoryLimitKb int    `json:"memory_limit_kb,omitempty"`
+	SchemaVersion int    `json:"schema_version,omitempty"`
+	SubmissionMetadata
+}
+
+type CreateRepositorySubmissionResponse struct {
+	CreateSubmissionResponse
+	FileCount int   `json:"file_count"`
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+type CreateSubmissionResponse struct {
+	SubmissionID  int64              `json:"submission_id"`
+	Status        string             `json:"status"`
+	Message       string             `json:"message"`
+	SchemaVersion int                `json:"schema_version,omitempty"`
+	Verdict       *StructuredVerdict `json:"verdict,omitempty"`
+}
+
+// ListOptions pages submission listings; Tags filters by metadata tag.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	Tags   []string
+}
+
+type SubmissionPage struct {
+	Submissions []Submission `json:"submissions"`
+	Limit       int          `json:"limit"`
+	Offset      int          `json:"offset"`
+}
+
+type SampleRunResult struct {
+	Verdict      Verdict            `json:"verdict"`
+	CompileError string             `json:"compile_error,omitempty"`
+	TestsPassed  int                `json:"tests_passed"`
+	TestsTotal   int                `json:"tests_total"`
+	Tests        []SampleTestResult `json:"tests"`
+}
+
+type SampleTestResult struct {
+	TestNumber      int     `json:"test_number"`
+	TestCaseID      int64   `json:"test_case_id"`
+	Verdict         Verdict `json:"verdict"`
+	ExecutionTimeMs int     `json:"execution_time_ms"`
+	MemoryUsedKb    int     `json:"memory_used_kb"`
+	Input           string  `json:"input"`
+	ExpectedOutput  string  `json:"expected_output"`
+	ActualOutput    string  `json:"actual_output"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// InputValidationReport is the outcome of running a problem's input validator
+// over its tests.
+type InputValidationReport struct {
+	Valid        bool                    `json:"valid"`
+	CompileError string                  `json:"compile_error,omitempty"`
+	Tests        []InputValidationResult `json:"tests"`
+}
+
+type InputValidationResult struct {
+	TestCaseID      int64  `json:"test_case_id"`
+	TestNumber      int    `json:"test_number"`
+	Valid           bool   `json:"valid"`
+	Message         string `json:"message,omitempty"`
+	ExecutionTimeMs int    `json:"execution_time_ms"`
+}
+
+type CertificateVerification struct {
+	Valid       bool                `json:"valid"`
+	Certificate *VerdictCertificate `json:"certificate,omitempty"`
+}
+
+type SigningPublicKey struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+}
+
+type JudgeStatus struct {
+	TotalWorkers  int  `json:"total_workers"`
+	ActiveWorkers int  `json:"active_workers"`
+	QueueSize     int  `json:"queue_size"`
+	Buffered      int  `json:"buffered"`
+	IsHealthy     bool `json:"is_healthy"`
+}
+
+type QueueStatus struct {
+	QueueSize int  `json:"queue_size"`
+	IsHealthy bool `json:"is_healthy"`
+}
+
+type ScaleResult struct {
+	Message          string `json:"message"`
+	PreviousWorkers  int    `json:"previous_workers,omitempty"`
+	CurrentWorkers   int    `json:"current_workers"`
+	RequestedWorkers int    `json:"requested_workers,omitempty"`
+}
+
+type Version struct {
+	Service             string `json:"service"`
+	SchemaVersion       int64  `json:"schema_version,omitempty"`
+	TargetSchemaVersion int64  `json:"target_schema_version,omitempty"`
+	SchemaReady         bool   `json:"schema_ready,omitempty"`
+}
+
+type EffectiveLimits struct {
+	ProblemID     int64          `json:"problem_id"`
+	Source        string         `json:"source"`
+	TimeLimitMs   int            `json:"time_limit_ms"`
+	MemoryLimitKb int            `json:"memory_limit_kb"`
+	Override      *LimitOverride `json:"override,omitempty"`
+	ContentError  string         `json:"content_error,omitempty"`
+	Budget        *JudgingBudget `json:"budget,omitempty"`
+}
+
+type JudgingBudget struct {
+	TestCount   int   `json:"test_count"`
+	EstimatedMs int64 `json:"estimated_ms"`
+	LimitMs     int64 `json:"limit_ms"`
+	Exceeded    bool  `json:"exceeded"`
+}
+
+type LimitOverrideRequest struct {
+	TimeLimitMs   int    `json:"time_limit_ms"`
+	MemoryLimitKb int    `json:"memory_limit_kb"`
+	TTLMinutes    int    `json:"ttl_minutes"`
+	Reason        string `json:"reason"`
+}
+
+type OutdatedSubmissions struct {
+	ProblemID     int64   `json:"problem_id"`
+	SubmissionIDs []int64 `json:"submission_ids"`
+	HasMore       bool    `json:"has_more"`
+}
+
+type TestsetRefresh struct {
+	ProblemID      int64  `json:"problem_id"`
+	TestsetVersion string `json:"testset_version"`
+	Flagged        int64  `json:"flagged"`
+}
+
+type TransferRequest struct {
+	UserID int64  `json:"user_id"`
+	TeamID *int64 `json:"team_id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+type EventPage struct {
+	Events    []EventLogEntry `json:"events"`
+	NextSince int64           `json:"next_since"`
+	HasMore   bool            `json:"has_more"`
+}
+
+type ScalingEventPage struct {
+	Events []ScalingEvent `json:"events"`
+	DryRun bool           `json:"dry_run"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+type QueueSnapshotPage struct {
+	Snapshots []QueueSnapshot `json:"snapshots"`
+	At        time.Time       `json:"at"`
+	Window    string          `json:"window"`
+	HasMore   bool            `json:"has_more"`
+}
+
+type DiagnosticsBundle struct {
+	ObjectURL   string    `json:"object_url"`
+	DownloadURL string    `json:"download_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	SizeBytes   int       `json:"size_bytes"`
+}
+
+type ContestEnvironmentStatus struct {
+	Pin             ContestEnvironment `json:"pin"`
+	AvailableOnNode bool               `json:"available_on_node"`
+}
+
+type StartShadowRequest struct {
+	CheckerURL    *string `json:"checker_url,omitempty"`
+	TimeLimitMs   *int    `json:"time_limit_ms,omitempty"`
+	MemoryLimitKb *int    `json:"memory_limit_kb,omitempty"`
+	Note          string  `json:"note,omitempty"`
+}
+
+// SimulateLimitsRequest sets the candidate limits. Limit defaults to the 200
+// latest accepted submissions; RerunSample re-judges up to 10 of the slowest.
+type SimulateLimitsRequest struct {
+	TimeLimitMs   int `json:"time_limit_ms"`
+	MemoryLimitKb int `json:"memory_limit_kb"`
+	Limit         int `json:"limit,omitempty"`
+	RerunSample   int `json:"rerun_sample,omitempty"`
+}
+
+// RejudgeProblemRequest filters the submissions to rejudge; zero values
+// match everything.
+type RejudgeProblemRequest struct {
+	Verdicts        []Verdict  `json:"verdicts,omitempty"`
+	SubmittedAfter  *time.Time `json:"submitted_after,omitempty"`
+	SubmittedBefore *time.Time `json:"submitted_before,omitempty"`
+}
+
+// AddCanaryRequest leaves ExpectedVerdict empty to expect the submission's
+// current verdict.
+type AddCanaryRequest struct {
+	SubmissionID    int64   `json:"submission_id"`
+	ExpectedVerdict Verdict `json:"expected_verdict,omitempty"`
+}
+
+// PlagiarismThrottle reports how plagiarism detection is backing off for
+// judge load; State is "running", "slowed" or "paused".
+type PlagiarismThrottle struct {
+	State       string    `json:"state"`
+	Since       time.Time `json:"since"`
+	Load        JudgeLoad `json:"load"`
+	Utilization float64   `json:"utilization"`
+	CheckedAt   time.Time `json:"checked_at"`
+	Backlog     int       `json:"backlog"`
+}
+
+// SandboxCapabilities describe the node's sandbox backend. Without
+// CgroupMetrics, reported time and memory include sandbox overhead and memory
+// limit verdicts come only from the enforced limit. Devices is set only by
+// ProbeSandboxDevices.
+type SandboxCapabilities struct {
+	Backend       string          `json:"backend"`
+	CgroupMetrics bool            `json:"cgroup_metrics"`
+	Seccomp       bool            `json:"seccomp"`
+	ProcessLimit  bool            `json:"process_limit"`
+	Devices       map[string]bool `json:"devices,omitempty"`
+}
+
+// BoxPoolStats reports the node's pool of reusable sandbox boxes. Fallbacks
+// count runs that found the pool exhausted and used a fresh box.
+type BoxPoolStats struct {
+	Size        int     `json:"size"`
+	Available   int     `json:"available"`
+	Warm        int     `json:"warm"`
+	InUse       int     `json:"in_use"`
+	Utilization float64 `json:"utilization"`
+	Acquired    int64   `json:"acquired"`
+	Fallbacks   int64   `json:"fallbacks"`
+	Resets      int64   `json:"resets"`
+	Reinits     int64   `json:"reinits"`
+	Dropped     int64   `json:"dropped"`
+}
+
+// WarmPoolStats reports one language's warm boxes. HitRate is the share of
+// executions that found a warm box ready.
+type WarmPoolStats struct {
+	Language         string  `json:"language"`
+	Target           int     `json:"target"`
+	Ready            int     `json:"ready"`
+	Hits             int64   `json:"hits"`
+	Misses           int64   `json:"misses"`
+	HitRate          float64 `json:"hit_rate"`
+	Reinits          int64   `json:"reinits"`
+	OldestAgeSeconds float64 `json:"oldest_age_seconds"`
+}
+
+type SubmissionTests struct {
+	SubmissionID int64        `json:"submission_id"`
+	Tests        []TestResult `json:"tests"`
+}
+
+// SubmissionResults is the per-test verdict breakdown; Redacted is set when
+// fields only admins may see were removed.
+type SubmissionResults struct {
+	SubmissionID int64        `json:"submission_id"`
+	Verdict      Verdict      `json:"verdict"`
+	Redacted     bool         `json:"redacted"`
+	Results      []TestResult `json:"results"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	RateLimit     int      `json:"rate_limit,omitempty"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty"`
+}
+
+// IssuedAPIKey holds a new key's secret, which the service never shows again.
+type IssuedAPIKey struct {
+	APIKey APIKey `json:"api_key"`
+	Key    string `json:"key"`
+}
+
+// ProblemScoringUpdate reports how many user scores were recomputed under
+// the new aggregation.
+type ProblemScoringUpdate struct {
+	Scoring    ProblemScoring `json:"scoring"`
+	Recomputed int64          `json:"recomputed"`
+}
+
+type ProblemScoresPage struct {
+	ProblemID int64              `json:"problem_id"`
+	Scores    []UserProblemScore `json:"scores"`
+	Limit     int                `json:"limit"`
+	Offset    int                `json:"offset"`
+}
+
+type PlagiarismReportPage struct {
+	Reports []PlagiarismReport `json:"reports"`
+	Limit   int                `json:"limit"`
+	Offset  int                `json:"offset"`
+}
+
+type PlagiarismReportDetail struct {
+	Report      PlagiarismReport `json:"report"`
+	Submission1 Submission       `json:"submission1"`
+	Submission2 Submission       `json:"submission2"`
+}
+
+type ReviewPlagiarismRequest struct {
+	Status string `json:"status"`
+	Notes  string `json:"notes,omitempty"`
+}
+
+// SimilarSubmission is one hit of a similarity search; Score is the highest
+// score over the detector's algorithms.
+type SimilarSubmission struct {
+	SubmissionID int64           `json:"submission_id"`
+	UserID       int64           `json:"user_id"`
+	ContestID    *int64          `json:"contest_id,omitempty"`
+	Language     string          `json:"language"`
+	Verdict      Verdict         `json:"verdict"`
+	Score        float64         `json:"score"`
+	Algorithm    string          `json:"algorithm"`
+	Regions      []MatchedRegion `json:"regions"`
+}
+
+// MatchedRegion is a run of lines shared by both submissions, numbered from
+// 1; Preview is taken from the searched submission.
+type MatchedRegion struct {
+	StartLine      int    `json:"start_line"`
+	EndLine        int    `json:"end_line"`
+	OtherStartLine int    `json:"other_start_line"`
+	OtherEndLine   int    `json:"other_end_line"`
+	Preview        string `json:"preview"`
+}
+
+// PlagiarismDiff is a line-level diff of a report's two submissions. Op is
+// "equal", "removed" or "added"; Matched marks lines the flagging algorithm
+// found in both submissions.
+type PlagiarismDiff struct {
+	ReportID  int64           `json:"report_id"`
+	Algorithm string          `json:"algorithm"`
+	Score     float64         `json:"similarity_score"`
+	First     DiffSubmission  `json:"first"`
+	Second    DiffSubmission  `json:"second"`
+	Lines     []DiffLine      `json:"lines"`
+	Regions   []MatchedRegion `json:"regions"`
+	Stats     map[string]int  `json:"stats"`
+	Truncated bool            `json:"truncated"`
+}
+
+type DiffSubmission struct {
+	SubmissionID int64  `json:"submission_id"`
+	UserID       int64  `json:"user_id"`
+	Language     string `json:"language"`
+	LineCount    int    `json:"line_count"`
+	MatchedLines []int  `json:"matched_lines"`
+}
+
+type DiffLine struct {
+	Op           string `json:"op"`
+	FirstLine    int    `json:"first_line,omitempty"`
+	SecondLine   int    `json:"second_line,omitempty"`
+	Text         string `json:"text"`
+	OtherText    string `json:"other_text,omitempty"`
+	Matched      bool   `json:"matched"`
+	OtherMatched bool   `json:"other_matched,omitempty"`
+}
+
+type SimilarSubmissions struct {
+	SubmissionID int64               `json:"submission_id"`
+	Scope        string              `json:"scope"`
+	Similar      []SimilarSubmission `json:"similar"`
+}
+
+type DeadLetters struct {
+	Messages []DeadLetterEntry `json:"messages"`
+	HasMore  bool              `json:"has_more"`
+}
+
+// RequeueDeadLettersRequest requeues only SubmissionIDs when set, examining
+// at most Limit messages (default 100).
+type RequeueDeadLettersRequest struct {
+	SubmissionIDs []int64 `json:"submission_ids,omitempty"`
+	Limit         int     `json:"limit,omitempty"`
+}
+
+// PurgeDeadLettersRequest drops the messages of SubmissionIDs and of
+// ProblemID, examining at most Limit messages (default 100).
+type PurgeDeadLettersRequest struct {
+	SubmissionIDs []int64 `json:"submission_ids,omitempty"`
+	ProblemID     int64   `json:"problem_id,omitempty"`
+	Limit         int     `json:"limit,omitempty"`
+}
+
+type NodeDrainStatus struct {
+	Node    string `json:"node"`
+	Drained bool   `json:"drained"`
+}
+
+type CreateLanguageRequest struct {
+	LanguageCode string `json:"language_code"`
+	LanguageSettings
+}
+
+type InvalidateCacheRequest struct {
+	SubmissionIDs []int64  `json:"submission_ids,omitempty"`
+	ProblemIDs    []int64  `json:"problem_ids,omitempty"`
+	Languages     []string `json:"languages,omitempty"`
+	JudgeStatus   bool     `json:"judge_status,omitempty"`
+	// Targets are also dropped on every judge node: problem:123,
+	// language:cpp, user-perms:42 or all-testcases.
+	Targets []string `json:"targets,omitempty"`
+}